@@ -36,6 +36,87 @@ var E_Schemapath = &proto.ExtensionDesc{
 	Filename:      "github.com/openconfig/ygot/proto/yext/yext.proto",
 }
 
+var E_IdentityBase = &proto.ExtensionDesc{
+	ExtendedType:  (*google_protobuf.FieldOptions)(nil),
+	ExtensionType: (*string)(nil),
+	Field:         1041,
+	Name:          "yext.identity_base",
+	Tag:           "bytes,1041,opt,name=identity_base,json=identityBase",
+	Filename:      "github.com/openconfig/ygot/proto/yext/yext.proto",
+}
+
+var E_When = &proto.ExtensionDesc{
+	ExtendedType:  (*google_protobuf.FieldOptions)(nil),
+	ExtensionType: (*string)(nil),
+	Field:         1042,
+	Name:          "yext.when",
+	Tag:           "bytes,1042,opt,name=when",
+	Filename:      "github.com/openconfig/ygot/proto/yext/yext.proto",
+}
+
+var E_FractionDigits = &proto.ExtensionDesc{
+	ExtendedType:  (*google_protobuf.FieldOptions)(nil),
+	ExtensionType: (*uint32)(nil),
+	Field:         1043,
+	Name:          "yext.fraction_digits",
+	Tag:           "varint,1043,opt,name=fraction_digits,json=fractionDigits",
+	Filename:      "github.com/openconfig/ygot/proto/yext/yext.proto",
+}
+
+var E_MinElements = &proto.ExtensionDesc{
+	ExtendedType:  (*google_protobuf.FieldOptions)(nil),
+	ExtensionType: (*uint64)(nil),
+	Field:         1044,
+	Name:          "yext.min_elements",
+	Tag:           "varint,1044,opt,name=min_elements,json=minElements",
+	Filename:      "github.com/openconfig/ygot/proto/yext/yext.proto",
+}
+
+var E_MaxElements = &proto.ExtensionDesc{
+	ExtendedType:  (*google_protobuf.FieldOptions)(nil),
+	ExtensionType: (*uint64)(nil),
+	Field:         1045,
+	Name:          "yext.max_elements",
+	Tag:           "varint,1045,opt,name=max_elements,json=maxElements",
+	Filename:      "github.com/openconfig/ygot/proto/yext/yext.proto",
+}
+
+var E_EmptyEnum = &proto.ExtensionDesc{
+	ExtendedType:  (*google_protobuf.FieldOptions)(nil),
+	ExtensionType: (*bool)(nil),
+	Field:         1046,
+	Name:          "yext.empty_enum",
+	Tag:           "varint,1046,opt,name=empty_enum,json=emptyEnum",
+	Filename:      "github.com/openconfig/ygot/proto/yext/yext.proto",
+}
+
+var E_EnumDefault = &proto.ExtensionDesc{
+	ExtendedType:  (*google_protobuf.FieldOptions)(nil),
+	ExtensionType: (*string)(nil),
+	Field:         1047,
+	Name:          "yext.enum_default",
+	Tag:           "bytes,1047,opt,name=enum_default,json=enumDefault",
+	Filename:      "github.com/openconfig/ygot/proto/yext/yext.proto",
+}
+
+var E_LeaflistSemantics = &proto.ExtensionDesc{
+	ExtendedType:  (*google_protobuf.FieldOptions)(nil),
+	ExtensionType: (*string)(nil),
+	Field:         1048,
+	Name:          "yext.leaflist_semantics",
+	Tag:           "bytes,1048,opt,name=leaflist_semantics,json=leaflistSemantics",
+	Filename:      "github.com/openconfig/ygot/proto/yext/yext.proto",
+}
+
+var E_DefaultValue = &proto.ExtensionDesc{
+	ExtendedType:  (*google_protobuf.FieldOptions)(nil),
+	ExtensionType: ([]string)(nil),
+	Field:         1049,
+	Name:          "yext.default_value",
+	Tag:           "bytes,1049,rep,name=default_value,json=defaultValue",
+	Filename:      "github.com/openconfig/ygot/proto/yext/yext.proto",
+}
+
 var E_YangName = &proto.ExtensionDesc{
 	ExtendedType:  (*google_protobuf.EnumValueOptions)(nil),
 	ExtensionType: (*string)(nil),
@@ -47,23 +128,43 @@ var E_YangName = &proto.ExtensionDesc{
 
 func init() {
 	proto.RegisterExtension(E_Schemapath)
+	proto.RegisterExtension(E_IdentityBase)
+	proto.RegisterExtension(E_When)
+	proto.RegisterExtension(E_FractionDigits)
+	proto.RegisterExtension(E_MinElements)
+	proto.RegisterExtension(E_MaxElements)
+	proto.RegisterExtension(E_EmptyEnum)
+	proto.RegisterExtension(E_EnumDefault)
+	proto.RegisterExtension(E_LeaflistSemantics)
+	proto.RegisterExtension(E_DefaultValue)
 	proto.RegisterExtension(E_YangName)
 }
 
 func init() { proto.RegisterFile("github.com/openconfig/ygot/proto/yext/yext.proto", fileDescriptor0) }
 
 var fileDescriptor0 = []byte{
-	// 181 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x64, 0xcc, 0xb1, 0x0a, 0xc2, 0x30,
-	0x10, 0x80, 0x61, 0x04, 0x91, 0x36, 0x63, 0x27, 0x11, 0x84, 0xba, 0x39, 0x25, 0x82, 0x5b, 0x07,
-	0x9d, 0x74, 0x54, 0x70, 0x70, 0x95, 0x34, 0xbd, 0xa6, 0x81, 0x26, 0x17, 0xda, 0x0b, 0xd8, 0xb7,
-	0xf0, 0x91, 0xa5, 0x0d, 0x82, 0xe8, 0x72, 0xdc, 0xc1, 0xff, 0x1d, 0xdb, 0x69, 0x43, 0x4d, 0x28,
-	0xb9, 0x42, 0x2b, 0xd0, 0x83, 0x53, 0xe8, 0x6a, 0xa3, 0xc5, 0xa0, 0x91, 0x84, 0xef, 0x90, 0x50,
-	0x0c, 0xf0, 0xa4, 0x69, 0xf0, 0xe9, 0xce, 0xe6, 0xe3, 0xbe, 0xca, 0x35, 0xa2, 0x6e, 0x21, 0x36,
-	0x65, 0xa8, 0x45, 0x05, 0xbd, 0xea, 0x8c, 0x27, 0xec, 0x62, 0x57, 0x1c, 0x18, 0xeb, 0x55, 0x03,
-	0x56, 0x7a, 0x49, 0x4d, 0xb6, 0xe6, 0x11, 0xf0, 0x0f, 0xe0, 0x67, 0x03, 0x6d, 0x75, 0xf5, 0x64,
-	0xd0, 0xf5, 0xcb, 0x57, 0x92, 0xcf, 0xb6, 0xe9, 0xed, 0x4b, 0x14, 0x47, 0x96, 0x0e, 0xd2, 0xe9,
-	0x87, 0x93, 0x16, 0xb2, 0xcd, 0x1f, 0x3f, 0xb9, 0x60, 0xef, 0xb2, 0x0d, 0xf0, 0xf3, 0x22, 0x19,
-	0xd1, 0x45, 0x5a, 0x28, 0x17, 0x53, 0xbb, 0x7f, 0x07, 0x00, 0x00, 0xff, 0xff, 0x80, 0x8f, 0xa8,
-	0x80, 0xe3, 0x00, 0x00, 0x00,
+	// 364 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x8c, 0xd2, 0x4f, 0x4b, 0xfb, 0x30,
+	0x18, 0xc0, 0x71, 0x7e, 0xfc, 0x86, 0x74, 0xd9, 0xa6, 0xd8, 0x93, 0x08, 0xc2, 0xbc, 0x79, 0x6a,
+	0x45, 0x6f, 0x05, 0x15, 0x75, 0xd3, 0x93, 0x0a, 0x15, 0xbc, 0x86, 0xb4, 0x7d, 0xda, 0x06, 0x9a,
+	0xa4, 0x34, 0xa9, 0xae, 0xef, 0xc2, 0xff, 0x7f, 0xde, 0xad, 0x24, 0x59, 0x45, 0xf4, 0x10, 0x2f,
+	0x63, 0xa5, 0xcf, 0xe7, 0x4b, 0x52, 0x1e, 0xb4, 0x5b, 0x50, 0x55, 0xb6, 0x49, 0x90, 0x0a, 0x16,
+	0x8a, 0x1a, 0x78, 0x2a, 0x78, 0x4e, 0x8b, 0xb0, 0x2b, 0x84, 0x0a, 0xeb, 0x46, 0x28, 0x11, 0x76,
+	0xb0, 0x50, 0xe6, 0x27, 0x30, 0xcf, 0xfe, 0x40, 0xff, 0xdf, 0x9c, 0x16, 0x42, 0x14, 0x15, 0xd8,
+	0x99, 0xa4, 0xcd, 0xc3, 0x0c, 0x64, 0xda, 0xd0, 0x5a, 0x89, 0xc6, 0xce, 0x45, 0x87, 0x08, 0xc9,
+	0xb4, 0x04, 0x46, 0x6a, 0xa2, 0x4a, 0x7f, 0x2b, 0xb0, 0x20, 0xe8, 0x41, 0x70, 0x46, 0xa1, 0xca,
+	0xae, 0x6a, 0x45, 0x05, 0x97, 0x1b, 0xf7, 0xde, 0xf4, 0xdf, 0xce, 0x30, 0xfe, 0x26, 0xa2, 0x53,
+	0x34, 0xa1, 0x19, 0x70, 0x45, 0x55, 0x87, 0x13, 0x22, 0xc1, 0x95, 0x78, 0xb0, 0x89, 0x71, 0x8f,
+	0x4e, 0x88, 0x84, 0x68, 0x0f, 0x0d, 0xee, 0x4a, 0xe0, 0x2e, 0xfb, 0x68, 0xad, 0x99, 0x8d, 0xce,
+	0xd1, 0x5a, 0xde, 0x90, 0x54, 0xbf, 0xc1, 0x19, 0x2d, 0xa8, 0x92, 0x2e, 0xfe, 0xa4, 0xf9, 0x24,
+	0x5e, 0xed, 0xd9, 0xcc, 0xa8, 0xe8, 0x18, 0x8d, 0x19, 0xe5, 0x18, 0x2a, 0x60, 0xc0, 0xdd, 0x95,
+	0x67, 0x5d, 0x19, 0xc4, 0x23, 0x46, 0xf9, 0x7c, 0x49, 0x4c, 0x82, 0x2c, 0xfe, 0x9c, 0x78, 0xe9,
+	0x13, 0x64, 0xf1, 0x95, 0x38, 0x40, 0x08, 0x58, 0xad, 0x3a, 0x0c, 0xbc, 0x65, 0xae, 0xc0, 0xab,
+	0x0e, 0x78, 0xf1, 0xd0, 0x88, 0x39, 0x6f, 0x99, 0x3e, 0x81, 0x86, 0x38, 0x83, 0x9c, 0xb4, 0x95,
+	0x72, 0x05, 0xde, 0xec, 0x97, 0x1c, 0x69, 0x33, 0xb3, 0x24, 0xba, 0x40, 0x7e, 0x05, 0x24, 0xaf,
+	0xa8, 0x54, 0x58, 0x02, 0x23, 0x5c, 0xd1, 0xd4, 0x79, 0x95, 0x77, 0x1b, 0x5a, 0xef, 0xe5, 0x75,
+	0x0f, 0xf5, 0x62, 0x2c, 0x0f, 0x83, 0x6f, 0x49, 0xd5, 0x3a, 0x17, 0xe3, 0xc3, 0x9b, 0xfe, 0xd7,
+	0x8b, 0xb1, 0x44, 0x37, 0xda, 0x44, 0x47, 0x68, 0xd8, 0x11, 0x5e, 0x60, 0x4e, 0x18, 0xf8, 0xdb,
+	0xbf, 0x02, 0xfa, 0xea, 0x66, 0xf0, 0xc7, 0x82, 0x7a, 0x1a, 0x5d, 0x12, 0x06, 0xc9, 0x8a, 0x99,
+	0xdd, 0xff, 0x1c, 0x00, 0xbc, 0x3c, 0xaf, 0x09, 0x41, 0x03, 0x00, 0x00,
 }
@@ -19,8 +19,10 @@
 package main
 
 import (
+	"encoding/json"
 	"flag"
 	"fmt"
+	"io/ioutil"
 	"os"
 	"path/filepath"
 	"strings"
@@ -44,10 +46,45 @@ var (
 	generateFakeRoot    = flag.Bool("generate_fakeroot", false, "If set to true, a fake element at the root of the data tree is generated. The fake root's name can be controlled with the fakeroot_name flag.")
 	fakeRootName        = flag.String("fakeroot_name", "Device", "The name of the fake root entity.")
 	annotateSchemaPaths = flag.Bool("add_schemapaths", true, "If set to true, the schema path of each YANG entity is added as a protobuf field option")
+	fieldDescriptions   = flag.Bool("add_field_descriptions", true, "If set to true, the YANG description of each entity is output as a comment above the protobuf field representing it.")
 	annotateEnumNames   = flag.Bool("add_enumnames", true, "If set to true, each value within output enums will be annotated with the label in the original YANG schema.")
 	packageHierarchy    = flag.Bool("package_hierarchy", false, "If set to true, an individual protobuf package is output per level of the YANG schema tree.")
 	callerName          = flag.String("caller_name", "proto_generator", "The name of the generator binary that should be recorded in output files.")
 	excludeState        = flag.Bool("exclude_state", false, "If set to true, state (config false) fields in the YANG schema are not included in the generated Protobuf messages.")
+	upperSnakeEnums     = flag.Bool("upper_snake_enum_values", false, "If set to true, the names of the values within output enums are converted to UPPER_SNAKE_CASE.")
+	identityrefStrings  = flag.Bool("identityref_as_string", false, "If set to true, identityref leaves are output as strings annotated with their base identity, rather than as enumerated types.")
+	identitiesFile      = flag.Bool("identities_file", false, "If set to true, the enumerated types generated for YANG identities are output to a single identities.proto file, rather than the enum package.")
+	schemaPathField     = flag.Bool("add_schema_path_field", false, "If set to true, a _schema_path field is added to each generated message, which can be populated with the schema path of the message.")
+	integerTypes        = flag.String("integer_types", "", "Comma separated list of mappings of YANG integer types to the protobuf type that should be used to represent them, in the form yang-type=proto-type, e.g., uint8=ywrapper.UintValue,uint64=uint64.")
+	manifestFile        = flag.String("manifest_file", "", "Path to which a JSON manifest describing the generated messages, their fields, field numbers, and schema paths, should be written. If unset, no manifest is written.")
+	inputManifestFile   = flag.String("input_manifest_file", "", "Path to a JSON manifest written by a prior generation of the messages, using manifest_file. Field numbers that were used by the prior generation, and are no longer used, are output as reserved.")
+	reservedTagsFile    = flag.String("reserved_tags_file", "", "Path to a JSON file containing a map, keyed by the YANG schema path of each message, of the field numbers that were used by a prior generation of the message. Field numbers that are no longer used are output as reserved.")
+	whenPolicy          = flag.String("when_policy", "include", "The policy used for fields corresponding to YANG schema nodes that have a when statement. One of include (the fields are output), exclude (the fields are not output), or annotate (the fields are output with the when statement's XPath expression as a field option).")
+	proto2              = flag.Bool("proto2", false, "If set to true, the generated protobufs use proto2 syntax, with mandatory leaves output as required fields, and native protobuf types used for scalar leaves.")
+	goPackageBase       = flag.String("go_package_base", "", "The Go import path within which the Go code for the generated protobufs is located, used to set the go_package option of each generated file. If unset, the base_import_path is used.")
+	javaPackageBase     = flag.String("java_package_base", "", "The Java package within which the Java code for the generated protobufs is located, used to set the java_package option of each generated file. If unset, no java_package option is output.")
+	modulePackages      = flag.String("module_packages", "", "Comma separated list of mappings of YANG module names to the protobuf package, relative to package_name, that should be used for the module's data tree, in the form module=package, e.g., openconfig-interfaces=interfaces.")
+	messageOptionsFile  = flag.String("message_options_file", "", "Path to a JSON file containing a map, keyed by the YANG schema path of each message, of the options that should be output within the message, each of the form name = value, e.g., (my.custom) = true.")
+	cardinalityPolicy   = flag.String("cardinality_policy", "omit", "The policy used to output the min-elements and max-elements of YANG lists and leaf-lists. One of omit (the cardinality is not output), comment (the cardinality is output as a comment above the field), annotate (the cardinality is output as field options), or both.")
+	enumZeroValueName   = flag.String("enum_zero_value_name", "UNSET", "The label used for the zero value of each generated enum, which indicates that the field is unset.")
+	enumZeroPolicy      = flag.String("enum_zero_value_policy", "prefixed", "The policy used to name the zero value of each generated enum. One of prefixed (the label is prefixed with the name of the enum, as for other values), or plain (the label is output without a prefix).")
+	enumValueOffset     = flag.Int64("enum_value_offset", 1, "The offset added to the value of each YANG enumeration value to determine the value of the generated enum value, such that the values below it are reserved. The values of the enums generated for identities are also at least the offset.")
+	emptyEnumPolicy     = flag.String("empty_enum_policy", "emit", "The policy used to output YANG identities that have no derived identities. One of emit (an enum containing only the zero value is output), string (no enum is output, and referencing fields are mapped to strings), or annotate (as for string, with leaves annotated with the empty_enum field option).")
+	escapeReservedWords = flag.Bool("escape_reserved_words", false, "If set to true, an underscore is appended to the names of generated fields that are protobuf keywords, e.g., message.")
+	uniqueNameSuffix    = flag.String("unique_name_suffix", "", "The suffix used to disambiguate generated names that would otherwise clash. If it contains %d, it is formatted with a counter starting at 1, otherwise it is appended until the name is unique. Defaults to an underscore.")
+	typedefUnions       = flag.Bool("typedef_union_messages", false, "If set to true, YANG unions that are defined by a typedef are output as a message within the unions package that is shared by all fields of the type, rather than as a oneof within each message.")
+	qualifyNestedKeys   = flag.Bool("qualify_nested_list_keys", false, "If set to true, the key message of a list that is directly within another list is prefixed with the name of the enclosing list's message, such that lists of the same name within different lists do not result in key messages with clashing names.")
+	singleKeyMaps       = flag.Bool("single_key_list_maps", false, "If set to true, keyed lists with a single key whose type is a protobuf integer, bool or string type are output as a map keyed by the value of the key, rather than as a repeated field of a key message.")
+	enumDefaults        = flag.Bool("annotate_enum_defaults", false, "If set to true, the YANG default of enumeration and identityref leaves is annotated onto the generated field using the (yext.enum_default) option, and the zero value of all generated enums indicates that the field is unset.")
+	scalarDefaults      = flag.Bool("annotate_scalar_defaults", false, "If set to true, the YANG default of leaves and leaf-lists that are not enumerations or identityrefs is annotated onto the generated field using the (yext.default_value) option.")
+	leafListSemantics   = flag.Bool("annotate_leaflist_semantics", false, "If set to true, leaf-list fields are annotated with the (yext.leaflist_semantics) option, which is list for leaf-lists that are ordered-by user, and set for those that are ordered-by system.")
+	commentStyle        = flag.String("comment_style", "line", "The style of the comments that are output in the generated protobufs. One of line (comments start with //), or block (comments are delimited by /* and */).")
+	packagePolicy       = flag.String("package_policy", "path", "The policy used to derive the protobuf package of each generated message. One of path (packages follow the, optionally compressed, schema path of the message's parent), uncompressed_path (packages follow the full schema path, regardless of path compression), or single (all messages are output in the base package, or that specified for their module in module_packages).")
+	maxPackageDepth     = flag.Int("max_package_depth", 0, "If set to a non-zero value, the maximum number of elements, below the base package, of the name of each generated package. Elements beyond the maximum are replaced by a single element containing a hash of them, and the unabbreviated name is output in a comment in the package's header.")
+	googleWrappers      = flag.Bool("google_wrappers", false, "If set to true, the wrapper types defined in google/protobuf/wrappers.proto are used for scalar fields in place of the equivalent ywrapper types.")
+	splitConfigState    = flag.Bool("split_config_state", false, "If set to true, the leaves of each generated message are output in separate Config and State messages according to whether they are writable or read-only.")
+	orderOneofsByTag    = flag.Bool("order_oneofs_by_tag", false, "If set to true, the fields within each generated oneof are output in ascending order of their field numbers, rather than in the order of their types.")
+	groupingNames       = flag.Bool("grouping_message_names", false, "If set to true, messages for containers and lists whose contents are entirely instantiated from a single YANG grouping are named after the grouping.")
 )
 
 // main parses command-line flags to determine the set of YANG modules for
@@ -90,6 +127,146 @@ func main() {
 		}
 	}
 
+	// Determine the protobuf types that the user has requested to be used
+	// for YANG integer types.
+	intTypes := map[yang.TypeKind]string{}
+	if len(*integerTypes) > 0 {
+		for _, m := range strings.Split(*integerTypes, ",") {
+			mp := strings.Split(m, "=")
+			if len(mp) != 2 {
+				log.Exitf("Error: invalid integer type mapping %s, must be of the form yang-type=proto-type", m)
+			}
+			k, ok := yang.TypeKindFromName[mp[0]]
+			if !ok {
+				log.Exitf("Error: invalid YANG type %s in integer type mapping %s", mp[0], m)
+			}
+			intTypes[k] = mp[1]
+		}
+	}
+
+	// Determine the packages that the user has requested to be used for
+	// specific modules.
+	modPkgs := map[string]string{}
+	if len(*modulePackages) > 0 {
+		for _, m := range strings.Split(*modulePackages, ",") {
+			mp := strings.Split(m, "=")
+			if len(mp) != 2 {
+				log.Exitf("Error: invalid module package mapping %s, must be of the form module=package", m)
+			}
+			modPkgs[mp[0]] = mp[1]
+		}
+	}
+
+	// Load the field numbers that were used by a prior generation of the
+	// messages, such that those that are no longer used can be reserved.
+	var reservedTags map[string][]uint32
+	if *reservedTagsFile != "" {
+		b, err := ioutil.ReadFile(*reservedTagsFile)
+		if err != nil {
+			log.Exitf("Error: could not read reserved tags file %s: %v", *reservedTagsFile, err)
+		}
+		if err := json.Unmarshal(b, &reservedTags); err != nil {
+			log.Exitf("Error: could not parse reserved tags file %s: %v", *reservedTagsFile, err)
+		}
+	}
+
+	// Reserve the field numbers that were recorded in the manifest of a
+	// prior generation, in addition to those that were explicitly specified.
+	if *inputManifestFile != "" {
+		f, err := os.Open(*inputManifestFile)
+		if err != nil {
+			log.Exitf("Error: could not open manifest file %s: %v", *inputManifestFile, err)
+		}
+		m, err := ygen.ReadProtoManifest(f)
+		f.Close()
+		if err != nil {
+			log.Exitf("Error: could not parse manifest file %s: %v", *inputManifestFile, err)
+		}
+		if reservedTags == nil {
+			reservedTags = map[string][]uint32{}
+		}
+		for p, t := range m.ReservedFieldTags() {
+			reservedTags[p] = append(reservedTags[p], t...)
+		}
+	}
+
+	// Load the options that are to be output for specific messages.
+	var msgOpts map[string][]string
+	if *messageOptionsFile != "" {
+		b, err := ioutil.ReadFile(*messageOptionsFile)
+		if err != nil {
+			log.Exitf("Error: could not read message options file %s: %v", *messageOptionsFile, err)
+		}
+		if err := json.Unmarshal(b, &msgOpts); err != nil {
+			log.Exitf("Error: could not parse message options file %s: %v", *messageOptionsFile, err)
+		}
+	}
+
+	// Determine how fields with a when statement should be output.
+	policies := map[string]ygen.ProtoWhenPolicy{
+		"include":  ygen.IncludeWhenNodes,
+		"exclude":  ygen.ExcludeWhenNodes,
+		"annotate": ygen.AnnotateWhenNodes,
+	}
+	wp, ok := policies[*whenPolicy]
+	if !ok {
+		log.Exitf("Error: invalid when policy %s, must be one of include, exclude or annotate", *whenPolicy)
+	}
+
+	// Determine how the cardinality of lists and leaf-lists should be output.
+	cardPolicies := map[string]ygen.ProtoCardinalityPolicy{
+		"omit":     ygen.OmitCardinality,
+		"comment":  ygen.CommentCardinality,
+		"annotate": ygen.AnnotateCardinality,
+		"both":     ygen.CommentAndAnnotateCardinality,
+	}
+	cp, ok := cardPolicies[*cardinalityPolicy]
+	if !ok {
+		log.Exitf("Error: invalid cardinality policy %s, must be one of omit, comment, annotate or both", *cardinalityPolicy)
+	}
+
+	// Determine how the zero value of each enum should be named.
+	zeroPolicies := map[string]ygen.ProtoEnumZeroValuePolicy{
+		"prefixed": ygen.PrefixedEnumZeroValue,
+		"plain":    ygen.PlainEnumZeroValue,
+	}
+	zp, ok := zeroPolicies[*enumZeroPolicy]
+	if !ok {
+		log.Exitf("Error: invalid enum zero value policy %s, must be one of prefixed or plain", *enumZeroPolicy)
+	}
+
+	// Determine how identities without derived identities should be output.
+	emptyPolicies := map[string]ygen.ProtoEmptyEnumPolicy{
+		"emit":     ygen.EmitEmptyEnums,
+		"string":   ygen.StringEmptyEnums,
+		"annotate": ygen.AnnotateEmptyEnums,
+	}
+	ep, ok := emptyPolicies[*emptyEnumPolicy]
+	if !ok {
+		log.Exitf("Error: invalid empty enum policy %s, must be one of emit, string or annotate", *emptyEnumPolicy)
+	}
+
+	// Determine the style of comments that should be output.
+	commentStyles := map[string]ygen.ProtoCommentStyle{
+		"line":  ygen.LineComments,
+		"block": ygen.BlockComments,
+	}
+	cs, ok := commentStyles[*commentStyle]
+	if !ok {
+		log.Exitf("Error: invalid comment style %s, must be one of line or block", *commentStyle)
+	}
+
+	// Determine how the package of each message should be derived.
+	packagePolicies := map[string]ygen.ProtoPackagePolicy{
+		"path":              ygen.PathPackages,
+		"uncompressed_path": ygen.UncompressedPathPackages,
+		"single":            ygen.SinglePackage,
+	}
+	pp, ok := packagePolicies[*packagePolicy]
+	if !ok {
+		log.Exitf("Error: invalid package policy %s, must be one of path, uncompressed_path or single", *packagePolicy)
+	}
+
 	// Perform the code generation.
 	cg := ygen.NewYANGCodeGenerator(&ygen.GeneratorConfig{
 		CompressOCPaths:  *compressPaths,
@@ -102,12 +279,46 @@ func main() {
 			IgnoreSubmoduleCircularDependencies: *ignoreCircDeps,
 		},
 		ProtoOptions: ygen.ProtoOpts{
-			BaseImportPath:      *baseImportPath,
-			YwrapperPath:        *ywrapperPath,
-			YextPath:            *yextPath,
-			AnnotateSchemaPaths: *annotateSchemaPaths,
-			AnnotateEnumNames:   *annotateEnumNames,
-			NestedMessages:      !*packageHierarchy,
+			BaseImportPath:           *baseImportPath,
+			YwrapperPath:             *ywrapperPath,
+			YextPath:                 *yextPath,
+			AnnotateSchemaPaths:      *annotateSchemaPaths,
+			AnnotateDescriptions:     *fieldDescriptions,
+			AnnotateEnumNames:        *annotateEnumNames,
+			NestedMessages:           !*packageHierarchy,
+			UpperSnakeCaseEnumValues: *upperSnakeEnums,
+			IdentityrefAsString:      *identityrefStrings,
+			IdentitiesFile:           *identitiesFile,
+			IntegerTypes:             intTypes,
+			SchemaPathField:          *schemaPathField,
+			ReservedFieldTags:        reservedTags,
+			MessageOptions:           msgOpts,
+			WhenPolicy:               wp,
+			Proto2:                   *proto2,
+			GoPackageBase:            *goPackageBase,
+			JavaPackageBase:          *javaPackageBase,
+			ModulePackages:           modPkgs,
+			GroupingMessageNames:     *groupingNames,
+			OrderOneofsByTag:         *orderOneofsByTag,
+			CardinalityPolicy:        cp,
+			SplitConfigState:         *splitConfigState,
+			EnumZeroValueName:        *enumZeroValueName,
+			EnumZeroValuePolicy:      zp,
+			EnumValueOffset:          *enumValueOffset,
+			EmptyEnumPolicy:          ep,
+			EscapeReservedWords:      *escapeReservedWords,
+			UniqueNameSuffix:         *uniqueNameSuffix,
+			TypedefUnionMessages:     *typedefUnions,
+			QualifyNestedListKeys:    *qualifyNestedKeys,
+			SingleKeyListsAsMaps:     *singleKeyMaps,
+			AnnotateEnumDefaults:     *enumDefaults,
+			AnnotateScalarDefaults:   *scalarDefaults,
+			LeafListSemantics:        *leafListSemantics,
+			CommentStyle:             cs,
+			PackagePolicy:            pp,
+			MaxPackageDepth:          *maxPackageDepth,
+			GoogleWrapperTypes:       *googleWrappers,
+			GenerateManifest:         *manifestFile != "",
 		},
 		ExcludeState: *excludeState,
 	})
@@ -138,4 +349,15 @@ func main() {
 		}
 		f.Sync()
 	}
+
+	if *manifestFile != "" {
+		f, err := os.Create(*manifestFile)
+		if err != nil {
+			log.Exitf("could not create manifest file %v, got error: %v", *manifestFile, err)
+		}
+		defer f.Close()
+		if err := ygen.WriteProtoManifest(f, generatedProtoCode.Manifest); err != nil {
+			log.Exitf("could not write manifest file %v, got error: %v", *manifestFile, err)
+		}
+	}
 }
@@ -0,0 +1,339 @@
+// Copyright 2018 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package testutil
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/google/go-cmp/cmp"
+	gnmipb "github.com/openconfig/gnmi/proto/gnmi"
+	"github.com/openconfig/gnmi/value"
+)
+
+// AssertUpdateEqual compares the gNMI Updates want and got, and reports an
+// error to t if they are not equal. Prior to comparison, the paths of the
+// updates are normalised such that path elements with no keys are considered
+// equal regardless of whether their key map is nil or empty. The error
+// reported renders the path and value of both updates, using PathString and
+// TypedValueString respectively.
+func AssertUpdateEqual(t testing.TB, want, got *gnmipb.Update) {
+	t.Helper()
+	if proto.Equal(canonicalUpdate(want), canonicalUpdate(got)) {
+		return
+	}
+	t.Errorf("updates not equal,\ngot:  %s\nwant: %s", updateString(got), updateString(want))
+}
+
+// AssertLeaflistEqualUnordered compares the gNMI TypedValues want and got,
+// which are both expected to contain a leaf-list value, and reports an error to
+// t if they do not contain the same set of elements. The order of the elements
+// is ignored, such that values of ordered-by system leaf-lists can be compared,
+// but the number of times each element occurs must match. The error reported
+// lists the elements that are missing from, or extra in, got, each rendered
+// using TypedValueString.
+func AssertLeaflistEqualUnordered(t testing.TB, want, got *gnmipb.TypedValue) {
+	t.Helper()
+	wl, gl := want.GetLeaflistVal(), got.GetLeaflistVal()
+	if wl == nil || gl == nil {
+		t.Errorf("values are not both leaf-lists,\ngot:  %s\nwant: %s", TypedValueString(got), TypedValueString(want))
+		return
+	}
+
+	unmatched := map[string]int{}
+	for _, e := range gl.Element {
+		unmatched[TypedValueString(e)]++
+	}
+
+	var missing, extra []string
+	for _, e := range wl.Element {
+		s := TypedValueString(e)
+		if unmatched[s] == 0 {
+			missing = append(missing, s)
+			continue
+		}
+		unmatched[s]--
+	}
+	for _, e := range gl.Element {
+		s := TypedValueString(e)
+		if unmatched[s] == 0 {
+			continue
+		}
+		unmatched[s]--
+		extra = append(extra, s)
+	}
+
+	if missing == nil && extra == nil {
+		return
+	}
+	t.Errorf("leaf-list values not equal,\nmissing: [%s]\nextra:   [%s]", strings.Join(missing, ", "), strings.Join(extra, ", "))
+}
+
+// AssertTimestampsMonotonic checks that the timestamps of the gNMI
+// Notifications in n are non-decreasing, as is expected of the notifications
+// received from a telemetry stream, and reports an error to t if they are not.
+// Only the first notification whose timestamp is lower than that of its
+// predecessor is reported.
+func AssertTimestampsMonotonic(t testing.TB, n []*gnmipb.Notification) {
+	t.Helper()
+	for i := 1; i < len(n); i++ {
+		if prev, cur := n[i-1].GetTimestamp(), n[i].GetTimestamp(); cur < prev {
+			t.Errorf("notification timestamps are not monotonic, notification %d has timestamp %d, notification %d has timestamp %d", i-1, prev, i, cur)
+			return
+		}
+	}
+}
+
+// AssertAllUnderPrefix checks that the absolute path of each update and delete
+// within the gNMI Notification n, formed by joining its path to the prefix of
+// n, is within the subtree of the path prefix, as determined by PathIsPrefix.
+// It reports an error to t listing the paths that are not under the prefix,
+// such that a handler that is expected to modify only a subtree of the data
+// can be checked.
+func AssertAllUnderPrefix(t testing.TB, n *gnmipb.Notification, prefix *gnmipb.Path) {
+	t.Helper()
+	var outside []string
+	for _, u := range n.GetUpdate() {
+		if p := joinPaths(n.GetPrefix(), u.GetPath()); !PathIsPrefix(prefix, p) {
+			outside = append(outside, PathString(p))
+		}
+	}
+	for _, d := range n.GetDelete() {
+		if p := joinPaths(n.GetPrefix(), d); !PathIsPrefix(prefix, p) {
+			outside = append(outside, fmt.Sprintf("%s (deleted)", PathString(p)))
+		}
+	}
+
+	if outside == nil {
+		return
+	}
+	t.Errorf("notification contains paths that are not under prefix %s: [%s]", PathString(prefix), strings.Join(outside, ", "))
+}
+
+// AssertOnlyExpectedPaths checks that the absolute path of each update and
+// delete within the gNMI Notification n, formed by joining its path to the
+// prefix of n, is one of the expected paths. Paths are considered to match
+// where neither is less than the other, as determined by PathLess. It reports
+// an error to t listing the paths that are not expected, such that a handler
+// can be restricted to modifying a known set of paths.
+func AssertOnlyExpectedPaths(t testing.TB, n *gnmipb.Notification, expected []*gnmipb.Path) {
+	t.Helper()
+	isExpected := func(p *gnmipb.Path) bool {
+		for _, e := range expected {
+			if !PathLess(p, e) && !PathLess(e, p) {
+				return true
+			}
+		}
+		return false
+	}
+
+	var unexpected []string
+	for _, u := range n.GetUpdate() {
+		if p := joinPaths(n.GetPrefix(), u.GetPath()); !isExpected(p) {
+			unexpected = append(unexpected, PathString(p))
+		}
+	}
+	for _, d := range n.GetDelete() {
+		if p := joinPaths(n.GetPrefix(), d); !isExpected(p) {
+			unexpected = append(unexpected, fmt.Sprintf("%s (deleted)", PathString(p)))
+		}
+	}
+
+	if unexpected == nil {
+		return
+	}
+	t.Errorf("notification contains unexpected paths: [%s]", strings.Join(unexpected, ", "))
+}
+
+// AssertScalarValue converts the gNMI TypedValue tv to the corresponding Go
+// value using value.ToScalar, and reports an error to t if it is not equal to
+// want, as determined by reflect.DeepEqual. Since the types of the values are
+// compared, want must be of the type returned by value.ToScalar - e.g., an
+// int64 for an IntVal, a uint64 for a UintVal, and a []interface{} for a
+// LeaflistVal. The error reported renders both values along with their Go
+// types. An error is also reported if tv cannot be converted to a scalar value.
+func AssertScalarValue(t testing.TB, tv *gnmipb.TypedValue, want interface{}) {
+	t.Helper()
+	got, err := value.ToScalar(tv)
+	if err != nil {
+		t.Errorf("cannot convert value %s to a scalar: %v", TypedValueString(tv), err)
+		return
+	}
+	if reflect.DeepEqual(got, want) {
+		return
+	}
+	t.Errorf("scalar values not equal,\ngot:  %v (%T)\nwant: %v (%T)", got, got, want, want)
+}
+
+// AssertEqualIgnoringTimestamp compares the gNMI Notifications want and got,
+// ignoring their timestamps, and reports an error to t if they are not equal,
+// such that the contents of notifications received at different times can be
+// compared. The order of the updates and deletes within the notifications is
+// ignored, as per NotificationComparer. The error reported contains the diff
+// between the notifications, as returned by NotificationSetDiff.
+func AssertEqualIgnoringTimestamp(t testing.TB, want, got *gnmipb.Notification) {
+	t.Helper()
+	want, got = withoutTimestamp(want), withoutTimestamp(got)
+	if cmp.Equal(got, want, NotificationComparer()) {
+		return
+	}
+	diff := NotificationSetDiff([]*gnmipb.Notification{got}, []*gnmipb.Notification{want})
+	t.Errorf("notifications not equal ignoring timestamps, diff(-got,+want):\n%s", diff)
+}
+
+// AssertDeterministicGeneration calls the code generation function genFn the
+// specified number of runs, and reports an error to t if the output of any
+// run differs from that of the first, such that generators that depend on the
+// iteration order of maps can be detected. The error reported contains a
+// unified diff between the output of the first run that differs and that of
+// the first run. If runs is less than two, genFn is called twice. If genFn
+// returns an error, it is reported and no further runs are made.
+func AssertDeterministicGeneration(t testing.TB, genFn func() (string, error), runs int) {
+	t.Helper()
+	if runs < 2 {
+		runs = 2
+	}
+
+	want, err := genFn()
+	if err != nil {
+		t.Errorf("generation run 0 returned an error: %v", err)
+		return
+	}
+	for i := 1; i < runs; i++ {
+		got, err := genFn()
+		if err != nil {
+			t.Errorf("generation run %d returned an error: %v", i, err)
+			return
+		}
+		if got == want {
+			continue
+		}
+		diff, err := GenerateUnifiedDiff(got, want)
+		if err != nil {
+			diff = fmt.Sprintf("could not generate diff: %v", err)
+		}
+		t.Errorf("output of generation run %d differs from run 0, diff(-got,+want):\n%s", i, diff)
+		return
+	}
+}
+
+// canonicalUpdate returns a copy of the gNMI Update u with its path in
+// canonical form, as described by canonicalPath.
+func canonicalUpdate(u *gnmipb.Update) *gnmipb.Update {
+	if u == nil {
+		return nil
+	}
+	c := proto.Clone(u).(*gnmipb.Update)
+	canonicalPath(c.Path)
+	return c
+}
+
+// withoutTimestamp returns a copy of the gNMI Notification n with its timestamp
+// set to zero.
+func withoutTimestamp(n *gnmipb.Notification) *gnmipb.Notification {
+	if n == nil {
+		return nil
+	}
+	c := proto.Clone(n).(*gnmipb.Notification)
+	c.Timestamp = 0
+	return c
+}
+
+// updateString returns a human-readable representation of the gNMI Update u.
+func updateString(u *gnmipb.Update) string {
+	if u == nil {
+		return "<nil>"
+	}
+	s := fmt.Sprintf("%s = %s", PathString(u.Path), TypedValueString(u.Val))
+	if u.Duplicates != 0 {
+		s = fmt.Sprintf("%s (duplicates: %d)", s, u.Duplicates)
+	}
+	return s
+}
+
+// PathString returns a human-readable representation of the gNMI Path p, in
+// the form /elem/list[key=value]. The keys of each path element are sorted
+// such that the output is deterministic, and are escaped as described by
+// KeyString, such that the keys of each element can be distinguished. If the
+// path specifies an origin, it
+// is prepended to the output, separated by a colon. Paths that use only the
+// deprecated element field are rendered using its contents.
+func PathString(p *gnmipb.Path) string {
+	if p == nil {
+		return "<nil>"
+	}
+
+	var b bytes.Buffer
+	if p.Origin != "" {
+		fmt.Fprintf(&b, "%s:", p.Origin)
+	}
+
+	if len(p.Elem) == 0 && len(p.Element) != 0 {
+		b.WriteString("/")
+		b.WriteString(strings.Join(p.Element, "/"))
+		return b.String()
+	}
+
+	if len(p.Elem) == 0 {
+		b.WriteString("/")
+		return b.String()
+	}
+
+	for _, e := range p.Elem {
+		fmt.Fprintf(&b, "/%s", e.Name)
+		for _, kv := range keyValueStrings(e.Key) {
+			fmt.Fprintf(&b, "[%s]", kv)
+		}
+	}
+	return b.String()
+}
+
+// KeyString returns a deterministic representation of the keys of a gNMI
+// PathElem, in the form k1=v1,k2=v2, with the keys sorted by name. Backslash,
+// comma, equals and closing square bracket characters within the names and
+// values of the keys are escaped with a backslash, such that maps with
+// different keys result in different strings. An empty string is returned if
+// there are no keys.
+func KeyString(keys map[string]string) string {
+	return strings.Join(keyValueStrings(keys), ",")
+}
+
+// keyEscaper escapes the characters that delimit the keys of a PathElem in the
+// output of KeyString and PathString.
+var keyEscaper = strings.NewReplacer(`\`, `\\`, `,`, `\,`, `=`, `\=`, `]`, `\]`)
+
+// keyValueStrings returns the keys of a gNMI PathElem, sorted by name, each in
+// the form k=v, with the names and values escaped by keyEscaper.
+func keyValueStrings(keys map[string]string) []string {
+	kv := make([]string, 0, len(keys))
+	for _, k := range sortedKeys(keys) {
+		kv = append(kv, fmt.Sprintf("%s=%s", keyEscaper.Replace(k), keyEscaper.Replace(keys[k])))
+	}
+	return kv
+}
+
+// TypedValueString returns a human-readable representation of the gNMI
+// TypedValue v, which includes both the type of the value, and its contents,
+// e.g., string_val:"foo".
+func TypedValueString(v *gnmipb.TypedValue) string {
+	if v == nil {
+		return "<nil>"
+	}
+	return strings.TrimSpace(proto.CompactTextString(v))
+}
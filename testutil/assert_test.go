@@ -0,0 +1,708 @@
+// Copyright 2018 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package testutil
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	gnmipb "github.com/openconfig/gnmi/proto/gnmi"
+)
+
+// recordingTB is a testing.TB that records the errors that are reported to it
+// rather than failing the test.
+type recordingTB struct {
+	testing.TB
+	errs []string
+}
+
+// Helper implements the testing.TB interface.
+func (r *recordingTB) Helper() {}
+
+// Errorf implements the testing.TB interface, recording the error reported.
+func (r *recordingTB) Errorf(format string, args ...interface{}) {
+	r.errs = append(r.errs, fmt.Sprintf(format, args...))
+}
+
+func TestAssertUpdateEqual(t *testing.T) {
+	tests := []struct {
+		name    string
+		inWant  *gnmipb.Update
+		inGot   *gnmipb.Update
+		wantErr string
+	}{{
+		name: "equal updates",
+		inWant: &gnmipb.Update{
+			Path: mustPath("a", "b"),
+			Val:  &gnmipb.TypedValue{Value: &gnmipb.TypedValue_StringVal{StringVal: "foo"}},
+		},
+		inGot: &gnmipb.Update{
+			Path: mustPath("a", "b"),
+			Val:  &gnmipb.TypedValue{Value: &gnmipb.TypedValue_StringVal{StringVal: "foo"}},
+		},
+	}, {
+		name: "equal updates with empty and nil keys",
+		inWant: &gnmipb.Update{
+			Path: &gnmipb.Path{Elem: []*gnmipb.PathElem{{Name: "a", Key: map[string]string{}}}},
+			Val:  &gnmipb.TypedValue{Value: &gnmipb.TypedValue_IntVal{IntVal: 42}},
+		},
+		inGot: &gnmipb.Update{
+			Path: mustPath("a"),
+			Val:  &gnmipb.TypedValue{Value: &gnmipb.TypedValue_IntVal{IntVal: 42}},
+		},
+	}, {
+		name: "different values",
+		inWant: &gnmipb.Update{
+			Path: mustPath("a", "b"),
+			Val:  &gnmipb.TypedValue{Value: &gnmipb.TypedValue_StringVal{StringVal: "foo"}},
+		},
+		inGot: &gnmipb.Update{
+			Path: mustPath("a", "b"),
+			Val:  &gnmipb.TypedValue{Value: &gnmipb.TypedValue_UintVal{UintVal: 42}},
+		},
+		wantErr: "updates not equal,\ngot:  /a/b = uint_val:42\nwant: /a/b = string_val:\"foo\"",
+	}, {
+		name: "different paths with keys",
+		inWant: &gnmipb.Update{
+			Path: &gnmipb.Path{Elem: []*gnmipb.PathElem{{Name: "list", Key: map[string]string{"k2": "v2", "k1": "v1"}}}},
+			Val:  &gnmipb.TypedValue{Value: &gnmipb.TypedValue_BoolVal{BoolVal: true}},
+		},
+		inGot: &gnmipb.Update{
+			Path: &gnmipb.Path{Elem: []*gnmipb.PathElem{{Name: "list", Key: map[string]string{"k1": "v3"}}}},
+			Val:  &gnmipb.TypedValue{Value: &gnmipb.TypedValue_BoolVal{BoolVal: true}},
+		},
+		wantErr: "updates not equal,\ngot:  /list[k1=v3] = bool_val:true\nwant: /list[k1=v1][k2=v2] = bool_val:true",
+	}, {
+		name: "nil got update",
+		inWant: &gnmipb.Update{
+			Path: mustPath("a"),
+		},
+		wantErr: "updates not equal,\ngot:  <nil>\nwant: /a = <nil>",
+	}}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &recordingTB{}
+			AssertUpdateEqual(r, tt.inWant, tt.inGot)
+
+			if tt.wantErr == "" {
+				if len(r.errs) != 0 {
+					t.Fatalf("AssertUpdateEqual(%v, %v): got unexpected errors: %v", tt.inWant, tt.inGot, r.errs)
+				}
+				return
+			}
+
+			if len(r.errs) != 1 {
+				t.Fatalf("AssertUpdateEqual(%v, %v): did not get expected number of errors, got: %v, want: 1", tt.inWant, tt.inGot, r.errs)
+			}
+
+			if got := r.errs[0]; got != tt.wantErr {
+				t.Fatalf("AssertUpdateEqual(%v, %v): did not get expected error message, got:\n%s\nwant:\n%s", tt.inWant, tt.inGot, got, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestAssertLeaflistEqualUnordered(t *testing.T) {
+	leaflist := func(vals ...string) *gnmipb.TypedValue {
+		l := &gnmipb.ScalarArray{}
+		for _, v := range vals {
+			l.Element = append(l.Element, &gnmipb.TypedValue{Value: &gnmipb.TypedValue_StringVal{StringVal: v}})
+		}
+		return &gnmipb.TypedValue{Value: &gnmipb.TypedValue_LeaflistVal{LeaflistVal: l}}
+	}
+
+	tests := []struct {
+		name    string
+		inWant  *gnmipb.TypedValue
+		inGot   *gnmipb.TypedValue
+		wantErr string
+	}{{
+		name:   "equal leaf-lists",
+		inWant: leaflist("a", "b", "c"),
+		inGot:  leaflist("a", "b", "c"),
+	}, {
+		name:   "reordered leaf-lists",
+		inWant: leaflist("a", "b", "c", "b"),
+		inGot:  leaflist("b", "c", "b", "a"),
+	}, {
+		name:    "differing elements",
+		inWant:  leaflist("a", "b", "c"),
+		inGot:   leaflist("c", "d", "a"),
+		wantErr: "leaf-list values not equal,\nmissing: [string_val:\"b\"]\nextra:   [string_val:\"d\"]",
+	}, {
+		name:    "differing number of occurrences",
+		inWant:  leaflist("a", "b"),
+		inGot:   leaflist("a", "b", "b"),
+		wantErr: "leaf-list values not equal,\nmissing: []\nextra:   [string_val:\"b\"]",
+	}, {
+		name:    "not a leaf-list",
+		inWant:  leaflist("a"),
+		inGot:   &gnmipb.TypedValue{Value: &gnmipb.TypedValue_StringVal{StringVal: "a"}},
+		wantErr: "values are not both leaf-lists,\ngot:  string_val:\"a\"\nwant: leaflist_val:<element:<string_val:\"a\" > >",
+	}}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &recordingTB{}
+			AssertLeaflistEqualUnordered(r, tt.inWant, tt.inGot)
+
+			if tt.wantErr == "" {
+				if len(r.errs) != 0 {
+					t.Fatalf("AssertLeaflistEqualUnordered(%v, %v): got unexpected errors: %v", tt.inWant, tt.inGot, r.errs)
+				}
+				return
+			}
+
+			if len(r.errs) != 1 {
+				t.Fatalf("AssertLeaflistEqualUnordered(%v, %v): did not get expected number of errors, got: %v, want: 1", tt.inWant, tt.inGot, r.errs)
+			}
+
+			if got := r.errs[0]; got != tt.wantErr {
+				t.Fatalf("AssertLeaflistEqualUnordered(%v, %v): did not get expected error message, got:\n%s\nwant:\n%s", tt.inWant, tt.inGot, got, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestAssertTimestampsMonotonic(t *testing.T) {
+	notifications := func(ts ...int64) []*gnmipb.Notification {
+		var n []*gnmipb.Notification
+		for _, t := range ts {
+			n = append(n, &gnmipb.Notification{Timestamp: t})
+		}
+		return n
+	}
+
+	tests := []struct {
+		name    string
+		in      []*gnmipb.Notification
+		wantErr string
+	}{{
+		name: "no notifications",
+	}, {
+		name: "single notification",
+		in:   notifications(42),
+	}, {
+		name: "increasing timestamps",
+		in:   notifications(1, 2, 3),
+	}, {
+		name: "equal timestamps",
+		in:   notifications(1, 2, 2, 3),
+	}, {
+		name:    "decreasing timestamp",
+		in:      notifications(1, 3, 2, 4),
+		wantErr: "notification timestamps are not monotonic, notification 1 has timestamp 3, notification 2 has timestamp 2",
+	}, {
+		name:    "multiple decreasing timestamps reports first",
+		in:      notifications(5, 4, 3),
+		wantErr: "notification timestamps are not monotonic, notification 0 has timestamp 5, notification 1 has timestamp 4",
+	}, {
+		name:    "nil notification is treated as zero timestamp",
+		in:      []*gnmipb.Notification{{Timestamp: 1}, nil},
+		wantErr: "notification timestamps are not monotonic, notification 0 has timestamp 1, notification 1 has timestamp 0",
+	}}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &recordingTB{}
+			AssertTimestampsMonotonic(r, tt.in)
+
+			if tt.wantErr == "" {
+				if len(r.errs) != 0 {
+					t.Fatalf("AssertTimestampsMonotonic(%v): got unexpected errors: %v", tt.in, r.errs)
+				}
+				return
+			}
+
+			if len(r.errs) != 1 {
+				t.Fatalf("AssertTimestampsMonotonic(%v): did not get expected number of errors, got: %v, want: 1", tt.in, r.errs)
+			}
+
+			if got := r.errs[0]; got != tt.wantErr {
+				t.Fatalf("AssertTimestampsMonotonic(%v): did not get expected error message, got:\n%s\nwant:\n%s", tt.in, got, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestAssertAllUnderPrefix(t *testing.T) {
+	intf := func(name string, leaf ...string) *gnmipb.Path {
+		p := &gnmipb.Path{Elem: []*gnmipb.PathElem{
+			{Name: "interfaces"},
+			{Name: "interface", Key: map[string]string{"name": name}},
+		}}
+		for _, l := range leaf {
+			p.Elem = append(p.Elem, &gnmipb.PathElem{Name: l})
+		}
+		return p
+	}
+
+	tests := []struct {
+		name     string
+		inNotif  *gnmipb.Notification
+		inPrefix *gnmipb.Path
+		wantErr  string
+	}{{
+		name:     "empty notification",
+		inNotif:  &gnmipb.Notification{},
+		inPrefix: intf("eth0"),
+	}, {
+		name: "all paths within prefix",
+		inNotif: &gnmipb.Notification{
+			Update: []*gnmipb.Update{{Path: intf("eth0", "config", "mtu")}, {Path: intf("eth0")}},
+			Delete: []*gnmipb.Path{intf("eth0", "config", "description")},
+		},
+		inPrefix: intf("eth0"),
+	}, {
+		name: "paths within prefix after joining notification prefix",
+		inNotif: &gnmipb.Notification{
+			Prefix: intf("eth0"),
+			Update: []*gnmipb.Update{{Path: mustPath("config", "mtu")}},
+			Delete: []*gnmipb.Path{mustPath("state")},
+		},
+		inPrefix: intf("eth0", "config"),
+		wantErr:  "notification contains paths that are not under prefix /interfaces/interface[name=eth0]/config: [/interfaces/interface[name=eth0]/state (deleted)]",
+	}, {
+		name: "update outside of prefix",
+		inNotif: &gnmipb.Notification{
+			Update: []*gnmipb.Update{
+				{Path: intf("eth0", "config", "mtu")},
+				{Path: intf("eth1", "config", "mtu")},
+				{Path: mustPath("system", "config", "hostname")},
+			},
+		},
+		inPrefix: intf("eth0"),
+		wantErr:  "notification contains paths that are not under prefix /interfaces/interface[name=eth0]: [/interfaces/interface[name=eth1]/config/mtu, /system/config/hostname]",
+	}, {
+		name: "wildcard prefix key",
+		inNotif: &gnmipb.Notification{
+			Update: []*gnmipb.Update{{Path: intf("eth0", "config", "mtu")}, {Path: intf("eth1", "config", "mtu")}},
+		},
+		inPrefix: intf("*"),
+	}}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &recordingTB{}
+			AssertAllUnderPrefix(r, tt.inNotif, tt.inPrefix)
+
+			if tt.wantErr == "" {
+				if len(r.errs) != 0 {
+					t.Fatalf("AssertAllUnderPrefix(%v, %v): got unexpected errors: %v", tt.inNotif, tt.inPrefix, r.errs)
+				}
+				return
+			}
+
+			if len(r.errs) != 1 {
+				t.Fatalf("AssertAllUnderPrefix(%v, %v): did not get expected number of errors, got: %v, want: 1", tt.inNotif, tt.inPrefix, r.errs)
+			}
+
+			if got := r.errs[0]; got != tt.wantErr {
+				t.Fatalf("AssertAllUnderPrefix(%v, %v): did not get expected error message, got:\n%s\nwant:\n%s", tt.inNotif, tt.inPrefix, got, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestAssertOnlyExpectedPaths(t *testing.T) {
+	tests := []struct {
+		name       string
+		inNotif    *gnmipb.Notification
+		inExpected []*gnmipb.Path
+		wantErr    string
+	}{{
+		name:       "empty notification",
+		inNotif:    &gnmipb.Notification{},
+		inExpected: []*gnmipb.Path{mustPath("a")},
+	}, {
+		name: "all paths expected",
+		inNotif: &gnmipb.Notification{
+			Update: []*gnmipb.Update{{Path: mustPath("a", "b")}},
+			Delete: []*gnmipb.Path{mustPath("a", "c")},
+		},
+		inExpected: []*gnmipb.Path{mustPath("a", "c"), mustPath("a", "b"), mustPath("a", "d")},
+	}, {
+		name: "paths expected after joining notification prefix",
+		inNotif: &gnmipb.Notification{
+			Prefix: &gnmipb.Path{Elem: []*gnmipb.PathElem{{Name: "list", Key: map[string]string{"key": "one"}}}},
+			Update: []*gnmipb.Update{{Path: mustPath("config", "key")}},
+		},
+		inExpected: []*gnmipb.Path{{
+			Elem: []*gnmipb.PathElem{{Name: "list", Key: map[string]string{"key": "one"}}, {Name: "config"}, {Name: "key"}},
+		}},
+	}, {
+		name: "unexpected update and delete",
+		inNotif: &gnmipb.Notification{
+			Update: []*gnmipb.Update{{Path: mustPath("a", "b")}, {Path: mustPath("a", "b", "c")}},
+			Delete: []*gnmipb.Path{mustPath("a")},
+		},
+		inExpected: []*gnmipb.Path{mustPath("a", "b")},
+		wantErr:    "notification contains unexpected paths: [/a/b/c, /a (deleted)]",
+	}, {
+		name: "path with differing key value",
+		inNotif: &gnmipb.Notification{
+			Update: []*gnmipb.Update{{Path: &gnmipb.Path{Elem: []*gnmipb.PathElem{{Name: "list", Key: map[string]string{"key": "two"}}}}}},
+		},
+		inExpected: []*gnmipb.Path{{Elem: []*gnmipb.PathElem{{Name: "list", Key: map[string]string{"key": "one"}}}}},
+		wantErr:    "notification contains unexpected paths: [/list[key=two]]",
+	}, {
+		name: "no expected paths",
+		inNotif: &gnmipb.Notification{
+			Update: []*gnmipb.Update{{Path: mustPath("a")}},
+		},
+		wantErr: "notification contains unexpected paths: [/a]",
+	}}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &recordingTB{}
+			AssertOnlyExpectedPaths(r, tt.inNotif, tt.inExpected)
+
+			if tt.wantErr == "" {
+				if len(r.errs) != 0 {
+					t.Fatalf("AssertOnlyExpectedPaths(%v, %v): got unexpected errors: %v", tt.inNotif, tt.inExpected, r.errs)
+				}
+				return
+			}
+
+			if len(r.errs) != 1 {
+				t.Fatalf("AssertOnlyExpectedPaths(%v, %v): did not get expected number of errors, got: %v, want: 1", tt.inNotif, tt.inExpected, r.errs)
+			}
+
+			if got := r.errs[0]; got != tt.wantErr {
+				t.Fatalf("AssertOnlyExpectedPaths(%v, %v): did not get expected error message, got:\n%s\nwant:\n%s", tt.inNotif, tt.inExpected, got, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestAssertScalarValue(t *testing.T) {
+	tests := []struct {
+		name    string
+		inTV    *gnmipb.TypedValue
+		inWant  interface{}
+		wantErr string
+	}{{
+		name:   "equal string",
+		inTV:   &gnmipb.TypedValue{Value: &gnmipb.TypedValue_StringVal{StringVal: "foo"}},
+		inWant: "foo",
+	}, {
+		name:    "differing string",
+		inTV:    &gnmipb.TypedValue{Value: &gnmipb.TypedValue_StringVal{StringVal: "foo"}},
+		inWant:  "bar",
+		wantErr: "scalar values not equal,\ngot:  foo (string)\nwant: bar (string)",
+	}, {
+		name:   "equal uint",
+		inTV:   &gnmipb.TypedValue{Value: &gnmipb.TypedValue_UintVal{UintVal: 42}},
+		inWant: uint64(42),
+	}, {
+		name:    "differing uint",
+		inTV:    &gnmipb.TypedValue{Value: &gnmipb.TypedValue_UintVal{UintVal: 42}},
+		inWant:  uint64(43),
+		wantErr: "scalar values not equal,\ngot:  42 (uint64)\nwant: 43 (uint64)",
+	}, {
+		name:    "uint compared to int",
+		inTV:    &gnmipb.TypedValue{Value: &gnmipb.TypedValue_UintVal{UintVal: 42}},
+		inWant:  42,
+		wantErr: "scalar values not equal,\ngot:  42 (uint64)\nwant: 42 (int)",
+	}, {
+		name:   "equal bool",
+		inTV:   &gnmipb.TypedValue{Value: &gnmipb.TypedValue_BoolVal{BoolVal: true}},
+		inWant: true,
+	}, {
+		name:    "differing bool",
+		inTV:    &gnmipb.TypedValue{Value: &gnmipb.TypedValue_BoolVal{BoolVal: false}},
+		inWant:  true,
+		wantErr: "scalar values not equal,\ngot:  false (bool)\nwant: true (bool)",
+	}, {
+		name: "equal leaf-list",
+		inTV: &gnmipb.TypedValue{Value: &gnmipb.TypedValue_LeaflistVal{LeaflistVal: &gnmipb.ScalarArray{
+			Element: []*gnmipb.TypedValue{{Value: &gnmipb.TypedValue_StringVal{StringVal: "a"}}},
+		}}},
+		inWant: []interface{}{"a"},
+	}, {
+		name:    "non-scalar value",
+		inTV:    &gnmipb.TypedValue{Value: &gnmipb.TypedValue_JsonVal{JsonVal: []byte(`{"a":1}`)}},
+		inWant:  "a",
+		wantErr: "cannot convert value",
+	}}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &recordingTB{}
+			AssertScalarValue(r, tt.inTV, tt.inWant)
+
+			if tt.wantErr == "" {
+				if len(r.errs) != 0 {
+					t.Fatalf("AssertScalarValue(%v, %v): got unexpected errors: %v", tt.inTV, tt.inWant, r.errs)
+				}
+				return
+			}
+
+			if len(r.errs) != 1 {
+				t.Fatalf("AssertScalarValue(%v, %v): did not get expected number of errors, got: %v, want: 1", tt.inTV, tt.inWant, r.errs)
+			}
+			if !strings.HasPrefix(r.errs[0], tt.wantErr) {
+				t.Fatalf("AssertScalarValue(%v, %v): did not get expected error, got: %q, want prefix: %q", tt.inTV, tt.inWant, r.errs[0], tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestAssertEqualIgnoringTimestamp(t *testing.T) {
+	strUpd := func(path, val string) *gnmipb.Update {
+		return &gnmipb.Update{
+			Path: mustPath(path),
+			Val:  &gnmipb.TypedValue{Value: &gnmipb.TypedValue_StringVal{StringVal: val}},
+		}
+	}
+
+	tests := []struct {
+		name    string
+		inWant  *gnmipb.Notification
+		inGot   *gnmipb.Notification
+		wantErr string
+	}{{
+		name: "equal notifications",
+		inWant: &gnmipb.Notification{
+			Timestamp: 42,
+			Update:    []*gnmipb.Update{strUpd("a", "one")},
+		},
+		inGot: &gnmipb.Notification{
+			Timestamp: 42,
+			Update:    []*gnmipb.Update{strUpd("a", "one")},
+		},
+	}, {
+		name: "notifications differing only in timestamp and order",
+		inWant: &gnmipb.Notification{
+			Timestamp: 42,
+			Update:    []*gnmipb.Update{strUpd("a", "one"), strUpd("b", "two")},
+			Delete:    []*gnmipb.Path{mustPath("c")},
+		},
+		inGot: &gnmipb.Notification{
+			Timestamp: 84,
+			Update:    []*gnmipb.Update{strUpd("b", "two"), strUpd("a", "one")},
+			Delete:    []*gnmipb.Path{mustPath("c")},
+		},
+	}, {
+		name: "notifications differing in data",
+		inWant: &gnmipb.Notification{
+			Timestamp: 42,
+			Update:    []*gnmipb.Update{strUpd("a", "one")},
+		},
+		inGot: &gnmipb.Notification{
+			Timestamp: 84,
+			Update:    []*gnmipb.Update{strUpd("a", "two")},
+		},
+		wantErr: "notifications not equal ignoring timestamps, diff(-got,+want):",
+	}, {
+		name: "notifications differing in prefix",
+		inWant: &gnmipb.Notification{
+			Prefix: mustPath("a"),
+			Update: []*gnmipb.Update{strUpd("b", "one")},
+		},
+		inGot: &gnmipb.Notification{
+			Update: []*gnmipb.Update{strUpd("b", "one")},
+		},
+		wantErr: "notifications not equal ignoring timestamps, diff(-got,+want):",
+	}}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &recordingTB{}
+			AssertEqualIgnoringTimestamp(r, tt.inWant, tt.inGot)
+
+			if tt.wantErr == "" {
+				if len(r.errs) != 0 {
+					t.Fatalf("AssertEqualIgnoringTimestamp(%v, %v): got unexpected errors: %v", tt.inWant, tt.inGot, r.errs)
+				}
+				return
+			}
+
+			if len(r.errs) != 1 {
+				t.Fatalf("AssertEqualIgnoringTimestamp(%v, %v): did not get expected number of errors, got: %v, want: 1", tt.inWant, tt.inGot, r.errs)
+			}
+			if !strings.HasPrefix(r.errs[0], tt.wantErr) {
+				t.Fatalf("AssertEqualIgnoringTimestamp(%v, %v): did not get expected error, got: %q, want prefix: %q", tt.inWant, tt.inGot, r.errs[0], tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestAssertDeterministicGeneration(t *testing.T) {
+	// outputs returns a generation function that returns each of the outputs
+	// in turn, along with a pointer to the number of times it was called.
+	outputs := func(out ...string) (func() (string, error), *int) {
+		calls := 0
+		return func() (string, error) {
+			o := out[calls%len(out)]
+			calls++
+			if o == "error" {
+				return "", fmt.Errorf("generation failed")
+			}
+			return o, nil
+		}, &calls
+	}
+
+	tests := []struct {
+		name          string
+		inOutputs     []string
+		inRuns        int
+		wantCalls     int
+		wantErrSubstr string
+	}{{
+		name:      "deterministic output",
+		inOutputs: []string{"message A {}\n"},
+		inRuns:    5,
+		wantCalls: 5,
+	}, {
+		name:      "fewer than two runs",
+		inOutputs: []string{"message A {}\n"},
+		inRuns:    0,
+		wantCalls: 2,
+	}, {
+		name:          "output differs on a later run",
+		inOutputs:     []string{"message A {}\n", "message A {}\n", "message B {}\n"},
+		inRuns:        5,
+		wantCalls:     3,
+		wantErrSubstr: "output of generation run 2 differs from run 0, diff(-got,+want):\n--- got\n+++ want\n@@ -1,2 +1,2 @@\n-message B {}\n+message A {}\n",
+	}, {
+		name:          "generation error",
+		inOutputs:     []string{"message A {}\n", "error"},
+		inRuns:        5,
+		wantCalls:     2,
+		wantErrSubstr: "generation run 1 returned an error: generation failed",
+	}}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &recordingTB{}
+			genFn, calls := outputs(tt.inOutputs...)
+			AssertDeterministicGeneration(r, genFn, tt.inRuns)
+
+			if *calls != tt.wantCalls {
+				t.Errorf("AssertDeterministicGeneration(%v, %d): did not get expected number of calls, got: %d, want: %d", tt.inOutputs, tt.inRuns, *calls, tt.wantCalls)
+			}
+
+			if tt.wantErrSubstr == "" {
+				if len(r.errs) != 0 {
+					t.Fatalf("AssertDeterministicGeneration(%v, %d): got unexpected errors: %v", tt.inOutputs, tt.inRuns, r.errs)
+				}
+				return
+			}
+
+			if len(r.errs) != 1 {
+				t.Fatalf("AssertDeterministicGeneration(%v, %d): did not get expected number of errors, got: %v, want: 1", tt.inOutputs, tt.inRuns, r.errs)
+			}
+
+			if got := r.errs[0]; !strings.Contains(got, tt.wantErrSubstr) {
+				t.Fatalf("AssertDeterministicGeneration(%v, %d): did not get expected error message, got:\n%s\nwant substring:\n%s", tt.inOutputs, tt.inRuns, got, tt.wantErrSubstr)
+			}
+		})
+	}
+}
+
+func TestPathString(t *testing.T) {
+	tests := []struct {
+		name string
+		in   *gnmipb.Path
+		want string
+	}{{
+		name: "nil path",
+		want: "<nil>",
+	}, {
+		name: "empty path",
+		in:   &gnmipb.Path{},
+		want: "/",
+	}, {
+		name: "path with keys and origin",
+		in: &gnmipb.Path{
+			Origin: "openconfig",
+			Elem: []*gnmipb.PathElem{{
+				Name: "interfaces",
+			}, {
+				Name: "interface",
+				Key:  map[string]string{"name": "eth0"},
+			}},
+		},
+		want: "openconfig:/interfaces/interface[name=eth0]",
+	}, {
+		name: "path with multiple keys requiring escaping",
+		in: &gnmipb.Path{
+			Elem: []*gnmipb.PathElem{{
+				Name: "list",
+				Key:  map[string]string{"b": `x]y`, "a": `1=2\3`},
+			}},
+		},
+		want: `/list[a=1\=2\\3][b=x\]y]`,
+	}, {
+		name: "path using element",
+		in:   &gnmipb.Path{Element: []string{"a", "b"}},
+		want: "/a/b",
+	}}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := PathString(tt.in); got != tt.want {
+				t.Fatalf("PathString(%v): did not get expected string, got: %s, want: %s", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestKeyString(t *testing.T) {
+	tests := []struct {
+		name string
+		in   map[string]string
+		want string
+	}{{
+		name: "nil keys",
+		want: "",
+	}, {
+		name: "empty keys",
+		in:   map[string]string{},
+		want: "",
+	}, {
+		name: "single key",
+		in:   map[string]string{"name": "eth0"},
+		want: "name=eth0",
+	}, {
+		name: "multiple keys",
+		in:   map[string]string{"subinterface": "0", "name": "eth0", "address": "192.0.2.1"},
+		want: "address=192.0.2.1,name=eth0,subinterface=0",
+	}, {
+		name: "empty value",
+		in:   map[string]string{"b": "", "a": "1"},
+		want: "a=1,b=",
+	}, {
+		name: "values requiring escaping",
+		in:   map[string]string{"a": "1,b=2", "c": `x\y`},
+		want: `a=1\,b\=2,c=x\\y`,
+	}, {
+		name: "name requiring escaping",
+		in:   map[string]string{"a=b": "1"},
+		want: `a\=b=1`,
+	}}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := KeyString(tt.in); got != tt.want {
+				t.Fatalf("KeyString(%v): did not get expected string, got: %s, want: %s", tt.in, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,214 @@
+// Copyright 2018 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package testutil
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"sort"
+
+	"github.com/golang/protobuf/proto"
+	gnmipb "github.com/openconfig/gnmi/proto/gnmi"
+)
+
+// CanonicalNotification returns a copy of the gNMI Notification n in a
+// canonical form, such that two notifications that differ only in the order
+// of their updates or deletes, or in whether empty path element keys are
+// specified as nil or empty maps, are equal according to proto.Equal. The
+// Update and Delete fields are sorted using UpdateLess and PathLess
+// respectively. The input notification is not modified.
+func CanonicalNotification(n *gnmipb.Notification) *gnmipb.Notification {
+	if n == nil {
+		return nil
+	}
+
+	c := proto.Clone(n).(*gnmipb.Notification)
+	canonicalPath(c.Prefix)
+	for _, u := range c.Update {
+		canonicalPath(u.GetPath())
+	}
+	for _, d := range c.Delete {
+		canonicalPath(d)
+	}
+
+	sort.Sort(updateSet(c.Update))
+	sort.Sort(pathSet(c.Delete))
+	return c
+}
+
+// canonicalPath modifies the gNMI Path p in place such that each path element
+// that has no keys has a nil key map.
+func canonicalPath(p *gnmipb.Path) {
+	if p == nil {
+		return
+	}
+	for _, e := range p.Elem {
+		if len(e.Key) == 0 {
+			e.Key = nil
+		}
+	}
+}
+
+// CanonicalizeStream reads gNMI Notifications from the in channel, and writes
+// the canonical form of each, as returned by CanonicalNotification, to the out
+// channel in the order in which they were received. Only the notification that
+// is currently being processed is held in memory, such that arbitrarily large
+// captures can be processed. CanonicalizeStream returns when the in channel is
+// closed, at which point the out channel is closed.
+func CanonicalizeStream(in <-chan *gnmipb.Notification, out chan<- *gnmipb.Notification) {
+	defer close(out)
+	for n := range in {
+		out <- CanonicalNotification(n)
+	}
+}
+
+// NormalizeOpt is an interface that is implemented by the options to the
+// NormalizeTypedValue function.
+type NormalizeOpt interface {
+	// IsNormalizeOpt is a marker method for each NormalizeOpt.
+	IsNormalizeOpt()
+}
+
+// IntegersAsUint is a NormalizeOpt that specifies that non-negative IntVal
+// values should be normalized to UintVal values, such that an integer that is
+// encoded as signed by one implementation and unsigned by another compares as
+// equal.
+type IntegersAsUint struct{}
+
+// IsNormalizeOpt marks IntegersAsUint as a normalize option.
+func (*IntegersAsUint) IsNormalizeOpt() {}
+
+// SortJSONKeys is a NormalizeOpt that specifies that JsonVal and JsonIetfVal
+// values should be re-encoded such that the keys of each JSON object are
+// sorted, such that documents that differ only in the order of their keys, as
+// may be the case for values returned by different implementations, compare
+// as equal.
+type SortJSONKeys struct{}
+
+// IsNormalizeOpt marks SortJSONKeys as a normalize option.
+func (*SortJSONKeys) IsNormalizeOpt() {}
+
+// NormalizeTypedValue returns a copy of the gNMI TypedValue tv in a canonical
+// form, such that two values that are semantically equal, but are encoded
+// differently, are equal according to proto.Equal. DecimalVal values are
+// reduced to their lowest terms, such that trailing zeros are removed from the
+// digits and the precision is reduced accordingly (e.g., 1500 with a precision
+// of 3 becomes 15 with a precision of 1). JsonVal and JsonIetfVal values have
+// insignificant whitespace removed. If the IntegersAsUint option is specified,
+// non-negative IntVal values are converted to UintVal values. If the
+// SortJSONKeys option is specified, JsonVal and JsonIetfVal values that are
+// valid JSON are re-encoded in the canonical form returned by canonicalJSON.
+//
+// The elements of a LeaflistVal are normalized individually. The input value
+// is not modified.
+func NormalizeTypedValue(tv *gnmipb.TypedValue, opts ...NormalizeOpt) *gnmipb.TypedValue {
+	if tv == nil {
+		return nil
+	}
+
+	var intsAsUint, sortJSONKeys bool
+	for _, o := range opts {
+		switch o.(type) {
+		case *IntegersAsUint:
+			intsAsUint = true
+		case *SortJSONKeys:
+			sortJSONKeys = true
+		}
+	}
+
+	c := proto.Clone(tv).(*gnmipb.TypedValue)
+	normalizeTypedValue(c, intsAsUint, sortJSONKeys)
+	return c
+}
+
+// normalizeTypedValue modifies the gNMI TypedValue tv in place such that it is
+// in the canonical form described by NormalizeTypedValue.
+func normalizeTypedValue(tv *gnmipb.TypedValue, intsAsUint, sortJSONKeys bool) {
+	switch v := tv.Value.(type) {
+	case *gnmipb.TypedValue_DecimalVal:
+		d := v.DecimalVal
+		if d == nil {
+			return
+		}
+		if d.Digits == 0 {
+			d.Precision = 0
+		}
+		for d.Precision > 0 && d.Digits%10 == 0 {
+			d.Digits /= 10
+			d.Precision--
+		}
+	case *gnmipb.TypedValue_IntVal:
+		if intsAsUint && v.IntVal >= 0 {
+			tv.Value = &gnmipb.TypedValue_UintVal{uint64(v.IntVal)}
+		}
+	case *gnmipb.TypedValue_JsonVal:
+		v.JsonVal = normalizeJSON(v.JsonVal, sortJSONKeys)
+	case *gnmipb.TypedValue_JsonIetfVal:
+		v.JsonIetfVal = normalizeJSON(v.JsonIetfVal, sortJSONKeys)
+	case *gnmipb.TypedValue_LeaflistVal:
+		for _, e := range v.LeaflistVal.GetElement() {
+			normalizeTypedValue(e, intsAsUint, sortJSONKeys)
+		}
+	}
+}
+
+// normalizeJSON returns the JSON document j with insignificant whitespace
+// removed, as per compactJSON. If sortKeys is set, and j is valid JSON, it is
+// instead returned in the canonical form returned by canonicalJSON.
+func normalizeJSON(j []byte, sortKeys bool) []byte {
+	if sortKeys {
+		if c, ok := canonicalJSON(j); ok {
+			return c
+		}
+	}
+	return compactJSON(j)
+}
+
+// canonicalJSON returns the JSON document j in a canonical form, in which
+// insignificant whitespace is removed and the keys of each object are sorted,
+// by decoding it and re-encoding the result. Numbers are re-encoded as they
+// were specified in j. It returns false if j is not valid JSON.
+func canonicalJSON(j []byte) ([]byte, bool) {
+	d := json.NewDecoder(bytes.NewReader(j))
+	d.UseNumber()
+	var v interface{}
+	if err := d.Decode(&v); err != nil {
+		return nil, false
+	}
+	// Trailing data after the first JSON value means that j is not a single
+	// valid document.
+	if _, err := d.Token(); err != io.EOF {
+		return nil, false
+	}
+	var c bytes.Buffer
+	e := json.NewEncoder(&c)
+	e.SetEscapeHTML(false)
+	if err := e.Encode(v); err != nil {
+		return nil, false
+	}
+	return bytes.TrimSuffix(c.Bytes(), []byte("\n")), true
+}
+
+// compactJSON returns the JSON document j with insignificant whitespace
+// removed. If j is not valid JSON, it is returned with only leading and
+// trailing whitespace removed.
+func compactJSON(j []byte) []byte {
+	var b bytes.Buffer
+	if err := json.Compact(&b, j); err != nil {
+		return bytes.TrimSpace(j)
+	}
+	return b.Bytes()
+}
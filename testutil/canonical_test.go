@@ -0,0 +1,284 @@
+// Copyright 2018 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package testutil
+
+import (
+	"testing"
+
+	"github.com/golang/protobuf/proto"
+	gnmipb "github.com/openconfig/gnmi/proto/gnmi"
+)
+
+// mustPath returns a gNMI Path consisting of path elements with the names
+// specified.
+func mustPath(names ...string) *gnmipb.Path {
+	p := &gnmipb.Path{}
+	for _, n := range names {
+		p.Elem = append(p.Elem, &gnmipb.PathElem{Name: n})
+	}
+	return p
+}
+
+func TestCanonicalNotification(t *testing.T) {
+	tests := []struct {
+		name string
+		in   *gnmipb.Notification
+		want *gnmipb.Notification
+	}{{
+		name: "nil notification",
+		in:   nil,
+		want: nil,
+	}, {
+		name: "updates and deletes out of order",
+		in: &gnmipb.Notification{
+			Timestamp: 42,
+			Update: []*gnmipb.Update{{
+				Path: mustPath("b"),
+				Val:  &gnmipb.TypedValue{Value: &gnmipb.TypedValue_StringVal{StringVal: "b"}},
+			}, {
+				Path: mustPath("a"),
+				Val:  &gnmipb.TypedValue{Value: &gnmipb.TypedValue_StringVal{StringVal: "a"}},
+			}},
+			Delete: []*gnmipb.Path{mustPath("d"), mustPath("c")},
+		},
+		want: &gnmipb.Notification{
+			Timestamp: 42,
+			Update: []*gnmipb.Update{{
+				Path: mustPath("a"),
+				Val:  &gnmipb.TypedValue{Value: &gnmipb.TypedValue_StringVal{StringVal: "a"}},
+			}, {
+				Path: mustPath("b"),
+				Val:  &gnmipb.TypedValue{Value: &gnmipb.TypedValue_StringVal{StringVal: "b"}},
+			}},
+			Delete: []*gnmipb.Path{mustPath("c"), mustPath("d")},
+		},
+	}, {
+		name: "empty key map in prefix and update",
+		in: &gnmipb.Notification{
+			Prefix: &gnmipb.Path{Elem: []*gnmipb.PathElem{{Name: "a", Key: map[string]string{}}}},
+			Update: []*gnmipb.Update{{
+				Path: &gnmipb.Path{Elem: []*gnmipb.PathElem{{Name: "b", Key: map[string]string{}}}},
+			}},
+		},
+		want: &gnmipb.Notification{
+			Prefix: mustPath("a"),
+			Update: []*gnmipb.Update{{
+				Path: mustPath("b"),
+			}},
+		},
+	}}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			orig := proto.Clone(tt.in)
+			got := CanonicalNotification(tt.in)
+			if !proto.Equal(got, tt.want) {
+				t.Fatalf("CanonicalNotification(%v): did not get expected notification, got: %v, want: %v", tt.in, got, tt.want)
+			}
+			if !proto.Equal(tt.in, orig) {
+				t.Fatalf("CanonicalNotification(%v): input notification was modified, got: %v, want: %v", tt.in, tt.in, orig)
+			}
+		})
+	}
+}
+
+func TestCanonicalizeStream(t *testing.T) {
+	in := []*gnmipb.Notification{{
+		Timestamp: 1,
+		Delete:    []*gnmipb.Path{mustPath("z"), mustPath("y")},
+	}, {
+		Timestamp: 2,
+		Update: []*gnmipb.Update{{
+			Path: mustPath("b"),
+			Val:  &gnmipb.TypedValue{Value: &gnmipb.TypedValue_UintVal{UintVal: 2}},
+		}, {
+			Path: mustPath("a"),
+			Val:  &gnmipb.TypedValue{Value: &gnmipb.TypedValue_UintVal{UintVal: 1}},
+		}},
+	}, {
+		Timestamp: 0,
+		Prefix:    &gnmipb.Path{Elem: []*gnmipb.PathElem{{Name: "p", Key: map[string]string{}}}},
+	}}
+
+	inCh := make(chan *gnmipb.Notification)
+	outCh := make(chan *gnmipb.Notification)
+	go CanonicalizeStream(inCh, outCh)
+	go func() {
+		for _, n := range in {
+			inCh <- n
+		}
+		close(inCh)
+	}()
+
+	var got []*gnmipb.Notification
+	for n := range outCh {
+		got = append(got, n)
+	}
+
+	if len(got) != len(in) {
+		t.Fatalf("CanonicalizeStream: did not get expected number of notifications, got: %d, want: %d", len(got), len(in))
+	}
+
+	for i, n := range in {
+		if want := CanonicalNotification(n); !proto.Equal(got[i], want) {
+			t.Errorf("CanonicalizeStream: notification %d was not canonicalized, got: %v, want: %v", i, got[i], want)
+		}
+	}
+}
+
+func TestNormalizeTypedValue(t *testing.T) {
+	decimal := func(digits int64, precision uint32) *gnmipb.TypedValue {
+		return &gnmipb.TypedValue{Value: &gnmipb.TypedValue_DecimalVal{&gnmipb.Decimal64{Digits: digits, Precision: precision}}}
+	}
+	intVal := func(i int64) *gnmipb.TypedValue {
+		return &gnmipb.TypedValue{Value: &gnmipb.TypedValue_IntVal{i}}
+	}
+	uintVal := func(u uint64) *gnmipb.TypedValue {
+		return &gnmipb.TypedValue{Value: &gnmipb.TypedValue_UintVal{u}}
+	}
+
+	tests := []struct {
+		name   string
+		in     *gnmipb.TypedValue
+		inOpts []NormalizeOpt
+		want   *gnmipb.TypedValue
+	}{{
+		name: "nil value",
+	}, {
+		name: "decimal reduced to lowest terms",
+		in:   decimal(1500, 3),
+		want: decimal(15, 1),
+	}, {
+		name: "decimal reduced to an integer",
+		in:   decimal(-4200, 2),
+		want: decimal(-42, 0),
+	}, {
+		name: "decimal already in lowest terms",
+		in:   decimal(1234, 2),
+		want: decimal(1234, 2),
+	}, {
+		name: "zero decimal",
+		in:   decimal(0, 5),
+		want: decimal(0, 0),
+	}, {
+		name: "non-negative int without option",
+		in:   intVal(42),
+		want: intVal(42),
+	}, {
+		name:   "non-negative int converted to uint",
+		in:     intVal(42),
+		inOpts: []NormalizeOpt{&IntegersAsUint{}},
+		want:   uintVal(42),
+	}, {
+		name:   "zero int converted to uint",
+		in:     intVal(0),
+		inOpts: []NormalizeOpt{&IntegersAsUint{}},
+		want:   uintVal(0),
+	}, {
+		name:   "negative int not converted",
+		in:     intVal(-1),
+		inOpts: []NormalizeOpt{&IntegersAsUint{}},
+		want:   intVal(-1),
+	}, {
+		name: "JSON whitespace trimmed",
+		in:   &gnmipb.TypedValue{Value: &gnmipb.TypedValue_JsonVal{[]byte("{\n  \"a\": [1, 2],\n  \"b\": \"x y\"\n}\n")}},
+		want: &gnmipb.TypedValue{Value: &gnmipb.TypedValue_JsonVal{[]byte(`{"a":[1,2],"b":"x y"}`)}},
+	}, {
+		name: "JSON IETF whitespace trimmed",
+		in:   &gnmipb.TypedValue{Value: &gnmipb.TypedValue_JsonIetfVal{[]byte(" { \"m:a\" : 1 } ")}},
+		want: &gnmipb.TypedValue{Value: &gnmipb.TypedValue_JsonIetfVal{[]byte(`{"m:a":1}`)}},
+	}, {
+		name: "invalid JSON trimmed",
+		in:   &gnmipb.TypedValue{Value: &gnmipb.TypedValue_JsonVal{[]byte("  {invalid \n")}},
+		want: &gnmipb.TypedValue{Value: &gnmipb.TypedValue_JsonVal{[]byte("{invalid")}},
+	}, {
+		name:   "JSON keys sorted",
+		in:     &gnmipb.TypedValue{Value: &gnmipb.TypedValue_JsonVal{[]byte("{\"b\": {\"d\": 1.50, \"c\": [2, 1]},\n \"a\": \"x\"}")}},
+		inOpts: []NormalizeOpt{&SortJSONKeys{}},
+		want:   &gnmipb.TypedValue{Value: &gnmipb.TypedValue_JsonVal{[]byte(`{"a":"x","b":{"c":[2,1],"d":1.50}}`)}},
+	}, {
+		name:   "JSON IETF keys sorted",
+		in:     &gnmipb.TypedValue{Value: &gnmipb.TypedValue_JsonIetfVal{[]byte(`{"m:b":"<&>","m:a":2}`)}},
+		inOpts: []NormalizeOpt{&SortJSONKeys{}},
+		want:   &gnmipb.TypedValue{Value: &gnmipb.TypedValue_JsonIetfVal{[]byte(`{"m:a":2,"m:b":"<&>"}`)}},
+	}, {
+		name: "JSON keys not sorted without option",
+		in:   &gnmipb.TypedValue{Value: &gnmipb.TypedValue_JsonIetfVal{[]byte(`{"m:b":1,"m:a":2}`)}},
+		want: &gnmipb.TypedValue{Value: &gnmipb.TypedValue_JsonIetfVal{[]byte(`{"m:b":1,"m:a":2}`)}},
+	}, {
+		name:   "invalid JSON trimmed with sorted keys",
+		in:     &gnmipb.TypedValue{Value: &gnmipb.TypedValue_JsonVal{[]byte(" {\"b\":1} {\"a\":2} ")}},
+		inOpts: []NormalizeOpt{&SortJSONKeys{}},
+		want:   &gnmipb.TypedValue{Value: &gnmipb.TypedValue_JsonVal{[]byte(`{"b":1} {"a":2}`)}},
+	}, {
+		name: "leaf-list elements normalized",
+		in: &gnmipb.TypedValue{Value: &gnmipb.TypedValue_LeaflistVal{&gnmipb.ScalarArray{
+			Element: []*gnmipb.TypedValue{decimal(100, 2), intVal(7), intVal(-7)},
+		}}},
+		inOpts: []NormalizeOpt{&IntegersAsUint{}},
+		want: &gnmipb.TypedValue{Value: &gnmipb.TypedValue_LeaflistVal{&gnmipb.ScalarArray{
+			Element: []*gnmipb.TypedValue{decimal(1, 0), uintVal(7), intVal(-7)},
+		}}},
+	}, {
+		name: "other values unmodified",
+		in:   &gnmipb.TypedValue{Value: &gnmipb.TypedValue_StringVal{" str "}},
+		want: &gnmipb.TypedValue{Value: &gnmipb.TypedValue_StringVal{" str "}},
+	}}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var orig proto.Message
+			if tt.in != nil {
+				orig = proto.Clone(tt.in)
+			}
+			got := NormalizeTypedValue(tt.in, tt.inOpts...)
+			if !proto.Equal(got, tt.want) {
+				t.Fatalf("NormalizeTypedValue(%v): did not get expected value, got: %v, want: %v", tt.in, got, tt.want)
+			}
+			if tt.in != nil && !proto.Equal(tt.in, orig) {
+				t.Fatalf("NormalizeTypedValue(%v): input value was modified, got: %v, want: %v", tt.in, tt.in, orig)
+			}
+		})
+	}
+}
+
+func TestNormalizeTypedValueEquivalence(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b *gnmipb.TypedValue
+	}{{
+		name: "decimals with differing precision",
+		a:    &gnmipb.TypedValue{Value: &gnmipb.TypedValue_DecimalVal{&gnmipb.Decimal64{Digits: 25, Precision: 1}}},
+		b:    &gnmipb.TypedValue{Value: &gnmipb.TypedValue_DecimalVal{&gnmipb.Decimal64{Digits: 250000, Precision: 5}}},
+	}, {
+		name: "int and uint",
+		a:    &gnmipb.TypedValue{Value: &gnmipb.TypedValue_IntVal{10}},
+		b:    &gnmipb.TypedValue{Value: &gnmipb.TypedValue_UintVal{10}},
+	}, {
+		name: "JSON with reordered keys and differing whitespace",
+		a:    &gnmipb.TypedValue{Value: &gnmipb.TypedValue_JsonVal{[]byte(`{"a":1,"b":{"c":true,"d":null}}`)}},
+		b:    &gnmipb.TypedValue{Value: &gnmipb.TypedValue_JsonVal{[]byte("{\n  \"b\": {\"d\": null, \"c\": true},\n  \"a\": 1\n}")}},
+	}}
+
+	for _, tt := range tests {
+		if proto.Equal(tt.a, tt.b) {
+			t.Errorf("%s: test values were equal before normalization: %v, %v", tt.name, tt.a, tt.b)
+		}
+		na, nb := NormalizeTypedValue(tt.a, &IntegersAsUint{}, &SortJSONKeys{}), NormalizeTypedValue(tt.b, &IntegersAsUint{}, &SortJSONKeys{})
+		if !proto.Equal(na, nb) {
+			t.Errorf("%s: normalized values were not equal, got: %v and %v", tt.name, na, nb)
+		}
+	}
+}
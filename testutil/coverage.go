@@ -0,0 +1,107 @@
+// Copyright 2018 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package testutil
+
+import (
+	"bytes"
+	"sort"
+	"strings"
+
+	gnmipb "github.com/openconfig/gnmi/proto/gnmi"
+	"github.com/openconfig/goyang/pkg/yang"
+)
+
+// SchemaCoverage determines which of the leaves and leaf-lists of the YANG
+// schema tree rooted at schema are present within the updates of the gNMI
+// Notifications n. Each leaf is identified by its schema path relative to
+// schema, e.g., /interfaces/interface/config/name, with choice and case
+// statements omitted. The absolute path of each update, formed by joining its
+// path to the prefix of its notification, is matched against the schema paths
+// with all keys treated as wildcards, such that a leaf is covered if it is
+// present for any list entry. Module prefixes within the path element names,
+// and the origin of the path, are ignored. It returns the number of leaves
+// that are covered, the total number of leaves within the schema, and the
+// sorted schema paths of the leaves that are not covered. Deleted paths are
+// not considered to cover a leaf.
+func SchemaCoverage(schema *yang.Entry, n []*gnmipb.Notification) (covered, total int, missing []string) {
+	present := map[string]bool{}
+	for _, notif := range n {
+		for _, u := range notif.GetUpdate() {
+			present[wildcardPathString(joinPaths(notif.GetPrefix(), u.GetPath()))] = true
+		}
+	}
+
+	for _, p := range schemaLeafPaths(schema, "") {
+		total++
+		if present[p] {
+			covered++
+			continue
+		}
+		missing = append(missing, p)
+	}
+	sort.Strings(missing)
+	return covered, total, missing
+}
+
+// schemaLeafPaths returns the schema paths of the leaves and leaf-lists
+// within the YANG schema tree rooted at e, each of which is prefixed with the
+// path prefix. Choice and case statements do not contribute an element to the
+// returned paths, since they are not present in the data tree.
+func schemaLeafPaths(e *yang.Entry, prefix string) []string {
+	names := make([]string, 0, len(e.Dir))
+	for n := range e.Dir {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+
+	var paths []string
+	for _, n := range names {
+		ch := e.Dir[n]
+		switch {
+		case ch.IsLeaf(), ch.IsLeafList():
+			paths = append(paths, prefix+"/"+ch.Name)
+		case ch.IsChoice(), ch.IsCase():
+			paths = append(paths, schemaLeafPaths(ch, prefix)...)
+		case ch.IsDir():
+			paths = append(paths, schemaLeafPaths(ch, prefix+"/"+ch.Name)...)
+		}
+	}
+	return paths
+}
+
+// wildcardPathString returns a string representation of the gNMI Path p, in
+// the form /a/b/c, in which the keys of each path element are omitted, such
+// that they are treated as wildcards, and any module prefix of the name of
+// each path element is removed. Paths that use only the deprecated element
+// field are rendered using its contents.
+func wildcardPathString(p *gnmipb.Path) string {
+	names := p.GetElement()
+	if len(p.GetElem()) != 0 {
+		names = make([]string, 0, len(p.GetElem()))
+		for _, e := range p.GetElem() {
+			names = append(names, e.GetName())
+		}
+	}
+
+	var b bytes.Buffer
+	for _, n := range names {
+		if i := strings.Index(n, ":"); i != -1 {
+			n = n[i+1:]
+		}
+		b.WriteString("/")
+		b.WriteString(n)
+	}
+	return b.String()
+}
@@ -0,0 +1,136 @@
+// Copyright 2018 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package testutil
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	gnmipb "github.com/openconfig/gnmi/proto/gnmi"
+	"github.com/openconfig/goyang/pkg/yang"
+)
+
+func TestSchemaCoverage(t *testing.T) {
+	leaf := func(name string) *yang.Entry {
+		return &yang.Entry{Name: name, Kind: yang.LeafEntry}
+	}
+	dir := func(name string, kind yang.EntryKind, children ...*yang.Entry) *yang.Entry {
+		e := &yang.Entry{Name: name, Kind: kind, Dir: map[string]*yang.Entry{}}
+		for _, ch := range children {
+			e.Dir[ch.Name] = ch
+		}
+		return e
+	}
+
+	intf := dir("interface", yang.DirectoryEntry,
+		leaf("name"),
+		dir("config", yang.DirectoryEntry, leaf("name"), leaf("mtu")),
+	)
+	intf.ListAttr = &yang.ListAttr{}
+	addrs := leaf("addresses")
+	addrs.ListAttr = &yang.ListAttr{}
+
+	schema := dir("device", yang.DirectoryEntry,
+		dir("interfaces", yang.DirectoryEntry, intf),
+		dir("system", yang.DirectoryEntry,
+			leaf("hostname"),
+			addrs,
+			dir("mode", yang.ChoiceEntry,
+				dir("local", yang.CaseEntry, leaf("local-user")),
+				dir("remote", yang.CaseEntry, leaf("server")),
+			),
+		),
+	)
+
+	intfPrefix := func(name string) *gnmipb.Path {
+		return &gnmipb.Path{Elem: []*gnmipb.PathElem{
+			{Name: "interfaces"},
+			{Name: "interface", Key: map[string]string{"name": name}},
+		}}
+	}
+
+	tests := []struct {
+		name        string
+		in          []*gnmipb.Notification
+		wantCovered int
+		wantTotal   int
+		wantMissing []string
+	}{{
+		name:        "no notifications",
+		wantCovered: 0,
+		wantTotal:   7,
+		wantMissing: []string{
+			"/interfaces/interface/config/mtu",
+			"/interfaces/interface/config/name",
+			"/interfaces/interface/name",
+			"/system/addresses",
+			"/system/hostname",
+			"/system/local-user",
+			"/system/server",
+		},
+	}, {
+		name: "partial coverage",
+		in: []*gnmipb.Notification{{
+			Prefix: intfPrefix("eth0"),
+			Update: []*gnmipb.Update{{Path: mustPath("name")}, {Path: mustPath("config", "name")}},
+		}, {
+			Prefix: intfPrefix("eth1"),
+			Update: []*gnmipb.Update{{Path: mustPath("config", "mtu")}},
+		}, {
+			Update: []*gnmipb.Update{{Path: mustPath("openconfig-system:system", "local-user")}},
+			Delete: []*gnmipb.Path{mustPath("system", "hostname")},
+		}},
+		wantCovered: 4,
+		wantTotal:   7,
+		wantMissing: []string{
+			"/system/addresses",
+			"/system/hostname",
+			"/system/server",
+		},
+	}, {
+		name: "full coverage, with paths not in the schema",
+		in: []*gnmipb.Notification{{
+			Prefix: intfPrefix("eth0"),
+			Update: []*gnmipb.Update{
+				{Path: mustPath("name")},
+				{Path: mustPath("config", "name")},
+				{Path: mustPath("config", "mtu")},
+				{Path: mustPath("config", "unknown")},
+			},
+		}, {
+			Prefix: mustPath("system"),
+			Update: []*gnmipb.Update{
+				{Path: mustPath("hostname")},
+				{Path: mustPath("addresses")},
+				{Path: mustPath("local-user")},
+				{Path: mustPath("server")},
+			},
+		}},
+		wantCovered: 7,
+		wantTotal:   7,
+	}}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			covered, total, missing := SchemaCoverage(schema, tt.in)
+			if covered != tt.wantCovered || total != tt.wantTotal {
+				t.Errorf("SchemaCoverage(%v): did not get expected coverage, got: %d/%d, want: %d/%d", tt.in, covered, total, tt.wantCovered, tt.wantTotal)
+			}
+			if diff := cmp.Diff(missing, tt.wantMissing); diff != "" {
+				t.Errorf("SchemaCoverage(%v): did not get expected missing paths, diff(-got,+want):\n%s", tt.in, diff)
+			}
+		})
+	}
+}
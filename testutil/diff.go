@@ -14,7 +14,17 @@
 
 package testutil
 
-import "github.com/pmezard/go-difflib/difflib"
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/google/go-cmp/cmp"
+	gnmipb "github.com/openconfig/gnmi/proto/gnmi"
+	"github.com/pmezard/go-difflib/difflib"
+)
 
 // GenerateUnifiedDiff takes two strings and generates a diff that can be
 // shown to the user in a test error message.
@@ -29,3 +39,217 @@ func GenerateUnifiedDiff(got, want string) (string, error) {
 	}
 	return difflib.GetUnifiedDiffString(diffl)
 }
+
+// CompareGeneratedFiles compares the contents of two sets of generated files,
+// each specified as a map, keyed by file name, of the contents of the file. It
+// returns true if the two sets contain the same files with the same contents.
+// Otherwise it returns false, along with a report of the differences that is
+// suitable for output in test logs. The report contains, in order of file
+// name, a line for each file that is only in want, prefixed with "-", a line
+// for each file that is only in got, prefixed with "+", and for each file
+// whose contents differ, a line prefixed with "~" followed by a unified diff
+// of the contents of the file, as returned by GenerateUnifiedDiff.
+func CompareGeneratedFiles(want, got map[string]string) (bool, string) {
+	names := map[string]bool{}
+	for n := range want {
+		names[n] = true
+	}
+	for n := range got {
+		names[n] = true
+	}
+	sorted := make([]string, 0, len(names))
+	for n := range names {
+		sorted = append(sorted, n)
+	}
+	sort.Strings(sorted)
+
+	var b bytes.Buffer
+	for _, n := range sorted {
+		w, inWant := want[n]
+		g, inGot := got[n]
+		switch {
+		case !inGot:
+			fmt.Fprintf(&b, "- %s: missing file\n", n)
+		case !inWant:
+			fmt.Fprintf(&b, "+ %s: unexpected file\n", n)
+		case w != g:
+			fmt.Fprintf(&b, "~ %s: contents differ, diff(-got,+want):\n", n)
+			diff, err := GenerateUnifiedDiff(g, w)
+			if err != nil {
+				diff = fmt.Sprintf("cannot generate diff: %v\n", err)
+			}
+			// The diff is trimmed such that each file's diff is terminated by a
+			// single newline, regardless of whether the files end with one.
+			b.WriteString(strings.TrimRight(diff, "\n"))
+			b.WriteString("\n")
+		}
+	}
+	return b.Len() == 0, b.String()
+}
+
+// NotificationSetDiff returns a human-readable report of the differences
+// between the sets of gNMI Notifications a and b, in the format of cmp.Diff,
+// with lines prefixed by "-" for content only in a, and "+" for content only
+// in b. As per NotificationSetEqual, the notifications are converted to their
+// canonical form and sorted prior to being compared, such that neither the
+// order of the notifications, nor that of their updates and deletes, is
+// reported. Paths and values are rendered using PathString and
+// TypedValueString respectively, such that the report identifies the paths of
+// the updates and deletes that differ. A notification that is only within one
+// of the sets is reported in its entirety, in protobuf text format. An empty
+// string is returned if the notifications are equal.
+func NotificationSetDiff(a, b []*gnmipb.Notification) string {
+	return cmp.Diff(canonicalNotifications(a), canonicalNotifications(b),
+		cmp.Transformer("PathString", PathString),
+		cmp.Transformer("PathStrings", func(ps []*gnmipb.Path) []string {
+			ss := make([]string, 0, len(ps))
+			for _, p := range ps {
+				ss = append(ss, PathString(p))
+			}
+			return ss
+		}),
+		cmp.Transformer("TypedValueString", TypedValueString),
+		// The internal fields of the generated protobuf structs are ignored.
+		cmp.FilterPath(func(p cmp.Path) bool {
+			sf, ok := p.Last().(cmp.StructField)
+			return ok && strings.HasPrefix(sf.Name(), "XXX_")
+		}, cmp.Ignore()),
+	)
+}
+
+// CompactNotificationDiff returns a summary of the differences between the
+// gNMI Notifications in want and got that is suitable for output in test logs
+// where the notifications may be large. The summary contains one line per
+// path at which the two sets of notifications differ - either because the path
+// is only present in one set, or because its value(s) differ - sorted using
+// PathLess. Timestamps are not considered. At most maxLines lines describing
+// differences are output, with a footer indicating how many further
+// differences were omitted. If maxLines is less than or equal to zero, all
+// differences are output. If there are no differences, an empty string is
+// returned.
+//
+// The notifications are compared in the canonical form used by
+// NotificationSetDiff, but unlike NotificationSetDiff, which reports each
+// notification that differs in its entirety, differences are reported per
+// path, as described by pathDiffs, such that the size of the summary is
+// bounded by the number of paths that differ.
+func CompactNotificationDiff(want, got []*gnmipb.Notification, maxLines int) string {
+	diffs := pathDiffs(canonicalNotifications(want), canonicalNotifications(got))
+
+	var b bytes.Buffer
+	for i, d := range diffs {
+		if maxLines > 0 && i == maxLines {
+			fmt.Fprintf(&b, "…and %d more\n", len(diffs)-maxLines)
+			break
+		}
+		b.WriteString(d.String())
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+// pathDiff describes a difference at a single path between two sets of gNMI
+// Notifications.
+type pathDiff struct {
+	path *gnmipb.Path // path is the absolute path at which the difference occurs.
+	want []string     // want is the set of values at path in the expected notifications.
+	got  []string     // got is the set of values at path in the received notifications.
+}
+
+// String returns a single line human-readable representation of the pathDiff.
+// Paths that are missing from the received notifications are prefixed with
+// "-", those that are unexpected are prefixed with "+", and those that have
+// differing values are prefixed with "~".
+func (d *pathDiff) String() string {
+	p := PathString(d.path)
+	switch {
+	case len(d.got) == 0:
+		return fmt.Sprintf("- %s: %s", p, strings.Join(d.want, ", "))
+	case len(d.want) == 0:
+		return fmt.Sprintf("+ %s: %s", p, strings.Join(d.got, ", "))
+	}
+	return fmt.Sprintf("~ %s: want %s, got %s", p, strings.Join(d.want, ", "), strings.Join(d.got, ", "))
+}
+
+// pathDiffs returns the set of paths at which the contents of the want and
+// got gNMI Notifications differ, sorted using PathLess. Updates and deletes
+// are considered at their absolute path - i.e., with the prefix of the
+// notification that they are within prepended. Rather than comparing the
+// notifications themselves, as NotificationSetDiff does, the values at each
+// path are compared regardless of the notification that they are within, such
+// that notifications that differ only in their timestamps, or in how updates
+// are grouped into notifications, are not reported as differing.
+func pathDiffs(want, got []*gnmipb.Notification) []*pathDiff {
+	wantVals, gotVals := notificationValues(want), notificationValues(got)
+
+	paths := map[string]*gnmipb.Path{}
+	for k, v := range wantVals {
+		paths[k] = v.path
+	}
+	for k, v := range gotVals {
+		paths[k] = v.path
+	}
+
+	var diffs []*pathDiff
+	for k, p := range paths {
+		var w, g []string
+		if v, ok := wantVals[k]; ok {
+			w = v.vals
+		}
+		if v, ok := gotVals[k]; ok {
+			g = v.vals
+		}
+		sort.Strings(w)
+		sort.Strings(g)
+		if strings.Join(w, "\n") == strings.Join(g, "\n") {
+			continue
+		}
+		diffs = append(diffs, &pathDiff{path: p, want: w, got: g})
+	}
+
+	sort.Slice(diffs, func(i, j int) bool { return PathLess(diffs[i].path, diffs[j].path) })
+	return diffs
+}
+
+// pathValues stores the values that were found at a path in a set of gNMI
+// Notifications.
+type pathValues struct {
+	path *gnmipb.Path // path is the absolute path.
+	vals []string     // vals is the set of values, represented as strings.
+}
+
+// notificationValues returns the values found within the gNMI Notifications
+// ns, keyed by the string representation of the absolute path at which they
+// occur. Deleted paths are represented by the value "<deleted>".
+func notificationValues(ns []*gnmipb.Notification) map[string]*pathValues {
+	vals := map[string]*pathValues{}
+	add := func(p *gnmipb.Path, v string) {
+		k := PathString(p)
+		if vals[k] == nil {
+			vals[k] = &pathValues{path: p}
+		}
+		vals[k].vals = append(vals[k].vals, v)
+	}
+
+	for _, n := range ns {
+		for _, u := range n.GetUpdate() {
+			add(joinPaths(n.GetPrefix(), u.GetPath()), TypedValueString(u.GetVal()))
+		}
+		for _, d := range n.GetDelete() {
+			add(joinPaths(n.GetPrefix(), d), "<deleted>")
+		}
+	}
+	return vals
+}
+
+// joinPaths returns a new gNMI Path consisting of the path p appended to the
+// prefix pfx. The origin and target of the returned path are taken from pfx.
+func joinPaths(pfx, p *gnmipb.Path) *gnmipb.Path {
+	if pfx == nil {
+		return p
+	}
+	np := proto.Clone(pfx).(*gnmipb.Path)
+	np.Elem = append(np.Elem, p.GetElem()...)
+	np.Element = append(np.Element, p.GetElement()...)
+	return np
+}
@@ -0,0 +1,280 @@
+// Copyright 2018 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package testutil
+
+import (
+	"strings"
+	"testing"
+
+	gnmipb "github.com/openconfig/gnmi/proto/gnmi"
+)
+
+func TestCompactNotificationDiff(t *testing.T) {
+	update := func(name string, v uint64) *gnmipb.Update {
+		return &gnmipb.Update{
+			Path: mustPath(name),
+			Val:  &gnmipb.TypedValue{Value: &gnmipb.TypedValue_UintVal{UintVal: v}},
+		}
+	}
+
+	want := []*gnmipb.Notification{{
+		Timestamp: 1,
+		Prefix:    mustPath("pfx"),
+		Update:    []*gnmipb.Update{update("a", 1), update("b", 2), update("c", 3), update("d", 4)},
+	}}
+
+	tests := []struct {
+		name       string
+		inWant     []*gnmipb.Notification
+		inGot      []*gnmipb.Notification
+		inMaxLines int
+		want       string
+	}{{
+		name:   "no differences, differing timestamp and order",
+		inWant: want,
+		inGot: []*gnmipb.Notification{{
+			Timestamp: 2,
+			Prefix:    mustPath("pfx"),
+			Update:    []*gnmipb.Update{update("d", 4), update("c", 3), update("b", 2), update("a", 1)},
+		}},
+		inMaxLines: 1,
+	}, {
+		name:   "no differences, updates split across notifications",
+		inWant: want,
+		inGot: []*gnmipb.Notification{{
+			Timestamp: 1,
+			Prefix:    mustPath("pfx"),
+			Update:    []*gnmipb.Update{update("c", 3), update("d", 4)},
+		}, {
+			Timestamp: 2,
+			Update: []*gnmipb.Update{{
+				Path: mustPath("pfx", "a"),
+				Val:  &gnmipb.TypedValue{Value: &gnmipb.TypedValue_UintVal{UintVal: 1}},
+			}, {
+				Path: mustPath("pfx", "b"),
+				Val:  &gnmipb.TypedValue{Value: &gnmipb.TypedValue_UintVal{UintVal: 2}},
+			}},
+		}},
+		inMaxLines: 1,
+	}, {
+		name:   "differences within maxLines",
+		inWant: want,
+		inGot: []*gnmipb.Notification{{
+			Timestamp: 1,
+			Prefix:    mustPath("pfx"),
+			Update:    []*gnmipb.Update{update("a", 1), update("b", 42), update("c", 3), update("e", 5)},
+			Delete:    []*gnmipb.Path{mustPath("f")},
+		}},
+		inMaxLines: 10,
+		want: "~ /pfx/b: want uint_val:2, got uint_val:42\n" +
+			"- /pfx/d: uint_val:4\n" +
+			"+ /pfx/e: uint_val:5\n" +
+			"+ /pfx/f: <deleted>\n",
+	}, {
+		name:   "differences exceed maxLines",
+		inWant: want,
+		inGot: []*gnmipb.Notification{{
+			Timestamp: 1,
+			Prefix:    mustPath("pfx"),
+			Update:    []*gnmipb.Update{update("a", 1), update("b", 42), update("c", 3), update("e", 5)},
+			Delete:    []*gnmipb.Path{mustPath("f")},
+		}},
+		inMaxLines: 2,
+		want: "~ /pfx/b: want uint_val:2, got uint_val:42\n" +
+			"- /pfx/d: uint_val:4\n" +
+			"…and 2 more\n",
+	}, {
+		name:       "all differences output with no limit",
+		inWant:     want,
+		inMaxLines: 0,
+		want: "- /pfx/a: uint_val:1\n" +
+			"- /pfx/b: uint_val:2\n" +
+			"- /pfx/c: uint_val:3\n" +
+			"- /pfx/d: uint_val:4\n",
+	}}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := CompactNotificationDiff(tt.inWant, tt.inGot, tt.inMaxLines)
+			if got != tt.want {
+				diff, _ := GenerateUnifiedDiff(got, tt.want)
+				t.Fatalf("CompactNotificationDiff(%v, %v, %d): did not get expected output, diff(-got,+want):\n%s", tt.inWant, tt.inGot, tt.inMaxLines, diff)
+			}
+		})
+	}
+}
+
+func TestNotificationSetDiff(t *testing.T) {
+	update := func(name string, v uint64) *gnmipb.Update {
+		return &gnmipb.Update{
+			Path: mustPath(name),
+			Val:  &gnmipb.TypedValue{Value: &gnmipb.TypedValue_UintVal{UintVal: v}},
+		}
+	}
+
+	a := []*gnmipb.Notification{{
+		Timestamp: 1,
+		Prefix:    mustPath("pfx"),
+		Update:    []*gnmipb.Update{update("a", 1), update("b", 2)},
+		Delete:    []*gnmipb.Path{mustPath("pfx", "c")},
+	}, {
+		Timestamp: 2,
+		Update:    []*gnmipb.Update{update("d", 4)},
+	}}
+
+	tests := []struct {
+		name         string
+		inA          []*gnmipb.Notification
+		inB          []*gnmipb.Notification
+		wantEqual    bool
+		wantContains []string
+	}{{
+		name: "equal, differing order",
+		inA:  a,
+		inB: []*gnmipb.Notification{{
+			Timestamp: 2,
+			Update:    []*gnmipb.Update{update("d", 4)},
+		}, {
+			Timestamp: 1,
+			Prefix:    mustPath("pfx"),
+			Update:    []*gnmipb.Update{update("b", 2), update("a", 1)},
+			Delete:    []*gnmipb.Path{mustPath("pfx", "c")},
+		}},
+		wantEqual: true,
+	}, {
+		name: "differing update value",
+		inA:  a,
+		inB: []*gnmipb.Notification{{
+			Timestamp: 1,
+			Prefix:    mustPath("pfx"),
+			Update:    []*gnmipb.Update{update("a", 1), update("b", 42)},
+			Delete:    []*gnmipb.Path{mustPath("pfx", "c")},
+		}, {
+			Timestamp: 2,
+			Update:    []*gnmipb.Update{update("d", 4)},
+		}},
+		wantContains: []string{`"/b"`, "uint_val:2", "uint_val:42"},
+	}, {
+		name: "missing delete",
+		inA:  a,
+		inB: []*gnmipb.Notification{{
+			Timestamp: 1,
+			Prefix:    mustPath("pfx"),
+			Update:    []*gnmipb.Update{update("a", 1), update("b", 2)},
+		}, {
+			Timestamp: 2,
+			Update:    []*gnmipb.Update{update("d", 4)},
+		}},
+		wantContains: []string{`"/pfx/c"`},
+	}, {
+		name:         "missing notification",
+		inA:          a,
+		inB:          a[1:],
+		wantContains: []string{"timestamp:1", `name:"pfx"`},
+	}}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := NotificationSetDiff(tt.inA, tt.inB)
+			if (got == "") != tt.wantEqual {
+				t.Fatalf("NotificationSetDiff(%v, %v): did not get expected equality, got diff:\n%s\nwantEqual: %v", tt.inA, tt.inB, got, tt.wantEqual)
+			}
+			for _, s := range tt.wantContains {
+				if !strings.Contains(got, s) {
+					t.Errorf("NotificationSetDiff(%v, %v): diff does not contain %s, got:\n%s", tt.inA, tt.inB, s, got)
+				}
+			}
+		})
+	}
+}
+
+func TestCompareGeneratedFiles(t *testing.T) {
+	want := map[string]string{
+		"a.proto": "syntax = \"proto3\";\npackage a;",
+		"b.proto": "syntax = \"proto3\";\npackage b;",
+	}
+
+	tests := []struct {
+		name      string
+		inWant    map[string]string
+		inGot     map[string]string
+		wantEqual bool
+		wantDiff  string
+	}{{
+		name:      "equal files",
+		inWant:    want,
+		inGot:     map[string]string{"b.proto": want["b.proto"], "a.proto": want["a.proto"]},
+		wantEqual: true,
+	}, {
+		name:      "no files",
+		wantEqual: true,
+	}, {
+		name:   "added file",
+		inWant: want,
+		inGot: map[string]string{
+			"a.proto": want["a.proto"],
+			"b.proto": want["b.proto"],
+			"c.proto": "syntax = \"proto3\";\npackage c;",
+		},
+		wantDiff: "+ c.proto: unexpected file\n",
+	}, {
+		name:     "removed file",
+		inWant:   want,
+		inGot:    map[string]string{"b.proto": want["b.proto"]},
+		wantDiff: "- a.proto: missing file\n",
+	}, {
+		name:   "modified file",
+		inWant: want,
+		inGot: map[string]string{
+			"a.proto": "syntax = \"proto3\";\npackage z;",
+			"b.proto": want["b.proto"],
+		},
+		wantDiff: "~ a.proto: contents differ, diff(-got,+want):\n" +
+			"--- got\n" +
+			"+++ want\n" +
+			"@@ -1,2 +1,2 @@\n" +
+			" syntax = \"proto3\";\n" +
+			"-package z;\n" +
+			"+package a;\n",
+	}, {
+		name:   "added, removed and modified files",
+		inWant: want,
+		inGot: map[string]string{
+			"b.proto": "syntax = \"proto3\";",
+			"c.proto": "",
+		},
+		wantDiff: "- a.proto: missing file\n" +
+			"~ b.proto: contents differ, diff(-got,+want):\n" +
+			"--- got\n" +
+			"+++ want\n" +
+			"@@ -1 +1,2 @@\n" +
+			" syntax = \"proto3\";\n" +
+			"+package b;\n" +
+			"+ c.proto: unexpected file\n",
+	}}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotEqual, gotDiff := CompareGeneratedFiles(tt.inWant, tt.inGot)
+			if gotEqual != tt.wantEqual {
+				t.Errorf("CompareGeneratedFiles(%v, %v): did not get expected equality, got: %v, want: %v", tt.inWant, tt.inGot, gotEqual, tt.wantEqual)
+			}
+			if gotDiff != tt.wantDiff {
+				diff, _ := GenerateUnifiedDiff(gotDiff, tt.wantDiff)
+				t.Errorf("CompareGeneratedFiles(%v, %v): did not get expected report, diff(-got,+want):\n%s", tt.inWant, tt.inGot, diff)
+			}
+		})
+	}
+}
@@ -17,9 +17,12 @@
 package testutil
 
 import (
+	"bytes"
 	"fmt"
+	"math/big"
 	"reflect"
 	"sort"
+	"strings"
 
 	"github.com/golang/protobuf/proto"
 	"github.com/google/go-cmp/cmp"
@@ -29,42 +32,292 @@ import (
 )
 
 // NotificationSetEqual compares the contents of a and b and returns true if
-// they are equal. Order of the slices is ignored.
+// they are equal. Order of the slices is ignored, as is the order of the
+// updates and deletes within each notification. Both slices are sorted using
+// NotificationLess after converting each notification to its canonical form,
+// as returned by CanonicalNotification, and are then compared element-wise
+// using proto.Equal. Nil and empty slices are considered equal. The input
+// slices are not modified.
 func NotificationSetEqual(a, b []*gnmipb.Notification) bool {
-	for _, an := range a {
-		var matched bool
-		for _, bn := range b {
-			n := &notificationMatch{
-				timestamp: an.GetTimestamp() == bn.GetTimestamp(),
-				prefix:    proto.Equal(an.GetPrefix(), bn.GetPrefix()),
-				update:    cmp.Equal(an.GetUpdate(), bn.GetUpdate(), cmpopts.SortSlices(UpdateLess), cmpopts.EquateEmpty()),
-				delete:    cmp.Equal(an.GetDelete(), bn.GetDelete(), cmpopts.SortSlices(PathLess), cmpopts.EquateEmpty()),
-			}
-
-			if n.matched() {
-				matched = true
-				break
-			}
-		}
-		if !matched {
+	if len(a) != len(b) {
+		return false
+	}
+	sortedA, sortedB := canonicalNotifications(a), canonicalNotifications(b)
+	for i := range sortedA {
+		if !proto.Equal(sortedA[i], sortedB[i]) {
 			return false
 		}
 	}
 	return true
 }
 
-// notificationMatch tracks whether a gNMI notification pair has matched.
-type notificationMatch struct {
-	timestamp bool
-	prefix    bool
-	update    bool
-	delete    bool
+// canonicalNotifications returns a copy of the slice of gNMI Notifications ns,
+// with each notification converted to its canonical form, sorted using
+// NotificationLess.
+func canonicalNotifications(ns []*gnmipb.Notification) []*gnmipb.Notification {
+	c := make([]*gnmipb.Notification, 0, len(ns))
+	for _, n := range ns {
+		c = append(c, CanonicalNotification(n))
+	}
+	sort.Sort(notificationSet(c))
+	return c
+}
+
+// GetResponseEqual returns true if the gNMI GetResponses a and b are equal.
+// The notifications within the responses are compared using
+// NotificationSetEqual, such that their order is ignored, and all other fields
+// of the responses are compared using proto.Equal. Two nil responses are
+// considered equal, whereas a nil response is not equal to a non-nil response.
+// The input responses are not modified.
+func GetResponseEqual(a, b *gnmipb.GetResponse) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	if !NotificationSetEqual(a.GetNotification(), b.GetNotification()) {
+		return false
+	}
+
+	ac, bc := proto.Clone(a).(*gnmipb.GetResponse), proto.Clone(b).(*gnmipb.GetResponse)
+	ac.Notification, bc.Notification = nil, nil
+	return proto.Equal(ac, bc)
+}
+
+// SubscribeResponseEqual returns true if the gNMI SubscribeResponses a and b
+// are equal. Responses that both carry an update are equal if their
+// notifications are equal according to NotificationSetEqual, such that the
+// order of the updates and deletes within them is ignored. Responses that
+// carry a sync response, or an error, are equal if the value that they carry
+// is equal. Responses that carry differing arms of the response oneof are not
+// equal. All other fields of the responses are compared using proto.Equal. Two
+// nil responses are considered equal, whereas a nil response is not equal to a
+// non-nil response. The input responses are not modified.
+func SubscribeResponseEqual(a, b *gnmipb.SubscribeResponse) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+
+	au, aok := a.GetResponse().(*gnmipb.SubscribeResponse_Update)
+	bu, bok := b.GetResponse().(*gnmipb.SubscribeResponse_Update)
+	if !aok || !bok {
+		// The sync response and error arms are compared by value, and
+		// responses with differing arms are not equal.
+		return proto.Equal(a, b)
+	}
+	if !NotificationSetEqual([]*gnmipb.Notification{au.Update}, []*gnmipb.Notification{bu.Update}) {
+		return false
+	}
+
+	ac, bc := proto.Clone(a).(*gnmipb.SubscribeResponse), proto.Clone(b).(*gnmipb.SubscribeResponse)
+	ac.Response, bc.Response = nil, nil
+	return proto.Equal(ac, bc)
+}
+
+// MergeSetRequests merges the gNMI SetRequests reqs into a single SetRequest,
+// such that a batch of requests can be issued together. All requests must
+// have the same prefix - where a nil prefix is equivalent to an empty one -
+// otherwise an error is returned. The deletes, replaces and updates of the
+// requests are concatenated, and extensions are retained in order. Since a
+// SetRequest applies its deletes, then its replaces, and then its updates, an
+// operation on a path supersedes the operations on the same path in earlier
+// requests that would otherwise be applied after it - e.g., a delete removes
+// earlier replaces and updates of the path, and where a path is updated more
+// than once, the last update is retained. The deletes, replaces and updates
+// of the merged request are each sorted using PathLess, such that the output
+// is deterministic. The input requests are not modified.
+func MergeSetRequests(reqs []*gnmipb.SetRequest) (*gnmipb.SetRequest, error) {
+	merged := &gnmipb.SetRequest{}
+	if len(reqs) == 0 {
+		return merged, nil
+	}
+
+	nonNilPrefix := func(p *gnmipb.Path) *gnmipb.Path {
+		if p == nil {
+			return &gnmipb.Path{}
+		}
+		return p
+	}
+
+	prefix := nonNilPrefix(reqs[0].GetPrefix())
+	deletes := map[string]*gnmipb.Path{}
+	replaces, updates := map[string]*gnmipb.Update{}, map[string]*gnmipb.Update{}
+	for i, r := range reqs {
+		if p := nonNilPrefix(r.GetPrefix()); !proto.Equal(p, prefix) {
+			return nil, fmt.Errorf("request %d has prefix %s, which is not compatible with prefix %s", i, PathString(p), PathString(prefix))
+		}
+		for _, d := range r.GetDelete() {
+			k := PathString(d)
+			delete(replaces, k)
+			delete(updates, k)
+			deletes[k] = d
+		}
+		for _, u := range r.GetReplace() {
+			k := PathString(u.GetPath())
+			delete(updates, k)
+			replaces[k] = u
+		}
+		for _, u := range r.GetUpdate() {
+			updates[PathString(u.GetPath())] = u
+		}
+		merged.Extension = append(merged.Extension, r.GetExtension()...)
+	}
+
+	merged.Prefix = reqs[0].GetPrefix()
+	for _, d := range deletes {
+		merged.Delete = append(merged.Delete, d)
+	}
+	sort.Sort(pathSet(merged.Delete))
+	merged.Replace = sortedUpdates(replaces)
+	merged.Update = sortedUpdates(updates)
+	return merged, nil
+}
+
+// sortedUpdates returns the values of the map of gNMI Updates m as a slice,
+// sorted by the path of each update using PathLess.
+func sortedUpdates(m map[string]*gnmipb.Update) []*gnmipb.Update {
+	var us []*gnmipb.Update
+	for _, u := range m {
+		us = append(us, u)
+	}
+	sort.Slice(us, func(i, j int) bool { return PathLess(us[i].GetPath(), us[j].GetPath()) })
+	return us
+}
+
+// notificationEqual returns true if the gNMI Notifications a and b are equal,
+// ignoring the order of their updates and deletes. All other fields of the
+// notifications, such as the alias and whether they are atomic, are compared,
+// such that notifications that are equal are also not ordered relative to one
+// another by NotificationLess.
+func notificationEqual(a, b *gnmipb.Notification) bool {
+	return proto.Equal(CanonicalNotification(a), CanonicalNotification(b))
+}
+
+// NotificationComparer returns a cmp.Option that compares slices of gNMI
+// Notifications, ignoring their order, which is determined by sorting them
+// using NotificationLess. Each pair of notifications is compared ignoring the
+// order of their updates and deletes, such that, for example,
+// cmp.Diff(a, b, NotificationComparer()) returns an empty string for slices
+// of notifications that differ only in their ordering. If opts are specified,
+// the value of each update is normalized using NormalizeTypedValue with opts
+// prior to the notifications being ordered and compared, such that, for
+// example, NotificationComparer(&SortJSONKeys{}) considers JSON values that
+// differ only in the order of their keys to be equal.
+func NotificationComparer(opts ...NormalizeOpt) cmp.Option {
+	return cmp.Options{
+		cmpopts.SortSlices(func(a, b *gnmipb.Notification) bool {
+			return NotificationLess(normalizeNotificationValues(a, opts), normalizeNotificationValues(b, opts))
+		}),
+		cmp.Comparer(func(a, b *gnmipb.Notification) bool {
+			return notificationEqual(normalizeNotificationValues(a, opts), normalizeNotificationValues(b, opts))
+		}),
+	}
+}
+
+// UpdateComparer returns a cmp.Option that compares slices of gNMI Updates,
+// ignoring their order, which is determined by sorting them using UpdateLess.
+// Each pair of updates is compared using proto.Equal. If opts are specified,
+// the value of each update is normalized using NormalizeTypedValue with opts
+// prior to the updates being ordered and compared.
+func UpdateComparer(opts ...NormalizeOpt) cmp.Option {
+	return cmp.Options{
+		cmpopts.SortSlices(func(a, b *gnmipb.Update) bool {
+			return UpdateLess(normalizeUpdateValue(a, opts), normalizeUpdateValue(b, opts))
+		}),
+		cmp.Comparer(func(a, b *gnmipb.Update) bool {
+			return proto.Equal(normalizeUpdateValue(a, opts), normalizeUpdateValue(b, opts))
+		}),
+	}
+}
+
+// normalizeNotificationValues returns a copy of the gNMI Notification n in
+// which the value of each update is normalized using NormalizeTypedValue with
+// the supplied opts. If no opts are supplied, n is returned unmodified, such
+// that values are compared as they were encoded.
+func normalizeNotificationValues(n *gnmipb.Notification, opts []NormalizeOpt) *gnmipb.Notification {
+	if n == nil || len(opts) == 0 {
+		return n
+	}
+	c := proto.Clone(n).(*gnmipb.Notification)
+	for _, u := range c.Update {
+		u.Val = NormalizeTypedValue(u.Val, opts...)
+	}
+	return c
+}
+
+// normalizeUpdateValue returns a copy of the gNMI Update u in which its value
+// is normalized using NormalizeTypedValue with the supplied opts. If no opts
+// are supplied, u is returned unmodified.
+func normalizeUpdateValue(u *gnmipb.Update, opts []NormalizeOpt) *gnmipb.Update {
+	if u == nil || len(opts) == 0 {
+		return u
+	}
+	c := proto.Clone(u).(*gnmipb.Update)
+	c.Val = NormalizeTypedValue(c.Val, opts...)
+	return c
+}
+
+// PathComparer returns a cmp.Option that compares slices of gNMI Paths,
+// ignoring their order, which is determined by sorting them using PathLess.
+// Each pair of paths is compared using proto.Equal.
+func PathComparer() cmp.Option {
+	return cmp.Options{
+		cmpopts.SortSlices(PathLess),
+		cmp.Comparer(func(a, b *gnmipb.Path) bool { return proto.Equal(a, b) }),
+	}
+}
+
+// NotificationComparerWithAliases returns a cmp.Option that compares gNMI
+// Notifications after resolving the gNMI path aliases that they use. The
+// aliases map is keyed by the name of the alias (e.g., "#alias"), with the
+// value being the path that the alias refers to. Where the first element of
+// the prefix, or of an update or delete path, of a notification is an alias
+// within the map, the element is replaced by the elements of the aliased path
+// prior to comparison. The order of the updates and deletes within the
+// notifications is ignored.
+func NotificationComparerWithAliases(aliases map[string]*gnmipb.Path) cmp.Option {
+	return cmp.Comparer(func(a, b *gnmipb.Notification) bool {
+		return notificationEqual(expandNotificationAliases(a, aliases), expandNotificationAliases(b, aliases))
+	})
+}
+
+// expandNotificationAliases returns a copy of the gNMI Notification n with each
+// alias in the aliases map that is used within its prefix or paths expanded, as
+// described by expandPathAlias.
+func expandNotificationAliases(n *gnmipb.Notification, aliases map[string]*gnmipb.Path) *gnmipb.Notification {
+	if n == nil {
+		return nil
+	}
+
+	c := proto.Clone(n).(*gnmipb.Notification)
+	c.Prefix = expandPathAlias(c.Prefix, aliases)
+	for _, u := range c.Update {
+		u.Path = expandPathAlias(u.Path, aliases)
+	}
+	for i, d := range c.Delete {
+		c.Delete[i] = expandPathAlias(d, aliases)
+	}
+	return c
 }
 
-// matched determines whether the receiver notificationMatch n represents
-// a matched pair.
-func (n *notificationMatch) matched() bool {
-	return n.timestamp && n.prefix && n.update && n.delete
+// expandPathAlias returns the gNMI Path p with its first element expanded to
+// the path that it refers to if it is an alias within the aliases map. If the
+// first element is not an alias, p is returned unmodified. The target of p is
+// retained if the aliased path does not specify a target.
+func expandPathAlias(p *gnmipb.Path, aliases map[string]*gnmipb.Path) *gnmipb.Path {
+	if p == nil || len(p.Elem) == 0 {
+		return p
+	}
+
+	ap, ok := aliases[p.Elem[0].Name]
+	if !ok {
+		return p
+	}
+
+	np := proto.Clone(ap).(*gnmipb.Path)
+	np.Elem = append(np.Elem, p.Elem[1:]...)
+	if np.Target == "" {
+		np.Target = p.Target
+	}
+	return np
 }
 
 // UpdateSetEqual compares the contents of a and b and returns true if they are
@@ -73,6 +326,42 @@ func UpdateSetEqual(a, b []*gnmipb.Update) bool {
 	return cmp.Equal(a, b, cmpopts.SortSlices(UpdateLess), cmpopts.EquateEmpty())
 }
 
+// UpdateSetDiff compares the sets of gNMI Updates want and got, and returns
+// the updates in want that are not in got (missing), and those in got that are
+// not in want (extra). Updates are considered to be the same when their path
+// and value are equal, ignoring the order of the keys of each path element,
+// and whether a path element with no keys has a nil or empty key map. Each
+// update is matched at most once, such that an update that appears more times
+// in one set than in the other is returned for each additional occurrence.
+// The order of the input slices is retained in the returned slices.
+func UpdateSetDiff(want, got []*gnmipb.Update) (missing, extra []*gnmipb.Update) {
+	unmatched := map[string]int{}
+	for _, u := range got {
+		unmatched[updateString(canonicalUpdate(u))]++
+	}
+
+	matched := map[string]int{}
+	for _, u := range want {
+		k := updateString(canonicalUpdate(u))
+		if unmatched[k] == 0 {
+			missing = append(missing, u)
+			continue
+		}
+		unmatched[k]--
+		matched[k]++
+	}
+
+	for _, u := range got {
+		k := updateString(canonicalUpdate(u))
+		if matched[k] > 0 {
+			matched[k]--
+			continue
+		}
+		extra = append(extra, u)
+	}
+	return missing, extra
+}
+
 // updateSet is an alias for a slice of gNMI Update messages.
 type updateSet []*gnmipb.Update
 
@@ -81,6 +370,14 @@ func (u updateSet) Len() int           { return len(u) }
 func (u updateSet) Less(i, j int) bool { return UpdateLess(u[i], u[j]) }
 func (u updateSet) Swap(i, j int)      { u[i], u[j] = u[j], u[i] }
 
+// notificationSet is an alias for a slice of gNMI Notification messages.
+type notificationSet []*gnmipb.Notification
+
+// Len, Less, and Swap implement the sort.Interface interface.
+func (n notificationSet) Len() int           { return len(n) }
+func (n notificationSet) Less(i, j int) bool { return NotificationLess(n[i], n[j]) }
+func (n notificationSet) Swap(i, j int)      { n[i], n[j] = n[j], n[i] }
+
 // pathSet is an alias for a slice of gNMI Path messages.
 type pathSet []*gnmipb.Path
 
@@ -89,23 +386,72 @@ func (p pathSet) Len() int           { return len(p) }
 func (p pathSet) Less(i, j int) bool { return PathLess(p[i], p[j]) }
 func (p pathSet) Swap(i, j int)      { p[i], p[j] = p[j], p[i] }
 
+// DuplicatePaths returns the set of paths that are referenced more than once
+// within the gNMI Notification n - either by more than one update, or by both
+// an update and a delete. Paths are considered equal when neither is less than
+// the other according to PathLess. Each duplicated path is returned once, and
+// the returned slice is sorted using PathLess. If there are no duplicated paths,
+// nil is returned.
+func DuplicatePaths(n *gnmipb.Notification) []*gnmipb.Path {
+	var paths pathSet
+	for _, u := range n.GetUpdate() {
+		paths = append(paths, u.GetPath())
+	}
+	paths = append(paths, n.GetDelete()...)
+	sort.Sort(paths)
+
+	var dups []*gnmipb.Path
+	for i := 1; i < len(paths); i++ {
+		if PathLess(paths[i-1], paths[i]) {
+			continue
+		}
+		// Only record the path once, regardless of the number of times that
+		// it is duplicated.
+		if len(dups) != 0 && !PathLess(dups[len(dups)-1], paths[i]) {
+			continue
+		}
+		dups = append(dups, paths[i])
+	}
+	return dups
+}
+
+// GetUpdateValue returns the value of the update within the gNMI Notification
+// n whose absolute path - formed by appending the update's path to the prefix
+// of n - is equal to the path p. Paths are considered equal when neither is
+// less than the other according to PathLess. The returned bool indicates
+// whether such an update was found. If more than one update has the path p,
+// the value of the first is returned.
+func GetUpdateValue(n *gnmipb.Notification, p *gnmipb.Path) (*gnmipb.TypedValue, bool) {
+	for _, u := range n.GetUpdate() {
+		up := joinPaths(n.GetPrefix(), u.GetPath())
+		if !PathLess(up, p) && !PathLess(p, up) {
+			return u.GetVal(), true
+		}
+	}
+	return nil, false
+}
+
 // NotificationLess compares the two notifications a and b, returning true if
 // a is less than b, and false if not. Less is defined by:
 //  - Comparing the timestamp.
 //  - If equal timestamps, comparing the prefix using PathLess.
-//  - If equal prefixes, comparing the Updates using UpdateLess.
+//  - If equal prefixes, comparing the alias.
+//  - If equal aliases, comparing the Updates using UpdateLess.
 //  - If equal updates, comparing the Deletes using deleteLess.
+//  - If equal deletes, comparing the Atomic field, such that non-atomic
+//    notifications are less than atomic ones.
 // If all fields are equal, the function returns false to ensure that the
 // irreflexive property required by cmpopts.SortSlices is implemented.
 func NotificationLess(a, b *gnmipb.Notification) bool {
+	// A nil notification is less than any non-nil notification. Two nil
+	// notifications are equal, and hence neither is less than the other, such
+	// that the irreflexive property required by cmpopts is met.
 	switch {
-	case a == nil && b != nil:
-		return true
 	case a == nil && b == nil:
-		// Ensure notification less meets the irreflexive property required by
-		// cmpopts.
 		return false
-	case b == nil && a != nil:
+	case a == nil:
+		return true
+	case b == nil:
 		return false
 	}
 
@@ -121,6 +467,10 @@ func NotificationLess(a, b *gnmipb.Notification) bool {
 		return PathLess(a.Prefix, b.Prefix)
 	}
 
+	if a.Alias != b.Alias {
+		return a.Alias < b.Alias
+	}
+
 	if !cmp.Equal(a.Update, b.Update, cmpopts.SortSlices(UpdateLess), cmpopts.EquateEmpty()) {
 		if len(a.Update) < len(b.Update) {
 			return true
@@ -134,11 +484,9 @@ func NotificationLess(a, b *gnmipb.Notification) bool {
 		sort.Sort(updateSet(sortedA.Update))
 		sort.Sort(updateSet(sortedB.Update))
 
-		for _, uA := range sortedA.Update {
-			for _, uB := range sortedB.Update {
-				if !proto.Equal(uA, uB) {
-					return UpdateLess(uA, uB)
-				}
+		for i, uA := range sortedA.Update {
+			if uB := sortedB.Update[i]; !proto.Equal(uA, uB) {
+				return UpdateLess(uA, uB)
 			}
 		}
 	}
@@ -157,16 +505,18 @@ func NotificationLess(a, b *gnmipb.Notification) bool {
 		sort.Sort(pathSet(sortedA.Delete))
 		sort.Sort(pathSet(sortedB.Delete))
 
-		for _, dA := range sortedA.Delete {
-			for _, dB := range sortedB.Delete {
-				if !proto.Equal(dA, dB) {
-					return PathLess(dA, dB)
-				}
+		for i, dA := range sortedA.Delete {
+			if dB := sortedB.Delete[i]; !proto.Equal(dA, dB) {
+				return PathLess(dA, dB)
 			}
 		}
 	}
 
-	return true
+	if a.Atomic != b.Atomic {
+		return !a.Atomic
+	}
+
+	return false
 }
 
 // UpdateLess compares two gNMI Update messages and returns true if a < b.
@@ -221,9 +571,7 @@ func PathLess(a, b *gnmipb.Path) bool {
 			return ae.Name < be.Name
 		}
 
-		aKeys, bKeys := stringKeys(ae.Key), stringKeys(be.Key)
-		sort.Strings(aKeys)
-		sort.Strings(bKeys)
+		aKeys, bKeys := sortedKeys(ae.Key), sortedKeys(be.Key)
 
 		if len(aKeys) != len(bKeys) {
 			// Paths with more keys are considered less than paths
@@ -232,17 +580,19 @@ func PathLess(a, b *gnmipb.Path) bool {
 		}
 
 		for j := 0; j < len(aKeys); j++ {
-			ak, bk := aKeys[j], bKeys[j]
-			if ak != bk {
+			if ak, bk := aKeys[j], bKeys[j]; ak != bk {
 				// If the sorted list of keys is not equal, then use string
 				// comparison between the key names.
 				return ak < bk
 			}
+		}
 
-			av, bv := ae.Key[ak], be.Key[bk]
-			if av != bv {
-				// If the key names match, use the value of the key to determine
-				// equality.
+		// The key names are identical, hence the values of each key are
+		// looked up by name in both elements, in the sorted order of the
+		// names, such that the comparison is independent of the order of
+		// the maps.
+		for _, k := range aKeys {
+			if av, bv := ae.Key[k], be.Key[k]; av != bv {
 				return av < bv
 			}
 		}
@@ -271,12 +621,101 @@ func PathLess(a, b *gnmipb.Path) bool {
 	return false
 }
 
-// stringKeys returns a slice of the keys of the supplied map m.
-func stringKeys(m map[string]string) []string {
+// PathIsPrefix determines whether the gNMI Path prefix is a prefix of the gNMI
+// Path p, such that p refers to prefix itself or to a node within its subtree.
+// The origins of the paths must be equal. Each element of prefix must have the
+// same name as the corresponding element of p, and any keys that are specified
+// within it must have the same value in p, unless the value is the wildcard
+// "*". Keys that are not specified within prefix match any value. Paths that
+// use only the deprecated element field are compared using its contents.
+func PathIsPrefix(prefix, p *gnmipb.Path) bool {
+	if prefix.GetOrigin() != p.GetOrigin() {
+		return false
+	}
+
+	if len(prefix.GetElem()) == 0 && len(p.GetElem()) == 0 {
+		pe, e := prefix.GetElement(), p.GetElement()
+		if len(pe) > len(e) {
+			return false
+		}
+		for i := range pe {
+			if pe[i] != e[i] {
+				return false
+			}
+		}
+		return true
+	}
+
+	pe, e := prefix.GetElem(), p.GetElem()
+	if len(pe) > len(e) {
+		return false
+	}
+	for i := range pe {
+		if pe[i].GetName() != e[i].GetName() {
+			return false
+		}
+		for k, v := range pe[i].GetKey() {
+			if ev, ok := e[i].GetKey()[k]; v != "*" && (!ok || ev != v) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// ValidatePath checks that the gNMI Path p is well-formed, returning an error
+// describing the first problem that is found if it is not. Each element of the
+// path must have a non-empty name, as must each of its keys. A path must not
+// specify both the elem field and the deprecated element field, and an origin
+// may only be specified for paths that use the elem field. Origins and targets
+// are names rather than paths, and hence must not contain "/" characters. A
+// nil path is valid, and refers to the root.
+func ValidatePath(p *gnmipb.Path) error {
+	if p == nil {
+		return nil
+	}
+
+	switch {
+	case len(p.Elem) != 0 && len(p.Element) != 0:
+		return fmt.Errorf("path %s specifies both the elem and element fields", PathString(p))
+	case p.Origin != "" && len(p.Element) != 0:
+		return fmt.Errorf("path %s specifies origin %q with the deprecated element field", PathString(p), p.Origin)
+	case strings.Contains(p.Origin, "/"):
+		return fmt.Errorf("path %s has invalid origin %q, must not contain /", PathString(p), p.Origin)
+	case strings.Contains(p.Target, "/"):
+		return fmt.Errorf("path %s has invalid target %q, must not contain /", PathString(p), p.Target)
+	}
+
+	for i, e := range p.Elem {
+		switch {
+		case e == nil:
+			// The path cannot be rendered using PathString, since it does not
+			// handle nil elements.
+			return fmt.Errorf("path has a nil element at index %d", i)
+		case e.Name == "":
+			return fmt.Errorf("path %s has an element with an empty name at index %d", PathString(p), i)
+		}
+		if _, ok := e.Key[""]; ok {
+			return fmt.Errorf("path %s has a key with an empty name in element %s at index %d", PathString(p), e.Name, i)
+		}
+	}
+
+	for i, e := range p.Element {
+		if e == "" {
+			return fmt.Errorf("path %s has an element with an empty name at index %d", PathString(p), i)
+		}
+	}
+	return nil
+}
+
+// sortedKeys returns a slice of the keys of the supplied map m, sorted such
+// that they are in a deterministic order.
+func sortedKeys(m map[string]string) []string {
 	ss := []string{}
 	for k := range m {
 		ss = append(ss, k)
 	}
+	sort.Strings(ss)
 	return ss
 }
 
@@ -284,7 +723,8 @@ func stringKeys(m map[string]string) []string {
 // it returns true, otherwise it returns false. It can be used when comparing
 // typed values for sorting purposes. If the value within the TypedValue message
 // is not directly comparable, it formats it as a string and compares the two
-// strings specified.
+// strings specified. Decimal64 values are compared using their exact numeric value, and bytes
+// and encoded protobuf values are compared lexicographically.
 //
 // If nil input is provided for either a or b, the nil value is considered
 // less than the non-nil value. If both values are nil, b is considered less
@@ -307,6 +747,32 @@ func typedValueLess(a, b *gnmipb.TypedValue) bool {
 		return typedValueStringLess(reflect.ValueOf(aVal), reflect.ValueOf(bVal), aType, bType)
 	}
 
+	// AnyVal values contain a pointer to the Any message, whose string
+	// representation is not deterministic, and hence they are compared using
+	// their type URL, followed by their serialised value.
+	if _, ok := aVal.(*gnmipb.TypedValue_AnyVal); ok {
+		aAny, bAny := a.GetAnyVal(), b.GetAnyVal()
+		if aAny.GetTypeUrl() != bAny.GetTypeUrl() {
+			return aAny.GetTypeUrl() < bAny.GetTypeUrl()
+		}
+		return bytes.Compare(aAny.GetValue(), bAny.GetValue()) < 0
+	}
+
+	switch av := aVal.(type) {
+	case *gnmipb.TypedValue_DecimalVal:
+		// Decimal64 values are compared using their exact rational value, such
+		// that values that are encoded with a differing number of digits and
+		// precision, but are numerically equal, are considered equal.
+		return decimalRat(av.DecimalVal).Cmp(decimalRat(b.GetDecimalVal())) < 0
+	case *gnmipb.TypedValue_BytesVal:
+		// Bytes values are compared lexicographically using their raw contents,
+		// rather than the string representation of the byte slice.
+		return bytes.Compare(av.BytesVal, b.GetBytesVal()) < 0
+	case *gnmipb.TypedValue_ProtoBytes:
+		// Encoded protobuf values are compared in the same way as bytes values.
+		return bytes.Compare(av.ProtoBytes, b.GetProtoBytes()) < 0
+	}
+
 	// Since a comparison method cannot return an error, we must handle all cases
 	// where the type is not a scalar type - we do this be reverting to using
 	// the string representation.
@@ -341,6 +807,14 @@ func typedValueLess(a, b *gnmipb.TypedValue) bool {
 	}
 }
 
+// decimalRat returns the exact value of the gNMI Decimal64 d, i.e., its
+// digits multiplied by 10^-precision, as a big.Rat, such that no precision is
+// lost, and large values do not overflow. A nil d is treated as zero.
+func decimalRat(d *gnmipb.Decimal64) *big.Rat {
+	denom := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(d.GetPrecision())), nil)
+	return new(big.Rat).SetFrac(big.NewInt(d.GetDigits()), denom)
+}
+
 // typedValueStringLess takes two gNMI TypedValue.Value fields as their reflect.Value
 // and reflect.Type representations and converts them to a string to compare them. It
 // returns the value of the string less-than between the stringified a and b.
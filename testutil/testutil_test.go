@@ -15,8 +15,13 @@
 package testutil
 
 import (
+	"math"
+	"strings"
 	"testing"
 
+	"github.com/golang/protobuf/proto"
+	anypb "github.com/golang/protobuf/ptypes/any"
+	"github.com/google/go-cmp/cmp"
 	gnmipb "github.com/openconfig/gnmi/proto/gnmi"
 )
 
@@ -177,6 +182,122 @@ func TestNotificationSetEqual(t *testing.T) {
 			}},
 		}},
 		want: true,
+	}, {
+		name: "nil and empty slices",
+		inA:  nil,
+		inB:  []*gnmipb.Notification{},
+		want: true,
+	}, {
+		name: "additional notification in b",
+		inA: []*gnmipb.Notification{{
+			Timestamp: 42,
+		}},
+		inB: []*gnmipb.Notification{{
+			Timestamp: 42,
+		}, {
+			Timestamp: 84,
+		}},
+		want: false,
+	}, {
+		name: "duplicated notification",
+		inA: []*gnmipb.Notification{{
+			Timestamp: 42,
+		}, {
+			Timestamp: 42,
+		}},
+		inB: []*gnmipb.Notification{{
+			Timestamp: 42,
+		}, {
+			Timestamp: 84,
+		}},
+		want: false,
+	}, {
+		name: "reordered notifications with the same timestamp and reordered updates",
+		inA: []*gnmipb.Notification{{
+			Timestamp: 42,
+			Update: []*gnmipb.Update{{
+				Path: mustPath("a"),
+				Val:  &gnmipb.TypedValue{Value: &gnmipb.TypedValue_StringVal{"one"}},
+			}, {
+				Path: mustPath("b"),
+				Val:  &gnmipb.TypedValue{Value: &gnmipb.TypedValue_StringVal{"two"}},
+			}},
+		}, {
+			Timestamp: 42,
+			Update: []*gnmipb.Update{{
+				Path: mustPath("c"),
+				Val:  &gnmipb.TypedValue{Value: &gnmipb.TypedValue_StringVal{"three"}},
+			}, {
+				Path: mustPath("d"),
+				Val:  &gnmipb.TypedValue{Value: &gnmipb.TypedValue_StringVal{"four"}},
+			}},
+			Delete: []*gnmipb.Path{mustPath("e"), mustPath("f")},
+		}},
+		inB: []*gnmipb.Notification{{
+			Timestamp: 42,
+			Update: []*gnmipb.Update{{
+				Path: mustPath("d"),
+				Val:  &gnmipb.TypedValue{Value: &gnmipb.TypedValue_StringVal{"four"}},
+			}, {
+				Path: mustPath("c"),
+				Val:  &gnmipb.TypedValue{Value: &gnmipb.TypedValue_StringVal{"three"}},
+			}},
+			Delete: []*gnmipb.Path{mustPath("f"), mustPath("e")},
+		}, {
+			Timestamp: 42,
+			Update: []*gnmipb.Update{{
+				Path: mustPath("b"),
+				Val:  &gnmipb.TypedValue{Value: &gnmipb.TypedValue_StringVal{"two"}},
+			}, {
+				Path: mustPath("a"),
+				Val:  &gnmipb.TypedValue{Value: &gnmipb.TypedValue_StringVal{"one"}},
+			}},
+		}},
+		want: true,
+	}, {
+		name: "updates moved across notifications",
+		inA: []*gnmipb.Notification{{
+			Timestamp: 42,
+			Update: []*gnmipb.Update{{
+				Path: mustPath("a"),
+				Val:  &gnmipb.TypedValue{Value: &gnmipb.TypedValue_StringVal{"one"}},
+			}, {
+				Path: mustPath("b"),
+				Val:  &gnmipb.TypedValue{Value: &gnmipb.TypedValue_StringVal{"two"}},
+			}},
+		}, {
+			Timestamp: 42,
+			Update: []*gnmipb.Update{{
+				Path: mustPath("c"),
+				Val:  &gnmipb.TypedValue{Value: &gnmipb.TypedValue_StringVal{"three"}},
+			}},
+		}},
+		inB: []*gnmipb.Notification{{
+			Timestamp: 42,
+			Update: []*gnmipb.Update{{
+				Path: mustPath("a"),
+				Val:  &gnmipb.TypedValue{Value: &gnmipb.TypedValue_StringVal{"one"}},
+			}},
+		}, {
+			Timestamp: 42,
+			Update: []*gnmipb.Update{{
+				Path: mustPath("c"),
+				Val:  &gnmipb.TypedValue{Value: &gnmipb.TypedValue_StringVal{"three"}},
+			}, {
+				Path: mustPath("b"),
+				Val:  &gnmipb.TypedValue{Value: &gnmipb.TypedValue_StringVal{"two"}},
+			}},
+		}},
+	}, {
+		name: "equal sets differing only in alias, reordered",
+		inA:  []*gnmipb.Notification{{Timestamp: 42, Alias: "#x"}, {Timestamp: 42, Alias: "#y"}},
+		inB:  []*gnmipb.Notification{{Timestamp: 42, Alias: "#y"}, {Timestamp: 42, Alias: "#x"}},
+		want: true,
+	}, {
+		name: "unequal sets differing only in alias",
+		inA:  []*gnmipb.Notification{{Timestamp: 42, Alias: "#x"}, {Timestamp: 42, Alias: "#y"}},
+		inB:  []*gnmipb.Notification{{Timestamp: 42, Alias: "#y"}, {Timestamp: 42, Alias: "#z"}},
+		want: false,
 	}}
 
 	for _, tt := range tests {
@@ -188,6 +309,306 @@ func TestNotificationSetEqual(t *testing.T) {
 	}
 }
 
+func TestGetResponseEqual(t *testing.T) {
+	notif := func(ts int64, name, val string) *gnmipb.Notification {
+		return &gnmipb.Notification{
+			Timestamp: ts,
+			Update: []*gnmipb.Update{{
+				Path: mustPath(name),
+				Val:  &gnmipb.TypedValue{Value: &gnmipb.TypedValue_StringVal{val}},
+			}},
+		}
+	}
+
+	tests := []struct {
+		name string
+		inA  *gnmipb.GetResponse
+		inB  *gnmipb.GetResponse
+		want bool
+	}{{
+		name: "both nil",
+		want: true,
+	}, {
+		name: "one nil",
+		inA:  &gnmipb.GetResponse{},
+		want: false,
+	}, {
+		name: "equal responses",
+		inA:  &gnmipb.GetResponse{Notification: []*gnmipb.Notification{notif(1, "a", "one"), notif(2, "b", "two")}},
+		inB:  &gnmipb.GetResponse{Notification: []*gnmipb.Notification{notif(1, "a", "one"), notif(2, "b", "two")}},
+		want: true,
+	}, {
+		name: "reordered notifications",
+		inA:  &gnmipb.GetResponse{Notification: []*gnmipb.Notification{notif(1, "a", "one"), notif(2, "b", "two")}},
+		inB:  &gnmipb.GetResponse{Notification: []*gnmipb.Notification{notif(2, "b", "two"), notif(1, "a", "one")}},
+		want: true,
+	}, {
+		name: "reordered notifications differing only in alias",
+		inA:  &gnmipb.GetResponse{Notification: []*gnmipb.Notification{{Timestamp: 1, Alias: "#x"}, {Timestamp: 1, Alias: "#y"}}},
+		inB:  &gnmipb.GetResponse{Notification: []*gnmipb.Notification{{Timestamp: 1, Alias: "#y"}, {Timestamp: 1, Alias: "#x"}}},
+		want: true,
+	}, {
+		name: "differing notifications",
+		inA:  &gnmipb.GetResponse{Notification: []*gnmipb.Notification{notif(1, "a", "one")}},
+		inB:  &gnmipb.GetResponse{Notification: []*gnmipb.Notification{notif(1, "a", "two")}},
+		want: false,
+	}, {
+		name: "extra notification",
+		inA:  &gnmipb.GetResponse{Notification: []*gnmipb.Notification{notif(1, "a", "one")}},
+		inB:  &gnmipb.GetResponse{Notification: []*gnmipb.Notification{notif(1, "a", "one"), notif(2, "b", "two")}},
+		want: false,
+	}, {
+		name: "equal errors",
+		inA: &gnmipb.GetResponse{
+			Notification: []*gnmipb.Notification{notif(1, "a", "one"), notif(2, "b", "two")},
+			Error:        &gnmipb.Error{Code: 5, Message: "not found"},
+		},
+		inB: &gnmipb.GetResponse{
+			Notification: []*gnmipb.Notification{notif(2, "b", "two"), notif(1, "a", "one")},
+			Error:        &gnmipb.Error{Code: 5, Message: "not found"},
+		},
+		want: true,
+	}, {
+		name: "differing error codes",
+		inA: &gnmipb.GetResponse{
+			Notification: []*gnmipb.Notification{notif(1, "a", "one")},
+			Error:        &gnmipb.Error{Code: 5, Message: "not found"},
+		},
+		inB: &gnmipb.GetResponse{
+			Notification: []*gnmipb.Notification{notif(1, "a", "one")},
+			Error:        &gnmipb.Error{Code: 13, Message: "not found"},
+		},
+		want: false,
+	}, {
+		name: "error in only one response",
+		inA: &gnmipb.GetResponse{
+			Notification: []*gnmipb.Notification{notif(1, "a", "one")},
+		},
+		inB: &gnmipb.GetResponse{
+			Notification: []*gnmipb.Notification{notif(1, "a", "one")},
+			Error:        &gnmipb.Error{Message: "internal error"},
+		},
+		want: false,
+	}}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := GetResponseEqual(tt.inA, tt.inB); got != tt.want {
+				t.Fatalf("GetResponseEqual(%v, %v): did not get expected result, got: %v, want: %v", tt.inA, tt.inB, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSubscribeResponseEqual(t *testing.T) {
+	update := func(n *gnmipb.Notification) *gnmipb.SubscribeResponse {
+		return &gnmipb.SubscribeResponse{Response: &gnmipb.SubscribeResponse_Update{n}}
+	}
+	sync := func(v bool) *gnmipb.SubscribeResponse {
+		return &gnmipb.SubscribeResponse{Response: &gnmipb.SubscribeResponse_SyncResponse{v}}
+	}
+	errResp := func(code uint32) *gnmipb.SubscribeResponse {
+		return &gnmipb.SubscribeResponse{Response: &gnmipb.SubscribeResponse_Error{&gnmipb.Error{Code: code}}}
+	}
+	strUpdate := func(name, val string) *gnmipb.Update {
+		return &gnmipb.Update{
+			Path: mustPath(name),
+			Val:  &gnmipb.TypedValue{Value: &gnmipb.TypedValue_StringVal{val}},
+		}
+	}
+
+	tests := []struct {
+		name string
+		inA  *gnmipb.SubscribeResponse
+		inB  *gnmipb.SubscribeResponse
+		want bool
+	}{{
+		name: "both nil",
+		want: true,
+	}, {
+		name: "one nil",
+		inB:  sync(true),
+		want: false,
+	}, {
+		name: "update and update: equal with reordered updates",
+		inA: update(&gnmipb.Notification{
+			Timestamp: 42,
+			Update:    []*gnmipb.Update{strUpdate("a", "one"), strUpdate("b", "two")},
+			Delete:    []*gnmipb.Path{mustPath("c"), mustPath("d")},
+		}),
+		inB: update(&gnmipb.Notification{
+			Timestamp: 42,
+			Update:    []*gnmipb.Update{strUpdate("b", "two"), strUpdate("a", "one")},
+			Delete:    []*gnmipb.Path{mustPath("d"), mustPath("c")},
+		}),
+		want: true,
+	}, {
+		name: "update and update: differing values",
+		inA:  update(&gnmipb.Notification{Update: []*gnmipb.Update{strUpdate("a", "one")}}),
+		inB:  update(&gnmipb.Notification{Update: []*gnmipb.Update{strUpdate("a", "two")}}),
+		want: false,
+	}, {
+		name: "update and update: differing timestamps",
+		inA:  update(&gnmipb.Notification{Timestamp: 1}),
+		inB:  update(&gnmipb.Notification{Timestamp: 2}),
+		want: false,
+	}, {
+		name: "sync and sync: equal",
+		inA:  sync(true),
+		inB:  sync(true),
+		want: true,
+	}, {
+		name: "sync and sync: differing",
+		inA:  sync(true),
+		inB:  sync(false),
+		want: false,
+	}, {
+		name: "error and error: equal",
+		inA:  errResp(5),
+		inB:  errResp(5),
+		want: true,
+	}, {
+		name: "error and error: differing",
+		inA:  errResp(5),
+		inB:  errResp(13),
+		want: false,
+	}, {
+		name: "update and sync",
+		inA:  update(&gnmipb.Notification{}),
+		inB:  sync(true),
+		want: false,
+	}, {
+		name: "sync and update",
+		inA:  sync(false),
+		inB:  update(&gnmipb.Notification{}),
+		want: false,
+	}, {
+		name: "update and error",
+		inA:  update(&gnmipb.Notification{}),
+		inB:  errResp(0),
+		want: false,
+	}, {
+		name: "sync and error",
+		inA:  sync(false),
+		inB:  errResp(0),
+		want: false,
+	}, {
+		name: "no response and sync",
+		inA:  &gnmipb.SubscribeResponse{},
+		inB:  sync(false),
+		want: false,
+	}}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := SubscribeResponseEqual(tt.inA, tt.inB); got != tt.want {
+				t.Fatalf("SubscribeResponseEqual(%v, %v): did not get expected result, got: %v, want: %v", tt.inA, tt.inB, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMergeSetRequests(t *testing.T) {
+	strUpd := func(p *gnmipb.Path, s string) *gnmipb.Update {
+		return &gnmipb.Update{Path: p, Val: &gnmipb.TypedValue{Value: &gnmipb.TypedValue_StringVal{StringVal: s}}}
+	}
+
+	tests := []struct {
+		name    string
+		in      []*gnmipb.SetRequest
+		want    *gnmipb.SetRequest
+		wantErr bool
+	}{{
+		name: "no requests",
+		want: &gnmipb.SetRequest{},
+	}, {
+		name: "compatible prefixes",
+		in: []*gnmipb.SetRequest{{
+			Prefix: mustPath("a"),
+			Update: []*gnmipb.Update{strUpd(mustPath("d"), "one")},
+			Delete: []*gnmipb.Path{mustPath("c")},
+		}, {
+			Prefix:  mustPath("a"),
+			Update:  []*gnmipb.Update{strUpd(mustPath("b"), "two")},
+			Replace: []*gnmipb.Update{strUpd(mustPath("e"), "three")},
+		}},
+		want: &gnmipb.SetRequest{
+			Prefix:  mustPath("a"),
+			Delete:  []*gnmipb.Path{mustPath("c")},
+			Replace: []*gnmipb.Update{strUpd(mustPath("e"), "three")},
+			Update:  []*gnmipb.Update{strUpd(mustPath("b"), "two"), strUpd(mustPath("d"), "one")},
+		},
+	}, {
+		name: "nil and empty prefixes",
+		in: []*gnmipb.SetRequest{{
+			Update: []*gnmipb.Update{strUpd(mustPath("a"), "one")},
+		}, {
+			Prefix: &gnmipb.Path{},
+			Update: []*gnmipb.Update{strUpd(mustPath("b"), "two")},
+		}},
+		want: &gnmipb.SetRequest{
+			Update: []*gnmipb.Update{strUpd(mustPath("a"), "one"), strUpd(mustPath("b"), "two")},
+		},
+	}, {
+		name: "last update wins",
+		in: []*gnmipb.SetRequest{{
+			Update: []*gnmipb.Update{strUpd(mustPath("a"), "one")},
+		}, {
+			Update: []*gnmipb.Update{strUpd(mustPath("a"), "two")},
+		}},
+		want: &gnmipb.SetRequest{
+			Update: []*gnmipb.Update{strUpd(mustPath("a"), "two")},
+		},
+	}, {
+		name: "delete supersedes earlier replace and update",
+		in: []*gnmipb.SetRequest{{
+			Replace: []*gnmipb.Update{strUpd(mustPath("a"), "one")},
+			Update:  []*gnmipb.Update{strUpd(mustPath("b"), "two")},
+		}, {
+			Delete: []*gnmipb.Path{mustPath("a"), mustPath("b")},
+		}},
+		want: &gnmipb.SetRequest{
+			Delete: []*gnmipb.Path{mustPath("a"), mustPath("b")},
+		},
+	}, {
+		name: "replace supersedes earlier update",
+		in: []*gnmipb.SetRequest{{
+			Update: []*gnmipb.Update{strUpd(mustPath("a"), "one")},
+		}, {
+			Replace: []*gnmipb.Update{strUpd(mustPath("a"), "two")},
+		}},
+		want: &gnmipb.SetRequest{
+			Replace: []*gnmipb.Update{strUpd(mustPath("a"), "two")},
+		},
+	}, {
+		name: "incompatible prefixes",
+		in: []*gnmipb.SetRequest{{
+			Prefix: mustPath("a"),
+		}, {
+			Prefix: mustPath("b"),
+		}},
+		wantErr: true,
+	}, {
+		name: "incompatible nil and non-empty prefixes",
+		in: []*gnmipb.SetRequest{{}, {
+			Prefix: mustPath("a"),
+		}},
+		wantErr: true,
+	}}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := MergeSetRequests(tt.in)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("MergeSetRequests(%v): did not get expected error status, got: %v, wantErr: %v", tt.in, err, tt.wantErr)
+			}
+			if !proto.Equal(got, tt.want) {
+				t.Fatalf("MergeSetRequests(%v): did not get expected request, got: %v, want: %v", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
 func TestUpdateSetEqual(t *testing.T) {
 	tests := []struct {
 		name string
@@ -362,6 +783,95 @@ func TestUpdateSetEqual(t *testing.T) {
 	}
 }
 
+func TestUpdateSetDiff(t *testing.T) {
+	stringUpdate := func(v string, elems ...*gnmipb.PathElem) *gnmipb.Update {
+		return &gnmipb.Update{
+			Path: &gnmipb.Path{Elem: elems},
+			Val:  &gnmipb.TypedValue{Value: &gnmipb.TypedValue_StringVal{v}},
+		}
+	}
+
+	tests := []struct {
+		name        string
+		inWant      []*gnmipb.Update
+		inGot       []*gnmipb.Update
+		wantMissing []*gnmipb.Update
+		wantExtra   []*gnmipb.Update
+	}{{
+		name: "empty sets",
+	}, {
+		name:   "equal sets in different order",
+		inWant: []*gnmipb.Update{stringUpdate("a", &gnmipb.PathElem{Name: "a"}), stringUpdate("b", &gnmipb.PathElem{Name: "b"})},
+		inGot:  []*gnmipb.Update{stringUpdate("b", &gnmipb.PathElem{Name: "b"}), stringUpdate("a", &gnmipb.PathElem{Name: "a"})},
+	}, {
+		name: "equal sets with multiple keys and empty key maps",
+		inWant: []*gnmipb.Update{
+			stringUpdate("one", &gnmipb.PathElem{Name: "list", Key: map[string]string{"k1": "v1", "k2": "v2"}}, &gnmipb.PathElem{Name: "leaf"}),
+		},
+		inGot: []*gnmipb.Update{
+			stringUpdate("one", &gnmipb.PathElem{Name: "list", Key: map[string]string{"k2": "v2", "k1": "v1"}}, &gnmipb.PathElem{Name: "leaf", Key: map[string]string{}}),
+		},
+	}, {
+		name: "overlapping sets",
+		inWant: []*gnmipb.Update{
+			stringUpdate("one", &gnmipb.PathElem{Name: "list", Key: map[string]string{"k1": "v1", "k2": "v2"}}),
+			stringUpdate("two", &gnmipb.PathElem{Name: "b"}),
+			stringUpdate("three", &gnmipb.PathElem{Name: "c"}),
+		},
+		inGot: []*gnmipb.Update{
+			stringUpdate("three", &gnmipb.PathElem{Name: "c"}),
+			stringUpdate("one", &gnmipb.PathElem{Name: "list", Key: map[string]string{"k2": "v2", "k1": "v1"}}),
+			stringUpdate("TWO", &gnmipb.PathElem{Name: "b"}),
+			stringUpdate("four", &gnmipb.PathElem{Name: "d"}),
+		},
+		wantMissing: []*gnmipb.Update{
+			stringUpdate("two", &gnmipb.PathElem{Name: "b"}),
+		},
+		wantExtra: []*gnmipb.Update{
+			stringUpdate("TWO", &gnmipb.PathElem{Name: "b"}),
+			stringUpdate("four", &gnmipb.PathElem{Name: "d"}),
+		},
+	}, {
+		name: "different key values",
+		inWant: []*gnmipb.Update{
+			stringUpdate("one", &gnmipb.PathElem{Name: "list", Key: map[string]string{"k1": "v1", "k2": "v2"}}),
+		},
+		inGot: []*gnmipb.Update{
+			stringUpdate("one", &gnmipb.PathElem{Name: "list", Key: map[string]string{"k1": "v2", "k2": "v1"}}),
+		},
+		wantMissing: []*gnmipb.Update{
+			stringUpdate("one", &gnmipb.PathElem{Name: "list", Key: map[string]string{"k1": "v1", "k2": "v2"}}),
+		},
+		wantExtra: []*gnmipb.Update{
+			stringUpdate("one", &gnmipb.PathElem{Name: "list", Key: map[string]string{"k1": "v2", "k2": "v1"}}),
+		},
+	}, {
+		name: "repeated update",
+		inWant: []*gnmipb.Update{
+			stringUpdate("one", &gnmipb.PathElem{Name: "a"}),
+		},
+		inGot: []*gnmipb.Update{
+			stringUpdate("one", &gnmipb.PathElem{Name: "a"}),
+			stringUpdate("one", &gnmipb.PathElem{Name: "a"}),
+		},
+		wantExtra: []*gnmipb.Update{
+			stringUpdate("one", &gnmipb.PathElem{Name: "a"}),
+		},
+	}}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotMissing, gotExtra := UpdateSetDiff(tt.inWant, tt.inGot)
+			if diff := cmp.Diff(gotMissing, tt.wantMissing, cmp.Comparer(proto.Equal)); diff != "" {
+				t.Errorf("UpdateSetDiff(%v, %v): did not get expected missing updates, diff(-got,+want):\n%s", tt.inWant, tt.inGot, diff)
+			}
+			if diff := cmp.Diff(gotExtra, tt.wantExtra, cmp.Comparer(proto.Equal)); diff != "" {
+				t.Errorf("UpdateSetDiff(%v, %v): did not get expected extra updates, diff(-got,+want):\n%s", tt.inWant, tt.inGot, diff)
+			}
+		})
+	}
+}
+
 func TestNotificationLess(t *testing.T) {
 	tests := []struct {
 		name string
@@ -494,7 +1004,7 @@ func TestNotificationLess(t *testing.T) {
 		},
 		want: false,
 	}, {
-		name: "update: a < b multiple updates",
+		name: "update: b < a multiple updates",
 		inA: &gnmipb.Notification{
 			Update: []*gnmipb.Update{{
 				Path: &gnmipb.Path{
@@ -525,9 +1035,9 @@ func TestNotificationLess(t *testing.T) {
 				},
 			}},
 		},
-		want: true,
+		want: false,
 	}, {
-		name: "update: a < b multiple updates, different order",
+		name: "update: b < a multiple updates, different order",
 		inA: &gnmipb.Notification{
 			Update: []*gnmipb.Update{{
 				Path: &gnmipb.Path{
@@ -558,7 +1068,7 @@ func TestNotificationLess(t *testing.T) {
 				},
 			}},
 		},
-		want: true,
+		want: false,
 	}, {
 		name: "delete: a < b, length",
 		inA: &gnmipb.Notification{
@@ -640,7 +1150,7 @@ func TestNotificationLess(t *testing.T) {
 		},
 		want: false,
 	}, {
-		name: "delete: a < b - multiple paths",
+		name: "delete: b < a - multiple paths",
 		inA: &gnmipb.Notification{
 			Delete: []*gnmipb.Path{{
 				Elem: []*gnmipb.PathElem{{
@@ -669,7 +1179,7 @@ func TestNotificationLess(t *testing.T) {
 				}},
 			}},
 		},
-		want: true,
+		want: false,
 	}, {
 		name: "delete: b < a, multiple paths",
 		inA: &gnmipb.Notification{
@@ -702,18 +1212,175 @@ func TestNotificationLess(t *testing.T) {
 		},
 		want: false,
 	}, {
-		name: "nil: both nil",
-		want: false,
-	}, {
-		name: "nil: a nil, b not",
-		inB:  &gnmipb.Notification{Timestamp: 42},
+		name: "update: a < b, first sorted update equal",
+		inA: &gnmipb.Notification{
+			Update: []*gnmipb.Update{
+				{Path: &gnmipb.Path{Elem: []*gnmipb.PathElem{{Name: "a"}}}},
+				{Path: &gnmipb.Path{Elem: []*gnmipb.PathElem{{Name: "b"}}}},
+			},
+		},
+		inB: &gnmipb.Notification{
+			Update: []*gnmipb.Update{
+				{Path: &gnmipb.Path{Elem: []*gnmipb.PathElem{{Name: "c"}}}},
+				{Path: &gnmipb.Path{Elem: []*gnmipb.PathElem{{Name: "a"}}}},
+			},
+		},
 		want: true,
 	}, {
-		name: "nil: a not, b nil",
-		inA:  &gnmipb.Notification{Timestamp: 42},
-		want: false,
-	}}
-
+		name: "update: b < a, first sorted update equal",
+		inA: &gnmipb.Notification{
+			Update: []*gnmipb.Update{
+				{Path: &gnmipb.Path{Elem: []*gnmipb.PathElem{{Name: "c"}}}},
+				{Path: &gnmipb.Path{Elem: []*gnmipb.PathElem{{Name: "a"}}}},
+			},
+		},
+		inB: &gnmipb.Notification{
+			Update: []*gnmipb.Update{
+				{Path: &gnmipb.Path{Elem: []*gnmipb.PathElem{{Name: "a"}}}},
+				{Path: &gnmipb.Path{Elem: []*gnmipb.PathElem{{Name: "b"}}}},
+			},
+		},
+		want: false,
+	}, {
+		name: "delete: a < b, first sorted path equal",
+		inA: &gnmipb.Notification{
+			Delete: []*gnmipb.Path{
+				{Elem: []*gnmipb.PathElem{{Name: "a"}}},
+				{Elem: []*gnmipb.PathElem{{Name: "b"}}},
+			},
+		},
+		inB: &gnmipb.Notification{
+			Delete: []*gnmipb.Path{
+				{Elem: []*gnmipb.PathElem{{Name: "c"}}},
+				{Elem: []*gnmipb.PathElem{{Name: "a"}}},
+			},
+		},
+		want: true,
+	}, {
+		name: "delete: b < a, first sorted path equal",
+		inA: &gnmipb.Notification{
+			Delete: []*gnmipb.Path{
+				{Elem: []*gnmipb.PathElem{{Name: "c"}}},
+				{Elem: []*gnmipb.PathElem{{Name: "a"}}},
+			},
+		},
+		inB: &gnmipb.Notification{
+			Delete: []*gnmipb.Path{
+				{Elem: []*gnmipb.PathElem{{Name: "a"}}},
+				{Elem: []*gnmipb.PathElem{{Name: "b"}}},
+			},
+		},
+		want: false,
+	}, {
+		name: "atomic: a non-atomic, b atomic",
+		inA: &gnmipb.Notification{
+			Timestamp: 42,
+			Update: []*gnmipb.Update{{
+				Path: &gnmipb.Path{Elem: []*gnmipb.PathElem{{Name: "one"}}},
+				Val:  &gnmipb.TypedValue{Value: &gnmipb.TypedValue_StringVal{StringVal: "foo"}},
+			}},
+		},
+		inB: &gnmipb.Notification{
+			Timestamp: 42,
+			Update: []*gnmipb.Update{{
+				Path: &gnmipb.Path{Elem: []*gnmipb.PathElem{{Name: "one"}}},
+				Val:  &gnmipb.TypedValue{Value: &gnmipb.TypedValue_StringVal{StringVal: "foo"}},
+			}},
+			Atomic: true,
+		},
+		want: true,
+	}, {
+		name: "atomic: a atomic, b non-atomic",
+		inA: &gnmipb.Notification{
+			Timestamp: 42,
+			Update: []*gnmipb.Update{{
+				Path: &gnmipb.Path{Elem: []*gnmipb.PathElem{{Name: "one"}}},
+				Val:  &gnmipb.TypedValue{Value: &gnmipb.TypedValue_StringVal{StringVal: "foo"}},
+			}},
+			Atomic: true,
+		},
+		inB: &gnmipb.Notification{
+			Timestamp: 42,
+			Update: []*gnmipb.Update{{
+				Path: &gnmipb.Path{Elem: []*gnmipb.PathElem{{Name: "one"}}},
+				Val:  &gnmipb.TypedValue{Value: &gnmipb.TypedValue_StringVal{StringVal: "foo"}},
+			}},
+		},
+		want: false,
+	}, {
+		name: "equal updates in a different order: a, b",
+		inA: &gnmipb.Notification{
+			Timestamp: 42,
+			Update: []*gnmipb.Update{{
+				Path: &gnmipb.Path{Elem: []*gnmipb.PathElem{{Name: "one"}}},
+				Val:  &gnmipb.TypedValue{Value: &gnmipb.TypedValue_StringVal{StringVal: "foo"}},
+			}, {
+				Path: &gnmipb.Path{Elem: []*gnmipb.PathElem{{Name: "two"}}},
+				Val:  &gnmipb.TypedValue{Value: &gnmipb.TypedValue_StringVal{StringVal: "foo"}},
+			}},
+		},
+		inB: &gnmipb.Notification{
+			Timestamp: 42,
+			Update: []*gnmipb.Update{{
+				Path: &gnmipb.Path{Elem: []*gnmipb.PathElem{{Name: "two"}}},
+				Val:  &gnmipb.TypedValue{Value: &gnmipb.TypedValue_StringVal{StringVal: "foo"}},
+			}, {
+				Path: &gnmipb.Path{Elem: []*gnmipb.PathElem{{Name: "one"}}},
+				Val:  &gnmipb.TypedValue{Value: &gnmipb.TypedValue_StringVal{StringVal: "foo"}},
+			}},
+		},
+		want: false,
+	}, {
+		name: "equal updates in a different order: b, a",
+		inA: &gnmipb.Notification{
+			Timestamp: 42,
+			Update: []*gnmipb.Update{{
+				Path: &gnmipb.Path{Elem: []*gnmipb.PathElem{{Name: "two"}}},
+				Val:  &gnmipb.TypedValue{Value: &gnmipb.TypedValue_StringVal{StringVal: "foo"}},
+			}, {
+				Path: &gnmipb.Path{Elem: []*gnmipb.PathElem{{Name: "one"}}},
+				Val:  &gnmipb.TypedValue{Value: &gnmipb.TypedValue_StringVal{StringVal: "foo"}},
+			}},
+		},
+		inB: &gnmipb.Notification{
+			Timestamp: 42,
+			Update: []*gnmipb.Update{{
+				Path: &gnmipb.Path{Elem: []*gnmipb.PathElem{{Name: "one"}}},
+				Val:  &gnmipb.TypedValue{Value: &gnmipb.TypedValue_StringVal{StringVal: "foo"}},
+			}, {
+				Path: &gnmipb.Path{Elem: []*gnmipb.PathElem{{Name: "two"}}},
+				Val:  &gnmipb.TypedValue{Value: &gnmipb.TypedValue_StringVal{StringVal: "foo"}},
+			}},
+		},
+		want: false,
+	}, {
+		name: "differing aliases: a < b",
+		inA:  &gnmipb.Notification{Timestamp: 42, Alias: "#x"},
+		inB:  &gnmipb.Notification{Timestamp: 42, Alias: "#y"},
+		want: true,
+	}, {
+		name: "differing aliases: b < a",
+		inA:  &gnmipb.Notification{Timestamp: 42, Alias: "#y"},
+		inB:  &gnmipb.Notification{Timestamp: 42, Alias: "#x"},
+		want: false,
+	}, {
+		name: "nil: both nil",
+		want: false,
+	}, {
+		name: "nil: a nil, b not",
+		inB:  &gnmipb.Notification{Timestamp: 42},
+		want: true,
+	}, {
+		name: "nil: a not, b nil",
+		inA:  &gnmipb.Notification{Timestamp: 42},
+		want: false,
+	}, {
+		name: "nil: neither nil",
+		inA:  &gnmipb.Notification{Timestamp: 41},
+		inB:  &gnmipb.Notification{Timestamp: 42},
+		want: true,
+	}}
+
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			if got := NotificationLess(tt.inA, tt.inB); got != tt.want {
@@ -908,6 +1575,29 @@ func TestUpdateLess(t *testing.T) {
 			Duplicates: 0,
 		},
 		want: false,
+	}, {
+		name: "proto bytes values: a < b",
+		inA: &gnmipb.Update{
+			Path: &gnmipb.Path{
+				Elem: []*gnmipb.PathElem{{
+					Name: "one",
+				}},
+			},
+			Val: &gnmipb.TypedValue{
+				Value: &gnmipb.TypedValue_ProtoBytes{[]byte{0x09}},
+			},
+		},
+		inB: &gnmipb.Update{
+			Path: &gnmipb.Path{
+				Elem: []*gnmipb.PathElem{{
+					Name: "one",
+				}},
+			},
+			Val: &gnmipb.TypedValue{
+				Value: &gnmipb.TypedValue_ProtoBytes{[]byte{0x0a}},
+			},
+		},
+		want: true,
 	}}
 
 	for _, tt := range tests {
@@ -1167,6 +1857,36 @@ func TestPathLess(t *testing.T) {
 			Origin: "a",
 		},
 		want: false,
+	}, {
+		name: "multiple keys: a < b based on value of first sorted key",
+		inA: &gnmipb.Path{
+			Elem: []*gnmipb.PathElem{{
+				Name: "list",
+				Key:  map[string]string{"z": "2", "a": "1"},
+			}},
+		},
+		inB: &gnmipb.Path{
+			Elem: []*gnmipb.PathElem{{
+				Name: "list",
+				Key:  map[string]string{"a": "2", "z": "1"},
+			}},
+		},
+		want: true,
+	}, {
+		name: "multiple keys: b < a based on value of second sorted key",
+		inA: &gnmipb.Path{
+			Elem: []*gnmipb.PathElem{{
+				Name: "list",
+				Key:  map[string]string{"z": "2", "a": "1"},
+			}},
+		},
+		inB: &gnmipb.Path{
+			Elem: []*gnmipb.PathElem{{
+				Name: "list",
+				Key:  map[string]string{"a": "1", "z": "1"},
+			}},
+		},
+		want: false,
 	}, {
 		name: "path element: a < b based on path value",
 		inA: &gnmipb.Path{
@@ -1214,6 +1934,185 @@ func TestPathLess(t *testing.T) {
 	}
 }
 
+func TestValidatePath(t *testing.T) {
+	tests := []struct {
+		name          string
+		in            *gnmipb.Path
+		wantErrSubstr string
+	}{{
+		name: "nil path",
+	}, {
+		name: "empty path",
+		in:   &gnmipb.Path{},
+	}, {
+		name: "valid path with keys, origin and target",
+		in: &gnmipb.Path{
+			Origin: "openconfig",
+			Target: "dut",
+			Elem: []*gnmipb.PathElem{
+				{Name: "interfaces"},
+				{Name: "interface", Key: map[string]string{"name": "eth0"}},
+			},
+		},
+	}, {
+		name: "valid path using deprecated element field",
+		in:   &gnmipb.Path{Element: []string{"interfaces", "interface", "eth0"}},
+	}, {
+		name: "key with empty value",
+		in: &gnmipb.Path{Elem: []*gnmipb.PathElem{
+			{Name: "interface", Key: map[string]string{"name": ""}},
+		}},
+	}, {
+		name:          "element with empty name",
+		in:            &gnmipb.Path{Elem: []*gnmipb.PathElem{{Name: "interfaces"}, {Name: ""}}},
+		wantErrSubstr: "has an element with an empty name at index 1",
+	}, {
+		name:          "nil element",
+		in:            &gnmipb.Path{Elem: []*gnmipb.PathElem{nil}},
+		wantErrSubstr: "path has a nil element at index 0",
+	}, {
+		name: "key with empty name",
+		in: &gnmipb.Path{Elem: []*gnmipb.PathElem{
+			{Name: "interfaces"},
+			{Name: "interface", Key: map[string]string{"": "eth0"}},
+		}},
+		wantErrSubstr: "has a key with an empty name in element interface at index 1",
+	}, {
+		name:          "empty deprecated element",
+		in:            &gnmipb.Path{Element: []string{"interfaces", ""}},
+		wantErrSubstr: "has an element with an empty name at index 1",
+	}, {
+		name: "both elem and element specified",
+		in: &gnmipb.Path{
+			Elem:    []*gnmipb.PathElem{{Name: "interfaces"}},
+			Element: []string{"interfaces"},
+		},
+		wantErrSubstr: "specifies both the elem and element fields",
+	}, {
+		name: "origin with deprecated element field",
+		in: &gnmipb.Path{
+			Origin:  "openconfig",
+			Element: []string{"interfaces"},
+		},
+		wantErrSubstr: `specifies origin "openconfig" with the deprecated element field`,
+	}, {
+		name: "origin containing path",
+		in: &gnmipb.Path{
+			Origin: "openconfig/interfaces",
+			Elem:   []*gnmipb.PathElem{{Name: "interface"}},
+		},
+		wantErrSubstr: `has invalid origin "openconfig/interfaces"`,
+	}, {
+		name: "target containing path",
+		in: &gnmipb.Path{
+			Target: "dut/interfaces",
+			Elem:   []*gnmipb.PathElem{{Name: "interface"}},
+		},
+		wantErrSubstr: `has invalid target "dut/interfaces"`,
+	}}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidatePath(tt.in)
+			if tt.wantErrSubstr == "" {
+				if err != nil {
+					t.Fatalf("ValidatePath(%v): got unexpected error: %v", tt.in, err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErrSubstr) {
+				t.Fatalf("ValidatePath(%v): did not get expected error, got: %v, want error containing: %s", tt.in, err, tt.wantErrSubstr)
+			}
+		})
+	}
+}
+
+func TestPathIsPrefix(t *testing.T) {
+	keyed := func(key string, names ...string) *gnmipb.Path {
+		p := &gnmipb.Path{Elem: []*gnmipb.PathElem{{Name: "list", Key: map[string]string{"key": key}}}}
+		for _, n := range names {
+			p.Elem = append(p.Elem, &gnmipb.PathElem{Name: n})
+		}
+		return p
+	}
+
+	tests := []struct {
+		name     string
+		inPrefix *gnmipb.Path
+		inPath   *gnmipb.Path
+		want     bool
+	}{{
+		name:     "equal paths",
+		inPrefix: mustPath("a", "b"),
+		inPath:   mustPath("a", "b"),
+		want:     true,
+	}, {
+		name:     "path within prefix",
+		inPrefix: mustPath("a"),
+		inPath:   mustPath("a", "b", "c"),
+		want:     true,
+	}, {
+		name:     "root prefix",
+		inPrefix: &gnmipb.Path{},
+		inPath:   mustPath("a"),
+		want:     true,
+	}, {
+		name:   "nil prefix",
+		inPath: mustPath("a"),
+		want:   true,
+	}, {
+		name:     "prefix longer than path",
+		inPrefix: mustPath("a", "b"),
+		inPath:   mustPath("a"),
+	}, {
+		name:     "different element name",
+		inPrefix: mustPath("a", "b"),
+		inPath:   mustPath("a", "c", "d"),
+	}, {
+		name:     "different origin",
+		inPrefix: &gnmipb.Path{Origin: "openconfig", Elem: []*gnmipb.PathElem{{Name: "a"}}},
+		inPath:   mustPath("a", "b"),
+	}, {
+		name:     "matching keys",
+		inPrefix: keyed("one"),
+		inPath:   keyed("one", "config"),
+		want:     true,
+	}, {
+		name:     "different key value",
+		inPrefix: keyed("one"),
+		inPath:   keyed("two", "config"),
+	}, {
+		name:     "wildcard key value",
+		inPrefix: keyed("*"),
+		inPath:   keyed("two", "config"),
+		want:     true,
+	}, {
+		name:     "key not specified in prefix",
+		inPrefix: mustPath("list"),
+		inPath:   keyed("two", "config"),
+		want:     true,
+	}, {
+		name:     "key not specified in path",
+		inPrefix: keyed("one"),
+		inPath:   mustPath("list", "config"),
+	}, {
+		name:     "element paths",
+		inPrefix: &gnmipb.Path{Element: []string{"a", "b"}},
+		inPath:   &gnmipb.Path{Element: []string{"a", "b", "c"}},
+		want:     true,
+	}, {
+		name:     "different element paths",
+		inPrefix: &gnmipb.Path{Element: []string{"a", "b"}},
+		inPath:   &gnmipb.Path{Element: []string{"a", "c"}},
+	}}
+
+	for _, tt := range tests {
+		if got := PathIsPrefix(tt.inPrefix, tt.inPath); got != tt.want {
+			t.Errorf("%s: PathIsPrefix(%v, %v): did not get expected result, got: %v, want: %v", tt.name, tt.inPrefix, tt.inPath, got, tt.want)
+		}
+	}
+}
+
 func TestTypedValueLess(t *testing.T) {
 	tests := []struct {
 		name string
@@ -1452,6 +2351,204 @@ func TestTypedValueLess(t *testing.T) {
 			}},
 		},
 		want: false,
+	}, {
+		name: "any: different type URLs, a < b",
+		inA: &gnmipb.TypedValue{
+			Value: &gnmipb.TypedValue_AnyVal{&anypb.Any{TypeUrl: "type.googleapis.com/a.A", Value: []byte{0x02}}},
+		},
+		inB: &gnmipb.TypedValue{
+			Value: &gnmipb.TypedValue_AnyVal{&anypb.Any{TypeUrl: "type.googleapis.com/b.B", Value: []byte{0x01}}},
+		},
+		want: true,
+	}, {
+		name: "any: different type URLs, b < a",
+		inA: &gnmipb.TypedValue{
+			Value: &gnmipb.TypedValue_AnyVal{&anypb.Any{TypeUrl: "type.googleapis.com/b.B", Value: []byte{0x01}}},
+		},
+		inB: &gnmipb.TypedValue{
+			Value: &gnmipb.TypedValue_AnyVal{&anypb.Any{TypeUrl: "type.googleapis.com/a.A", Value: []byte{0x02}}},
+		},
+		want: false,
+	}, {
+		name: "any: same type URL, a < b by value",
+		inA: &gnmipb.TypedValue{
+			Value: &gnmipb.TypedValue_AnyVal{&anypb.Any{TypeUrl: "type.googleapis.com/a.A", Value: []byte{0x01, 0xff}}},
+		},
+		inB: &gnmipb.TypedValue{
+			Value: &gnmipb.TypedValue_AnyVal{&anypb.Any{TypeUrl: "type.googleapis.com/a.A", Value: []byte{0x02}}},
+		},
+		want: true,
+	}, {
+		name: "any: same type URL, b < a by value",
+		inA: &gnmipb.TypedValue{
+			Value: &gnmipb.TypedValue_AnyVal{&anypb.Any{TypeUrl: "type.googleapis.com/a.A", Value: []byte{0x02}}},
+		},
+		inB: &gnmipb.TypedValue{
+			Value: &gnmipb.TypedValue_AnyVal{&anypb.Any{TypeUrl: "type.googleapis.com/a.A", Value: []byte{0x01, 0xff}}},
+		},
+		want: false,
+	}, {
+		name: "bytes: empty < non-empty",
+		inA: &gnmipb.TypedValue{
+			Value: &gnmipb.TypedValue_BytesVal{[]byte{}},
+		},
+		inB: &gnmipb.TypedValue{
+			Value: &gnmipb.TypedValue_BytesVal{[]byte{0x00}},
+		},
+		want: true,
+	}, {
+		name: "bytes: non-empty > empty",
+		inA: &gnmipb.TypedValue{
+			Value: &gnmipb.TypedValue_BytesVal{[]byte{0x00}},
+		},
+		inB: &gnmipb.TypedValue{
+			Value: &gnmipb.TypedValue_BytesVal{nil},
+		},
+		want: false,
+	}, {
+		name: "bytes: prefix < longer value",
+		inA: &gnmipb.TypedValue{
+			Value: &gnmipb.TypedValue_BytesVal{[]byte{0x01, 0x02}},
+		},
+		inB: &gnmipb.TypedValue{
+			Value: &gnmipb.TypedValue_BytesVal{[]byte{0x01, 0x02, 0x00}},
+		},
+		want: true,
+	}, {
+		name: "bytes: longer value > prefix",
+		inA: &gnmipb.TypedValue{
+			Value: &gnmipb.TypedValue_BytesVal{[]byte{0x01, 0x02, 0x00}},
+		},
+		inB: &gnmipb.TypedValue{
+			Value: &gnmipb.TypedValue_BytesVal{[]byte{0x01, 0x02}},
+		},
+		want: false,
+	}, {
+		name: "bytes: compared by byte value, not length",
+		inA: &gnmipb.TypedValue{
+			Value: &gnmipb.TypedValue_BytesVal{[]byte{0x02}},
+		},
+		inB: &gnmipb.TypedValue{
+			Value: &gnmipb.TypedValue_BytesVal{[]byte{0x10, 0x00}},
+		},
+		want: true,
+	}, {
+		name: "bytes: equal values",
+		inA: &gnmipb.TypedValue{
+			Value: &gnmipb.TypedValue_BytesVal{[]byte("abc")},
+		},
+		inB: &gnmipb.TypedValue{
+			Value: &gnmipb.TypedValue_BytesVal{[]byte("abc")},
+		},
+		want: false,
+	}, {
+		name: "proto bytes: compared by byte value, not string representation",
+		inA: &gnmipb.TypedValue{
+			Value: &gnmipb.TypedValue_ProtoBytes{[]byte{0x09}},
+		},
+		inB: &gnmipb.TypedValue{
+			Value: &gnmipb.TypedValue_ProtoBytes{[]byte{0x0a}},
+		},
+		want: true,
+	}, {
+		name: "proto bytes: b < a",
+		inA: &gnmipb.TypedValue{
+			Value: &gnmipb.TypedValue_ProtoBytes{[]byte{0x0a}},
+		},
+		inB: &gnmipb.TypedValue{
+			Value: &gnmipb.TypedValue_ProtoBytes{[]byte{0x09, 0xff}},
+		},
+		want: false,
+	}, {
+		name: "proto bytes: equal values",
+		inA: &gnmipb.TypedValue{
+			Value: &gnmipb.TypedValue_ProtoBytes{[]byte{0x08, 0x01}},
+		},
+		inB: &gnmipb.TypedValue{
+			Value: &gnmipb.TypedValue_ProtoBytes{[]byte{0x08, 0x01}},
+		},
+		want: false,
+	}, {
+		name: "decimal: equal values with differing precision",
+		inA: &gnmipb.TypedValue{
+			Value: &gnmipb.TypedValue_DecimalVal{&gnmipb.Decimal64{Digits: 1234, Precision: 4}},
+		},
+		inB: &gnmipb.TypedValue{
+			Value: &gnmipb.TypedValue_DecimalVal{&gnmipb.Decimal64{Digits: 12340, Precision: 5}},
+		},
+		want: false,
+	}, {
+		name: "decimal: equal values with differing precision, reversed",
+		inA: &gnmipb.TypedValue{
+			Value: &gnmipb.TypedValue_DecimalVal{&gnmipb.Decimal64{Digits: 12340, Precision: 5}},
+		},
+		inB: &gnmipb.TypedValue{
+			Value: &gnmipb.TypedValue_DecimalVal{&gnmipb.Decimal64{Digits: 1234, Precision: 4}},
+		},
+		want: false,
+	}, {
+		name: "decimal: equal negative values with differing precision",
+		inA: &gnmipb.TypedValue{
+			Value: &gnmipb.TypedValue_DecimalVal{&gnmipb.Decimal64{Digits: -5, Precision: 1}},
+		},
+		inB: &gnmipb.TypedValue{
+			Value: &gnmipb.TypedValue_DecimalVal{&gnmipb.Decimal64{Digits: -500, Precision: 3}},
+		},
+		want: false,
+	}, {
+		name: "decimal: a < b with fewer digits",
+		inA: &gnmipb.TypedValue{
+			Value: &gnmipb.TypedValue_DecimalVal{&gnmipb.Decimal64{Digits: 999, Precision: 4}},
+		},
+		inB: &gnmipb.TypedValue{
+			Value: &gnmipb.TypedValue_DecimalVal{&gnmipb.Decimal64{Digits: 1, Precision: 1}},
+		},
+		want: true,
+	}, {
+		name: "decimal: b < a with more digits",
+		inA: &gnmipb.TypedValue{
+			Value: &gnmipb.TypedValue_DecimalVal{&gnmipb.Decimal64{Digits: 100001, Precision: 6}},
+		},
+		inB: &gnmipb.TypedValue{
+			Value: &gnmipb.TypedValue_DecimalVal{&gnmipb.Decimal64{Digits: 1, Precision: 1}},
+		},
+		want: false,
+	}, {
+		name: "decimal: negative a < b",
+		inA: &gnmipb.TypedValue{
+			Value: &gnmipb.TypedValue_DecimalVal{&gnmipb.Decimal64{Digits: -1, Precision: 0}},
+		},
+		inB: &gnmipb.TypedValue{
+			Value: &gnmipb.TypedValue_DecimalVal{&gnmipb.Decimal64{Digits: -9, Precision: 1}},
+		},
+		want: true,
+	}, {
+		name: "decimal: large values beyond float precision, a < b",
+		inA: &gnmipb.TypedValue{
+			Value: &gnmipb.TypedValue_DecimalVal{&gnmipb.Decimal64{Digits: math.MaxInt64 - 1, Precision: 0}},
+		},
+		inB: &gnmipb.TypedValue{
+			Value: &gnmipb.TypedValue_DecimalVal{&gnmipb.Decimal64{Digits: math.MaxInt64, Precision: 0}},
+		},
+		want: true,
+	}, {
+		name: "decimal: maximum precision",
+		inA: &gnmipb.TypedValue{
+			Value: &gnmipb.TypedValue_DecimalVal{&gnmipb.Decimal64{Digits: 1, Precision: 18}},
+		},
+		inB: &gnmipb.TypedValue{
+			Value: &gnmipb.TypedValue_DecimalVal{&gnmipb.Decimal64{Digits: 1, Precision: 17}},
+		},
+		want: true,
+	}, {
+		name: "any: equal values",
+		inA: &gnmipb.TypedValue{
+			Value: &gnmipb.TypedValue_AnyVal{&anypb.Any{TypeUrl: "type.googleapis.com/a.A", Value: []byte{0x01}}},
+		},
+		inB: &gnmipb.TypedValue{
+			Value: &gnmipb.TypedValue_AnyVal{&anypb.Any{TypeUrl: "type.googleapis.com/a.A", Value: []byte{0x01}}},
+		},
+		want: false,
 	}}
 
 	for _, tt := range tests {
@@ -1462,3 +2559,433 @@ func TestTypedValueLess(t *testing.T) {
 		})
 	}
 }
+
+func TestDuplicatePaths(t *testing.T) {
+	strVal := func(s string) *gnmipb.TypedValue {
+		return &gnmipb.TypedValue{Value: &gnmipb.TypedValue_StringVal{StringVal: s}}
+	}
+
+	tests := []struct {
+		name string
+		in   *gnmipb.Notification
+		want []*gnmipb.Path
+	}{{
+		name: "nil notification",
+	}, {
+		name: "no duplicates",
+		in: &gnmipb.Notification{
+			Update: []*gnmipb.Update{{
+				Path: mustPath("a", "b"),
+				Val:  strVal("one"),
+			}, {
+				Path: mustPath("a", "c"),
+				Val:  strVal("two"),
+			}},
+			Delete: []*gnmipb.Path{mustPath("a", "d")},
+		},
+	}, {
+		name: "same path in two updates",
+		in: &gnmipb.Notification{
+			Update: []*gnmipb.Update{{
+				Path: mustPath("a", "b"),
+				Val:  strVal("one"),
+			}, {
+				Path: mustPath("a", "c"),
+				Val:  strVal("two"),
+			}, {
+				Path: mustPath("a", "b"),
+				Val:  strVal("three"),
+			}},
+		},
+		want: []*gnmipb.Path{mustPath("a", "b")},
+	}, {
+		name: "same path in update and delete",
+		in: &gnmipb.Notification{
+			Update: []*gnmipb.Update{{
+				Path: mustPath("a", "b"),
+				Val:  strVal("one"),
+			}},
+			Delete: []*gnmipb.Path{mustPath("a", "b")},
+		},
+		want: []*gnmipb.Path{mustPath("a", "b")},
+	}, {
+		name: "path duplicated more than once, and paths with keys",
+		in: &gnmipb.Notification{
+			Update: []*gnmipb.Update{{
+				Path: mustPath("a"),
+				Val:  strVal("one"),
+			}, {
+				Path: mustPath("a"),
+				Val:  strVal("two"),
+			}, {
+				Path: &gnmipb.Path{Elem: []*gnmipb.PathElem{{Name: "list", Key: map[string]string{"k": "v1"}}}},
+				Val:  strVal("three"),
+			}, {
+				Path: &gnmipb.Path{Elem: []*gnmipb.PathElem{{Name: "list", Key: map[string]string{"k": "v2"}}}},
+				Val:  strVal("four"),
+			}, {
+				Path: &gnmipb.Path{Elem: []*gnmipb.PathElem{{Name: "list", Key: map[string]string{"k": "v1"}}}},
+				Val:  strVal("five"),
+			}},
+			Delete: []*gnmipb.Path{mustPath("a")},
+		},
+		want: []*gnmipb.Path{
+			mustPath("a"),
+			{Elem: []*gnmipb.PathElem{{Name: "list", Key: map[string]string{"k": "v1"}}}},
+		},
+	}}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := DuplicatePaths(tt.in)
+			if !cmp.Equal(got, tt.want, cmp.Comparer(proto.Equal)) {
+				t.Fatalf("DuplicatePaths(%v): did not get expected paths, got: %v, want: %v", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetUpdateValue(t *testing.T) {
+	strVal := func(s string) *gnmipb.TypedValue {
+		return &gnmipb.TypedValue{Value: &gnmipb.TypedValue_StringVal{StringVal: s}}
+	}
+
+	tests := []struct {
+		name      string
+		inNotif   *gnmipb.Notification
+		inPath    *gnmipb.Path
+		wantVal   *gnmipb.TypedValue
+		wantFound bool
+	}{{
+		name:   "nil notification",
+		inPath: mustPath("a"),
+	}, {
+		name: "present without prefix",
+		inNotif: &gnmipb.Notification{
+			Update: []*gnmipb.Update{{
+				Path: mustPath("a", "b"),
+				Val:  strVal("one"),
+			}, {
+				Path: mustPath("a", "c"),
+				Val:  strVal("two"),
+			}},
+		},
+		inPath:    mustPath("a", "c"),
+		wantVal:   strVal("two"),
+		wantFound: true,
+	}, {
+		name: "present with prefix",
+		inNotif: &gnmipb.Notification{
+			Prefix: &gnmipb.Path{
+				Target: "dev",
+				Elem:   []*gnmipb.PathElem{{Name: "list", Key: map[string]string{"k": "v1"}}},
+			},
+			Update: []*gnmipb.Update{{
+				Path: mustPath("a"),
+				Val:  strVal("one"),
+			}},
+		},
+		inPath: &gnmipb.Path{
+			Elem: []*gnmipb.PathElem{{Name: "list", Key: map[string]string{"k": "v1"}}, {Name: "a"}},
+		},
+		wantVal:   strVal("one"),
+		wantFound: true,
+	}, {
+		name: "relative path not matched when prefix is set",
+		inNotif: &gnmipb.Notification{
+			Prefix: mustPath("p"),
+			Update: []*gnmipb.Update{{
+				Path: mustPath("a"),
+				Val:  strVal("one"),
+			}},
+		},
+		inPath: mustPath("a"),
+	}, {
+		name: "absent path",
+		inNotif: &gnmipb.Notification{
+			Update: []*gnmipb.Update{{
+				Path: mustPath("a", "b"),
+				Val:  strVal("one"),
+			}},
+		},
+		inPath: mustPath("a", "d"),
+	}, {
+		name: "path differing in key value",
+		inNotif: &gnmipb.Notification{
+			Update: []*gnmipb.Update{{
+				Path: &gnmipb.Path{Elem: []*gnmipb.PathElem{{Name: "list", Key: map[string]string{"k": "v1"}}}},
+				Val:  strVal("one"),
+			}},
+		},
+		inPath: &gnmipb.Path{Elem: []*gnmipb.PathElem{{Name: "list", Key: map[string]string{"k": "v2"}}}},
+	}}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, found := GetUpdateValue(tt.inNotif, tt.inPath)
+			if found != tt.wantFound {
+				t.Fatalf("GetUpdateValue(%v, %v): did not get expected found status, got: %v, want: %v", tt.inNotif, tt.inPath, found, tt.wantFound)
+			}
+			if !proto.Equal(got, tt.wantVal) {
+				t.Fatalf("GetUpdateValue(%v, %v): did not get expected value, got: %v, want: %v", tt.inNotif, tt.inPath, got, tt.wantVal)
+			}
+		})
+	}
+}
+
+func TestComparers(t *testing.T) {
+	update := func(path, val string) *gnmipb.Update {
+		return &gnmipb.Update{
+			Path: mustPath(path),
+			Val:  &gnmipb.TypedValue{Value: &gnmipb.TypedValue_StringVal{val}},
+		}
+	}
+	jsonUpdate := func(path, val string) *gnmipb.Update {
+		return &gnmipb.Update{
+			Path: mustPath(path),
+			Val:  &gnmipb.TypedValue{Value: &gnmipb.TypedValue_JsonVal{[]byte(val)}},
+		}
+	}
+
+	tests := []struct {
+		name      string
+		inA       interface{}
+		inB       interface{}
+		inOpt     cmp.Option
+		wantEqual bool
+	}{{
+		name: "reordered notifications with reordered updates and deletes",
+		inA: []*gnmipb.Notification{{
+			Timestamp: 42,
+			Update:    []*gnmipb.Update{update("a", "one"), update("b", "two")},
+			Delete:    []*gnmipb.Path{mustPath("c"), mustPath("d")},
+		}, {
+			Timestamp: 84,
+			Update:    []*gnmipb.Update{update("a", "three")},
+		}},
+		inB: []*gnmipb.Notification{{
+			Timestamp: 84,
+			Update:    []*gnmipb.Update{update("a", "three")},
+		}, {
+			Timestamp: 42,
+			Update:    []*gnmipb.Update{update("b", "two"), update("a", "one")},
+			Delete:    []*gnmipb.Path{mustPath("d"), mustPath("c")},
+		}},
+		inOpt:     NotificationComparer(),
+		wantEqual: true,
+	}, {
+		name: "notifications with different values",
+		inA: []*gnmipb.Notification{{
+			Timestamp: 42,
+			Update:    []*gnmipb.Update{update("a", "one"), update("b", "two")},
+		}},
+		inB: []*gnmipb.Notification{{
+			Timestamp: 42,
+			Update:    []*gnmipb.Update{update("b", "two"), update("a", "three")},
+		}},
+		inOpt: NotificationComparer(),
+	}, {
+		name: "notifications with JSON values with reordered keys",
+		inA: []*gnmipb.Notification{{
+			Timestamp: 42,
+			Update:    []*gnmipb.Update{jsonUpdate("a", `{"b":1,"a":2}`)},
+		}},
+		inB: []*gnmipb.Notification{{
+			Timestamp: 42,
+			Update:    []*gnmipb.Update{jsonUpdate("a", `{"a":2,"b":1}`)},
+		}},
+		inOpt: NotificationComparer(),
+	}, {
+		name: "notifications with JSON values with reordered keys with sorted keys",
+		inA: []*gnmipb.Notification{{
+			Timestamp: 42,
+			Update:    []*gnmipb.Update{jsonUpdate("a", `{"b":1,"a":2}`)},
+		}, {
+			Timestamp: 84,
+			Update:    []*gnmipb.Update{jsonUpdate("a", `{"c":{"e":3,"d":4}}`)},
+		}},
+		inB: []*gnmipb.Notification{{
+			Timestamp: 84,
+			Update:    []*gnmipb.Update{jsonUpdate("a", `{"c": {"d": 4, "e": 3}}`)},
+		}, {
+			Timestamp: 42,
+			Update:    []*gnmipb.Update{jsonUpdate("a", `{"a":2,"b":1}`)},
+		}},
+		inOpt:     NotificationComparer(&SortJSONKeys{}),
+		wantEqual: true,
+	}, {
+		name: "notifications that differ only in atomic",
+		inA: []*gnmipb.Notification{{
+			Timestamp: 42,
+			Update:    []*gnmipb.Update{update("a", "one")},
+		}},
+		inB: []*gnmipb.Notification{{
+			Timestamp: 42,
+			Update:    []*gnmipb.Update{update("a", "one")},
+			Atomic:    true,
+		}},
+		inOpt: NotificationComparer(),
+	}, {
+		name: "notifications that differ only in alias",
+		inA: []*gnmipb.Notification{{
+			Timestamp: 42,
+			Alias:     "#one",
+			Update:    []*gnmipb.Update{update("a", "one")},
+		}},
+		inB: []*gnmipb.Notification{{
+			Timestamp: 42,
+			Alias:     "#two",
+			Update:    []*gnmipb.Update{update("a", "one")},
+		}},
+		inOpt: NotificationComparer(),
+	}, {
+		name:      "reordered updates",
+		inA:       []*gnmipb.Update{update("a", "one"), update("b", "two"), update("c", "three")},
+		inB:       []*gnmipb.Update{update("c", "three"), update("a", "one"), update("b", "two")},
+		inOpt:     UpdateComparer(),
+		wantEqual: true,
+	}, {
+		name:  "JSON values with reordered keys compared by content by default",
+		inA:   []*gnmipb.Update{jsonUpdate("a", `{"b":1,"a":2}`)},
+		inB:   []*gnmipb.Update{jsonUpdate("a", "{\n  \"a\": 2,\n  \"b\": 1\n}")},
+		inOpt: UpdateComparer(),
+	}, {
+		name:      "JSON values with reordered keys and differing whitespace with sorted keys",
+		inA:       []*gnmipb.Update{jsonUpdate("a", `{"b":1,"a":2}`), jsonUpdate("b", `{"c":[1,2]}`)},
+		inB:       []*gnmipb.Update{jsonUpdate("b", `{ "c": [1, 2] }`), jsonUpdate("a", "{\n  \"a\": 2,\n  \"b\": 1\n}")},
+		inOpt:     UpdateComparer(&SortJSONKeys{}),
+		wantEqual: true,
+	}, {
+		name:  "updates with different paths",
+		inA:   []*gnmipb.Update{update("a", "one"), update("b", "two")},
+		inB:   []*gnmipb.Update{update("b", "two"), update("c", "one")},
+		inOpt: UpdateComparer(),
+	}, {
+		name:      "reordered paths",
+		inA:       []*gnmipb.Path{mustPath("a", "b"), mustPath("a"), mustPath("c")},
+		inB:       []*gnmipb.Path{mustPath("c"), mustPath("a", "b"), mustPath("a")},
+		inOpt:     PathComparer(),
+		wantEqual: true,
+	}, {
+		name:  "different number of paths",
+		inA:   []*gnmipb.Path{mustPath("a"), mustPath("a")},
+		inB:   []*gnmipb.Path{mustPath("a")},
+		inOpt: PathComparer(),
+	}}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			diff := cmp.Diff(tt.inA, tt.inB, tt.inOpt)
+			if got := diff == ""; got != tt.wantEqual {
+				t.Fatalf("cmp.Diff(%v, %v): did not get expected equality, got: %v, want: %v, diff(-a,+b):\n%s", tt.inA, tt.inB, got, tt.wantEqual, diff)
+			}
+		})
+	}
+}
+
+func TestNotificationComparerWithAliases(t *testing.T) {
+	aliases := map[string]*gnmipb.Path{
+		"#eth0": {
+			Elem: []*gnmipb.PathElem{{
+				Name: "interfaces",
+			}, {
+				Name: "interface",
+				Key:  map[string]string{"name": "eth0"},
+			}},
+		},
+	}
+
+	expanded := &gnmipb.Notification{
+		Timestamp: 42,
+		Prefix: &gnmipb.Path{
+			Elem: []*gnmipb.PathElem{{
+				Name: "interfaces",
+			}, {
+				Name: "interface",
+				Key:  map[string]string{"name": "eth0"},
+			}, {
+				Name: "state",
+			}},
+		},
+		Update: []*gnmipb.Update{{
+			Path: mustPath("mtu"),
+			Val:  &gnmipb.TypedValue{Value: &gnmipb.TypedValue_UintVal{UintVal: 1500}},
+		}, {
+			Path: mustPath("description"),
+			Val:  &gnmipb.TypedValue{Value: &gnmipb.TypedValue_StringVal{StringVal: "foo"}},
+		}},
+	}
+
+	tests := []struct {
+		name  string
+		inA   *gnmipb.Notification
+		inB   *gnmipb.Notification
+		inOpt cmp.Option
+		want  bool
+	}{{
+		name: "aliased prefix equal to expanded prefix",
+		inA:  expanded,
+		inB: &gnmipb.Notification{
+			Timestamp: 42,
+			Prefix:    mustPath("#eth0", "state"),
+			Update: []*gnmipb.Update{{
+				Path: mustPath("description"),
+				Val:  &gnmipb.TypedValue{Value: &gnmipb.TypedValue_StringVal{StringVal: "foo"}},
+			}, {
+				Path: mustPath("mtu"),
+				Val:  &gnmipb.TypedValue{Value: &gnmipb.TypedValue_UintVal{UintVal: 1500}},
+			}},
+		},
+		inOpt: NotificationComparerWithAliases(aliases),
+		want:  true,
+	}, {
+		name: "aliased prefix not equal without alias map",
+		inA:  expanded,
+		inB: &gnmipb.Notification{
+			Timestamp: 42,
+			Prefix:    mustPath("#eth0", "state"),
+			Update:    expanded.Update,
+		},
+		inOpt: NotificationComparerWithAliases(nil),
+		want:  false,
+	}, {
+		name: "aliased prefix expands to different path",
+		inA:  expanded,
+		inB: &gnmipb.Notification{
+			Timestamp: 42,
+			Prefix:    mustPath("#eth0", "config"),
+			Update:    expanded.Update,
+		},
+		inOpt: NotificationComparerWithAliases(aliases),
+		want:  false,
+	}, {
+		name: "aliased delete path",
+		inA: &gnmipb.Notification{
+			Delete: []*gnmipb.Path{mustPath("#eth0", "config", "mtu")},
+		},
+		inB: &gnmipb.Notification{
+			Delete: []*gnmipb.Path{{
+				Elem: []*gnmipb.PathElem{{
+					Name: "interfaces",
+				}, {
+					Name: "interface",
+					Key:  map[string]string{"name": "eth0"},
+				}, {
+					Name: "config",
+				}, {
+					Name: "mtu",
+				}},
+			}},
+		},
+		inOpt: NotificationComparerWithAliases(aliases),
+		want:  true,
+	}}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := cmp.Equal(tt.inA, tt.inB, tt.inOpt); got != tt.want {
+				t.Fatalf("cmp.Equal(%v, %v, NotificationComparerWithAliases): did not get expected result, got: %v, want: %v", tt.inA, tt.inB, got, tt.want)
+			}
+		})
+	}
+}
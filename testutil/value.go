@@ -0,0 +1,200 @@
+// Copyright 2018 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package testutil
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+
+	gnmipb "github.com/openconfig/gnmi/proto/gnmi"
+	"github.com/openconfig/gnmi/value"
+	"github.com/openconfig/goyang/pkg/yang"
+)
+
+// intBits specifies the size, in bits, of each YANG integer type.
+var intBits = map[yang.TypeKind]uint{
+	yang.Yint8:   8,
+	yang.Yint16:  16,
+	yang.Yint32:  32,
+	yang.Yint64:  64,
+	yang.Yuint8:  8,
+	yang.Yuint16: 16,
+	yang.Yuint32: 32,
+	yang.Yuint64: 64,
+}
+
+// TypedValueFromGo returns a gNMI TypedValue containing the Go value v, which
+// is the value of a leaf of the YANG type kind. The kind determines the field
+// of the TypedValue that is populated, and the Go types that are accepted.
+// Strings are used for string, enumeration, identityref, bits and
+// instance-identifier values. Any Go integer type is accepted for the YANG
+// integer types, and an error is returned if the value cannot be represented
+// by the YANG type. Decimal64 values are specified as a float32 or float64,
+// and are stored with the smallest precision that represents the value. Bool
+// is used for boolean and empty values, where an empty value must be true,
+// since it indicates that the leaf is present. Binary values are specified as
+// a []byte. Named types with these underlying types are also accepted. Union
+// and leafref kinds are not supported, since their encoding depends on the
+// type that they resolve to.
+func TypedValueFromGo(v interface{}, kind yang.TypeKind) (*gnmipb.TypedValue, error) {
+	if v == nil {
+		return nil, fmt.Errorf("cannot encode nil value for YANG type %s", kind)
+	}
+	rv := reflect.ValueOf(v)
+
+	switch kind {
+	case yang.Ystring, yang.Yenum, yang.Yidentityref, yang.Ybits, yang.YinstanceIdentifier:
+		if rv.Kind() != reflect.String {
+			return nil, fmt.Errorf("value %v of type %T is not a string, as required for YANG type %s", v, v, kind)
+		}
+		return &gnmipb.TypedValue{Value: &gnmipb.TypedValue_StringVal{StringVal: rv.String()}}, nil
+	case yang.Yint8, yang.Yint16, yang.Yint32, yang.Yint64:
+		i, err := goInt(rv, intBits[kind])
+		if err != nil {
+			return nil, fmt.Errorf("value %v of type %T is not valid for YANG type %s: %v", v, v, kind, err)
+		}
+		return &gnmipb.TypedValue{Value: &gnmipb.TypedValue_IntVal{IntVal: i}}, nil
+	case yang.Yuint8, yang.Yuint16, yang.Yuint32, yang.Yuint64:
+		u, err := goUint(rv, intBits[kind])
+		if err != nil {
+			return nil, fmt.Errorf("value %v of type %T is not valid for YANG type %s: %v", v, v, kind, err)
+		}
+		return &gnmipb.TypedValue{Value: &gnmipb.TypedValue_UintVal{UintVal: u}}, nil
+	case yang.Ydecimal64:
+		if rv.Kind() != reflect.Float32 && rv.Kind() != reflect.Float64 {
+			return nil, fmt.Errorf("value %v of type %T is not a float, as required for YANG type %s", v, v, kind)
+		}
+		d, err := floatToDecimal(rv.Float(), rv.Type().Bits())
+		if err != nil {
+			return nil, fmt.Errorf("value %v is not valid for YANG type %s: %v", v, kind, err)
+		}
+		return &gnmipb.TypedValue{Value: &gnmipb.TypedValue_DecimalVal{DecimalVal: d}}, nil
+	case yang.Ybool, yang.Yempty:
+		if rv.Kind() != reflect.Bool {
+			return nil, fmt.Errorf("value %v of type %T is not a bool, as required for YANG type %s", v, v, kind)
+		}
+		if kind == yang.Yempty && !rv.Bool() {
+			return nil, fmt.Errorf("value of YANG type %s must be true, since the leaf is present", kind)
+		}
+		return &gnmipb.TypedValue{Value: &gnmipb.TypedValue_BoolVal{BoolVal: rv.Bool()}}, nil
+	case yang.Ybinary:
+		if rv.Kind() != reflect.Slice || rv.Type().Elem().Kind() != reflect.Uint8 {
+			return nil, fmt.Errorf("value %v of type %T is not a []byte, as required for YANG type %s", v, v, kind)
+		}
+		return &gnmipb.TypedValue{Value: &gnmipb.TypedValue_BytesVal{BytesVal: rv.Bytes()}}, nil
+	default:
+		return nil, fmt.Errorf("unsupported YANG type %s", kind)
+	}
+}
+
+// NotificationToMap returns a map, keyed by the absolute path of each update
+// within the gNMI Notification n, rendered using PathString, of the value of
+// the update. The absolute path is formed by joining the path of the update to
+// the prefix of n. Scalar values are converted to the corresponding Go type
+// using value.ToScalar, such that leaf-list values are returned as a
+// []interface{}. Values that are not scalar, such as JSON values, are included
+// in their string form, as returned by TypedValueString. Deleted paths are not
+// included in the map. An error is returned if an update does not specify a
+// value, or if a path is updated more than once.
+func NotificationToMap(n *gnmipb.Notification) (map[string]interface{}, error) {
+	m := map[string]interface{}{}
+	for _, u := range n.GetUpdate() {
+		p := PathString(joinPaths(n.GetPrefix(), u.GetPath()))
+		if u.GetVal() == nil {
+			return nil, fmt.Errorf("update for path %s does not specify a value", p)
+		}
+		if _, ok := m[p]; ok {
+			return nil, fmt.Errorf("path %s is updated more than once", p)
+		}
+		v, err := value.ToScalar(u.GetVal())
+		if err != nil {
+			v = TypedValueString(u.GetVal())
+		}
+		m[p] = v
+	}
+	return m, nil
+}
+
+// goInt returns the value of the Go integer rv as an int64, returning an
+// error if rv is not an integer, or cannot be represented as a signed integer
+// of the specified number of bits.
+func goInt(rv reflect.Value, bits uint) (int64, error) {
+	var i int64
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i = rv.Int()
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		u := rv.Uint()
+		if u > math.MaxInt64 {
+			return 0, fmt.Errorf("value is larger than %d bits", bits)
+		}
+		i = int64(u)
+	default:
+		return 0, fmt.Errorf("not an integer")
+	}
+	if min, max := -int64(1)<<(bits-1), int64(uint64(1)<<(bits-1)-1); i < min || i > max {
+		return 0, fmt.Errorf("value is outside of the range %d..%d", min, max)
+	}
+	return i, nil
+}
+
+// goUint returns the value of the Go integer rv as a uint64, returning an
+// error if rv is not an integer, or cannot be represented as an unsigned
+// integer of the specified number of bits.
+func goUint(rv reflect.Value, bits uint) (uint64, error) {
+	var u uint64
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i := rv.Int()
+		if i < 0 {
+			return 0, fmt.Errorf("value is negative")
+		}
+		u = uint64(i)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		u = rv.Uint()
+	default:
+		return 0, fmt.Errorf("not an integer")
+	}
+	if max := uint64(math.MaxUint64) >> (64 - bits); u > max {
+		return 0, fmt.Errorf("value is outside of the range 0..%d", max)
+	}
+	return u, nil
+}
+
+// floatToDecimal returns a gNMI Decimal64 representing the float f, which
+// has the specified size in bits. The precision of the returned value is the
+// smallest that represents f exactly as it would be formatted by strconv.
+func floatToDecimal(f float64, bits int) (*gnmipb.Decimal64, error) {
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return nil, fmt.Errorf("value is not a finite number")
+	}
+	s := strconv.FormatFloat(f, 'f', -1, bits)
+	var prec uint32
+	if i := strings.IndexByte(s, '.'); i != -1 {
+		prec = uint32(len(s) - i - 1)
+		s = s[:i] + s[i+1:]
+	}
+	if prec > 18 {
+		return nil, fmt.Errorf("value requires precision %d, greater than the maximum of 18", prec)
+	}
+	d, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("value cannot be represented as a decimal64: %v", err)
+	}
+	return &gnmipb.Decimal64{Digits: d, Precision: prec}, nil
+}
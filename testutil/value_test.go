@@ -0,0 +1,285 @@
+// Copyright 2018 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package testutil
+
+import (
+	"math"
+	"testing"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/google/go-cmp/cmp"
+	gnmipb "github.com/openconfig/gnmi/proto/gnmi"
+	"github.com/openconfig/goyang/pkg/yang"
+)
+
+func TestTypedValueFromGo(t *testing.T) {
+	type namedString string
+	type namedBool bool
+
+	tests := []struct {
+		name    string
+		inVal   interface{}
+		inKind  yang.TypeKind
+		want    *gnmipb.TypedValue
+		wantErr bool
+	}{{
+		name:   "string",
+		inVal:  "eth0",
+		inKind: yang.Ystring,
+		want:   &gnmipb.TypedValue{Value: &gnmipb.TypedValue_StringVal{StringVal: "eth0"}},
+	}, {
+		name:   "enumeration from named string type",
+		inVal:  namedString("UP"),
+		inKind: yang.Yenum,
+		want:   &gnmipb.TypedValue{Value: &gnmipb.TypedValue_StringVal{StringVal: "UP"}},
+	}, {
+		name:    "string kind with non-string value",
+		inVal:   42,
+		inKind:  yang.Ystring,
+		wantErr: true,
+	}, {
+		name:   "int8",
+		inVal:  int8(-128),
+		inKind: yang.Yint8,
+		want:   &gnmipb.TypedValue{Value: &gnmipb.TypedValue_IntVal{IntVal: -128}},
+	}, {
+		name:   "int64 from untyped constant",
+		inVal:  42,
+		inKind: yang.Yint64,
+		want:   &gnmipb.TypedValue{Value: &gnmipb.TypedValue_IntVal{IntVal: 42}},
+	}, {
+		name:    "int8 out of range",
+		inVal:   128,
+		inKind:  yang.Yint8,
+		wantErr: true,
+	}, {
+		name:    "int64 from too large uint64",
+		inVal:   uint64(math.MaxUint64),
+		inKind:  yang.Yint64,
+		wantErr: true,
+	}, {
+		name:   "uint16",
+		inVal:  65535,
+		inKind: yang.Yuint16,
+		want:   &gnmipb.TypedValue{Value: &gnmipb.TypedValue_UintVal{UintVal: 65535}},
+	}, {
+		name:   "uint64",
+		inVal:  uint64(math.MaxUint64),
+		inKind: yang.Yuint64,
+		want:   &gnmipb.TypedValue{Value: &gnmipb.TypedValue_UintVal{UintVal: math.MaxUint64}},
+	}, {
+		name:    "uint32 from negative value",
+		inVal:   -1,
+		inKind:  yang.Yuint32,
+		wantErr: true,
+	}, {
+		name:    "uint8 out of range",
+		inVal:   uint16(256),
+		inKind:  yang.Yuint8,
+		wantErr: true,
+	}, {
+		name:    "integer kind with float value",
+		inVal:   4.2,
+		inKind:  yang.Yint32,
+		wantErr: true,
+	}, {
+		name:   "decimal64",
+		inVal:  42.42,
+		inKind: yang.Ydecimal64,
+		want:   &gnmipb.TypedValue{Value: &gnmipb.TypedValue_DecimalVal{DecimalVal: &gnmipb.Decimal64{Digits: 4242, Precision: 2}}},
+	}, {
+		name:   "negative decimal64",
+		inVal:  -0.001,
+		inKind: yang.Ydecimal64,
+		want:   &gnmipb.TypedValue{Value: &gnmipb.TypedValue_DecimalVal{DecimalVal: &gnmipb.Decimal64{Digits: -1, Precision: 3}}},
+	}, {
+		name:   "integral decimal64",
+		inVal:  float32(10),
+		inKind: yang.Ydecimal64,
+		want:   &gnmipb.TypedValue{Value: &gnmipb.TypedValue_DecimalVal{DecimalVal: &gnmipb.Decimal64{Digits: 10}}},
+	}, {
+		name:   "decimal64 from float32",
+		inVal:  float32(0.1),
+		inKind: yang.Ydecimal64,
+		want:   &gnmipb.TypedValue{Value: &gnmipb.TypedValue_DecimalVal{DecimalVal: &gnmipb.Decimal64{Digits: 1, Precision: 1}}},
+	}, {
+		name:    "decimal64 with non-finite value",
+		inVal:   math.Inf(1),
+		inKind:  yang.Ydecimal64,
+		wantErr: true,
+	}, {
+		name:    "decimal64 with integer value",
+		inVal:   42,
+		inKind:  yang.Ydecimal64,
+		wantErr: true,
+	}, {
+		name:   "bool",
+		inVal:  false,
+		inKind: yang.Ybool,
+		want:   &gnmipb.TypedValue{Value: &gnmipb.TypedValue_BoolVal{BoolVal: false}},
+	}, {
+		name:   "empty",
+		inVal:  namedBool(true),
+		inKind: yang.Yempty,
+		want:   &gnmipb.TypedValue{Value: &gnmipb.TypedValue_BoolVal{BoolVal: true}},
+	}, {
+		name:    "empty set to false",
+		inVal:   false,
+		inKind:  yang.Yempty,
+		wantErr: true,
+	}, {
+		name:   "binary",
+		inVal:  []byte{0x01, 0x02},
+		inKind: yang.Ybinary,
+		want:   &gnmipb.TypedValue{Value: &gnmipb.TypedValue_BytesVal{BytesVal: []byte{0x01, 0x02}}},
+	}, {
+		name:    "binary with string value",
+		inVal:   "0102",
+		inKind:  yang.Ybinary,
+		wantErr: true,
+	}, {
+		name:    "nil value",
+		inKind:  yang.Ystring,
+		wantErr: true,
+	}, {
+		name:    "unsupported union kind",
+		inVal:   "foo",
+		inKind:  yang.Yunion,
+		wantErr: true,
+	}}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := TypedValueFromGo(tt.inVal, tt.inKind)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("TypedValueFromGo(%v, %v): did not get expected error status, got: %v, wantErr: %v", tt.inVal, tt.inKind, err, tt.wantErr)
+			}
+			if !proto.Equal(got, tt.want) {
+				t.Fatalf("TypedValueFromGo(%v, %v): did not get expected value, got: %v, want: %v", tt.inVal, tt.inKind, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNotificationToMap(t *testing.T) {
+	strVal := func(s string) *gnmipb.TypedValue {
+		return &gnmipb.TypedValue{Value: &gnmipb.TypedValue_StringVal{StringVal: s}}
+	}
+
+	tests := []struct {
+		name    string
+		in      *gnmipb.Notification
+		want    map[string]interface{}
+		wantErr bool
+	}{{
+		name: "empty notification",
+		in:   &gnmipb.Notification{},
+		want: map[string]interface{}{},
+	}, {
+		name: "scalar values",
+		in: &gnmipb.Notification{
+			Update: []*gnmipb.Update{{
+				Path: mustPath("a", "string"),
+				Val:  strVal("foo"),
+			}, {
+				Path: mustPath("a", "int"),
+				Val:  &gnmipb.TypedValue{Value: &gnmipb.TypedValue_IntVal{IntVal: -42}},
+			}, {
+				Path: mustPath("a", "uint"),
+				Val:  &gnmipb.TypedValue{Value: &gnmipb.TypedValue_UintVal{UintVal: 42}},
+			}, {
+				Path: mustPath("a", "bool"),
+				Val:  &gnmipb.TypedValue{Value: &gnmipb.TypedValue_BoolVal{BoolVal: true}},
+			}, {
+				Path: mustPath("a", "decimal"),
+				Val:  &gnmipb.TypedValue{Value: &gnmipb.TypedValue_DecimalVal{DecimalVal: &gnmipb.Decimal64{Digits: 125, Precision: 2}}},
+			}},
+		},
+		want: map[string]interface{}{
+			"/a/string":  "foo",
+			"/a/int":     int64(-42),
+			"/a/uint":    uint64(42),
+			"/a/bool":    true,
+			"/a/decimal": float32(1.25),
+		},
+	}, {
+		name: "leaf-list value",
+		in: &gnmipb.Notification{
+			Update: []*gnmipb.Update{{
+				Path: mustPath("a", "leaf-list"),
+				Val: &gnmipb.TypedValue{Value: &gnmipb.TypedValue_LeaflistVal{LeaflistVal: &gnmipb.ScalarArray{
+					Element: []*gnmipb.TypedValue{strVal("one"), strVal("two")},
+				}}},
+			}},
+		},
+		want: map[string]interface{}{
+			"/a/leaf-list": []interface{}{"one", "two"},
+		},
+	}, {
+		name: "paths joined to prefix",
+		in: &gnmipb.Notification{
+			Prefix: &gnmipb.Path{Elem: []*gnmipb.PathElem{{Name: "list", Key: map[string]string{"key": "one"}}}},
+			Update: []*gnmipb.Update{{
+				Path: mustPath("config", "key"),
+				Val:  strVal("one"),
+			}},
+			Delete: []*gnmipb.Path{mustPath("config", "value")},
+		},
+		want: map[string]interface{}{
+			"/list[key=one]/config/key": "one",
+		},
+	}, {
+		name: "non-scalar value",
+		in: &gnmipb.Notification{
+			Update: []*gnmipb.Update{{
+				Path: mustPath("a"),
+				Val:  &gnmipb.TypedValue{Value: &gnmipb.TypedValue_JsonVal{JsonVal: []byte(`{"b":1}`)}},
+			}},
+		},
+		want: map[string]interface{}{
+			"/a": `json_val:"{\"b\":1}"`,
+		},
+	}, {
+		name: "update without value",
+		in: &gnmipb.Notification{
+			Update: []*gnmipb.Update{{Path: mustPath("a")}},
+		},
+		wantErr: true,
+	}, {
+		name: "duplicate paths",
+		in: &gnmipb.Notification{
+			Update: []*gnmipb.Update{{
+				Path: mustPath("a"),
+				Val:  strVal("one"),
+			}, {
+				Path: mustPath("a"),
+				Val:  strVal("two"),
+			}},
+		},
+		wantErr: true,
+	}}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := NotificationToMap(tt.in)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NotificationToMap(%v): did not get expected error status, got: %v, wantErr: %v", tt.in, err, tt.wantErr)
+			}
+			if diff := cmp.Diff(got, tt.want); diff != "" {
+				t.Fatalf("NotificationToMap(%v): did not get expected map, diff(-got,+want):\n%s", tt.in, diff)
+			}
+		})
+	}
+}
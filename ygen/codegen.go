@@ -20,6 +20,7 @@ package ygen
 import (
 	"errors"
 	"fmt"
+	"path"
 	"sort"
 	"strings"
 
@@ -157,12 +158,351 @@ type ProtoOpts struct {
 	// original YANG names in the output protobuf file.
 	// See https://github.com/openconfig/ygot/blob/master/docs/yang-to-protobuf-transformations-spec.md#annotation-of-enums
 	AnnotateEnumNames bool
+	// AnnotateDescriptions specifies whether the description of the
+	// YANG schema node that each field corresponds to should be output as
+	// a comment above the field in the output protobuf file.
+	AnnotateDescriptions bool
 	// NestedMessages indicates whether nested messages should be
 	// output for the protobuf schema. If false, a separate package
 	// is generated per package.
 	NestedMessages bool
+	// UpperSnakeCaseEnumValues specifies whether the names of the values
+	// of generated enums (both for YANG identities and enumerations)
+	// should be converted to UPPER_SNAKE_CASE, e.g., adminUp is output
+	// as ADMIN_UP. When unset, the YANG name is used with only characters
+	// that are not valid in protobuf identifiers being replaced.
+	UpperSnakeCaseEnumValues bool
+	// IdentityrefAsString specifies whether leaves of type identityref
+	// should be mapped to a ywrapper.StringValue containing the
+	// module-qualified name of the identity, rather than to an enumerated
+	// type within the enum package. When set, the base identity of the
+	// leaf is annotated onto the field using the identity_base extension
+	// defined in yext.proto.
+	IdentityrefAsString bool
+	// IdentitiesFile specifies whether the enumerated types that are
+	// generated for YANG identities should be output to a single
+	// identities.proto file, within the identities package, rather than
+	// the package specified by EnumPackageName. The names of the generated
+	// enums are unique across all input modules. Enumerated types that are
+	// generated for typedefs remain within the enum package.
+	IdentitiesFile bool
+	// IntegerTypes specifies the protobuf type that should be used to
+	// represent each YANG integer type, keyed by the YANG type. Each type
+	// may be mapped to a ywrapper type (ywrapper.IntValue or
+	// ywrapper.UintValue), or to a native protobuf integer type (e.g.,
+	// uint64), which cannot distinguish an unset field from one set to
+	// zero. The type specified must be able to represent all values of the
+	// YANG type. YANG integer types that are not specified are mapped to
+	// the corresponding ywrapper type.
+	IntegerTypes map[yang.TypeKind]string
+	// SchemaPathField specifies whether a string field named _schema_path
+	// should be added to each generated message, such that the schema path
+	// of the message can be stored within the data, for example, for use
+	// in indexing. The tag of the field is derived from the message's
+	// schema path, and hence is stable across code generation runs.
+	SchemaPathField bool
+	// ReservedFieldTags specifies the field numbers that were used by a
+	// prior generation of each message, keyed by the YANG schema path of
+	// the message (e.g., /interfaces/interface). Field numbers that are
+	// no longer used by a field within the message are output as reserved,
+	// such that fields that are removed from the schema do not have their
+	// field numbers reused.
+	ReservedFieldTags map[string][]uint32
+	// MessageOptions specifies options that should be output for each
+	// message, keyed by the YANG schema path of the message (e.g.,
+	// /interfaces/interface). Each option is specified in the form
+	// name = value, e.g., (my.custom) = true, and is output as an option
+	// statement within the message.
+	MessageOptions map[string][]string
+	// CardinalityPolicy specifies how the min-elements and max-elements
+	// statements of YANG lists and leaf-lists are output for the fields
+	// that represent them in the generated messages.
+	CardinalityPolicy ProtoCardinalityPolicy
+	// GoogleWrapperTypes specifies whether the wrapper types defined in
+	// google/protobuf/wrappers.proto (e.g., google.protobuf.StringValue)
+	// should be used for scalar fields in place of the equivalent ywrapper
+	// types. Since there is no google.protobuf equivalent of the
+	// ywrapper.Decimal64Value type, it is used regardless of this option.
+	GoogleWrapperTypes bool
+	// EscapeReservedWords specifies whether an underscore is appended to
+	// the names of fields that are protobuf keywords, e.g., a leaf named
+	// message is output as a field named message_.
+	EscapeReservedWords bool
+	// UniqueNameSuffix specifies the suffix that is used to disambiguate
+	// the names of messages, fields, enums and enum values that would
+	// otherwise clash. If the suffix contains the "%d" verb, it is
+	// formatted with a counter starting at 1, e.g., "_%d" results in
+	// names such as foo_1 and foo_2. Otherwise, the suffix is appended
+	// until the name is unique. The names that result must be valid
+	// protobuf identifiers. If it is not specified, an underscore is used.
+	UniqueNameSuffix string
+	// TypedefUnionMessages specifies whether YANG unions that are defined
+	// by a typedef should be output as a message within the unions
+	// package, which is referenced by all fields whose type is the
+	// typedef, rather than as a oneof within each message that contains
+	// such a field. Each message contains a single oneof, named value.
+	// Unions that are defined inline within a leaf, and those that are
+	// used as list keys, are output as a oneof regardless of this option.
+	TypedefUnionMessages bool
+	// QualifyNestedListKeys specifies whether the key message of a list
+	// that is directly within another list should be named using the name
+	// of the enclosing list's message as a prefix, e.g., the key message of
+	// list b within list a is named ABKey rather than BKey. Key messages
+	// are output alongside the message of the enclosing list, and hence
+	// lists of the same name within different lists in the same package
+	// otherwise result in key messages with clashing names.
+	QualifyNestedListKeys bool
+	// SingleKeyListsAsMaps specifies whether keyed lists that have a
+	// single key, whose type is a protobuf integer, bool or string type,
+	// should be output as a map field, e.g., map<string, Foo> foo = 1;,
+	// keyed by the value of the key, rather than as a repeated field of a
+	// message containing the key and the list member. Lists with multiple
+	// keys, or with an enumerated, union or decimal64 key, are output as a
+	// repeated field regardless of this option. Since protobuf maps are
+	// unordered, the order of lists that are ordered-by user is not
+	// retained within such a map.
+	SingleKeyListsAsMaps bool
+	// AnnotateEnumDefaults specifies whether the YANG default of
+	// enumeration and identityref leaves should be annotated onto the
+	// generated field using the (yext.enum_default) option. By default, the
+	// default of an enumeration leaf is used as the zero value of the
+	// generated enum, whereas that of an identityref leaf, whose enum is
+	// shared by all leaves that reference the identity, is not output. When
+	// this option is set, the zero value of all generated enums indicates
+	// that the field is unset, and defaults of both kinds are annotated.
+	AnnotateEnumDefaults bool
+	// AnnotateScalarDefaults specifies whether the YANG default of leaves
+	// and leaf-lists whose type is not an enumeration or identityref should
+	// be annotated onto the generated field using the (yext.default_value)
+	// option. Since proto3 fields have no default, such defaults are
+	// otherwise not output. The option is repeated, such that each default
+	// of a leaf-list is annotated.
+	AnnotateScalarDefaults bool
+	// GenerateManifest specifies whether a manifest describing the
+	// generated messages - their fields, field numbers, and the schema
+	// paths that the fields correspond to - should be returned in the
+	// Manifest field of the generated output. The manifest can be written
+	// using WriteProtoManifest, and supplied to a subsequent generation,
+	// via its ReservedFieldTags method, such that the field numbers of
+	// removed fields are reserved.
+	GenerateManifest bool
+	// LeafListSemantics specifies whether leaf-list fields should be
+	// annotated with the (yext.leaflist_semantics) option, indicating
+	// whether the order of, and duplicates within, their values are
+	// meaningful. Leaf-lists that are ordered-by user are annotated as
+	// "list", whereas those that are ordered-by system, which is the
+	// default, are annotated as "set", since their values are unique and
+	// their order is not significant.
+	LeafListSemantics bool
+	// CommentStyle specifies the style of the comments that are output in
+	// the generated protobufs. By default, line comments are used.
+	CommentStyle ProtoCommentStyle
+	// PackagePolicy specifies how the protobuf package of each generated
+	// message is derived. By default, packages follow the path of the
+	// message's parent within the schema tree, which is compressed when
+	// CompressOCPaths is set.
+	PackagePolicy ProtoPackagePolicy
+	// MaxPackageDepth specifies the maximum number of elements, below the
+	// base package, of the name of each generated package. Where a package
+	// name would have more elements, those beyond the maximum are replaced
+	// by a single element, formed from the last of them and a hash of all
+	// of them, such that the name remains unique. The unabbreviated name
+	// of such a package is output in a comment in its header. If it is
+	// zero, package names are not abbreviated.
+	MaxPackageDepth int
+	// SplitConfigState specifies whether the leaves and leaf-lists of each
+	// generated message should be output in separate messages according
+	// to whether they are configuration or state. Writable leaves are
+	// output in a message named by appending Config to the name of the
+	// message, and read-only leaves in a message named by appending State.
+	// The original message contains fields named config and state that
+	// reference these messages.
+	SplitConfigState bool
+	// EnumZeroValueName specifies the label that is used for the value
+	// zero of each generated enum, which represents an unset value. It
+	// must be a valid protobuf identifier. If it is not specified, UNSET
+	// is used.
+	EnumZeroValueName string
+	// EnumZeroValuePolicy specifies whether the label of the value zero of
+	// each generated enum is prefixed with the name of the enum, as is the
+	// case for all other values.
+	EnumZeroValuePolicy ProtoEnumZeroValuePolicy
+	// EnumValueOffset specifies the offset that is added to the value of
+	// each YANG enumeration value to determine the value of the generated
+	// enum value, such that values below it are reserved, e.g., for
+	// sentinel values. The values of generated enums for identities are
+	// also at least the offset. The zero value is always used to indicate
+	// that a field is unset. If it is not specified, an offset of 1 is
+	// used. It does not apply to the enums generated for bits types, whose
+	// values are the positions of the bits.
+	EnumValueOffset int64
+	// EmptyEnumPolicy specifies how YANG identities that have no derived
+	// identities, and hence would be mapped to an enum containing only the
+	// zero value, are output.
+	EmptyEnumPolicy ProtoEmptyEnumPolicy
+	// OrderOneofsByTag specifies whether the fields within each oneof,
+	// such as those generated for a YANG union, should be output in
+	// ascending order of their field numbers. When unset, the fields of
+	// a oneof are output in the order of their types.
+	OrderOneofsByTag bool
+	// WhenPolicy specifies how fields that correspond to YANG schema nodes
+	// that have a when statement are output in the generated messages.
+	WhenPolicy ProtoWhenPolicy
+	// Proto2 specifies whether the generated protobufs should use proto2
+	// rather than proto3 syntax. When set, each field is explicitly
+	// labelled, with mandatory leaves and list keys being required, and
+	// scalar leaves are mapped to native protobuf types rather than the
+	// ywrapper types, since proto2 fields have explicit presence.
+	Proto2 bool
+	// GoPackageBase specifies the Go import path within which the Go code
+	// generated for the output protobuf packages is located. The
+	// go_package option of each package is set to this path joined with
+	// the directory of the package, e.g., GoPackageBase/base/child. If it
+	// is not specified, BaseImportPath is used, and if neither is specified
+	// then the go_package option is not output.
+	GoPackageBase string
+	// JavaPackageBase specifies the Java package within which the Java
+	// code generated for the output protobuf packages is located. The
+	// java_package option of each package is set to this package followed
+	// by the protobuf package name, e.g., com.example.base.child. If it is
+	// not specified, the java_package option is not output.
+	JavaPackageBase string
+	// ModulePackages specifies, keyed by YANG module name, the protobuf
+	// package that should be used for the entities within the module's
+	// data tree, relative to the base package. The package names for
+	// these entities are formed from the specified package followed by
+	// the path below the module, rather than being derived from the
+	// module's name. Messages that are in the base package when path
+	// compression is enabled are output in the specified package.
+	ModulePackages map[string]string
+	// GroupingMessageNames specifies whether the messages generated for
+	// containers and lists whose contents are entirely instantiated from a
+	// single YANG grouping should be named after the grouping, rather than
+	// after the container or list itself. This allows structures that are
+	// re-used at multiple points in the schema to be recognised. Since the
+	// grouping that an entry is instantiated from is only recorded by goyang
+	// when the StoreUses parse option is set, setting this option also sets
+	// StoreUses when the input YANG files are parsed.
+	GroupingMessageNames bool
 }
 
+// ProtoWhenPolicy specifies how fields that correspond to YANG schema nodes
+// with a when statement, and hence are only conditionally valid within the
+// data tree, are handled when generating protobuf messages.
+type ProtoWhenPolicy int64
+
+const (
+	// IncludeWhenNodes specifies that fields for nodes that have a when
+	// statement are included in the generated messages.
+	IncludeWhenNodes ProtoWhenPolicy = iota
+	// ExcludeWhenNodes specifies that fields for nodes that have a when
+	// statement are excluded from the generated messages.
+	ExcludeWhenNodes
+	// AnnotateWhenNodes specifies that fields for nodes that have a when
+	// statement are included in the generated messages, and annotated with
+	// the XPath expression of the when statement using the yext.when field
+	// option. Fields that are output as a oneof are not annotated, since
+	// field options cannot be specified for a oneof.
+	AnnotateWhenNodes
+)
+
+// ProtoCardinalityPolicy specifies how the number of elements that a YANG
+// list or leaf-list may contain, as specified by its min-elements and
+// max-elements statements, is output for the corresponding repeated field
+// when generating protobuf messages.
+type ProtoCardinalityPolicy int64
+
+const (
+	// OmitCardinality specifies that the cardinality of lists and
+	// leaf-lists is not output.
+	OmitCardinality ProtoCardinalityPolicy = iota
+	// CommentCardinality specifies that the cardinality of lists and
+	// leaf-lists is output as a comment above the corresponding field.
+	CommentCardinality
+	// AnnotateCardinality specifies that the cardinality of lists and
+	// leaf-lists is annotated onto the corresponding field using the
+	// yext.min_elements and yext.max_elements field options.
+	AnnotateCardinality
+	// CommentAndAnnotateCardinality specifies that the cardinality of lists
+	// and leaf-lists is output both as a comment and as field options.
+	CommentAndAnnotateCardinality
+)
+
+// ProtoEnumZeroValuePolicy specifies how the label of the value zero, which
+// represents an unset value, is output within each generated protobuf enum.
+type ProtoEnumZeroValuePolicy int64
+
+const (
+	// PrefixedEnumZeroValue specifies that the label of the zero value is
+	// prefixed with the name of the enum, as is the case for all other
+	// values, e.g., ADMIN_STATUS_UNSET. This ensures that the label is
+	// unique within the scope in which the enum is defined.
+	PrefixedEnumZeroValue ProtoEnumZeroValuePolicy = iota
+	// PlainEnumZeroValue specifies that the label of the zero value is
+	// output without a prefix, e.g., UNSET. Since protobuf requires enum
+	// value labels to be unique within the scope that contains the enum,
+	// this policy can only be used where there is a single enum within
+	// each scope.
+	PlainEnumZeroValue
+)
+
+// ProtoEmptyEnumPolicy specifies how a YANG identity that has no derived
+// identities is output within the generated protobufs. Since YANG requires
+// that an enumeration type has at least one enum statement, only identities
+// can result in such enumerated types.
+type ProtoEmptyEnumPolicy int64
+
+const (
+	// EmitEmptyEnums specifies that an enum is generated for a YANG identity
+	// that has no derived identities, which contains only the zero value.
+	EmitEmptyEnums ProtoEmptyEnumPolicy = iota
+	// StringEmptyEnums specifies that no enum is generated for a YANG
+	// identity that has no derived identities, and that identityref fields
+	// that reference it are mapped to a string.
+	StringEmptyEnums
+	// AnnotateEmptyEnums specifies that no enum is generated for a YANG
+	// identity that has no derived identities, and that identityref leaves
+	// that reference it are mapped to a string, which is annotated with the
+	// empty_enum field option.
+	AnnotateEmptyEnums
+)
+
+// ProtoPackagePolicy specifies how the protobuf package that each generated
+// message is output within is derived.
+type ProtoPackagePolicy int64
+
+const (
+	// PathPackages specifies that the package of each message is derived
+	// from the path of its parent within the schema tree, omitting the
+	// elements that are removed when path compression is enabled.
+	PathPackages ProtoPackagePolicy = iota
+	// UncompressedPathPackages specifies that the package of each message
+	// is derived from the full path of its parent within the schema tree,
+	// including the module, and those elements that are removed when path
+	// compression is enabled, such as the surrounding containers of lists.
+	UncompressedPathPackages
+	// SinglePackage specifies that all messages are output within the base
+	// package, or, where a package is specified in ModulePackages for the
+	// module that a message is within, within that package, such that
+	// there is at most a single package per module.
+	SinglePackage
+)
+
+// ProtoCommentStyle specifies the style of the comments that are output
+// within the generated protobufs.
+type ProtoCommentStyle int64
+
+const (
+	// LineComments specifies that comments are output as line comments,
+	// each line of which starts with //.
+	LineComments ProtoCommentStyle = iota
+	// BlockComments specifies that each comment is output as a block
+	// comment, delimited by /* and */. Comments that span multiple lines
+	// have their delimiters on separate lines, and each line of the
+	// comment prefixed by *.
+	BlockComments
+)
+
 // NewYANGCodeGenerator returns a new instance of the YANGCodeGenerator
 // struct to the calling function.
 func NewYANGCodeGenerator(c *GeneratorConfig) *YANGCodeGenerator {
@@ -256,6 +596,9 @@ type GeneratedProto3 struct {
 	// messages defined within the package. The calling application can write out the defined packages to the
 	// files expected by the protoc tool.
 	Packages map[string]Proto3Package
+	// Manifest describes the generated messages. It is populated only when
+	// the GenerateManifest option is set.
+	Manifest *ProtoManifest
 }
 
 // Proto3Package stores the code for a generated protobuf3 package.
@@ -444,18 +787,103 @@ func (cg *YANGCodeGenerator) GenerateGoCode(yangFiles, includePaths []string) (*
 // It returns a GeneratedProto3 struct containing the messages that are to be
 // output, along with any associated values (e.g., enumerations).
 func (cg *YANGCodeGenerator) GenerateProto3(yangFiles, includePaths []string) (*GeneratedProto3, util.Errors) {
-	mdef, errs := mappedDefinitions(yangFiles, includePaths, &cg.Config)
+	cfg := cg.Config
+	if cfg.ProtoOptions.GroupingMessageNames {
+		// The uses statements of each entry are required to determine the
+		// grouping from which it was instantiated.
+		cfg.YANGParseOptions.StoreUses = true
+	}
+	mdef, errs := mappedDefinitions(yangFiles, includePaths, &cfg)
 	if errs != nil {
 		return nil, errs
 	}
 
 	cg.state.schematree = mdef.schemaTree
+	cg.state.protoModulePackages = cg.Config.ProtoOptions.ModulePackages
+	cg.state.protoGroupingNames = cg.Config.ProtoOptions.GroupingMessageNames
+	cg.state.protoPackagePolicy = cg.Config.ProtoOptions.PackagePolicy
+	cg.state.protoMaxPackageDepth = cg.Config.ProtoOptions.MaxPackageDepth
+	cg.state.uniqueNameSuffix = cg.Config.ProtoOptions.UniqueNameSuffix
+
+	basePackageName := cg.Config.PackageName
+	if basePackageName == "" {
+		basePackageName = DefaultBasePackageName
+	}
+	enumPackageName := cg.Config.ProtoOptions.EnumPackageName
+	if enumPackageName == "" {
+		enumPackageName = DefaultEnumPackageName
+	}
+	ywrapperPath := cg.Config.ProtoOptions.YwrapperPath
+	if ywrapperPath == "" {
+		ywrapperPath = DefaultYwrapperPath
+	}
+	yextPath := cg.Config.ProtoOptions.YextPath
+	if yextPath == "" {
+		yextPath = DefaultYextPath
+	}
+
+	msgCfg := &protoMsgConfig{
+		compressPaths:       cg.Config.CompressOCPaths,
+		basePackageName:     basePackageName,
+		enumPackageName:     enumPackageName,
+		baseImportPath:      cg.Config.ProtoOptions.BaseImportPath,
+		annotateSchemaPaths: cg.Config.ProtoOptions.AnnotateSchemaPaths,
+		fieldDescriptions:   cg.Config.ProtoOptions.AnnotateDescriptions,
+		annotateEnumNames:   cg.Config.ProtoOptions.AnnotateEnumNames,
+		nestedMessages:      cg.Config.ProtoOptions.NestedMessages,
+		upperSnakeEnums:     cg.Config.ProtoOptions.UpperSnakeCaseEnumValues,
+		identityrefAsString: cg.Config.ProtoOptions.IdentityrefAsString,
+		identitiesFile:      cg.Config.ProtoOptions.IdentitiesFile,
+		integerTypes:        cg.Config.ProtoOptions.IntegerTypes,
+		schemaPathField:     cg.Config.ProtoOptions.SchemaPathField,
+		reservedTags:        cg.Config.ProtoOptions.ReservedFieldTags,
+		messageOptions:      cg.Config.ProtoOptions.MessageOptions,
+		orderOneofsByTag:    cg.Config.ProtoOptions.OrderOneofsByTag,
+		cardinalityPolicy:   cg.Config.ProtoOptions.CardinalityPolicy,
+		splitConfigState:    cg.Config.ProtoOptions.SplitConfigState,
+		enumZeroName:        cg.Config.ProtoOptions.EnumZeroValueName,
+		enumZeroPolicy:      cg.Config.ProtoOptions.EnumZeroValuePolicy,
+		enumValueOffset:     cg.Config.ProtoOptions.EnumValueOffset,
+		emptyEnumPolicy:     cg.Config.ProtoOptions.EmptyEnumPolicy,
+		escapeReservedWords: cg.Config.ProtoOptions.EscapeReservedWords,
+		nameSuffix:          cg.Config.ProtoOptions.UniqueNameSuffix,
+		typedefUnions:       cg.Config.ProtoOptions.TypedefUnionMessages,
+		qualifyNestedKeys:   cg.Config.ProtoOptions.QualifyNestedListKeys,
+		singleKeyMaps:       cg.Config.ProtoOptions.SingleKeyListsAsMaps,
+		annotateDefaults:    cg.Config.ProtoOptions.AnnotateEnumDefaults,
+		scalarDefaults:      cg.Config.ProtoOptions.AnnotateScalarDefaults,
+		manifest:            cg.Config.ProtoOptions.GenerateManifest,
+		leafListSemantics:   cg.Config.ProtoOptions.LeafListSemantics,
+		googleWrappers:      cg.Config.ProtoOptions.GoogleWrapperTypes,
+		whenPolicy:          cg.Config.ProtoOptions.WhenPolicy,
+		proto2:              cg.Config.ProtoOptions.Proto2,
+	}
+
+	// Validate the integer type mappings prior to generating any code, such that
+	// those for types that are not used in the schema are also checked.
+	for k := range msgCfg.integerTypes {
+		if _, err := protoIntegerType(k, "", msgCfg.integerTypes); err != nil {
+			return nil, []error{err}
+		}
+	}
+
+	if n := msgCfg.enumZeroName; n != "" && !isProtoIdentifier(n) {
+		return nil, []error{fmt.Errorf("invalid enum zero value name %q, must be a valid protobuf identifier", n)}
+	}
+
+	if o := msgCfg.enumValueOffset; o < 0 || o > protoMaxFieldTag {
+		return nil, []error{fmt.Errorf("invalid enum value offset %d, must be between 1 and %d, or 0 to use the default offset", o, protoMaxFieldTag)}
+	}
+
+	if sfx := msgCfg.nameSuffix; sfx != "" && !isProtoNameSuffix(sfx) {
+		return nil, []error{fmt.Errorf("invalid unique name suffix %q, must result in valid protobuf identifiers", sfx)}
+	}
 
 	penums, errs := cg.state.findEnumSet(mdef.enumEntries, cg.Config.CompressOCPaths, true)
 	if errs != nil {
 		return nil, errs
 	}
-	protoEnums, errs := writeProtoEnums(penums, cg.Config.ProtoOptions.AnnotateEnumNames)
+	protoEnums, protoIdentities, errs := writeProtoEnums(penums, msgCfg)
 	if errs != nil {
 		return nil, errs
 	}
@@ -468,6 +896,9 @@ func (cg *YANGCodeGenerator) GenerateProto3(yangFiles, includePaths []string) (*
 	genProto := &GeneratedProto3{
 		Packages: map[string]Proto3Package{},
 	}
+	if msgCfg.manifest {
+		genProto.Manifest = &ProtoManifest{}
+	}
 
 	// yerr stores errors encountered during code generation.
 	var yerr util.Errors
@@ -488,23 +919,6 @@ func (cg *YANGCodeGenerator) GenerateProto3(yangFiles, includePaths []string) (*
 	}
 	sort.Strings(msgPaths)
 
-	basePackageName := cg.Config.PackageName
-	if basePackageName == "" {
-		basePackageName = DefaultBasePackageName
-	}
-	enumPackageName := cg.Config.ProtoOptions.EnumPackageName
-	if enumPackageName == "" {
-		enumPackageName = DefaultEnumPackageName
-	}
-	ywrapperPath := cg.Config.ProtoOptions.YwrapperPath
-	if ywrapperPath == "" {
-		ywrapperPath = DefaultYwrapperPath
-	}
-	yextPath := cg.Config.ProtoOptions.YextPath
-	if yextPath == "" {
-		yextPath = DefaultYextPath
-	}
-
 	// Only create the enums package if there are enums that are within the schema.
 	if len(protoEnums) > 0 {
 		// Sort the set of enumerations so that they are deterministically output.
@@ -516,18 +930,21 @@ func (cg *YANGCodeGenerator) GenerateProto3(yangFiles, includePaths []string) (*
 		}
 	}
 
+	// Only create the identities package if there are identities that are to be
+	// output separately from the other enumerated types.
+	if len(protoIdentities) > 0 {
+		sort.Strings(protoIdentities)
+		fp := []string{basePackageName, protoIdentitiesPackageName, fmt.Sprintf("%s.proto", protoIdentitiesPackageName)}
+		genProto.Packages[fmt.Sprintf("%s.%s", basePackageName, protoIdentitiesPackageName)] = Proto3Package{
+			FilePath: fp,
+			Enums:    protoIdentities,
+		}
+	}
+
 	for _, n := range msgPaths {
 		m := msgMap[n]
 
-		genMsg, errs := writeProto3Msg(m, protoMsgs, cg.state, &protoMsgConfig{
-			compressPaths:       cg.Config.CompressOCPaths,
-			basePackageName:     basePackageName,
-			enumPackageName:     enumPackageName,
-			baseImportPath:      cg.Config.ProtoOptions.BaseImportPath,
-			annotateSchemaPaths: cg.Config.ProtoOptions.AnnotateSchemaPaths,
-			annotateEnumNames:   cg.Config.ProtoOptions.AnnotateEnumNames,
-			nestedMessages:      cg.Config.ProtoOptions.NestedMessages,
-		})
+		genMsg, errs := writeProto3Msg(m, protoMsgs, cg.state, msgCfg)
 
 		if errs != nil {
 			yerr = util.AppendErrs(yerr, errs)
@@ -546,6 +963,7 @@ func (cg *YANGCodeGenerator) GenerateProto3(yangFiles, includePaths []string) (*
 		} else {
 			genMsg.PackageName = fmt.Sprintf("%s.%s", basePackageName, genMsg.PackageName)
 		}
+		genProto.addManifestMessages(genMsg)
 
 		if pkgImports[genMsg.PackageName] == nil {
 			pkgImports[genMsg.PackageName] = map[string]interface{}{}
@@ -567,7 +985,65 @@ func (cg *YANGCodeGenerator) GenerateProto3(yangFiles, includePaths []string) (*
 		genProto.Packages[genMsg.PackageName] = tp
 	}
 
+	// The messages generated for union typedefs are shared by all fields of the
+	// type, and hence are output once all other messages have been generated.
+	if len(cg.state.protoTypedefUnions) > 0 {
+		var msgDefs []*protoMsg
+		for _, m := range cg.state.protoTypedefUnions {
+			msgDefs = append(msgDefs, m)
+		}
+		// The names of the messages are unique within the package, and hence
+		// are used to output them in a deterministic order.
+		sort.Slice(msgDefs, func(i, j int) bool { return msgDefs[i].Name < msgDefs[j].Name })
+
+		pkgName := fmt.Sprintf("%s.%s", basePackageName, protoUnionsPackageName)
+		genMsg, errs := genProto3MsgCode(pkgName, msgDefs, true)
+		if errs != nil {
+			yerr = util.AppendErrs(yerr, errs)
+		} else {
+			if pkgImports[pkgName] == nil {
+				pkgImports[pkgName] = map[string]interface{}{}
+			}
+			addNewKeys(pkgImports[pkgName], genMsg.RequiredImports)
+			genProto.addManifestMessages(genMsg)
+
+			tp, ok := genProto.Packages[pkgName]
+			if !ok {
+				tp = Proto3Package{
+					FilePath: protoPackageToFilePath(pkgName),
+					Messages: []string{},
+				}
+			}
+			tp.Messages = append(tp.Messages, genMsg.MessageCode)
+			genProto.Packages[pkgName] = tp
+		}
+	}
+
+	// Packages that import each other cannot be compiled, hence an error is
+	// returned rather than outputting them.
+	if c := protoImportCycle(pkgImports, cg.Config.ProtoOptions.BaseImportPath); c != nil {
+		yerr = util.AppendErr(yerr, fmt.Errorf("proto: generated packages have an import cycle: %s", strings.Join(c, " -> ")))
+	}
+
+	goPackageBase := cg.Config.ProtoOptions.GoPackageBase
+	if goPackageBase == "" {
+		goPackageBase = cg.Config.ProtoOptions.BaseImportPath
+	}
+
 	for n, pkg := range genProto.Packages {
+		var goPackage, javaPackage string
+		if goPackageBase != "" {
+			goPackage = path.Join(append([]string{goPackageBase}, pkg.FilePath[:len(pkg.FilePath)-1]...)...)
+		}
+		if jb := cg.Config.ProtoOptions.JavaPackageBase; jb != "" {
+			javaPackage = fmt.Sprintf("%s.%s", jb, n)
+		}
+
+		var abbreviatedFrom string
+		if full, ok := cg.state.protoAbbreviatedPackages[strings.TrimPrefix(n, basePackageName+".")]; ok {
+			abbreviatedFrom = fmt.Sprintf("%s.%s", basePackageName, full)
+		}
+
 		h, err := writeProto3Header(proto3Header{
 			PackageName:            n,
 			Imports:                stringKeys(pkgImports[n]),
@@ -577,12 +1053,26 @@ func (cg *YANGCodeGenerator) GenerateProto3(yangFiles, includePaths []string) (*
 			CallerName:             cg.Config.Caller,
 			YwrapperPath:           ywrapperPath,
 			YextPath:               yextPath,
+			Proto2:                 cg.Config.ProtoOptions.Proto2,
+			GoPackage:              goPackage,
+			JavaPackage:            javaPackage,
+			GoogleWrappers:         cg.Config.ProtoOptions.GoogleWrapperTypes && !cg.Config.ProtoOptions.Proto2,
+			AbbreviatedFrom:        abbreviatedFrom,
 		})
 		if err != nil {
 			yerr = util.AppendErrs(yerr, errs)
 			continue
 		}
 		pkg.Header = h
+		if cg.Config.ProtoOptions.CommentStyle == BlockComments {
+			pkg.Header = blockComments(pkg.Header)
+			for i, m := range pkg.Messages {
+				pkg.Messages[i] = blockComments(m)
+			}
+			for i, e := range pkg.Enums {
+				pkg.Enums[i] = blockComments(e)
+			}
+		}
 		genProto.Packages[n] = pkg
 	}
 
@@ -590,9 +1080,74 @@ func (cg *YANGCodeGenerator) GenerateProto3(yangFiles, includePaths []string) (*
 		return nil, yerr
 	}
 
+	if genProto.Manifest != nil {
+		sortProtoManifest(genProto.Manifest)
+	}
+
 	return genProto, nil
 }
 
+// addManifestMessages adds the messages described by the generated message
+// m to the manifest of the generated code, if a manifest is being generated.
+func (g *GeneratedProto3) addManifestMessages(m *generatedProto3Message) {
+	if g.Manifest == nil {
+		return
+	}
+	for _, mm := range m.Manifest {
+		mm.Package = m.PackageName
+		g.Manifest.Messages = append(g.Manifest.Messages, mm)
+	}
+}
+
+// Proto3CodeOpts stores the options used when generating protobufs using the
+// GenerateProto3Code function.
+type Proto3CodeOpts struct {
+	// CompressPaths specifies whether the paths of the input OpenConfig
+	// schema should be compressed in the generated protobufs.
+	CompressPaths bool
+	// BasePackageName is the name of the protobuf package within which all
+	// other generated packages are contained. If it is not specified,
+	// DefaultBasePackageName is used.
+	BasePackageName string
+	// EnumPackageName is the name of the package, within the base package,
+	// within which enumerated types that are used in multiple parts of the
+	// schema are output. If it is not specified, the EnumPackageName
+	// specified within ProtoOptions is used, and if neither is specified
+	// DefaultEnumPackageName is used.
+	EnumPackageName string
+	// CallerName is the name of the binary calling the generator library,
+	// it is included in the header of the generated files.
+	CallerName string
+	// ProtoOptions stores the remaining Protobuf specific options.
+	ProtoOptions ProtoOpts
+}
+
+// GenerateProto3Code generates Protobuf 3 code for the YANG modules in
+// yangFiles, with included modules being searched for in includePaths, using
+// the options specified in opts. It returns a GeneratedProto3 struct
+// containing the contents of each generated package, keyed by the name of the
+// package. It is a convenience wrapper for callers that do not otherwise
+// require a YANGCodeGenerator.
+func GenerateProto3Code(yangFiles, includePaths []string, opts Proto3CodeOpts) (*GeneratedProto3, error) {
+	protoOpts := opts.ProtoOptions
+	if opts.EnumPackageName != "" {
+		protoOpts.EnumPackageName = opts.EnumPackageName
+	}
+
+	cg := NewYANGCodeGenerator(&GeneratorConfig{
+		CompressOCPaths: opts.CompressPaths,
+		PackageName:     opts.BasePackageName,
+		Caller:          opts.CallerName,
+		ProtoOptions:    protoOpts,
+	})
+
+	out, errs := cg.GenerateProto3(yangFiles, includePaths)
+	if errs != nil {
+		return nil, errs
+	}
+	return out, nil
+}
+
 // processModules takes a list of the filenames of YANG modules (yangFiles),
 // and a list of paths in which included modules or submodules may be found,
 // and returns a processed set of yang.Entry pointers which correspond to the
@@ -623,6 +1178,10 @@ func processModules(yangFiles, includePaths []string, options yang.Options) ([]*
 		return nil, errs
 	}
 
+	// Processing the modules also applies any deviations that they specify,
+	// such that the entries that are returned, and hence the generated code,
+	// reflect the deviated schema - e.g., with substatements that are added
+	// or replaced by a deviate statement.
 	if errs := moduleSet.Process(); errs != nil {
 		return nil, errs
 	}
@@ -21,6 +21,7 @@ import (
 	"io/ioutil"
 	"path/filepath"
 	"reflect"
+	"strings"
 	"testing"
 
 	"github.com/kylelemons/godebug/pretty"
@@ -1212,6 +1213,143 @@ func TestGenerateProto3(t *testing.T) {
 	}
 }
 
+func TestGenerateProto3Deviations(t *testing.T) {
+	inFiles := []string{filepath.Join(TestRoot, "testdata", "proto", "proto-deviations.yang")}
+	cg := NewYANGCodeGenerator(&GeneratorConfig{
+		Caller: "codegen-tests",
+		ProtoOptions: ProtoOpts{
+			AnnotateScalarDefaults: true,
+		},
+	})
+	got, err := cg.GenerateProto3(inFiles, nil)
+	if err != nil {
+		t.Fatalf("cg.GenerateProto3(%v, nil): got unexpected error: %v", inFiles, err)
+	}
+
+	pkg, ok := got.Packages["openconfig.proto_deviations"]
+	if !ok {
+		t.Fatalf("cg.GenerateProto3(%v, nil): did not find package openconfig.proto_deviations, got: %v", inFiles, got.Packages)
+	}
+	var parent string
+	for _, m := range pkg.Messages {
+		if strings.Contains(m, "message Parent {") {
+			parent = m
+		}
+	}
+	if parent == "" {
+		t.Fatalf("cg.GenerateProto3(%v, nil): did not find message Parent, got: %v", inFiles, pkg.Messages)
+	}
+
+	if !strings.Contains(parent, `replaced_default = `) || !strings.Contains(parent, `(yext.default_value) = "deviated"`) {
+		t.Errorf("cg.GenerateProto3(%v, nil): did not get default replaced by deviation, got:\n%s", inFiles, parent)
+	}
+	if strings.Contains(parent, `"original"`) {
+		t.Errorf("cg.GenerateProto3(%v, nil): got default of leaf prior to deviation, got:\n%s", inFiles, parent)
+	}
+	if strings.Contains(parent, "unsupported") {
+		t.Errorf("cg.GenerateProto3(%v, nil): got field for leaf that is not-supported, got:\n%s", inFiles, parent)
+	}
+}
+
+func TestGenerateProto3GroupingMessageNames(t *testing.T) {
+	inFiles := []string{filepath.Join(TestRoot, "testdata", "proto", "proto-grouping-names.yang")}
+	// The StoreUses parse option is deliberately not set, since it should be
+	// set by the generator when grouping message names are requested.
+	cg := NewYANGCodeGenerator(&GeneratorConfig{
+		Caller: "codegen-tests",
+		ProtoOptions: ProtoOpts{
+			GroupingMessageNames: true,
+		},
+	})
+	got, err := cg.GenerateProto3(inFiles, nil)
+	if err != nil {
+		t.Fatalf("cg.GenerateProto3(%v, nil): got unexpected error: %v", inFiles, err)
+	}
+
+	for _, pkg := range []string{"openconfig.proto_grouping_names.eth", "openconfig.proto_grouping_names.lag"} {
+		p, ok := got.Packages[pkg]
+		if !ok {
+			t.Errorf("cg.GenerateProto3(%v, nil): did not find package %s, got: %v", inFiles, pkg, got.Packages)
+			continue
+		}
+		var found bool
+		for _, m := range p.Messages {
+			if strings.Contains(m, "message InterfaceCounters {") {
+				found = true
+			}
+		}
+		if !found {
+			t.Errorf("cg.GenerateProto3(%v, nil): did not find message named after grouping in package %s, got: %v", inFiles, pkg, p.Messages)
+		}
+	}
+}
+
+func TestGenerateProto3Code(t *testing.T) {
+	tests := []struct {
+		name            string
+		inFiles         []string
+		inOpts          Proto3CodeOpts
+		wantOutputFiles map[string]string
+		wantErr         bool
+	}{{
+		name:    "simple module with compression",
+		inFiles: []string{filepath.Join(TestRoot, "testdata", "proto", "proto-test-a.yang")},
+		inOpts: Proto3CodeOpts{
+			CompressPaths: true,
+			CallerName:    "codegen-tests",
+		},
+		wantOutputFiles: map[string]string{
+			"openconfig":        filepath.Join(TestRoot, "testdata", "proto", "proto-test-a.compress.parent.formatted-txt"),
+			"openconfig.parent": filepath.Join(TestRoot, "testdata", "proto", "proto-test-a.compress.parent.child.formatted-txt"),
+		},
+	}, {
+		name:    "non-existent module",
+		inFiles: []string{filepath.Join(TestRoot, "testdata", "proto", "does-not-exist.yang")},
+		inOpts:  Proto3CodeOpts{CallerName: "codegen-tests"},
+		wantErr: true,
+	}}
+
+	for _, tt := range tests {
+		got, err := GenerateProto3Code(tt.inFiles, nil, tt.inOpts)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: GenerateProto3Code(%v, nil, %v): did not get expected error status, got: %v, wantErr: %v", tt.name, tt.inFiles, tt.inOpts, err, tt.wantErr)
+		}
+		if err != nil {
+			continue
+		}
+
+		if len(got.Packages) != len(tt.wantOutputFiles) {
+			t.Errorf("%s: GenerateProto3Code(%v, nil, %v): did not get expected number of packages, got: %d, want: %d", tt.name, tt.inFiles, tt.inOpts, len(got.Packages), len(tt.wantOutputFiles))
+		}
+
+		for pkg, wantFile := range tt.wantOutputFiles {
+			wantCode, err := ioutil.ReadFile(wantFile)
+			if err != nil {
+				t.Fatalf("%s: ioutil.ReadFile(%v): could not read file for package %s", tt.name, wantFile, pkg)
+			}
+
+			gotPkg, ok := got.Packages[pkg]
+			if !ok {
+				t.Errorf("%s: GenerateProto3Code(%v, nil, %v): did not find expected package %s in output", tt.name, tt.inFiles, tt.inOpts, pkg)
+				continue
+			}
+
+			var gotCode bytes.Buffer
+			gotCode.WriteString(gotPkg.Header)
+			for _, m := range gotPkg.Messages {
+				fmt.Fprintf(&gotCode, "%s\n", m)
+			}
+			for _, e := range gotPkg.Enums {
+				gotCode.WriteString(e)
+			}
+
+			if diff, _ := testutil.GenerateUnifiedDiff(gotCode.String(), string(wantCode)); diff != "" {
+				t.Errorf("%s: GenerateProto3Code(%v, nil, %v) for package %s, did not get expected code (code file: %v), diff(-got,+want):\n%s", tt.name, tt.inFiles, tt.inOpts, pkg, wantFile, diff)
+			}
+		}
+	}
+}
+
 func TestCreateFakeRoot(t *testing.T) {
 	tests := []struct {
 		name            string
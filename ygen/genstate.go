@@ -78,11 +78,40 @@ type genState struct {
 	// a path to be resolved into the calculated Protobuf package name that
 	// is to be used for it.
 	uniqueProtoPackages map[string]string
+	// protoTypedefUnions is a map, keyed by the name of a YANG typedef in the
+	// form module-name/typedef-name, of the protobuf message that is shared by
+	// all fields whose type is the typedef, when union typedefs are output as
+	// messages within the unions package.
+	protoTypedefUnions map[string]*protoMsg
 	// generatedUnions stores a map, keyed by the output name for a union,
 	// that has already been output in the generated code. This ensures that
 	// where two entities re-use a union that has already been created (e.g.,
 	// a leafref to a union) then it is output only once in the generated code.
 	generatedUnions map[string]bool
+	// protoModulePackages is a map, keyed by YANG module name, of the protobuf
+	// package name that should be used in place of the module's name when
+	// deriving the package names for the entities within the module's data tree.
+	protoModulePackages map[string]string
+	// protoGroupingNames specifies whether protobuf messages for entities
+	// whose contents are instantiated from a single grouping should be named
+	// after the grouping.
+	protoGroupingNames bool
+	// protoPackagePolicy specifies how the protobuf package of each
+	// generated message is derived.
+	protoPackagePolicy ProtoPackagePolicy
+	// protoMaxPackageDepth specifies the maximum number of elements of the
+	// name of each generated protobuf package, beyond which the name is
+	// abbreviated. If it is zero, names are not abbreviated.
+	protoMaxPackageDepth int
+	// protoAbbreviatedPackages is a map, keyed by the name of each
+	// generated protobuf package whose name was abbreviated, of the
+	// unabbreviated name of the package.
+	protoAbbreviatedPackages map[string]string
+	// uniqueNameSuffix is the suffix used to disambiguate the names of
+	// messages and enumerated types that would otherwise clash, as
+	// described by makeNameUniqueWithSuffix. If it is empty, an underscore
+	// is used.
+	uniqueNameSuffix string
 }
 
 // newGenState creates a new genState instance, initialised with the default state
@@ -101,10 +130,18 @@ func newGenState() *genState {
 		uniqueEnumeratedLeafNames:    make(map[string]string),
 		uniqueProtoMsgNames:          make(map[string]map[string]bool),
 		uniqueProtoPackages:          make(map[string]string),
+		protoAbbreviatedPackages:     make(map[string]string),
+		protoTypedefUnions:           make(map[string]*protoMsg),
 		generatedUnions:              make(map[string]bool),
 	}
 }
 
+// makeNameUnique makes the name specified unique within the definedNames map,
+// using the suffix for disambiguated names that is configured for the genState.
+func (s *genState) makeNameUnique(name string, definedNames map[string]bool) string {
+	return makeNameUniqueWithSuffix(name, definedNames, s.uniqueNameSuffix)
+}
+
 // enumeratedUnionEntry takes an input YANG union yang.Entry and returns the set of enumerated
 // values that should be generated for the entry. New yang.Entry instances are synthesised within
 // the yangEnums returned such that enumerations can be generated directly from the output of
@@ -406,7 +443,7 @@ func (s *genState) identityrefBaseTypeFromIdentity(i *yang.Identity, noUnderscor
 	}
 	// The name of an identityref base type must be unique within the entire generated
 	// code, so the context of name generation is global.
-	uniqueName := makeNameUnique(name, s.definedGlobals)
+	uniqueName := s.makeNameUnique(name, s.definedGlobals)
 	s.uniqueIdentityNames[identityKey] = uniqueName
 	return uniqueName
 }
@@ -463,7 +500,7 @@ func (s *genState) resolveEnumName(e *yang.Entry, compressPaths, noUnderscores b
 		if noUnderscores {
 			name = strings.Replace(name, "_", "", -1)
 		}
-		uniqueName := makeNameUnique(name, s.definedGlobals)
+		uniqueName := s.makeNameUnique(name, s.definedGlobals)
 		s.uniqueEnumeratedLeafNames[identifierPath] = uniqueName
 		return uniqueName
 	}
@@ -476,7 +513,7 @@ func (s *genState) resolveEnumName(e *yang.Entry, compressPaths, noUnderscores b
 		}
 		nbuf.WriteString(yang.CamelCase(p))
 	}
-	uniqueName := makeNameUnique(nbuf.String(), s.definedGlobals)
+	uniqueName := s.makeNameUnique(nbuf.String(), s.definedGlobals)
 	s.uniqueEnumeratedLeafNames[identifierPath] = uniqueName
 	return uniqueName
 }
@@ -528,7 +565,7 @@ func (s *genState) resolveTypedefEnumeratedName(e *yang.Entry, noUnderscores boo
 	if noUnderscores {
 		name = strings.Replace(name, "_", "", -1)
 	}
-	uniqueName := makeNameUnique(name, s.definedGlobals)
+	uniqueName := s.makeNameUnique(name, s.definedGlobals)
 	s.uniqueEnumeratedTypedefNames[typedefKey] = uniqueName
 	return uniqueName, nil
 }
@@ -601,3 +638,24 @@ func (s *genState) resolveLeafrefTarget(path string, contextEntry *yang.Entry) (
 
 	return target, nil
 }
+
+// resolveLeafrefChain takes an input entry e, and follows the leafrefs that it,
+// and the leaves that it references, are of until a leaf whose type is not a
+// leafref is found. The leaf that is found is returned, or e if it is not a
+// leafref. An error is returned if the leafrefs form a cycle, since the type of
+// the leaves within the cycle cannot be determined.
+func (s *genState) resolveLeafrefChain(e *yang.Entry) (*yang.Entry, error) {
+	seen := map[*yang.Entry]bool{}
+	for e.Type != nil && e.Type.Kind == yang.Yleafref {
+		if seen[e] {
+			return nil, fmt.Errorf("leafref %s is within a cycle of leafrefs", e.Path())
+		}
+		seen[e] = true
+		target, err := s.resolveLeafrefTarget(e.Type.Path, e)
+		if err != nil {
+			return nil, err
+		}
+		e = target
+	}
+	return e, nil
+}
@@ -179,12 +179,31 @@ func (s *genState) goStructName(e *yang.Entry, compressOCPaths, genFakeRoot bool
 // definedNames map. If the name has already been defined, an underscore is appended
 // to the name until it is unique.
 func makeNameUnique(name string, definedNames map[string]bool) string {
-	for {
-		if _, nameUsed := definedNames[name]; !nameUsed {
-			definedNames[name] = true
-			return name
+	return makeNameUniqueWithSuffix(name, definedNames, "")
+}
+
+// makeNameUniqueWithSuffix makes the name specified unique based on the names
+// already defined within the definedNames map, using the suffix supplied to
+// disambiguate it. If the suffix is empty, an underscore is appended to the name
+// until it is unique, as per makeNameUnique. If the suffix contains the "%d" verb,
+// the suffix is formatted with an integer counter, starting at 1, which is
+// incremented until the suffixed name is unique. Otherwise, the suffix is appended
+// to the name until it is unique.
+func makeNameUniqueWithSuffix(name string, definedNames map[string]bool, suffix string) string {
+	if suffix == "" {
+		suffix = "_"
+	}
+	candidate := name
+	for i := 1; ; i++ {
+		if _, nameUsed := definedNames[candidate]; !nameUsed {
+			definedNames[candidate] = true
+			return candidate
+		}
+		if strings.Contains(suffix, "%d") {
+			candidate = fmt.Sprintf("%s%s", name, fmt.Sprintf(suffix, i))
+			continue
 		}
-		name = fmt.Sprintf("%s_", name)
+		candidate = fmt.Sprintf("%s%s", candidate, suffix)
 	}
 }
 
@@ -941,6 +941,10 @@ func (t *{{ .ParentReceiver }}) To_{{ .Name }}(i interface{}) ({{ .Name }}, erro
 			return i + 1
 		},
 		"toUpper": strings.ToUpper,
+		// commentLines splits a string into the lines that it should be output
+		// as within a comment, wrapping lines that are longer than
+		// protoCommentWidth.
+		"commentLines": func(s string) []string { return wrapCommentLines(s, protoCommentWidth) },
 		"indentLines": func(s string) string {
 			var b bytes.Buffer
 			p := strings.Split(s, "\n")
@@ -16,6 +16,7 @@ package ygen
 
 import (
 	"fmt"
+	"hash/fnv"
 	"sort"
 	"strings"
 
@@ -33,10 +34,43 @@ type resolveProtoTypeArgs struct {
 	// enumPackageName is the name of the package within which global enumerated values
 	// are defined (i.e., typedefs that contain enumerations, or YANG identities).
 	enumPackageName string
+	// identityPackageName is the name of the package within which the enumerated
+	// values generated for YANG identities are defined. If it is not specified, the
+	// enumPackageName is used.
+	identityPackageName string
+	// integerTypes specifies the protobuf type that should be used for each
+	// YANG integer type. Integer types that are not specified are mapped to
+	// the default ywrapper type.
+	integerTypes map[yang.TypeKind]string
 	// scalaraTypeInSingleTypeUnion specifies whether scalar types should be used
 	// when a union contains only one base type, or whether the protobuf wrapper
 	// types should be used.
 	scalarTypeInSingleTypeUnion bool
+	// skipEmptyIdentities specifies whether identityrefs whose base identity has
+	// no derived identities should be mapped to strings, since no enumerated type
+	// is generated for the base identity.
+	skipEmptyIdentities bool
+	// googleWrappers specifies whether the google.protobuf wrapper types should be
+	// used in place of the equivalent ywrapper types.
+	googleWrappers bool
+}
+
+// wrapperType returns the wrapper type that should be used in place of the
+// ywrapper type t. If the arguments specify that the google.protobuf wrapper
+// types are to be used, and there is an equivalent of t in googleWrapperTypes,
+// it is returned, otherwise t is returned.
+func (pargs resolveProtoTypeArgs) wrapperType(t string) string {
+	if gt, ok := googleWrapperTypes[t]; ok && pargs.googleWrappers {
+		return gt
+	}
+	return t
+}
+
+// isSkippedIdentityref returns true if t is an identityref whose base identity
+// has no derived identities, and the arguments specify that such identityrefs
+// are to be mapped to strings.
+func (pargs resolveProtoTypeArgs) isSkippedIdentityref(t *yang.YangType) bool {
+	return pargs.skipEmptyIdentities && t.Kind == yang.Yidentityref && t.IdentityBase != nil && len(t.IdentityBase.Values) == 0
 }
 
 // yangTypeToProtoType takes an input resolveTypeArgs (containing a yang.YangType
@@ -50,8 +84,14 @@ type resolveProtoTypeArgs struct {
 // See https://github.com/openconfig/ygot/blob/master/docs/yang-to-protobuf-transformations-spec.md
 // for additional details as to the transformation from YANG to Protobuf.
 func (s *genState) yangTypeToProtoType(args resolveTypeArgs, pargs resolveProtoTypeArgs) (*mappedType, error) {
+	// Identityrefs for which no enumerated type is generated are mapped to
+	// strings, regardless of whether they are defined within a typedef.
+	if pargs.isSkippedIdentityref(args.yangType) {
+		return &mappedType{nativeType: pargs.wrapperType("ywrapper.StringValue")}, nil
+	}
+
 	// Handle typedef cases.
-	mtype, err := s.enumeratedTypedefTypeName(args, fmt.Sprintf("%s.%s.", pargs.basePackageName, pargs.enumPackageName), true)
+	mtype, err := s.enumeratedTypedefTypeName(args, pargs.globalEnumPrefix(args.yangType), true)
 	if err != nil {
 		return nil, err
 	}
@@ -63,17 +103,31 @@ func (s *genState) yangTypeToProtoType(args resolveTypeArgs, pargs resolveProtoT
 
 	switch args.yangType.Kind {
 	case yang.Yint8, yang.Yint16, yang.Yint32, yang.Yint64:
-		return &mappedType{nativeType: "ywrapper.IntValue"}, nil
+		t, err := protoIntegerType(args.yangType.Kind, pargs.wrapperType("ywrapper.IntValue"), pargs.integerTypes)
+		if err != nil {
+			return nil, err
+		}
+		return &mappedType{nativeType: t}, nil
 	case yang.Yuint8, yang.Yuint16, yang.Yuint32, yang.Yuint64:
-		return &mappedType{nativeType: "ywrapper.UintValue"}, nil
+		t, err := protoIntegerType(args.yangType.Kind, pargs.wrapperType("ywrapper.UintValue"), pargs.integerTypes)
+		if err != nil {
+			return nil, err
+		}
+		return &mappedType{nativeType: t}, nil
 	case yang.Ybinary:
-		return &mappedType{nativeType: "ywrapper.BytesValue"}, nil
+		return &mappedType{nativeType: pargs.wrapperType("ywrapper.BytesValue")}, nil
 	case yang.Ybool, yang.Yempty:
-		return &mappedType{nativeType: "ywrapper.BoolValue"}, nil
+		return &mappedType{nativeType: pargs.wrapperType("ywrapper.BoolValue")}, nil
 	case yang.Ystring:
-		return &mappedType{nativeType: "ywrapper.StringValue"}, nil
+		return &mappedType{nativeType: pargs.wrapperType("ywrapper.StringValue")}, nil
 	case yang.Ydecimal64:
+		// There is no google.protobuf wrapper type that can represent a decimal64
+		// without loss of precision, hence the ywrapper type is always used.
 		return &mappedType{nativeType: "ywrapper.Decimal64Value"}, nil
+	case yang.Ybits:
+		// Bits are represented as a bitmask, where the bit at each position
+		// defined in the YANG type is set if the corresponding bit is set.
+		return &mappedType{nativeType: pargs.wrapperType("ywrapper.UintValue")}, nil
 	case yang.Yleafref:
 		// We look up the leafref in the schema tree to be able to
 		// determine what type to map to.
@@ -81,6 +135,11 @@ func (s *genState) yangTypeToProtoType(args resolveTypeArgs, pargs resolveProtoT
 		if err != nil {
 			return nil, err
 		}
+		// The target may itself be a leafref, in which case the leaf that
+		// it ultimately references determines the type.
+		if target, err = s.resolveLeafrefChain(target); err != nil {
+			return nil, err
+		}
 		return s.yangTypeToProtoType(resolveTypeArgs{yangType: target.Type, contextEntry: target}, pargs)
 	case yang.Yenum:
 		// Return any enumeration simply as the leaf's CamelCase name
@@ -108,21 +167,95 @@ func (s *genState) yangTypeToProtoType(args resolveTypeArgs, pargs resolveProtoT
 		return s.protoUnionType(args, pargs)
 	default:
 		// TODO(robjs): Implement types that are missing within this function.
-		// Missing types are:
-		//  - binary
-		//  - bits
 		// We cannot return an interface{} in protobuf, so therefore
 		// we just throw an error with types that we cannot map.
 		return nil, fmt.Errorf("unimplemented type: %v", args.yangType.Kind)
 	}
 }
 
+// integerWidth describes the range of values that can be represented by an
+// integer type.
+type integerWidth struct {
+	signed bool // signed indicates whether the type can represent negative values.
+	bits   int  // bits is the number of bits used to represent the value.
+}
+
+var (
+	// yangIntegerWidths defines the width of each of the YANG integer types.
+	yangIntegerWidths = map[yang.TypeKind]integerWidth{
+		yang.Yint8:   {signed: true, bits: 8},
+		yang.Yint16:  {signed: true, bits: 16},
+		yang.Yint32:  {signed: true, bits: 32},
+		yang.Yint64:  {signed: true, bits: 64},
+		yang.Yuint8:  {signed: false, bits: 8},
+		yang.Yuint16: {signed: false, bits: 16},
+		yang.Yuint32: {signed: false, bits: 32},
+		yang.Yuint64: {signed: false, bits: 64},
+	}
+
+	// googleWrapperTypes maps each of the ywrapper types that are used to
+	// represent YANG leaves to the equivalent google.protobuf wrapper type.
+	googleWrapperTypes = map[string]string{
+		"ywrapper.BoolValue":   "google.protobuf.BoolValue",
+		"ywrapper.BytesValue":  "google.protobuf.BytesValue",
+		"ywrapper.IntValue":    "google.protobuf.Int64Value",
+		"ywrapper.StringValue": "google.protobuf.StringValue",
+		"ywrapper.UintValue":   "google.protobuf.UInt64Value",
+	}
+
+	// protoIntegerWidths defines the width of each of the protobuf types that can
+	// be used to represent a YANG integer type.
+	protoIntegerWidths = map[string]integerWidth{
+		"ywrapper.IntValue":  {signed: true, bits: 64},
+		"ywrapper.UintValue": {signed: false, bits: 64},
+		"int32":              {signed: true, bits: 32},
+		"int64":              {signed: true, bits: 64},
+		"sint32":             {signed: true, bits: 32},
+		"sint64":             {signed: true, bits: 64},
+		"sfixed32":           {signed: true, bits: 32},
+		"sfixed64":           {signed: true, bits: 64},
+		"uint32":             {signed: false, bits: 32},
+		"uint64":             {signed: false, bits: 64},
+		"fixed32":            {signed: false, bits: 32},
+		"fixed64":            {signed: false, bits: 64},
+	}
+)
+
+// protoIntegerType returns the protobuf type that should be used to represent
+// the YANG integer type kind. If the integerTypes map specifies a type for kind
+// it is returned, otherwise defaultType is returned. An error is returned if the
+// type specified cannot represent all values of the YANG type.
+func protoIntegerType(kind yang.TypeKind, defaultType string, integerTypes map[yang.TypeKind]string) (string, error) {
+	t, ok := integerTypes[kind]
+	if !ok {
+		return defaultType, nil
+	}
+
+	yw, ok := yangIntegerWidths[kind]
+	if !ok {
+		return "", fmt.Errorf("cannot map %v to protobuf type %s, it is not a YANG integer type", kind, t)
+	}
+
+	pw, ok := protoIntegerWidths[t]
+	switch {
+	case !ok:
+		return "", fmt.Errorf("invalid protobuf type %s specified for %v, not an integer type", t, kind)
+	case yw.signed && !pw.signed, pw.bits < yw.bits, pw.signed && !yw.signed && pw.bits == yw.bits:
+		return "", fmt.Errorf("protobuf type %s cannot represent all values of %v", t, kind)
+	}
+	return t, nil
+}
+
 // yangTypeToProtoScalarType takes an input resolveTypeArgs and returns the protobuf
 // in-built type that is used to represent it. It is used within list keys where the
 // value cannot be nil/unset.
 func (s *genState) yangTypeToProtoScalarType(args resolveTypeArgs, pargs resolveProtoTypeArgs) (*mappedType, error) {
+	if pargs.isSkippedIdentityref(args.yangType) {
+		return &mappedType{nativeType: "string"}, nil
+	}
+
 	// Handle typedef cases.
-	mtype, err := s.enumeratedTypedefTypeName(args, fmt.Sprintf("%s.%s.", pargs.basePackageName, pargs.enumPackageName), true)
+	mtype, err := s.enumeratedTypedefTypeName(args, pargs.globalEnumPrefix(args.yangType), true)
 	if err != nil {
 		return nil, err
 	}
@@ -146,11 +279,16 @@ func (s *genState) yangTypeToProtoScalarType(args resolveTypeArgs, pargs resolve
 		// Decimal64 continues to be a message even when we are mapping scalars
 		// as there is not an equivalent Protobuf type.
 		return &mappedType{nativeType: "ywrapper.Decimal64Value"}, nil
+	case yang.Ybits:
+		return &mappedType{nativeType: "uint64"}, nil
 	case yang.Yleafref:
 		target, err := s.resolveLeafrefTarget(args.yangType.Path, args.contextEntry)
 		if err != nil {
 			return nil, err
 		}
+		if target, err = s.resolveLeafrefChain(target); err != nil {
+			return nil, err
+		}
 		return s.yangTypeToProtoScalarType(resolveTypeArgs{yangType: target.Type, contextEntry: target}, pargs)
 	case yang.Yenum:
 		// Return any enumeration simply as the leaf's CamelCase name
@@ -176,8 +314,6 @@ func (s *genState) yangTypeToProtoScalarType(args resolveTypeArgs, pargs resolve
 		return s.protoUnionType(args, pargs)
 	default:
 		// TODO(robjs): implement missing types.
-		//	- binary
-		//	- bits
 		return nil, fmt.Errorf("unimplemented type in scalar generation: %s", args.yangType.Kind)
 	}
 }
@@ -213,7 +349,7 @@ func (s *genState) protoUnionType(args resolveTypeArgs, pargs resolveProtoTypeAr
 		for st, t := range unionTypes {
 			// Handle the case whereby there is an identityref and we simply
 			// want to return the type that has been resolved.
-			if t.Kind == yang.Yidentityref || t.Kind == yang.Yenum {
+			if (t.Kind == yang.Yidentityref && !pargs.isSkippedIdentityref(t)) || t.Kind == yang.Yenum {
 				return &mappedType{
 					nativeType:        st,
 					isEnumeratedValue: true,
@@ -280,6 +416,10 @@ func (s *genState) protoUnionSubTypes(subtype *yang.YangType, ctx *yang.Entry, c
 	var mtype *mappedType
 	switch subtype.Kind {
 	case yang.Yidentityref:
+		if pargs.isSkippedIdentityref(subtype) {
+			mtype = &mappedType{nativeType: "string"}
+			break
+		}
 		// Handle the case that the context entry is not the correct entry to deal with. This occurs when the subtype is
 		// an identityref.
 		mtype = &mappedType{
@@ -305,7 +445,9 @@ func (s *genState) protoUnionSubTypes(subtype *yang.YangType, ctx *yang.Entry, c
 
 // protoMsgName takes a yang.Entry and converts it to its protobuf message name,
 // ensuring that the name that is returned is unique within the package that it is
-// being contained within.
+// being contained within. If the protoGroupingNames field of the receiver is set,
+// and the contents of the entry are instantiated from a single grouping, the
+// message is named after the grouping.
 func (s *genState) protoMsgName(e *yang.Entry, compressPaths bool) string {
 	// Return a cached name if one has already been computed.
 	if n, ok := s.uniqueDirectoryNames[e.Path()]; ok {
@@ -317,7 +459,14 @@ func (s *genState) protoMsgName(e *yang.Entry, compressPaths bool) string {
 		s.uniqueProtoMsgNames[pkg] = make(map[string]bool)
 	}
 
-	n := makeNameUnique(yang.CamelCase(e.Name), s.uniqueProtoMsgNames[pkg])
+	name := e.Name
+	if s.protoGroupingNames {
+		if g, ok := groupingOrigin(e); ok {
+			name = g
+		}
+	}
+
+	n := s.makeNameUnique(yang.CamelCase(name), s.uniqueProtoMsgNames[pkg])
 	s.uniqueProtoMsgNames[pkg][n] = true
 
 	// Record that this was the proto message name that was used.
@@ -334,11 +483,31 @@ func (s *genState) protoMsgName(e *yang.Entry, compressPaths bool) string {
 // are omitted from the path, i.e., /openconfig-interfaces/interfaces/interface/config/name
 // becomes interface (since modules, surrounding containers, and config/state containers
 // are not considered with path compression enabled.
+//
+// If a package has been specified for the module at the root of the data tree
+// that the entry is within in the protoModulePackages map of the receiver, the
+// package name is formed from it, followed by the path below the module.
+//
+// If the protoPackagePolicy of the receiver is UncompressedPathPackages, the
+// package name is derived from the uncompressed path, regardless of the value
+// of compressPaths. If it is SinglePackage, the package specified for the
+// entry's module, or otherwise the base package, is used for all entries.
+//
+// If the protoMaxPackageDepth of the receiver is set, package names with more
+// elements are abbreviated as described by abbreviateProtoPackage, and the
+// unabbreviated name is recorded in the protoAbbreviatedPackages map.
 func (s *genState) protobufPackage(e *yang.Entry, compressPaths bool) string {
 	if e.Node != nil && e.Node.NName() == rootElementNodeName {
 		return ""
 	}
 
+	switch s.protoPackagePolicy {
+	case SinglePackage:
+		return s.protoModulePackage(e)
+	case UncompressedPathPackages:
+		compressPaths = false
+	}
+
 	parent := e.Parent
 	// In the case of path compression, then the parent of a list is the parent
 	// one level up, as is the case for if there are config and state containers.
@@ -354,6 +523,10 @@ func (s *genState) protobufPackage(e *yang.Entry, compressPaths bool) string {
 
 	parts := []string{}
 	for p := parent; p != nil; p = p.Parent {
+		if pkg, ok := s.protoModulePackages[p.Name]; ok && p.Parent == nil {
+			parts = append(parts, pkg)
+			continue
+		}
 		if compressPaths && !isOCCompressedValidElement(p) || !compressPaths && isChoiceOrCase(p) {
 			// If compress paths is enabled, and this entity would not
 			// have been included in the generated protobuf output, therefore
@@ -368,10 +541,15 @@ func (s *genState) protobufPackage(e *yang.Entry, compressPaths bool) string {
 		parts[i], parts[len(parts)-1-i] = parts[len(parts)-1-i], parts[i]
 	}
 
+	name := abbreviateProtoPackage(parts, s.protoMaxPackageDepth)
+
 	// Make the name unique since foo.bar.baz-bat and foo.bar.baz_bat will
 	// become the same name in the safeProtoIdentifierName transformation above.
-	n := makeNameUnique(strings.Join(parts, "."), s.definedGlobals)
+	n := s.makeNameUnique(name, s.definedGlobals)
 	s.definedGlobals[n] = true
+	if full := strings.Join(parts, "."); name != full {
+		s.protoAbbreviatedPackages[n] = full
+	}
 
 	// Record the mapping between this entry's parent and the defined
 	// package name that was used.
@@ -380,7 +558,50 @@ func (s *genState) protobufPackage(e *yang.Entry, compressPaths bool) string {
 	return n
 }
 
+// abbreviateProtoPackage returns the protobuf package name formed from the
+// supplied elements. If maxDepth is non-zero, and there are more elements than
+// it, the elements from maxDepth onwards are replaced by a single element,
+// which is the last of them suffixed with a hash of all of them. For example,
+// with a maxDepth of 2, a.b.c.d becomes a.d_<hash of b.c.d>.
+func abbreviateProtoPackage(parts []string, maxDepth int) string {
+	if maxDepth <= 0 || len(parts) <= maxDepth {
+		return strings.Join(parts, ".")
+	}
+	abbrev := parts[maxDepth-1:]
+	h := fnv.New32()
+	h.Write([]byte(strings.Join(abbrev, ".")))
+	keep := append([]string{}, parts[:maxDepth-1]...)
+	return strings.Join(append(keep, fmt.Sprintf("%s_%08x", abbrev[len(abbrev)-1], h.Sum32())), ".")
+}
+
+// protoModulePackage returns the package that has been specified for the module at
+// the root of the data tree that the entry e is within, or the empty string if no
+// package was specified.
+func (s *genState) protoModulePackage(e *yang.Entry) string {
+	return s.protoModulePackages[dataTreeModuleName(e)]
+}
+
 // protoIdentityName returns the name that should be used for an identityref base.
 func (s *genState) protoIdentityName(pargs resolveProtoTypeArgs, i *yang.Identity) string {
-	return fmt.Sprintf("%s.%s.%s", pargs.basePackageName, pargs.enumPackageName, s.identityrefBaseTypeFromIdentity(i, true))
+	return fmt.Sprintf("%s.%s.%s", pargs.basePackageName, pargs.identityPackage(), s.identityrefBaseTypeFromIdentity(i, true))
+}
+
+// identityPackage returns the name of the package within which enumerated types
+// generated for YANG identities are defined.
+func (p resolveProtoTypeArgs) identityPackage() string {
+	if p.identityPackageName == "" {
+		return p.enumPackageName
+	}
+	return p.identityPackageName
+}
+
+// globalEnumPrefix returns the prefix that should be used for the name of a
+// global enumerated type that is generated for the YANG type t, such that it
+// is qualified by the package in which it is defined.
+func (p resolveProtoTypeArgs) globalEnumPrefix(t *yang.YangType) string {
+	pkg := p.enumPackageName
+	if t.Kind == yang.Yidentityref {
+		pkg = p.identityPackage()
+	}
+	return fmt.Sprintf("%s.%s.", p.basePackageName, pkg)
 }
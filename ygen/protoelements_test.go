@@ -95,10 +95,15 @@ func TestYangTypeToProtoType(t *testing.T) {
 		in:          []resolveTypeArgs{{yangType: &yang.YangType{Kind: yang.Ydecimal64}}},
 		wantWrapper: &mappedType{nativeType: "ywrapper.Decimal64Value"},
 		wantSame:    true,
+	}, {
+		name:        "bits",
+		in:          []resolveTypeArgs{{yangType: &yang.YangType{Kind: yang.Ybits}}},
+		wantWrapper: &mappedType{nativeType: "ywrapper.UintValue"},
+		wantScalar:  &mappedType{nativeType: "uint64"},
 	}, {
 		name: "unmapped types",
 		in: []resolveTypeArgs{
-			{yangType: &yang.YangType{Kind: yang.Ybits}},
+			{yangType: &yang.YangType{Kind: yang.YinstanceIdentifier}},
 		},
 		wantErr: true,
 	}, {
@@ -431,6 +436,54 @@ func TestYangTypeToProtoType(t *testing.T) {
 		},
 		wantWrapper: &mappedType{unionTypes: map[string]int{"bool": 0, "string": 1}},
 		wantSame:    true,
+	}, {
+		name: "leafref to cycle of leafrefs",
+		in: []resolveTypeArgs{{
+			yangType: &yang.YangType{
+				Kind: yang.Yleafref,
+				Path: "/foo/bar",
+			},
+			contextEntry: &yang.Entry{
+				Name: "leaf",
+			},
+		}},
+		inEntries: []*yang.Entry{
+			{
+				Name: "foo",
+				Parent: &yang.Entry{
+					Name: "module",
+				},
+				Dir: map[string]*yang.Entry{
+					"bar": {
+						Name: "bar",
+						Type: &yang.YangType{
+							Kind: yang.Yleafref,
+							Path: "/foo/baz",
+						},
+						Parent: &yang.Entry{
+							Name: "foo",
+							Parent: &yang.Entry{
+								Name: "module",
+							},
+						},
+					},
+					"baz": {
+						Name: "baz",
+						Type: &yang.YangType{
+							Kind: yang.Yleafref,
+							Path: "/foo/bar",
+						},
+						Parent: &yang.Entry{
+							Name: "foo",
+							Parent: &yang.Entry{
+								Name: "module",
+							},
+						},
+					},
+				},
+			},
+		},
+		wantErr: true,
 	}}
 
 	for _, tt := range tests {
@@ -479,6 +532,97 @@ func TestYangTypeToProtoType(t *testing.T) {
 	}
 }
 
+func TestYangTypeToProtoTypeIntegerTypes(t *testing.T) {
+	mixedWidths := map[yang.TypeKind]string{
+		yang.Yuint8:  "ywrapper.UintValue",
+		yang.Yuint16: "ywrapper.UintValue",
+		yang.Yuint32: "int64",
+		yang.Yuint64: "uint64",
+		yang.Yint8:   "sint32",
+	}
+
+	tests := []struct {
+		name           string
+		inKind         yang.TypeKind
+		inIntegerTypes map[yang.TypeKind]string
+		want           string
+		wantErr        bool
+	}{{
+		name:           "uint8 mapped to wrapper",
+		inKind:         yang.Yuint8,
+		inIntegerTypes: mixedWidths,
+		want:           "ywrapper.UintValue",
+	}, {
+		name:           "uint16 mapped to wrapper",
+		inKind:         yang.Yuint16,
+		inIntegerTypes: mixedWidths,
+		want:           "ywrapper.UintValue",
+	}, {
+		name:           "uint32 mapped to wider signed type",
+		inKind:         yang.Yuint32,
+		inIntegerTypes: mixedWidths,
+		want:           "int64",
+	}, {
+		name:           "uint64 mapped to native type",
+		inKind:         yang.Yuint64,
+		inIntegerTypes: mixedWidths,
+		want:           "uint64",
+	}, {
+		name:           "int8 mapped to native type",
+		inKind:         yang.Yint8,
+		inIntegerTypes: mixedWidths,
+		want:           "sint32",
+	}, {
+		name:           "int16 not in mapping uses default",
+		inKind:         yang.Yint16,
+		inIntegerTypes: mixedWidths,
+		want:           "ywrapper.IntValue",
+	}, {
+		name:           "uint64 mapped to type that is too narrow",
+		inKind:         yang.Yuint64,
+		inIntegerTypes: map[yang.TypeKind]string{yang.Yuint64: "uint32"},
+		wantErr:        true,
+	}, {
+		name:           "uint64 mapped to signed type of the same width",
+		inKind:         yang.Yuint64,
+		inIntegerTypes: map[yang.TypeKind]string{yang.Yuint64: "int64"},
+		wantErr:        true,
+	}, {
+		name:           "int8 mapped to unsigned type",
+		inKind:         yang.Yint8,
+		inIntegerTypes: map[yang.TypeKind]string{yang.Yint8: "ywrapper.UintValue"},
+		wantErr:        true,
+	}, {
+		name:           "int32 mapped to non-integer type",
+		inKind:         yang.Yint32,
+		inIntegerTypes: map[yang.TypeKind]string{yang.Yint32: "string"},
+		wantErr:        true,
+	}}
+
+	for _, tt := range tests {
+		s := newGenState()
+		got, err := s.yangTypeToProtoType(resolveTypeArgs{
+			yangType: &yang.YangType{Kind: tt.inKind},
+		}, resolveProtoTypeArgs{
+			basePackageName: "basePackage",
+			enumPackageName: "enumPackage",
+			integerTypes:    tt.inIntegerTypes,
+		})
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: yangTypeToProtoType(%v): got unexpected error, got: %v, want error: %v", tt.name, tt.inKind, err, tt.wantErr)
+			continue
+		}
+
+		if err != nil {
+			continue
+		}
+
+		if got.nativeType != tt.want {
+			t.Errorf("%s: yangTypeToProtoType(%v): did not get expected type, got: %s, want: %s", tt.name, tt.inKind, got.nativeType, tt.want)
+		}
+	}
+}
+
 func TestProtoMsgName(t *testing.T) {
 	tests := []struct {
 		name                   string
@@ -577,12 +721,87 @@ func TestProtoMsgName(t *testing.T) {
 	}
 }
 
+func TestProtoMsgNameGroupingOrigin(t *testing.T) {
+	module := &yang.Entry{Name: "module", Kind: yang.DirectoryEntry}
+	grouping := &yang.Entry{
+		Name: "interface-counters",
+		Dir: map[string]*yang.Entry{
+			"in-pkts":  {Name: "in-pkts"},
+			"out-pkts": {Name: "out-pkts"},
+		},
+	}
+
+	// useSite returns a container named name within a container named parent
+	// which instantiates the contents of the grouping, along with any extra
+	// children that are specified.
+	useSite := func(parent, name string, extra ...string) *yang.Entry {
+		p := &yang.Entry{Name: parent, Kind: yang.DirectoryEntry, Parent: module, Dir: map[string]*yang.Entry{}}
+		e := &yang.Entry{
+			Name:   name,
+			Kind:   yang.DirectoryEntry,
+			Parent: p,
+			Dir:    map[string]*yang.Entry{},
+			Uses:   []*yang.UsesStmt{{Uses: &yang.Uses{Name: grouping.Name}, Grouping: grouping}},
+		}
+		for n := range grouping.Dir {
+			e.Dir[n] = &yang.Entry{Name: n, Parent: e}
+		}
+		for _, n := range extra {
+			e.Dir[n] = &yang.Entry{Name: n, Parent: e}
+		}
+		p.Dir[name] = e
+		return e
+	}
+
+	tests := []struct {
+		name            string
+		inEntries       []*yang.Entry
+		inGroupingNames bool
+		wantCompress    []string
+		wantUncompress  []string
+	}{{
+		name:           "grouping used at two sites, grouping names disabled",
+		inEntries:      []*yang.Entry{useSite("eth", "state"), useSite("lag", "counters")},
+		wantCompress:   []string{"State", "Counters"},
+		wantUncompress: []string{"State", "Counters"},
+	}, {
+		name:            "grouping used at two sites",
+		inEntries:       []*yang.Entry{useSite("eth", "state"), useSite("lag", "counters")},
+		inGroupingNames: true,
+		wantCompress:    []string{"InterfaceCounters", "InterfaceCounters"},
+		wantUncompress:  []string{"InterfaceCounters", "InterfaceCounters"},
+	}, {
+		name:            "container with children not from the grouping",
+		inEntries:       []*yang.Entry{useSite("eth", "state", "name")},
+		inGroupingNames: true,
+		wantCompress:    []string{"State"},
+		wantUncompress:  []string{"State"},
+	}}
+
+	for _, tt := range tests {
+		for compress, want := range map[bool][]string{true: tt.wantCompress, false: tt.wantUncompress} {
+			s := newGenState()
+			s.protoGroupingNames = tt.inGroupingNames
+
+			var got []string
+			for _, e := range tt.inEntries {
+				got = append(got, s.protoMsgName(e, compress))
+			}
+
+			if diff := pretty.Compare(got, want); diff != "" {
+				t.Errorf("%s: protoMsgName(compress: %v): did not get expected names, diff(-got,+want):\n%s", tt.name, compress, diff)
+			}
+		}
+	}
+}
+
 func TestProtoPackageName(t *testing.T) {
 	tests := []struct {
 		name                  string
 		inEntry               *yang.Entry
 		inDefinedGlobals      map[string]bool
 		inUniqueProtoPackages map[string]string
+		inModulePackages      map[string]string
 		wantCompress          string
 		wantUncompress        string
 	}{{
@@ -691,11 +910,55 @@ func TestProtoPackageName(t *testing.T) {
 		},
 		wantCompress:   "",
 		wantUncompress: "module.surrounding_container",
+	}, {
+		name: "entry within module with overridden package",
+		inEntry: &yang.Entry{
+			Name: "leaf",
+			Parent: &yang.Entry{
+				Name: "child-container",
+				Dir:  map[string]*yang.Entry{},
+				Parent: &yang.Entry{
+					Name: "container",
+					Dir:  map[string]*yang.Entry{},
+					Parent: &yang.Entry{
+						Name: "module",
+					},
+				},
+			},
+		},
+		inModulePackages: map[string]string{"module": "custom.pkg"},
+		wantCompress:     "custom.pkg.container.child_container",
+		wantUncompress:   "custom.pkg.container.child_container",
+	}, {
+		name: "top-level entry within module with overridden package",
+		inEntry: &yang.Entry{
+			Name: "container",
+			Dir:  map[string]*yang.Entry{},
+			Parent: &yang.Entry{
+				Name: "module",
+			},
+		},
+		inModulePackages: map[string]string{"module": "custom.pkg"},
+		wantCompress:     "custom.pkg",
+		wantUncompress:   "custom.pkg",
+	}, {
+		name: "entry within module without overridden package",
+		inEntry: &yang.Entry{
+			Name: "container",
+			Dir:  map[string]*yang.Entry{},
+			Parent: &yang.Entry{
+				Name: "module",
+			},
+		},
+		inModulePackages: map[string]string{"other-module": "custom.pkg"},
+		wantCompress:     "",
+		wantUncompress:   "module",
 	}}
 
 	for _, tt := range tests {
 		for compress, want := range map[bool]string{true: tt.wantCompress, false: tt.wantUncompress} {
 			s := newGenState()
+			s.protoModulePackages = tt.inModulePackages
 			if tt.inDefinedGlobals != nil {
 				s.definedGlobals = tt.inDefinedGlobals
 			}
@@ -710,3 +973,153 @@ func TestProtoPackageName(t *testing.T) {
 		}
 	}
 }
+
+func TestProtoPackagePolicy(t *testing.T) {
+	module := &yang.Entry{
+		Name: "module",
+		Kind: yang.DirectoryEntry,
+		Dir:  map[string]*yang.Entry{},
+	}
+	parent := &yang.Entry{
+		Name:   "parent",
+		Kind:   yang.DirectoryEntry,
+		Dir:    map[string]*yang.Entry{},
+		Parent: module,
+	}
+	child := &yang.Entry{
+		Name:   "child",
+		Kind:   yang.DirectoryEntry,
+		Dir:    map[string]*yang.Entry{},
+		Parent: parent,
+	}
+	leaf := &yang.Entry{
+		Name:   "leaf",
+		Type:   &yang.YangType{Kind: yang.Ystring},
+		Parent: child,
+	}
+	module.Dir["parent"] = parent
+	parent.Dir["child"] = child
+	child.Dir["leaf"] = leaf
+
+	tests := []struct {
+		name             string
+		inPolicy         ProtoPackagePolicy
+		inCompressPaths  bool
+		inModulePackages map[string]string
+		// want is the expected package of the parent, child and leaf entries
+		// respectively.
+		want []string
+	}{{
+		name:            "path packages with compression",
+		inPolicy:        PathPackages,
+		inCompressPaths: true,
+		want:            []string{"", "parent", "parent.child"},
+	}, {
+		name:     "path packages without compression",
+		inPolicy: PathPackages,
+		want:     []string{"module", "module.parent", "module.parent.child"},
+	}, {
+		name:            "uncompressed path packages with compression",
+		inPolicy:        UncompressedPathPackages,
+		inCompressPaths: true,
+		want:            []string{"module", "module.parent", "module.parent.child"},
+	}, {
+		name:     "uncompressed path packages without compression",
+		inPolicy: UncompressedPathPackages,
+		want:     []string{"module", "module.parent", "module.parent.child"},
+	}, {
+		name:            "single package with compression",
+		inPolicy:        SinglePackage,
+		inCompressPaths: true,
+		want:            []string{"", "", ""},
+	}, {
+		name:     "single package without compression",
+		inPolicy: SinglePackage,
+		want:     []string{"", "", ""},
+	}, {
+		name:             "single package per module",
+		inPolicy:         SinglePackage,
+		inModulePackages: map[string]string{"module": "custom.pkg"},
+		want:             []string{"custom.pkg", "custom.pkg", "custom.pkg"},
+	}}
+
+	for _, tt := range tests {
+		s := newGenState()
+		s.protoPackagePolicy = tt.inPolicy
+		s.protoModulePackages = tt.inModulePackages
+
+		for i, e := range []*yang.Entry{parent, child, leaf} {
+			if got := s.protobufPackage(e, tt.inCompressPaths); got != tt.want[i] {
+				t.Errorf("%s: protobufPackage(%s, %v): did not get expected package name, got: %q, want: %q", tt.name, e.Path(), tt.inCompressPaths, got, tt.want[i])
+			}
+		}
+	}
+}
+
+func TestProtoPackageMaxDepth(t *testing.T) {
+	// Build the schema /module/a/b/{c,d}/x/leaf, such that the packages of
+	// the two leaves differ only in elements beyond the maximum depth.
+	entry := func(name string, parent *yang.Entry) *yang.Entry {
+		e := &yang.Entry{Name: name, Kind: yang.DirectoryEntry, Dir: map[string]*yang.Entry{}, Parent: parent}
+		if parent != nil {
+			parent.Dir[name] = e
+		}
+		return e
+	}
+	module := entry("module", nil)
+	b := entry("b", entry("a", module))
+	cx, dx := entry("x", entry("c", b)), entry("x", entry("d", b))
+	leaf := func(parent *yang.Entry) *yang.Entry {
+		e := &yang.Entry{Name: "leaf", Type: &yang.YangType{Kind: yang.Ystring}, Parent: parent}
+		parent.Dir["leaf"] = e
+		return e
+	}
+	cLeaf, dLeaf := leaf(cx), leaf(dx)
+
+	tests := []struct {
+		name      string
+		inEntries []*yang.Entry
+		inDepth   int
+		// want is the expected package of each of the entries.
+		want []string
+		// wantAbbreviated is the expected map of abbreviated package names
+		// to the unabbreviated names.
+		wantAbbreviated map[string]string
+	}{{
+		name:            "no maximum depth",
+		inEntries:       []*yang.Entry{b, cLeaf, dLeaf},
+		want:            []string{"module.a", "module.a.b.c.x", "module.a.b.d.x"},
+		wantAbbreviated: map[string]string{},
+	}, {
+		name:            "maximum depth not exceeded",
+		inEntries:       []*yang.Entry{b, cLeaf, dLeaf},
+		inDepth:         5,
+		want:            []string{"module.a", "module.a.b.c.x", "module.a.b.d.x"},
+		wantAbbreviated: map[string]string{},
+	}, {
+		name:      "maximum depth exceeded",
+		inEntries: []*yang.Entry{b, cx, cLeaf, dLeaf},
+		inDepth:   3,
+		want:      []string{"module.a", "module.a.c_619a1fa8", "module.a.x_889cd61a", "module.a.x_8aae22d1"},
+		wantAbbreviated: map[string]string{
+			"module.a.c_619a1fa8": "module.a.b.c",
+			"module.a.x_889cd61a": "module.a.b.c.x",
+			"module.a.x_8aae22d1": "module.a.b.d.x",
+		},
+	}}
+
+	for _, tt := range tests {
+		s := newGenState()
+		s.protoMaxPackageDepth = tt.inDepth
+
+		for i, e := range tt.inEntries {
+			if got := s.protobufPackage(e, false); got != tt.want[i] {
+				t.Errorf("%s: protobufPackage(%s, false): did not get expected package name, got: %q, want: %q", tt.name, e.Path(), got, tt.want[i])
+			}
+		}
+
+		if diff := pretty.Compare(s.protoAbbreviatedPackages, tt.wantAbbreviated); diff != "" {
+			t.Errorf("%s: protobufPackage: did not get expected abbreviated packages, diff(-got,+want):\n%s", tt.name, diff)
+		}
+	}
+}
@@ -18,10 +18,13 @@ import (
 	"bytes"
 	"fmt"
 	"hash/fnv"
+	"math"
 	"path/filepath"
 	"sort"
+	"strconv"
 	"strings"
 	"text/template"
+	"unicode"
 
 	"github.com/openconfig/goyang/pkg/yang"
 	"github.com/openconfig/ygot/util"
@@ -47,8 +50,12 @@ const (
 )
 
 const (
-	// protoEnumZeroName is the name given to the value 0 in each generated protobuf enum.
+	// protoEnumZeroName is the default name given to the value 0 in each generated protobuf enum.
 	protoEnumZeroName string = "UNSET"
+	// protoEnumValueOffset is the default offset that is added to the value of each
+	// YANG enumeration value to determine the value of the generated protobuf enum
+	// value, such that the value 0 can represent that the field is unset.
+	protoEnumValueOffset int64 = 1
 	// protoAnyType is the name of the type to use for a google.protobuf.Any field.
 	protoAnyType = "google.protobuf.Any"
 	// protoAnyPackage is the name of the import to be used when a google.protobuf.Any field
@@ -58,9 +65,57 @@ const (
 	// to specify the repeated message that makes up the list's key. The repeated message is
 	// called <ListNameInCamelCase><protoListKeyMessageSuffix>.
 	protoListKeyMessageSuffix = "Key"
+	// protoIdentitiesPackageName specifies the name of the package, within the base
+	// package, that enumerated types generated for YANG identities are output to when
+	// they are to be grouped into a single file.
+	protoIdentitiesPackageName = "identities"
+	// protoUnionsPackageName specifies the name of the package, within the base
+	// package, that messages generated for YANG union typedefs are output to when
+	// they are to be shared by the fields that use them.
+	protoUnionsPackageName = "unions"
+	// protoTypedefUnionFieldName specifies the name of the oneof within each
+	// message that is generated for a YANG union typedef.
+	protoTypedefUnionFieldName = "value"
+	// protoSchemaPathFieldName specifies the name of the field that is added to each
+	// generated message to store its schema path, when such fields are requested.
+	protoSchemaPathFieldName = "_schema_path"
+	// protoMaxFieldTag is the maximum field number that can be used in a protobuf
+	// message, 2^29-1.
+	protoMaxFieldTag = 0x1fffffff
 	// protoSchemaAnnotationOption specifies the name of the FieldOption used to annotate
 	// schemapaths into a protobuf message.
 	protoSchemaAnnotationOption = "(yext.schemapath)"
+	// protoIdentityBaseAnnotationOption specifies the name of the FieldOption used to
+	// annotate the base identity of an identityref leaf that is mapped to a string.
+	protoIdentityBaseAnnotationOption = "(yext.identity_base)"
+	// protoWhenAnnotationOption specifies the name of the FieldOption used to annotate
+	// the XPath expression of a YANG when statement into a protobuf message.
+	protoWhenAnnotationOption = "(yext.when)"
+	// protoFractionDigitsAnnotationOption specifies the name of the FieldOption used
+	// to annotate the fraction-digits of a YANG decimal64 leaf into a protobuf message.
+	protoFractionDigitsAnnotationOption = "(yext.fraction_digits)"
+	// protoEmptyEnumAnnotationOption specifies the name of the FieldOption used to annotate
+	// that an identityref leaf references an identity that has no derived identities.
+	protoEmptyEnumAnnotationOption = "(yext.empty_enum)"
+	// protoEnumDefaultAnnotationOption specifies the name of the FieldOption used to
+	// annotate the default value of an enumeration or identityref leaf.
+	protoEnumDefaultAnnotationOption = "(yext.enum_default)"
+	// protoMinElementsAnnotationOption specifies the name of the FieldOption used to
+	// annotate the min-elements of a YANG list or leaf-list into a protobuf message.
+	protoMinElementsAnnotationOption = "(yext.min_elements)"
+	// protoMaxElementsAnnotationOption specifies the name of the FieldOption used to
+	// annotate the max-elements of a YANG list or leaf-list into a protobuf message.
+	protoMaxElementsAnnotationOption = "(yext.max_elements)"
+	// protoLeafListSemanticsAnnotationOption specifies the name of the FieldOption used
+	// to annotate whether the order of, and duplicates within, a YANG leaf-list are meaningful.
+	protoLeafListSemanticsAnnotationOption = "(yext.leaflist_semantics)"
+	// protoDefaultValueAnnotationOption specifies the name of the FieldOption used to
+	// annotate the default value of a leaf or leaf-list that is not of an enumerated type.
+	protoDefaultValueAnnotationOption = "(yext.default_value)"
+	// protoFieldTagExtension is the name of the YANG extension, defined within the
+	// OpenConfig code generation extensions module, that can be used to explicitly
+	// specify the protobuf tag of a field.
+	protoFieldTagExtension = "field-number"
 	// protoMatchingListNameKeySuffix defines the suffix that should be added to a list
 	// key's name in the case that it matches the name of the list itself. This is required
 	// since in the case that we have YANG whereby there is a list that has a key
@@ -89,8 +144,59 @@ const (
 	// makeNameUnique which would append "_" to the name of the key we explicitly
 	// append _ plus the string defined in protoMatchingListNameKeySuffix to the list name.
 	protoMatchingListNameKeySuffix = "key"
+	// protoCommentWidth is the maximum width of the comment lines that are output
+	// for the descriptions of fields.
+	protoCommentWidth = 70
 )
 
+// protoReservedWords is the set of keywords of the protobuf language. Whilst
+// protoc accepts these as field names, some tools that consume the generated
+// protobufs do not, and hence they can optionally be escaped.
+var protoReservedWords = map[string]bool{
+	"bool":       true,
+	"bytes":      true,
+	"double":     true,
+	"enum":       true,
+	"extend":     true,
+	"extensions": true,
+	"false":      true,
+	"fixed32":    true,
+	"fixed64":    true,
+	"float":      true,
+	"group":      true,
+	"import":     true,
+	"inf":        true,
+	"int32":      true,
+	"int64":      true,
+	"map":        true,
+	"max":        true,
+	"message":    true,
+	"nan":        true,
+	"oneof":      true,
+	"option":     true,
+	"optional":   true,
+	"package":    true,
+	"public":     true,
+	"repeated":   true,
+	"required":   true,
+	"reserved":   true,
+	"returns":    true,
+	"rpc":        true,
+	"service":    true,
+	"sfixed32":   true,
+	"sfixed64":   true,
+	"sint32":     true,
+	"sint64":     true,
+	"stream":     true,
+	"string":     true,
+	"syntax":     true,
+	"to":         true,
+	"true":       true,
+	"uint32":     true,
+	"uint64":     true,
+	"weak":       true,
+}
+
 // protoMsgField describes a field of a protobuf message.
 // Note, throughout this package private structs that have public fields are used
 // in text/template which cannot refer to unexported fields.
@@ -99,9 +205,14 @@ type protoMsgField struct {
 	Name        string           // Name is the field's name.
 	Type        string           // Type is the protobuf type for the field.
 	IsRepeated  bool             // IsRepeated indicates whether the field is repeated.
+	IsMap       bool             // IsMap indicates whether the field is a map, and hence is output without a label.
 	Options     []*protoOption   // Extensions is the set of field extensions that should be specified for the field.
 	IsOneOf     bool             // IsOneOf indicates that the field is a oneof and hence consists of multiple subfields.
 	OneOfFields []*protoMsgField // OneOfFields contains the set of fields within the oneof
+	Comment     string           // Comment is a comment that should be output prior to the field's definition.
+	IsRequired  bool             // IsRequired indicates whether the field is required, and is used only when proto2 syntax is output.
+	Description string           // Description is the YANG description of the field, which is output as a comment prior to the field's definition.
+	SchemaPaths []string         // SchemaPaths is the set of YANG schema paths of the field, which is populated only when a manifest is being generated.
 }
 
 // protoOption describes a protobuf (message or field) option.
@@ -114,24 +225,35 @@ type protoOption struct {
 
 // protoMsg describes a protobuf message.
 type protoMsg struct {
-	Name        string                    // Name is the name of the protobuf message to be output.
-	YANGPath    string                    // YANGPath stores the path that the message corresponds to within the YANG schema.
-	Fields      []*protoMsgField          // Fields is a slice of the fields that are within the message.
-	Imports     []string                  // Imports is a slice of strings that contains the relative import paths that are required by this message.
-	Enums       map[string]*protoMsgEnum  // Enums lists the embedded enumerations within the message.
-	ChildMsgs   []*generatedProto3Message // ChildMsgs is the set of messages that should be embedded within the message.
-	PathComment bool                      // PathComment - when set - indicates that comments that specify the path to a message should be included in the output protobuf.
+	Name         string                    // Name is the name of the protobuf message to be output.
+	YANGPath     string                    // YANGPath stores the path that the message corresponds to within the YANG schema.
+	Fields       []*protoMsgField          // Fields is a slice of the fields that are within the message.
+	Imports      []string                  // Imports is a slice of strings that contains the relative import paths that are required by this message.
+	Enums        map[string]*protoMsgEnum  // Enums lists the embedded enumerations within the message.
+	ChildMsgs    []*generatedProto3Message // ChildMsgs is the set of messages that should be embedded within the message.
+	PathComment  bool                      // PathComment - when set - indicates that comments that specify the path to a message should be included in the output protobuf.
+	ReservedTags []uint32                  // ReservedTags is the sorted set of field numbers that were previously used within the message, and hence should be reserved.
+	Proto2       bool                      // Proto2 indicates that the message is output using proto2 syntax, such that each field is explicitly labelled.
+	Options      []string                  // Options is the set of message options, each of the form name = value, that should be output within the message.
+	Module       string                    // Module is the name of the YANG module whose data tree contains the element that the message represents.
+	AugmentedBy  string                    // AugmentedBy is the name of the YANG module that augments the element into the data tree of Module, if any.
+	ListKey      bool                      // ListKey indicates that the message was generated for the key of the YANG list at YANGPath.
 }
 
 // protoMsgEnum represents an embedded enumeration within a protobuf message.
 type protoMsgEnum struct {
-	Values map[int64]protoEnumValue // Values that the enumerated type can take.
+	Values     map[int64]protoEnumValue // Values that the enumerated type can take.
+	AllowAlias bool                     // AllowAlias indicates that more than one value of the enum has the same number.
 }
 
 // protoEnumValue describes a value within a Protobuf enumeration.
 type protoEnumValue struct {
 	ProtoLabel string // ProtoLabel is the label that should be used for the value in the protobuf.
 	YANGLabel  string // YANGLabel is the label that was originally specified in the YANG schema.
+	Unprefixed bool   // Unprefixed indicates that the label should not be prefixed with the name of the enumerated type.
+	// Aliases is the set of values that have the same number as the value,
+	// and are output as aliases of it.
+	Aliases []protoEnumValue
 }
 
 // protoEnum represents an enumeration that is defined at the root of a protobuf
@@ -141,6 +263,7 @@ type protoEnum struct {
 	Description string                   // Description is a string description of the enumerated type within the YANG schema, used in comments.
 	Values      map[int64]protoEnumValue // Values contains the string names, keyed by enum value, that the enumerated type can take.
 	ValuePrefix string                   // ValuePrefix contains the string prefix that should be prepended to each value within the enumerated type.
+	AllowAlias  bool                     // AllowAlias indicates that more than one value of the enum has the same number.
 }
 
 // proto3Header describes the header of a Protobuf3 package.
@@ -153,6 +276,11 @@ type proto3Header struct {
 	CallerName             string   // CallerName indicates the name of the entity initiating code generation.
 	YwrapperPath           string   // YwrapperPath is the path to the ywrapper.proto file, excluding the filename.
 	YextPath               string   // YextPath is the path to the yext.proto file, excluding the filename.
+	Proto2                 bool     // Proto2 indicates that the package should be output using proto2 syntax.
+	GoPackage              string   // GoPackage is the value of the go_package option for the package, which is omitted if it is empty.
+	JavaPackage            string   // JavaPackage is the value of the java_package option for the package, which is omitted if it is empty.
+	GoogleWrappers         bool     // GoogleWrappers indicates that the google.protobuf wrapper types are used within the package, and hence should be imported.
+	AbbreviatedFrom        string   // AbbreviatedFrom is the unabbreviated name of the package, which is output in a comment if the package name was abbreviated.
 }
 
 var (
@@ -172,12 +300,27 @@ var (
 //   - {{ $importPath }}
 {{- end -}}
 {{- end }}
-syntax = "proto3";
+{{- if .AbbreviatedFrom }}
+//
+// The name of this package is an abbreviation of {{ .AbbreviatedFrom }}.
+{{- end }}
+syntax = "{{ if .Proto2 }}proto2{{ else }}proto3{{ end }}";
 
 package {{ .PackageName }};
+{{- if or .GoPackage .JavaPackage }}
+{{ end }}
+{{- if .GoPackage }}
+option go_package = "{{ .GoPackage }}";
+{{- end }}
+{{- if .JavaPackage }}
+option java_package = "{{ .JavaPackage }}";
+{{- end }}
 
 import "{{ .YwrapperPath }}/ywrapper.proto";
 import "{{ .YextPath }}/yext.proto";
+{{- if .GoogleWrappers }}
+import "google/protobuf/wrappers.proto";
+{{- end }}
 {{- range $importedProto := .Imports }}
 import "{{ $importedProto }}";
 {{- end }}
@@ -188,21 +331,41 @@ import "{{ $importedProto }}";
 	protoMessageTemplate = `
 {{ if .PathComment -}}
 // {{ .Name }} represents the {{ .YANGPath }} YANG schema element.
+{{ if .Module -}}
+// Defined in module {{ .Module }}{{ if .AugmentedBy }}, augmented by module {{ .AugmentedBy }}{{ end }}.
+{{ end -}}
 {{ end -}}
 message {{ .Name }} {
+{{- range $opt := .Options }}
+  option {{ $opt }};
+{{- end -}}
 {{- range $idx, $msg := .ChildMsgs -}}
 	{{- indentLines $msg.MessageCode -}}
 {{- end -}}
 {{- range $ename, $enum := .Enums }}
   enum {{ $ename }} {
+    {{- if $enum.AllowAlias }}
+    option allow_alias = true;
+    {{- end }}
     {{- range $i, $val := $enum.Values }}
-    {{ toUpper $ename }}_{{ $val.ProtoLabel }} = {{ $i }}
+    {{ if not $val.Unprefixed }}{{ toUpper $ename }}_{{ end }}{{ $val.ProtoLabel }} = {{ $i }}
     {{- if ne $val.YANGLabel "" }} [(yext.yang_name) = "{{ $val.YANGLabel }}"]{{ end -}}
     ;
+    {{- range $alias := $val.Aliases }}
+    {{ if not $alias.Unprefixed }}{{ toUpper $ename }}_{{ end }}{{ $alias.ProtoLabel }} = {{ $i }}
+    {{- if ne $alias.YANGLabel "" }} [(yext.yang_name) = "{{ $alias.YANGLabel }}"]{{ end -}}
+    ;
+    {{- end }}
     {{- end }}
   }
 {{- end -}}
 {{- range $idx, $field := .Fields }}
+  {{ if $field.Comment -}}
+  // {{ $field.Comment }}
+  {{ end -}}
+  {{ range $line := commentLines $field.Description -}}
+  // {{ $line }}
+  {{ end -}}
   {{ if $field.IsOneOf -}}
   oneof {{ $field.Name }} {
     {{- range $ooField := .OneOfFields }}
@@ -210,7 +373,7 @@ message {{ .Name }} {
     {{- end }}
   }
   {{- else -}}
-  {{ if $field.IsRepeated }}repeated {{ end -}}
+  {{ if $field.IsRepeated }}repeated {{ else if $field.IsMap }}{{ else if $.Proto2 }}{{ if $field.IsRequired }}required {{ else }}optional {{ end }}{{ end -}}
   {{ $field.Type }} {{ $field.Name }} = {{ $field.Tag }}
   {{- $noOptions := len .Options -}}
   {{- if ne $noOptions 0 }} [
@@ -223,6 +386,9 @@ message {{ .Name }} {
   ;
   {{- end -}}
 {{- end }}
+{{- range $tag := .ReservedTags }}
+  reserved {{ $tag }};
+{{- end }}
 }`
 
 	// protoListKeyTemplate is generated as a wrapper around each list entry within
@@ -234,10 +400,18 @@ message {{ .Name }} {
 message {{ .Name }} {
 {{- range $ename, $enum := .Enums }}
   enum {{ $ename }} {
+    {{- if $enum.AllowAlias }}
+    option allow_alias = true;
+    {{- end }}
     {{- range $i, $val := $enum.Values }}
-    {{ toUpper $ename }}_{{ $val.ProtoLabel }} = {{ $i }}
+    {{ if not $val.Unprefixed }}{{ toUpper $ename }}_{{ end }}{{ $val.ProtoLabel }} = {{ $i }}
     {{- if ne $val.YANGLabel "" }} [(yext.yang_name) = "{{ $val.YANGLabel }}"]{{ end -}}
     ;
+    {{- range $alias := $val.Aliases }}
+    {{ if not $alias.Unprefixed }}{{ toUpper $ename }}_{{ end }}{{ $alias.ProtoLabel }} = {{ $i }}
+    {{- if ne $alias.YANGLabel "" }} [(yext.yang_name) = "{{ $alias.YANGLabel }}"]{{ end -}}
+    ;
+    {{- end }}
     {{- end }}
   }
 {{- end -}}
@@ -249,6 +423,7 @@ message {{ .Name }} {
     {{- end }}
   }
   {{- else -}}
+  {{ if $.Proto2 }}{{ if $field.IsRequired }}required {{ else }}optional {{ end }}{{ end -}}
   {{ $field.Type }} {{ $field.Name }} = {{ $field.Tag }}
   {{- $noOptions := len .Options -}}
   {{- if ne $noOptions 0 }} [
@@ -270,10 +445,18 @@ message {{ .Name }} {
 	protoEnumTemplate = `
 // {{ .Name }} represents an enumerated type generated for the {{ .Description }}.
 enum {{ .Name }} {
+{{- if .AllowAlias }}
+  option allow_alias = true;
+{{- end }}
 {{- range $i, $val := .Values }}
-  {{ toUpper $.ValuePrefix }}_{{ $val.ProtoLabel }} = {{ $i }}
+  {{ if not $val.Unprefixed }}{{ toUpper $.ValuePrefix }}_{{ end }}{{ $val.ProtoLabel }} = {{ $i }}
   {{- if ne $val.YANGLabel "" }} [(yext.yang_name) = "{{ $val.YANGLabel }}"]{{ end -}}
   ;
+{{- range $alias := $val.Aliases }}
+  {{ if not $alias.Unprefixed }}{{ toUpper $.ValuePrefix }}_{{ end }}{{ $alias.ProtoLabel }} = {{ $i }}
+  {{- if ne $alias.YANGLabel "" }} [(yext.yang_name) = "{{ $alias.YANGLabel }}"]{{ end -}}
+  ;
+{{- end }}
 {{- end }}
 }
 `
@@ -298,8 +481,17 @@ func writeProto3Header(in proto3Header) (string, error) {
 	}
 
 	// Sort the list of imports such that they are output in alphabetical
-	// order, minimising diffs.
-	sort.Strings(in.Imports)
+	// order, minimising diffs, and remove any duplicates, since protoc
+	// rejects files that import the same file more than once. The imports
+	// are copied, such that the caller's slice is not modified.
+	sorted := append([]string{}, in.Imports...)
+	sort.Strings(sorted)
+	in.Imports = nil
+	for i, imp := range sorted {
+		if i == 0 || imp != sorted[i-1] {
+			in.Imports = append(in.Imports, imp)
+		}
+	}
 
 	var b bytes.Buffer
 	if err := protoTemplates["header"].Execute(&b, in); err != nil {
@@ -311,9 +503,10 @@ func writeProto3Header(in proto3Header) (string, error) {
 
 // generatedProto3Message contains the code for a proto3 message.
 type generatedProto3Message struct {
-	PackageName     string   // PackageName is the name of the package that the proto3 message is within.
-	MessageCode     string   // MessageCode contains the proto3 definition of the message.
-	RequiredImports []string // RequiredImports contains the imports that are required by the generated message.
+	PackageName     string                  // PackageName is the name of the package that the proto3 message is within.
+	MessageCode     string                  // MessageCode contains the proto3 definition of the message.
+	RequiredImports []string                // RequiredImports contains the imports that are required by the generated message.
+	Manifest        []*ProtoManifestMessage // Manifest describes the messages, including those nested within them, that are defined by MessageCode.
 }
 
 // protoMsgConfig defines the set of configuration options required to generate a Protobuf message.
@@ -324,7 +517,136 @@ type protoMsgConfig struct {
 	baseImportPath      string // baseImportPath specifies the path that should be used for importing the generated files.
 	annotateSchemaPaths bool   // annotateSchemaPaths uses the yext protobuf field extensions to annotate the paths from the schema into the output protobuf.
 	annotateEnumNames   bool   // annotateEnumNames uses the yext protobuf enum value extensions to annoate the original YANG name for an enum into the output protobuf.
+	fieldDescriptions   bool   // fieldDescriptions indicates whether the YANG description of each field should be output as a comment above it.
 	nestedMessages      bool   // nestedMessages indicates whether nested messages should be output for the protobuf schema.
+	upperSnakeEnums     bool   // upperSnakeEnums indicates whether the names of enum values should be converted to UPPER_SNAKE_CASE.
+	identityrefAsString bool   // identityrefAsString indicates whether identityref leaves should be mapped to strings rather than to enumerated types.
+	identitiesFile      bool   // identitiesFile indicates whether enumerated types generated for YANG identities should be output in a single identities package rather than the enum package.
+	// integerTypes specifies the protobuf type that should be used for each YANG integer type.
+	integerTypes map[yang.TypeKind]string
+	// schemaPathField indicates whether a field storing the schema path of each message should be added to it.
+	schemaPathField bool
+	// reservedTags specifies, keyed by the YANG schema path of a message, the field numbers that were
+	// previously used within the message.
+	reservedTags map[string][]uint32
+	// messageOptions specifies, keyed by the YANG schema path of a message, the options that
+	// should be output within the message.
+	messageOptions map[string][]string
+	// cardinalityPolicy specifies how the min-elements and max-elements of lists and leaf-lists
+	// are output.
+	cardinalityPolicy ProtoCardinalityPolicy
+	// googleWrappers indicates whether the google.protobuf wrapper types should be used in place
+	// of the equivalent ywrapper types.
+	googleWrappers bool
+	// escapeReservedWords indicates whether field names that are protobuf keywords should have
+	// an underscore appended to them.
+	escapeReservedWords bool
+	// nameSuffix specifies the suffix used to disambiguate the names of fields and enum values
+	// that would otherwise clash, as described by makeNameUniqueWithSuffix.
+	nameSuffix string
+	// typedefUnions indicates whether unions that are defined by a typedef should be output as a
+	// message within the unions package that is shared by all fields of the type, rather than as a
+	// oneof within each message.
+	typedefUnions bool
+	// qualifyNestedKeys indicates whether the key message of a list that is directly within another
+	// list should be prefixed with the name of the enclosing list's message.
+	qualifyNestedKeys bool
+	// singleKeyMaps indicates whether keyed lists that have a single key, whose type can be used
+	// as the key of a protobuf map, should be output as a map keyed by the key's value.
+	singleKeyMaps bool
+	// annotateDefaults indicates whether the default value of enumeration and identityref leaves
+	// should be annotated onto the field, rather than being used as the zero value of embedded
+	// enums.
+	annotateDefaults bool
+	// manifest indicates whether the schema paths of each field should be recorded, such that
+	// they can be included in the manifest of the generated messages.
+	manifest bool
+	// scalarDefaults indicates whether the default values of leaves and leaf-lists that are
+	// not of an enumerated type should be annotated onto the field.
+	scalarDefaults bool
+	// leafListSemantics indicates whether leaf-list fields should be annotated with whether
+	// they have set or list semantics, according to the leaf-list's ordered-by statement.
+	leafListSemantics bool
+	// splitConfigState indicates whether the leaves of each message should be output in separate
+	// messages according to whether they are configuration or state.
+	splitConfigState bool
+	// enumZeroName specifies the label used for the zero value of each generated enum. If it is
+	// empty, protoEnumZeroName is used.
+	enumZeroName string
+	// enumZeroPolicy specifies whether the zero value of each generated enum is prefixed with
+	// the name of the enum.
+	enumZeroPolicy ProtoEnumZeroValuePolicy
+	// enumValueOffset specifies the offset that is added to the value of each YANG enumeration
+	// value, and the minimum value of each identity, in the generated enums. If it is zero,
+	// protoEnumValueOffset is used.
+	enumValueOffset int64
+	// emptyEnumPolicy specifies how identities that have no derived identities are output.
+	emptyEnumPolicy ProtoEmptyEnumPolicy
+	// orderOneofsByTag indicates whether the fields within each oneof should be output in
+	// ascending order of their field numbers, rather than in the order of their types.
+	orderOneofsByTag bool
+	// whenPolicy specifies how fields that have a YANG when statement are output.
+	whenPolicy ProtoWhenPolicy
+	// proto2 indicates that messages should be output using proto2 syntax, such that scalar
+	// fields use native protobuf types rather than wrapper types, and mandatory leaves are required.
+	proto2 bool
+}
+
+// makeNameUnique makes the name specified unique within the definedNames map,
+// using the suffix for disambiguated names that is specified by the configuration c.
+func (c *protoMsgConfig) makeNameUnique(name string, definedNames map[string]bool) string {
+	return makeNameUniqueWithSuffix(name, definedNames, c.nameSuffix)
+}
+
+// enumZeroValue returns the value that should be used for the zero value of
+// each generated enum, based on the configuration c.
+func (c *protoMsgConfig) enumZeroValue() protoEnumValue {
+	n := c.enumZeroName
+	if n == "" {
+		n = protoEnumZeroName
+	}
+	return protoEnumValue{
+		ProtoLabel: n,
+		Unprefixed: c.enumZeroPolicy == PlainEnumZeroValue,
+	}
+}
+
+// valueOffset returns the offset that is added to the value of each YANG
+// enumeration value to determine the value of the generated enum value.
+func (c *protoMsgConfig) valueOffset() int64 {
+	if c.enumValueOffset == 0 {
+		return protoEnumValueOffset
+	}
+	return c.enumValueOffset
+}
+
+// identityPackageName returns the name of the package in which the enumerated
+// types that are generated for YANG identities are defined.
+func (c *protoMsgConfig) identityPackageName() string {
+	if c.identitiesFile {
+		return protoIdentitiesPackageName
+	}
+	return c.enumPackageName
+}
+
+// resolveProtoTypeArgs returns the arguments required for resolving the protobuf
+// type of a field based on the configuration c.
+func (c *protoMsgConfig) resolveProtoTypeArgs() resolveProtoTypeArgs {
+	return resolveProtoTypeArgs{
+		basePackageName:     c.basePackageName,
+		enumPackageName:     c.enumPackageName,
+		identityPackageName: c.identityPackageName(),
+		integerTypes:        c.integerTypes,
+		skipEmptyIdentities: c.emptyEnumPolicy != EmitEmptyEnums,
+		googleWrappers:      c.googleWrappers,
+	}
+}
+
+// skipIdentityEnum returns true if the enum generated for the YANG identity i
+// should not be output, since i has no derived identities, and the configuration
+// c specifies that such enums are to be skipped.
+func (c *protoMsgConfig) skipIdentityEnum(i *yang.Identity) bool {
+	return c.emptyEnumPolicy != EmitEmptyEnums && len(i.Values) == 0
 }
 
 // writeProto3Message outputs the generated Protobuf3 code for a particular protobuf message. It takes:
@@ -337,13 +659,96 @@ type protoMsgConfig struct {
 //  name of the package it is within, the code for the message, and any imports for packages that are referenced by
 //  the message.
 func writeProto3Msg(msg *yangDirectory, msgs map[string]*yangDirectory, state *genState, cfg *protoMsgConfig) (*generatedProto3Message, util.Errors) {
-	if cfg.nestedMessages {
-		if !outputNestedMessage(msg, cfg.compressPaths) {
-			return nil, nil
+	var gmsg *generatedProto3Message
+	var errs util.Errors
+	switch {
+	case cfg.nestedMessages && !outputNestedMessage(msg, cfg.compressPaths):
+		return nil, nil
+	case cfg.nestedMessages:
+		gmsg, errs = writeProto3MsgNested(msg, msgs, state, cfg)
+	default:
+		gmsg, errs = writeProto3MsgSingleMsg(msg, msgs, state, cfg)
+	}
+	if gmsg != nil {
+		gmsg.MessageCode = normalizeProtoCode(gmsg.MessageCode)
+	}
+	return gmsg, errs
+}
+
+// normalizeProtoCode returns the generated protobuf code in s with consistent
+// whitespace, such that the output does not depend on the whitespace that is
+// produced by the templates. Trailing whitespace is removed from each line,
+// and consecutive blank lines are collapsed into a single blank line. Exactly
+// one blank line is output between top-level definitions. The code that is
+// returned starts with a single newline and has no trailing newline, such
+// that when each message is written followed by a newline, messages are
+// separated by a blank line and the file ends with a single newline.
+func normalizeProtoCode(s string) string {
+	var lines []string
+	for _, l := range strings.Split(s, "\n") {
+		l = strings.TrimRight(l, " \t")
+		n := len(lines)
+		switch {
+		case l == "" && (n == 0 || lines[n-1] == ""):
+			continue
+		case l != "" && n != 0 && lines[n-1] == "}" && !strings.HasPrefix(l, " "):
+			lines = append(lines, "")
+		}
+		lines = append(lines, l)
+	}
+	for len(lines) != 0 && lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return fmt.Sprintf("\n%s", strings.Join(lines, "\n"))
+}
+
+// blockComments returns the generated protobuf code in s with each run of
+// consecutive line comments that have the same indentation replaced by a
+// block comment. A comment of a single line is output as /* text */, whereas
+// for longer comments the delimiters are output on separate lines, and each
+// line of the comment is prefixed by *. Since a block comment cannot contain
+// its closing delimiter, any occurrence of */ within a comment is escaped.
+func blockComments(s string) string {
+	lines := strings.Split(s, "\n")
+	var out []string
+	for i := 0; i < len(lines); {
+		indent, text, ok := lineComment(lines[i])
+		if !ok {
+			out = append(out, lines[i])
+			i++
+			continue
+		}
+		comment := []string{text}
+		for i++; i < len(lines); i++ {
+			ind, t, ok := lineComment(lines[i])
+			if !ok || ind != indent {
+				break
+			}
+			comment = append(comment, t)
+		}
+		if len(comment) == 1 {
+			out = append(out, fmt.Sprintf("%s/* %s */", indent, text))
+			continue
+		}
+		out = append(out, fmt.Sprintf("%s/*", indent))
+		for _, t := range comment {
+			out = append(out, strings.TrimRight(fmt.Sprintf("%s * %s", indent, t), " "))
 		}
-		return writeProto3MsgNested(msg, msgs, state, cfg)
+		out = append(out, fmt.Sprintf("%s */", indent))
+	}
+	return strings.Join(out, "\n")
+}
+
+// lineComment determines whether the line l of protobuf code is a line
+// comment, returning its indentation and its text, with the leading // and a
+// single following space removed, and any */ within it escaped.
+func lineComment(l string) (string, string, bool) {
+	t := strings.TrimLeft(l, " \t")
+	if !strings.HasPrefix(t, "//") {
+		return "", "", false
 	}
-	return writeProto3MsgSingleMsg(msg, msgs, state, cfg)
+	text := strings.TrimPrefix(strings.TrimPrefix(t, "//"), " ")
+	return l[:len(l)-len(t)], strings.Replace(text, "*/", "* /", -1), true
 }
 
 // outputNestedMessage determines whether the message represented by the supplied
@@ -425,14 +830,18 @@ func writeProto3MsgNested(msg *yangDirectory, msgs map[string]*yangDirectory, st
 			allImports[i] = true
 		}
 
-		epk := filepath.Join(cfg.baseImportPath, cfg.basePackageName, cfg.enumPackageName, fmt.Sprintf("%s.proto", cfg.enumPackageName))
+		epk := importPath(cfg.baseImportPath, cfg.basePackageName, cfg.enumPackageName)
+		ipk := importPath(cfg.baseImportPath, cfg.basePackageName, cfg.identityPackageName())
 		for i := range allImports {
 			if !strings.HasPrefix(i, cfg.baseImportPath) {
 				imports = append(imports, i)
 			}
-			if allImports[epk] {
-				imports = append(imports, epk)
-			}
+		}
+		if allImports[epk] {
+			imports = append(imports, epk)
+		}
+		if ipk != epk && allImports[ipk] {
+			imports = append(imports, ipk)
 		}
 	}
 	gmsg.RequiredImports = imports
@@ -455,6 +864,13 @@ func protobufPackageForMsg(msg *yangDirectory, state *genState, compressPaths, n
 		return "", fmt.Errorf("YANG schema element %s does not have a parent, protobuf messages are not generated for modules", msg.entry.Path())
 	}
 
+	switch state.protoPackagePolicy {
+	case SinglePackage:
+		return state.protoModulePackage(msg.entry), nil
+	case UncompressedPathPackages:
+		compressPaths = false
+	}
+
 	e := msg.entry
 	// If we have nested messages enabled, the protobuf package name is defined
 	// based on the top-level message within the schema tree that is created -
@@ -464,14 +880,15 @@ func protobufPackageForMsg(msg *yangDirectory, state *genState, compressPaths, n
 			if e.Parent.Parent == nil {
 				// In the special case that the grandparent of this entry is nil, and
 				// compress paths is enabled, then we are a top-level schema element - so
-				// this message should be in the root package.
-				return "", nil
+				// this message should be in the root package, or that specified for the
+				// module.
+				return state.protoModulePackage(e), nil
 			}
 			if e.IsList() && e.Parent.Parent.Parent == nil {
 				// If this is a list, and our great-grandparent is a module, then
 				// since the level above this node has been compressed out, then it
 				// is at the root.
-				return "", nil
+				return state.protoModulePackage(e), nil
 			}
 		}
 
@@ -511,6 +928,7 @@ func genProto3MsgCode(pkg string, msgDefs []*protoMsg, pathComment bool) (*gener
 	var b bytes.Buffer
 	var errs util.Errors
 	imports := map[string]interface{}{}
+	var manifest []*ProtoManifestMessage
 	for i, msgDef := range msgDefs {
 		// Sort the child messages into a determinstic order. We cannot use the
 		// package name as a key as it may be the same for multiple packages, therefore
@@ -537,6 +955,13 @@ func genProto3MsgCode(pkg string, msgDefs []*protoMsg, pathComment bool) (*gener
 			return nil, []error{err}
 		}
 		addNewKeys(imports, msgDef.Imports)
+		manifest = append(manifest, protoManifestMessage(msgDef))
+		for _, m := range msgDef.ChildMsgs {
+			for _, cm := range m.Manifest {
+				cm.Name = fmt.Sprintf("%s.%s", msgDef.Name, cm.Name)
+				manifest = append(manifest, cm)
+			}
+		}
 		if i != len(msgDefs)-1 {
 			b.WriteRune('\n')
 		}
@@ -550,6 +975,7 @@ func genProto3MsgCode(pkg string, msgDefs []*protoMsg, pathComment bool) (*gener
 		PackageName:     pkg,
 		MessageCode:     b.String(),
 		RequiredImports: stringKeys(imports),
+		Manifest:        manifest,
 	}, nil
 }
 
@@ -575,7 +1001,19 @@ func genProto3Msg(msg *yangDirectory, msgs map[string]*yangDirectory, state *gen
 		ChildMsgs: childMsgs,
 	}
 
+	// The fake root is not defined by any module, and hence its message
+	// does not specify one.
+	if msg.entry != nil && !msg.isFakeRoot {
+		msgDef.Module = dataTreeModuleName(msg.entry)
+		if m := augmentingModuleName(msg.entry); m != msgDef.Module {
+			msgDef.AugmentedBy = m
+		}
+	}
+
 	definedFieldNames := map[string]bool{}
+	// definedTags stores the field numbers that have been used within the
+	// message, such that colliding tags can be made unique.
+	definedTags := map[uint32]bool{}
 	imports := map[string]interface{}{}
 
 	var fNames []string
@@ -584,6 +1022,40 @@ func genProto3Msg(msg *yangDirectory, msgs map[string]*yangDirectory, state *gen
 	}
 	sort.Strings(fNames)
 
+	// Tags that are explicitly specified for fields within the YANG schema are
+	// recorded prior to any other tags being assigned, such that calculated
+	// tags are made unique with respect to them. Invalid explicit tags are
+	// reported when the field itself is generated.
+	explicitTags := map[uint32]string{}
+	for _, name := range fNames {
+		field := msg.fields[name]
+		t, ok, err := explicitFieldTag(field)
+		if err != nil || !ok {
+			continue
+		}
+		if other, dup := explicitTags[t]; dup {
+			errs = append(errs, fmt.Errorf("proto: fields %s and %s of message %s both specify field tag %d", other, field.Name, msg.name, t))
+			continue
+		}
+		explicitTags[t] = field.Name
+		definedTags[t] = true
+	}
+
+	if cfg.schemaPathField {
+		f, err := protoSchemaPathField(msg, definedFieldNames, cfg)
+		if err != nil {
+			errs = append(errs, err)
+		} else {
+			f.Tag = uniqueFieldTag(f.Tag, definedTags)
+			msgDef.Fields = append(msgDef.Fields, f)
+		}
+	}
+
+	// leafConfig stores, for each field that represents a leaf or leaf-list,
+	// whether the leaf is configuration, such that the fields can be split
+	// into separate config and state messages.
+	leafConfig := map[*protoMsgField]bool{}
+
 	skipFields := map[string]bool{}
 	if isKeyedList(msg.entry) {
 		skipFields = listKeyFieldsMap(msg.entry)
@@ -596,17 +1068,47 @@ func genProto3Msg(msg *yangDirectory, msgs map[string]*yangDirectory, state *gen
 
 		field := msg.fields[name]
 
+		when, hasWhen := whenXPath(field)
+		if hasWhen && cfg.whenPolicy == ExcludeWhenNodes {
+			continue
+		}
+
 		fieldDef := &protoMsgField{
-			Name: makeNameUnique(safeProtoIdentifierName(name), definedFieldNames),
+			Name:       cfg.makeNameUnique(safeProtoFieldName(name, cfg), definedFieldNames),
+			IsRequired: cfg.proto2 && field.IsLeaf() && field.Mandatory == yang.TSTrue,
 		}
 
-		t, err := protoTagForEntry(field)
-		if err != nil {
+		if cfg.fieldDescriptions {
+			fieldDef.Description = field.Description
+		}
+
+		if cfg.manifest {
+			p, err := protoSchemaPaths(msg, field, cfg.compressPaths)
+			if err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			fieldDef.SchemaPaths = p
+		}
+
+		t, explicitTag, err := protoTagForEntry(field)
+		switch {
+		case err != nil:
 			errs = append(errs, fmt.Errorf("proto: could not generate tag for field %s: %v", field.Name, err))
 			continue
+		case explicitTag && explicitTags[t] != field.Name:
+			// The tag conflicts with that of another field, which has already
+			// been reported.
+			continue
 		}
 		fieldDef.Tag = t
 
+		// fieldImports stores the imports that are required by the field, which
+		// are only added to those of the message once the field has been
+		// successfully generated, such that a field that is dropped due to an
+		// error does not result in an unused import.
+		fieldImports := map[string]interface{}{}
+
 		defArgs := &protoDefinitionArgs{
 			field:              field,
 			directory:          msg,
@@ -623,7 +1125,7 @@ func genProto3Msg(msg *yangDirectory, msgs map[string]*yangDirectory, state *gen
 				errs = append(errs, listErrs...)
 				continue
 			}
-			addNewKeys(imports, listImports)
+			addNewKeys(fieldImports, listImports)
 			if keyMsg != nil {
 				msgDefs = append(msgDefs, keyMsg)
 			}
@@ -633,20 +1135,21 @@ func genProto3Msg(msg *yangDirectory, msgs map[string]*yangDirectory, state *gen
 				errs = append(errs, err)
 				continue
 			}
-			addNewKeys(imports, cImports)
+			addNewKeys(fieldImports, cImports)
 		case field.IsLeaf() || field.IsLeafList():
 			repeatedMsg, lImports, lErrs := addProtoLeafOrLeafListField(fieldDef, msgDef, defArgs)
 			if lErrs != nil {
 				errs = append(errs, lErrs...)
 				continue
 			}
-			addNewKeys(imports, lImports)
+			addNewKeys(fieldImports, lImports)
 			if repeatedMsg != nil {
 				msgDefs = append(msgDefs, repeatedMsg)
 			}
+			leafConfig[fieldDef] = isConfig(field)
 		case isAnydata(field):
 			fieldDef.Type = protoAnyType
-			imports[protoAnyPackage] = true
+			fieldImports[protoAnyPackage] = true
 		default:
 			err = fmt.Errorf("proto: unknown field type in message %s, field %s", msg.name, field.Name)
 		}
@@ -660,16 +1163,253 @@ func genProto3Msg(msg *yangDirectory, msgs map[string]*yangDirectory, state *gen
 			fieldDef.Options = append(fieldDef.Options, o)
 		}
 
+		if hasWhen && cfg.whenPolicy == AnnotateWhenNodes {
+			fieldDef.Options = append(fieldDef.Options, protoWhenAnnotation(when))
+		}
+
+		if cfg.cardinalityPolicy != OmitCardinality {
+			c, cerr := listCardinality(field)
+			if cerr != nil {
+				errs = append(errs, cerr)
+				continue
+			}
+			addProtoCardinality(fieldDef, c, cfg.cardinalityPolicy)
+		}
+
+		if cfg.leafListSemantics && field.IsLeafList() {
+			fieldDef.Options = append(fieldDef.Options, protoLeafListSemanticsAnnotation(field))
+		}
+
 		if err != nil {
 			errs = append(errs, err)
 			continue
 		}
+
+		// Ensure that the tags that were calculated for the field do not collide
+		// with those of other fields within the message. Explicitly specified
+		// tags have already been checked for collisions, and are used as is.
+		switch {
+		case fieldDef.IsOneOf && explicitTag:
+			errs = append(errs, fmt.Errorf("proto: field %s of message %s specifies a field tag, but is output as a oneof", field.Name, msg.name))
+			continue
+		case fieldDef.IsOneOf:
+			for _, f := range fieldDef.OneOfFields {
+				f.Tag = uniqueFieldTag(f.Tag, definedTags)
+			}
+		case !explicitTag:
+			fieldDef.Tag = uniqueFieldTag(fieldDef.Tag, definedTags)
+		}
 		msgDef.Fields = append(msgDef.Fields, fieldDef)
+		for i := range fieldImports {
+			imports[i] = true
+		}
+	}
+
+	var splitMsgs []*protoMsg
+	if cfg.splitConfigState {
+		var err error
+		splitMsgs, err = splitConfigStateFields(msgDef, leafConfig, definedFieldNames, definedTags)
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	// The fields of the message are output in ascending order of their field
+	// numbers, such that the message definition reads in the order in which
+	// fields are serialised.
+	sortFieldsByTag(msgDef.Fields)
+	for _, m := range splitMsgs {
+		sortFieldsByTag(m.Fields)
+	}
+
+	if cfg.orderOneofsByTag {
+		// The oneofs within the messages generated for list keys and leaf-lists
+		// of unions are also ordered. The fields of the split config and state
+		// messages were taken from msgDef, and hence are already ordered.
+		sortOneofFieldsByTag(msgDef.Fields)
+		for _, m := range msgDefs {
+			sortOneofFieldsByTag(m.Fields)
+		}
+	}
+
+	for _, m := range splitMsgs {
+		m.Proto2 = cfg.proto2
+	}
+	if cfg.nestedMessages && splitMsgs != nil {
+		// When nested messages are being output, the config and state messages
+		// are output within the message whose leaves they contain.
+		sc, scerrs := genProto3MsgCode(parentPkg, splitMsgs, false)
+		if scerrs != nil {
+			errs = append(errs, scerrs...)
+		} else {
+			msgDef.ChildMsgs = append(msgDef.ChildMsgs, sc)
+		}
+	} else {
+		msgDefs = append(msgDefs, splitMsgs...)
 	}
 
 	msgDef.Imports = stringKeys(imports)
+	msgDef.ReservedTags = reservedFieldTags(cfg.reservedTags[msgDef.YANGPath], definedTags)
+	msgDef.Options = cfg.messageOptions[msgDef.YANGPath]
+
+	msgDefs = append(msgDefs, msgDef)
+	for _, m := range msgDefs {
+		m.Proto2 = cfg.proto2
+	}
+
+	return msgDefs, errs
+}
+
+// splitConfigStateFields moves the fields of msgDef that represent leaves or
+// leaf-lists into separate messages, named by appending Config and State to the
+// name of msgDef, according to whether the leaf is configuration or state, as
+// specified by leafConfig. The enumerated types that are defined within msgDef
+// for the fields that are moved are moved with them. A field is added to msgDef
+// for each of the messages that is created, named config or state, with a tag
+// derived from its path. Field names and tags that are used are recorded in
+// definedFieldNames and definedTags. The generated messages are returned, and
+// no message is generated if there are no leaves of the corresponding kind.
+func splitConfigStateFields(msgDef *protoMsg, leafConfig map[*protoMsgField]bool, definedFieldNames map[string]bool, definedTags map[uint32]bool) ([]*protoMsg, error) {
+	var fields, configFields, stateFields []*protoMsgField
+	for _, f := range msgDef.Fields {
+		config, ok := leafConfig[f]
+		switch {
+		case !ok:
+			fields = append(fields, f)
+		case config:
+			configFields = append(configFields, f)
+		default:
+			stateFields = append(stateFields, f)
+		}
+	}
+	msgDef.Fields = fields
+
+	var splitMsgs []*protoMsg
+	for _, s := range []struct {
+		name   string
+		fields []*protoMsgField
+	}{
+		{"config", configFields},
+		{"state", stateFields},
+	} {
+		if len(s.fields) == 0 {
+			continue
+		}
+		if definedFieldNames[s.name] {
+			return nil, fmt.Errorf("proto: cannot split leaves of message %s into config and state messages, field %s already exists", msgDef.Name, s.name)
+		}
+		definedFieldNames[s.name] = true
+
+		m := &protoMsg{
+			Name:        fmt.Sprintf("%s%s", msgDef.Name, yang.CamelCase(s.name)),
+			YANGPath:    msgDef.YANGPath,
+			Enums:       map[string]*protoMsgEnum{},
+			Fields:      s.fields,
+			Module:      msgDef.Module,
+			AugmentedBy: msgDef.AugmentedBy,
+		}
+		for _, f := range s.fields {
+			types := []string{f.Type}
+			for _, of := range f.OneOfFields {
+				types = append(types, of.Type)
+			}
+			for _, t := range types {
+				if e, ok := msgDef.Enums[t]; ok {
+					m.Enums[t] = e
+					delete(msgDef.Enums, t)
+				}
+			}
+		}
+
+		t, err := fieldTag(fmt.Sprintf("%s/%s", msgDef.YANGPath, s.name))
+		if err != nil {
+			return nil, fmt.Errorf("proto: could not generate tag for %s field of %s: %v", s.name, msgDef.Name, err)
+		}
+		msgDef.Fields = append(msgDef.Fields, &protoMsgField{
+			Name: s.name,
+			Type: m.Name,
+			Tag:  uniqueFieldTag(t, definedTags),
+		})
+		splitMsgs = append(splitMsgs, m)
+	}
+	return splitMsgs, nil
+}
+
+// sortFieldsByTag sorts the supplied fields into ascending order of their
+// field numbers, such that they are output in the order that they are
+// serialised on the wire. A oneof is ordered by the lowest field number of
+// its fields, the order of which is unchanged. Fields with the same field
+// number are ordered by name.
+func sortFieldsByTag(fields []*protoMsgField) {
+	tag := func(f *protoMsgField) uint32 {
+		if !f.IsOneOf || len(f.OneOfFields) == 0 {
+			return f.Tag
+		}
+		t := f.OneOfFields[0].Tag
+		for _, of := range f.OneOfFields[1:] {
+			if of.Tag < t {
+				t = of.Tag
+			}
+		}
+		return t
+	}
+	sort.SliceStable(fields, func(i, j int) bool {
+		if ti, tj := tag(fields[i]), tag(fields[j]); ti != tj {
+			return ti < tj
+		}
+		return fields[i].Name < fields[j].Name
+	})
+}
+
+// sortOneofFieldsByTag sorts the fields within each oneof in the supplied
+// fields into ascending order of their field numbers, leaving the order of
+// the supplied fields themselves unchanged.
+func sortOneofFieldsByTag(fields []*protoMsgField) {
+	for _, f := range fields {
+		if f.IsOneOf {
+			sortFieldsByTag(f.OneOfFields)
+		}
+	}
+}
+
+// reservedFieldTags takes an input set of field numbers that were previously
+// used within a message, and the set of field numbers that are used by the
+// fields that are currently defined in the message, and returns the sorted,
+// de-duplicated, set of field numbers that are no longer used and hence should
+// be reserved such that they are not reused by fields subsequently added to
+// the message. Since field numbers are derived from the schema path of each
+// field, a field that remains within the schema retains its field number.
+func reservedFieldTags(previousTags []uint32, definedTags map[uint32]bool) []uint32 {
+	seen := map[uint32]bool{}
+	var tags []uint32
+	for _, t := range previousTags {
+		if definedTags[t] || seen[t] {
+			continue
+		}
+		seen[t] = true
+		tags = append(tags, t)
+	}
+	sort.Slice(tags, func(i, j int) bool { return tags[i] < tags[j] })
+	return tags
+}
+
+// protoSchemaPathField returns a field that is to be added to the message
+// generated for msg to store its schema path. The tag of the field is
+// calculated from the path of msg such that it is stable across code
+// generation runs. The name of the field is added to definedFieldNames, and
+// disambiguated using the suffix specified by cfg.
+func protoSchemaPathField(msg *yangDirectory, definedFieldNames map[string]bool, cfg *protoMsgConfig) (*protoMsgField, error) {
+	t, err := fieldTag(fmt.Sprintf("%s/%s", msg.entry.Path(), protoSchemaPathFieldName))
+	if err != nil {
+		return nil, fmt.Errorf("proto: could not generate tag for schema path field of %s: %v", msg.name, err)
+	}
 
-	return append(msgDefs, msgDef), errs
+	return &protoMsgField{
+		Name:    cfg.makeNameUnique(protoSchemaPathFieldName, definedFieldNames),
+		Type:    "string",
+		Tag:     t,
+		Comment: fmt.Sprintf("%s stores the schema path of this message, %s.", protoSchemaPathFieldName, slicePathToString(msg.path)),
+	}, nil
 }
 
 // protoDefinitionArgs is used as the input argument when YANG is being mapped to protobuf.
@@ -712,8 +1452,9 @@ func addProtoListField(fieldDef *protoMsgField, msgDef *protoMsg, args *protoDef
 
 	fieldDef.Type = listDef.listType
 
-	// Lists are always repeated fields.
-	fieldDef.IsRepeated = true
+	// Lists are repeated fields, unless they are output as a map.
+	fieldDef.IsRepeated = !listDef.isMap
+	fieldDef.IsMap = listDef.isMap
 	return nKeyMsg, listDef.imports, nil
 }
 
@@ -730,20 +1471,30 @@ func addProtoContainerField(fieldDef *protoMsgField, args *protoDefinitionArgs)
 
 	var pfx string
 	if !(args.cfg.compressPaths && args.directory.isFakeRoot) {
+		// The package of the child is determined by its location in the data
+		// tree, rather than the module that defines it. A container that is
+		// introduced by an augment from another module is therefore within the
+		// package of the augmented node, and is imported and qualified based on
+		// that package.
 		childpkg := args.state.protobufPackage(childmsg.entry, args.cfg.compressPaths)
-		// Add the import to the slice of imports if it is not already
-		// there. This allows the message file to import the required
-		// child packages.
-		childpath := importPath(args.cfg.baseImportPath, args.cfg.basePackageName, childpkg)
-		if imports[childpath] == nil {
-			if !args.cfg.nestedMessages || args.directory.isFakeRoot {
-				imports[childpath] = true
+		// When the child is in the same package as its parent - as is the
+		// case when all messages are output in a single package - the type
+		// does not need to be qualified, and no import is required.
+		if childpkg != args.parentPkg {
+			// Add the import to the slice of imports if it is not already
+			// there. This allows the message file to import the required
+			// child packages.
+			childpath := importPath(args.cfg.baseImportPath, args.cfg.basePackageName, childpkg)
+			if imports[childpath] == nil {
+				if !args.cfg.nestedMessages || args.directory.isFakeRoot {
+					imports[childpath] = true
+				}
 			}
-		}
 
-		p, _ := stripPackagePrefix(args.parentPkg, childpkg)
-		if !args.cfg.nestedMessages || args.directory.isFakeRoot {
-			pfx = fmt.Sprintf("%s.", p)
+			p, _ := stripPackagePrefix(args.parentPkg, childpkg)
+			if !args.cfg.nestedMessages || args.directory.isFakeRoot {
+				pfx = fmt.Sprintf("%s.", p)
+			}
 		}
 	}
 	fieldDef.Type = fmt.Sprintf("%s%s", pfx, childmsg.name)
@@ -765,6 +1516,10 @@ func addProtoLeafOrLeafListField(fieldDef *protoMsgField, msgDef *protoMsg, args
 	}
 
 	fieldDef.Type = d.protoType
+	fieldDef.Options = append(fieldDef.Options, d.options...)
+	if d.comment != "" {
+		fieldDef.Comment = d.comment
+	}
 
 	// For any enumerations that were within the field definition, glean them into the
 	// message definition.
@@ -791,10 +1546,8 @@ func addProtoLeafOrLeafListField(fieldDef *protoMsgField, msgDef *protoMsg, args
 		}
 	}
 
-	// Add the global enumeration package if it is referenced by this field.
-	if d.globalEnum {
-		imports = append(imports, importPath(args.cfg.baseImportPath, args.cfg.basePackageName, args.cfg.enumPackageName))
-	}
+	// Add the global enumeration packages that are referenced by this field.
+	imports = append(imports, d.enumImports...)
 
 	if args.field.ListAttr != nil {
 		fieldDef.IsRepeated = true
@@ -804,12 +1557,15 @@ func addProtoLeafOrLeafListField(fieldDef *protoMsgField, msgDef *protoMsg, args
 
 // writeProtoEnums takes a map of enumerated types within the YANG schema and
 // returns the mapped Protobuf enum definition corresponding to each type. If
-// the annotateEnumNames bool is set, then the original enum value label is
-// stored in the definition. Since leaves that are of type enumeration are
-// output directly within a Protobuf message, these are skipped.
-func writeProtoEnums(enums map[string]*yangEnum, annotateEnumNames bool) ([]string, util.Errors) {
+// the annotateEnumNames field of the supplied cfg is set, then the original
+// enum value label is stored in the definition. Since leaves that are of type
+// enumeration are output directly within a Protobuf message, these are skipped.
+// If the identitiesFile field of the supplied cfg is set, the enums generated
+// for YANG identities are returned in the second slice, such that they can be
+// output in a separate file, otherwise all enums are returned in the first.
+func writeProtoEnums(enums map[string]*yangEnum, cfg *protoMsgConfig) ([]string, []string, util.Errors) {
 	var errs util.Errors
-	var genEnums []string
+	var genEnums, genIdentities []string
 	for _, enum := range enums {
 		// TODO(robjs): Currently, we do not skip enumerations that are within unions
 		// that have been extracted by findEnumSet here. This means that we can end
@@ -823,46 +1579,29 @@ func writeProtoEnums(enums map[string]*yangEnum, annotateEnumNames bool) ([]stri
 			continue
 		}
 
+		if isIdentityrefLeaf(enum.entry) && cfg.skipIdentityEnum(enum.entry.Type.IdentityBase) {
+			// Skip identities that have no derived identities, since the fields
+			// that reference them are mapped to strings.
+			continue
+		}
+
 		// Make the name of the enum upper case to follow Protobuf enum convention.
 		p := &protoEnum{Name: enum.name}
 		switch {
 		case isIdentityrefLeaf(enum.entry):
-			// For an identityref the values are based on
-			// the name of the identities that correspond with the base, and the value
-			// is gleaned from the YANG schema.
-			values := map[int64]protoEnumValue{
-				0: {ProtoLabel: protoEnumZeroName},
-			}
-
-			// Ensure that we output the identity values in a determinstic order.
-			nameMap := map[string]*yang.Identity{}
-			var names []string
-			for _, v := range enum.entry.Type.IdentityBase.Values {
-				names = append(names, v.Name)
-				nameMap[v.Name] = v
-			}
-
-			for _, n := range names {
-				v := nameMap[n]
-				// Calculate a tag value for the identity values, since otherwise when another
-				// module augments this module then the enum values may be subject to change.
-				tag, err := fieldTag(fmt.Sprintf("%s%s", enum.entry.Type.IdentityBase.Name, v.Name))
-				if err != nil {
-					errs = append(errs, fmt.Errorf("cannot calculate tag for %s: %v", v.Name, err))
-				}
-
-				values[int64(tag)] = toProtoEnumValue(strings.ToUpper(safeProtoIdentifierName(v.Name)), v.Name, annotateEnumNames)
-			}
-			p.Values = values
+			ge, ierrs := genProtoIdentityEnum(enum.entry.Type.IdentityBase, cfg)
+			errs = append(errs, ierrs...)
+			p.Values = ge.Values
 			p.ValuePrefix = strings.ToUpper(enum.name)
 			p.Description = fmt.Sprintf("YANG identity %s", enum.entry.Type.IdentityBase.Name)
 		case enum.entry.Type.Kind == yang.Yenum:
-			ge, err := genProtoEnum(enum.entry, annotateEnumNames)
+			ge, err := genProtoEnum(enum.entry, cfg)
 			if err != nil {
 				errs = append(errs, err)
 				continue
 			}
 			p.Values = ge.Values
+			p.AllowAlias = ge.AllowAlias
 
 			// If the supplied enum entry has the valuePrefix annotation then use it to
 			// calculate the enum value names.
@@ -891,86 +1630,491 @@ func writeProtoEnums(enums map[string]*yangEnum, annotateEnumNames bool) ([]stri
 			errs = append(errs, fmt.Errorf("cannot generate enumeration for %s: %v", enum.name, err))
 			continue
 		}
+		if cfg.identitiesFile && isIdentityrefLeaf(enum.entry) {
+			genIdentities = append(genIdentities, b.String())
+			continue
+		}
 		genEnums = append(genEnums, b.String())
 	}
 
 	if len(errs) != 0 {
-		return nil, errs
+		return nil, nil, errs
 	}
-	return genEnums, nil
+	return genEnums, genIdentities, nil
 }
 
-// genProtoEnum takes an input yang.Entry that contains an enumerated type
-// and returns a protoMsgEnum that contains its definition within the proto
-// schema. If the annotateEnumNames bool is set, then the original YANG name
-// is stored with each enum value.
-func genProtoEnum(field *yang.Entry, annotateEnumNames bool) (*protoMsgEnum, error) {
-	eval := map[int64]protoEnumValue{}
-	names := field.Type.Enum.NameMap()
-	eval[0] = protoEnumValue{ProtoLabel: protoEnumZeroName}
+// protoScalarTypes is the set of scalar types that are built into protobuf.
+var protoScalarTypes = map[string]bool{
+	"double":   true,
+	"float":    true,
+	"int32":    true,
+	"int64":    true,
+	"uint32":   true,
+	"uint64":   true,
+	"sint32":   true,
+	"sint64":   true,
+	"fixed32":  true,
+	"fixed64":  true,
+	"sfixed32": true,
+	"sfixed64": true,
+	"bool":     true,
+	"string":   true,
+	"bytes":    true,
+}
 
-	if d := field.DefaultValue(); d != "" {
-		if _, ok := names[d]; !ok {
-			return nil, fmt.Errorf("enumeration %s specified a default - %s - that was not a valid value", field.Path(), d)
+// validateEnumReferences checks that each field within the supplied messages
+// that references an enumerated type has a corresponding enum definition. The
+// messages and enums are keyed by the fully qualified name of the package that
+// they are within. A field is considered to reference an enumerated type if its
+// type - or the value type of a map field - is not a protobuf scalar type, a
+// ywrapper or google.protobuf type, or the name of one of the supplied
+// messages. Unqualified type names must correspond to an enum that is embedded
+// within the message, or a message within the same package, whereas qualified
+// names (e.g., base.enums.Name) are resolved to the package that they specify.
+// It returns an error for each reference that does not have a definition, and
+// can be used to detect enumerated types that have been routed incorrectly
+// during code generation.
+func validateEnumReferences(msgs map[string][]protoMsg, enums map[string][]protoEnum) []error {
+	msgNames := map[string]bool{}
+	for pkg, ms := range msgs {
+		for _, m := range ms {
+			msgNames[fmt.Sprintf("%s.%s", pkg, m.Name)] = true
+		}
+	}
+	enumNames := map[string]bool{}
+	for pkg, es := range enums {
+		for _, e := range es {
+			enumNames[fmt.Sprintf("%s.%s", pkg, e.Name)] = true
 		}
-
-		eval[0] = toProtoEnumValue(safeProtoIdentifierName(d), d, annotateEnumNames)
 	}
 
-	for n := range names {
-		if n == field.DefaultValue() {
-			// Can't happen if there was not a default, since "" is not
-			// a valid enumeration name in YANG.
-			continue
+	// isMsg returns true if the fully qualified type name t is a message, or a
+	// type that is nested within a message.
+	isMsg := func(t string) bool {
+		for {
+			if msgNames[t] {
+				return true
+			}
+			i := strings.LastIndex(t, ".")
+			if i == -1 {
+				return false
+			}
+			t = t[:i]
 		}
-		// Names are converted to upper case to follow the protobuf style guide,
-		// adding one to ensure that the 0 value can represent unused values.
-		eval[field.Type.Enum.Value(n)+1] = toProtoEnumValue(safeProtoIdentifierName(n), n, annotateEnumNames)
 	}
 
-	return &protoMsgEnum{Values: eval}, nil
-}
+	var errs []error
+	checkField := func(pkg string, m protoMsg, f *protoMsgField) {
+		t := f.Type
+		if strings.HasPrefix(t, "map<") && strings.HasSuffix(t, ">") {
+			kv := strings.SplitN(strings.TrimSuffix(strings.TrimPrefix(t, "map<"), ">"), ",", 2)
+			if len(kv) != 2 {
+				errs = append(errs, fmt.Errorf("message %s field %s has invalid map type %s", m.Name, f.Name, f.Type))
+				return
+			}
+			t = strings.TrimSpace(kv[1])
+		}
 
-// protoMsgListField describes a list field within a protobuf mesage.
-type protoMsgListField struct {
-	listType string   // listType is the name of the message that represents a list member.
-	imports  []string // imports is the set of modules that are required by this list message.
-}
+		switch {
+		case t == "", protoScalarTypes[t]:
+			return
+		case strings.HasPrefix(t, "ywrapper."), strings.HasPrefix(t, "google.protobuf."):
+			return
+		case !strings.Contains(t, "."):
+			if _, ok := m.Enums[t]; !ok && !msgNames[fmt.Sprintf("%s.%s", pkg, t)] {
+				errs = append(errs, fmt.Errorf("message %s field %s references embedded enum %s that is not defined", m.Name, f.Name, t))
+			}
+		case !enumNames[t] && !isMsg(t):
+			errs = append(errs, fmt.Errorf("message %s field %s references enum %s that is not defined", m.Name, f.Name, t))
+		}
+	}
 
-// protoListDefinition takes an input field described by a yang.Entry, the generator context (the set of proto messages, and the generator
-// state), along with whether path compression is enabled and generates the proto message definition for the list. It returns the definition
-// of the field representing the list as a protoMsgListField and an optional message which stores the key of a keyed list.
-func protoListDefinition(args *protoDefinitionArgs) (*protoMsgListField, *protoMsg, error) {
-	listMsg, ok := args.definedDirectories[args.field.Path()]
-	if !ok {
-		return nil, nil, fmt.Errorf("proto: could not resolve list %s into a defined message", args.field.Path())
+	var pkgs []string
+	for pkg := range msgs {
+		pkgs = append(pkgs, pkg)
+	}
+	sort.Strings(pkgs)
+	for _, pkg := range pkgs {
+		for _, m := range msgs[pkg] {
+			for _, f := range m.Fields {
+				checkField(pkg, m, f)
+				for _, of := range f.OneOfFields {
+					checkField(pkg, m, of)
+				}
+			}
+		}
 	}
+	return errs
+}
 
-	listMsgName, ok := args.state.uniqueDirectoryNames[args.field.Path()]
-	if !ok {
-		return nil, nil, fmt.Errorf("proto: could not find unique message name for %s", args.field.Path())
+// checkEnumWireCompat checks whether the enums in next, which are generated by
+// a subsequent version of a schema, are wire compatible with those in prev.
+// Enums are matched by name, and values within them are matched by label. It
+// returns an error for each value of an enum in prev whose number has changed,
+// or that is no longer defined, in next, since data encoded using the previous
+// enum would not be decoded as the same value. Enums and values that are added
+// in next do not affect compatibility.
+func checkEnumWireCompat(prev, next []protoEnum) []error {
+	nextValues := map[string]map[string]int64{}
+	for _, e := range next {
+		nextValues[e.Name] = protoEnumLabelValues(e.Values)
 	}
 
-	childPkg := args.state.protobufPackage(listMsg.entry, args.cfg.compressPaths)
+	sorted := append([]protoEnum{}, prev...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Name < sorted[j].Name })
 
-	var listKeyMsg *protoMsg
-	var listDef *protoMsgListField
-	if !isKeyedList(listMsg.entry) {
-		// In proto3 we represent unkeyed lists as a
-		// repeated field of the list message.
-		listDef = &protoMsgListField{
-			listType: listMsgName,
+	var errs []error
+	for _, e := range sorted {
+		nv, ok := nextValues[e.Name]
+		if !ok {
+			errs = append(errs, fmt.Errorf("enum %s was removed", e.Name))
+			continue
+		}
+		pv := protoEnumLabelValues(e.Values)
+		var labels []string
+		for l := range pv {
+			labels = append(labels, l)
 		}
-		if !args.cfg.nestedMessages {
-			p := fmt.Sprintf("%s.%s.%s", args.cfg.basePackageName, childPkg, listMsgName)
-			p, _ = stripPackagePrefix(fmt.Sprintf("%s.%s", args.cfg.basePackageName, args.parentPkg), p)
-			listDef = &protoMsgListField{
-				listType: p,
+		sort.Strings(labels)
+		for _, l := range labels {
+			n, ok := nv[l]
+			switch {
+			case !ok:
+				errs = append(errs, fmt.Errorf("enum %s value %s (%d) was removed", e.Name, l, pv[l]))
+			case n != pv[l]:
+				errs = append(errs, fmt.Errorf("enum %s value %s changed from %d to %d", e.Name, l, pv[l], n))
 			}
-			listDef.imports = []string{importPath(args.cfg.baseImportPath, args.cfg.basePackageName, childPkg)}
 		}
-	} else {
-		// YANG lists are mapped to a repeated message structure as described
+	}
+	return errs
+}
+
+// protoEnumLabelValues returns a map, keyed by label, of the number of each of
+// the values, including aliases, of an enum.
+func protoEnumLabelValues(values map[int64]protoEnumValue) map[string]int64 {
+	m := map[string]int64{}
+	for n, v := range values {
+		m[v.ProtoLabel] = n
+		for _, a := range v.Aliases {
+			m[a.ProtoLabel] = n
+		}
+	}
+	return m
+}
+
+// genProtoIdentityEnum takes an input YANG identity, base, and returns a
+// protoMsgEnum that contains the definition of the enum that is generated for
+// the identityref leaves that reference it. The values of the enum are the
+// identities derived from base, whose numbers are calculated from a hash of
+// their names, such that they are stable as identities are added. The zero
+// value indicates that a field is unset. Identities for which a value cannot
+// be calculated are omitted from the enum, and the errors encountered are
+// returned.
+func genProtoIdentityEnum(base *yang.Identity, cfg *protoMsgConfig) (*protoMsgEnum, []error) {
+	// For an identityref the values are based on
+	// the name of the identities that correspond with the base, and the value
+	// is gleaned from the YANG schema.
+	values := map[int64]protoEnumValue{
+		0: cfg.enumZeroValue(),
+	}
+
+	// Ensure that we output the identity values in a determinstic order. Where
+	// identities that are defined in different modules have the same name, that
+	// which is defined in the module of the base identity is output first,
+	// followed by the others in the order of the names of their modules.
+	baseModule := identityModuleName(base)
+	identities := append([]*yang.Identity{}, base.Values...)
+	sort.SliceStable(identities, func(i, j int) bool {
+		a, b := identities[i], identities[j]
+		if a.Name != b.Name {
+			return a.Name < b.Name
+		}
+		am, bm := identityModuleName(a), identityModuleName(b)
+		if (am == baseModule) != (bm == baseModule) {
+			return am == baseModule
+		}
+		return am < bm
+	})
+
+	// definedTags stores the values that have been used within the enum, such that
+	// colliding values are made unique rather than overwriting one another.
+	definedTags := map[uint32]bool{0: true}
+	tags := make([]uint32, len(identities))
+	failed := map[int]bool{}
+	var collided []int
+	var errs []error
+	for i, v := range identities {
+		// Calculate a tag value for the identity values, since otherwise when another
+		// module augments this module then the enum values may be subject to change.
+		// Since the value is derived only from the name of the base and the identity,
+		// adding an identity does not change the values of the existing identities.
+		tag, err := identityEnumValue(fmt.Sprintf("%s%s", base.Name, v.Name), cfg.valueOffset())
+		if err != nil {
+			errs = append(errs, fmt.Errorf("cannot calculate tag for %s: %v", v.Name, err))
+			failed[i] = true
+			continue
+		}
+		if definedTags[tag] {
+			collided = append(collided, i)
+			continue
+		}
+		definedTags[tag] = true
+		tags[i] = tag
+	}
+
+	// Identities whose values collide with that of another identity - which
+	// occurs where identities in different modules have the same name - have
+	// their value calculated from a hash that is qualified by the name of their
+	// module, followed by a counter if that value is also used. Values are not
+	// probed sequentially, since the value following a colliding value may be
+	// that of an identity that is subsequently added.
+	for _, i := range collided {
+		v := identities[i]
+		s := fmt.Sprintf("%s:%s:%s", identityModuleName(v), base.Name, v.Name)
+		for n := 1; ; n++ {
+			tag, err := identityEnumValue(s, cfg.valueOffset())
+			if err != nil {
+				errs = append(errs, fmt.Errorf("cannot calculate tag for %s: %v", v.Name, err))
+				failed[i] = true
+				break
+			}
+			if !definedTags[tag] {
+				definedTags[tag] = true
+				tags[i] = tag
+				break
+			}
+			s = fmt.Sprintf("%s:%s:%s:%d", identityModuleName(v), base.Name, v.Name, n)
+		}
+	}
+
+	definedLabels := map[string]bool{values[0].ProtoLabel: true}
+	for i, v := range identities {
+		if failed[i] {
+			continue
+		}
+		label := strings.ToUpper(safeProtoIdentifierName(v.Name))
+		if cfg.upperSnakeEnums {
+			label = upperSnakeCase(v.Name)
+		}
+		values[int64(tags[i])] = toProtoEnumValue(cfg.makeNameUnique(label, definedLabels), v.Name, cfg.annotateEnumNames)
+	}
+
+	return &protoMsgEnum{Values: values}, errs
+}
+
+// identityEnumValue returns the value of the enum value that is generated for
+// an identity, calculated from a hash of the string s. Values below the offset
+// off are reserved, and hence the hash is mapped into the range between off
+// and the maximum value of an enum value, such that hashes that fall within
+// the range are unchanged, and those below it wrap around to its end rather
+// than all being mapped to the same value.
+func identityEnumValue(s string, off int64) (uint32, error) {
+	tag, err := fieldTag(s)
+	if err != nil {
+		return 0, err
+	}
+	if int64(tag) < off {
+		tag = uint32(int64(tag) + math.MaxInt32 - off + 1)
+	}
+	return tag, nil
+}
+
+// identityModuleName returns the name of the module in which the identity i is
+// defined, or the empty string if it is not known.
+func identityModuleName(i *yang.Identity) string {
+	if i.Parent == nil {
+		return ""
+	}
+	m := yang.RootNode(i)
+	if m == nil {
+		return ""
+	}
+	if m.Kind() == "submodule" && m.BelongsTo != nil {
+		return m.BelongsTo.Name
+	}
+	return m.Name
+}
+
+// genProtoEnum takes an input yang.Entry that contains an enumerated type
+// and returns a protoMsgEnum that contains its definition within the proto
+// schema. If the annotateEnumNames field of the supplied cfg is set, then the
+// original YANG name is stored with each enum value. If upperSnakeEnums is set,
+// the labels of the values are converted to UPPER_SNAKE_CASE. The default value
+// of the field, if any, is used as the zero value of the enum, unless
+// annotateDefaults is set, in which case the zero value always indicates that
+// the field is unset, and the default is annotated onto the field. A value
+// whose number is the same as that of another value is output as an alias of
+// it, with AllowAlias set in the returned protoMsgEnum.
+func genProtoEnum(field *yang.Entry, cfg *protoMsgConfig) (*protoMsgEnum, error) {
+	eval := map[int64]protoEnumValue{}
+	names := field.Type.Enum.NameMap()
+	eval[0] = cfg.enumZeroValue()
+
+	label := func(n string) string {
+		if cfg.upperSnakeEnums {
+			return upperSnakeCase(n)
+		}
+		return safeProtoIdentifierName(n)
+	}
+
+	var zeroDefault string
+	if d := field.DefaultValue(); d != "" {
+		if _, ok := names[d]; !ok {
+			return nil, fmt.Errorf("enumeration %s specified a default - %s - that was not a valid value", field.Path(), d)
+		}
+
+		if !cfg.annotateDefaults {
+			zeroDefault = d
+			eval[0] = toProtoEnumValue(label(d), d, cfg.annotateEnumNames)
+		}
+	}
+
+	// Process the names in order of their value such that where two names map
+	// to the same label, the label that is made unique is deterministic.
+	var ordered []string
+	for n := range names {
+		ordered = append(ordered, n)
+	}
+	sort.Slice(ordered, func(i, j int) bool { return names[ordered[i]] < names[ordered[j]] })
+
+	var allowAlias bool
+	definedLabels := map[string]bool{eval[0].ProtoLabel: true}
+	for _, n := range ordered {
+		if n == zeroDefault {
+			// Can't happen if there was not a default, since "" is not
+			// a valid enumeration name in YANG.
+			continue
+		}
+		// Names are converted to upper case to follow the protobuf style guide,
+		// adding the offset to ensure that the 0 value can represent unused
+		// values.
+		v := names[n] + cfg.valueOffset()
+		if v > math.MaxInt32 {
+			return nil, fmt.Errorf("enumeration %s value %s has value %d with offset %d, which cannot be represented in a protobuf enum", field.Path(), n, v, cfg.valueOffset())
+		}
+		ev := toProtoEnumValue(cfg.makeNameUnique(label(n), definedLabels), n, cfg.annotateEnumNames)
+		if ex, ok := eval[v]; ok {
+			// The value has the same number as one that is already defined - for
+			// example, where the offset maps a negative YANG value to zero - and
+			// hence it is output as an alias, rather than overwriting it.
+			ex.Aliases = append(ex.Aliases, ev)
+			eval[v] = ex
+			allowAlias = true
+			continue
+		}
+		eval[v] = ev
+	}
+
+	return &protoMsgEnum{Values: eval, AllowAlias: allowAlias}, nil
+}
+
+// genProtoBitsEnum takes an input yang.YangType describing a YANG bits type,
+// and returns the enumerated type that documents the position of each of the
+// bits that it defines within the bitmask used to represent it. The value of
+// each enumerated value is the position of the bit. Since a protobuf enum must
+// have a zero value, the configured zero value is added if no bit is at
+// position zero.
+func genProtoBitsEnum(t *yang.YangType, cfg *protoMsgConfig) (*protoMsgEnum, error) {
+	if t.Bit == nil {
+		return nil, fmt.Errorf("bits type %s does not define any bits", t.Name)
+	}
+	names := t.Bit.NameMap()
+
+	label := func(n string) string {
+		if cfg.upperSnakeEnums {
+			return upperSnakeCase(n)
+		}
+		return safeProtoIdentifierName(n)
+	}
+
+	// Process the names in order of their position such that where two names
+	// map to the same label, the label that is made unique is deterministic.
+	var ordered []string
+	for n, pos := range names {
+		if pos < 0 || pos > 63 {
+			return nil, fmt.Errorf("bit %s of type %s has position %d, which cannot be represented in a 64-bit bitmask", n, t.Name, pos)
+		}
+		ordered = append(ordered, n)
+	}
+	sort.Slice(ordered, func(i, j int) bool { return names[ordered[i]] < names[ordered[j]] })
+
+	eval := map[int64]protoEnumValue{}
+	definedLabels := map[string]bool{}
+	if len(ordered) == 0 || names[ordered[0]] != 0 {
+		eval[0] = cfg.enumZeroValue()
+		definedLabels[eval[0].ProtoLabel] = true
+	}
+	for _, n := range ordered {
+		eval[names[n]] = toProtoEnumValue(cfg.makeNameUnique(label(n), definedLabels), n, cfg.annotateEnumNames)
+	}
+
+	return &protoMsgEnum{Values: eval}, nil
+}
+
+// protoMsgListField describes a list field within a protobuf mesage.
+type protoMsgListField struct {
+	listType string   // listType is the name of the message that represents a list member.
+	imports  []string // imports is the set of modules that are required by this list message.
+	isMap    bool     // isMap indicates that the list is output as a map, whose type is specified by listType.
+}
+
+// protoMapKeyTypes is the set of protobuf scalar types that can be used as the
+// key of a protobuf map field.
+var protoMapKeyTypes = map[string]bool{
+	"int32":    true,
+	"int64":    true,
+	"uint32":   true,
+	"uint64":   true,
+	"sint32":   true,
+	"sint64":   true,
+	"fixed32":  true,
+	"fixed64":  true,
+	"sfixed32": true,
+	"sfixed64": true,
+	"bool":     true,
+	"string":   true,
+}
+
+// protoListDefinition takes an input field described by a yang.Entry, the generator context (the set of proto messages, and the generator
+// state), along with whether path compression is enabled and generates the proto message definition for the list. It returns the definition
+// of the field representing the list as a protoMsgListField and an optional message which stores the key of a keyed list.
+func protoListDefinition(args *protoDefinitionArgs) (*protoMsgListField, *protoMsg, error) {
+	listMsg, ok := args.definedDirectories[args.field.Path()]
+	if !ok {
+		return nil, nil, fmt.Errorf("proto: could not resolve list %s into a defined message", args.field.Path())
+	}
+
+	listMsgName, ok := args.state.uniqueDirectoryNames[args.field.Path()]
+	if !ok {
+		return nil, nil, fmt.Errorf("proto: could not find unique message name for %s", args.field.Path())
+	}
+
+	childPkg := args.state.protobufPackage(listMsg.entry, args.cfg.compressPaths)
+
+	var listKeyMsg *protoMsg
+	var listDef *protoMsgListField
+	var mapKeyType string
+	if isKeyedList(listMsg.entry) && args.cfg.singleKeyMaps {
+		var err error
+		if mapKeyType, err = protoMapKeyType(listMsg, args); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	switch {
+	case !isKeyedList(listMsg.entry):
+		// In proto3 we represent unkeyed lists as a
+		// repeated field of the list message.
+		listDef = protoListMemberField(childPkg, listMsgName, args)
+	case mapKeyType != "":
+		// Keyed lists with a single key that can be used as the key of a
+		// protobuf map are output as a map of the key's value to the list
+		// message, such that no key message is required.
+		listDef = protoListMemberField(childPkg, listMsgName, args)
+		listDef.listType = fmt.Sprintf("map<%s, %s>", mapKeyType, listDef.listType)
+		listDef.isMap = true
+	default:
+		// YANG lists are mapped to a repeated message structure as described
 		// in the YANG to Protobuf transformation specification.
 		var err error
 		listKeyMsg, err = genListKeyProto(childPkg, listMsgName, &protoDefinitionArgs{
@@ -983,6 +2127,17 @@ func protoListDefinition(args *protoDefinitionArgs) (*protoMsgListField, *protoM
 		if err != nil {
 			return nil, nil, fmt.Errorf("proto: could not build mapping for list entry %s: %v", args.field.Path(), err)
 		}
+		// The key message is output alongside the message of the list's parent,
+		// such that when the parent is itself a list, lists of the same name
+		// within different lists in the same package would otherwise result in
+		// key messages with the same name.
+		if args.cfg.qualifyNestedKeys && args.directory.entry.IsList() {
+			parentName, ok := args.state.uniqueDirectoryNames[args.directory.entry.Path()]
+			if !ok {
+				return nil, nil, fmt.Errorf("proto: could not find unique message name for %s", args.directory.entry.Path())
+			}
+			listKeyMsg.Name = fmt.Sprintf("%s%s", parentName, listKeyMsg.Name)
+		}
 		// The type of this field is just the key message's name, since it
 		// will be in the same package as the field's parent.
 		listDef = &protoMsgListField{
@@ -993,26 +2148,112 @@ func protoListDefinition(args *protoDefinitionArgs) (*protoMsgListField, *protoM
 	return listDef, listKeyMsg, nil
 }
 
+// protoListMemberField returns the definition of a field whose type is the
+// message named listMsgName, within the package childPkg, that represents a
+// member of the list described by args. The type is qualified by its package,
+// and the package is imported, where it differs from that of the list's parent.
+func protoListMemberField(childPkg, listMsgName string, args *protoDefinitionArgs) *protoMsgListField {
+	// When the list message is in the same package as its parent - as is
+	// the case when path compression places a list directly beneath the
+	// fake root - the type does not need to be qualified, and no import is
+	// required.
+	if args.cfg.nestedMessages || childPkg == args.parentPkg {
+		return &protoMsgListField{
+			listType: listMsgName,
+		}
+	}
+
+	childFQ, parentFQ := args.cfg.basePackageName, args.cfg.basePackageName
+	if childPkg != "" {
+		childFQ = fmt.Sprintf("%s.%s", args.cfg.basePackageName, childPkg)
+	}
+	if args.parentPkg != "" {
+		parentFQ = fmt.Sprintf("%s.%s", args.cfg.basePackageName, args.parentPkg)
+	}
+	p, _ := stripPackagePrefix(parentFQ, fmt.Sprintf("%s.%s", childFQ, listMsgName))
+	return &protoMsgListField{
+		listType: p,
+		imports:  []string{filepath.Join(append([]string{args.cfg.baseImportPath}, protoPackageToFilePath(childFQ)...)...)},
+	}
+}
+
+// protoMapKeyType returns the protobuf type of the key of the keyed list
+// described by args, whose message is listMsg, if the list can be output as a
+// protobuf map. An empty string is returned if the list has more than one key,
+// or if the type of its key cannot be used as the key of a map, as is the
+// case for enumerated, union and decimal64 keys.
+func protoMapKeyType(listMsg *yangDirectory, args *protoDefinitionArgs) (string, error) {
+	keys := strings.Fields(args.field.Key)
+	if len(keys) != 1 {
+		return "", nil
+	}
+	kf, ok := listMsg.fields[keys[0]]
+	if !ok {
+		return "", fmt.Errorf("list %s included a key %s that did not exist", args.field.Path(), keys[0])
+	}
+
+	pargs := args.cfg.resolveProtoTypeArgs()
+	pargs.scalarTypeInSingleTypeUnion = true
+	scalarType, err := args.state.yangTypeToProtoScalarType(resolveTypeArgs{
+		yangType:     kf.Type,
+		contextEntry: kf,
+	}, pargs)
+	if err != nil {
+		return "", fmt.Errorf("list %s included a key %s that did not have a valid proto type: %v: %v", args.field.Path(), keys[0], kf.Type, err)
+	}
+	if scalarType.unionTypes != nil || !protoMapKeyTypes[scalarType.nativeType] {
+		return "", nil
+	}
+	return scalarType.nativeType, nil
+}
+
 // protoDefinedLeaf defines a YANG leaf within a protobuf message.
 type protoDefinedLeaf struct {
 	protoType   string                   // protoType is the protobuf type that the leaf should be mapped to.
-	globalEnum  bool                     // globalEnum indicates whether the leaf's type is a global scope enumeration (identityref, or typedef defining an enumeration)
+	enumImports []string                 // enumImports specifies the imports required for the global scope enumerations (identityref, or typedef defining an enumeration) used by the leaf.
 	enums       map[string]*protoMsgEnum // enums defines the set of enumerated values that are required for this leaf within the parent message.
 	oneofs      []*protoMsgField         // oneofs defines the set of types within the leaf, if the returned leaf type is a protobuf oneof.
 	repeatedMsg *protoMsg                // repeatedMsgs returns a message that should be repeated for this leaf, used in the case of a leaf-list of unions.
+	options     []*protoOption           // options specifies the field options that should be output for the leaf.
+	comment     string                   // comment specifies a comment that should be output prior to the field for the leaf.
 }
 
 // protoLeafDefinition takes an input leafName, and a set of protoDefinitionArgs specifying the context
 // for the leaf definition, and returns a protoDefinedLeaf describing how it is to be mapped within the
 // protobuf parent message.
 func protoLeafDefinition(leafName string, args *protoDefinitionArgs) (*protoDefinedLeaf, error) {
-	protoType, err := args.state.yangTypeToProtoType(resolveTypeArgs{
+	if isIdentityrefLeaf(args.field) && (args.cfg.identityrefAsString || args.cfg.skipIdentityEnum(args.field.Type.IdentityBase)) {
+		// When identityrefs are being represented as strings, or the base identity
+		// has no derived identities and hence no enum is generated for it, the leaf
+		// is mapped to a string wrapper, and the base identity is annotated onto the
+		// field such that the enumerated package is not referenced.
+		t := args.cfg.resolveProtoTypeArgs().wrapperType("ywrapper.StringValue")
+		if args.cfg.proto2 {
+			t = "string"
+		}
+		opts := []*protoOption{protoIdentityBaseAnnotation(args.field.Type.IdentityBase)}
+		if args.cfg.emptyEnumPolicy == AnnotateEmptyEnums && len(args.field.Type.IdentityBase.Values) == 0 {
+			opts = append(opts, &protoOption{Name: protoEmptyEnumAnnotationOption, Value: "true"})
+		}
+		return &protoDefinedLeaf{
+			protoType: t,
+			enums:     map[string]*protoMsgEnum{},
+			options:   opts,
+		}, nil
+	}
+
+	resolveType := args.state.yangTypeToProtoType
+	pargs := args.cfg.resolveProtoTypeArgs()
+	if args.cfg.proto2 {
+		// Since proto2 fields have explicit presence, the wrapper types are not
+		// required, and hence the scalar types are used.
+		resolveType = args.state.yangTypeToProtoScalarType
+		pargs.scalarTypeInSingleTypeUnion = true
+	}
+	protoType, err := resolveType(resolveTypeArgs{
 		yangType:     args.field.Type,
 		contextEntry: args.field,
-	}, resolveProtoTypeArgs{
-		basePackageName: args.cfg.basePackageName,
-		enumPackageName: args.cfg.enumPackageName,
-	})
+	}, pargs)
 	if err != nil {
 		return nil, err
 	}
@@ -1022,22 +2263,55 @@ func protoLeafDefinition(leafName string, args *protoDefinitionArgs) (*protoDefi
 		enums:     map[string]*protoMsgEnum{},
 	}
 
+	// Decimal64 values are mapped to a message that does not carry the precision
+	// of the value, and hence the fraction-digits of the YANG type are annotated
+	// onto the field.
+	fd, err := decimal64FractionDigits(args.field, args.state)
+	if err != nil {
+		return nil, err
+	}
+	if fd != 0 {
+		d.options = append(d.options, protoFractionDigitsAnnotation(fd))
+	}
+
 	switch {
 	case isSimpleEnumerationType(args.field.Type):
 		// For fields that are simple enumerations within a message, then we embed an enumeration
 		// within the Protobuf message.
-		e, err := genProtoEnum(args.field, args.cfg.annotateEnumNames)
+		e, err := genProtoEnum(args.field, args.cfg)
 		if err != nil {
 			return nil, err
 		}
 
-		d.protoType = makeNameUnique(protoType.nativeType, args.definedFieldNames)
+		d.protoType = args.cfg.makeNameUnique(protoType.nativeType, args.definedFieldNames)
 		d.enums = map[string]*protoMsgEnum{}
 		d.enums[d.protoType] = e
+	case args.field.Type.Kind == yang.Ybits:
+		// Bits are mapped to a bitmask, and hence an enumeration that documents
+		// the position of each bit within the mask is embedded in the message
+		// alongside the field.
+		e, err := genProtoBitsEnum(args.field.Type, args.cfg)
+		if err != nil {
+			return nil, err
+		}
+		d.enums[args.cfg.makeNameUnique(yang.CamelCase(args.field.Name), args.definedFieldNames)] = e
+	case args.field.Type.Kind == yang.Yempty:
+		// An empty leaf carries no value, and hence is mapped to a bool that
+		// indicates whether the leaf is present in the data tree.
+		d.comment = fmt.Sprintf("%s represents a YANG empty leaf, and is set to true when the leaf is present.", leafName)
 	case isEnumType(args.field.Type):
-		d.globalEnum = true
+		d.enumImports = []string{globalEnumImportPath(args.cfg.baseImportPath, protoType.nativeType)}
+	case protoType.unionTypes != nil && args.cfg.typedefUnions && args.field.Type.Name != "union":
+		// Unions that are defined by a typedef are mapped to a message within the
+		// unions package, which is shared by all fields of the type.
+		t, err := typedefUnionType(args.field, args.state, args.cfg)
+		if err != nil {
+			return nil, err
+		}
+		d.protoType = t
+		d.enumImports = []string{globalEnumImportPath(args.cfg.baseImportPath, t)}
 	case protoType.unionTypes != nil:
-		u, err := unionFieldToOneOf(leafName, args.field, protoType, args.cfg.annotateEnumNames)
+		u, err := unionFieldToOneOf(leafName, args.field, protoType, args.cfg)
 		if err != nil {
 			return nil, err
 		}
@@ -1047,7 +2321,7 @@ func protoLeafDefinition(leafName string, args *protoDefinitionArgs) (*protoDefi
 			d.enums[n] = e
 		}
 
-		d.globalEnum = u.hadGlobalEnums
+		d.enumImports = u.enumImports
 
 		// Append the oneof that was in the union.
 		d.oneofs = append(d.oneofs, u.oneOfFields...)
@@ -1058,9 +2332,75 @@ func protoLeafDefinition(leafName string, args *protoDefinitionArgs) (*protoDefi
 		}
 	}
 
+	if args.cfg.annotateDefaults && isEnumType(args.field.Type) {
+		o, err := protoEnumDefault(args.field)
+		if err != nil {
+			return nil, err
+		}
+		if o != nil {
+			d.options = append(d.options, o)
+		}
+	}
+
+	if args.cfg.scalarDefaults && !isEnumType(args.field.Type) {
+		d.options = append(d.options, protoDefaultValueAnnotations(yangDefaults(args.field))...)
+	}
+
 	return d, nil
 }
 
+// yangDefaults returns the YANG defaults of the leaf or leaf-list field, or
+// nil if it has none. Since a leaf-list may have more than one default, which
+// are not all recorded within the yang.Entry, the defaults of a leaf-list are
+// read from the statement from which it was created.
+func yangDefaults(field *yang.Entry) []string {
+	if ll, ok := field.Node.(*yang.LeafList); ok && len(ll.Default) != 0 {
+		var d []string
+		for _, v := range ll.Default {
+			d = append(d, v.Name)
+		}
+		return d
+	}
+	if d := field.DefaultValue(); d != "" {
+		return []string{d}
+	}
+	return nil
+}
+
+// protoEnumDefault returns a protoOption annotating the YANG name of the
+// default value of the enumeration or identityref leaf field, or nil if the
+// leaf has no default. Since the identity that is the default of an
+// identityref leaf may be qualified by a module prefix, the prefix is removed,
+// such that defaults of both kinds are annotated in the same form as the YANG
+// names of the values of the generated enums. An error is returned if the
+// default is not a value of the leaf's type.
+func protoEnumDefault(field *yang.Entry) (*protoOption, error) {
+	d := field.DefaultValue()
+	if d == "" {
+		return nil, nil
+	}
+
+	switch field.Type.Kind {
+	case yang.Yenum:
+		if _, ok := field.Type.Enum.NameMap()[d]; !ok {
+			return nil, fmt.Errorf("enumeration %s specified a default - %s - that was not a valid value", field.Path(), d)
+		}
+	case yang.Yidentityref:
+		d = util.StripModulePrefix(d)
+		var found bool
+		for _, v := range field.Type.IdentityBase.Values {
+			if v.Name == d {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("identityref %s specified a default - %s - that is not derived from base %s", field.Path(), d, field.Type.IdentityBase.Name)
+		}
+	}
+	return protoEnumDefaultAnnotation(d), nil
+}
+
 // toProtoEnumValue takes an input enum definition - with a protobuf and YANG label, and returns
 // a protoEnumValue. The YANGLabel is only stored if annotateEnumValues is set.
 func toProtoEnumValue(protoName, yangName string, annotateEnumValues bool) protoEnumValue {
@@ -1073,6 +2413,57 @@ func toProtoEnumValue(protoName, yangName string, annotateEnumValues bool) proto
 	return ev
 }
 
+// wrapCommentLines splits the string s into lines, such that it can be output
+// as a comment. Each line of s is output as at least one line, with lines that
+// are longer than width wrapped at word boundaries. Leading and trailing
+// whitespace, and blank lines, are removed. Words that are longer than width
+// are not split.
+func wrapCommentLines(s string, width int) []string {
+	var lines []string
+	for _, l := range strings.Split(s, "\n") {
+		words := strings.Fields(l)
+		if len(words) == 0 {
+			continue
+		}
+		cur := words[0]
+		for _, w := range words[1:] {
+			if len(cur)+1+len(w) > width {
+				lines = append(lines, cur)
+				cur = w
+				continue
+			}
+			cur = fmt.Sprintf("%s %s", cur, w)
+		}
+		lines = append(lines, cur)
+	}
+	return lines
+}
+
+// isProtoIdentifier returns true if name is a valid protobuf identifier, which
+// consists of a letter followed by letters, digits, or underscores.
+func isProtoIdentifier(name string) bool {
+	for i, c := range name {
+		switch {
+		case c >= 'A' && c <= 'Z', c >= 'a' && c <= 'z':
+		case i > 0 && (c >= '0' && c <= '9' || c == '_'):
+		default:
+			return false
+		}
+	}
+	return name != ""
+}
+
+// isProtoNameSuffix returns true if suffix can be used to disambiguate the names
+// of protobuf identifiers, as described by makeNameUniqueWithSuffix, such that the
+// names that are generated are valid protobuf identifiers. The suffix may contain
+// at most one "%d" verb, and no other formatting directives.
+func isProtoNameSuffix(suffix string) bool {
+	if strings.Count(suffix, "%") != strings.Count(suffix, "%d") || strings.Count(suffix, "%d") > 1 {
+		return false
+	}
+	return isProtoIdentifier("a" + strings.Replace(suffix, "%d", "1", 1))
+}
+
 // safeProtoIdentifierName takes an input string which represents the name of a YANG schema
 // element and sanitises for use as a protobuf field name.
 func safeProtoIdentifierName(name string) string {
@@ -1084,17 +2475,86 @@ func safeProtoIdentifierName(name string) string {
 	//	ident = letter { letter | decimalDigit | "_" }
 	//
 	// Therefore we need to ensure that the "-", and "." characters that are allowed
-	// in the YANG are replaced.
+	// in the YANG are replaced. The names of YANG enum values are not restricted to
+	// being identifiers, and commonly include "/" (e.g., 10/100), hence this is
+	// also replaced.
 	replacer := strings.NewReplacer(
 		".", "_",
 		"-", "_",
+		"/", "_",
 	)
 	return replacer.Replace(name)
 }
 
-// protoTagForEntry returns a protobuf tag value for the entry e.
-func protoTagForEntry(e *yang.Entry) (uint32, error) {
-	return fieldTag(e.Path())
+// safeProtoFieldName takes an input string which represents the name of a YANG schema
+// element and returns the name of the protobuf field that represents it, sanitised
+// using safeProtoIdentifierName. If the escapeReservedWords field of cfg is set, and
+// the sanitised name is a protobuf keyword, an underscore is appended to it. The
+// returned name is not guaranteed to be unique within the message.
+func safeProtoFieldName(name string, cfg *protoMsgConfig) string {
+	n := safeProtoIdentifierName(name)
+	if cfg.escapeReservedWords && protoReservedWords[n] {
+		n = fmt.Sprintf("%s_", n)
+	}
+	return n
+}
+
+// upperSnakeCase takes an input string which represents the name of a YANG
+// enumerated value or identity and converts it to UPPER_SNAKE_CASE, as is
+// recommended by the protobuf style guide for enum values. Word boundaries
+// are determined by the "-", "." and "_" characters, by a lower case letter
+// being followed by an upper case letter (e.g., adminUp becomes ADMIN_UP), and
+// by the last upper case letter of an acronym that is followed by a lower case
+// letter (e.g., HTTPServer becomes HTTP_SERVER).
+func upperSnakeCase(name string) string {
+	rs := []rune(safeProtoIdentifierName(name))
+	var b bytes.Buffer
+	for i, r := range rs {
+		if i != 0 && unicode.IsUpper(r) {
+			prev := rs[i-1]
+			nextLower := i+1 < len(rs) && unicode.IsLower(rs[i+1])
+			if unicode.IsLower(prev) || (nextLower && (unicode.IsUpper(prev) || unicode.IsDigit(prev))) {
+				b.WriteRune('_')
+			}
+		}
+		b.WriteRune(unicode.ToUpper(r))
+	}
+	return b.String()
+}
+
+// protoTagForEntry returns a protobuf tag value for the entry e. If a tag is
+// explicitly specified for e using the field-number extension it is
+// returned, and the bool return value is set to true. Otherwise, the tag is
+// calculated from the path of e.
+func protoTagForEntry(e *yang.Entry) (uint32, bool, error) {
+	if t, ok, err := explicitFieldTag(e); err != nil || ok {
+		return t, ok, err
+	}
+	t, err := fieldTag(e.Path())
+	return t, false, err
+}
+
+// explicitFieldTag returns the protobuf tag that is explicitly specified for
+// the entry e using an extension named field-number (e.g.,
+// occodegenext:field-number 7;), and a bool indicating whether such an extension
+// was found. An error is returned if the specified tag is not a valid protobuf
+// field number, or is within the range 19,000-19,999 reserved by protobuf.
+// Unlike calculated tags, explicit tags may be within the range 1-1,000.
+func explicitFieldTag(e *yang.Entry) (uint32, bool, error) {
+	for _, s := range e.Exts {
+		if p := strings.Split(s.Keyword, ":"); len(p) < 2 || p[1] != protoFieldTagExtension || !s.HasArgument {
+			continue
+		}
+		v, err := strconv.ParseUint(strings.Trim(strings.TrimSpace(s.Argument), `"`), 10, 32)
+		if err != nil {
+			return 0, false, fmt.Errorf("invalid field tag %s specified for %s: %v", s.Argument, e.Path(), err)
+		}
+		if t := uint32(v); t == 0 || t > protoMaxFieldTag || (t >= 19000 && t <= 19999) {
+			return 0, false, fmt.Errorf("field tag %d specified for %s is not a valid protobuf field number", v, e.Path())
+		}
+		return uint32(v), true, nil
+	}
+	return 0, false, nil
 }
 
 // fieldTag takes an input string and calculates a FNV hash for the value. If the
@@ -1106,13 +2566,35 @@ func fieldTag(s string) (uint32, error) {
 		return 0, fmt.Errorf("could not write field path to hash: %v", err)
 	}
 
-	v := h.Sum32() & 0x1fffffff // 2^29-1
-	if (v >= 19000 && v <= 19999) || (v >= 1 && v <= 1000) {
+	v := h.Sum32() & protoMaxFieldTag
+	if !validFieldTag(v) {
 		return fieldTag(fmt.Sprintf("%s_", s))
 	}
 	return v, nil
 }
 
+// validFieldTag returns true if the tag t can be used for a generated field. The
+// tags 1-1,000 are not used such that they are available for fields that are
+// manually added to messages, and 19,000-19,999 are reserved by protobuf.
+func validFieldTag(t uint32) bool {
+	return t > 1000 && t <= protoMaxFieldTag && (t < 19000 || t > 19999)
+}
+
+// uniqueFieldTag returns the tag t if it has not already been used within the
+// message whose tags are recorded in definedTags. If it has, the tags following
+// t are probed until one that is both unused and valid is found, and returned.
+// The returned tag is added to definedTags.
+func uniqueFieldTag(t uint32, definedTags map[uint32]bool) uint32 {
+	for definedTags[t] || !validFieldTag(t) {
+		t++
+		if t > protoMaxFieldTag {
+			t = 1
+		}
+	}
+	definedTags[t] = true
+	return t
+}
+
 // genListKeyProto generates a protoMsg that describes the proto3 message that represents
 // the key of a list for YANG lists. It takes a yangDirectory pointer to the list being
 // described, the name of the list, the package name that the list is within, and the
@@ -1123,9 +2605,14 @@ func genListKeyProto(listPackage string, listName string, args *protoDefinitionA
 		Name:     n,
 		YANGPath: args.field.Path(),
 		Enums:    map[string]*protoMsgEnum{},
+		Module:   dataTreeModuleName(args.field),
+		ListKey:  true,
+	}
+	if m := augmentingModuleName(args.field); m != km.Module {
+		km.AugmentedBy = m
 	}
 
-	if listPackage != "" {
+	if listPackage != "" && listPackage != args.parentPkg {
 		km.Imports = []string{importPath(args.cfg.baseImportPath, args.cfg.basePackageName, listPackage)}
 	}
 
@@ -1137,39 +2624,40 @@ func genListKeyProto(listPackage string, listName string, args *protoDefinitionA
 			return nil, fmt.Errorf("list %s included a key %s did that did not exist", args.field.Path(), k)
 		}
 
+		pargs := args.cfg.resolveProtoTypeArgs()
+		// When there is a union within a list key that has a single type within it
+		// e.g.,:
+		// list foo {
+		//   key "bar";
+		//   leaf bar {
+		//     type union {
+		//       type string { pattern "a.*" }
+		//			 type string { pattern "b.*" }
+		//     }
+		//   }
+		// }
+		// Then we want to use the scalar type rather than the wrapper type in
+		// this message since all keys must be set. We therefore signal this in
+		// the call to the type resolution.
+		pargs.scalarTypeInSingleTypeUnion = true
 		scalarType, err := args.state.yangTypeToProtoScalarType(resolveTypeArgs{
 			yangType:     kf.Type,
 			contextEntry: kf,
-		}, resolveProtoTypeArgs{
-			basePackageName: args.cfg.basePackageName,
-			enumPackageName: args.cfg.enumPackageName,
-			// When there is a union within a list key that has a single type within it
-			// e.g.,:
-			// list foo {
-			//   key "bar";
-			//   leaf bar {
-			//     type union {
-			//       type string { pattern "a.*" }
-			//			 type string { pattern "b.*" }
-			//     }
-			//   }
-			// }
-			// Then we want to use the scalar type rather than the wrapper type in
-			// this message since all keys must be set. We therefore signal this in
-			// the call to the type resolution.
-			scalarTypeInSingleTypeUnion: true,
-		})
+		}, pargs)
 		if err != nil {
-			return nil, fmt.Errorf("list %s included a key %s that did not have a valid proto type: %v", args.field.Path(), k, kf.Type)
+			return nil, fmt.Errorf("list %s included a key %s that did not have a valid proto type: %v: %v", args.field.Path(), k, kf.Type, err)
 		}
 
 		var enumEntry *yang.Entry
 		var unionEntry *yang.Entry
 		switch {
 		case kf.Type.Kind == yang.Yleafref:
-			target, err := args.state.resolveLeafrefTarget(kf.Type.Path, kf)
+			// The key may reference a leaf that is itself a leafref, hence
+			// the leaf that is ultimately referenced determines whether the
+			// key is an enumeration or union.
+			target, err := args.state.resolveLeafrefChain(kf)
 			if err != nil {
-				return nil, fmt.Errorf("error generating type for list %s key %s: type %v", args.field.Path(), k, kf.Type)
+				return nil, fmt.Errorf("error generating type for list %s key %s: %v", args.field.Path(), k, err)
 			}
 
 			if isSimpleEnumerationType(target.Type) {
@@ -1180,8 +2668,8 @@ func genListKeyProto(listPackage string, listName string, args *protoDefinitionA
 				unionEntry = target
 			}
 
-			if isIdentityrefLeaf(target) {
-				km.Imports = append(km.Imports, importPath(args.cfg.baseImportPath, args.cfg.basePackageName, args.cfg.enumPackageName))
+			if isIdentityrefLeaf(target) && !args.cfg.skipIdentityEnum(target.Type.IdentityBase) {
+				km.Imports = append(km.Imports, importPath(args.cfg.baseImportPath, args.cfg.basePackageName, args.cfg.identityPackageName()))
 			}
 		case isSimpleEnumerationType(kf.Type):
 			enumEntry = kf
@@ -1192,27 +2680,28 @@ func genListKeyProto(listPackage string, listName string, args *protoDefinitionA
 		// Make the name of the key unique. We handle the case that the list name
 		// matches the key field name by appending the protoMatchingListNameKeySuffix
 		// to the field name, as described in the definition of protoMatchingListNameKeySuffix.
-		fName := makeNameUnique(safeProtoIdentifierName(k), definedFieldNames)
+		fName := args.cfg.makeNameUnique(safeProtoFieldName(k, args.cfg), definedFieldNames)
 		if args.field.Name == k {
 			fName = fmt.Sprintf("%s_%s", fName, protoMatchingListNameKeySuffix)
 		}
 
 		fd := &protoMsgField{
-			Name: fName,
-			Tag:  ctag,
+			Name:       fName,
+			Tag:        ctag,
+			IsRequired: args.cfg.proto2,
 		}
 		switch {
 		case enumEntry != nil:
-			enum, err := genProtoEnum(enumEntry, args.cfg.annotateEnumNames)
+			enum, err := genProtoEnum(enumEntry, args.cfg)
 			if err != nil {
 				return nil, fmt.Errorf("error generating type for list %s key %s, type %v", args.field.Path(), k, enumEntry.Type)
 			}
-			tn := makeNameUnique(scalarType.nativeType, definedFieldNames)
+			tn := args.cfg.makeNameUnique(scalarType.nativeType, definedFieldNames)
 			fd.Type = tn
 			km.Enums[tn] = enum
 		case unionEntry != nil:
 			fd.IsOneOf = true
-			u, err := unionFieldToOneOf(fd.Name, unionEntry, scalarType, args.cfg.annotateEnumNames)
+			u, err := unionFieldToOneOf(fd.Name, unionEntry, scalarType, args.cfg)
 			if err != nil {
 				return nil, fmt.Errorf("error generating type for union list key %s in list %s", k, args.field.Path())
 			}
@@ -1220,9 +2709,7 @@ func genListKeyProto(listPackage string, listName string, args *protoDefinitionA
 			for n, e := range u.enums {
 				km.Enums[n] = e
 			}
-			if u.hadGlobalEnums {
-				km.Imports = append(km.Imports, importPath(args.cfg.baseImportPath, args.cfg.basePackageName, args.cfg.enumPackageName))
-			}
+			km.Imports = append(km.Imports, u.enumImports...)
 		default:
 			fd.Type = scalarType.nativeType
 		}
@@ -1235,6 +2722,14 @@ func genListKeyProto(listPackage string, listName string, args *protoDefinitionA
 			fd.Options = append(fd.Options, o)
 		}
 
+		if args.cfg.manifest {
+			p, err := protoSchemaPaths(args.directory, kf, args.cfg.compressPaths)
+			if err != nil {
+				return nil, err
+			}
+			fd.SchemaPaths = p
+		}
+
 		km.Fields = append(km.Fields, fd)
 		ctag++
 	}
@@ -1246,14 +2741,15 @@ func genListKeyProto(listPackage string, listName string, args *protoDefinitionA
 	if !args.cfg.nestedMessages {
 		p, _ := stripPackagePrefix(args.parentPkg, listPackage)
 		ltype = fmt.Sprintf("%s.%s", p, listName)
-		if listPackage == "" {
-			// Handle the case that the context of the list is already the base package.
+		if listPackage == "" || listPackage == args.parentPkg {
+			// Handle the case that the context of the list is already the base
+			// package, or the package of the list's parent.
 			ltype = listName
 		}
 	}
 
 	km.Fields = append(km.Fields, &protoMsgField{
-		Name: safeProtoIdentifierName(args.field.Name),
+		Name: safeProtoFieldName(args.field.Name, args.cfg),
 		Type: ltype,
 		Tag:  ctag,
 	})
@@ -1262,9 +2758,9 @@ func genListKeyProto(listPackage string, listName string, args *protoDefinitionA
 }
 
 // enumInProtoUnionField parses an enum that is within a union and returns the generated
-// enumeration that should be included within a protobuf message for it. If annotateEnumNames
-// is set to true, the enumerated value's original names are stored.
-func enumInProtoUnionField(name string, etype *yang.YangType, annotateEnumNames bool) (map[string]*protoMsgEnum, error) {
+// enumeration that should be included within a protobuf message for it. The cfg supplied
+// determines how the values of the enumeration are labelled.
+func enumInProtoUnionField(name string, etype *yang.YangType, cfg *protoMsgConfig) (map[string]*protoMsgEnum, error) {
 	enums := map[string]*protoMsgEnum{}
 	for _, t := range etype.Type {
 		if isSimpleEnumerationType(t) {
@@ -1272,7 +2768,7 @@ func enumInProtoUnionField(name string, etype *yang.YangType, annotateEnumNames
 			enum, err := genProtoEnum(&yang.Entry{
 				Name: n,
 				Type: t,
-			}, annotateEnumNames)
+			}, cfg)
 			if err != nil {
 				return nil, err
 			}
@@ -1280,7 +2776,7 @@ func enumInProtoUnionField(name string, etype *yang.YangType, annotateEnumNames
 		}
 
 		if isUnionType(t) {
-			es, err := enumInProtoUnionField(name, t, annotateEnumNames)
+			es, err := enumInProtoUnionField(name, t, cfg)
 			if err != nil {
 				return nil, err
 			}
@@ -1296,18 +2792,24 @@ func enumInProtoUnionField(name string, etype *yang.YangType, annotateEnumNames
 // protoUnionField stores information relating to a oneof field within a protobuf
 // message.
 type protoUnionField struct {
-	oneOfFields    []*protoMsgField         // oneOfFields contains a set of fields that are within a oneof.
-	enums          map[string]*protoMsgEnum // enums stores a definition of any simple enumeration types within the YANG union.
-	repeatedMsg    *protoMsg                // repeatedMsg stores a message that contains fields that should be repeated, and is used to store a YANG leaf-list of union leaves.
-	hadGlobalEnums bool                     // hadGlobalEnums determines whether there was a global scope enum (typedef, identityref) in the message.
+	oneOfFields []*protoMsgField         // oneOfFields contains a set of fields that are within a oneof.
+	enums       map[string]*protoMsgEnum // enums stores a definition of any simple enumeration types within the YANG union.
+	repeatedMsg *protoMsg                // repeatedMsg stores a message that contains fields that should be repeated, and is used to store a YANG leaf-list of union leaves.
+	enumImports []string                 // enumImports specifies the imports required for the global scope enums (typedef, identityref) in the message.
 }
 
 // unionFieldToOneOf takes an input name, a yang.Entry containing a field definition and a mappedType
 // containing the proto type that the entry has been mapped to, and returns a definition of a union
-// field within the protobuf message. If the annotateEnumNames boolean is set, then any enumerated types
-// within the union have their original names within the YANG schema appended.
-func unionFieldToOneOf(fieldName string, e *yang.Entry, mtype *mappedType, annotateEnumNames bool) (*protoUnionField, error) {
-	enums, err := enumInProtoUnionField(fieldName, e.Type, annotateEnumNames)
+// field within the protobuf message. If the annotateEnumNames field of the supplied cfg is set, then
+// any enumerated types within the union have their original names within the YANG schema appended.
+//
+// The members of the oneof are ordered by the name of their protobuf type, rather than the order
+// in which the member types are specified in the YANG union, since nested unions are flattened and
+// duplicate member types removed. The tag of each member is derived from the path of the field and
+// the name of the member's type, such that both the order and tags of the members are stable across
+// generation runs.
+func unionFieldToOneOf(fieldName string, e *yang.Entry, mtype *mappedType, cfg *protoMsgConfig) (*protoUnionField, error) {
+	enums, err := enumInProtoUnionField(fieldName, e.Type, cfg)
 	if err != nil {
 		return nil, err
 	}
@@ -1318,7 +2820,7 @@ func unionFieldToOneOf(fieldName string, e *yang.Entry, mtype *mappedType, annot
 	}
 	sort.Strings(typeNames)
 
-	var importGlobalEnums bool
+	importGlobalEnums := map[string]interface{}{}
 	var oofs []*protoMsgField
 	for _, t := range typeNames {
 		// Split the type name on "." to ensure that we don't have oneof options
@@ -1327,7 +2829,7 @@ func unionFieldToOneOf(fieldName string, e *yang.Entry, mtype *mappedType, annot
 		// present and hence should import this path.
 		tp := strings.Split(t, ".")
 		if len(tp) > 1 {
-			importGlobalEnums = true
+			importGlobalEnums[globalEnumImportPath(cfg.baseImportPath, t)] = true
 		}
 		tn := tp[len(tp)-1]
 		// Calculate the tag by having the path, with the type name appended to it
@@ -1346,6 +2848,9 @@ func unionFieldToOneOf(fieldName string, e *yang.Entry, mtype *mappedType, annot
 		oofs = append(oofs, st)
 	}
 
+	enumImports := stringKeys(importGlobalEnums)
+	sort.Strings(enumImports)
+
 	if e.IsLeafList() {
 		// In this case, we cannot return a oneof, since it is not possible to have a repeated
 		// oneof, therefore we return a message that contains the protoMsgFields that are defined
@@ -1357,19 +2862,79 @@ func unionFieldToOneOf(fieldName string, e *yang.Entry, mtype *mappedType, annot
 		}
 
 		return &protoUnionField{
-			enums:          enums,
-			repeatedMsg:    p,
-			hadGlobalEnums: importGlobalEnums,
+			enums:       enums,
+			repeatedMsg: p,
+			enumImports: enumImports,
 		}, nil
 	}
 
 	return &protoUnionField{
-		oneOfFields:    oofs,
-		enums:          enums,
-		hadGlobalEnums: importGlobalEnums,
+		oneOfFields: oofs,
+		enums:       enums,
+		enumImports: enumImports,
 	}, nil
 }
 
+// typedefUnionType returns the fully qualified name of the message, within the
+// unions package, that represents the YANG union typedef that is the type of
+// the entry e. The message is generated the first time that the typedef is
+// encountered, and stored in the protoTypedefUnions map of the supplied state,
+// such that a single definition is shared by all fields of the type. Since a
+// typedef does not have a path, it is identified by its name and the name of
+// the module in which it is used, in the same manner as enumerated typedefs.
+// The message contains a single oneof, whose members are determined as per
+// unionFieldToOneOf, using the typedef rather than the entry as the context,
+// such that the tags of the members do not depend on where it is used.
+func typedefUnionType(e *yang.Entry, state *genState, cfg *protoMsgConfig) (string, error) {
+	modName := parentModuleName(e.Node)
+	key := fmt.Sprintf("%s/%s", modName, e.Type.Name)
+	if m, ok := state.protoTypedefUnions[key]; ok {
+		return fmt.Sprintf("%s.%s.%s", cfg.basePackageName, protoUnionsPackageName, m.Name), nil
+	}
+
+	// Enumerations within the union are named according to the name of the
+	// context entry, and hence the entry is renamed to the name of the oneof,
+	// such that the name of the enum is the same for all uses of the typedef.
+	ctx := *e
+	ctx.Name = protoTypedefUnionFieldName
+	mtype, err := state.yangTypeToProtoScalarType(resolveTypeArgs{
+		yangType:     e.Type,
+		contextEntry: &ctx,
+	}, cfg.resolveProtoTypeArgs())
+	if err != nil {
+		return "", err
+	}
+
+	u, err := unionFieldToOneOf(protoTypedefUnionFieldName, &yang.Entry{
+		Name:   e.Type.Name,
+		Type:   e.Type,
+		Parent: &yang.Entry{Name: modName},
+	}, mtype, cfg)
+	if err != nil {
+		return "", err
+	}
+	if _, ok := state.uniqueProtoMsgNames[protoUnionsPackageName]; !ok {
+		state.uniqueProtoMsgNames[protoUnionsPackageName] = map[string]bool{}
+	}
+	m := &protoMsg{
+		Name:     state.makeNameUnique(yang.CamelCase(e.Type.Name), state.uniqueProtoMsgNames[protoUnionsPackageName]),
+		YANGPath: fmt.Sprintf("%s typedef %s", modName, e.Type.Name),
+		Fields: []*protoMsgField{{
+			Name:        protoTypedefUnionFieldName,
+			IsOneOf:     true,
+			OneOfFields: u.oneOfFields,
+		}},
+		Enums:   u.enums,
+		Imports: u.enumImports,
+		Proto2:  cfg.proto2,
+	}
+	if cfg.orderOneofsByTag {
+		sortOneofFieldsByTag(m.Fields)
+	}
+	state.protoTypedefUnions[key] = m
+	return fmt.Sprintf("%s.%s.%s", cfg.basePackageName, protoUnionsPackageName, m.Name), nil
+}
+
 // protoPackageToFilePath takes an input string containing a period separated protobuf package
 // name in the form parent.child and returns a path to the file that it should be written to
 // assuming a hierarchical directory structure is used. If the package supplied is
@@ -1401,6 +2966,187 @@ func protoSchemaPathAnnotation(msg *yangDirectory, field *yang.Entry, compressPa
 	return &protoOption{Name: protoSchemaAnnotationOption, Value: b.String()}, nil
 }
 
+// protoSchemaPaths returns the absolute schema paths of the field within the
+// message msg.
+func protoSchemaPaths(msg *yangDirectory, field *yang.Entry, compressPaths bool) ([]string, error) {
+	smapp, err := findMapPaths(msg, field, compressPaths, true)
+	if err != nil {
+		return nil, err
+	}
+	var paths []string
+	for _, p := range smapp {
+		paths = append(paths, slicePathToString(p))
+	}
+	return paths, nil
+}
+
+// protoIdentityBaseAnnotation returns a protoOption annotating the base identity
+// i of an identityref field. The base is qualified by the name of the module in
+// which it is defined, i.e., it is of the form module-name:identity-name.
+func protoIdentityBaseAnnotation(i *yang.Identity) *protoOption {
+	return &protoOption{
+		Name:  protoIdentityBaseAnnotationOption,
+		Value: fmt.Sprintf("%q", fmt.Sprintf("%s:%s", parentModuleName(i), i.Name)),
+	}
+}
+
+// protoWhenAnnotation returns a protoOption annotating the XPath expression of
+// the YANG when statement of a field.
+func protoWhenAnnotation(xpath string) *protoOption {
+	return &protoOption{
+		Name:  protoWhenAnnotationOption,
+		Value: fmt.Sprintf("%q", xpath),
+	}
+}
+
+// protoEnumDefaultAnnotation returns a protoOption annotating the YANG name of
+// the default value of an enumerated field.
+func protoEnumDefaultAnnotation(name string) *protoOption {
+	return &protoOption{
+		Name:  protoEnumDefaultAnnotationOption,
+		Value: fmt.Sprintf("%q", name),
+	}
+}
+
+// protoDefaultValueAnnotations returns the protoOptions annotating the
+// defaults of a field whose type is not enumerated. Since a leaf-list may have
+// more than one default, and the option is repeated, an option is returned for
+// each default, in the order that they were specified.
+func protoDefaultValueAnnotations(defaults []string) []*protoOption {
+	var opts []*protoOption
+	for _, d := range defaults {
+		opts = append(opts, &protoOption{
+			Name:  protoDefaultValueAnnotationOption,
+			Value: fmt.Sprintf("%q", d),
+		})
+	}
+	return opts
+}
+
+// protoFractionDigitsAnnotation returns a protoOption annotating the
+// fraction-digits of a decimal64 field.
+func protoFractionDigitsAnnotation(fd int) *protoOption {
+	return &protoOption{
+		Name:  protoFractionDigitsAnnotationOption,
+		Value: fmt.Sprintf("%d", fd),
+	}
+}
+
+// protoLeafListSemanticsAnnotation returns a protoOption annotating whether
+// the leaf-list e has set semantics, such that its values are unique and their
+// order is not significant, or list semantics. Leaf-lists that are ordered-by
+// user are lists, whereas those that are ordered-by system - which is the
+// default - are sets.
+func protoLeafListSemanticsAnnotation(e *yang.Entry) *protoOption {
+	semantics := "set"
+	if e.ListAttr != nil && e.ListAttr.OrderedBy != nil && e.ListAttr.OrderedBy.Name == "user" {
+		semantics = "list"
+	}
+	return &protoOption{
+		Name:  protoLeafListSemanticsAnnotationOption,
+		Value: fmt.Sprintf("%q", semantics),
+	}
+}
+
+// yangCardinality describes the number of elements that a YANG list or
+// leaf-list is permitted to contain.
+type yangCardinality struct {
+	min, max       uint64
+	hasMin, hasMax bool
+}
+
+// listCardinality returns the cardinality specified by the min-elements and
+// max-elements statements of the list or leaf-list e. A max-elements value of
+// unbounded is treated as though it were not specified. If e is not a list or
+// leaf-list, or neither statement is specified, nil is returned.
+func listCardinality(e *yang.Entry) (*yangCardinality, error) {
+	if e.ListAttr == nil {
+		return nil, nil
+	}
+
+	c := &yangCardinality{}
+	if v := e.ListAttr.MinElements; v != nil {
+		n, err := strconv.ParseUint(v.Name, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("proto: invalid min-elements %s for %s: %v", v.Name, e.Path(), err)
+		}
+		c.min, c.hasMin = n, true
+	}
+	if v := e.ListAttr.MaxElements; v != nil && v.Name != "unbounded" {
+		n, err := strconv.ParseUint(v.Name, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("proto: invalid max-elements %s for %s: %v", v.Name, e.Path(), err)
+		}
+		c.max, c.hasMax = n, true
+	}
+
+	if !c.hasMin && !c.hasMax {
+		return nil, nil
+	}
+	return c, nil
+}
+
+// addProtoCardinality outputs the cardinality c of the list or leaf-list that
+// fieldDef represents according to the policy supplied. When the cardinality is
+// output as a comment, it is appended to any existing comment for the field.
+// Options are not added to a field that is output as a oneof, since field
+// options cannot be specified for a oneof.
+func addProtoCardinality(fieldDef *protoMsgField, c *yangCardinality, policy ProtoCardinalityPolicy) {
+	if c == nil {
+		return
+	}
+
+	if policy == CommentCardinality || policy == CommentAndAnnotateCardinality {
+		var cmt string
+		switch {
+		case c.hasMin && c.hasMax:
+			cmt = fmt.Sprintf("%s must contain between %d and %d elements.", fieldDef.Name, c.min, c.max)
+		case c.hasMin:
+			cmt = fmt.Sprintf("%s must contain at least %d elements.", fieldDef.Name, c.min)
+		default:
+			cmt = fmt.Sprintf("%s must contain at most %d elements.", fieldDef.Name, c.max)
+		}
+		if fieldDef.Comment != "" {
+			cmt = fmt.Sprintf("%s %s", fieldDef.Comment, cmt)
+		}
+		fieldDef.Comment = cmt
+	}
+
+	if (policy == AnnotateCardinality || policy == CommentAndAnnotateCardinality) && !fieldDef.IsOneOf {
+		if c.hasMin {
+			fieldDef.Options = append(fieldDef.Options, &protoOption{
+				Name:  protoMinElementsAnnotationOption,
+				Value: fmt.Sprintf("%d", c.min),
+			})
+		}
+		if c.hasMax {
+			fieldDef.Options = append(fieldDef.Options, &protoOption{
+				Name:  protoMaxElementsAnnotationOption,
+				Value: fmt.Sprintf("%d", c.max),
+			})
+		}
+	}
+}
+
+// decimal64FractionDigits returns the fraction-digits of the decimal64 type of
+// the leaf or leaf-list e. If e is a leafref, the fraction-digits of the leaf
+// that it references are returned. Zero is returned if e is not of decimal64
+// type.
+func decimal64FractionDigits(e *yang.Entry, s *genState) (int, error) {
+	if e.Type == nil {
+		return 0, nil
+	}
+	target, err := s.resolveLeafrefChain(e)
+	if err != nil {
+		return 0, err
+	}
+	t := target.Type
+	if t == nil || t.Kind != yang.Ydecimal64 {
+		return 0, nil
+	}
+	return t.FractionDigits, nil
+}
+
 // stripPackagePrefix removes the prefix of pfx from the path supplied. If pfx
 // is not a prefix of path the entire path is returned. If the prefix was
 // stripped, the returned bool is set.
@@ -1424,3 +3170,79 @@ func stripPackagePrefix(pfx, path string) (string, bool) {
 func importPath(baseImportPath, basePkgName, childPkg string) string {
 	return filepath.Join(append([]string{baseImportPath}, protoPackageToFilePath(fmt.Sprintf("%s.%s", basePkgName, childPkg))...)...)
 }
+
+// protoImportCycle determines whether the imports between the generated
+// protobuf packages form a cycle, which cannot be compiled by protoc. The
+// imports required by each package are supplied in pkgImports, keyed by the
+// fully qualified name of the package. Imports are resolved to the generated
+// package that they refer to using the base import path supplied, and imports
+// of files that are not generated are ignored. If a cycle is found, the names
+// of the packages within it are returned in import order, with the first
+// package repeated at the end of the slice. If there is no cycle, nil is
+// returned. Packages are visited in sorted order, such that the cycle that is
+// reported is deterministic.
+func protoImportCycle(pkgImports map[string]map[string]interface{}, baseImportPath string) []string {
+	pkgByPath := map[string]string{}
+	var pkgs []string
+	for pkg := range pkgImports {
+		pkgByPath[filepath.Join(append([]string{baseImportPath}, protoPackageToFilePath(pkg)...)...)] = pkg
+		pkgs = append(pkgs, pkg)
+	}
+	sort.Strings(pkgs)
+
+	deps := map[string][]string{}
+	for _, pkg := range pkgs {
+		imports := stringKeys(pkgImports[pkg])
+		sort.Strings(imports)
+		for _, i := range imports {
+			if d, ok := pkgByPath[i]; ok {
+				deps[pkg] = append(deps[pkg], d)
+			}
+		}
+	}
+
+	// inPath records whether each package is on the current traversal path,
+	// and is false for packages whose imports have been fully explored.
+	inPath := map[string]bool{}
+	var path []string
+	var visit func(string) []string
+	visit = func(pkg string) []string {
+		inPath[pkg] = true
+		path = append(path, pkg)
+		for _, d := range deps[pkg] {
+			onPath, seen := inPath[d]
+			switch {
+			case onPath:
+				for i, p := range path {
+					if p == d {
+						return append(append([]string{}, path[i:]...), d)
+					}
+				}
+			case !seen:
+				if c := visit(d); c != nil {
+					return c
+				}
+			}
+		}
+		path = path[:len(path)-1]
+		inPath[pkg] = false
+		return nil
+	}
+
+	for _, pkg := range pkgs {
+		if _, seen := inPath[pkg]; !seen {
+			if c := visit(pkg); c != nil {
+				return c
+			}
+		}
+	}
+	return nil
+}
+
+// globalEnumImportPath returns the path that should be imported to reference the
+// global enumerated type with the fully qualified name typeName, e.g.,
+// base.enums.TypeName, when the base import path is baseImportPath.
+func globalEnumImportPath(baseImportPath, typeName string) string {
+	tp := strings.Split(typeName, ".")
+	return filepath.Join(append([]string{baseImportPath}, protoPackageToFilePath(strings.Join(tp[:len(tp)-1], "."))...)...)
+}
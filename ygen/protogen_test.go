@@ -15,10 +15,15 @@
 package ygen
 
 import (
+	"fmt"
+	"math"
 	"reflect"
 	"sort"
+	"strings"
 	"testing"
 
+	"github.com/golang/protobuf/proto"
+	descpb "github.com/golang/protobuf/protoc-gen-go/descriptor"
 	"github.com/kylelemons/godebug/pretty"
 	"github.com/openconfig/goyang/pkg/yang"
 	"github.com/openconfig/ygot/testutil"
@@ -53,7 +58,96 @@ func protoMsgEq(a, b *protoMsg) bool {
 	return true
 }
 
+// HeadersEquivalent compares the proto3Header structs a and b, returning
+// true if they are equivalent. The Imports and SourceYANGIncludePaths fields
+// are compared as sets, such that the order in which they are specified is
+// ignored. If the headers are not equivalent, a description of the difference
+// between them is returned.
+func HeadersEquivalent(a, b proto3Header) (bool, string) {
+	asSet := func(ss []string) map[string]bool {
+		m := map[string]bool{}
+		for _, s := range ss {
+			m[s] = true
+		}
+		return m
+	}
+
+	var diffs []string
+	if diff := pretty.Compare(asSet(a.Imports), asSet(b.Imports)); diff != "" {
+		diffs = append(diffs, fmt.Sprintf("Imports differ, diff(-a,+b):\n%s", diff))
+	}
+	if diff := pretty.Compare(asSet(a.SourceYANGIncludePaths), asSet(b.SourceYANGIncludePaths)); diff != "" {
+		diffs = append(diffs, fmt.Sprintf("SourceYANGIncludePaths differ, diff(-a,+b):\n%s", diff))
+	}
+
+	// The remaining fields are compared directly, having removed those that
+	// have already been compared as sets.
+	a.Imports, b.Imports = nil, nil
+	a.SourceYANGIncludePaths, b.SourceYANGIncludePaths = nil, nil
+	if diff := pretty.Compare(a, b); diff != "" {
+		diffs = append(diffs, fmt.Sprintf("other fields differ, diff(-a,+b):\n%s", diff))
+	}
+
+	if diffs != nil {
+		return false, strings.Join(diffs, "\n")
+	}
+	return true, ""
+}
+
+// AssertEnumValues checks that the enum e has exactly the values specified in
+// want, which maps each value's number to its protobuf label. If it does not,
+// the test is failed with a description of each value that is missing from e,
+// is unexpected, or has a different label.
+func AssertEnumValues(t testing.TB, e protoEnum, want map[int64]string) {
+	t.Helper()
+
+	var nums []int64
+	for n := range e.Values {
+		nums = append(nums, n)
+	}
+	for n := range want {
+		if _, ok := e.Values[n]; !ok {
+			nums = append(nums, n)
+		}
+	}
+	sort.Slice(nums, func(i, j int) bool { return nums[i] < nums[j] })
+
+	var diffs []string
+	for _, n := range nums {
+		got, gotOK := e.Values[n]
+		w, wantOK := want[n]
+		switch {
+		case !gotOK:
+			diffs = append(diffs, fmt.Sprintf("missing value %d: %s", n, w))
+		case !wantOK:
+			diffs = append(diffs, fmt.Sprintf("unexpected value %d: %s", n, got.ProtoLabel))
+		case got.ProtoLabel != w:
+			diffs = append(diffs, fmt.Sprintf("value %d: got label %s, want: %s", n, got.ProtoLabel, w))
+		}
+	}
+	if diffs != nil {
+		t.Errorf("enum %s does not have the expected values:\n%s", e.Name, strings.Join(diffs, "\n"))
+	}
+}
+
+// recordingTB is a testing.TB that records the errors that are reported to it,
+// rather than failing the test.
+type recordingTB struct {
+	testing.TB
+	errs []string
+}
+
+func (r *recordingTB) Helper() {}
+
+func (r *recordingTB) Errorf(format string, args ...interface{}) {
+	r.errs = append(r.errs, fmt.Sprintf(format, args...))
+}
+
 func TestGenProto3Msg(t *testing.T) {
+	simpleEnum := yang.NewEnumType()
+	simpleEnum.Set("VALUE_ONE", 0)
+	simpleEnum.Set("VALUE_TWO", 1)
+
 	tests := []struct {
 		name                   string
 		inMsg                  *yangDirectory
@@ -66,6 +160,8 @@ func TestGenProto3Msg(t *testing.T) {
 		inAnnotateSchemaPaths  bool
 		inParentPackage        string
 		inChildMsgs            []*generatedProto3Message
+		inModulePackages       map[string]string
+		inPackagePolicy        ProtoPackagePolicy
 		wantMsgs               map[string]*protoMsg
 		wantErr                bool
 	}{{
@@ -106,6 +202,45 @@ func TestGenProto3Msg(t *testing.T) {
 				}},
 			},
 		},
+	}, {
+		name: "simple message with fields whose tags collide",
+		inMsg: &yangDirectory{
+			name: "MessageName",
+			entry: &yang.Entry{
+				Name: "message-name",
+				Dir:  map[string]*yang.Entry{},
+				Kind: yang.DirectoryEntry,
+			},
+			fields: map[string]*yang.Entry{
+				// The paths of these leaves have the same FNV hash.
+				"leaf-19784": {
+					Name: "leaf-19784",
+					Type: &yang.YangType{Kind: yang.Ystring},
+				},
+				"leaf-56420": {
+					Name: "leaf-56420",
+					Type: &yang.YangType{Kind: yang.Ystring},
+				},
+			},
+			path: []string{"", "root", "message-name"},
+		},
+		inBasePackage: "base",
+		inEnumPackage: "enums",
+		wantMsgs: map[string]*protoMsg{
+			"MessageName": {
+				Name:     "MessageName",
+				YANGPath: "/root/message-name",
+				Fields: []*protoMsgField{{
+					Tag:  82303082,
+					Name: "leaf_19784",
+					Type: "ywrapper.StringValue",
+				}, {
+					Tag:  82303083,
+					Name: "leaf_56420",
+					Type: "ywrapper.StringValue",
+				}},
+			},
+		},
 	}, {
 		name: "simple message with child messages, ensure no difference in logic",
 		inMsg: &yangDirectory{
@@ -229,6 +364,83 @@ func TestGenProto3Msg(t *testing.T) {
 				}},
 			},
 		},
+	}, {
+		name: "simple message with nested union leaf with duplicate and enumerated types",
+		inMsg: &yangDirectory{
+			name: "MessageName",
+			entry: &yang.Entry{
+				Name: "message-name",
+				Dir:  map[string]*yang.Entry{},
+				Kind: yang.DirectoryEntry,
+			},
+			fields: map[string]*yang.Entry{
+				"field-one": {
+					Name: "field-one",
+					Type: &yang.YangType{
+						Kind: yang.Yunion,
+						Type: []*yang.YangType{
+							{Kind: yang.Ystring},
+							{
+								Kind: yang.Yunion,
+								Type: []*yang.YangType{
+									{Kind: yang.Yuint32},
+									{Kind: yang.Ystring},
+								},
+							},
+							{
+								Name: "enumeration",
+								Kind: yang.Yenum,
+								Enum: simpleEnum,
+							},
+						},
+					},
+					Parent: &yang.Entry{Name: "message-name"},
+					Node: &yang.Leaf{
+						Name: "field-one",
+						Parent: &yang.Module{
+							Name: "base",
+						},
+					},
+				},
+			},
+			path: []string{"", "root", "message-name"},
+		},
+		inBasePackage: "base",
+		inEnumPackage: "enums",
+		wantMsgs: map[string]*protoMsg{
+			"MessageName": {
+				Name:     "MessageName",
+				YANGPath: "/root/message-name",
+				Enums: map[string]*protoMsgEnum{
+					"FieldOne": {
+						Values: map[int64]protoEnumValue{
+							0: {ProtoLabel: "UNSET"},
+							1: {ProtoLabel: "VALUE_ONE"},
+							2: {ProtoLabel: "VALUE_TWO"},
+						},
+					},
+				},
+				Fields: []*protoMsgField{{
+					Tag:     93773213,
+					Name:    "field_one",
+					Type:    "",
+					IsOneOf: true,
+					OneOfFields: []*protoMsgField{{
+						Tag:  183412712,
+						Name: "field_one_fieldone",
+						Type: "FieldOne",
+					}, {
+						Tag:  121134859,
+						Name: "field_one_string",
+						Type: "string",
+					}, {
+						Tag:  248414766,
+						Name: "field_one_uint64",
+						Type: "uint64",
+					}},
+				}},
+			},
+		},
 	}, {
 		name: "simple message with leaf-list and a message child, compression on",
 		inMsg: &yangDirectory{
@@ -293,7 +505,7 @@ func TestGenProto3Msg(t *testing.T) {
 			},
 		},
 	}, {
-		name: "simple message with leaf-list and a message child, compression off",
+		name: "unkeyed list in the same package as its parent, compression on",
 		inMsg: &yangDirectory{
 			name: "AMessage",
 			entry: &yang.Entry{
@@ -302,15 +514,11 @@ func TestGenProto3Msg(t *testing.T) {
 				Kind: yang.DirectoryEntry,
 			},
 			fields: map[string]*yang.Entry{
-				"leaf-list": {
-					Name:     "leaf-list",
-					Type:     &yang.YangType{Kind: yang.Ystring},
+				"list": {
+					Name:     "list",
+					Kind:     yang.DirectoryEntry,
 					ListAttr: &yang.ListAttr{},
-				},
-				"container-child": {
-					Name: "container-child",
-					Dir:  map[string]*yang.Entry{},
-					Kind: yang.DirectoryEntry,
+					Dir:      map[string]*yang.Entry{},
 					Parent: &yang.Entry{
 						Name: "a-message",
 						Parent: &yang.Entry{
@@ -322,10 +530,13 @@ func TestGenProto3Msg(t *testing.T) {
 			path: []string{"", "root", "a-message"},
 		},
 		inMsgs: map[string]*yangDirectory{
-			"/root/a-message/container-child": {
-				name: "ContainerChild",
+			"/root/a-message/list": {
+				name: "List",
 				entry: &yang.Entry{
-					Name: "container-child",
+					Name:     "list",
+					Kind:     yang.DirectoryEntry,
+					ListAttr: &yang.ListAttr{},
+					Dir:      map[string]*yang.Entry{},
 					Parent: &yang.Entry{
 						Name: "a-message",
 						Parent: &yang.Entry{
@@ -335,1635 +546,7413 @@ func TestGenProto3Msg(t *testing.T) {
 				},
 			},
 		},
-		inBasePackage: "base",
-		inEnumPackage: "enums",
+		inUniqueDirectoryNames: map[string]string{"/root/a-message/list": "List"},
+		inCompressPaths:        true,
+		inBasePackage:          "base",
+		inEnumPackage:          "enums",
 		wantMsgs: map[string]*protoMsg{
 			"AMessage": {
 				Name:     "AMessage",
 				YANGPath: "/root/a-message",
 				Fields: []*protoMsgField{{
-					Tag:        299656613,
-					Name:       "leaf_list",
-					Type:       "ywrapper.StringValue",
+					Tag:        287692141,
+					Name:       "list",
+					Type:       "List",
 					IsRepeated: true,
-				}, {
-					Tag:  17594927,
-					Name: "container_child",
-					Type: "root.a_message.ContainerChild",
 				}},
-				Imports: []string{"base/root/a_message/a_message.proto"},
 			},
 		},
 	}, {
-		name: "message with list",
+		name: "unkeyed list in a child package, compression on",
 		inMsg: &yangDirectory{
-			name: "AMessageWithAList",
+			name: "AMessage",
 			entry: &yang.Entry{
-				Name: "a-message-with-a-list",
+				Name: "a-message",
 				Dir:  map[string]*yang.Entry{},
 				Kind: yang.DirectoryEntry,
 			},
 			fields: map[string]*yang.Entry{
 				"list": {
-					Name: "list",
+					Name:     "list",
+					Kind:     yang.DirectoryEntry,
+					ListAttr: &yang.ListAttr{},
+					Dir:      map[string]*yang.Entry{},
 					Parent: &yang.Entry{
-						Name: "a-message-with-a-list",
-					},
-					Kind: yang.DirectoryEntry,
-					Dir: map[string]*yang.Entry{
-						"key": {
-							Name: "key",
-							Type: &yang.YangType{Kind: yang.Ystring},
+						Name: "surrounding-container",
+						Kind: yang.DirectoryEntry,
+						Parent: &yang.Entry{
+							Name: "a-message",
+							Kind: yang.DirectoryEntry,
+							Parent: &yang.Entry{
+								Name: "root",
+							},
 						},
 					},
-					Key:      "key",
-					ListAttr: &yang.ListAttr{},
 				},
 			},
-			path: []string{"", "a-message-with-a-list", "list"},
-		},
-		inBasePackage: "base",
-		inEnumPackage: "enums",
-		inUniqueDirectoryNames: map[string]string{
-			"/a-message-with-a-list/list": "List",
+			path: []string{"", "root", "a-message"},
 		},
 		inMsgs: map[string]*yangDirectory{
-			"/a-message-with-a-list/list": {
+			"/root/a-message/surrounding-container/list": {
 				name: "List",
 				entry: &yang.Entry{
-					Name: "list",
+					Name:     "list",
+					Kind:     yang.DirectoryEntry,
+					ListAttr: &yang.ListAttr{},
+					Dir:      map[string]*yang.Entry{},
 					Parent: &yang.Entry{
-						Name: "a-message-with-a-list",
-					},
-					Kind: yang.DirectoryEntry,
-					Dir: map[string]*yang.Entry{
-						"key": {
-							Name: "key",
-							Type: &yang.YangType{Kind: yang.Ystring},
+						Name: "surrounding-container",
+						Kind: yang.DirectoryEntry,
+						Parent: &yang.Entry{
+							Name: "a-message",
+							Kind: yang.DirectoryEntry,
+							Parent: &yang.Entry{
+								Name: "root",
+							},
 						},
 					},
-					Key:      "key",
-					ListAttr: &yang.ListAttr{},
-				},
-				fields: map[string]*yang.Entry{
-					"key": {
-						Name: "key",
-						Type: &yang.YangType{Kind: yang.Ystring},
-					},
 				},
 			},
 		},
+		inUniqueDirectoryNames: map[string]string{"/root/a-message/surrounding-container/list": "List"},
+		inCompressPaths:        true,
+		inBasePackage:          "base",
+		inEnumPackage:          "enums",
 		wantMsgs: map[string]*protoMsg{
-			"AMessageWithAList": {
-				Name:     "AMessageWithAList",
-				YANGPath: "/a-message-with-a-list/list",
+			"AMessage": {
+				Name:     "AMessage",
+				YANGPath: "/root/a-message",
 				Fields: []*protoMsgField{{
+					Tag:        535460038,
 					Name:       "list",
-					Type:       "ListKey",
-					Tag:        200573382,
+					Type:       "a_message.List",
 					IsRepeated: true,
 				}},
-			},
-			"ListKey": {
-				Name:     "ListKey",
-				YANGPath: "/a-message-with-a-list/list",
-				Fields: []*protoMsgField{{
-					Tag:        1,
-					Name:       "key",
-					Type:       "string",
-					IsRepeated: false,
-				}, {
-					Tag:  2,
-					Name: "list",
-					Type: "a_message_with_a_list.List",
-				}},
-				Imports: []string{"base/a_message_with_a_list/a_message_with_a_list.proto"},
+				Imports: []string{"base/a_message/a_message.proto"},
 			},
 		},
 	}, {
-		name: "message with list, where the key has the same name as list",
+		name: "simple message with leaf-list and a message child, compression off",
 		inMsg: &yangDirectory{
-			name: "AMessageWithAList",
+			name: "AMessage",
 			entry: &yang.Entry{
-				Name: "a-message-with-a-list",
+				Name: "a-message",
 				Dir:  map[string]*yang.Entry{},
 				Kind: yang.DirectoryEntry,
 			},
 			fields: map[string]*yang.Entry{
-				"list": {
-					Name: "list",
-					Parent: &yang.Entry{
-						Name: "a-message-with-a-list",
-					},
+				"leaf-list": {
+					Name:     "leaf-list",
+					Type:     &yang.YangType{Kind: yang.Ystring},
+					ListAttr: &yang.ListAttr{},
+				},
+				"container-child": {
+					Name: "container-child",
+					Dir:  map[string]*yang.Entry{},
 					Kind: yang.DirectoryEntry,
-					Dir: map[string]*yang.Entry{
-						"list": {
-							Name: "list",
-							Type: &yang.YangType{Kind: yang.Ystring},
+					Parent: &yang.Entry{
+						Name: "a-message",
+						Parent: &yang.Entry{
+							Name: "root",
 						},
 					},
-					Key:      "list",
-					ListAttr: &yang.ListAttr{},
 				},
 			},
-			path: []string{"", "a-message-with-a-list", "list"},
-		},
-		inBasePackage: "base",
-		inEnumPackage: "enums",
-		inUniqueDirectoryNames: map[string]string{
-			"/a-message-with-a-list/list": "List",
+			path: []string{"", "root", "a-message"},
 		},
 		inMsgs: map[string]*yangDirectory{
-			"/a-message-with-a-list/list": {
-				name: "List",
+			"/root/a-message/container-child": {
+				name: "ContainerChild",
 				entry: &yang.Entry{
-					Name: "list",
+					Name: "container-child",
 					Parent: &yang.Entry{
-						Name: "a-message-with-a-list",
-					},
-					Kind: yang.DirectoryEntry,
-					Dir: map[string]*yang.Entry{
-						"key": {
-							Name: "list",
-							Type: &yang.YangType{Kind: yang.Ystring},
+						Name: "a-message",
+						Parent: &yang.Entry{
+							Name: "root",
 						},
 					},
-					Key:      "list",
-					ListAttr: &yang.ListAttr{},
-				},
-				fields: map[string]*yang.Entry{
-					"list": {
-						Name: "list",
-						Type: &yang.YangType{Kind: yang.Ystring},
-					},
 				},
 			},
 		},
+		inBasePackage: "base",
+		inEnumPackage: "enums",
 		wantMsgs: map[string]*protoMsg{
-			"AMessageWithAList": {
-				Name:     "AMessageWithAList",
-				YANGPath: "/a-message-with-a-list/list",
+			"AMessage": {
+				Name:     "AMessage",
+				YANGPath: "/root/a-message",
 				Fields: []*protoMsgField{{
-					Name:       "list",
-					Type:       "ListKey",
-					Tag:        200573382,
+					Tag:        299656613,
+					Name:       "leaf_list",
+					Type:       "ywrapper.StringValue",
 					IsRepeated: true,
-				}},
-			},
-			"ListKey": {
-				Name:     "ListKey",
-				YANGPath: "/a-message-with-a-list/list",
-				Fields: []*protoMsgField{{
-					Tag:        1,
-					Name:       "list_key",
-					Type:       "string",
-					IsRepeated: false,
 				}, {
-					Tag:  2,
-					Name: "list",
-					Type: "a_message_with_a_list.List",
+					Tag:  17594927,
+					Name: "container_child",
+					Type: "root.a_message.ContainerChild",
 				}},
-				Imports: []string{"base/a_message_with_a_list/a_message_with_a_list.proto"},
+				Imports: []string{"base/root/a_message/a_message.proto"},
 			},
 		},
 	}, {
-		name: "message with missing directory",
+		name: "message with a container child introduced by an augment from another module",
 		inMsg: &yangDirectory{
-			name:  "foo",
-			entry: &yang.Entry{Name: "foo"},
+			name: "Parent",
+			entry: &yang.Entry{
+				Name: "parent",
+				Dir:  map[string]*yang.Entry{},
+				Kind: yang.DirectoryEntry,
+				Parent: &yang.Entry{
+					Name: "a",
+				},
+			},
 			fields: map[string]*yang.Entry{
-				"bar": {
-					Name: "bar",
-					Kind: yang.DirectoryEntry,
+				"extra": {
+					Name: "extra",
 					Dir:  map[string]*yang.Entry{},
+					Kind: yang.DirectoryEntry,
+					Parent: &yang.Entry{
+						Name: "parent",
+						Parent: &yang.Entry{
+							Name: "a",
+						},
+					},
+					// The node is defined in module b, which augments /a:parent.
+					Node: &yang.Container{
+						Name: "extra",
+						Parent: &yang.Module{
+							Name: "b",
+						},
+					},
 				},
 			},
+			path: []string{"", "a", "parent"},
+		},
+		inMsgs: map[string]*yangDirectory{
+			"/a/parent/extra": {
+				name: "Extra",
+				entry: &yang.Entry{
+					Name: "extra",
+					Parent: &yang.Entry{
+						Name: "parent",
+						Parent: &yang.Entry{
+							Name: "a",
+						},
+					},
+					Node: &yang.Container{
+						Name: "extra",
+						Parent: &yang.Module{
+							Name: "b",
+						},
+					},
+				},
+			},
+		},
+		inBasePackage:   "base",
+		inEnumPackage:   "enums",
+		inParentPackage: "a",
+		wantMsgs: map[string]*protoMsg{
+			"Parent": {
+				Name:     "Parent",
+				YANGPath: "/a/parent",
+				Fields: []*protoMsgField{{
+					Tag:  59003239,
+					Name: "extra",
+					Type: "parent.Extra",
+				}},
+				Imports: []string{"base/a/parent/parent.proto"},
+			},
 		},
-		wantErr: true,
 	}, {
-		name: "message with an unimplemented mapping",
+		name: "message with a container child within a module with an overridden package",
 		inMsg: &yangDirectory{
-			name: "MessageWithInvalidContents",
+			name: "Parent",
 			entry: &yang.Entry{
-				Name: "message-with-invalid-contents",
+				Name: "parent",
 				Dir:  map[string]*yang.Entry{},
 				Kind: yang.DirectoryEntry,
+				Parent: &yang.Entry{
+					Name: "a",
+				},
 			},
 			fields: map[string]*yang.Entry{
-				"unimplemented": {
-					Name: "unimplemented",
-					Kind: yang.LeafEntry,
-					Type: &yang.YangType{
-						Kind: yang.Yunion,
-						Type: []*yang.YangType{
-							{Kind: yang.Ybinary},
-							{Kind: yang.Ybits},
-							{Kind: yang.YinstanceIdentifier},
+				"child": {
+					Name: "child",
+					Dir:  map[string]*yang.Entry{},
+					Kind: yang.DirectoryEntry,
+					Parent: &yang.Entry{
+						Name: "parent",
+						Parent: &yang.Entry{
+							Name: "a",
 						},
 					},
 				},
 			},
-			path: []string{"", "messasge-with-invalid-contents", "unimplemented"},
+			path: []string{"", "a", "parent"},
+		},
+		inMsgs: map[string]*yangDirectory{
+			"/a/parent/child": {
+				name: "Child",
+				entry: &yang.Entry{
+					Name: "child",
+					Parent: &yang.Entry{
+						Name: "parent",
+						Parent: &yang.Entry{
+							Name: "a",
+						},
+					},
+				},
+			},
+		},
+		inBasePackage:    "base",
+		inEnumPackage:    "enums",
+		inParentPackage:  "custom.pkg",
+		inModulePackages: map[string]string{"a": "custom.pkg"},
+		wantMsgs: map[string]*protoMsg{
+			"Parent": {
+				Name:     "Parent",
+				YANGPath: "/a/parent",
+				Fields: []*protoMsgField{{
+					Tag:  474156915,
+					Name: "child",
+					Type: "parent.Child",
+				}},
+				Imports: []string{"base/custom/pkg/parent/parent.proto"},
+			},
 		},
-		wantErr: true,
 	}, {
-		name: "message with any anydata field",
+		name: "message with a container child within a single package per module",
 		inMsg: &yangDirectory{
-			name: "MessageWithAnydata",
+			name: "Parent",
 			entry: &yang.Entry{
-				Name: "message-with-anydata",
-				Kind: yang.DirectoryEntry,
+				Name: "parent",
 				Dir:  map[string]*yang.Entry{},
+				Kind: yang.DirectoryEntry,
+				Parent: &yang.Entry{
+					Name: "a",
+				},
 			},
 			fields: map[string]*yang.Entry{
-				"any-data": {
-					Name: "any-data",
-					Kind: yang.AnyDataEntry,
+				"child": {
+					Name: "child",
+					Dir:  map[string]*yang.Entry{},
+					Kind: yang.DirectoryEntry,
+					Parent: &yang.Entry{
+						Name: "parent",
+						Parent: &yang.Entry{
+							Name: "a",
+						},
+					},
 				},
-				"leaf": {
-					Name: "leaf",
-					Kind: yang.LeafEntry,
-					Type: &yang.YangType{Kind: yang.Ystring},
+			},
+			path: []string{"", "a", "parent"},
+		},
+		inMsgs: map[string]*yangDirectory{
+			"/a/parent/child": {
+				name: "Child",
+				entry: &yang.Entry{
+					Name: "child",
+					Parent: &yang.Entry{
+						Name: "parent",
+						Parent: &yang.Entry{
+							Name: "a",
+						},
+					},
 				},
 			},
-			path: []string{"", "message-with-anydata"},
 		},
-		inBasePackage: "base",
-		inEnumPackage: "enums",
+		inBasePackage:    "base",
+		inEnumPackage:    "enums",
+		inParentPackage:  "custom.pkg",
+		inModulePackages: map[string]string{"a": "custom.pkg"},
+		inPackagePolicy:  SinglePackage,
 		wantMsgs: map[string]*protoMsg{
-			"MessageWithAnydata": {
-				Name:     "MessageWithAnydata",
-				YANGPath: "/message-with-anydata",
-				Imports:  []string{"google/protobuf/any.proto"},
+			"Parent": {
+				Name:     "Parent",
+				YANGPath: "/a/parent",
 				Fields: []*protoMsgField{{
-					Tag:  453452743,
-					Name: "any_data",
-					Type: "google.protobuf.Any",
-				}, {
-					Tag:  463279904,
-					Name: "leaf",
-					Type: "ywrapper.StringValue",
+					Tag:  474156915,
+					Name: "child",
+					Type: "Child",
 				}},
 			},
 		},
 	}, {
-		name: "message with annotate schema paths enabled",
+		name: "message with list",
 		inMsg: &yangDirectory{
-			name: "MessageWithAnnotations",
+			name: "AMessageWithAList",
 			entry: &yang.Entry{
-				Name: "message-with-annotations",
-				Kind: yang.DirectoryEntry,
+				Name: "a-message-with-a-list",
 				Dir:  map[string]*yang.Entry{},
-				Parent: &yang.Entry{
-					Name: "two",
+				Kind: yang.DirectoryEntry,
+			},
+			fields: map[string]*yang.Entry{
+				"list": {
+					Name: "list",
 					Parent: &yang.Entry{
-						Name: "one",
+						Name: "a-message-with-a-list",
 					},
+					Kind: yang.DirectoryEntry,
+					Dir: map[string]*yang.Entry{
+						"key": {
+							Name: "key",
+							Type: &yang.YangType{Kind: yang.Ystring},
+						},
+					},
+					Key:      "key",
+					ListAttr: &yang.ListAttr{},
 				},
 			},
-			fields: map[string]*yang.Entry{
-				"leaf": {
-					Name: "leaf",
-					Kind: yang.LeafEntry,
-					Type: &yang.YangType{Kind: yang.Ystring},
+			path: []string{"", "a-message-with-a-list", "list"},
+		},
+		inBasePackage: "base",
+		inEnumPackage: "enums",
+		inUniqueDirectoryNames: map[string]string{
+			"/a-message-with-a-list/list": "List",
+		},
+		inMsgs: map[string]*yangDirectory{
+			"/a-message-with-a-list/list": {
+				name: "List",
+				entry: &yang.Entry{
+					Name: "list",
 					Parent: &yang.Entry{
-						Name: "two",
-						Parent: &yang.Entry{
-							Name: "one",
+						Name: "a-message-with-a-list",
+					},
+					Kind: yang.DirectoryEntry,
+					Dir: map[string]*yang.Entry{
+						"key": {
+							Name: "key",
+							Type: &yang.YangType{Kind: yang.Ystring},
 						},
 					},
+					Key:      "key",
+					ListAttr: &yang.ListAttr{},
+				},
+				fields: map[string]*yang.Entry{
+					"key": {
+						Name: "key",
+						Type: &yang.YangType{Kind: yang.Ystring},
+					},
 				},
 			},
-			path: []string{"", "one", "two"},
 		},
-		inBasePackage:         "base",
-		inEnumPackage:         "enums",
-		inAnnotateSchemaPaths: true,
 		wantMsgs: map[string]*protoMsg{
-			"MessageWithAnnotations": {
-				Name:     "MessageWithAnnotations",
-				YANGPath: "/one/two",
+			"AMessageWithAList": {
+				Name:     "AMessageWithAList",
+				YANGPath: "/a-message-with-a-list/list",
 				Fields: []*protoMsgField{{
-					Name: "leaf",
-					Tag:  60047678,
-					Type: "ywrapper.StringValue",
-					Options: []*protoOption{{
-						Name:  "(yext.schemapath)",
-						Value: `"/two/leaf"`,
-					}},
+					Name:       "list",
+					Type:       "ListKey",
+					Tag:        200573382,
+					IsRepeated: true,
+				}},
+			},
+			"ListKey": {
+				Name:     "ListKey",
+				YANGPath: "/a-message-with-a-list/list",
+				Fields: []*protoMsgField{{
+					Tag:        1,
+					Name:       "key",
+					Type:       "string",
+					IsRepeated: false,
+				}, {
+					Tag:  2,
+					Name: "list",
+					Type: "a_message_with_a_list.List",
 				}},
+				Imports: []string{"base/a_message_with_a_list/a_message_with_a_list.proto"},
 			},
 		},
-	}}
-
-	for _, tt := range tests {
-		s := newGenState()
-		// Seed the state with the supplied message names that have been provided.
-		s.uniqueDirectoryNames = tt.inUniqueDirectoryNames
-
-		gotMsgs, errs := genProto3Msg(tt.inMsg, tt.inMsgs, s, &protoMsgConfig{
-			compressPaths:       tt.inCompressPaths,
-			basePackageName:     tt.inBasePackage,
-			enumPackageName:     tt.inEnumPackage,
-			baseImportPath:      tt.inBaseImportPath,
-			annotateSchemaPaths: tt.inAnnotateSchemaPaths,
-		}, tt.inParentPackage, tt.inChildMsgs)
-
-		if (errs != nil) != tt.wantErr {
-			t.Errorf("s: genProtoMsg(%#v, %#v, *genState, %v, %v, %s, %s): did not get expected error status, got: %v, wanted err: %v", tt.name, tt.inMsg, tt.inMsgs, tt.inCompressPaths, tt.inBasePackage, tt.inEnumPackage, errs, tt.wantErr)
-		}
-
-		if tt.wantErr {
-			continue
-		}
-
-		notSeen := map[string]bool{}
-		for _, w := range tt.wantMsgs {
-			notSeen[w.Name] = true
-		}
-
-		for _, got := range gotMsgs {
-			want, ok := tt.wantMsgs[got.Name]
-			if !ok {
-				t.Errorf("%s: genProtoMsg(%#v, %#v, *genState): got unexpected message, got: %v, want: %v", tt.name, tt.inMsg, tt.inMsgs, got.Name, tt.wantMsgs)
-				continue
-			}
-			delete(notSeen, got.Name)
-
-			if !protoMsgEq(got, want) {
-				diff := pretty.Compare(got, want)
-				t.Errorf("%s: genProtoMsg(%#v, %#v, *genState): did not get expected protobuf message definition, diff(-got,+want):\n%s", tt.name, tt.inMsg, tt.inMsgs, diff)
-			}
-		}
-
-		if len(notSeen) != 0 {
-			t.Errorf("%s: genProtoMsg(%#v, %#v, *genState); did not test all returned messages, got remaining messages: %v, want: none", tt.name, tt.inMsg, tt.inMsgs, notSeen)
-		}
-	}
-}
-
-func TestSafeProtoName(t *testing.T) {
-	tests := []struct {
-		name string
-		in   string
-		want string
-	}{{
-		name: "contains hyphen",
-		in:   "with-hyphen",
-		want: "with_hyphen",
-	}, {
-		name: "contains period",
-		in:   "with.period",
-		want: "with_period",
 	}, {
-		name: "unchanged",
-		in:   "unchanged",
-		want: "unchanged",
-	}}
-
-	for _, tt := range tests {
-		if got := safeProtoIdentifierName(tt.in); got != tt.want {
-			t.Errorf("%s: safeProtoFieldName(%s): did not get expected name, got: %v, want: %v", tt.name, tt.in, got, tt.want)
-		}
-	}
-}
-
-func TestWriteProtoMsg(t *testing.T) {
-	// A definition of an enumerated type.
-	enumeratedLeafDef := yang.NewEnumType()
-	enumeratedLeafDef.Set("ONE", int64(1))
-	enumeratedLeafDef.Set("FORTYTWO", int64(42))
-
-	tests := []struct {
-		name                   string
-		inMsg                  *yangDirectory
-		inMsgs                 map[string]*yangDirectory
-		inBasePackageName      string
-		inEnumPackageName      string
-		inBaseImportPath       string
-		inUniqueDirectoryNames map[string]string
-		inNestedMessages       bool
-		wantCompress           *generatedProto3Message
-		wantUncompress         *generatedProto3Message
-		wantCompressErr        bool
-		wantUncompressErr      bool
-	}{{
-		name: "simple message with scalar fields",
+		name: "message with list in a single package",
 		inMsg: &yangDirectory{
-			name: "MessageName",
+			name: "AMessageWithAList",
 			entry: &yang.Entry{
-				Name: "message-name",
-				Kind: yang.DirectoryEntry,
+				Name: "a-message-with-a-list",
 				Dir:  map[string]*yang.Entry{},
-				Parent: &yang.Entry{
-					Name: "container",
-					Kind: yang.DirectoryEntry,
-					Dir:  map[string]*yang.Entry{},
+				Kind: yang.DirectoryEntry,
+			},
+			fields: map[string]*yang.Entry{
+				"list": {
+					Name: "list",
 					Parent: &yang.Entry{
-						Name: "module",
-						Kind: yang.DirectoryEntry,
-						Dir:  map[string]*yang.Entry{},
+						Name: "a-message-with-a-list",
 					},
+					Kind: yang.DirectoryEntry,
+					Dir: map[string]*yang.Entry{
+						"key": {
+							Name: "key",
+							Type: &yang.YangType{Kind: yang.Ystring},
+						},
+					},
+					Key:      "key",
+					ListAttr: &yang.ListAttr{},
 				},
-				Node: &yang.Container{Name: "message-name"},
 			},
-			fields: map[string]*yang.Entry{
-				"field-one": {
-					Name: "field-one",
-					Type: &yang.YangType{Kind: yang.Ystring},
+			path: []string{"", "a-message-with-a-list", "list"},
+		},
+		inBasePackage:   "base",
+		inEnumPackage:   "enums",
+		inPackagePolicy: SinglePackage,
+		inUniqueDirectoryNames: map[string]string{
+			"/a-message-with-a-list/list": "List",
+		},
+		inMsgs: map[string]*yangDirectory{
+			"/a-message-with-a-list/list": {
+				name: "List",
+				entry: &yang.Entry{
+					Name: "list",
+					Parent: &yang.Entry{
+						Name: "a-message-with-a-list",
+					},
+					Kind: yang.DirectoryEntry,
+					Dir: map[string]*yang.Entry{
+						"key": {
+							Name: "key",
+							Type: &yang.YangType{Kind: yang.Ystring},
+						},
+					},
+					Key:      "key",
+					ListAttr: &yang.ListAttr{},
+				},
+				fields: map[string]*yang.Entry{
+					"key": {
+						Name: "key",
+						Type: &yang.YangType{Kind: yang.Ystring},
+					},
 				},
 			},
-			path: []string{"", "module", "container", "message-name"},
-		},
-		inBasePackageName: "base",
-		inEnumPackageName: "enums",
-		wantCompress: &generatedProto3Message{
-			PackageName: "container",
-			MessageCode: `
-// MessageName represents the /module/container/message-name YANG schema element.
-message MessageName {
-  ywrapper.StringValue field_one = 410095931;
-}`,
 		},
-		wantUncompress: &generatedProto3Message{
-			PackageName: "module.container",
-			MessageCode: `
-// MessageName represents the /module/container/message-name YANG schema element.
-message MessageName {
-  ywrapper.StringValue field_one = 410095931;
-}`,
+		wantMsgs: map[string]*protoMsg{
+			"AMessageWithAList": {
+				Name:     "AMessageWithAList",
+				YANGPath: "/a-message-with-a-list/list",
+				Fields: []*protoMsgField{{
+					Name:       "list",
+					Type:       "ListKey",
+					Tag:        200573382,
+					IsRepeated: true,
+				}},
+			},
+			"ListKey": {
+				Name:     "ListKey",
+				YANGPath: "/a-message-with-a-list/list",
+				Fields: []*protoMsgField{{
+					Tag:        1,
+					Name:       "key",
+					Type:       "string",
+					IsRepeated: false,
+				}, {
+					Tag:  2,
+					Name: "list",
+					Type: "List",
+				}},
+			},
 		},
 	}, {
-		name: "simple message with other messages embedded",
+		name: "message with list, where the key has the same name as list",
 		inMsg: &yangDirectory{
-			name: "MessageName",
+			name: "AMessageWithAList",
 			entry: &yang.Entry{
-				Name: "message-name",
+				Name: "a-message-with-a-list",
+				Dir:  map[string]*yang.Entry{},
 				Kind: yang.DirectoryEntry,
-				Parent: &yang.Entry{
-					Name: "module",
-					Kind: yang.DirectoryEntry,
-				},
 			},
 			fields: map[string]*yang.Entry{
-				"child": {
-					Name: "child",
-					Kind: yang.DirectoryEntry,
-					Dir:  map[string]*yang.Entry{},
+				"list": {
+					Name: "list",
 					Parent: &yang.Entry{
-						Name: "message-name",
-						Kind: yang.DirectoryEntry,
-						Parent: &yang.Entry{
-							Name: "module",
-							Kind: yang.DirectoryEntry,
+						Name: "a-message-with-a-list",
+					},
+					Kind: yang.DirectoryEntry,
+					Dir: map[string]*yang.Entry{
+						"list": {
+							Name: "list",
+							Type: &yang.YangType{Kind: yang.Ystring},
 						},
 					},
+					Key:      "list",
+					ListAttr: &yang.ListAttr{},
 				},
 			},
-			path: []string{"", "module", "message-name"},
+			path: []string{"", "a-message-with-a-list", "list"},
 		},
-		inMsgs: map[string]*yangDirectory{
-			"/module/message-name/child": {
-				name: "Child",
+		inBasePackage: "base",
+		inEnumPackage: "enums",
+		inUniqueDirectoryNames: map[string]string{
+			"/a-message-with-a-list/list": "List",
+		},
+		inMsgs: map[string]*yangDirectory{
+			"/a-message-with-a-list/list": {
+				name: "List",
 				entry: &yang.Entry{
-					Name: "child",
-					Kind: yang.DirectoryEntry,
+					Name: "list",
 					Parent: &yang.Entry{
-						Name: "message-name",
-						Kind: yang.DirectoryEntry,
-						Parent: &yang.Entry{
-							Name: "module",
-							Kind: yang.DirectoryEntry,
+						Name: "a-message-with-a-list",
+					},
+					Kind: yang.DirectoryEntry,
+					Dir: map[string]*yang.Entry{
+						"key": {
+							Name: "list",
+							Type: &yang.YangType{Kind: yang.Ystring},
 						},
 					},
+					Key:      "list",
+					ListAttr: &yang.ListAttr{},
+				},
+				fields: map[string]*yang.Entry{
+					"list": {
+						Name: "list",
+						Type: &yang.YangType{Kind: yang.Ystring},
+					},
 				},
 			},
 		},
-		inBasePackageName: "base",
-		inEnumPackageName: "enums",
-		wantCompress: &generatedProto3Message{
-			PackageName: "",
-			MessageCode: `
-// MessageName represents the /module/message-name YANG schema element.
-message MessageName {
-  message_name.Child child = 399980855;
-}`,
-			RequiredImports: []string{"base/message_name/message_name.proto"},
+		wantMsgs: map[string]*protoMsg{
+			"AMessageWithAList": {
+				Name:     "AMessageWithAList",
+				YANGPath: "/a-message-with-a-list/list",
+				Fields: []*protoMsgField{{
+					Name:       "list",
+					Type:       "ListKey",
+					Tag:        200573382,
+					IsRepeated: true,
+				}},
+			},
+			"ListKey": {
+				Name:     "ListKey",
+				YANGPath: "/a-message-with-a-list/list",
+				Fields: []*protoMsgField{{
+					Tag:        1,
+					Name:       "list_key",
+					Type:       "string",
+					IsRepeated: false,
+				}, {
+					Tag:  2,
+					Name: "list",
+					Type: "a_message_with_a_list.List",
+				}},
+				Imports: []string{"base/a_message_with_a_list/a_message_with_a_list.proto"},
+			},
 		},
-		wantUncompress: &generatedProto3Message{
-			PackageName: "module",
-			MessageCode: `
-// MessageName represents the /module/message-name YANG schema element.
-message MessageName {
-  message_name.Child child = 399980855;
-}`,
-			RequiredImports: []string{"base/module/message_name/message_name.proto"},
+	}, {
+		name: "message with missing directory",
+		inMsg: &yangDirectory{
+			name:  "foo",
+			entry: &yang.Entry{Name: "foo"},
+			fields: map[string]*yang.Entry{
+				"bar": {
+					Name: "bar",
+					Kind: yang.DirectoryEntry,
+					Dir:  map[string]*yang.Entry{},
+				},
+			},
 		},
+		wantErr: true,
 	}, {
-		name: "simple message with other messages embedded - with nested messages",
+		name: "message with an unimplemented mapping",
 		inMsg: &yangDirectory{
-			name: "MessageName",
+			name: "MessageWithInvalidContents",
 			entry: &yang.Entry{
-				Name: "message-name",
+				Name: "message-with-invalid-contents",
+				Dir:  map[string]*yang.Entry{},
 				Kind: yang.DirectoryEntry,
-				Parent: &yang.Entry{
-					Name: "module",
-					Kind: yang.DirectoryEntry,
-				},
 			},
 			fields: map[string]*yang.Entry{
-				"child": {
-					Name: "child",
-					Kind: yang.DirectoryEntry,
-					Dir:  map[string]*yang.Entry{},
-					Parent: &yang.Entry{
-						Name: "message-name",
-						Kind: yang.DirectoryEntry,
-						Parent: &yang.Entry{
-							Name: "module",
-							Kind: yang.DirectoryEntry,
+				"unimplemented": {
+					Name: "unimplemented",
+					Kind: yang.LeafEntry,
+					Type: &yang.YangType{
+						Kind: yang.Yunion,
+						Type: []*yang.YangType{
+							{Kind: yang.Ybinary},
+							{Kind: yang.Ybits},
+							{Kind: yang.YinstanceIdentifier},
 						},
 					},
 				},
 			},
-			path: []string{"", "module", "message-name"},
+			path: []string{"", "messasge-with-invalid-contents", "unimplemented"},
 		},
-		inMsgs: map[string]*yangDirectory{
-			"/module/message-name/child": {
-				name: "Child",
-				entry: &yang.Entry{
-					Name: "child",
-					Kind: yang.DirectoryEntry,
-					Parent: &yang.Entry{
-						Name: "message-name",
-						Kind: yang.DirectoryEntry,
-						Parent: &yang.Entry{
-							Name: "module",
-							Kind: yang.DirectoryEntry,
-						},
-					},
+		wantErr: true,
+	}, {
+		name: "message with any anydata field",
+		inMsg: &yangDirectory{
+			name: "MessageWithAnydata",
+			entry: &yang.Entry{
+				Name: "message-with-anydata",
+				Kind: yang.DirectoryEntry,
+				Dir:  map[string]*yang.Entry{},
+			},
+			fields: map[string]*yang.Entry{
+				"any-data": {
+					Name: "any-data",
+					Kind: yang.AnyDataEntry,
 				},
-				path: []string{"", "module", "message-name", "child"},
-				fields: map[string]*yang.Entry{
-					"leaf": {
-						Name: "leaf",
-						Type: &yang.YangType{Kind: yang.Ystring},
-					},
+				"leaf": {
+					Name: "leaf",
+					Kind: yang.LeafEntry,
+					Type: &yang.YangType{Kind: yang.Ystring},
 				},
 			},
+			path: []string{"", "message-with-anydata"},
 		},
-		inBasePackageName: "base",
-		inEnumPackageName: "enums",
-		inNestedMessages:  true,
-		wantCompress: &generatedProto3Message{
-			PackageName: "",
-			MessageCode: `
-message MessageName {
-  message Child {
-    ywrapper.StringValue leaf = 463279904;
-  }
-  Child child = 399980855;
-}`,
-		},
-		wantUncompress: &generatedProto3Message{
-			PackageName: "module",
-			MessageCode: `
-message MessageName {
-  message Child {
-    ywrapper.StringValue leaf = 463279904;
-  }
-  Child child = 399980855;
-}`,
+		inBasePackage: "base",
+		inEnumPackage: "enums",
+		wantMsgs: map[string]*protoMsg{
+			"MessageWithAnydata": {
+				Name:     "MessageWithAnydata",
+				YANGPath: "/message-with-anydata",
+				Imports:  []string{"google/protobuf/any.proto"},
+				Fields: []*protoMsgField{{
+					Tag:  453452743,
+					Name: "any_data",
+					Type: "google.protobuf.Any",
+				}, {
+					Tag:  463279904,
+					Name: "leaf",
+					Type: "ywrapper.StringValue",
+				}},
+			},
 		},
 	}, {
-		name: "simple message with an enumeration leaf",
+		name: "message with annotate schema paths enabled",
 		inMsg: &yangDirectory{
-			name: "MessageName",
+			name: "MessageWithAnnotations",
 			entry: &yang.Entry{
-				Name: "message-name",
+				Name: "message-with-annotations",
 				Kind: yang.DirectoryEntry,
+				Dir:  map[string]*yang.Entry{},
 				Parent: &yang.Entry{
-					Name: "module",
-					Kind: yang.DirectoryEntry,
+					Name: "two",
+					Parent: &yang.Entry{
+						Name: "one",
+					},
 				},
 			},
 			fields: map[string]*yang.Entry{
-				"enum": {
-					Name: "enum",
+				"leaf": {
+					Name: "leaf",
 					Kind: yang.LeafEntry,
+					Type: &yang.YangType{Kind: yang.Ystring},
 					Parent: &yang.Entry{
-						Name: "message-name",
+						Name: "two",
 						Parent: &yang.Entry{
-							Name: "module",
+							Name: "one",
 						},
 					},
-					Type: &yang.YangType{
-						Name: "enumeration",
-						Kind: yang.Yenum,
-						Enum: enumeratedLeafDef,
-					},
 				},
 			},
-			path: []string{"", "module", "message-name"},
-		},
-		inBasePackageName: "base",
-		inEnumPackageName: "enums",
-		wantCompress: &generatedProto3Message{
-			PackageName: "",
-			MessageCode: `
-// MessageName represents the /module/message-name YANG schema element.
-message MessageName {
-  enum Enum {
-    ENUM_UNSET = 0;
-    ENUM_ONE = 2;
-    ENUM_FORTYTWO = 43;
-  }
-  Enum enum = 278979784;
-}`,
+			path: []string{"", "one", "two"},
 		},
-		wantUncompress: &generatedProto3Message{
-			PackageName: "module",
-			MessageCode: `
-// MessageName represents the /module/message-name YANG schema element.
-message MessageName {
-  enum Enum {
-    ENUM_UNSET = 0;
-    ENUM_ONE = 2;
-    ENUM_FORTYTWO = 43;
-  }
-  Enum enum = 278979784;
-}`,
+		inBasePackage:         "base",
+		inEnumPackage:         "enums",
+		inAnnotateSchemaPaths: true,
+		wantMsgs: map[string]*protoMsg{
+			"MessageWithAnnotations": {
+				Name:     "MessageWithAnnotations",
+				YANGPath: "/one/two",
+				Fields: []*protoMsgField{{
+					Name: "leaf",
+					Tag:  60047678,
+					Type: "ywrapper.StringValue",
+					Options: []*protoOption{{
+						Name:  "(yext.schemapath)",
+						Value: `"/two/leaf"`,
+					}},
+				}},
+			},
 		},
 	}, {
-		name: "simple message with a list",
-		inMsg: &yangDirectory{
-			name: "AMessage",
-			entry: &yang.Entry{
-				Name: "a-message",
-				Kind: yang.DirectoryEntry,
-				Parent: &yang.Entry{
-					Name: "module",
-					Kind: yang.DirectoryEntry,
-				},
-			},
-			fields: map[string]*yang.Entry{
-				"list": {
-					Name:     "list",
-					Kind:     yang.DirectoryEntry,
-					ListAttr: &yang.ListAttr{},
-					Key:      "keyfield",
-					Dir: map[string]*yang.Entry{
-						"keyfield": {
-							Name: "keyfield",
-							Type: &yang.YangType{
-								Kind: yang.Ystring,
-							},
-						},
-					},
-					Parent: &yang.Entry{
-						Name: "surrounding-container",
-						Kind: yang.DirectoryEntry,
-						Parent: &yang.Entry{
-							Name: "a-message",
-							Kind: yang.DirectoryEntry,
-							Parent: &yang.Entry{
-								Name: "module",
-								Kind: yang.DirectoryEntry,
-							},
-						},
-					},
+		name: "compressed message with annotate schema paths enabled",
+		inMsg: func() *yangDirectory {
+			mod := &yang.Entry{Name: "mod"}
+			msg := &yang.Entry{
+				Name:   "a-message",
+				Kind:   yang.DirectoryEntry,
+				Dir:    map[string]*yang.Entry{},
+				Parent: mod,
+			}
+			config := &yang.Entry{
+				Name:   "config",
+				Kind:   yang.DirectoryEntry,
+				Dir:    map[string]*yang.Entry{},
+				Parent: msg,
+			}
+			leaf := &yang.Entry{
+				Name:   "leaf",
+				Kind:   yang.LeafEntry,
+				Type:   &yang.YangType{Kind: yang.Ystring},
+				Parent: config,
+			}
+			child := &yang.Entry{
+				Name:   "child",
+				Kind:   yang.DirectoryEntry,
+				Dir:    map[string]*yang.Entry{},
+				Parent: msg,
+			}
+			config.Dir["leaf"] = leaf
+			msg.Dir["config"] = config
+			msg.Dir["child"] = child
+			return &yangDirectory{
+				name:  "AMessage",
+				entry: msg,
+				fields: map[string]*yang.Entry{
+					"leaf":  leaf,
+					"child": child,
 				},
-			},
-		},
+				path: []string{"", "mod", "a-message"},
+			}
+		}(),
 		inMsgs: map[string]*yangDirectory{
-			"/module/a-message/surrounding-container/list": {
-				name: "List",
+			"/mod/a-message/child": {
+				name: "Child",
 				entry: &yang.Entry{
-					Name:     "list",
-					Kind:     yang.DirectoryEntry,
-					ListAttr: &yang.ListAttr{},
-					Key:      "keyfield",
-					Dir: map[string]*yang.Entry{
-						"keyfield": {
-							Name: "keyfield",
-							Type: &yang.YangType{
-								Kind: yang.Ystring,
-							},
-						},
-					},
+					Name: "child",
+					Kind: yang.DirectoryEntry,
+					Dir:  map[string]*yang.Entry{},
 					Parent: &yang.Entry{
-						Name: "surrounding-container",
-						Kind: yang.DirectoryEntry,
-						Parent: &yang.Entry{
-							Name: "a-message",
-							Kind: yang.DirectoryEntry,
-							Parent: &yang.Entry{
-								Name: "module",
-								Kind: yang.DirectoryEntry,
-							},
-						},
-					},
-				},
-				fields: map[string]*yang.Entry{
-					"keyfield": {
-						Name: "keyfield",
-						Type: &yang.YangType{
-							Kind: yang.Ystring,
-						},
+						Name:   "a-message",
+						Parent: &yang.Entry{Name: "mod"},
 					},
 				},
 			},
 		},
-		inBasePackageName:      "base",
-		inEnumPackageName:      "enums",
-		inUniqueDirectoryNames: map[string]string{"/module/a-message/surrounding-container/list": "List"},
-		wantCompress: &generatedProto3Message{
-			PackageName: "",
-			MessageCode: `
-// ListKey represents the /module/a-message/surrounding-container/list YANG schema element.
-message ListKey {
-  string keyfield = 1;
-  a_message.List list = 2;
+		inCompressPaths:       true,
+		inBasePackage:         "base",
+		inEnumPackage:         "enums",
+		inAnnotateSchemaPaths: true,
+		wantMsgs: map[string]*protoMsg{
+			"AMessage": {
+				Name:     "AMessage",
+				YANGPath: "/mod/a-message",
+				Fields: []*protoMsgField{{
+					Name: "child",
+					Tag:  485576545,
+					Type: "a_message.Child",
+					Options: []*protoOption{{
+						Name:  "(yext.schemapath)",
+						Value: `"/a-message/child"`,
+					}},
+				}, {
+					Name: "leaf",
+					Tag:  180504610,
+					Type: "ywrapper.StringValue",
+					Options: []*protoOption{{
+						Name:  "(yext.schemapath)",
+						Value: `"/a-message/config/leaf"`,
+					}},
+				}},
+				Imports: []string{"base/a_message/a_message.proto"},
+			},
+		},
+	}}
+
+	for _, tt := range tests {
+		s := newGenState()
+		// Seed the state with the supplied message names that have been provided.
+		s.uniqueDirectoryNames = tt.inUniqueDirectoryNames
+		s.protoModulePackages = tt.inModulePackages
+		s.protoPackagePolicy = tt.inPackagePolicy
+
+		gotMsgs, errs := genProto3Msg(tt.inMsg, tt.inMsgs, s, &protoMsgConfig{
+			compressPaths:       tt.inCompressPaths,
+			basePackageName:     tt.inBasePackage,
+			enumPackageName:     tt.inEnumPackage,
+			baseImportPath:      tt.inBaseImportPath,
+			annotateSchemaPaths: tt.inAnnotateSchemaPaths,
+		}, tt.inParentPackage, tt.inChildMsgs)
+
+		if (errs != nil) != tt.wantErr {
+			t.Errorf("s: genProtoMsg(%#v, %#v, *genState, %v, %v, %s, %s): did not get expected error status, got: %v, wanted err: %v", tt.name, tt.inMsg, tt.inMsgs, tt.inCompressPaths, tt.inBasePackage, tt.inEnumPackage, errs, tt.wantErr)
+		}
+
+		if tt.wantErr {
+			continue
+		}
+
+		notSeen := map[string]bool{}
+		for _, w := range tt.wantMsgs {
+			notSeen[w.Name] = true
+		}
+
+		for _, got := range gotMsgs {
+			want, ok := tt.wantMsgs[got.Name]
+			if !ok {
+				t.Errorf("%s: genProtoMsg(%#v, %#v, *genState): got unexpected message, got: %v, want: %v", tt.name, tt.inMsg, tt.inMsgs, got.Name, tt.wantMsgs)
+				continue
+			}
+			delete(notSeen, got.Name)
+
+			if !protoMsgEq(got, want) {
+				diff := pretty.Compare(got, want)
+				t.Errorf("%s: genProtoMsg(%#v, %#v, *genState): did not get expected protobuf message definition, diff(-got,+want):\n%s", tt.name, tt.inMsg, tt.inMsgs, diff)
+			}
+		}
+
+		if len(notSeen) != 0 {
+			t.Errorf("%s: genProtoMsg(%#v, %#v, *genState); did not test all returned messages, got remaining messages: %v, want: none", tt.name, tt.inMsg, tt.inMsgs, notSeen)
+		}
+	}
 }
 
-// AMessage represents the  YANG schema element.
-message AMessage {
-  repeated ListKey list = 486198550;
-}`,
-			RequiredImports: []string{"base/a_message/a_message.proto"},
-		},
-		wantUncompress: &generatedProto3Message{
-			PackageName: "module",
-			MessageCode: `
-// ListKey represents the /module/a-message/surrounding-container/list YANG schema element.
-message ListKey {
-  string keyfield = 1;
-  a_message.surrounding_container.List list = 2;
+func TestSafeProtoName(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{{
+		name: "contains hyphen",
+		in:   "with-hyphen",
+		want: "with_hyphen",
+	}, {
+		name: "contains period",
+		in:   "with.period",
+		want: "with_period",
+	}, {
+		name: "contains slash",
+		in:   "10/100",
+		want: "10_100",
+	}, {
+		name: "unchanged",
+		in:   "unchanged",
+		want: "unchanged",
+	}}
+
+	for _, tt := range tests {
+		if got := safeProtoIdentifierName(tt.in); got != tt.want {
+			t.Errorf("%s: safeProtoFieldName(%s): did not get expected name, got: %v, want: %v", tt.name, tt.in, got, tt.want)
+		}
+	}
 }
 
-// AMessage represents the  YANG schema element.
-message AMessage {
-  repeated ListKey list = 486198550;
-}`,
-			RequiredImports: []string{"base/module/a_message/surrounding_container/surrounding_container.proto"},
-		},
+func TestUpperSnakeCase(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{{
+		name: "lower camel case",
+		in:   "adminUp",
+		want: "ADMIN_UP",
 	}, {
-		name: "simple message with a list - nested messages",
-		inMsg: &yangDirectory{
-			name: "AMessage",
-			entry: &yang.Entry{
-				Name: "a-message",
-				Kind: yang.DirectoryEntry,
-				Parent: &yang.Entry{
-					Name: "module",
-					Kind: yang.DirectoryEntry,
-				},
-			},
-			fields: map[string]*yang.Entry{
-				"list": {
-					Name:     "list",
-					Kind:     yang.DirectoryEntry,
-					ListAttr: &yang.ListAttr{},
-					Key:      "keyfield",
-					Dir: map[string]*yang.Entry{
-						"keyfield": {
-							Name: "keyfield",
-							Type: &yang.YangType{
-								Kind: yang.Ystring,
-							},
-						},
-					},
-					Parent: &yang.Entry{
-						Name: "surrounding-container",
-						Kind: yang.DirectoryEntry,
-						Parent: &yang.Entry{
-							Name: "a-message",
-							Kind: yang.DirectoryEntry,
-							Parent: &yang.Entry{
-								Name: "module",
-								Kind: yang.DirectoryEntry,
-							},
-						},
-					},
-				},
-			},
-			path: []string{"", "module", "a-message"},
+		name: "hyphenated",
+		in:   "admin-up",
+		want: "ADMIN_UP",
+	}, {
+		name: "acronym followed by word",
+		in:   "HTTPServer",
+		want: "HTTP_SERVER",
+	}, {
+		name: "digit followed by word",
+		in:   "ipv4Address",
+		want: "IPV4_ADDRESS",
+	}, {
+		name: "already upper snake case with period",
+		in:   "SPEED_2.5G",
+		want: "SPEED_2_5G",
+	}}
+
+	for _, tt := range tests {
+		if got := upperSnakeCase(tt.in); got != tt.want {
+			t.Errorf("%s: upperSnakeCase(%s): did not get expected name, got: %v, want: %v", tt.name, tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestWriteProtoMsg(t *testing.T) {
+	// twoLevelMsg is a container, parent, which has a child container, that
+	// in turn has a child container, grandchild.
+	moduleEntry := &yang.Entry{Name: "module", Kind: yang.DirectoryEntry, Dir: map[string]*yang.Entry{}}
+	parentEntry := &yang.Entry{Name: "parent", Kind: yang.DirectoryEntry, Parent: moduleEntry, Dir: map[string]*yang.Entry{}}
+	childEntry := &yang.Entry{Name: "child", Kind: yang.DirectoryEntry, Parent: parentEntry, Dir: map[string]*yang.Entry{}}
+	grandchildEntry := &yang.Entry{Name: "grandchild", Kind: yang.DirectoryEntry, Parent: childEntry, Dir: map[string]*yang.Entry{}}
+	leafEntry := &yang.Entry{Name: "leaf", Kind: yang.LeafEntry, Parent: grandchildEntry, Type: &yang.YangType{Kind: yang.Ystring}}
+	moduleEntry.Dir["parent"] = parentEntry
+	parentEntry.Dir["child"] = childEntry
+	childEntry.Dir["grandchild"] = grandchildEntry
+	grandchildEntry.Dir["leaf"] = leafEntry
+
+	twoLevelMsg := &yangDirectory{
+		name:   "Parent",
+		entry:  parentEntry,
+		fields: map[string]*yang.Entry{"child": childEntry},
+		path:   []string{"", "module", "parent"},
+	}
+	twoLevelMsgs := map[string]*yangDirectory{
+		"/module/parent/child": {
+			name:   "Child",
+			entry:  childEntry,
+			fields: map[string]*yang.Entry{"grandchild": grandchildEntry},
+			path:   []string{"", "module", "parent", "child"},
 		},
-		inMsgs: map[string]*yangDirectory{
-			"/module/a-message/surrounding-container/list": {
-				name: "List",
-				entry: &yang.Entry{
-					Name:     "list",
-					Kind:     yang.DirectoryEntry,
-					ListAttr: &yang.ListAttr{},
-					Key:      "keyfield",
-					Dir: map[string]*yang.Entry{
-						"keyfield": {
-							Name: "keyfield",
-							Type: &yang.YangType{
-								Kind: yang.Ystring,
-							},
-						},
-					},
+		"/module/parent/child/grandchild": {
+			name:   "Grandchild",
+			entry:  grandchildEntry,
+			fields: map[string]*yang.Entry{"leaf": leafEntry},
+			path:   []string{"", "module", "parent", "child", "grandchild"},
+		},
+	}
+
+	// A definition of an enumerated type.
+	enumeratedLeafDef := yang.NewEnumType()
+	enumeratedLeafDef.Set("ONE", int64(1))
+	enumeratedLeafDef.Set("FORTYTWO", int64(42))
+
+	// A definition of a bits type.
+	bitsLeafDef := yang.NewBitfield()
+	bitsLeafDef.Set("up", int64(0))
+	bitsLeafDef.Set("running", int64(3))
+	bitsLeafDef.Set("lower-layer-down", int64(7))
+
+	tests := []struct {
+		name                   string
+		inMsg                  *yangDirectory
+		inMsgs                 map[string]*yangDirectory
+		inBasePackageName      string
+		inEnumPackageName      string
+		inBaseImportPath       string
+		inUniqueDirectoryNames map[string]string
+		inNestedMessages       bool
+		inIdentityrefAsString  bool
+		inIdentitiesFile       bool
+		inSchemaPathField      bool
+		inReservedTags         map[string][]uint32
+		inProto2               bool
+		wantCompress           *generatedProto3Message
+		wantUncompress         *generatedProto3Message
+		wantCompressErr        bool
+		wantUncompressErr      bool
+	}{{
+		name: "simple message with scalar fields",
+		inMsg: &yangDirectory{
+			name: "MessageName",
+			entry: &yang.Entry{
+				Name: "message-name",
+				Kind: yang.DirectoryEntry,
+				Dir:  map[string]*yang.Entry{},
+				Parent: &yang.Entry{
+					Name: "container",
+					Kind: yang.DirectoryEntry,
+					Dir:  map[string]*yang.Entry{},
 					Parent: &yang.Entry{
-						Name: "surrounding-container",
+						Name: "module",
 						Kind: yang.DirectoryEntry,
-						Parent: &yang.Entry{
-							Name: "a-message",
-							Kind: yang.DirectoryEntry,
-							Parent: &yang.Entry{
-								Name: "module",
-								Kind: yang.DirectoryEntry,
-							},
-						},
+						Dir:  map[string]*yang.Entry{},
 					},
 				},
-				fields: map[string]*yang.Entry{
-					"keyfield": {
-						Name: "keyfield",
-						Type: &yang.YangType{
-							Kind: yang.Ystring,
-						},
-					},
+				Node: &yang.Container{Name: "message-name"},
+			},
+			fields: map[string]*yang.Entry{
+				"field-one": {
+					Name: "field-one",
+					Type: &yang.YangType{Kind: yang.Ystring},
 				},
 			},
+			path: []string{"", "module", "container", "message-name"},
 		},
-		inBasePackageName:      "base",
-		inEnumPackageName:      "enums",
-		inNestedMessages:       true,
-		inUniqueDirectoryNames: map[string]string{"/module/a-message/surrounding-container/list": "List"},
+		inBasePackageName: "base",
+		inEnumPackageName: "enums",
 		wantCompress: &generatedProto3Message{
-			PackageName: "",
+			PackageName: "container",
 			MessageCode: `
-message AMessage {
-  message ListKey {
-    string keyfield = 1;
-    List list = 2;
-  }
-  repeated ListKey list = 486198550;
+// MessageName represents the /module/container/message-name YANG schema element.
+// Defined in module module.
+message MessageName {
+  ywrapper.StringValue field_one = 410095931;
 }`,
 		},
 		wantUncompress: &generatedProto3Message{
-			PackageName: "module",
+			PackageName: "module.container",
 			MessageCode: `
-message AMessage {
-  message ListKey {
-    string keyfield = 1;
-    List list = 2;
-  }
-  repeated ListKey list = 486198550;
+// MessageName represents the /module/container/message-name YANG schema element.
+// Defined in module module.
+message MessageName {
+  ywrapper.StringValue field_one = 410095931;
 }`,
 		},
 	}, {
-		name: "simple message with unkeyed list - nested messages",
+		name: "simple message with schema path field",
 		inMsg: &yangDirectory{
-			name: "AMessage",
+			name: "MessageName",
 			entry: &yang.Entry{
-				Name: "a-message",
+				Name: "message-name",
 				Kind: yang.DirectoryEntry,
+				Dir:  map[string]*yang.Entry{},
 				Parent: &yang.Entry{
-					Name: "module",
+					Name: "container",
 					Kind: yang.DirectoryEntry,
-				},
-			},
-			fields: map[string]*yang.Entry{
-				"list": {
-					Name:     "list",
-					Kind:     yang.DirectoryEntry,
-					ListAttr: &yang.ListAttr{},
-					Dir: map[string]*yang.Entry{
-						"keyfield": {
-							Name: "keyfield",
-							Type: &yang.YangType{
-								Kind: yang.Ystring,
-							},
-						},
-					},
+					Dir:  map[string]*yang.Entry{},
 					Parent: &yang.Entry{
-						Name: "surrounding-container",
+						Name: "module",
 						Kind: yang.DirectoryEntry,
-						Parent: &yang.Entry{
-							Name: "a-message",
-							Kind: yang.DirectoryEntry,
-							Parent: &yang.Entry{
-								Name: "module",
-								Kind: yang.DirectoryEntry,
-							},
-						},
+						Dir:  map[string]*yang.Entry{},
 					},
 				},
+				Node: &yang.Container{Name: "message-name"},
 			},
-			path: []string{"", "module", "a-message"},
-		},
-		inMsgs: map[string]*yangDirectory{
-			"/module/a-message/surrounding-container/list": {
-				name: "List",
-				entry: &yang.Entry{
-					Name:     "list",
-					Kind:     yang.DirectoryEntry,
-					ListAttr: &yang.ListAttr{},
-					Dir: map[string]*yang.Entry{
-						"keyfield": {
-							Name: "keyfield",
-							Type: &yang.YangType{
-								Kind: yang.Ystring,
-							},
-						},
-					},
-					Parent: &yang.Entry{
-						Name: "surrounding-container",
-						Kind: yang.DirectoryEntry,
-						Parent: &yang.Entry{
-							Name: "a-message",
-							Kind: yang.DirectoryEntry,
-							Parent: &yang.Entry{
-								Name: "module",
-								Kind: yang.DirectoryEntry,
-							},
-						},
-					},
-				},
-				fields: map[string]*yang.Entry{
-					"keyfield": {
-						Name: "keyfield",
-						Type: &yang.YangType{
-							Kind: yang.Ystring,
-						},
-					},
+			fields: map[string]*yang.Entry{
+				"field-one": {
+					Name: "field-one",
+					Type: &yang.YangType{Kind: yang.Ystring},
 				},
 			},
+			path: []string{"", "module", "container", "message-name"},
 		},
-		inBasePackageName:      "base",
-		inEnumPackageName:      "enums",
-		inNestedMessages:       true,
-		inUniqueDirectoryNames: map[string]string{"/module/a-message/surrounding-container/list": "List"},
+		inBasePackageName: "base",
+		inEnumPackageName: "enums",
+		inSchemaPathField: true,
 		wantCompress: &generatedProto3Message{
-			PackageName: "",
+			PackageName: "container",
 			MessageCode: `
-message AMessage {
-  repeated List list = 486198550;
+// MessageName represents the /module/container/message-name YANG schema element.
+// Defined in module module.
+message MessageName {
+  // _schema_path stores the schema path of this message, /module/container/message-name.
+  string _schema_path = 123782659;
+  ywrapper.StringValue field_one = 410095931;
 }`,
 		},
 		wantUncompress: &generatedProto3Message{
-			PackageName: "module",
+			PackageName: "module.container",
 			MessageCode: `
-message AMessage {
-  repeated List list = 486198550;
+// MessageName represents the /module/container/message-name YANG schema element.
+// Defined in module module.
+message MessageName {
+  // _schema_path stores the schema path of this message, /module/container/message-name.
+  string _schema_path = 123782659;
+  ywrapper.StringValue field_one = 410095931;
 }`,
 		},
 	}, {
-		name: "message skipped due to path length",
-		inMsg: &yangDirectory{
-			name:  "messagename",
-			path:  []string{"one", "two"},
-			entry: &yang.Entry{},
-		},
-		inBasePackageName: "base",
-		inEnumPackageName: "enums",
-		inNestedMessages:  true,
-		wantCompress:      nil,
-		wantUncompress:    nil,
-	}, {
-		name: "simple message with an identityref leaf",
+		name: "simple message with proto2 syntax",
 		inMsg: &yangDirectory{
 			name: "MessageName",
 			entry: &yang.Entry{
 				Name: "message-name",
 				Kind: yang.DirectoryEntry,
+				Dir:  map[string]*yang.Entry{},
 				Parent: &yang.Entry{
-					Name: "module",
+					Name: "container",
 					Kind: yang.DirectoryEntry,
+					Dir:  map[string]*yang.Entry{},
+					Parent: &yang.Entry{
+						Name: "module",
+						Kind: yang.DirectoryEntry,
+						Dir:  map[string]*yang.Entry{},
+					},
+				},
+				Node: &yang.Container{Name: "message-name"},
+			},
+			fields: map[string]*yang.Entry{
+				"field-one": {
+					Name:      "field-one",
+					Type:      &yang.YangType{Kind: yang.Ystring},
+					Mandatory: yang.TSTrue,
+				},
+				"field-two": {
+					Name: "field-two",
+					Type: &yang.YangType{Kind: yang.Yuint8},
 				},
+				"field-three": {
+					Name:     "field-three",
+					Type:     &yang.YangType{Kind: yang.Ystring},
+					ListAttr: &yang.ListAttr{},
+				},
+			},
+			path: []string{"", "module", "container", "message-name"},
+		},
+		inBasePackageName: "base",
+		inEnumPackageName: "enums",
+		inProto2:          true,
+		wantCompress: &generatedProto3Message{
+			PackageName: "container",
+			MessageCode: `
+// MessageName represents the /module/container/message-name YANG schema element.
+// Defined in module module.
+message MessageName {
+  optional uint64 field_two = 25944937;
+  repeated string field_three = 151168411;
+  required string field_one = 410095931;
+}`,
+		},
+		wantUncompress: &generatedProto3Message{
+			PackageName: "module.container",
+			MessageCode: `
+// MessageName represents the /module/container/message-name YANG schema element.
+// Defined in module module.
+message MessageName {
+  optional uint64 field_two = 25944937;
+  repeated string field_three = 151168411;
+  required string field_one = 410095931;
+}`,
+		},
+	}, {
+		name: "simple message with reserved tags",
+		inMsg: &yangDirectory{
+			name: "MessageName",
+			entry: &yang.Entry{
+				Name: "message-name",
+				Kind: yang.DirectoryEntry,
+				Dir:  map[string]*yang.Entry{},
+				Parent: &yang.Entry{
+					Name: "container",
+					Kind: yang.DirectoryEntry,
+					Dir:  map[string]*yang.Entry{},
+					Parent: &yang.Entry{
+						Name: "module",
+						Kind: yang.DirectoryEntry,
+						Dir:  map[string]*yang.Entry{},
+					},
+				},
+				Node: &yang.Container{Name: "message-name"},
+			},
+			fields: map[string]*yang.Entry{
+				"field-one": {
+					Name: "field-one",
+					Type: &yang.YangType{Kind: yang.Ystring},
+				},
+			},
+			path: []string{"", "module", "container", "message-name"},
+		},
+		inBasePackageName: "base",
+		inEnumPackageName: "enums",
+		inReservedTags: map[string][]uint32{
+			"/module/container/message-name": {300000000, 410095931, 2000, 300000000},
+			"/module/container/other":        {42},
+		},
+		wantCompress: &generatedProto3Message{
+			PackageName: "container",
+			MessageCode: `
+// MessageName represents the /module/container/message-name YANG schema element.
+// Defined in module module.
+message MessageName {
+  ywrapper.StringValue field_one = 410095931;
+  reserved 2000;
+  reserved 300000000;
+}`,
+		},
+		wantUncompress: &generatedProto3Message{
+			PackageName: "module.container",
+			MessageCode: `
+// MessageName represents the /module/container/message-name YANG schema element.
+// Defined in module module.
+message MessageName {
+  ywrapper.StringValue field_one = 410095931;
+  reserved 2000;
+  reserved 300000000;
+}`,
+		},
+	}, {
+		name: "simple message with other messages embedded",
+		inMsg: &yangDirectory{
+			name: "MessageName",
+			entry: &yang.Entry{
+				Name: "message-name",
+				Kind: yang.DirectoryEntry,
+				Parent: &yang.Entry{
+					Name: "module",
+					Kind: yang.DirectoryEntry,
+				},
+			},
+			fields: map[string]*yang.Entry{
+				"child": {
+					Name: "child",
+					Kind: yang.DirectoryEntry,
+					Dir:  map[string]*yang.Entry{},
+					Parent: &yang.Entry{
+						Name: "message-name",
+						Kind: yang.DirectoryEntry,
+						Parent: &yang.Entry{
+							Name: "module",
+							Kind: yang.DirectoryEntry,
+						},
+					},
+				},
+			},
+			path: []string{"", "module", "message-name"},
+		},
+		inMsgs: map[string]*yangDirectory{
+			"/module/message-name/child": {
+				name: "Child",
+				entry: &yang.Entry{
+					Name: "child",
+					Kind: yang.DirectoryEntry,
+					Parent: &yang.Entry{
+						Name: "message-name",
+						Kind: yang.DirectoryEntry,
+						Parent: &yang.Entry{
+							Name: "module",
+							Kind: yang.DirectoryEntry,
+						},
+					},
+				},
+			},
+		},
+		inBasePackageName: "base",
+		inEnumPackageName: "enums",
+		wantCompress: &generatedProto3Message{
+			PackageName: "",
+			MessageCode: `
+// MessageName represents the /module/message-name YANG schema element.
+// Defined in module module.
+message MessageName {
+  message_name.Child child = 399980855;
+}`,
+			RequiredImports: []string{"base/message_name/message_name.proto"},
+		},
+		wantUncompress: &generatedProto3Message{
+			PackageName: "module",
+			MessageCode: `
+// MessageName represents the /module/message-name YANG schema element.
+// Defined in module module.
+message MessageName {
+  message_name.Child child = 399980855;
+}`,
+			RequiredImports: []string{"base/module/message_name/message_name.proto"},
+		},
+	}, {
+		name:              "message with two levels of embedded messages",
+		inMsg:             twoLevelMsg,
+		inMsgs:            twoLevelMsgs,
+		inBasePackageName: "base",
+		inEnumPackageName: "enums",
+		wantCompress: &generatedProto3Message{
+			PackageName: "",
+			MessageCode: `
+// Parent represents the /module/parent YANG schema element.
+// Defined in module module.
+message Parent {
+  parent.Child child = 54979980;
+}`,
+			RequiredImports: []string{"base/parent/parent.proto"},
+		},
+		wantUncompress: &generatedProto3Message{
+			PackageName: "module",
+			MessageCode: `
+// Parent represents the /module/parent YANG schema element.
+// Defined in module module.
+message Parent {
+  parent.Child child = 54979980;
+}`,
+			RequiredImports: []string{"base/module/parent/parent.proto"},
+		},
+	}, {
+		name:              "message with two levels of embedded messages - with nested messages",
+		inMsg:             twoLevelMsg,
+		inMsgs:            twoLevelMsgs,
+		inBasePackageName: "base",
+		inEnumPackageName: "enums",
+		inNestedMessages:  true,
+		wantCompress: &generatedProto3Message{
+			PackageName: "",
+			MessageCode: `
+message Parent {
+  message Child {
+    message Grandchild {
+      ywrapper.StringValue leaf = 293631826;
+    }
+    Grandchild grandchild = 374857955;
+  }
+  Child child = 54979980;
+}`,
+		},
+		wantUncompress: &generatedProto3Message{
+			PackageName: "module",
+			MessageCode: `
+message Parent {
+  message Child {
+    message Grandchild {
+      ywrapper.StringValue leaf = 293631826;
+    }
+    Grandchild grandchild = 374857955;
+  }
+  Child child = 54979980;
+}`,
+		},
+	}, {
+		name: "simple message with other messages embedded - with nested messages",
+		inMsg: &yangDirectory{
+			name: "MessageName",
+			entry: &yang.Entry{
+				Name: "message-name",
+				Kind: yang.DirectoryEntry,
+				Parent: &yang.Entry{
+					Name: "module",
+					Kind: yang.DirectoryEntry,
+				},
+			},
+			fields: map[string]*yang.Entry{
+				"child": {
+					Name: "child",
+					Kind: yang.DirectoryEntry,
+					Dir:  map[string]*yang.Entry{},
+					Parent: &yang.Entry{
+						Name: "message-name",
+						Kind: yang.DirectoryEntry,
+						Parent: &yang.Entry{
+							Name: "module",
+							Kind: yang.DirectoryEntry,
+						},
+					},
+				},
+			},
+			path: []string{"", "module", "message-name"},
+		},
+		inMsgs: map[string]*yangDirectory{
+			"/module/message-name/child": {
+				name: "Child",
+				entry: &yang.Entry{
+					Name: "child",
+					Kind: yang.DirectoryEntry,
+					Parent: &yang.Entry{
+						Name: "message-name",
+						Kind: yang.DirectoryEntry,
+						Parent: &yang.Entry{
+							Name: "module",
+							Kind: yang.DirectoryEntry,
+						},
+					},
+				},
+				path: []string{"", "module", "message-name", "child"},
+				fields: map[string]*yang.Entry{
+					"leaf": {
+						Name: "leaf",
+						Type: &yang.YangType{Kind: yang.Ystring},
+					},
+				},
+			},
+		},
+		inBasePackageName: "base",
+		inEnumPackageName: "enums",
+		inNestedMessages:  true,
+		wantCompress: &generatedProto3Message{
+			PackageName: "",
+			MessageCode: `
+message MessageName {
+  message Child {
+    ywrapper.StringValue leaf = 463279904;
+  }
+  Child child = 399980855;
+}`,
+		},
+		wantUncompress: &generatedProto3Message{
+			PackageName: "module",
+			MessageCode: `
+message MessageName {
+  message Child {
+    ywrapper.StringValue leaf = 463279904;
+  }
+  Child child = 399980855;
+}`,
+		},
+	}, {
+		name: "simple message with an enumeration leaf",
+		inMsg: &yangDirectory{
+			name: "MessageName",
+			entry: &yang.Entry{
+				Name: "message-name",
+				Kind: yang.DirectoryEntry,
+				Parent: &yang.Entry{
+					Name: "module",
+					Kind: yang.DirectoryEntry,
+				},
+			},
+			fields: map[string]*yang.Entry{
+				"enum": {
+					Name: "enum",
+					Kind: yang.LeafEntry,
+					Parent: &yang.Entry{
+						Name: "message-name",
+						Parent: &yang.Entry{
+							Name: "module",
+						},
+					},
+					Type: &yang.YangType{
+						Name: "enumeration",
+						Kind: yang.Yenum,
+						Enum: enumeratedLeafDef,
+					},
+				},
+			},
+			path: []string{"", "module", "message-name"},
+		},
+		inBasePackageName: "base",
+		inEnumPackageName: "enums",
+		wantCompress: &generatedProto3Message{
+			PackageName: "",
+			MessageCode: `
+// MessageName represents the /module/message-name YANG schema element.
+// Defined in module module.
+message MessageName {
+  enum Enum {
+    ENUM_UNSET = 0;
+    ENUM_ONE = 2;
+    ENUM_FORTYTWO = 43;
+  }
+  Enum enum = 278979784;
+}`,
+		},
+		wantUncompress: &generatedProto3Message{
+			PackageName: "module",
+			MessageCode: `
+// MessageName represents the /module/message-name YANG schema element.
+// Defined in module module.
+message MessageName {
+  enum Enum {
+    ENUM_UNSET = 0;
+    ENUM_ONE = 2;
+    ENUM_FORTYTWO = 43;
+  }
+  Enum enum = 278979784;
+}`,
+		},
+	}, {
+		name: "simple message with a list",
+		inMsg: &yangDirectory{
+			name: "AMessage",
+			entry: &yang.Entry{
+				Name: "a-message",
+				Kind: yang.DirectoryEntry,
+				Parent: &yang.Entry{
+					Name: "module",
+					Kind: yang.DirectoryEntry,
+				},
+			},
+			fields: map[string]*yang.Entry{
+				"list": {
+					Name:     "list",
+					Kind:     yang.DirectoryEntry,
+					ListAttr: &yang.ListAttr{},
+					Key:      "keyfield",
+					Dir: map[string]*yang.Entry{
+						"keyfield": {
+							Name: "keyfield",
+							Type: &yang.YangType{
+								Kind: yang.Ystring,
+							},
+						},
+					},
+					Parent: &yang.Entry{
+						Name: "surrounding-container",
+						Kind: yang.DirectoryEntry,
+						Parent: &yang.Entry{
+							Name: "a-message",
+							Kind: yang.DirectoryEntry,
+							Parent: &yang.Entry{
+								Name: "module",
+								Kind: yang.DirectoryEntry,
+							},
+						},
+					},
+				},
+			},
+		},
+		inMsgs: map[string]*yangDirectory{
+			"/module/a-message/surrounding-container/list": {
+				name: "List",
+				entry: &yang.Entry{
+					Name:     "list",
+					Kind:     yang.DirectoryEntry,
+					ListAttr: &yang.ListAttr{},
+					Key:      "keyfield",
+					Dir: map[string]*yang.Entry{
+						"keyfield": {
+							Name: "keyfield",
+							Type: &yang.YangType{
+								Kind: yang.Ystring,
+							},
+						},
+					},
+					Parent: &yang.Entry{
+						Name: "surrounding-container",
+						Kind: yang.DirectoryEntry,
+						Parent: &yang.Entry{
+							Name: "a-message",
+							Kind: yang.DirectoryEntry,
+							Parent: &yang.Entry{
+								Name: "module",
+								Kind: yang.DirectoryEntry,
+							},
+						},
+					},
+				},
+				fields: map[string]*yang.Entry{
+					"keyfield": {
+						Name: "keyfield",
+						Type: &yang.YangType{
+							Kind: yang.Ystring,
+						},
+					},
+				},
+			},
+		},
+		inBasePackageName:      "base",
+		inEnumPackageName:      "enums",
+		inUniqueDirectoryNames: map[string]string{"/module/a-message/surrounding-container/list": "List"},
+		wantCompress: &generatedProto3Message{
+			PackageName: "",
+			MessageCode: `
+// ListKey represents the /module/a-message/surrounding-container/list YANG schema element.
+// Defined in module module.
+message ListKey {
+  string keyfield = 1;
+  a_message.List list = 2;
+}
+
+// AMessage represents the  YANG schema element.
+// Defined in module module.
+message AMessage {
+  repeated ListKey list = 486198550;
+}`,
+			RequiredImports: []string{"base/a_message/a_message.proto"},
+		},
+		wantUncompress: &generatedProto3Message{
+			PackageName: "module",
+			MessageCode: `
+// ListKey represents the /module/a-message/surrounding-container/list YANG schema element.
+// Defined in module module.
+message ListKey {
+  string keyfield = 1;
+  a_message.surrounding_container.List list = 2;
+}
+
+// AMessage represents the  YANG schema element.
+// Defined in module module.
+message AMessage {
+  repeated ListKey list = 486198550;
+}`,
+			RequiredImports: []string{"base/module/a_message/surrounding_container/surrounding_container.proto"},
+		},
+	}, {
+		name: "simple message with a list - nested messages",
+		inMsg: &yangDirectory{
+			name: "AMessage",
+			entry: &yang.Entry{
+				Name: "a-message",
+				Kind: yang.DirectoryEntry,
+				Parent: &yang.Entry{
+					Name: "module",
+					Kind: yang.DirectoryEntry,
+				},
+			},
+			fields: map[string]*yang.Entry{
+				"list": {
+					Name:     "list",
+					Kind:     yang.DirectoryEntry,
+					ListAttr: &yang.ListAttr{},
+					Key:      "keyfield",
+					Dir: map[string]*yang.Entry{
+						"keyfield": {
+							Name: "keyfield",
+							Type: &yang.YangType{
+								Kind: yang.Ystring,
+							},
+						},
+					},
+					Parent: &yang.Entry{
+						Name: "surrounding-container",
+						Kind: yang.DirectoryEntry,
+						Parent: &yang.Entry{
+							Name: "a-message",
+							Kind: yang.DirectoryEntry,
+							Parent: &yang.Entry{
+								Name: "module",
+								Kind: yang.DirectoryEntry,
+							},
+						},
+					},
+				},
+			},
+			path: []string{"", "module", "a-message"},
+		},
+		inMsgs: map[string]*yangDirectory{
+			"/module/a-message/surrounding-container/list": {
+				name: "List",
+				entry: &yang.Entry{
+					Name:     "list",
+					Kind:     yang.DirectoryEntry,
+					ListAttr: &yang.ListAttr{},
+					Key:      "keyfield",
+					Dir: map[string]*yang.Entry{
+						"keyfield": {
+							Name: "keyfield",
+							Type: &yang.YangType{
+								Kind: yang.Ystring,
+							},
+						},
+					},
+					Parent: &yang.Entry{
+						Name: "surrounding-container",
+						Kind: yang.DirectoryEntry,
+						Parent: &yang.Entry{
+							Name: "a-message",
+							Kind: yang.DirectoryEntry,
+							Parent: &yang.Entry{
+								Name: "module",
+								Kind: yang.DirectoryEntry,
+							},
+						},
+					},
+				},
+				fields: map[string]*yang.Entry{
+					"keyfield": {
+						Name: "keyfield",
+						Type: &yang.YangType{
+							Kind: yang.Ystring,
+						},
+					},
+				},
+			},
+		},
+		inBasePackageName:      "base",
+		inEnumPackageName:      "enums",
+		inNestedMessages:       true,
+		inUniqueDirectoryNames: map[string]string{"/module/a-message/surrounding-container/list": "List"},
+		wantCompress: &generatedProto3Message{
+			PackageName: "",
+			MessageCode: `
+message AMessage {
+  message ListKey {
+    string keyfield = 1;
+    List list = 2;
+  }
+  repeated ListKey list = 486198550;
+}`,
+		},
+		wantUncompress: &generatedProto3Message{
+			PackageName: "module",
+			MessageCode: `
+message AMessage {
+  message ListKey {
+    string keyfield = 1;
+    List list = 2;
+  }
+  repeated ListKey list = 486198550;
+}`,
+		},
+	}, {
+		name: "simple message with unkeyed list - nested messages",
+		inMsg: &yangDirectory{
+			name: "AMessage",
+			entry: &yang.Entry{
+				Name: "a-message",
+				Kind: yang.DirectoryEntry,
+				Parent: &yang.Entry{
+					Name: "module",
+					Kind: yang.DirectoryEntry,
+				},
+			},
+			fields: map[string]*yang.Entry{
+				"list": {
+					Name:     "list",
+					Kind:     yang.DirectoryEntry,
+					ListAttr: &yang.ListAttr{},
+					Dir: map[string]*yang.Entry{
+						"keyfield": {
+							Name: "keyfield",
+							Type: &yang.YangType{
+								Kind: yang.Ystring,
+							},
+						},
+					},
+					Parent: &yang.Entry{
+						Name: "surrounding-container",
+						Kind: yang.DirectoryEntry,
+						Parent: &yang.Entry{
+							Name: "a-message",
+							Kind: yang.DirectoryEntry,
+							Parent: &yang.Entry{
+								Name: "module",
+								Kind: yang.DirectoryEntry,
+							},
+						},
+					},
+				},
+			},
+			path: []string{"", "module", "a-message"},
+		},
+		inMsgs: map[string]*yangDirectory{
+			"/module/a-message/surrounding-container/list": {
+				name: "List",
+				entry: &yang.Entry{
+					Name:     "list",
+					Kind:     yang.DirectoryEntry,
+					ListAttr: &yang.ListAttr{},
+					Dir: map[string]*yang.Entry{
+						"keyfield": {
+							Name: "keyfield",
+							Type: &yang.YangType{
+								Kind: yang.Ystring,
+							},
+						},
+					},
+					Parent: &yang.Entry{
+						Name: "surrounding-container",
+						Kind: yang.DirectoryEntry,
+						Parent: &yang.Entry{
+							Name: "a-message",
+							Kind: yang.DirectoryEntry,
+							Parent: &yang.Entry{
+								Name: "module",
+								Kind: yang.DirectoryEntry,
+							},
+						},
+					},
+				},
+				fields: map[string]*yang.Entry{
+					"keyfield": {
+						Name: "keyfield",
+						Type: &yang.YangType{
+							Kind: yang.Ystring,
+						},
+					},
+				},
+			},
+		},
+		inBasePackageName:      "base",
+		inEnumPackageName:      "enums",
+		inNestedMessages:       true,
+		inUniqueDirectoryNames: map[string]string{"/module/a-message/surrounding-container/list": "List"},
+		wantCompress: &generatedProto3Message{
+			PackageName: "",
+			MessageCode: `
+message AMessage {
+  repeated List list = 486198550;
+}`,
+		},
+		wantUncompress: &generatedProto3Message{
+			PackageName: "module",
+			MessageCode: `
+message AMessage {
+  repeated List list = 486198550;
+}`,
+		},
+	}, {
+		name: "message skipped due to path length",
+		inMsg: &yangDirectory{
+			name:  "messagename",
+			path:  []string{"one", "two"},
+			entry: &yang.Entry{},
+		},
+		inBasePackageName: "base",
+		inEnumPackageName: "enums",
+		inNestedMessages:  true,
+		wantCompress:      nil,
+		wantUncompress:    nil,
+	}, {
+		name: "simple message with an identityref leaf",
+		inMsg: &yangDirectory{
+			name: "MessageName",
+			entry: &yang.Entry{
+				Name: "message-name",
+				Kind: yang.DirectoryEntry,
+				Parent: &yang.Entry{
+					Name: "module",
+					Kind: yang.DirectoryEntry,
+				},
+			},
+			fields: map[string]*yang.Entry{
+				"identityref": {
+					Name: "identityref",
+					Kind: yang.LeafEntry,
+					Parent: &yang.Entry{
+						Name: "message-name",
+						Parent: &yang.Entry{
+							Name: "module",
+						},
+					},
+					Type: &yang.YangType{
+						Name: "identityref",
+						Kind: yang.Yidentityref,
+						IdentityBase: &yang.Identity{
+							Name: "foo-identity",
+							Values: []*yang.Identity{
+								{Name: "ONE"},
+								{Name: "TWO"},
+							},
+							Parent: &yang.Module{
+								Name: "test-module",
+							},
+						},
+					},
+				},
+			},
+			path: []string{"", "module-name", "message-name"},
+		},
+		inBasePackageName: "base",
+		inEnumPackageName: "enums",
+		wantCompress: &generatedProto3Message{
+			PackageName: "",
+			MessageCode: `
+// MessageName represents the /module-name/message-name YANG schema element.
+// Defined in module module.
+message MessageName {
+  base.enums.TestModuleFooIdentity identityref = 518954308;
+}`,
+			RequiredImports: []string{"base/enums/enums.proto"},
+		},
+		wantUncompress: &generatedProto3Message{
+			PackageName: "module",
+			MessageCode: `
+// MessageName represents the /module-name/message-name YANG schema element.
+// Defined in module module.
+message MessageName {
+  base.enums.TestModuleFooIdentity identityref = 518954308;
+}`,
+			RequiredImports: []string{"base/enums/enums.proto"},
+		},
+	}, {
+		name: "simple message with an identityref leaf-list",
+		inMsg: &yangDirectory{
+			name: "MessageName",
+			entry: &yang.Entry{
+				Name: "message-name",
+				Kind: yang.DirectoryEntry,
+				Parent: &yang.Entry{
+					Name: "module",
+					Kind: yang.DirectoryEntry,
+				},
+			},
+			fields: map[string]*yang.Entry{
+				"identityref": {
+					Name:     "identityref",
+					Kind:     yang.LeafEntry,
+					ListAttr: &yang.ListAttr{},
+					Parent: &yang.Entry{
+						Name: "message-name",
+						Parent: &yang.Entry{
+							Name: "module",
+						},
+					},
+					Type: &yang.YangType{
+						Name: "identityref",
+						Kind: yang.Yidentityref,
+						IdentityBase: &yang.Identity{
+							Name: "foo-identity",
+							Values: []*yang.Identity{
+								{Name: "ONE"},
+								{Name: "TWO"},
+							},
+							Parent: &yang.Module{
+								Name: "test-module",
+							},
+						},
+					},
+				},
+			},
+			path: []string{"", "module-name", "message-name"},
+		},
+		inBasePackageName: "base",
+		inEnumPackageName: "enums",
+		wantCompress: &generatedProto3Message{
+			PackageName: "",
+			MessageCode: `
+// MessageName represents the /module-name/message-name YANG schema element.
+// Defined in module module.
+message MessageName {
+  repeated base.enums.TestModuleFooIdentity identityref = 518954308;
+}`,
+			RequiredImports: []string{"base/enums/enums.proto"},
+		},
+		wantUncompress: &generatedProto3Message{
+			PackageName: "module",
+			MessageCode: `
+// MessageName represents the /module-name/message-name YANG schema element.
+// Defined in module module.
+message MessageName {
+  repeated base.enums.TestModuleFooIdentity identityref = 518954308;
+}`,
+			RequiredImports: []string{"base/enums/enums.proto"},
+		},
+	}, {
+		name: "simple message with an identityref leaf mapped to a string",
+		inMsg: &yangDirectory{
+			name: "MessageName",
+			entry: &yang.Entry{
+				Name: "message-name",
+				Kind: yang.DirectoryEntry,
+				Parent: &yang.Entry{
+					Name: "module",
+					Kind: yang.DirectoryEntry,
+				},
+			},
+			fields: map[string]*yang.Entry{
+				"identityref": {
+					Name: "identityref",
+					Kind: yang.LeafEntry,
+					Parent: &yang.Entry{
+						Name: "message-name",
+						Parent: &yang.Entry{
+							Name: "module",
+						},
+					},
+					Type: &yang.YangType{
+						Name: "identityref",
+						Kind: yang.Yidentityref,
+						IdentityBase: &yang.Identity{
+							Name: "foo-identity",
+							Values: []*yang.Identity{
+								{Name: "ONE"},
+								{Name: "TWO"},
+							},
+							Parent: &yang.Module{
+								Name: "test-module",
+							},
+						},
+					},
+				},
+			},
+			path: []string{"", "module-name", "message-name"},
+		},
+		inBasePackageName:     "base",
+		inEnumPackageName:     "enums",
+		inIdentityrefAsString: true,
+		wantCompress: &generatedProto3Message{
+			PackageName: "",
+			MessageCode: `
+// MessageName represents the /module-name/message-name YANG schema element.
+// Defined in module module.
+message MessageName {
+  ywrapper.StringValue identityref = 518954308 [(yext.identity_base) = "test-module:foo-identity"];
+}`,
+		},
+		wantUncompress: &generatedProto3Message{
+			PackageName: "module",
+			MessageCode: `
+// MessageName represents the /module-name/message-name YANG schema element.
+// Defined in module module.
+message MessageName {
+  ywrapper.StringValue identityref = 518954308 [(yext.identity_base) = "test-module:foo-identity"];
+}`,
+		},
+	}, {
+		name: "container with an empty leaf",
+		inMsg: &yangDirectory{
+			name: "Container",
+			entry: &yang.Entry{
+				Name: "container",
+				Kind: yang.DirectoryEntry,
+				Parent: &yang.Entry{
+					Name: "module",
+					Kind: yang.DirectoryEntry,
+				},
+			},
+			fields: map[string]*yang.Entry{
+				"enabled": {
+					Name: "enabled",
+					Kind: yang.LeafEntry,
+					Parent: &yang.Entry{
+						Name: "container",
+						Parent: &yang.Entry{
+							Name: "module",
+						},
+					},
+					Type: &yang.YangType{
+						Name: "empty",
+						Kind: yang.Yempty,
+					},
+				},
+			},
+			path: []string{"", "module", "container"},
+		},
+		inBasePackageName: "base",
+		inEnumPackageName: "enums",
+		wantCompress: &generatedProto3Message{
+			PackageName: "",
+			MessageCode: `
+// Container represents the /module/container YANG schema element.
+// Defined in module module.
+message Container {
+  // enabled represents a YANG empty leaf, and is set to true when the leaf is present.
+  ywrapper.BoolValue enabled = 55079946;
+}`,
+		},
+		wantUncompress: &generatedProto3Message{
+			PackageName: "module",
+			MessageCode: `
+// Container represents the /module/container YANG schema element.
+// Defined in module module.
+message Container {
+  // enabled represents a YANG empty leaf, and is set to true when the leaf is present.
+  ywrapper.BoolValue enabled = 55079946;
+}`,
+		},
+	}, {
+		name: "simple message with a bits leaf",
+		inMsg: &yangDirectory{
+			name: "MessageName",
+			entry: &yang.Entry{
+				Name: "message-name",
+				Kind: yang.DirectoryEntry,
+				Parent: &yang.Entry{
+					Name: "module",
+					Kind: yang.DirectoryEntry,
+				},
+			},
+			fields: map[string]*yang.Entry{
+				"flags": {
+					Name: "flags",
+					Kind: yang.LeafEntry,
+					Parent: &yang.Entry{
+						Name: "message-name",
+						Parent: &yang.Entry{
+							Name: "module",
+						},
+					},
+					Type: &yang.YangType{
+						Name: "bits",
+						Kind: yang.Ybits,
+						Bit:  bitsLeafDef,
+					},
+				},
+			},
+			path: []string{"", "module-name", "message-name"},
+		},
+		inBasePackageName: "base",
+		inEnumPackageName: "enums",
+		wantCompress: &generatedProto3Message{
+			PackageName: "",
+			MessageCode: `
+// MessageName represents the /module-name/message-name YANG schema element.
+// Defined in module module.
+message MessageName {
+  enum Flags {
+    FLAGS_up = 0;
+    FLAGS_running = 3;
+    FLAGS_lower_layer_down = 7;
+  }
+  ywrapper.UintValue flags = 502072460;
+}`,
+		},
+		wantUncompress: &generatedProto3Message{
+			PackageName: "module",
+			MessageCode: `
+// MessageName represents the /module-name/message-name YANG schema element.
+// Defined in module module.
+message MessageName {
+  enum Flags {
+    FLAGS_up = 0;
+    FLAGS_running = 3;
+    FLAGS_lower_layer_down = 7;
+  }
+  ywrapper.UintValue flags = 502072460;
+}`,
+		},
+	}, {
+		name: "simple message with decimal64 leaf and leaf-list",
+		inMsg: &yangDirectory{
+			name: "MessageName",
+			entry: &yang.Entry{
+				Name: "message-name",
+				Kind: yang.DirectoryEntry,
+				Parent: &yang.Entry{
+					Name: "module",
+					Kind: yang.DirectoryEntry,
+				},
+			},
+			fields: map[string]*yang.Entry{
+				"counter": {
+					Name: "counter",
+					Kind: yang.LeafEntry,
+					Parent: &yang.Entry{
+						Name: "message-name",
+						Parent: &yang.Entry{
+							Name: "module",
+						},
+					},
+					Type: &yang.YangType{
+						Kind:           yang.Ydecimal64,
+						FractionDigits: 2,
+					},
+				},
+				"samples": {
+					Name:     "samples",
+					Kind:     yang.LeafEntry,
+					ListAttr: &yang.ListAttr{},
+					Parent: &yang.Entry{
+						Name: "message-name",
+						Parent: &yang.Entry{
+							Name: "module",
+						},
+					},
+					Type: &yang.YangType{
+						Kind:           yang.Ydecimal64,
+						FractionDigits: 18,
+					},
+				},
+			},
+			path: []string{"", "module-name", "message-name"},
+		},
+		inBasePackageName: "base",
+		inEnumPackageName: "enums",
+		wantCompress: &generatedProto3Message{
+			PackageName: "",
+			MessageCode: `
+// MessageName represents the /module-name/message-name YANG schema element.
+// Defined in module module.
+message MessageName {
+  repeated ywrapper.Decimal64Value samples = 283381836 [(yext.fraction_digits) = 18];
+  ywrapper.Decimal64Value counter = 420243049 [(yext.fraction_digits) = 2];
+}`,
+		},
+		wantUncompress: &generatedProto3Message{
+			PackageName: "module",
+			MessageCode: `
+// MessageName represents the /module-name/message-name YANG schema element.
+// Defined in module module.
+message MessageName {
+  repeated ywrapper.Decimal64Value samples = 283381836 [(yext.fraction_digits) = 18];
+  ywrapper.Decimal64Value counter = 420243049 [(yext.fraction_digits) = 2];
+}`,
+		},
+	}, {
+		name: "simple message with an identityref leaf with identities in a separate file",
+		inMsg: &yangDirectory{
+			name: "MessageName",
+			entry: &yang.Entry{
+				Name: "message-name",
+				Kind: yang.DirectoryEntry,
+				Parent: &yang.Entry{
+					Name: "module",
+					Kind: yang.DirectoryEntry,
+				},
+			},
+			fields: map[string]*yang.Entry{
+				"identityref": {
+					Name: "identityref",
+					Kind: yang.LeafEntry,
+					Parent: &yang.Entry{
+						Name: "message-name",
+						Parent: &yang.Entry{
+							Name: "module",
+						},
+					},
+					Type: &yang.YangType{
+						Name: "identityref",
+						Kind: yang.Yidentityref,
+						IdentityBase: &yang.Identity{
+							Name: "foo-identity",
+							Values: []*yang.Identity{
+								{Name: "ONE"},
+								{Name: "TWO"},
+							},
+							Parent: &yang.Module{
+								Name: "test-module",
+							},
+						},
+					},
+				},
+			},
+			path: []string{"", "module-name", "message-name"},
+		},
+		inBasePackageName: "base",
+		inEnumPackageName: "enums",
+		inIdentitiesFile:  true,
+		wantCompress: &generatedProto3Message{
+			PackageName: "",
+			MessageCode: `
+// MessageName represents the /module-name/message-name YANG schema element.
+// Defined in module module.
+message MessageName {
+  base.identities.TestModuleFooIdentity identityref = 518954308;
+}`,
+			RequiredImports: []string{"base/identities/identities.proto"},
+		},
+		wantUncompress: &generatedProto3Message{
+			PackageName: "module",
+			MessageCode: `
+// MessageName represents the /module-name/message-name YANG schema element.
+// Defined in module module.
+message MessageName {
+  base.identities.TestModuleFooIdentity identityref = 518954308;
+}`,
+			RequiredImports: []string{"base/identities/identities.proto"},
+		},
+	}, {
+		name: "nested message with identityref and enumerated typedef leaves",
+		inMsg: &yangDirectory{
+			name: "MessageName",
+			entry: &yang.Entry{
+				Name: "message-name",
+				Kind: yang.DirectoryEntry,
+				Parent: &yang.Entry{
+					Name: "module",
+					Kind: yang.DirectoryEntry,
+				},
+			},
+			fields: map[string]*yang.Entry{
+				"identityref": {
+					Name: "identityref",
+					Kind: yang.LeafEntry,
+					Parent: &yang.Entry{
+						Name: "message-name",
+						Parent: &yang.Entry{
+							Name: "module",
+						},
+					},
+					Type: &yang.YangType{
+						Name: "identityref",
+						Kind: yang.Yidentityref,
+						IdentityBase: &yang.Identity{
+							Name: "foo-identity",
+							Values: []*yang.Identity{
+								{Name: "ONE"},
+								{Name: "TWO"},
+							},
+							Parent: &yang.Module{
+								Name: "test-module",
+							},
+						},
+					},
+				},
+				"derived-enum": {
+					Name: "derived-enum",
+					Kind: yang.LeafEntry,
+					Parent: &yang.Entry{
+						Name: "message-name",
+						Parent: &yang.Entry{
+							Name: "module",
+						},
+					},
+					Type: &yang.YangType{
+						Kind: yang.Yenum,
+						Name: "derived-enum",
+						Enum: &yang.EnumType{},
+					},
+					Node: &yang.Leaf{
+						Name: "derived-enum",
+						Parent: &yang.Module{
+							Name: "base",
+						},
+					},
+				},
+			},
+			path: []string{"", "module-name", "message-name"},
+		},
+		inBasePackageName: "base",
+		inEnumPackageName: "enums",
+		inBaseImportPath:  "github.com/foo/bar",
+		inIdentitiesFile:  true,
+		inNestedMessages:  true,
+		wantCompress: &generatedProto3Message{
+			PackageName: "",
+			MessageCode: `
+message MessageName {
+  base.enums.BaseDerivedEnum derived_enum = 293184780;
+  base.identities.TestModuleFooIdentity identityref = 518954308;
+}`,
+			RequiredImports: []string{"github.com/foo/bar/base/enums/enums.proto", "github.com/foo/bar/base/identities/identities.proto"},
+		},
+		wantUncompress: &generatedProto3Message{
+			PackageName: "module",
+			MessageCode: `
+message MessageName {
+  base.enums.BaseDerivedEnum derived_enum = 293184780;
+  base.identities.TestModuleFooIdentity identityref = 518954308;
+}`,
+			RequiredImports: []string{"github.com/foo/bar/base/enums/enums.proto", "github.com/foo/bar/base/identities/identities.proto"},
+		},
+	}}
+
+	for _, tt := range tests {
+		wantErr := map[bool]bool{true: tt.wantCompressErr, false: tt.wantUncompressErr}
+		for compress, want := range map[bool]*generatedProto3Message{true: tt.wantCompress, false: tt.wantUncompress} {
+			s := newGenState()
+			// Seed the message names with the supplied input.
+			s.uniqueDirectoryNames = tt.inUniqueDirectoryNames
+
+			got, errs := writeProto3Msg(tt.inMsg, tt.inMsgs, s, &protoMsgConfig{
+				compressPaths:       compress,
+				basePackageName:     tt.inBasePackageName,
+				enumPackageName:     tt.inEnumPackageName,
+				baseImportPath:      tt.inBaseImportPath,
+				nestedMessages:      tt.inNestedMessages,
+				identityrefAsString: tt.inIdentityrefAsString,
+				identitiesFile:      tt.inIdentitiesFile,
+				schemaPathField:     tt.inSchemaPathField,
+				reservedTags:        tt.inReservedTags,
+				proto2:              tt.inProto2,
+			})
+
+			if (errs != nil) != wantErr[compress] {
+				t.Errorf("%s: writeProto3Msg(%v, %v, %v, %v): did not get expected error return status, got: %v, wanted error: %v", tt.name, tt.inMsg, tt.inMsgs, s, compress, errs, wantErr[compress])
+			}
+
+			if errs != nil || got == nil {
+				continue
+			}
+
+			if got.PackageName != want.PackageName {
+				t.Errorf("%s: writeProto3Msg(%v, %v, %v, %v): did not get expected package name, got: %v, want: %v", tt.name, tt.inMsg, tt.inMsgs, s, compress, got.PackageName, want.PackageName)
+			}
+
+			if !reflect.DeepEqual(got.RequiredImports, want.RequiredImports) {
+				t.Errorf("%s: writeProto3Msg(%v, %v, %v, %v): did not get expected set of imports, got: %v, want: %v", tt.name, tt.inMsg, tt.inMsgs, s, compress, got.RequiredImports, want.RequiredImports)
+			}
+
+			if diff := pretty.Compare(got.MessageCode, want.MessageCode); diff != "" {
+				if diffl, err := testutil.GenerateUnifiedDiff(got.MessageCode, want.MessageCode); err == nil {
+					diff = diffl
+				}
+				t.Errorf("%s: writeProto3Msg(%v, %v, %v, %v): did not get expected message returned, diff(-got,+want):\n%s", tt.name, tt.inMsg, tt.inMsgs, s, compress, diff)
+			}
+		}
+	}
+}
+
+func TestWriteProto3HeaderAbbreviatedPackage(t *testing.T) {
+	tests := []struct {
+		name              string
+		inAbbreviatedFrom string
+		wantComment       bool
+	}{{
+		name: "package name not abbreviated",
+	}, {
+		name:              "package name abbreviated",
+		inAbbreviatedFrom: "base.module.a.b.c",
+		wantComment:       true,
+	}}
+
+	for _, tt := range tests {
+		got, err := writeProto3Header(proto3Header{
+			PackageName:     "base.module.c_619a1fa8",
+			YwrapperPath:    DefaultYwrapperPath,
+			YextPath:        DefaultYextPath,
+			AbbreviatedFrom: tt.inAbbreviatedFrom,
+		})
+		if err != nil {
+			t.Errorf("%s: writeProto3Header(...): got unexpected error: %v", tt.name, err)
+			continue
+		}
+		comment := "// The name of this package is an abbreviation of base.module.a.b.c.\nsyntax"
+		if gotComment := strings.Contains(got, comment); gotComment != tt.wantComment {
+			t.Errorf("%s: writeProto3Header(...): did not get expected comment, got header:\n%s\nwant comment: %v", tt.name, got, tt.wantComment)
+		}
+	}
+}
+
+func TestWriteProto3HeaderSyntax(t *testing.T) {
+	tests := []struct {
+		name       string
+		inProto2   bool
+		wantSyntax string
+	}{{
+		name:       "proto3",
+		wantSyntax: `syntax = "proto3";`,
+	}, {
+		name:       "proto2",
+		inProto2:   true,
+		wantSyntax: `syntax = "proto2";`,
+	}}
+
+	for _, tt := range tests {
+		got, err := writeProto3Header(proto3Header{
+			PackageName:  "pkg",
+			YwrapperPath: DefaultYwrapperPath,
+			YextPath:     DefaultYextPath,
+			Proto2:       tt.inProto2,
+		})
+		if err != nil {
+			t.Errorf("%s: writeProto3Header(...): got unexpected error: %v", tt.name, err)
+			continue
+		}
+		if !strings.Contains(got, tt.wantSyntax) {
+			t.Errorf("%s: writeProto3Header(...): did not get expected syntax %s, got:\n%s", tt.name, tt.wantSyntax, got)
+		}
+	}
+}
+
+func TestWriteProto3HeaderImports(t *testing.T) {
+	tests := []struct {
+		name        string
+		inImports   []string
+		wantImports []string
+	}{{
+		name: "no imports",
+	}, {
+		name:        "sorted imports",
+		inImports:   []string{"base/a.proto", "base/b.proto"},
+		wantImports: []string{"base/a.proto", "base/b.proto"},
+	}, {
+		name:        "unsorted, duplicated imports",
+		inImports:   []string{"base/c.proto", "base/a.proto", "base/c.proto", "base/b/b.proto", "base/a.proto"},
+		wantImports: []string{"base/a.proto", "base/b/b.proto", "base/c.proto"},
+	}}
+
+	for _, tt := range tests {
+		in := append([]string{}, tt.inImports...)
+		got, err := writeProto3Header(proto3Header{
+			PackageName:  "pkg",
+			YwrapperPath: DefaultYwrapperPath,
+			YextPath:     DefaultYextPath,
+			Imports:      in,
+		})
+		if err != nil {
+			t.Errorf("%s: writeProto3Header(...): got unexpected error: %v", tt.name, err)
+			continue
+		}
+
+		var gotImports []string
+		for _, l := range strings.Split(got, "\n") {
+			if strings.HasPrefix(l, `import "base/`) {
+				gotImports = append(gotImports, strings.TrimSuffix(strings.TrimPrefix(l, `import "`), `";`))
+			}
+		}
+		if diff := pretty.Compare(gotImports, tt.wantImports); diff != "" {
+			t.Errorf("%s: writeProto3Header(...): did not get expected imports, diff(-got,+want):\n%s", tt.name, diff)
+		}
+		if diff := pretty.Compare(in, tt.inImports); diff != "" {
+			t.Errorf("%s: writeProto3Header(...): input imports were modified, diff(-got,+want):\n%s", tt.name, diff)
+		}
+	}
+}
+
+func TestWriteProto3HeaderGoogleWrappers(t *testing.T) {
+	wrappersImport := `import "google/protobuf/wrappers.proto";`
+	for _, google := range []bool{false, true} {
+		got, err := writeProto3Header(proto3Header{
+			PackageName:    "pkg",
+			YwrapperPath:   DefaultYwrapperPath,
+			YextPath:       DefaultYextPath,
+			GoogleWrappers: google,
+		})
+		if err != nil {
+			t.Errorf("writeProto3Header(...), GoogleWrappers: %v: got unexpected error: %v", google, err)
+			continue
+		}
+		if gotImport := strings.Contains(got, wrappersImport); gotImport != google {
+			t.Errorf("writeProto3Header(...), GoogleWrappers: %v: did not get expected import of wrappers.proto, got:\n%s", google, got)
+		}
+	}
+}
+
+func TestWriteProto3HeaderPackageOptions(t *testing.T) {
+	tests := []struct {
+		name          string
+		inGoPackage   string
+		inJavaPackage string
+		want          string
+	}{{
+		name: "no package options",
+		want: `package base.child;
+
+import "ywrapper/ywrapper.proto";`,
+	}, {
+		name:        "go package",
+		inGoPackage: "github.com/foo/bar/base/child",
+		want: `package base.child;
+
+option go_package = "github.com/foo/bar/base/child";
+
+import "ywrapper/ywrapper.proto";`,
+	}, {
+		name:          "java package",
+		inJavaPackage: "com.example.base.child",
+		want: `package base.child;
+
+option java_package = "com.example.base.child";
+
+import "ywrapper/ywrapper.proto";`,
+	}, {
+		name:          "go and java package",
+		inGoPackage:   "github.com/foo/bar/base/child",
+		inJavaPackage: "com.example.base.child",
+		want: `package base.child;
+
+option go_package = "github.com/foo/bar/base/child";
+option java_package = "com.example.base.child";
+
+import "ywrapper/ywrapper.proto";`,
+	}}
+
+	for _, tt := range tests {
+		got, err := writeProto3Header(proto3Header{
+			PackageName:  "base.child",
+			YwrapperPath: "ywrapper",
+			YextPath:     "yext",
+			GoPackage:    tt.inGoPackage,
+			JavaPackage:  tt.inJavaPackage,
+		})
+		if err != nil {
+			t.Errorf("%s: writeProto3Header(...): got unexpected error: %v", tt.name, err)
+			continue
+		}
+		if !strings.Contains(got, tt.want) {
+			t.Errorf("%s: writeProto3Header(...): did not get expected package options, got:\n%s\nwant:\n%s", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestGenProtoBitsEnum(t *testing.T) {
+	bits := func(positions map[string]int64) *yang.EnumType {
+		b := yang.NewBitfield()
+		for n, p := range positions {
+			b.Set(n, p)
+		}
+		return b
+	}
+
+	tests := []struct {
+		name                string
+		inType              *yang.YangType
+		inUpperSnakeEnums   bool
+		inAnnotateEnumNames bool
+		want                *protoMsgEnum
+		wantErr             bool
+	}{{
+		name: "bits with a bit at position zero",
+		inType: &yang.YangType{
+			Name: "bits",
+			Kind: yang.Ybits,
+			Bit:  bits(map[string]int64{"up": 0, "running": 3, "lower-layer-down": 7}),
+		},
+		want: &protoMsgEnum{
+			Values: map[int64]protoEnumValue{
+				0: {ProtoLabel: "up"},
+				3: {ProtoLabel: "running"},
+				7: {ProtoLabel: "lower_layer_down"},
+			},
+		},
+	}, {
+		name: "bits without a bit at position zero",
+		inType: &yang.YangType{
+			Name: "bits",
+			Kind: yang.Ybits,
+			Bit:  bits(map[string]int64{"one": 1, "two": 2, "sixty-three": 63}),
+		},
+		inUpperSnakeEnums:   true,
+		inAnnotateEnumNames: true,
+		want: &protoMsgEnum{
+			Values: map[int64]protoEnumValue{
+				0:  {ProtoLabel: "UNSET"},
+				1:  {ProtoLabel: "ONE", YANGLabel: "one"},
+				2:  {ProtoLabel: "TWO", YANGLabel: "two"},
+				63: {ProtoLabel: "SIXTY_THREE", YANGLabel: "sixty-three"},
+			},
+		},
+	}, {
+		name: "bit position that cannot be represented",
+		inType: &yang.YangType{
+			Name: "bits",
+			Kind: yang.Ybits,
+			Bit:  bits(map[string]int64{"one": 1, "sixty-four": 64}),
+		},
+		wantErr: true,
+	}, {
+		name:    "bits type without bits",
+		inType:  &yang.YangType{Name: "bits", Kind: yang.Ybits},
+		wantErr: true,
+	}}
+
+	for _, tt := range tests {
+		got, err := genProtoBitsEnum(tt.inType, &protoMsgConfig{
+			upperSnakeEnums:   tt.inUpperSnakeEnums,
+			annotateEnumNames: tt.inAnnotateEnumNames,
+		})
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: genProtoBitsEnum(%v): did not get expected error status, got: %v, wantErr: %v", tt.name, tt.inType, err, tt.wantErr)
+		}
+		if err != nil {
+			continue
+		}
+
+		if diff := pretty.Compare(got, tt.want); diff != "" {
+			t.Errorf("%s: genProtoBitsEnum(%v): did not get expected enum, diff(-got,+want):\n%s", tt.name, tt.inType, diff)
+		}
+	}
+}
+
+func TestGenListKeyProto(t *testing.T) {
+	tests := []struct {
+		name          string
+		inListPackage string
+		inListName    string
+		inArgs        *protoDefinitionArgs
+		wantMsg       *protoMsg
+		wantErr       bool
+	}{{
+		name:          "simple list key proto",
+		inListPackage: "pkg",
+		inListName:    "list",
+		inArgs: &protoDefinitionArgs{
+			field: &yang.Entry{
+				Name:     "list",
+				Kind:     yang.DirectoryEntry,
+				ListAttr: &yang.ListAttr{},
+				Key:      "key",
+				Dir:      map[string]*yang.Entry{},
+			},
+			directory: &yangDirectory{
+				name: "List",
+				fields: map[string]*yang.Entry{
+					"key": {
+						Name: "key",
+						Type: &yang.YangType{
+							Kind: yang.Ystring,
+						},
+					},
+				},
+			},
+			definedDirectories: map[string]*yangDirectory{},
+			state: &genState{
+				uniqueDirectoryNames: map[string]string{
+					"/list": "List",
+				},
+			},
+			cfg: &protoMsgConfig{
+				compressPaths:   false,
+				basePackageName: "base",
+				baseImportPath:  "base/path",
+			},
+		},
+		wantMsg: &protoMsg{
+			Name:     "listKey",
+			YANGPath: "/list",
+			Module:   "list",
+			ListKey:  true,
+			Fields: []*protoMsgField{{
+				Tag:  1,
+				Name: "key",
+				Type: "string",
+			}, {
+				Tag:  2,
+				Name: "list",
+				Type: "pkg.list",
+			}},
+			Imports: []string{"base/path/base/pkg/pkg.proto"},
+		},
+	}, {
+		name:          "list with union key - string and int",
+		inListPackage: "pkg",
+		inListName:    "list",
+		inArgs: &protoDefinitionArgs{
+			field: &yang.Entry{
+				Name:     "list",
+				Kind:     yang.DirectoryEntry,
+				ListAttr: &yang.ListAttr{},
+				Key:      "key",
+				Dir:      map[string]*yang.Entry{},
+			},
+			directory: &yangDirectory{
+				name: "List",
+				fields: map[string]*yang.Entry{
+					"key": {
+						Name: "key",
+						Type: &yang.YangType{
+							Kind: yang.Yunion,
+							Type: []*yang.YangType{
+								{Kind: yang.Ystring},
+								{Kind: yang.Yint8},
+							},
+						},
+					},
+				},
+			},
+			definedDirectories: map[string]*yangDirectory{},
+			state: &genState{
+				uniqueDirectoryNames: map[string]string{
+					"/list": "List",
+				},
+			},
+			cfg: &protoMsgConfig{
+				compressPaths:   false,
+				basePackageName: "base",
+				baseImportPath:  "base/path",
+			},
+		},
+		wantMsg: &protoMsg{
+			Name:     "listKey",
+			YANGPath: "/list",
+			Module:   "list",
+			ListKey:  true,
+			Fields: []*protoMsgField{{
+				Tag:     1,
+				Name:    "key",
+				IsOneOf: true,
+				OneOfFields: []*protoMsgField{{
+					Tag:  232819104,
+					Name: "key_sint64",
+					Type: "sint64",
+				}, {
+					Tag:  470483267,
+					Name: "key_string",
+					Type: "string",
+				}},
+			}, {
+				Tag:  2,
+				Name: "list",
+				Type: "pkg.list",
+			}},
+			Imports: []string{"base/path/base/pkg/pkg.proto"},
+		},
+	}, {
+		name:          "list with union key - string and uint16",
+		inListPackage: "pkg",
+		inListName:    "list",
+		inArgs: &protoDefinitionArgs{
+			field: &yang.Entry{
+				Name:     "list",
+				Kind:     yang.DirectoryEntry,
+				ListAttr: &yang.ListAttr{},
+				Key:      "key",
+				Dir:      map[string]*yang.Entry{},
+			},
+			directory: &yangDirectory{
+				name: "List",
+				fields: map[string]*yang.Entry{
+					"key": {
+						Name: "key",
+						Type: &yang.YangType{
+							Kind: yang.Yunion,
+							Type: []*yang.YangType{
+								{Kind: yang.Ystring},
+								{Kind: yang.Yuint16},
+							},
+						},
+					},
+				},
+			},
+			definedDirectories: map[string]*yangDirectory{},
+			state: &genState{
+				uniqueDirectoryNames: map[string]string{
+					"/list": "List",
+				},
+			},
+			cfg: &protoMsgConfig{
+				compressPaths:   false,
+				basePackageName: "base",
+				baseImportPath:  "base/path",
+			},
+		},
+		wantMsg: &protoMsg{
+			Name:     "listKey",
+			YANGPath: "/list",
+			Module:   "list",
+			ListKey:  true,
+			Fields: []*protoMsgField{{
+				Tag:     1,
+				Name:    "key",
+				IsOneOf: true,
+				OneOfFields: []*protoMsgField{{
+					Tag:  470483267,
+					Name: "key_string",
+					Type: "string",
+				}, {
+					Tag:  221721542,
+					Name: "key_uint64",
+					Type: "uint64",
+				}},
+			}, {
+				Tag:  2,
+				Name: "list",
+				Type: "pkg.list",
+			}},
+			Imports: []string{"base/path/base/pkg/pkg.proto"},
+		},
+	}, {
+		name:          "list with union key - unsupported type",
+		inListPackage: "pkg",
+		inListName:    "list",
+		inArgs: &protoDefinitionArgs{
+			field: &yang.Entry{
+				Name:     "list",
+				Kind:     yang.DirectoryEntry,
+				ListAttr: &yang.ListAttr{},
+				Key:      "key",
+				Dir:      map[string]*yang.Entry{},
+			},
+			directory: &yangDirectory{
+				name: "List",
+				fields: map[string]*yang.Entry{
+					"key": {
+						Name: "key",
+						Type: &yang.YangType{
+							Kind: yang.Yunion,
+							Type: []*yang.YangType{
+								{Kind: yang.Ystring},
+								{Kind: yang.YinstanceIdentifier},
+							},
+						},
+					},
+				},
+			},
+			definedDirectories: map[string]*yangDirectory{},
+			state: &genState{
+				uniqueDirectoryNames: map[string]string{
+					"/list": "List",
+				},
+			},
+			cfg: &protoMsgConfig{
+				compressPaths:   false,
+				basePackageName: "base",
+				baseImportPath:  "base/path",
+			},
+		},
+		wantErr: true,
+	}, {
+		name:          "list with union key - two string",
+		inListPackage: "pkg",
+		inListName:    "list",
+		inArgs: &protoDefinitionArgs{
+			field: &yang.Entry{
+				Name:     "list",
+				Kind:     yang.DirectoryEntry,
+				ListAttr: &yang.ListAttr{},
+				Key:      "key",
+				Dir:      map[string]*yang.Entry{},
+			},
+			directory: &yangDirectory{
+				name: "List",
+				fields: map[string]*yang.Entry{
+					"key": {
+						Name: "key",
+						Type: &yang.YangType{
+							Kind: yang.Yunion,
+							Type: []*yang.YangType{
+								{Kind: yang.Ystring, Pattern: []string{"b.*"}},
+								{Kind: yang.Ystring, Pattern: []string{"a.*"}},
+							},
+						},
+					},
+				},
+			},
+			definedDirectories: map[string]*yangDirectory{},
+			state: &genState{
+				uniqueDirectoryNames: map[string]string{
+					"/list": "List",
+				},
+			},
+			cfg: &protoMsgConfig{
+				compressPaths:   false,
+				basePackageName: "base",
+				baseImportPath:  "base/path",
+			},
+		},
+		wantMsg: &protoMsg{
+			Name:     "listKey",
+			YANGPath: "/list",
+			Module:   "list",
+			ListKey:  true,
+			Fields: []*protoMsgField{{
+				Tag:  1,
+				Name: "key",
+				Type: "string",
+			}, {
+				Tag:  2,
+				Name: "list",
+				Type: "pkg.list",
+			}},
+			Imports: []string{"base/path/base/pkg/pkg.proto"},
+		},
+	}}
+
+	for _, tt := range tests {
+		got, err := genListKeyProto(tt.inListPackage, tt.inListName, tt.inArgs)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: genListKeyProto(%s, %s, %#v): got unexpected error returned, got: %v, want err: %v", tt.name, tt.inListPackage, tt.inListName, tt.inArgs, err, tt.wantErr)
+		}
+
+		if diff := pretty.Compare(got, tt.wantMsg); diff != "" {
+			t.Errorf("%s: genListKeyProto(%s, %s, %#v): did not get expected return message, diff(-got,+want):\n%s", tt.name, tt.inListPackage, tt.inListName, tt.inArgs, diff)
+		}
+	}
+}
+
+func TestGenListKeyProtoLeafrefKey(t *testing.T) {
+	module := &yang.Entry{Name: "module"}
+	container := &yang.Entry{Name: "container", Parent: module, Dir: map[string]*yang.Entry{}}
+	module.Dir = map[string]*yang.Entry{"container": container}
+	leaf := func(name string, t *yang.YangType) *yang.Entry {
+		e := &yang.Entry{Name: name, Kind: yang.LeafEntry, Parent: container, Type: t}
+		container.Dir[name] = e
+		return e
+	}
+	leafref := func(path string) *yang.YangType {
+		return &yang.YangType{Kind: yang.Yleafref, Path: path}
+	}
+	leaf("target", &yang.YangType{Kind: yang.Yuint32})
+	leaf("reference", leafref("/container/target"))
+	leaf("cycle-a", leafref("/container/cycle-b"))
+	leaf("cycle-b", leafref("/container/cycle-a"))
+
+	tree, err := buildSchemaTree([]*yang.Entry{container})
+	if err != nil {
+		t.Fatalf("buildSchemaTree(%v): got unexpected error: %v", container, err)
+	}
+
+	tests := []struct {
+		name     string
+		inKey    *yang.Entry
+		wantType string
+		wantErr  string
+	}{{
+		name:     "key that is a leafref to a uint32 leaf",
+		inKey:    &yang.Entry{Name: "key", Parent: module, Type: leafref("/container/target")},
+		wantType: "uint64",
+	}, {
+		name:     "key that is a leafref to a leafref to a uint32 leaf",
+		inKey:    &yang.Entry{Name: "key", Parent: module, Type: leafref("/container/reference")},
+		wantType: "uint64",
+	}, {
+		name:    "key that is a leafref to a cycle of leafrefs",
+		inKey:   &yang.Entry{Name: "key", Parent: module, Type: leafref("/container/cycle-a")},
+		wantErr: "within a cycle of leafrefs",
+	}}
+
+	for _, tt := range tests {
+		args := &protoDefinitionArgs{
+			field: &yang.Entry{
+				Name:     "list",
+				Kind:     yang.DirectoryEntry,
+				ListAttr: &yang.ListAttr{},
+				Key:      "key",
+				Dir:      map[string]*yang.Entry{},
+			},
+			directory: &yangDirectory{
+				name:   "List",
+				fields: map[string]*yang.Entry{"key": tt.inKey},
+			},
+			definedDirectories: map[string]*yangDirectory{},
+			state:              &genState{schematree: tree},
+			cfg:                &protoMsgConfig{basePackageName: "base"},
+		}
+
+		got, err := genListKeyProto("", "List", args)
+		if (err != nil) != (tt.wantErr != "") {
+			t.Errorf("%s: genListKeyProto(%v): did not get expected error status, got: %v, wantErr: %v", tt.name, tt.inKey, err, tt.wantErr)
+			continue
+		}
+		if err != nil {
+			if !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("%s: genListKeyProto(%v): did not get expected error, got: %v, want error containing: %s", tt.name, tt.inKey, err, tt.wantErr)
+			}
+			continue
+		}
+		if got.Fields[0].Type != tt.wantType {
+			t.Errorf("%s: genListKeyProto(%v): did not get expected key type, got: %s, want: %s", tt.name, tt.inKey, got.Fields[0].Type, tt.wantType)
+		}
+	}
+}
+
+func TestWriteProtoEnums(t *testing.T) {
+	// Create mock enumerations within goyang since we cannot create them in-line.
+	testEnums := map[string][]string{
+		"enumOne":   {"SPEED_2.5G", "SPEED_40G"},
+		"enumTwo":   {"VALUE_1", "VALUE_2"},
+		"enumThree": {"adminUp", "HTTPServer"},
+		"enumFour":  {"10/100", "speed-1.5G/full"},
+	}
+	testYANGEnums := map[string]*yang.EnumType{}
+
+	for name, values := range testEnums {
+		enum := yang.NewEnumType()
+		for i, v := range values {
+			enum.Set(v, int64(i))
+		}
+		testYANGEnums[name] = enum
+	}
+
+	tests := []struct {
+		name                string
+		inEnums             map[string]*yangEnum
+		inAnnotateEnumNames bool
+		inUpperSnakeEnums   bool
+		wantEnums           []string
+		wantErr             bool
+	}{{
+		name: "skipped enumeration type",
+		inEnums: map[string]*yangEnum{
+			"e": {
+				name: "e",
+				entry: &yang.Entry{
+					Name: "e",
+					Type: &yang.YangType{
+						Name: "enumeration",
+						Kind: yang.Yenum,
+					},
+				},
+			},
+		},
+		wantEnums: []string{},
+	}, {
+		name: "enum for identityref",
+		inEnums: map[string]*yangEnum{
+			"EnumeratedValue": {
+				name: "EnumeratedValue",
+				entry: &yang.Entry{
+					Type: &yang.YangType{
+						IdentityBase: &yang.Identity{
+							Name: "IdentityValue",
+							Values: []*yang.Identity{
+								{Name: "VALUE_A", Parent: &yang.Module{Name: "mod"}},
+								{Name: "VALUE_B", Parent: &yang.Module{Name: "mod2"}},
+							},
+						},
+					},
+				},
+			},
+		},
+		wantEnums: []string{
+			`
+// EnumeratedValue represents an enumerated type generated for the YANG identity IdentityValue.
+enum EnumeratedValue {
+  ENUMERATEDVALUE_UNSET = 0;
+  ENUMERATEDVALUE_VALUE_A = 321526273;
+  ENUMERATEDVALUE_VALUE_B = 321526274;
+}
+`,
+		},
+	}, {
+		name: "enum for identityref with identities that have the same name",
+		inEnums: map[string]*yangEnum{
+			"EnumeratedValue": {
+				name: "EnumeratedValue",
+				entry: &yang.Entry{
+					Type: &yang.YangType{
+						IdentityBase: &yang.Identity{
+							Name: "IdentityValue",
+							Values: []*yang.Identity{
+								{Name: "VALUE_A", Parent: &yang.Module{Name: "mod"}},
+								{Name: "VALUE_A", Parent: &yang.Module{Name: "mod2"}},
+							},
+						},
+					},
+				},
+			},
+		},
+		wantEnums: []string{
+			`
+// EnumeratedValue represents an enumerated type generated for the YANG identity IdentityValue.
+enum EnumeratedValue {
+  ENUMERATEDVALUE_UNSET = 0;
+  ENUMERATEDVALUE_VALUE_A = 321526273;
+  ENUMERATEDVALUE_VALUE_A_ = 427309721;
+}
+`,
+		},
+	}, {
+		name: "enum for typedef enumeration",
+		inEnums: map[string]*yangEnum{
+			"e": {
+				name: "EnumName",
+				entry: &yang.Entry{
+					Name: "e",
+					Type: &yang.YangType{
+						Name: "typedef",
+						Kind: yang.Yenum,
+						Enum: testYANGEnums["enumOne"],
+					},
+					Annotation: map[string]interface{}{
+						"valuePrefix": []string{"enum-name"},
+					},
+				},
+			},
+			"f": {
+				name: "SecondEnum",
+				entry: &yang.Entry{
+					Name: "f",
+					Type: &yang.YangType{
+						Name: "derived",
+						Kind: yang.Yenum,
+						Enum: testYANGEnums["enumTwo"],
+					},
+					Annotation: map[string]interface{}{
+						"valuePrefix": []string{"secondenum"},
+					},
+				},
+			},
+		},
+		inAnnotateEnumNames: true,
+		wantEnums: []string{
+			`
+// EnumName represents an enumerated type generated for the YANG enumerated type typedef.
+enum EnumName {
+  ENUMNAME_UNSET = 0;
+  ENUMNAME_SPEED_2_5G = 1 [(yext.yang_name) = "SPEED_2.5G"];
+  ENUMNAME_SPEED_40G = 2 [(yext.yang_name) = "SPEED_40G"];
+}
+`, `
+// SecondEnum represents an enumerated type generated for the YANG enumerated type derived.
+enum SecondEnum {
+  SECONDENUM_UNSET = 0;
+  SECONDENUM_VALUE_1 = 1 [(yext.yang_name) = "VALUE_1"];
+  SECONDENUM_VALUE_2 = 2 [(yext.yang_name) = "VALUE_2"];
+}
+`,
+		},
+	}, {
+		name: "enum with values that are sanitised",
+		inEnums: map[string]*yangEnum{
+			"e": {
+				name: "PortSpeed",
+				entry: &yang.Entry{
+					Name: "e",
+					Type: &yang.YangType{
+						Name: "port-speed",
+						Kind: yang.Yenum,
+						Enum: testYANGEnums["enumFour"],
+					},
+					Annotation: map[string]interface{}{
+						"valuePrefix": []string{"port-speed"},
+					},
+				},
+			},
+		},
+		inAnnotateEnumNames: true,
+		wantEnums: []string{
+			`
+// PortSpeed represents an enumerated type generated for the YANG enumerated type port-speed.
+enum PortSpeed {
+  PORTSPEED_UNSET = 0;
+  PORTSPEED_10_100 = 1 [(yext.yang_name) = "10/100"];
+  PORTSPEED_speed_1_5G_full = 2 [(yext.yang_name) = "speed-1.5G/full"];
+}
+`,
+		},
+	}, {
+		name: "enum for identityref with upper snake case values",
+		inEnums: map[string]*yangEnum{
+			"EnumeratedValue": {
+				name: "EnumeratedValue",
+				entry: &yang.Entry{
+					Type: &yang.YangType{
+						IdentityBase: &yang.Identity{
+							Name: "IdentityValue",
+							Values: []*yang.Identity{
+								{Name: "adminUp", Parent: &yang.Module{Name: "mod"}},
+								{Name: "admin_up", Parent: &yang.Module{Name: "mod"}},
+							},
+						},
+					},
+				},
+			},
+		},
+		inAnnotateEnumNames: true,
+		inUpperSnakeEnums:   true,
+		wantEnums: []string{
+			`
+// EnumeratedValue represents an enumerated type generated for the YANG identity IdentityValue.
+enum EnumeratedValue {
+  ENUMERATEDVALUE_UNSET = 0;
+  ENUMERATEDVALUE_ADMIN_UP = 203681768 [(yext.yang_name) = "adminUp"];
+  ENUMERATEDVALUE_ADMIN_UP_ = 495162585 [(yext.yang_name) = "admin_up"];
+}
+`,
+		},
+	}, {
+		name: "enum for typedef enumeration with upper snake case values",
+		inEnums: map[string]*yangEnum{
+			"e": {
+				name: "EnumName",
+				entry: &yang.Entry{
+					Name: "e",
+					Type: &yang.YangType{
+						Name: "typedef",
+						Kind: yang.Yenum,
+						Enum: testYANGEnums["enumThree"],
+					},
+					Annotation: map[string]interface{}{
+						"valuePrefix": []string{"enum-name"},
+					},
+				},
+			},
+		},
+		inUpperSnakeEnums: true,
+		wantEnums: []string{
+			`
+// EnumName represents an enumerated type generated for the YANG enumerated type typedef.
+enum EnumName {
+  ENUMNAME_UNSET = 0;
+  ENUMNAME_ADMIN_UP = 1;
+  ENUMNAME_HTTP_SERVER = 2;
+}
+`,
+		},
+	}}
+
+	for _, tt := range tests {
+		got, _, err := writeProtoEnums(tt.inEnums, &protoMsgConfig{
+			annotateEnumNames: tt.inAnnotateEnumNames,
+			upperSnakeEnums:   tt.inUpperSnakeEnums,
+		})
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: writeProtoEnums(%v): did not get expected error, got: %v", tt.name, tt.inEnums, err)
+		}
+
+		if err != nil {
+			continue
+		}
+
+		// Sort the returned output to avoid test flakes.
+		sort.Strings(got)
+		if diff := pretty.Compare(got, tt.wantEnums); diff != "" {
+			t.Errorf("%s: writeProtoEnums(%v): did not get expected output, diff(-got,+want):\n%s", tt.name, tt.inEnums, diff)
+		}
+	}
+}
+
+func TestAssertEnumValues(t *testing.T) {
+	ge, errs := genProtoIdentityEnum(&yang.Identity{
+		Name: "IdentityValue",
+		Values: []*yang.Identity{
+			{Name: "VALUE_A", Parent: &yang.Module{Name: "mod"}},
+			{Name: "VALUE_B", Parent: &yang.Module{Name: "mod"}},
+		},
+	}, &protoMsgConfig{})
+	if errs != nil {
+		t.Fatalf("genProtoIdentityEnum: got unexpected errors: %v", errs)
+	}
+	e := protoEnum{Name: "EnumeratedValue", Values: ge.Values}
+
+	tests := []struct {
+		name     string
+		inWant   map[int64]string
+		wantErrs []string
+	}{{
+		name: "values equal",
+		inWant: map[int64]string{
+			0:         "UNSET",
+			321526273: "VALUE_A",
+			321526274: "VALUE_B",
+		},
+	}, {
+		name: "missing, unexpected and changed values",
+		inWant: map[int64]string{
+			0:         "UNSET",
+			42:        "VALUE_C",
+			321526273: "VALUE_Z",
+		},
+		wantErrs: []string{"enum EnumeratedValue does not have the expected values:\n" +
+			"missing value 42: VALUE_C\n" +
+			"value 321526273: got label VALUE_A, want: VALUE_Z\n" +
+			"unexpected value 321526274: VALUE_B"},
+	}}
+
+	for _, tt := range tests {
+		r := &recordingTB{TB: t}
+		AssertEnumValues(r, e, tt.inWant)
+		if diff := pretty.Compare(r.errs, tt.wantErrs); diff != "" {
+			t.Errorf("%s: AssertEnumValues(...): did not get expected errors, diff(-got,+want):\n%s", tt.name, diff)
+		}
+	}
+}
+
+func TestWriteProtoEnumsIdentityValuesStable(t *testing.T) {
+	identity := func(name, module string) *yang.Identity {
+		return &yang.Identity{Name: name, Parent: &yang.Module{Name: module}}
+	}
+	identityEnum := func(vals ...*yang.Identity) map[string]*yangEnum {
+		return map[string]*yangEnum{
+			"EnumeratedValue": {
+				name: "EnumeratedValue",
+				entry: &yang.Entry{
+					Type: &yang.YangType{
+						IdentityBase: &yang.Identity{
+							Name:   "IdentityValue",
+							Parent: &yang.Module{Name: "mod"},
+							Values: vals,
+						},
+					},
+				},
+			},
+		}
+	}
+
+	tests := []struct {
+		name     string
+		inBefore []*yang.Identity
+		inAfter  []*yang.Identity
+	}{{
+		// Insert identities both before and between the existing identities in
+		// alphabetical order.
+		name:     "identities added",
+		inBefore: []*yang.Identity{identity("VALUE_B", "mod"), identity("VALUE_D", "mod")},
+		inAfter:  []*yang.Identity{identity("VALUE_D", "mod"), identity("VALUE_C", "mod"), identity("VALUE_B", "mod"), identity("VALUE_A", "mod")},
+	}, {
+		// The names of VALUE_A and VALUE_B differ only in their last character,
+		// and hence their hashes are adjacent. The identity that collides with
+		// VALUE_A must not take the value of VALUE_B.
+		name:     "colliding identity added",
+		inBefore: []*yang.Identity{identity("VALUE_A", "mod"), identity("VALUE_B", "mod")},
+		inAfter:  []*yang.Identity{identity("VALUE_A", "mod"), identity("VALUE_A", "augmenting-mod"), identity("VALUE_B", "mod")},
+	}, {
+		name:     "colliding identity added to identity in another module",
+		inBefore: []*yang.Identity{identity("VALUE_A", "mod"), identity("VALUE_A", "mod2"), identity("VALUE_B", "mod")},
+		inAfter:  []*yang.Identity{identity("VALUE_A", "mod3"), identity("VALUE_A", "mod2"), identity("VALUE_A", "mod"), identity("VALUE_B", "mod")},
+	}}
+
+	for _, tt := range tests {
+		before, _, errs := writeProtoEnums(identityEnum(tt.inBefore...), &protoMsgConfig{})
+		if errs != nil {
+			t.Errorf("%s: writeProtoEnums: got unexpected errors: %v", tt.name, errs)
+			continue
+		}
+		after, _, errs := writeProtoEnums(identityEnum(tt.inAfter...), &protoMsgConfig{})
+		if errs != nil {
+			t.Errorf("%s: writeProtoEnums: got unexpected errors: %v", tt.name, errs)
+			continue
+		}
+		if len(before) != 1 || len(after) != 1 {
+			t.Errorf("%s: writeProtoEnums: did not get single enum, got: %v and %v", tt.name, before, after)
+			continue
+		}
+
+		afterLines := map[string]bool{}
+		for _, l := range strings.Split(after[0], "\n") {
+			afterLines[l] = true
+		}
+		for _, l := range strings.Split(before[0], "\n") {
+			if !strings.Contains(l, " = ") {
+				continue
+			}
+			if !afterLines[l] {
+				t.Errorf("%s: writeProtoEnums: value %q was changed by adding identities, got:\n%s", tt.name, l, after[0])
+			}
+		}
+	}
+}
+
+func TestWriteProtoEnumsIdentitiesFile(t *testing.T) {
+	identityLeaf := func(module string) *yang.Entry {
+		return &yang.Entry{
+			Name: "leaf",
+			Type: &yang.YangType{
+				Name: "identityref",
+				Kind: yang.Yidentityref,
+				IdentityBase: &yang.Identity{
+					Name: "foo",
+					Values: []*yang.Identity{
+						{Name: "ONE"},
+						{Name: "TWO"},
+					},
+					Parent: &yang.Module{
+						Name: module,
+					},
+				},
+			},
+		}
+	}
+
+	s := newGenState()
+	enums, errs := s.findEnumSet(map[string]*yang.Entry{
+		"/mod-a/leaf": identityLeaf("mod-a"),
+		"/mod-b/leaf": identityLeaf("mod-b"),
+	}, false, true)
+	if errs != nil {
+		t.Fatalf("findEnumSet: got unexpected errors: %v", errs)
+	}
+
+	gotEnums, gotIdentities, errs := writeProtoEnums(enums, &protoMsgConfig{identitiesFile: true})
+	if errs != nil {
+		t.Fatalf("writeProtoEnums: got unexpected errors: %v", errs)
+	}
+
+	if len(gotEnums) != 0 {
+		t.Errorf("writeProtoEnums: got unexpected enums outside of the identities file: %v", gotEnums)
+	}
+
+	wantIdentities := []string{`
+// ModAFoo represents an enumerated type generated for the YANG identity foo.
+enum ModAFoo {
+  MODAFOO_UNSET = 0;
+  MODAFOO_TWO = 141377855;
+  MODAFOO_ONE = 523467345;
+}
+`, `
+// ModBFoo represents an enumerated type generated for the YANG identity foo.
+enum ModBFoo {
+  MODBFOO_UNSET = 0;
+  MODBFOO_TWO = 141377855;
+  MODBFOO_ONE = 523467345;
+}
+`}
+
+	sort.Strings(gotIdentities)
+	if diff := pretty.Compare(gotIdentities, wantIdentities); diff != "" {
+		t.Errorf("writeProtoEnums: did not get expected identities, diff(-got,+want):\n%s", diff)
+	}
+}
+
+func TestWriteProtoEnumsZeroValue(t *testing.T) {
+	enum := yang.NewEnumType()
+	enum.Set("VALUE_1", 0)
+	enum.Set("VALUE_2", 1)
+
+	inEnums := map[string]*yangEnum{
+		"e": {
+			name: "EnumName",
+			entry: &yang.Entry{
+				Name: "e",
+				Type: &yang.YangType{
+					Name: "typedef",
+					Kind: yang.Yenum,
+					Enum: enum,
+				},
+				Annotation: map[string]interface{}{
+					"valuePrefix": []string{"enum-name"},
+				},
+			},
+		},
+		"EnumeratedValue": {
+			name: "EnumeratedValue",
+			entry: &yang.Entry{
+				Type: &yang.YangType{
+					IdentityBase: &yang.Identity{
+						Name: "IdentityValue",
+						Values: []*yang.Identity{
+							{Name: "VALUE_A", Parent: &yang.Module{Name: "mod"}},
+							{Name: "VALUE_B", Parent: &yang.Module{Name: "mod2"}},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	tests := []struct {
+		name       string
+		inZeroName string
+		inPolicy   ProtoEnumZeroValuePolicy
+		wantEnums  []string
+	}{{
+		name:     "prefixed zero value",
+		inPolicy: PrefixedEnumZeroValue,
+		wantEnums: []string{`
+// EnumName represents an enumerated type generated for the YANG enumerated type typedef.
+enum EnumName {
+  ENUMNAME_UNSET = 0;
+  ENUMNAME_VALUE_1 = 1;
+  ENUMNAME_VALUE_2 = 2;
+}
+`, `
+// EnumeratedValue represents an enumerated type generated for the YANG identity IdentityValue.
+enum EnumeratedValue {
+  ENUMERATEDVALUE_UNSET = 0;
+  ENUMERATEDVALUE_VALUE_A = 321526273;
+  ENUMERATEDVALUE_VALUE_B = 321526274;
+}
+`},
+	}, {
+		name:     "plain zero value",
+		inPolicy: PlainEnumZeroValue,
+		wantEnums: []string{`
+// EnumName represents an enumerated type generated for the YANG enumerated type typedef.
+enum EnumName {
+  UNSET = 0;
+  ENUMNAME_VALUE_1 = 1;
+  ENUMNAME_VALUE_2 = 2;
+}
+`, `
+// EnumeratedValue represents an enumerated type generated for the YANG identity IdentityValue.
+enum EnumeratedValue {
+  UNSET = 0;
+  ENUMERATEDVALUE_VALUE_A = 321526273;
+  ENUMERATEDVALUE_VALUE_B = 321526274;
+}
+`},
+	}, {
+		name:       "custom prefixed zero value",
+		inZeroName: "UNKNOWN",
+		inPolicy:   PrefixedEnumZeroValue,
+		wantEnums: []string{`
+// EnumName represents an enumerated type generated for the YANG enumerated type typedef.
+enum EnumName {
+  ENUMNAME_UNKNOWN = 0;
+  ENUMNAME_VALUE_1 = 1;
+  ENUMNAME_VALUE_2 = 2;
+}
+`, `
+// EnumeratedValue represents an enumerated type generated for the YANG identity IdentityValue.
+enum EnumeratedValue {
+  ENUMERATEDVALUE_UNKNOWN = 0;
+  ENUMERATEDVALUE_VALUE_A = 321526273;
+  ENUMERATEDVALUE_VALUE_B = 321526274;
+}
+`},
+	}, {
+		name:       "custom plain zero value",
+		inZeroName: "UNKNOWN",
+		inPolicy:   PlainEnumZeroValue,
+		wantEnums: []string{`
+// EnumName represents an enumerated type generated for the YANG enumerated type typedef.
+enum EnumName {
+  UNKNOWN = 0;
+  ENUMNAME_VALUE_1 = 1;
+  ENUMNAME_VALUE_2 = 2;
+}
+`, `
+// EnumeratedValue represents an enumerated type generated for the YANG identity IdentityValue.
+enum EnumeratedValue {
+  UNKNOWN = 0;
+  ENUMERATEDVALUE_VALUE_A = 321526273;
+  ENUMERATEDVALUE_VALUE_B = 321526274;
+}
+`},
+	}}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, _, errs := writeProtoEnums(inEnums, &protoMsgConfig{
+				enumZeroName:   tt.inZeroName,
+				enumZeroPolicy: tt.inPolicy,
+			})
+			if errs != nil {
+				t.Fatalf("writeProtoEnums: got unexpected errors: %v", errs)
+			}
+
+			// Sort the returned output to avoid test flakes.
+			sort.Strings(got)
+			if diff := pretty.Compare(got, tt.wantEnums); diff != "" {
+				t.Errorf("writeProtoEnums: did not get expected output, diff(-got,+want):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestWriteProtoEnumsValueOffset(t *testing.T) {
+	enum := yang.NewEnumType()
+	enum.Set("VALUE_1", 0)
+	enum.Set("VALUE_2", 1)
+
+	inEnums := map[string]*yangEnum{
+		"e": {
+			name: "EnumName",
+			entry: &yang.Entry{
+				Name: "e",
+				Type: &yang.YangType{
+					Name: "typedef",
+					Kind: yang.Yenum,
+					Enum: enum,
+				},
+			},
+		},
+		"EnumeratedValue": {
+			name: "EnumeratedValue",
+			entry: &yang.Entry{
+				Type: &yang.YangType{
+					IdentityBase: &yang.Identity{
+						Name: "IdentityValue",
+						Values: []*yang.Identity{
+							{Name: "VALUE_A", Parent: &yang.Module{Name: "mod"}},
+							{Name: "VALUE_B", Parent: &yang.Module{Name: "mod2"}},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	tests := []struct {
+		name      string
+		inOffset  int64
+		wantEnums []string
+	}{{
+		name: "default offset",
+		wantEnums: []string{`
+// EnumName represents an enumerated type generated for the YANG enumerated type typedef.
+enum EnumName {
+  ENUMNAME_UNSET = 0;
+  ENUMNAME_VALUE_1 = 1;
+  ENUMNAME_VALUE_2 = 2;
+}
+`, `
+// EnumeratedValue represents an enumerated type generated for the YANG identity IdentityValue.
+enum EnumeratedValue {
+  ENUMERATEDVALUE_UNSET = 0;
+  ENUMERATEDVALUE_VALUE_A = 321526273;
+  ENUMERATEDVALUE_VALUE_B = 321526274;
+}
+`},
+	}, {
+		name:     "values start at offset",
+		inOffset: 10,
+		wantEnums: []string{`
+// EnumName represents an enumerated type generated for the YANG enumerated type typedef.
+enum EnumName {
+  ENUMNAME_UNSET = 0;
+  ENUMNAME_VALUE_1 = 10;
+  ENUMNAME_VALUE_2 = 11;
+}
+`, `
+// EnumeratedValue represents an enumerated type generated for the YANG identity IdentityValue.
+enum EnumeratedValue {
+  ENUMERATEDVALUE_UNSET = 0;
+  ENUMERATEDVALUE_VALUE_A = 321526273;
+  ENUMERATEDVALUE_VALUE_B = 321526274;
+}
+`},
+	}, {
+		name:     "identity values within reserved range are moved above offset",
+		inOffset: 400000000,
+		wantEnums: []string{`
+// EnumName represents an enumerated type generated for the YANG enumerated type typedef.
+enum EnumName {
+  ENUMNAME_UNSET = 0;
+  ENUMNAME_VALUE_1 = 400000000;
+  ENUMNAME_VALUE_2 = 400000001;
+}
+`, `
+// EnumeratedValue represents an enumerated type generated for the YANG identity IdentityValue.
+enum EnumeratedValue {
+  ENUMERATEDVALUE_UNSET = 0;
+  ENUMERATEDVALUE_VALUE_A = 2069009921;
+  ENUMERATEDVALUE_VALUE_B = 2069009922;
+}
+`},
+	}}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, _, errs := writeProtoEnums(inEnums, &protoMsgConfig{
+				enumValueOffset: tt.inOffset,
+			})
+			if errs != nil {
+				t.Fatalf("writeProtoEnums: got unexpected errors: %v", errs)
+			}
+
+			// Sort the returned output to avoid test flakes.
+			sort.Strings(got)
+			if diff := pretty.Compare(got, tt.wantEnums); diff != "" {
+				t.Errorf("writeProtoEnums: did not get expected output, diff(-got,+want):\n%s", diff)
+			}
+		})
+	}
+
+	t.Run("value exceeds maximum with offset", func(t *testing.T) {
+		large := yang.NewEnumType()
+		large.Set("MAX", math.MaxInt32)
+		_, err := genProtoEnum(&yang.Entry{
+			Name: "e",
+			Type: &yang.YangType{Name: "enumeration", Kind: yang.Yenum, Enum: large},
+		}, &protoMsgConfig{})
+		if err == nil {
+			t.Fatalf("genProtoEnum: did not get expected error for value that cannot be represented")
+		}
+	})
+}
+
+func TestIdentityEnumValue(t *testing.T) {
+	tests := []struct {
+		name  string
+		inStr string
+		inOff int64
+		want  uint32
+	}{{
+		name:  "hash above offset",
+		inStr: "IdentityValueVALUE_A",
+		inOff: 1,
+		want:  321526273,
+	}, {
+		name:  "hash equal to offset",
+		inStr: "IdentityValueVALUE_A",
+		inOff: 321526273,
+		want:  321526273,
+	}, {
+		name:  "hash below offset",
+		inStr: "IdentityValueVALUE_A",
+		inOff: 321526274,
+		want:  math.MaxInt32,
+	}, {
+		name:  "hash below offset wraps to top of range",
+		inStr: "IdentityValueVALUE_A",
+		inOff: 400000000,
+		want:  2069009921,
+	}, {
+		name:  "adjacent hash below offset remains distinct",
+		inStr: "IdentityValueVALUE_B",
+		inOff: 400000000,
+		want:  2069009922,
+	}, {
+		name:  "maximum offset",
+		inStr: "IdentityValueVALUE_A",
+		inOff: protoMaxFieldTag,
+		want:  321526273 + math.MaxInt32 - protoMaxFieldTag + 1,
+	}}
+
+	for _, tt := range tests {
+		got, err := identityEnumValue(tt.inStr, tt.inOff)
+		if err != nil {
+			t.Errorf("%s: identityEnumValue(%s, %d): got unexpected error: %v", tt.name, tt.inStr, tt.inOff, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("%s: identityEnumValue(%s, %d): did not get expected value, got: %d, want: %d", tt.name, tt.inStr, tt.inOff, got, tt.want)
+		}
+		if int64(got) < tt.inOff || got > math.MaxInt32 {
+			t.Errorf("%s: identityEnumValue(%s, %d): got value %d outside of range [%d, %d]", tt.name, tt.inStr, tt.inOff, got, tt.inOff, math.MaxInt32)
+		}
+	}
+}
+
+func TestProtoEnumAllowAlias(t *testing.T) {
+	// With the default offset of 1, the value NEGATIVE is mapped to the same
+	// number as the zero value.
+	enum := yang.NewEnumType()
+	enum.Set("NEGATIVE", -1)
+	enum.Set("ONE", 1)
+	enumType := &yang.YangType{Name: "typedef", Kind: yang.Yenum, Enum: enum}
+
+	t.Run("enum generated for a typedef", func(t *testing.T) {
+		got, _, errs := writeProtoEnums(map[string]*yangEnum{
+			"e": {
+				name:  "EnumName",
+				entry: &yang.Entry{Name: "e", Type: enumType},
+			},
+		}, &protoMsgConfig{annotateEnumNames: true})
+		if errs != nil {
+			t.Fatalf("writeProtoEnums: got unexpected errors: %v", errs)
+		}
+
+		want := []string{`
+// EnumName represents an enumerated type generated for the YANG enumerated type typedef.
+enum EnumName {
+  option allow_alias = true;
+  ENUMNAME_UNSET = 0;
+  ENUMNAME_NEGATIVE = 0 [(yext.yang_name) = "NEGATIVE"];
+  ENUMNAME_ONE = 2 [(yext.yang_name) = "ONE"];
+}
+`}
+		if diff := pretty.Compare(got, want); diff != "" {
+			t.Errorf("writeProtoEnums: did not get expected output, diff(-got,+want):\n%s", diff)
+		}
+	})
+
+	t.Run("enum within a message", func(t *testing.T) {
+		got, errs := writeProto3Msg(&yangDirectory{
+			name: "MessageName",
+			entry: &yang.Entry{
+				Name:   "message-name",
+				Kind:   yang.DirectoryEntry,
+				Parent: &yang.Entry{Name: "module", Kind: yang.DirectoryEntry},
+			},
+			fields: map[string]*yang.Entry{
+				"enum": {
+					Name: "enum",
+					Kind: yang.LeafEntry,
+					Parent: &yang.Entry{
+						Name:   "message-name",
+						Parent: &yang.Entry{Name: "module"},
+					},
+					Type: &yang.YangType{Name: "enumeration", Kind: yang.Yenum, Enum: enum},
+				},
+			},
+			path: []string{"", "module", "message-name"},
+		}, nil, newGenState(), &protoMsgConfig{
+			basePackageName: "base",
+			enumPackageName: "enums",
+		})
+		if errs != nil {
+			t.Fatalf("writeProto3Msg: got unexpected errors: %v", errs)
+		}
+
+		want := `
+// MessageName represents the /module/message-name YANG schema element.
+// Defined in module module.
+message MessageName {
+  enum Enum {
+    option allow_alias = true;
+    ENUM_UNSET = 0;
+    ENUM_NEGATIVE = 0;
+    ENUM_ONE = 2;
+  }
+  Enum enum = 278979784;
+}`
+		if diff := pretty.Compare(got.MessageCode, want); diff != "" {
+			if diffl, err := testutil.GenerateUnifiedDiff(got.MessageCode, want); err == nil {
+				diff = diffl
+			}
+			t.Errorf("writeProto3Msg: did not get expected message, diff(-got,+want):\n%s", diff)
+		}
+	})
+}
+
+func TestGenProto3MsgEmptyEnumPolicy(t *testing.T) {
+	emptyBase := &yang.Identity{
+		Name:   "empty-identity",
+		Parent: &yang.Module{Name: "test-module"},
+	}
+	parent := &yang.Entry{
+		Name:   "message-name",
+		Parent: &yang.Entry{Name: "module"},
+	}
+	msg := &yangDirectory{
+		name: "MessageName",
+		entry: &yang.Entry{
+			Name:   "message-name",
+			Kind:   yang.DirectoryEntry,
+			Parent: &yang.Entry{Name: "module", Kind: yang.DirectoryEntry},
+		},
+		fields: map[string]*yang.Entry{
+			"empty-ref": {
+				Name:   "empty-ref",
+				Kind:   yang.LeafEntry,
+				Parent: parent,
+				Type: &yang.YangType{
+					Name:         "identityref",
+					Kind:         yang.Yidentityref,
+					IdentityBase: emptyBase,
+				},
+			},
+			"union-ref": {
+				Name:   "union-ref",
+				Kind:   yang.LeafEntry,
+				Parent: parent,
+				Type: &yang.YangType{
+					Name: "union",
+					Kind: yang.Yunion,
+					Type: []*yang.YangType{{
+						Name:         "identityref",
+						Kind:         yang.Yidentityref,
+						IdentityBase: emptyBase,
+					}, {
+						Name: "uint32",
+						Kind: yang.Yuint32,
+					}},
+				},
+			},
+		},
+		path: []string{"", "module", "message-name"},
+	}
+
+	tests := []struct {
+		name        string
+		inPolicy    ProtoEmptyEnumPolicy
+		wantFields  []*protoMsgField
+		wantImports []string
+	}{{
+		name:     "emit empty enums",
+		inPolicy: EmitEmptyEnums,
+		wantFields: []*protoMsgField{{
+			Tag:  3872866,
+			Name: "empty_ref",
+			Type: "base.enums.TestModuleEmptyIdentity",
+		}, {
+			Tag:     132349524,
+			Name:    "union_ref",
+			IsOneOf: true,
+			OneOfFields: []*protoMsgField{{
+				Tag:  212566632,
+				Name: "union_ref_testmoduleemptyidentity",
+				Type: "base.enums.TestModuleEmptyIdentity",
+			}, {
+				Tag:  406269637,
+				Name: "union_ref_uint64",
+				Type: "uint64",
+			}},
+		}},
+		wantImports: []string{"base/enums/enums.proto"},
+	}, {
+		name:     "map empty enums to strings",
+		inPolicy: StringEmptyEnums,
+		wantFields: []*protoMsgField{{
+			Tag:  3872866,
+			Name: "empty_ref",
+			Type: "ywrapper.StringValue",
+			Options: []*protoOption{{
+				Name:  "(yext.identity_base)",
+				Value: `"test-module:empty-identity"`,
+			}},
+		}, {
+			Tag:     132349524,
+			Name:    "union_ref",
+			IsOneOf: true,
+			OneOfFields: []*protoMsgField{{
+				Tag:  328477788,
+				Name: "union_ref_string",
+				Type: "string",
+			}, {
+				Tag:  406269637,
+				Name: "union_ref_uint64",
+				Type: "uint64",
+			}},
+		}},
+	}, {
+		name:     "annotate empty enums",
+		inPolicy: AnnotateEmptyEnums,
+		wantFields: []*protoMsgField{{
+			Tag:  3872866,
+			Name: "empty_ref",
+			Type: "ywrapper.StringValue",
+			Options: []*protoOption{{
+				Name:  "(yext.identity_base)",
+				Value: `"test-module:empty-identity"`,
+			}, {
+				Name:  "(yext.empty_enum)",
+				Value: "true",
+			}},
+		}, {
+			Tag:     132349524,
+			Name:    "union_ref",
+			IsOneOf: true,
+			OneOfFields: []*protoMsgField{{
+				Tag:  328477788,
+				Name: "union_ref_string",
+				Type: "string",
+			}, {
+				Tag:  406269637,
+				Name: "union_ref_uint64",
+				Type: "uint64",
+			}},
+		}},
+	}}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, errs := genProto3Msg(msg, nil, newGenState(), &protoMsgConfig{
+				basePackageName: "base",
+				enumPackageName: "enums",
+				emptyEnumPolicy: tt.inPolicy,
+			}, "", nil)
+			if errs != nil {
+				t.Fatalf("genProto3Msg(%v): got unexpected errors: %v", msg, errs)
+			}
+			if len(got) != 1 {
+				t.Fatalf("genProto3Msg(%v): did not get expected single message, got: %v", msg, got)
+			}
+			if diff := pretty.Compare(got[0].Fields, tt.wantFields); diff != "" {
+				t.Errorf("genProto3Msg(%v): did not get expected fields, diff(-got,+want):\n%s", msg, diff)
+			}
+			if diff := pretty.Compare(got[0].Imports, tt.wantImports); diff != "" {
+				t.Errorf("genProto3Msg(%v): did not get expected imports, diff(-got,+want):\n%s", msg, diff)
+			}
+		})
+	}
+}
+
+func TestWriteProtoEnumsEmptyIdentity(t *testing.T) {
+	inEnums := map[string]*yangEnum{
+		"TestModuleEmptyIdentity": {
+			name: "TestModuleEmptyIdentity",
+			entry: &yang.Entry{
+				Type: &yang.YangType{
+					IdentityBase: &yang.Identity{
+						Name:   "empty-identity",
+						Parent: &yang.Module{Name: "test-module"},
+					},
+				},
+			},
+		},
+	}
+
+	tests := []struct {
+		name      string
+		inPolicy  ProtoEmptyEnumPolicy
+		wantEnums []string
+	}{{
+		name:     "emit empty enums",
+		inPolicy: EmitEmptyEnums,
+		wantEnums: []string{`
+// TestModuleEmptyIdentity represents an enumerated type generated for the YANG identity empty-identity.
+enum TestModuleEmptyIdentity {
+  TESTMODULEEMPTYIDENTITY_UNSET = 0;
+}
+`},
+	}, {
+		name:     "skip empty enums mapped to strings",
+		inPolicy: StringEmptyEnums,
+	}, {
+		name:     "skip empty enums with annotation",
+		inPolicy: AnnotateEmptyEnums,
+	}}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, _, errs := writeProtoEnums(inEnums, &protoMsgConfig{emptyEnumPolicy: tt.inPolicy})
+			if errs != nil {
+				t.Fatalf("writeProtoEnums: got unexpected errors: %v", errs)
+			}
+			if diff := pretty.Compare(got, tt.wantEnums); diff != "" {
+				t.Errorf("writeProtoEnums: did not get expected output, diff(-got,+want):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestIsProtoIdentifier(t *testing.T) {
+	tests := []struct {
+		in   string
+		want bool
+	}{
+		{"UNSET", true},
+		{"Unknown_0", true},
+		{"", false},
+		{"_UNSET", false},
+		{"0_UNSET", false},
+		{"NOT-SET", false},
+		{"NOT SET", false},
+	}
+
+	for _, tt := range tests {
+		if got := isProtoIdentifier(tt.in); got != tt.want {
+			t.Errorf("isProtoIdentifier(%q): did not get expected result, got: %v, want: %v", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestUnionFieldToOneOf(t *testing.T) {
+	// Create mock enumerations within goyang since we cannot create them in-line.
+	testEnums := map[string][]string{
+		"enumOne": {"SPEED_2.5G", "SPEED_40G"},
+	}
+	testYANGEnums := map[string]*yang.EnumType{}
+
+	for name, values := range testEnums {
+		enum := yang.NewEnumType()
+		for i, v := range values {
+			enum.Set(v, int64(i))
+		}
+		testYANGEnums[name] = enum
+	}
+
+	tests := []struct {
+		name                string
+		inName              string
+		inEntry             *yang.Entry
+		inMappedType        *mappedType
+		inAnnotateEnumNames bool
+		wantFields          []*protoMsgField
+		wantEnums           map[string]*protoMsgEnum
+		wantRepeatedMsg     *protoMsg
+		wantErr             bool
+	}{{
+		name:   "simple string union",
+		inName: "FieldName",
+		inEntry: &yang.Entry{
+			Name: "field-name",
+			Type: &yang.YangType{
+				Type: []*yang.YangType{
+					{Kind: yang.Ystring},
+					{Kind: yang.Yint8},
+				},
+			},
+		},
+		inMappedType: &mappedType{
+			unionTypes: map[string]int{
+				"string": 0,
+				"sint64": 0,
+			},
+		},
+		wantFields: []*protoMsgField{{
+			Tag:  171677331,
+			Name: "FieldName_sint64",
+			Type: "sint64",
+		}, {
+			Tag:  173535000,
+			Name: "FieldName_string",
+			Type: "string",
+		}},
+		wantEnums: map[string]*protoMsgEnum{},
+	}, {
+		name:   "decimal64 union",
+		inName: "FieldName",
+		inEntry: &yang.Entry{
+			Name: "field-name",
+			Type: &yang.YangType{
+				Type: []*yang.YangType{
+					{Kind: yang.Ystring},
+					{Kind: yang.Ydecimal64},
+				},
+			},
+		},
+		inMappedType: &mappedType{
+			unionTypes: map[string]int{
+				"string":             0,
+				"ywrapper.Decimal64": 1,
+			},
+		},
+		wantFields: []*protoMsgField{{
+			Tag:  173535000,
+			Name: "FieldName_string",
+			Type: "string",
+		}, {
+			Tag:  328616554,
+			Name: "FieldName_decimal64",
+			Type: "ywrapper.Decimal64",
+		}},
+		wantEnums: map[string]*protoMsgEnum{},
+	}, {
+		name:   "union with an enumeration",
+		inName: "FieldName",
+		inEntry: &yang.Entry{
+			Name: "field-name",
+			Type: &yang.YangType{
+				Type: []*yang.YangType{
+					{
+						Name: "enumeration",
+						Kind: yang.Yenum,
+						Enum: testYANGEnums["enumOne"],
+					},
+					{Kind: yang.Ystring},
+				},
+			},
+		},
+		inMappedType: &mappedType{
+			unionTypes: map[string]int{
+				"SomeEnumType": 0,
+				"string":       1,
+			},
+		},
+		inAnnotateEnumNames: true,
+		wantFields: []*protoMsgField{{
+			Tag:  29065580,
+			Name: "FieldName_someenumtype",
+			Type: "SomeEnumType",
+		}, {
+			Tag:  173535000,
+			Name: "FieldName_string",
+			Type: "string",
+		}},
+		wantEnums: map[string]*protoMsgEnum{
+			"FieldName": {
+				Values: map[int64]protoEnumValue{
+					0: {ProtoLabel: "UNSET"},
+					1: {ProtoLabel: "SPEED_2_5G", YANGLabel: "SPEED_2.5G"},
+					2: {ProtoLabel: "SPEED_40G", YANGLabel: "SPEED_40G"},
+				},
+			},
+		},
+	}, {
+		name:   "leaflist of union",
+		inName: "FieldName",
+		inEntry: &yang.Entry{
+			Name: "field-name",
+			Type: &yang.YangType{
+				Type: []*yang.YangType{
+					{Kind: yang.Ystring},
+					{Kind: yang.Yuint8},
+				},
+			},
+			Parent:   &yang.Entry{Name: "parent"},
+			ListAttr: &yang.ListAttr{},
+		},
+		inMappedType: &mappedType{
+			unionTypes: map[string]int{
+				"string": 0,
+				"uint64": 1,
+			},
+		},
+		wantRepeatedMsg: &protoMsg{
+			Name:     "FieldNameUnion",
+			YANGPath: "/parent/field-name union field field-name",
+			Fields: []*protoMsgField{{
+				Tag:  85114709,
+				Name: "FieldName_string",
+				Type: "string",
+			}, {
+				Tag:  192993976,
+				Name: "FieldName_uint64",
+				Type: "uint64",
+			}},
+		},
+	}}
+
+	for _, tt := range tests {
+		got, err := unionFieldToOneOf(tt.inName, tt.inEntry, tt.inMappedType, &protoMsgConfig{annotateEnumNames: tt.inAnnotateEnumNames})
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: unionFieldToOneOf(%s, %v, %v, %v): did not get expected error, got: %v, wanted err: %v", tt.name, tt.inName, tt.inEntry, tt.inMappedType, tt.inAnnotateEnumNames, err, tt.wantErr)
+		}
+
+		if err != nil {
+			continue
+		}
+
+		if diff := pretty.Compare(got.oneOfFields, tt.wantFields); diff != "" {
+			t.Errorf("%s: unionFieldToOneOf(%s, %v, %v, %v): did not get expected set of fields, diff(-got,+want):\n%s", tt.name, tt.inName, tt.inEntry, tt.inMappedType, tt.inAnnotateEnumNames, diff)
+		}
+
+		if diff := pretty.Compare(got.enums, tt.wantEnums); diff != "" {
+			t.Errorf("%s: unionFieldToOneOf(%s, %v, %v, %v): did not get expected set of enums, diff(-got,+want):\n%s", tt.name, tt.inName, tt.inEntry, tt.inMappedType, tt.inAnnotateEnumNames, diff)
+		}
+
+		if diff := pretty.Compare(got.repeatedMsg, tt.wantRepeatedMsg); diff != "" {
+			t.Errorf("%s: unionFieldToOneOf(%s, %v, %v, %v): did not get expected repeated message, diff(-got,+want):\n%s", tt.name, tt.inName, tt.inEntry, tt.inMappedType, tt.inAnnotateEnumNames, diff)
+		}
+	}
+}
+
+func TestStripPackagePrefix(t *testing.T) {
+	tests := []struct {
+		name         string
+		inPrefix     string
+		inPath       string
+		want         string
+		wantStripped bool
+	}{{
+		name:         "invalid prefix at element one",
+		inPrefix:     "one.two",
+		inPath:       "two.four",
+		want:         "two.four",
+		wantStripped: false,
+	}, {
+		name:         "single element prefix",
+		inPrefix:     "one",
+		inPath:       "one.three",
+		want:         "three",
+		wantStripped: true,
+	}, {
+		name:         "longer prefix",
+		inPrefix:     "one.two.three",
+		inPath:       "one.two.three.five",
+		want:         "five",
+		wantStripped: true,
+	}}
+
+	for _, tt := range tests {
+		got, stripped := stripPackagePrefix(tt.inPrefix, tt.inPath)
+		if got != tt.want {
+			t.Errorf("%s: stripPackagePrefix(%s, %s): did not get expected output, got: %s, want: %s", tt.name, tt.inPrefix, tt.inPath, got, tt.want)
+		}
+
+		if stripped != tt.wantStripped {
+			t.Errorf("%s: stripPackagePrefix(%s, %s): did not get expected stipped status, got: %v, want: %v", tt.name, tt.inPrefix, tt.inPath, stripped, tt.wantStripped)
+		}
+	}
+
+}
+
+func TestUniqueFieldTag(t *testing.T) {
+	tests := []struct {
+		name          string
+		inTag         uint32
+		inDefinedTags map[uint32]bool
+		want          uint32
+	}{{
+		name:          "unused tag",
+		inTag:         4242,
+		inDefinedTags: map[uint32]bool{},
+		want:          4242,
+	}, {
+		name:          "used tag",
+		inTag:         4242,
+		inDefinedTags: map[uint32]bool{4242: true, 4243: true},
+		want:          4244,
+	}, {
+		name:          "probe skips reserved range",
+		inTag:         18999,
+		inDefinedTags: map[uint32]bool{18999: true},
+		want:          20000,
+	}, {
+		name:          "probe wraps at maximum tag",
+		inTag:         protoMaxFieldTag,
+		inDefinedTags: map[uint32]bool{protoMaxFieldTag: true},
+		want:          1001,
+	}}
+
+	for _, tt := range tests {
+		got := uniqueFieldTag(tt.inTag, tt.inDefinedTags)
+		if got != tt.want {
+			t.Errorf("%s: uniqueFieldTag(%d, %v): did not get expected tag, got: %d, want: %d", tt.name, tt.inTag, tt.inDefinedTags, got, tt.want)
+		}
+		if !tt.inDefinedTags[got] {
+			t.Errorf("%s: uniqueFieldTag(%d, ...): returned tag %d was not added to defined tags", tt.name, tt.inTag, got)
+		}
+	}
+}
+
+func TestFieldTagRange(t *testing.T) {
+	for i := 0; i < 50000; i++ {
+		p := fmt.Sprintf("/module/container/leaf-%d", i)
+		got, err := fieldTag(p)
+		if err != nil {
+			t.Fatalf("fieldTag(%s): got unexpected error: %v", p, err)
+		}
+		if !validFieldTag(got) {
+			t.Errorf("fieldTag(%s): got invalid tag %d", p, got)
+		}
+		if again, _ := fieldTag(p); again != got {
+			t.Errorf("fieldTag(%s): tag not stable, got: %d, then: %d", p, got, again)
+		}
+	}
+}
+
+func TestValidateEnumReferences(t *testing.T) {
+	tests := []struct {
+		name     string
+		inMsgs   map[string][]protoMsg
+		inEnums  map[string][]protoEnum
+		wantErrs int
+	}{{
+		name: "no enum references",
+		inMsgs: map[string][]protoMsg{
+			"base": {{
+				Name: "MessageName",
+				Fields: []*protoMsgField{{
+					Name: "field_one",
+					Type: "ywrapper.StringValue",
+				}, {
+					Name: "field_two",
+					Type: "string",
+				}, {
+					Name: "field_three",
+					Type: "base.a.Child",
+				}, {
+					Name: "field_four",
+					Type: "Sibling",
+				}},
+			}, {
+				Name: "Sibling",
+			}},
+			"base.a": {{
+				Name: "Child",
+			}},
+		},
+	}, {
+		name: "embedded enum that is defined",
+		inMsgs: map[string][]protoMsg{
+			"base": {{
+				Name: "MessageName",
+				Fields: []*protoMsgField{{
+					Name: "field_one",
+					Type: "FieldOne",
+				}},
+				Enums: map[string]*protoMsgEnum{
+					"FieldOne": {Values: map[int64]protoEnumValue{0: {ProtoLabel: "UNSET"}}},
+				},
+			}},
+		},
+	}, {
+		name: "embedded enum that is not defined",
+		inMsgs: map[string][]protoMsg{
+			"base": {{
+				Name: "MessageName",
+				Fields: []*protoMsgField{{
+					Name: "field_one",
+					Type: "FieldOne",
+				}},
+			}},
+		},
+		wantErrs: 1,
+	}, {
+		name: "global enum that is defined",
+		inMsgs: map[string][]protoMsg{
+			"base": {{
+				Name: "MessageName",
+				Fields: []*protoMsgField{{
+					Name: "field_one",
+					Type: "base.enums.ModuleFieldOne",
+				}},
+			}},
+		},
+		inEnums: map[string][]protoEnum{"base.enums": {{Name: "ModuleFieldOne"}}},
+	}, {
+		name: "global enum within a oneof that is not defined",
+		inMsgs: map[string][]protoMsg{
+			"base": {{
+				Name: "MessageName",
+				Fields: []*protoMsgField{{
+					Name:    "field_one",
+					IsOneOf: true,
+					OneOfFields: []*protoMsgField{{
+						Name: "field_one_string",
+						Type: "string",
+					}, {
+						Name: "field_one_modulefieldone",
+						Type: "base.enums.ModuleFieldOne",
+					}},
+				}},
+			}},
+		},
+		inEnums:  map[string][]protoEnum{"base.enums": {{Name: "ModuleFieldTwo"}}},
+		wantErrs: 1,
+	}, {
+		name: "enum with the name of a message in another package",
+		inMsgs: map[string][]protoMsg{
+			"base": {{
+				Name: "MessageName",
+				Fields: []*protoMsgField{{
+					Name: "field_one",
+					Type: "base.enums.Child",
+				}},
+			}},
+			"base.a": {{
+				Name: "Child",
+			}},
+		},
+		wantErrs: 1,
+	}, {
+		name: "map of list messages",
+		inMsgs: map[string][]protoMsg{
+			"base": {{
+				Name: "MessageName",
+				Fields: []*protoMsgField{{
+					Name: "list",
+					Type: "map<string, base.a.List>",
+				}},
+			}},
+			"base.a": {{
+				Name: "List",
+			}},
+		},
+	}, {
+		name: "map whose value type is not defined",
+		inMsgs: map[string][]protoMsg{
+			"base": {{
+				Name: "MessageName",
+				Fields: []*protoMsgField{{
+					Name: "list",
+					Type: "map<uint32, base.enums.Missing>",
+				}},
+			}},
+		},
+		wantErrs: 1,
+	}, {
+		name: "message within the unions package",
+		inMsgs: map[string][]protoMsg{
+			"base": {{
+				Name: "MessageName",
+				Fields: []*protoMsgField{{
+					Name: "field_one",
+					Type: "base.unions.UnionTypedef",
+				}},
+			}},
+			"base.unions": {{
+				Name: "UnionTypedef",
+				Fields: []*protoMsgField{{
+					Name:    "union_typedef",
+					IsOneOf: true,
+					OneOfFields: []*protoMsgField{{
+						Name: "union_typedef_string",
+						Type: "string",
+					}, {
+						Name: "union_typedef_modulefieldone",
+						Type: "base.enums.ModuleFieldOne",
+					}},
+				}},
+			}},
+		},
+		inEnums: map[string][]protoEnum{"base.enums": {{Name: "ModuleFieldOne"}}},
+	}, {
+		name: "message nested within a message",
+		inMsgs: map[string][]protoMsg{
+			"base": {{
+				Name: "MessageName",
+				Fields: []*protoMsgField{{
+					Name: "field_one",
+					Type: "base.a.Parent.Child",
+				}},
+			}},
+			"base.a": {{
+				Name: "Parent",
+			}},
+		},
+	}}
+
+	for _, tt := range tests {
+		if got := validateEnumReferences(tt.inMsgs, tt.inEnums); len(got) != tt.wantErrs {
+			t.Errorf("%s: validateEnumReferences(%v, %v): did not get expected number of errors, got: %v, want: %d errors", tt.name, tt.inMsgs, tt.inEnums, got, tt.wantErrs)
+		}
+	}
+}
+
+func TestCheckEnumWireCompat(t *testing.T) {
+	enum := func(name string, values map[int64]string) protoEnum {
+		e := protoEnum{Name: name, Values: map[int64]protoEnumValue{}}
+		for n, l := range values {
+			e.Values[n] = protoEnumValue{ProtoLabel: l}
+		}
+		return e
+	}
+
+	tests := []struct {
+		name     string
+		inPrev   []protoEnum
+		inNext   []protoEnum
+		wantErrs []string
+	}{{
+		name:   "identical enums",
+		inPrev: []protoEnum{enum("Speed", map[int64]string{0: "UNSET", 1: "FAST", 2: "SLOW"})},
+		inNext: []protoEnum{enum("Speed", map[int64]string{0: "UNSET", 1: "FAST", 2: "SLOW"})},
+	}, {
+		name:     "renumbered value",
+		inPrev:   []protoEnum{enum("Speed", map[int64]string{0: "UNSET", 1: "FAST", 2: "SLOW"})},
+		inNext:   []protoEnum{enum("Speed", map[int64]string{0: "UNSET", 1: "FAST", 3: "SLOW"})},
+		wantErrs: []string{"enum Speed value SLOW changed from 2 to 3"},
+	}, {
+		name:     "removed value",
+		inPrev:   []protoEnum{enum("Speed", map[int64]string{0: "UNSET", 1: "FAST", 2: "SLOW"})},
+		inNext:   []protoEnum{enum("Speed", map[int64]string{0: "UNSET", 1: "FAST"})},
+		wantErrs: []string{"enum Speed value SLOW (2) was removed"},
+	}, {
+		name:   "added value",
+		inPrev: []protoEnum{enum("Speed", map[int64]string{0: "UNSET", 1: "FAST"})},
+		inNext: []protoEnum{enum("Speed", map[int64]string{0: "UNSET", 1: "FAST", 2: "SLOW"})},
+	}, {
+		name:   "added enum",
+		inPrev: []protoEnum{enum("Speed", map[int64]string{0: "UNSET"})},
+		inNext: []protoEnum{
+			enum("Duplex", map[int64]string{0: "UNSET", 1: "FULL"}),
+			enum("Speed", map[int64]string{0: "UNSET"}),
+		},
+	}, {
+		name:     "removed enum",
+		inPrev:   []protoEnum{enum("Speed", map[int64]string{0: "UNSET"})},
+		wantErrs: []string{"enum Speed was removed"},
+	}, {
+		name:   "value that becomes an alias of another",
+		inPrev: []protoEnum{enum("Speed", map[int64]string{0: "UNSET", 1: "FAST", 2: "QUICK"})},
+		inNext: []protoEnum{{
+			Name: "Speed",
+			Values: map[int64]protoEnumValue{
+				0: {ProtoLabel: "UNSET"},
+				1: {ProtoLabel: "FAST", Aliases: []protoEnumValue{{ProtoLabel: "QUICK"}}},
+			},
+			AllowAlias: true,
+		}},
+		wantErrs: []string{"enum Speed value QUICK changed from 2 to 1"},
+	}, {
+		name: "errors are ordered by enum and label",
+		inPrev: []protoEnum{
+			enum("Speed", map[int64]string{0: "UNSET", 1: "FAST", 2: "SLOW"}),
+			enum("Duplex", map[int64]string{0: "UNSET", 1: "FULL"}),
+		},
+		inNext: []protoEnum{enum("Speed", map[int64]string{0: "UNSET", 2: "FAST", 1: "SLOW"})},
+		wantErrs: []string{
+			"enum Duplex was removed",
+			"enum Speed value FAST changed from 1 to 2",
+			"enum Speed value SLOW changed from 2 to 1",
+		},
+	}}
+
+	for _, tt := range tests {
+		var got []string
+		for _, err := range checkEnumWireCompat(tt.inPrev, tt.inNext) {
+			got = append(got, err.Error())
+		}
+		if diff := pretty.Compare(got, tt.wantErrs); diff != "" {
+			t.Errorf("%s: checkEnumWireCompat(%v, %v): did not get expected errors, diff(-got,+want):\n%s", tt.name, tt.inPrev, tt.inNext, diff)
+		}
+	}
+}
+
+func TestReservedFieldTags(t *testing.T) {
+	tests := []struct {
+		name          string
+		inPrevious    []uint32
+		inDefinedTags map[uint32]bool
+		want          []uint32
+	}{{
+		name: "no previous tags",
+	}, {
+		name:          "all previous tags in use",
+		inPrevious:    []uint32{1001, 1002},
+		inDefinedTags: map[uint32]bool{1001: true, 1002: true},
+	}, {
+		name:          "unused tags are sorted and de-duplicated",
+		inPrevious:    []uint32{5000, 1001, 3000, 5000},
+		inDefinedTags: map[uint32]bool{1001: true},
+		want:          []uint32{3000, 5000},
+	}}
+
+	for _, tt := range tests {
+		if got := reservedFieldTags(tt.inPrevious, tt.inDefinedTags); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: reservedFieldTags(%v, %v): did not get expected tags, got: %v, want: %v", tt.name, tt.inPrevious, tt.inDefinedTags, got, tt.want)
+		}
+	}
+}
+
+func TestGenProto3MsgUnionOrderStable(t *testing.T) {
+	msg := &yangDirectory{
+		name: "MessageName",
+		entry: &yang.Entry{
+			Name: "message-name",
+			Dir:  map[string]*yang.Entry{},
+			Kind: yang.DirectoryEntry,
+		},
+		fields: map[string]*yang.Entry{
+			"field-one": {
+				Name: "field-one",
+				Type: &yang.YangType{
+					Kind: yang.Yunion,
+					Type: []*yang.YangType{
+						{Kind: yang.Ystring},
+						{Kind: yang.Yuint8},
+						{Kind: yang.Ybool},
+						{Kind: yang.Yint16},
+						{Kind: yang.Ydecimal64},
+					},
+				},
+			},
+		},
+		path: []string{"", "root", "message-name"},
+	}
+
+	want := []*protoMsgField{{
+		Tag:  503279666,
+		Name: "field_one_bool",
+		Type: "bool",
+	}, {
+		Tag:  225170402,
+		Name: "field_one_sint64",
+		Type: "sint64",
+	}, {
+		Tag:  299030977,
+		Name: "field_one_string",
+		Type: "string",
+	}, {
+		Tag:  7680484,
+		Name: "field_one_uint64",
+		Type: "uint64",
+	}, {
+		Tag:  417260256,
+		Name: "field_one_decimal64value",
+		Type: "ywrapper.Decimal64Value",
+	}}
+
+	for i := 0; i < 10; i++ {
+		got, errs := genProto3Msg(msg, nil, newGenState(), &protoMsgConfig{basePackageName: "base", enumPackageName: "enums"}, "", nil)
+		if errs != nil {
+			t.Fatalf("genProto3Msg(%v): could not generate message, got errors: %v", msg, errs)
+		}
+		if len(got) != 1 || len(got[0].Fields) != 1 {
+			t.Fatalf("genProto3Msg(%v): did not get expected single message with one field, got: %v", msg, got)
+		}
+		if diff := pretty.Compare(got[0].Fields[0].OneOfFields, want); diff != "" {
+			t.Fatalf("genProto3Msg(%v): run %d did not get expected oneof members, diff(-got,+want):\n%s", msg, i, diff)
+		}
+	}
+}
+
+// protoMsgDescriptorErrors checks that the fields of the protoMsg msg match
+// those of the descriptor d, which is expected to have been produced by
+// compiling the protobuf rendered for msg. Each field must have the same tag,
+// cardinality and type in both, and fields within a oneof must be within a
+// oneof of the same name in the descriptor. It returns an error for each
+// difference, such that drift between the structured representation of a
+// message and the rendered template can be detected.
+func protoMsgDescriptorErrors(msg *protoMsg, d *descpb.DescriptorProto) []error {
+	var errs []error
+	if msg.Name != d.GetName() {
+		errs = append(errs, fmt.Errorf("message name %s does not match descriptor name %s", msg.Name, d.GetName()))
+	}
+
+	descFields := map[string]*descpb.FieldDescriptorProto{}
+	for _, f := range d.GetField() {
+		descFields[f.GetName()] = f
+	}
+
+	checkField := func(f *protoMsgField, oneof string) {
+		df, ok := descFields[f.Name]
+		if !ok {
+			errs = append(errs, fmt.Errorf("field %s is not in the descriptor", f.Name))
+			return
+		}
+		delete(descFields, f.Name)
+
+		if uint32(df.GetNumber()) != f.Tag {
+			errs = append(errs, fmt.Errorf("field %s has tag %d, descriptor has tag %d", f.Name, f.Tag, df.GetNumber()))
+		}
+		if repeated := df.GetLabel() == descpb.FieldDescriptorProto_LABEL_REPEATED; repeated != f.IsRepeated {
+			errs = append(errs, fmt.Errorf("field %s has repeated %v, descriptor has repeated %v", f.Name, f.IsRepeated, repeated))
+		}
+
+		switch {
+		case protoScalarTypes[f.Type]:
+			if want := descpb.FieldDescriptorProto_Type(descpb.FieldDescriptorProto_Type_value["TYPE_"+strings.ToUpper(f.Type)]); df.GetType() != want {
+				errs = append(errs, fmt.Errorf("field %s has type %s, descriptor has type %s", f.Name, want, df.GetType()))
+			}
+		case !strings.HasSuffix(df.GetTypeName(), "."+f.Type):
+			errs = append(errs, fmt.Errorf("field %s has type %s, descriptor has type %s", f.Name, f.Type, df.GetTypeName()))
+		}
+
+		var descOneof string
+		if df.OneofIndex != nil && int(df.GetOneofIndex()) < len(d.GetOneofDecl()) {
+			descOneof = d.GetOneofDecl()[df.GetOneofIndex()].GetName()
+		}
+		if descOneof != oneof {
+			errs = append(errs, fmt.Errorf("field %s is within oneof %q, descriptor has oneof %q", f.Name, oneof, descOneof))
+		}
+	}
+
+	for _, f := range msg.Fields {
+		if !f.IsOneOf {
+			checkField(f, "")
+			continue
+		}
+		for _, of := range f.OneOfFields {
+			checkField(of, f.Name)
+		}
+	}
+
+	for n := range descFields {
+		errs = append(errs, fmt.Errorf("descriptor field %s is not in the message", n))
+	}
+	return errs
+}
+
+func TestProtoMsgDescriptorErrors(t *testing.T) {
+	msg := &protoMsg{
+		Name: "MessageName",
+		Fields: []*protoMsgField{{
+			Tag:  410095931,
+			Name: "field_one",
+			Type: "ywrapper.StringValue",
+		}, {
+			Tag:        332121324,
+			Name:       "field_two",
+			Type:       "sint64",
+			IsRepeated: true,
+		}, {
+			Name:    "field_three",
+			IsOneOf: true,
+			OneOfFields: []*protoMsgField{{
+				Tag:  225170402,
+				Name: "field_three_sint64",
+				Type: "sint64",
+			}, {
+				Tag:  299030977,
+				Name: "field_three_basederivedenumenum",
+				Type: "base.enums.BaseDerivedEnumEnum",
+			}},
+		}},
+	}
+
+	// descriptor returns the descriptor that corresponds to msg, with the
+	// field named f modified by the function mod, if specified.
+	descriptor := func(f string, mod func(*descpb.FieldDescriptorProto)) *descpb.DescriptorProto {
+		d := &descpb.DescriptorProto{
+			Name: proto.String("MessageName"),
+			Field: []*descpb.FieldDescriptorProto{{
+				Name:     proto.String("field_one"),
+				Number:   proto.Int32(410095931),
+				Label:    descpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+				Type:     descpb.FieldDescriptorProto_TYPE_MESSAGE.Enum(),
+				TypeName: proto.String(".ywrapper.StringValue"),
+			}, {
+				Name:   proto.String("field_two"),
+				Number: proto.Int32(332121324),
+				Label:  descpb.FieldDescriptorProto_LABEL_REPEATED.Enum(),
+				Type:   descpb.FieldDescriptorProto_TYPE_SINT64.Enum(),
+			}, {
+				Name:       proto.String("field_three_sint64"),
+				Number:     proto.Int32(225170402),
+				Label:      descpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+				Type:       descpb.FieldDescriptorProto_TYPE_SINT64.Enum(),
+				OneofIndex: proto.Int32(0),
+			}, {
+				Name:       proto.String("field_three_basederivedenumenum"),
+				Number:     proto.Int32(299030977),
+				Label:      descpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+				Type:       descpb.FieldDescriptorProto_TYPE_ENUM.Enum(),
+				TypeName:   proto.String(".base.enums.BaseDerivedEnumEnum"),
+				OneofIndex: proto.Int32(0),
+			}},
+			OneofDecl: []*descpb.OneofDescriptorProto{{
+				Name: proto.String("field_three"),
+			}},
+		}
+		for _, df := range d.Field {
+			if df.GetName() == f && mod != nil {
+				mod(df)
+			}
+		}
+		return d
+	}
+
+	tests := []struct {
+		name         string
+		inDescriptor *descpb.DescriptorProto
+		wantErrs     int
+	}{{
+		name:         "matching descriptor",
+		inDescriptor: descriptor("", nil),
+	}, {
+		name: "mismatched tag",
+		inDescriptor: descriptor("field_one", func(f *descpb.FieldDescriptorProto) {
+			f.Number = proto.Int32(42)
+		}),
+		wantErrs: 1,
+	}, {
+		name: "mismatched scalar type",
+		inDescriptor: descriptor("field_two", func(f *descpb.FieldDescriptorProto) {
+			f.Type = descpb.FieldDescriptorProto_TYPE_UINT64.Enum()
+		}),
+		wantErrs: 1,
+	}, {
+		name: "mismatched cardinality",
+		inDescriptor: descriptor("field_two", func(f *descpb.FieldDescriptorProto) {
+			f.Label = descpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum()
+		}),
+		wantErrs: 1,
+	}, {
+		name: "mismatched message type",
+		inDescriptor: descriptor("field_three_basederivedenumenum", func(f *descpb.FieldDescriptorProto) {
+			f.TypeName = proto.String(".base.enums.OtherEnum")
+		}),
+		wantErrs: 1,
+	}, {
+		name: "field outside of oneof",
+		inDescriptor: descriptor("field_three_sint64", func(f *descpb.FieldDescriptorProto) {
+			f.OneofIndex = nil
+		}),
+		wantErrs: 1,
+	}, {
+		name: "field missing from descriptor and field missing from message",
+		inDescriptor: descriptor("field_one", func(f *descpb.FieldDescriptorProto) {
+			f.Name = proto.String("field_four")
+		}),
+		wantErrs: 2,
+	}}
+
+	for _, tt := range tests {
+		if got := protoMsgDescriptorErrors(msg, tt.inDescriptor); len(got) != tt.wantErrs {
+			t.Errorf("%s: protoMsgDescriptorErrors(%v, %v): did not get expected number of errors, got: %v, want: %d errors", tt.name, msg, tt.inDescriptor, got, tt.wantErrs)
+		}
+	}
+}
+
+func TestGenProto3MsgWhenPolicy(t *testing.T) {
+	msg := &yangDirectory{
+		name: "MessageName",
+		entry: &yang.Entry{
+			Name: "message-name",
+			Dir:  map[string]*yang.Entry{},
+			Kind: yang.DirectoryEntry,
+		},
+		fields: map[string]*yang.Entry{
+			"field-one": {
+				Name: "field-one",
+				Type: &yang.YangType{Kind: yang.Ystring},
+				Node: &yang.Leaf{Name: "field-one"},
+			},
+			"field-two": {
+				Name: "field-two",
+				Type: &yang.YangType{Kind: yang.Ystring},
+				Node: &yang.Leaf{
+					Name: "field-two",
+					When: &yang.Value{Name: `../field-one = "a"`},
+				},
+			},
+		},
+		path: []string{"", "root", "message-name"},
+	}
+
+	fieldOne := &protoMsgField{
+		Tag:  410095931,
+		Name: "field_one",
+		Type: "ywrapper.StringValue",
+	}
+
+	tests := []struct {
+		name         string
+		inWhenPolicy ProtoWhenPolicy
+		wantFields   []*protoMsgField
+	}{{
+		name:         "include when nodes",
+		inWhenPolicy: IncludeWhenNodes,
+		wantFields: []*protoMsgField{{
+			Tag:  25944937,
+			Name: "field_two",
+			Type: "ywrapper.StringValue",
+		}, fieldOne},
+	}, {
+		name:         "exclude when nodes",
+		inWhenPolicy: ExcludeWhenNodes,
+		wantFields:   []*protoMsgField{fieldOne},
+	}, {
+		name:         "annotate when nodes",
+		inWhenPolicy: AnnotateWhenNodes,
+		wantFields: []*protoMsgField{{
+			Tag:  25944937,
+			Name: "field_two",
+			Type: "ywrapper.StringValue",
+			Options: []*protoOption{{
+				Name:  "(yext.when)",
+				Value: `"../field-one = \"a\""`,
+			}},
+		}, fieldOne},
+	}}
+
+	for _, tt := range tests {
+		got, errs := genProto3Msg(msg, nil, newGenState(), &protoMsgConfig{
+			basePackageName: "base",
+			enumPackageName: "enums",
+			whenPolicy:      tt.inWhenPolicy,
+		}, "", nil)
+		if errs != nil {
+			t.Errorf("%s: genProto3Msg(%v): got unexpected errors: %v", tt.name, msg, errs)
+			continue
+		}
+		if len(got) != 1 {
+			t.Errorf("%s: genProto3Msg(%v): did not get expected single message, got: %v", tt.name, msg, got)
+			continue
+		}
+		if diff := pretty.Compare(got[0].Fields, tt.wantFields); diff != "" {
+			t.Errorf("%s: genProto3Msg(%v): did not get expected fields, diff(-got,+want):\n%s", tt.name, msg, diff)
+		}
+	}
+}
+
+func TestGenProto3MsgLeafListSemantics(t *testing.T) {
+	leafList := func(name string, orderedBy *yang.Value) *yang.Entry {
+		return &yang.Entry{
+			Name:     name,
+			Type:     &yang.YangType{Kind: yang.Ystring},
+			ListAttr: &yang.ListAttr{OrderedBy: orderedBy},
+			Node:     &yang.LeafList{Name: name},
+		}
+	}
+	msg := &yangDirectory{
+		name: "MessageName",
+		entry: &yang.Entry{
+			Name: "message-name",
+			Dir:  map[string]*yang.Entry{},
+			Kind: yang.DirectoryEntry,
+		},
+		fields: map[string]*yang.Entry{
+			"default-ordered": leafList("default-ordered", nil),
+			"system-ordered":  leafList("system-ordered", &yang.Value{Name: "system"}),
+			"user-ordered":    leafList("user-ordered", &yang.Value{Name: "user"}),
+			"leaf": {
+				Name: "leaf",
+				Type: &yang.YangType{Kind: yang.Ystring},
+				Node: &yang.Leaf{Name: "leaf"},
+			},
+		},
+		path: []string{"", "root", "message-name"},
+	}
+
+	setOpt := []*protoOption{{Name: "(yext.leaflist_semantics)", Value: `"set"`}}
+	listOpt := []*protoOption{{Name: "(yext.leaflist_semantics)", Value: `"list"`}}
+
+	tests := []struct {
+		name                string
+		inLeafListSemantics bool
+		wantOptions         map[string][]*protoOption
+	}{{
+		name:        "leaf-lists not annotated",
+		wantOptions: map[string][]*protoOption{},
+	}, {
+		name:                "leaf-lists annotated according to ordered-by",
+		inLeafListSemantics: true,
+		wantOptions: map[string][]*protoOption{
+			"default_ordered": setOpt,
+			"system_ordered":  setOpt,
+			"user_ordered":    listOpt,
+		},
+	}}
+
+	for _, tt := range tests {
+		got, errs := genProto3Msg(msg, nil, newGenState(), &protoMsgConfig{
+			basePackageName:   "base",
+			enumPackageName:   "enums",
+			leafListSemantics: tt.inLeafListSemantics,
+		}, "", nil)
+		if errs != nil {
+			t.Errorf("%s: genProto3Msg(%v): got unexpected errors: %v", tt.name, msg, errs)
+			continue
+		}
+		if len(got) != 1 {
+			t.Errorf("%s: genProto3Msg(%v): did not get expected single message, got: %v", tt.name, msg, got)
+			continue
+		}
+
+		gotOptions := map[string][]*protoOption{}
+		for _, f := range got[0].Fields {
+			if len(f.Options) != 0 {
+				gotOptions[f.Name] = f.Options
+			}
+		}
+		if diff := pretty.Compare(gotOptions, tt.wantOptions); diff != "" {
+			t.Errorf("%s: genProto3Msg(%v): did not get expected field options, diff(-got,+want):\n%s", tt.name, msg, diff)
+		}
+	}
+}
+
+func TestGenProto3MsgGoogleWrappers(t *testing.T) {
+	msg := &yangDirectory{
+		name: "MessageName",
+		entry: &yang.Entry{
+			Name: "message-name",
+			Dir:  map[string]*yang.Entry{},
+			Kind: yang.DirectoryEntry,
+		},
+		fields: map[string]*yang.Entry{
+			"counter": {
+				Name: "counter",
+				Type: &yang.YangType{Kind: yang.Yuint64},
+				Node: &yang.Leaf{Name: "counter"},
+			},
+			"description": {
+				Name: "description",
+				Type: &yang.YangType{Kind: yang.Ystring},
+				Node: &yang.Leaf{Name: "description"},
+			},
+		},
+		path: []string{"", "root", "message-name"},
+	}
+
+	tests := []struct {
+		name             string
+		inGoogleWrappers bool
+		wantFields       []*protoMsgField
+	}{{
+		name: "ywrapper types",
+		wantFields: []*protoMsgField{{
+			Tag:  326592766,
+			Name: "description",
+			Type: "ywrapper.StringValue",
+		}, {
+			Tag:  508538396,
+			Name: "counter",
+			Type: "ywrapper.UintValue",
+		}},
+	}, {
+		name:             "google.protobuf wrapper types",
+		inGoogleWrappers: true,
+		wantFields: []*protoMsgField{{
+			Tag:  326592766,
+			Name: "description",
+			Type: "google.protobuf.StringValue",
+		}, {
+			Tag:  508538396,
+			Name: "counter",
+			Type: "google.protobuf.UInt64Value",
+		}},
+	}}
+
+	for _, tt := range tests {
+		got, errs := genProto3Msg(msg, nil, newGenState(), &protoMsgConfig{
+			basePackageName: "base",
+			enumPackageName: "enums",
+			googleWrappers:  tt.inGoogleWrappers,
+		}, "", nil)
+		if errs != nil {
+			t.Errorf("%s: genProto3Msg(%v): got unexpected errors: %v", tt.name, msg, errs)
+			continue
+		}
+		if len(got) != 1 {
+			t.Errorf("%s: genProto3Msg(%v): did not get expected single message, got: %v", tt.name, msg, got)
+			continue
+		}
+		if diff := pretty.Compare(got[0].Fields, tt.wantFields); diff != "" {
+			t.Errorf("%s: genProto3Msg(%v): did not get expected fields, diff(-got,+want):\n%s", tt.name, msg, diff)
+		}
+	}
+}
+
+func TestGenProto3MsgTypedefUnions(t *testing.T) {
+	enumType := yang.NewEnumType()
+	enumType.Set("ONE", int64(1))
+	enumType.Set("TWO", int64(2))
+
+	typedefUnion := &yang.YangType{
+		Name: "typedef-union",
+		Kind: yang.Yunion,
+		Type: []*yang.YangType{
+			{Kind: yang.Ystring},
+			{Kind: yang.Yuint32},
+			{Name: "enumeration", Kind: yang.Yenum, Enum: enumType},
+		},
+	}
+	mod := &yang.Module{Name: "test-module"}
+	leaf := func(name string, t *yang.YangType) *yang.Entry {
+		return &yang.Entry{
+			Name: name,
+			Type: t,
+			Node: &yang.Leaf{Name: name, Parent: mod},
+		}
+	}
+	msg := &yangDirectory{
+		name: "MessageName",
+		entry: &yang.Entry{
+			Name: "message-name",
+			Dir:  map[string]*yang.Entry{},
+			Kind: yang.DirectoryEntry,
+		},
+		fields: map[string]*yang.Entry{
+			"first":  leaf("first", typedefUnion),
+			"second": leaf("second", typedefUnion),
+			"inline": leaf("inline", &yang.YangType{
+				Name: "union",
+				Kind: yang.Yunion,
+				Type: []*yang.YangType{{Kind: yang.Ystring}, {Kind: yang.Ybool}},
+			}),
+		},
+		path: []string{"", "root", "message-name"},
+	}
+
+	tests := []struct {
+		name            string
+		inTypedefUnions bool
+		wantTypes       map[string]string
+		wantUnionMsgs   map[string]*protoMsg
+		wantImports     []string
+	}{{
+		name: "unions output as oneofs",
+		wantTypes: map[string]string{
+			"first":  "",
+			"inline": "",
+			"second": "",
+		},
+		wantUnionMsgs: map[string]*protoMsg{},
+	}, {
+		name:            "typedef unions output as shared message",
+		inTypedefUnions: true,
+		wantTypes: map[string]string{
+			"first":  "base.unions.TypedefUnion",
+			"inline": "",
+			"second": "base.unions.TypedefUnion",
+		},
+		wantUnionMsgs: map[string]*protoMsg{
+			"test-module/typedef-union": {
+				Name:     "TypedefUnion",
+				YANGPath: "test-module typedef typedef-union",
+				Fields: []*protoMsgField{{
+					Name:    "value",
+					IsOneOf: true,
+					OneOfFields: []*protoMsgField{{
+						Tag:  402385173,
+						Name: "value_value",
+						Type: "Value",
+					}, {
+						Tag:  55104453,
+						Name: "value_string",
+						Type: "string",
+					}, {
+						Tag:  128511048,
+						Name: "value_uint64",
+						Type: "uint64",
+					}},
+				}},
+				Enums: map[string]*protoMsgEnum{
+					"Value": {
+						Values: map[int64]protoEnumValue{
+							0: {ProtoLabel: "UNSET"},
+							2: {ProtoLabel: "ONE"},
+							3: {ProtoLabel: "TWO"},
+						},
+					},
+				},
+			},
+		},
+		wantImports: []string{"base/unions/unions.proto"},
+	}}
+
+	for _, tt := range tests {
+		s := newGenState()
+		got, errs := genProto3Msg(msg, nil, s, &protoMsgConfig{
+			basePackageName: "base",
+			enumPackageName: "enums",
+			typedefUnions:   tt.inTypedefUnions,
+		}, "", nil)
+		if errs != nil {
+			t.Errorf("%s: genProto3Msg(%v): got unexpected errors: %v", tt.name, msg, errs)
+			continue
+		}
+		if len(got) != 1 {
+			t.Errorf("%s: genProto3Msg(%v): did not get expected single message, got: %v", tt.name, msg, got)
+			continue
+		}
+
+		gotTypes := map[string]string{}
+		for _, f := range got[0].Fields {
+			if f.IsOneOf == (tt.wantTypes[f.Name] != "") {
+				t.Errorf("%s: genProto3Msg(%v): field %s has unexpected oneof status, got: %v", tt.name, msg, f.Name, f.IsOneOf)
+			}
+			gotTypes[f.Name] = f.Type
+		}
+		if diff := pretty.Compare(gotTypes, tt.wantTypes); diff != "" {
+			t.Errorf("%s: genProto3Msg(%v): did not get expected field types, diff(-got,+want):\n%s", tt.name, msg, diff)
+		}
+		if diff := pretty.Compare(got[0].Imports, tt.wantImports); diff != "" {
+			t.Errorf("%s: genProto3Msg(%v): did not get expected imports, diff(-got,+want):\n%s", tt.name, msg, diff)
+		}
+		if diff := pretty.Compare(s.protoTypedefUnions, tt.wantUnionMsgs); diff != "" {
+			t.Errorf("%s: genProto3Msg(%v): did not get expected shared union messages, diff(-got,+want):\n%s", tt.name, msg, diff)
+		}
+	}
+}
+
+func TestGenProto3MsgNestedListKeys(t *testing.T) {
+	root := &yang.Entry{Name: "root"}
+	keyLeaf := func(name string, parent *yang.Entry) *yang.Entry {
+		return &yang.Entry{
+			Name:   name,
+			Type:   &yang.YangType{Kind: yang.Ystring},
+			Parent: parent,
+		}
+	}
+	list := func(name, key string, parent *yang.Entry) *yang.Entry {
+		e := &yang.Entry{
+			Name:     name,
+			Kind:     yang.DirectoryEntry,
+			Key:      key,
+			ListAttr: &yang.ListAttr{},
+			Parent:   parent,
+			Dir:      map[string]*yang.Entry{},
+		}
+		e.Dir[key] = keyLeaf(key, e)
+		return e
+	}
+
+	// Both outer lists, a and c, contain a list named b, whose messages are
+	// each named B, within the a and c packages respectively.
+	outerA, outerC := list("a", "name", root), list("c", "name", root)
+	innerAB, innerCB := list("b", "id", outerA), list("b", "id", outerC)
+	outerA.Dir["b"], outerC.Dir["b"] = innerAB, innerCB
+
+	dir := func(name string, e *yang.Entry) *yangDirectory {
+		fields := map[string]*yang.Entry{}
+		for k, v := range e.Dir {
+			fields[k] = v
+		}
+		return &yangDirectory{
+			name:   name,
+			entry:  e,
+			fields: fields,
+			path:   strings.Split(e.Path(), "/"),
+		}
+	}
+	msgs := map[string]*yangDirectory{
+		"/root/a":   dir("A", outerA),
+		"/root/c":   dir("C", outerC),
+		"/root/a/b": dir("B", innerAB),
+		"/root/c/b": dir("B", innerCB),
+	}
+	uniqueNames := map[string]string{
+		"/root/a":   "A",
+		"/root/c":   "C",
+		"/root/a/b": "B",
+		"/root/c/b": "B",
+	}
+
+	tests := []struct {
+		name                string
+		inQualifyNestedKeys bool
+		inNestedMessages    bool
+		wantKeyMsgs         map[string]string
+		wantListRefs        map[string]string
+	}{{
+		name: "nested list keys not qualified",
+		wantKeyMsgs: map[string]string{
+			"/root/a": "BKey",
+			"/root/c": "BKey",
+		},
+		wantListRefs: map[string]string{
+			"/root/a": "root.a.B",
+			"/root/c": "root.c.B",
+		},
+	}, {
+		name:                "nested list keys qualified by enclosing list",
+		inQualifyNestedKeys: true,
+		wantKeyMsgs: map[string]string{
+			"/root/a": "ABKey",
+			"/root/c": "CBKey",
+		},
+		wantListRefs: map[string]string{
+			"/root/a": "root.a.B",
+			"/root/c": "root.c.B",
+		},
+	}, {
+		name:                "nested list keys qualified by enclosing list, nested messages",
+		inQualifyNestedKeys: true,
+		inNestedMessages:    true,
+		wantKeyMsgs: map[string]string{
+			"/root/a": "ABKey",
+			"/root/c": "CBKey",
+		},
+	}}
+
+	for _, tt := range tests {
+		s := newGenState()
+		s.uniqueDirectoryNames = uniqueNames
+		cfg := &protoMsgConfig{
+			basePackageName:   "base",
+			enumPackageName:   "enums",
+			nestedMessages:    tt.inNestedMessages,
+			qualifyNestedKeys: tt.inQualifyNestedKeys,
+		}
+		for _, p := range []string{"/root/a", "/root/c"} {
+			got, errs := genProto3Msg(msgs[p], msgs, s, cfg, "", nil)
+			if errs != nil {
+				t.Errorf("%s: genProto3Msg(%s): got unexpected errors: %v", tt.name, p, errs)
+				continue
+			}
+
+			// The message for the enclosing list is output after the key
+			// messages that it references.
+			listMsg := got[len(got)-1]
+			var fieldType string
+			for _, f := range listMsg.Fields {
+				if f.Name == "b" {
+					fieldType = f.Type
+				}
+			}
+			if want := tt.wantKeyMsgs[p]; fieldType != want {
+				t.Errorf("%s: genProto3Msg(%s): did not get expected type for field b, got: %s, want: %s", tt.name, p, fieldType, want)
+			}
+
+			// The key message is output as a child message of the enclosing
+			// list's message when nested messages are output, and alongside it
+			// otherwise.
+			var gotKeyMsgs []string
+			if tt.inNestedMessages {
+				for _, c := range listMsg.ChildMsgs {
+					if f := strings.Fields(c.MessageCode); len(f) > 1 && f[0] == "message" {
+						gotKeyMsgs = append(gotKeyMsgs, f[1])
+					}
+				}
+			} else {
+				for _, m := range got[:len(got)-1] {
+					gotKeyMsgs = append(gotKeyMsgs, m.Name)
+					// The key message must reference the message of the list
+					// within the enclosing list's package.
+					if ref := m.Fields[len(m.Fields)-1].Type; ref != tt.wantListRefs[p] {
+						t.Errorf("%s: genProto3Msg(%s): key message %s did not reference expected list message, got: %s, want: %s", tt.name, p, m.Name, ref, tt.wantListRefs[p])
+					}
+				}
+			}
+			if diff := pretty.Compare(gotKeyMsgs, []string{tt.wantKeyMsgs[p]}); diff != "" {
+				t.Errorf("%s: genProto3Msg(%s): did not get expected key messages, diff(-got,+want):\n%s", tt.name, p, diff)
+			}
+		}
+	}
+}
+
+func TestGenProto3MsgSingleKeyListMaps(t *testing.T) {
+	cont := &yang.Entry{
+		Name:   "c",
+		Kind:   yang.DirectoryEntry,
+		Parent: &yang.Entry{Name: "root"},
+		Dir:    map[string]*yang.Entry{},
+	}
+	list := func(name string, keys map[string]yang.TypeKind) *yang.Entry {
+		e := &yang.Entry{
+			Name:     name,
+			Kind:     yang.DirectoryEntry,
+			ListAttr: &yang.ListAttr{},
+			Parent:   cont,
+			Dir:      map[string]*yang.Entry{},
+		}
+		var names []string
+		for k, kind := range keys {
+			names = append(names, k)
+			e.Dir[k] = &yang.Entry{
+				Name:   k,
+				Type:   &yang.YangType{Kind: kind},
+				Parent: e,
+			}
+		}
+		sort.Strings(names)
+		e.Key = strings.Join(names, " ")
+		cont.Dir[name] = e
+		return e
+	}
+	stringList := list("string-list", map[string]yang.TypeKind{"name": yang.Ystring})
+	multiList := list("multi-list", map[string]yang.TypeKind{"name": yang.Ystring, "id": yang.Yuint32})
+	decimalList := list("decimal-list", map[string]yang.TypeKind{"value": yang.Ydecimal64})
+	decimalList.Dir["value"].Type.FractionDigits = 2
+
+	dir := func(name string, e *yang.Entry) *yangDirectory {
+		fields := map[string]*yang.Entry{}
+		for k, v := range e.Dir {
+			fields[k] = v
+		}
+		return &yangDirectory{
+			name:   name,
+			entry:  e,
+			fields: fields,
+			path:   strings.Split(e.Path(), "/"),
+		}
+	}
+	msgs := map[string]*yangDirectory{
+		"/root/c":              dir("C", cont),
+		"/root/c/string-list":  dir("StringList", stringList),
+		"/root/c/multi-list":   dir("MultiList", multiList),
+		"/root/c/decimal-list": dir("DecimalList", decimalList),
+	}
+
+	tests := []struct {
+		name            string
+		inSingleKeyMaps bool
+		wantTypes       map[string]string
+		wantMaps        map[string]bool
+		wantKeyMsgs     []string
+	}{{
+		name: "lists output as repeated key messages",
+		wantTypes: map[string]string{
+			"string_list":  "StringListKey",
+			"multi_list":   "MultiListKey",
+			"decimal_list": "DecimalListKey",
+		},
+		wantMaps:    map[string]bool{},
+		wantKeyMsgs: []string{"DecimalListKey", "MultiListKey", "StringListKey"},
+	}, {
+		name:            "single string-keyed list output as map",
+		inSingleKeyMaps: true,
+		wantTypes: map[string]string{
+			"string_list":  "map<string, root.c.StringList>",
+			"multi_list":   "MultiListKey",
+			"decimal_list": "DecimalListKey",
+		},
+		wantMaps:    map[string]bool{"string_list": true},
+		wantKeyMsgs: []string{"DecimalListKey", "MultiListKey"},
+	}}
+
+	for _, tt := range tests {
+		s := newGenState()
+		s.uniqueDirectoryNames = map[string]string{
+			"/root/c":              "C",
+			"/root/c/string-list":  "StringList",
+			"/root/c/multi-list":   "MultiList",
+			"/root/c/decimal-list": "DecimalList",
+		}
+		got, errs := genProto3Msg(msgs["/root/c"], msgs, s, &protoMsgConfig{
+			basePackageName: "base",
+			enumPackageName: "enums",
+			singleKeyMaps:   tt.inSingleKeyMaps,
+		}, "", nil)
+		if errs != nil {
+			t.Errorf("%s: genProto3Msg: got unexpected errors: %v", tt.name, errs)
+			continue
+		}
+
+		// The message for the container is output after the key messages
+		// that it references.
+		var gotKeyMsgs []string
+		for _, m := range got[:len(got)-1] {
+			gotKeyMsgs = append(gotKeyMsgs, m.Name)
+		}
+		sort.Strings(gotKeyMsgs)
+		if diff := pretty.Compare(gotKeyMsgs, tt.wantKeyMsgs); diff != "" {
+			t.Errorf("%s: genProto3Msg: did not get expected key messages, diff(-got,+want):\n%s", tt.name, diff)
+		}
+
+		gotTypes := map[string]string{}
+		for _, f := range got[len(got)-1].Fields {
+			gotTypes[f.Name] = f.Type
+			if f.IsMap != tt.wantMaps[f.Name] || f.IsRepeated == f.IsMap {
+				t.Errorf("%s: genProto3Msg: field %s has unexpected map status, got map: %v, repeated: %v, want map: %v", tt.name, f.Name, f.IsMap, f.IsRepeated, tt.wantMaps[f.Name])
+			}
+		}
+		if diff := pretty.Compare(gotTypes, tt.wantTypes); diff != "" {
+			t.Errorf("%s: genProto3Msg: did not get expected field types, diff(-got,+want):\n%s", tt.name, diff)
+		}
+	}
+}
+
+func TestGenProto3MsgPresenceContainerInList(t *testing.T) {
+	root := &yang.Entry{Name: "root", Kind: yang.DirectoryEntry, Dir: map[string]*yang.Entry{}}
+	list := &yang.Entry{
+		Name:     "list",
+		Kind:     yang.DirectoryEntry,
+		Key:      "name",
+		ListAttr: &yang.ListAttr{},
+		Parent:   root,
+		Dir:      map[string]*yang.Entry{},
+	}
+	root.Dir["list"] = list
+	list.Dir["name"] = &yang.Entry{
+		Name:   "name",
+		Type:   &yang.YangType{Kind: yang.Ystring},
+		Parent: list,
+		Node:   &yang.Leaf{Name: "name"},
+	}
+	presence := &yang.Entry{
+		Name:   "presence",
+		Kind:   yang.DirectoryEntry,
+		Parent: list,
+		Dir:    map[string]*yang.Entry{},
+		Extra:  map[string][]interface{}{"presence": {&yang.Value{Name: "enabled"}}},
+	}
+	list.Dir["presence"] = presence
+	presence.Dir["value"] = &yang.Entry{
+		Name:   "value",
+		Type:   &yang.YangType{Kind: yang.Ystring},
+		Parent: presence,
+		Node:   &yang.Leaf{Name: "value"},
+	}
+
+	dir := func(name string, e *yang.Entry) *yangDirectory {
+		fields := map[string]*yang.Entry{}
+		for k, v := range e.Dir {
+			fields[k] = v
+		}
+		return &yangDirectory{
+			name:   name,
+			entry:  e,
+			fields: fields,
+			path:   strings.Split(e.Path(), "/"),
+		}
+	}
+	msgs := map[string]*yangDirectory{
+		"/root/list":          dir("List", list),
+		"/root/list/presence": dir("Presence", presence),
+	}
+
+	tests := []struct {
+		name             string
+		inNestedMessages bool
+		wantFields       map[string]*protoMsgField
+	}{{
+		name: "presence container in list, separate packages",
+		wantFields: map[string]*protoMsgField{
+			"presence": {Name: "presence", Type: "list.Presence"},
+		},
+	}, {
+		name:             "presence container in list, nested messages",
+		inNestedMessages: true,
+		wantFields: map[string]*protoMsgField{
+			"presence": {Name: "presence", Type: "Presence"},
+		},
+	}}
+
+	for _, tt := range tests {
+		s := newGenState()
+		s.uniqueDirectoryNames = map[string]string{
+			"/root/list":          "List",
+			"/root/list/presence": "Presence",
+		}
+		got, errs := genProto3Msg(msgs["/root/list"], msgs, s, &protoMsgConfig{
+			basePackageName: "base",
+			enumPackageName: "enums",
+			nestedMessages:  tt.inNestedMessages,
+		}, "root", nil)
+		if errs != nil {
+			t.Errorf("%s: genProto3Msg: got unexpected errors: %v", tt.name, errs)
+			continue
+		}
+		if len(got) != 1 {
+			t.Errorf("%s: genProto3Msg: did not get expected single message, got: %v", tt.name, got)
+			continue
+		}
+
+		// The presence of the container is indicated by whether the singular
+		// message field that represents it is set within the list entry's
+		// message, and hence no separate marker field is output.
+		gotFields := map[string]*protoMsgField{}
+		for _, f := range got[0].Fields {
+			gotFields[f.Name] = &protoMsgField{Name: f.Name, Type: f.Type, IsRepeated: f.IsRepeated}
+		}
+		if diff := pretty.Compare(gotFields, tt.wantFields); diff != "" {
+			t.Errorf("%s: genProto3Msg: did not get expected fields, diff(-got,+want):\n%s", tt.name, diff)
+		}
+	}
+}
+
+func TestGenProto3MsgModuleComment(t *testing.T) {
+	modA, modB := &yang.Module{Name: "mod-a"}, &yang.Module{Name: "mod-b"}
+	subB := &yang.Module{Name: "mod-b-sub", BelongsTo: &yang.BelongsTo{Name: "mod-b"}}
+
+	msg := func(node yang.Node) *yangDirectory {
+		e := &yang.Entry{
+			Name:   "container",
+			Kind:   yang.DirectoryEntry,
+			Parent: &yang.Entry{Name: "mod-a", Kind: yang.DirectoryEntry},
+			Node:   node,
+			Dir:    map[string]*yang.Entry{},
+		}
+		e.Dir["leaf"] = &yang.Entry{
+			Name:   "leaf",
+			Type:   &yang.YangType{Kind: yang.Ystring},
+			Parent: e,
+			Node:   &yang.Leaf{Name: "leaf"},
+		}
+		return &yangDirectory{
+			name:   "Container",
+			entry:  e,
+			fields: map[string]*yang.Entry{"leaf": e.Dir["leaf"]},
+			path:   []string{"", "mod-a", "container"},
+		}
+	}
+
+	tests := []struct {
+		name        string
+		in          *yangDirectory
+		wantComment string
+	}{{
+		name:        "element defined in the module",
+		in:          msg(&yang.Container{Name: "container", Parent: modA}),
+		wantComment: "// Defined in module mod-a.\n",
+	}, {
+		name:        "element augmented by another module",
+		in:          msg(&yang.Container{Name: "container", Parent: &yang.Augment{Name: "/mod-a:root", Parent: modB}}),
+		wantComment: "// Defined in module mod-a, augmented by module mod-b.\n",
+	}, {
+		name:        "element augmented by a submodule",
+		in:          msg(&yang.Container{Name: "container", Parent: &yang.Augment{Name: "/mod-a:root", Parent: subB}}),
+		wantComment: "// Defined in module mod-a, augmented by module mod-b.\n",
+	}, {
+		name:        "element augmented by the same module",
+		in:          msg(&yang.Container{Name: "container", Parent: &yang.Augment{Name: "/mod-a:root", Parent: modA}}),
+		wantComment: "// Defined in module mod-a.\n",
+	}}
+
+	for _, tt := range tests {
+		msgs, errs := genProto3Msg(tt.in, nil, newGenState(), &protoMsgConfig{
+			basePackageName: "base",
+			enumPackageName: "enums",
+		}, "", nil)
+		if errs != nil {
+			t.Errorf("%s: genProto3Msg(%v): got unexpected errors: %v", tt.name, tt.in, errs)
+			continue
+		}
+		got, errs := genProto3MsgCode("base", msgs, true)
+		if errs != nil {
+			t.Errorf("%s: genProto3MsgCode(%v): got unexpected errors: %v", tt.name, msgs, errs)
+			continue
+		}
+		if !strings.Contains(got.MessageCode, tt.wantComment) {
+			t.Errorf("%s: genProto3MsgCode(%v): did not get expected module comment %q, got:\n%s", tt.name, msgs, tt.wantComment, got.MessageCode)
+		}
+	}
+}
+
+func TestGenProto3MsgEnumDefaults(t *testing.T) {
+	enumType := yang.NewEnumType()
+	enumType.Set("ONE", int64(0))
+	enumType.Set("TWO", int64(1))
+
+	parent := &yang.Entry{
+		Name:   "message-name",
+		Parent: &yang.Entry{Name: "module"},
+	}
+	msg := &yangDirectory{
+		name: "MessageName",
+		entry: &yang.Entry{
+			Name:   "message-name",
+			Kind:   yang.DirectoryEntry,
+			Parent: &yang.Entry{Name: "module", Kind: yang.DirectoryEntry},
+		},
+		fields: map[string]*yang.Entry{
+			"enumeration": {
+				Name:    "enumeration",
+				Kind:    yang.LeafEntry,
+				Parent:  parent,
+				Default: "TWO",
+				Type: &yang.YangType{
+					Name: "enumeration",
+					Kind: yang.Yenum,
+					Enum: enumType,
+				},
+			},
+			"identityref": {
+				Name:    "identityref",
+				Kind:    yang.LeafEntry,
+				Parent:  parent,
+				Default: "tm:TWO",
+				Type: &yang.YangType{
+					Name: "identityref",
+					Kind: yang.Yidentityref,
+					IdentityBase: &yang.Identity{
+						Name: "foo-identity",
+						Values: []*yang.Identity{
+							{Name: "ONE"},
+							{Name: "TWO"},
+						},
+						Parent: &yang.Module{Name: "test-module"},
+					},
+				},
+			},
+		},
+		path: []string{"", "module", "message-name"},
+	}
+
+	tests := []struct {
+		name               string
+		inAnnotateDefaults bool
+		wantCode           string
+	}{{
+		name: "enumeration default used as zero value",
+		wantCode: `
+// MessageName represents the /module/message-name YANG schema element.
+// Defined in module module.
+message MessageName {
+  enum Enumeration {
+    ENUMERATION_TWO = 0;
+    ENUMERATION_ONE = 1;
+  }
+  Enumeration enumeration = 194526076;
+  base.enums.TestModuleFooIdentity identityref = 518954308;
+}`,
+	}, {
+		name:               "enumeration and identityref defaults annotated",
+		inAnnotateDefaults: true,
+		wantCode: `
+// MessageName represents the /module/message-name YANG schema element.
+// Defined in module module.
+message MessageName {
+  enum Enumeration {
+    ENUMERATION_UNSET = 0;
+    ENUMERATION_ONE = 1;
+    ENUMERATION_TWO = 2;
+  }
+  Enumeration enumeration = 194526076 [(yext.enum_default) = "TWO"];
+  base.enums.TestModuleFooIdentity identityref = 518954308 [(yext.enum_default) = "TWO"];
+}`,
+	}}
+
+	for _, tt := range tests {
+		got, errs := writeProto3Msg(msg, nil, newGenState(), &protoMsgConfig{
+			compressPaths:    true,
+			basePackageName:  "base",
+			enumPackageName:  "enums",
+			annotateDefaults: tt.inAnnotateDefaults,
+		})
+		if errs != nil {
+			t.Errorf("%s: writeProto3Msg(%v): got unexpected errors: %v", tt.name, msg, errs)
+			continue
+		}
+		if diff := pretty.Compare(got.MessageCode, tt.wantCode); diff != "" {
+			if diffl, err := testutil.GenerateUnifiedDiff(got.MessageCode, tt.wantCode); err == nil {
+				diff = diffl
+			}
+			t.Errorf("%s: writeProto3Msg(%v): did not get expected message code, diff(-got,+want):\n%s", tt.name, msg, diff)
+		}
+	}
+}
+
+func TestProtoEnumDefault(t *testing.T) {
+	enumType := yang.NewEnumType()
+	enumType.Set("ONE", int64(0))
+	identityType := &yang.YangType{
+		Kind: yang.Yidentityref,
+		IdentityBase: &yang.Identity{
+			Name:   "base-identity",
+			Values: []*yang.Identity{{Name: "DERIVED"}},
+		},
+	}
+
+	tests := []struct {
+		name    string
+		in      *yang.Entry
+		want    *protoOption
+		wantErr bool
+	}{{
+		name: "enumeration without default",
+		in:   &yang.Entry{Name: "leaf", Type: &yang.YangType{Kind: yang.Yenum, Enum: enumType}},
+	}, {
+		name: "enumeration with default",
+		in:   &yang.Entry{Name: "leaf", Default: "ONE", Type: &yang.YangType{Kind: yang.Yenum, Enum: enumType}},
+		want: &protoOption{Name: "(yext.enum_default)", Value: `"ONE"`},
+	}, {
+		name:    "enumeration with invalid default",
+		in:      &yang.Entry{Name: "leaf", Default: "TWO", Type: &yang.YangType{Kind: yang.Yenum, Enum: enumType}},
+		wantErr: true,
+	}, {
+		name: "identityref with unprefixed default",
+		in:   &yang.Entry{Name: "leaf", Default: "DERIVED", Type: identityType},
+		want: &protoOption{Name: "(yext.enum_default)", Value: `"DERIVED"`},
+	}, {
+		name: "identityref with prefixed default",
+		in:   &yang.Entry{Name: "leaf", Default: "pfx:DERIVED", Type: identityType},
+		want: &protoOption{Name: "(yext.enum_default)", Value: `"DERIVED"`},
+	}, {
+		name:    "identityref with default not derived from base",
+		in:      &yang.Entry{Name: "leaf", Default: "pfx:OTHER", Type: identityType},
+		wantErr: true,
+	}}
+
+	for _, tt := range tests {
+		got, err := protoEnumDefault(tt.in)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: protoEnumDefault(%v): did not get expected error, got: %v, wantErr: %v", tt.name, tt.in, err, tt.wantErr)
+			continue
+		}
+		if diff := pretty.Compare(got, tt.want); diff != "" {
+			t.Errorf("%s: protoEnumDefault(%v): did not get expected option, diff(-got,+want):\n%s", tt.name, tt.in, diff)
+		}
+	}
+}
+
+func TestGenProto3MsgScalarDefaults(t *testing.T) {
+	parent := &yang.Entry{
+		Name:   "message-name",
+		Parent: &yang.Entry{Name: "module"},
+	}
+	msg := &yangDirectory{
+		name: "MessageName",
+		entry: &yang.Entry{
+			Name:   "message-name",
+			Kind:   yang.DirectoryEntry,
+			Parent: &yang.Entry{Name: "module", Kind: yang.DirectoryEntry},
+		},
+		fields: map[string]*yang.Entry{
+			"mtu": {
+				Name:    "mtu",
+				Kind:    yang.LeafEntry,
+				Parent:  parent,
+				Default: "10",
+				Type:    &yang.YangType{Kind: yang.Yuint32},
+			},
+			"description": {
+				Name:   "description",
+				Kind:   yang.LeafEntry,
+				Parent: parent,
+				Type:   &yang.YangType{Kind: yang.Ystring},
+			},
+			"servers": {
+				Name:     "servers",
+				Kind:     yang.LeafEntry,
+				ListAttr: &yang.ListAttr{},
+				Parent:   parent,
+				Default:  "192.0.2.1",
+				Type:     &yang.YangType{Kind: yang.Ystring},
+			},
+			"dns-servers": {
+				Name:     "dns-servers",
+				Kind:     yang.LeafEntry,
+				ListAttr: &yang.ListAttr{},
+				Parent:   parent,
+				Type:     &yang.YangType{Kind: yang.Ystring},
+				Node: &yang.LeafList{
+					Name:    "dns-servers",
+					Default: []*yang.Value{{Name: "192.0.2.53"}, {Name: "192.0.2.54"}},
+				},
+			},
+		},
+		path: []string{"", "module", "message-name"},
+	}
+
+	tests := []struct {
+		name             string
+		inScalarDefaults bool
+		wantCode         string
+	}{{
+		name: "defaults not annotated",
+		wantCode: `
+// MessageName represents the /module/message-name YANG schema element.
+// Defined in module module.
+message MessageName {
+  ywrapper.UintValue mtu = 295514681;
+  ywrapper.StringValue description = 399831435;
+  repeated ywrapper.StringValue dns_servers = 464889163;
+  repeated ywrapper.StringValue servers = 478360185;
+}`,
+	}, {
+		name:             "leaf and leaf-list defaults annotated",
+		inScalarDefaults: true,
+		wantCode: `
+// MessageName represents the /module/message-name YANG schema element.
+// Defined in module module.
+message MessageName {
+  ywrapper.UintValue mtu = 295514681 [(yext.default_value) = "10"];
+  ywrapper.StringValue description = 399831435;
+  repeated ywrapper.StringValue dns_servers = 464889163 [(yext.default_value) = "192.0.2.53",(yext.default_value) = "192.0.2.54"];
+  repeated ywrapper.StringValue servers = 478360185 [(yext.default_value) = "192.0.2.1"];
+}`,
+	}}
+
+	for _, tt := range tests {
+		got, errs := writeProto3Msg(msg, nil, newGenState(), &protoMsgConfig{
+			compressPaths:   true,
+			basePackageName: "base",
+			enumPackageName: "enums",
+			scalarDefaults:  tt.inScalarDefaults,
+		})
+		if errs != nil {
+			t.Errorf("%s: writeProto3Msg(%v): got unexpected errors: %v", tt.name, msg, errs)
+			continue
+		}
+		if diff := pretty.Compare(got.MessageCode, tt.wantCode); diff != "" {
+			if diffl, err := testutil.GenerateUnifiedDiff(got.MessageCode, tt.wantCode); err == nil {
+				diff = diffl
+			}
+			t.Errorf("%s: writeProto3Msg(%v): did not get expected message code, diff(-got,+want):\n%s", tt.name, msg, diff)
+		}
+	}
+}
+
+func TestProtoDefaultValueAnnotations(t *testing.T) {
+	tests := []struct {
+		name string
+		in   []string
+		want []*protoOption
+	}{{
+		name: "no defaults",
+	}, {
+		name: "single default",
+		in:   []string{"10"},
+		want: []*protoOption{{Name: "(yext.default_value)", Value: `"10"`}},
+	}, {
+		name: "multiple leaf-list defaults",
+		in:   []string{"192.0.2.1", "192.0.2.2"},
+		want: []*protoOption{
+			{Name: "(yext.default_value)", Value: `"192.0.2.1"`},
+			{Name: "(yext.default_value)", Value: `"192.0.2.2"`},
+		},
+	}, {
+		name: "default requiring escaping",
+		in:   []string{`a "quoted" value`},
+		want: []*protoOption{{Name: "(yext.default_value)", Value: `"a \"quoted\" value"`}},
+	}}
+
+	for _, tt := range tests {
+		if diff := pretty.Compare(protoDefaultValueAnnotations(tt.in), tt.want); diff != "" {
+			t.Errorf("%s: protoDefaultValueAnnotations(%v): did not get expected options, diff(-got,+want):\n%s", tt.name, tt.in, diff)
+		}
+	}
+}
+
+func TestWrapperType(t *testing.T) {
+	tests := []struct {
+		in               string
+		inGoogleWrappers bool
+		want             string
+	}{
+		{"ywrapper.StringValue", false, "ywrapper.StringValue"},
+		{"ywrapper.StringValue", true, "google.protobuf.StringValue"},
+		{"ywrapper.IntValue", true, "google.protobuf.Int64Value"},
+		{"ywrapper.UintValue", true, "google.protobuf.UInt64Value"},
+		{"ywrapper.BoolValue", true, "google.protobuf.BoolValue"},
+		{"ywrapper.BytesValue", true, "google.protobuf.BytesValue"},
+		{"ywrapper.Decimal64Value", true, "ywrapper.Decimal64Value"},
+		{"sint64", true, "sint64"},
+	}
+
+	for _, tt := range tests {
+		if got := (resolveProtoTypeArgs{googleWrappers: tt.inGoogleWrappers}).wrapperType(tt.in); got != tt.want {
+			t.Errorf("wrapperType(%s), googleWrappers: %v: did not get expected type, got: %s, want: %s", tt.in, tt.inGoogleWrappers, got, tt.want)
+		}
+	}
+}
+
+func TestGenProto3MsgEscapeReservedWords(t *testing.T) {
+	leaf := func(name string) *yang.Entry {
+		return &yang.Entry{
+			Name: name,
+			Type: &yang.YangType{Kind: yang.Ystring},
+			Node: &yang.Leaf{Name: name},
+		}
+	}
+	msg := &yangDirectory{
+		name: "MessageName",
+		entry: &yang.Entry{
+			Name: "message-name",
+			Dir:  map[string]*yang.Entry{},
+			Kind: yang.DirectoryEntry,
+		},
+		fields: map[string]*yang.Entry{
+			"message":  leaf("message"),
+			"message_": leaf("message_"),
+			"option":   leaf("option"),
+			"name":     leaf("name"),
+		},
+		path: []string{"", "root", "message-name"},
+	}
+
+	tests := []struct {
+		name                  string
+		inEscapeReservedWords bool
+		wantNames             []string
+	}{{
+		name:      "reserved words not escaped",
+		wantNames: []string{"message", "message_", "option", "name"},
+	}, {
+		name:                  "reserved words escaped",
+		inEscapeReservedWords: true,
+		wantNames:             []string{"message_", "message__", "option_", "name"},
+	}}
+
+	for _, tt := range tests {
+		got, errs := genProto3Msg(msg, nil, newGenState(), &protoMsgConfig{
+			basePackageName:     "base",
+			enumPackageName:     "enums",
+			escapeReservedWords: tt.inEscapeReservedWords,
+		}, "", nil)
+		if errs != nil {
+			t.Errorf("%s: genProto3Msg(%v): got unexpected errors: %v", tt.name, msg, errs)
+			continue
+		}
+		if len(got) != 1 {
+			t.Errorf("%s: genProto3Msg(%v): did not get expected single message, got: %v", tt.name, msg, got)
+			continue
+		}
+		var gotNames []string
+		for _, f := range got[0].Fields {
+			gotNames = append(gotNames, f.Name)
+		}
+		if diff := pretty.Compare(gotNames, tt.wantNames); diff != "" {
+			t.Errorf("%s: genProto3Msg(%v): did not get expected field names, diff(-got,+want):\n%s", tt.name, msg, diff)
+		}
+	}
+}
+
+func TestSafeProtoFieldName(t *testing.T) {
+	tests := []struct {
+		in                    string
+		inEscapeReservedWords bool
+		want                  string
+	}{
+		{"message", false, "message"},
+		{"message", true, "message_"},
+		{"option", true, "option_"},
+		{"reserved", true, "reserved_"},
+		{"config-name", true, "config_name"},
+		{"oneof.value", true, "oneof_value"},
+	}
+
+	for _, tt := range tests {
+		if got := safeProtoFieldName(tt.in, &protoMsgConfig{escapeReservedWords: tt.inEscapeReservedWords}); got != tt.want {
+			t.Errorf("safeProtoFieldName(%q, escape: %v): did not get expected name, got: %s, want: %s", tt.in, tt.inEscapeReservedWords, got, tt.want)
+		}
+	}
+}
+
+func TestGenProto3MsgUniqueNameSuffix(t *testing.T) {
+	leaf := func(name string) *yang.Entry {
+		return &yang.Entry{
+			Name: name,
+			Type: &yang.YangType{Kind: yang.Ystring},
+			Node: &yang.Leaf{Name: name},
+		}
+	}
+	// All of the fields of the message map to the protobuf field name
+	// field_name, such that they must be disambiguated.
+	msg := &yangDirectory{
+		name: "MessageName",
+		entry: &yang.Entry{
+			Name: "message-name",
+			Dir:  map[string]*yang.Entry{},
+			Kind: yang.DirectoryEntry,
+		},
+		fields: map[string]*yang.Entry{
+			"field-name": leaf("field-name"),
+			"field.name": leaf("field.name"),
+			"field_name": leaf("field_name"),
+		},
+		path: []string{"", "root", "message-name"},
+	}
+
+	tests := []struct {
+		name      string
+		inSuffix  string
+		wantNames []string
+	}{{
+		name:      "default suffix",
+		wantNames: []string{"field_name", "field_name_", "field_name__"},
+	}, {
+		name:      "numbered suffix",
+		inSuffix:  "_%d",
+		wantNames: []string{"field_name", "field_name_1", "field_name_2"},
+	}, {
+		name:      "custom suffix",
+		inSuffix:  "_dup",
+		wantNames: []string{"field_name", "field_name_dup", "field_name_dup_dup"},
+	}}
+
+	for _, tt := range tests {
+		got, errs := genProto3Msg(msg, nil, newGenState(), &protoMsgConfig{
+			basePackageName: "base",
+			enumPackageName: "enums",
+			nameSuffix:      tt.inSuffix,
+		}, "", nil)
+		if errs != nil {
+			t.Errorf("%s: genProto3Msg(%v): got unexpected errors: %v", tt.name, msg, errs)
+			continue
+		}
+		if len(got) != 1 {
+			t.Errorf("%s: genProto3Msg(%v): did not get expected single message, got: %v", tt.name, msg, got)
+			continue
+		}
+		var gotNames []string
+		for _, f := range got[0].Fields {
+			gotNames = append(gotNames, f.Name)
+		}
+		sort.Strings(gotNames)
+		if diff := pretty.Compare(gotNames, tt.wantNames); diff != "" {
+			t.Errorf("%s: genProto3Msg(%v): did not get expected field names, diff(-got,+want):\n%s", tt.name, msg, diff)
+		}
+	}
+}
+
+func TestMakeNameUniqueWithSuffix(t *testing.T) {
+	tests := []struct {
+		name     string
+		inSuffix string
+		want     []string
+	}{{
+		name: "empty suffix",
+		want: []string{"Name", "Name_", "Name__"},
+	}, {
+		name:     "numbered suffix",
+		inSuffix: "_%d",
+		want:     []string{"Name", "Name_1", "Name_2"},
+	}, {
+		name:     "numbered suffix without separator",
+		inSuffix: "%d",
+		want:     []string{"Name", "Name1", "Name2"},
+	}, {
+		name:     "custom suffix",
+		inSuffix: "X",
+		want:     []string{"Name", "NameX", "NameXX"},
+	}}
+
+	for _, tt := range tests {
+		s := newGenState()
+		s.uniqueNameSuffix = tt.inSuffix
+		defined := map[string]bool{}
+		var got []string
+		for range tt.want {
+			got = append(got, s.makeNameUnique("Name", defined))
+		}
+		if diff := pretty.Compare(got, tt.want); diff != "" {
+			t.Errorf("%s: makeNameUniqueWithSuffix(Name, %q): did not get expected names, diff(-got,+want):\n%s", tt.name, tt.inSuffix, diff)
+		}
+	}
+
+	// A numbered name that is already defined is skipped.
+	defined := map[string]bool{"Name": true, "Name_1": true}
+	if got, want := makeNameUniqueWithSuffix("Name", defined, "_%d"), "Name_2"; got != want {
+		t.Errorf("makeNameUniqueWithSuffix(Name, _%%d): did not get expected name with Name_1 defined, got: %s, want: %s", got, want)
+	}
+}
+
+func TestIsProtoNameSuffix(t *testing.T) {
+	tests := []struct {
+		in   string
+		want bool
+	}{
+		{"_", true},
+		{"_%d", true},
+		{"%d", true},
+		{"Dup", true},
+		{"_%d_%d", false},
+		{"_%s", false},
+		{"-", false},
+		{"%", false},
+	}
+
+	for _, tt := range tests {
+		if got := isProtoNameSuffix(tt.in); got != tt.want {
+			t.Errorf("isProtoNameSuffix(%q): did not get expected result, got: %v, want: %v", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestWriteProto3MsgDeterministic(t *testing.T) {
+	leaf := func(name string, kind yang.TypeKind) *yang.Entry {
+		return &yang.Entry{
+			Name: name,
+			Type: &yang.YangType{Kind: kind},
+			Node: &yang.Leaf{Name: name},
+		}
+	}
+	fields := map[string]*yang.Entry{
+		"union-field": {
+			Name: "union-field",
+			Type: &yang.YangType{
+				Name: "union",
+				Kind: yang.Yunion,
+				Type: []*yang.YangType{
+					{Kind: yang.Ystring},
+					{Kind: yang.Yint8},
+					{Kind: yang.Yuint32},
+					{Kind: yang.Ybool},
+				},
+			},
+			Node: &yang.Leaf{Name: "union-field"},
+		},
+		"leaf-list": {
+			Name:     "leaf-list",
+			Type:     &yang.YangType{Kind: yang.Yint32},
+			ListAttr: &yang.ListAttr{},
+			Node:     &yang.LeafList{Name: "leaf-list"},
+		},
+	}
+	for _, n := range []string{"alpha", "bravo", "charlie", "delta", "echo"} {
+		fields[n] = leaf(n, yang.Ystring)
+	}
+	msg := &yangDirectory{
+		name: "MessageName",
+		entry: &yang.Entry{
+			Name:   "message-name",
+			Dir:    map[string]*yang.Entry{},
+			Kind:   yang.DirectoryEntry,
+			Parent: &yang.Entry{Name: "root"},
+		},
+		fields: fields,
+		path:   []string{"", "root", "message-name"},
+	}
+
+	testutil.AssertDeterministicGeneration(t, func() (string, error) {
+		got, errs := writeProto3Msg(msg, nil, newGenState(), &protoMsgConfig{
+			basePackageName: "base",
+			enumPackageName: "enums",
+		})
+		if errs != nil {
+			return "", errs
+		}
+		return fmt.Sprintf("%s\n%v", got.MessageCode, got.RequiredImports), nil
+	}, 10)
+}
+
+func TestGenProto3MsgErroredFieldImports(t *testing.T) {
+	msg := &yangDirectory{
+		name: "MessageName",
+		entry: &yang.Entry{
+			Name: "message-name",
+			Dir:  map[string]*yang.Entry{},
+			Kind: yang.DirectoryEntry,
+		},
+		fields: map[string]*yang.Entry{
+			"field-one": {
+				Name: "field-one",
+				Type: &yang.YangType{Kind: yang.Ystring},
+				Node: &yang.Leaf{Name: "field-one"},
+			},
+			// field-two is a union containing an identityref, which requires the
+			// enum package to be imported, but is not output since it specifies a
+			// field tag, which cannot be used for a oneof.
+			"field-two": {
+				Name: "field-two",
+				Type: &yang.YangType{
+					Name: "union",
+					Kind: yang.Yunion,
+					Type: []*yang.YangType{{
+						Name: "identityref",
+						Kind: yang.Yidentityref,
+						IdentityBase: &yang.Identity{
+							Name:   "base-identity",
+							Values: []*yang.Identity{{Name: "DERIVED"}},
+							Parent: &yang.Module{Name: "test-module"},
+						},
+					}, {
+						Name: "string",
+						Kind: yang.Ystring,
+					}},
+				},
+				Node: &yang.Leaf{Name: "field-two"},
+				Exts: []*yang.Statement{{
+					Keyword:     "occodegenext:field-number",
+					HasArgument: true,
+					Argument:    "42",
+				}},
+			},
+		},
+		path: []string{"", "root", "message-name"},
+	}
+
+	got, errs := genProto3Msg(msg, nil, newGenState(), &protoMsgConfig{
+		basePackageName: "base",
+		enumPackageName: "enums",
+	}, "", nil)
+	if errs == nil {
+		t.Fatalf("genProto3Msg(%v): did not get expected error", msg)
+	}
+	if len(got) != 1 {
+		t.Fatalf("genProto3Msg(%v): did not get expected single message, got: %v", msg, got)
+	}
+
+	var gotNames []string
+	for _, f := range got[0].Fields {
+		gotNames = append(gotNames, f.Name)
+	}
+	if want := []string{"field_one"}; !reflect.DeepEqual(gotNames, want) {
+		t.Errorf("genProto3Msg(%v): did not get expected fields, got: %v, want: %v", msg, gotNames, want)
+	}
+	if len(got[0].Imports) != 0 {
+		t.Errorf("genProto3Msg(%v): got unexpected imports for field that was not output: %v", msg, got[0].Imports)
+	}
+}
+
+func TestWriteProto3MsgOptions(t *testing.T) {
+	msg := func(name string) *yangDirectory {
+		return &yangDirectory{
+			name: yang.CamelCase(name),
+			entry: &yang.Entry{
+				Name:   name,
+				Dir:    map[string]*yang.Entry{},
+				Kind:   yang.DirectoryEntry,
+				Parent: &yang.Entry{Name: "root", Kind: yang.DirectoryEntry},
 			},
 			fields: map[string]*yang.Entry{
-				"identityref": {
-					Name: "identityref",
-					Kind: yang.LeafEntry,
-					Parent: &yang.Entry{
-						Name: "message-name",
-						Parent: &yang.Entry{
-							Name: "module",
-						},
-					},
-					Type: &yang.YangType{
-						Name: "identityref",
-						Kind: yang.Yidentityref,
-						IdentityBase: &yang.Identity{
-							Name: "foo-identity",
-							Values: []*yang.Identity{
-								{Name: "ONE"},
-								{Name: "TWO"},
-							},
-							Parent: &yang.Module{
-								Name: "test-module",
-							},
-						},
-					},
+				"field": {
+					Name: "field",
+					Type: &yang.YangType{Kind: yang.Ystring},
 				},
 			},
-			path: []string{"", "module-name", "message-name"},
+			path: []string{"", "root", name},
+		}
+	}
+
+	cfg := &protoMsgConfig{
+		basePackageName: "base",
+		enumPackageName: "enums",
+		messageOptions: map[string][]string{
+			"/root/one": {"(my.custom) = true", `(my.name) = "one"`},
 		},
-		inBasePackageName: "base",
-		inEnumPackageName: "enums",
-		wantCompress: &generatedProto3Message{
-			PackageName: "",
-			MessageCode: `
-// MessageName represents the /module-name/message-name YANG schema element.
-message MessageName {
-  base.enums.TestModuleFooIdentity identityref = 518954308;
+	}
+
+	tests := []struct {
+		name     string
+		inMsg    *yangDirectory
+		wantCode string
+	}{{
+		name:  "message with options",
+		inMsg: msg("one"),
+		wantCode: `
+// One represents the /root/one YANG schema element.
+// Defined in module root.
+message One {
+  option (my.custom) = true;
+  option (my.name) = "one";
+  ywrapper.StringValue field = 30485524;
 }`,
-			RequiredImports: []string{"base/enums/enums.proto"},
-		},
-		wantUncompress: &generatedProto3Message{
-			PackageName: "module",
-			MessageCode: `
-// MessageName represents the /module-name/message-name YANG schema element.
-message MessageName {
-  base.enums.TestModuleFooIdentity identityref = 518954308;
+	}, {
+		name:  "message without options",
+		inMsg: msg("two"),
+		wantCode: `
+// Two represents the /root/two YANG schema element.
+// Defined in module root.
+message Two {
+  ywrapper.StringValue field = 30485524;
 }`,
-			RequiredImports: []string{"base/enums/enums.proto"},
-		},
 	}}
 
 	for _, tt := range tests {
-		wantErr := map[bool]bool{true: tt.wantCompressErr, false: tt.wantUncompressErr}
-		for compress, want := range map[bool]*generatedProto3Message{true: tt.wantCompress, false: tt.wantUncompress} {
-			s := newGenState()
-			// Seed the message names with the supplied input.
-			s.uniqueDirectoryNames = tt.inUniqueDirectoryNames
-
-			got, errs := writeProto3Msg(tt.inMsg, tt.inMsgs, s, &protoMsgConfig{
-				compressPaths:   compress,
-				basePackageName: tt.inBasePackageName,
-				enumPackageName: tt.inEnumPackageName,
-				baseImportPath:  tt.inBaseImportPath,
-				nestedMessages:  tt.inNestedMessages,
-			})
-
-			if (errs != nil) != wantErr[compress] {
-				t.Errorf("%s: writeProto3Msg(%v, %v, %v, %v): did not get expected error return status, got: %v, wanted error: %v", tt.name, tt.inMsg, tt.inMsgs, s, compress, errs, wantErr[compress])
-			}
-
-			if errs != nil || got == nil {
-				continue
+		got, errs := writeProto3Msg(tt.inMsg, nil, newGenState(), cfg)
+		if errs != nil {
+			t.Errorf("%s: writeProto3Msg(%v): got unexpected errors: %v", tt.name, tt.inMsg, errs)
+			continue
+		}
+		if diff := pretty.Compare(got.MessageCode, tt.wantCode); diff != "" {
+			if diffl, _ := testutil.GenerateUnifiedDiff(got.MessageCode, tt.wantCode); diffl != "" {
+				diff = diffl
 			}
+			t.Errorf("%s: writeProto3Msg(%v): did not get expected message code, diff(-got,+want):\n%s", tt.name, tt.inMsg, diff)
+		}
+	}
+}
 
-			if got.PackageName != want.PackageName {
-				t.Errorf("%s: writeProto3Msg(%v, %v, %v, %v): did not get expected package name, got: %v, want: %v", tt.name, tt.inMsg, tt.inMsgs, s, compress, got.PackageName, want.PackageName)
-			}
+func TestWriteProto3MsgCardinality(t *testing.T) {
+	msg := &yangDirectory{
+		name: "Bounded",
+		entry: &yang.Entry{
+			Name:   "bounded",
+			Dir:    map[string]*yang.Entry{},
+			Kind:   yang.DirectoryEntry,
+			Parent: &yang.Entry{Name: "root", Kind: yang.DirectoryEntry},
+		},
+		fields: map[string]*yang.Entry{
+			"values": {
+				Name: "values",
+				Type: &yang.YangType{Kind: yang.Ystring},
+				ListAttr: &yang.ListAttr{
+					MinElements: &yang.Value{Name: "1"},
+					MaxElements: &yang.Value{Name: "4"},
+				},
+			},
+			"unbounded": {
+				Name: "unbounded",
+				Type: &yang.YangType{Kind: yang.Ystring},
+				ListAttr: &yang.ListAttr{
+					MaxElements: &yang.Value{Name: "unbounded"},
+				},
+			},
+		},
+		path: []string{"", "root", "bounded"},
+	}
 
-			if !reflect.DeepEqual(got.RequiredImports, want.RequiredImports) {
-				t.Errorf("%s: writeProto3Msg(%v, %v, %v, %v): did not get expected set of imports, got: %v, want: %v", tt.name, tt.inMsg, tt.inMsgs, s, compress, got.RequiredImports, want.RequiredImports)
-			}
+	tests := []struct {
+		name     string
+		inPolicy ProtoCardinalityPolicy
+		wantCode string
+	}{{
+		name:     "cardinality omitted",
+		inPolicy: OmitCardinality,
+		wantCode: `
+// Bounded represents the /root/bounded YANG schema element.
+// Defined in module root.
+message Bounded {
+  repeated ywrapper.StringValue unbounded = 229870768;
+  repeated ywrapper.StringValue values = 496803634;
+}`,
+	}, {
+		name:     "cardinality as comment",
+		inPolicy: CommentCardinality,
+		wantCode: `
+// Bounded represents the /root/bounded YANG schema element.
+// Defined in module root.
+message Bounded {
+  repeated ywrapper.StringValue unbounded = 229870768;
+  // values must contain between 1 and 4 elements.
+  repeated ywrapper.StringValue values = 496803634;
+}`,
+	}, {
+		name:     "cardinality as options",
+		inPolicy: AnnotateCardinality,
+		wantCode: `
+// Bounded represents the /root/bounded YANG schema element.
+// Defined in module root.
+message Bounded {
+  repeated ywrapper.StringValue unbounded = 229870768;
+  repeated ywrapper.StringValue values = 496803634 [(yext.min_elements) = 1,(yext.max_elements) = 4];
+}`,
+	}, {
+		name:     "cardinality as comment and options",
+		inPolicy: CommentAndAnnotateCardinality,
+		wantCode: `
+// Bounded represents the /root/bounded YANG schema element.
+// Defined in module root.
+message Bounded {
+  repeated ywrapper.StringValue unbounded = 229870768;
+  // values must contain between 1 and 4 elements.
+  repeated ywrapper.StringValue values = 496803634 [(yext.min_elements) = 1,(yext.max_elements) = 4];
+}`,
+	}}
 
-			if diff := pretty.Compare(got.MessageCode, want.MessageCode); diff != "" {
-				if diffl, err := testutil.GenerateUnifiedDiff(got.MessageCode, want.MessageCode); err == nil {
-					diff = diffl
-				}
-				t.Errorf("%s: writeProto3Msg(%v, %v, %v, %v): did not get expected message returned, diff(-got,+want):\n%s", tt.name, tt.inMsg, tt.inMsgs, s, compress, diff)
+	for _, tt := range tests {
+		got, errs := writeProto3Msg(msg, nil, newGenState(), &protoMsgConfig{
+			basePackageName:   "base",
+			enumPackageName:   "enums",
+			cardinalityPolicy: tt.inPolicy,
+		})
+		if errs != nil {
+			t.Errorf("%s: writeProto3Msg(%v): got unexpected errors: %v", tt.name, msg, errs)
+			continue
+		}
+		if diff := pretty.Compare(got.MessageCode, tt.wantCode); diff != "" {
+			if diffl, _ := testutil.GenerateUnifiedDiff(got.MessageCode, tt.wantCode); diffl != "" {
+				diff = diffl
 			}
+			t.Errorf("%s: writeProto3Msg(%v): did not get expected message code, diff(-got,+want):\n%s", tt.name, msg, diff)
 		}
 	}
 }
 
-func TestGenListKeyProto(t *testing.T) {
+func TestListCardinality(t *testing.T) {
 	tests := []struct {
-		name          string
-		inListPackage string
-		inListName    string
-		inArgs        *protoDefinitionArgs
-		wantMsg       *protoMsg
-		wantErr       bool
+		name    string
+		in      *yang.ListAttr
+		want    *yangCardinality
+		wantErr bool
 	}{{
-		name:          "simple list key proto",
-		inListPackage: "pkg",
-		inListName:    "list",
-		inArgs: &protoDefinitionArgs{
-			field: &yang.Entry{
-				Name:     "list",
-				Kind:     yang.DirectoryEntry,
-				ListAttr: &yang.ListAttr{},
-				Key:      "key",
-				Dir:      map[string]*yang.Entry{},
+		name: "not a list",
+	}, {
+		name: "no bounds",
+		in:   &yang.ListAttr{},
+	}, {
+		name: "min-elements only",
+		in:   &yang.ListAttr{MinElements: &yang.Value{Name: "2"}},
+		want: &yangCardinality{min: 2, hasMin: true},
+	}, {
+		name: "max-elements only",
+		in:   &yang.ListAttr{MaxElements: &yang.Value{Name: "16"}},
+		want: &yangCardinality{max: 16, hasMax: true},
+	}, {
+		name: "unbounded max-elements",
+		in:   &yang.ListAttr{MinElements: &yang.Value{Name: "1"}, MaxElements: &yang.Value{Name: "unbounded"}},
+		want: &yangCardinality{min: 1, hasMin: true},
+	}, {
+		name:    "invalid min-elements",
+		in:      &yang.ListAttr{MinElements: &yang.Value{Name: "-1"}},
+		wantErr: true,
+	}, {
+		name:    "invalid max-elements",
+		in:      &yang.ListAttr{MaxElements: &yang.Value{Name: "many"}},
+		wantErr: true,
+	}}
+
+	for _, tt := range tests {
+		e := &yang.Entry{Name: "list", ListAttr: tt.in}
+		got, err := listCardinality(e)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: listCardinality(%v): did not get expected error status, got: %v, wantErr: %v", tt.name, tt.in, err, tt.wantErr)
+		}
+		if diff := pretty.Compare(got, tt.want); diff != "" {
+			t.Errorf("%s: listCardinality(%v): did not get expected cardinality, diff(-got,+want):\n%s", tt.name, tt.in, diff)
+		}
+	}
+}
+
+func TestGenProto3MsgSplitConfigState(t *testing.T) {
+	stateEnum := yang.NewEnumType()
+	stateEnum.Set("UP", 0)
+	stateEnum.Set("DOWN", 1)
+
+	msg := func(fields ...string) *yangDirectory {
+		parent := &yang.Entry{
+			Name:   "a-message",
+			Kind:   yang.DirectoryEntry,
+			Dir:    map[string]*yang.Entry{},
+			Parent: &yang.Entry{Name: "root", Kind: yang.DirectoryEntry},
+		}
+		all := map[string]*yang.Entry{
+			"name": {
+				Name:   "name",
+				Type:   &yang.YangType{Kind: yang.Ystring},
+				Config: yang.TSTrue,
+				Parent: parent,
 			},
-			directory: &yangDirectory{
-				name: "List",
-				fields: map[string]*yang.Entry{
-					"key": {
-						Name: "key",
-						Type: &yang.YangType{
-							Kind: yang.Ystring,
-						},
-					},
-				},
+			"enabled": {
+				Name:   "enabled",
+				Type:   &yang.YangType{Kind: yang.Ybool},
+				Parent: parent,
 			},
-			definedDirectories: map[string]*yangDirectory{},
-			state: &genState{
-				uniqueDirectoryNames: map[string]string{
-					"/list": "List",
+			"oper-status": {
+				Name:   "oper-status",
+				Type:   &yang.YangType{Kind: yang.Yenum, Name: "enumeration", Enum: stateEnum},
+				Config: yang.TSFalse,
+				Parent: parent,
+				Node: &yang.Leaf{
+					Name:   "oper-status",
+					Parent: &yang.Module{Name: "base"},
 				},
 			},
-			cfg: &protoMsgConfig{
-				compressPaths:   false,
-				basePackageName: "base",
-				baseImportPath:  "base/path",
-			},
-		},
-		wantMsg: &protoMsg{
-			Name:     "listKey",
-			YANGPath: "/list",
-			Fields: []*protoMsgField{{
-				Tag:  1,
-				Name: "key",
-				Type: "string",
-			}, {
-				Tag:  2,
-				Name: "list",
-				Type: "pkg.list",
-			}},
-			Imports: []string{"base/path/base/pkg/pkg.proto"},
-		},
-	}, {
-		name:          "list with union key - string and int",
-		inListPackage: "pkg",
-		inListName:    "list",
-		inArgs: &protoDefinitionArgs{
-			field: &yang.Entry{
-				Name:     "list",
-				Kind:     yang.DirectoryEntry,
+			"counters": {
+				Name:     "counters",
+				Type:     &yang.YangType{Kind: yang.Yuint64},
 				ListAttr: &yang.ListAttr{},
-				Key:      "key",
-				Dir:      map[string]*yang.Entry{},
-			},
-			directory: &yangDirectory{
-				name: "List",
-				fields: map[string]*yang.Entry{
-					"key": {
-						Name: "key",
-						Type: &yang.YangType{
-							Kind: yang.Yunion,
-							Type: []*yang.YangType{
-								{Kind: yang.Ystring},
-								{Kind: yang.Yint8},
-							},
-						},
-					},
-				},
+				Config:   yang.TSFalse,
+				Parent:   parent,
 			},
-			definedDirectories: map[string]*yangDirectory{},
-			state: &genState{
-				uniqueDirectoryNames: map[string]string{
-					"/list": "List",
-				},
+			"config": {
+				Name:   "config",
+				Type:   &yang.YangType{Kind: yang.Ystring},
+				Parent: parent,
 			},
-			cfg: &protoMsgConfig{
-				compressPaths:   false,
-				basePackageName: "base",
-				baseImportPath:  "base/path",
+		}
+		d := &yangDirectory{
+			name:   "AMessage",
+			entry:  parent,
+			fields: map[string]*yang.Entry{},
+			path:   []string{"", "root", "a-message"},
+		}
+		for _, f := range fields {
+			d.fields[f] = all[f]
+			parent.Dir[f] = all[f]
+		}
+		return d
+	}
+
+	tests := []struct {
+		name     string
+		inMsg    *yangDirectory
+		wantMsgs map[string]*protoMsg
+		wantErr  bool
+	}{{
+		name:  "mixed config and state leaves",
+		inMsg: msg("name", "enabled", "oper-status", "counters"),
+		wantMsgs: map[string]*protoMsg{
+			"AMessage": {
+				Name:     "AMessage",
+				YANGPath: "/root/a-message",
+				Module:   "root",
+				Enums:    map[string]*protoMsgEnum{},
+				Fields: []*protoMsgField{{
+					Name: "config",
+					Type: "AMessageConfig",
+					Tag:  280256943,
+				}, {
+					Name: "state",
+					Type: "AMessageState",
+					Tag:  340817952,
+				}},
 			},
-		},
-		wantMsg: &protoMsg{
-			Name:     "listKey",
-			YANGPath: "/list",
-			Fields: []*protoMsgField{{
-				Tag:     1,
-				Name:    "key",
-				IsOneOf: true,
-				OneOfFields: []*protoMsgField{{
-					Tag:  232819104,
-					Name: "key_sint64",
-					Type: "sint64",
+			"AMessageConfig": {
+				Name:     "AMessageConfig",
+				YANGPath: "/root/a-message",
+				Module:   "root",
+				Enums:    map[string]*protoMsgEnum{},
+				Fields: []*protoMsgField{{
+					Name: "enabled",
+					Type: "ywrapper.BoolValue",
+					Tag:  98851388,
 				}, {
-					Tag:  470483267,
-					Name: "key_string",
-					Type: "string",
+					Name: "name",
+					Type: "ywrapper.StringValue",
+					Tag:  328240900,
 				}},
-			}, {
-				Tag:  2,
-				Name: "list",
-				Type: "pkg.list",
-			}},
-			Imports: []string{"base/path/base/pkg/pkg.proto"},
-		},
-	}, {
-		name:          "list with union key - two string",
-		inListPackage: "pkg",
-		inListName:    "list",
-		inArgs: &protoDefinitionArgs{
-			field: &yang.Entry{
-				Name:     "list",
-				Kind:     yang.DirectoryEntry,
-				ListAttr: &yang.ListAttr{},
-				Key:      "key",
-				Dir:      map[string]*yang.Entry{},
 			},
-			directory: &yangDirectory{
-				name: "List",
-				fields: map[string]*yang.Entry{
-					"key": {
-						Name: "key",
-						Type: &yang.YangType{
-							Kind: yang.Yunion,
-							Type: []*yang.YangType{
-								{Kind: yang.Ystring, Pattern: []string{"b.*"}},
-								{Kind: yang.Ystring, Pattern: []string{"a.*"}},
-							},
+			"AMessageState": {
+				Name:     "AMessageState",
+				YANGPath: "/root/a-message",
+				Module:   "root",
+				Enums: map[string]*protoMsgEnum{
+					"OperStatus": {
+						Values: map[int64]protoEnumValue{
+							0: {ProtoLabel: "UNSET"},
+							1: {ProtoLabel: "UP"},
+							2: {ProtoLabel: "DOWN"},
 						},
 					},
 				},
+				Fields: []*protoMsgField{{
+					Name:       "counters",
+					Type:       "ywrapper.UintValue",
+					Tag:        60121266,
+					IsRepeated: true,
+				}, {
+					Name: "oper_status",
+					Type: "OperStatus",
+					Tag:  493024640,
+				}},
 			},
-			definedDirectories: map[string]*yangDirectory{},
-			state: &genState{
-				uniqueDirectoryNames: map[string]string{
-					"/list": "List",
-				},
+		},
+	}, {
+		name:  "only config leaves",
+		inMsg: msg("name"),
+		wantMsgs: map[string]*protoMsg{
+			"AMessage": {
+				Name:     "AMessage",
+				YANGPath: "/root/a-message",
+				Module:   "root",
+				Enums:    map[string]*protoMsgEnum{},
+				Fields: []*protoMsgField{{
+					Name: "config",
+					Type: "AMessageConfig",
+					Tag:  280256943,
+				}},
 			},
-			cfg: &protoMsgConfig{
-				compressPaths:   false,
-				basePackageName: "base",
-				baseImportPath:  "base/path",
+			"AMessageConfig": {
+				Name:     "AMessageConfig",
+				YANGPath: "/root/a-message",
+				Module:   "root",
+				Enums:    map[string]*protoMsgEnum{},
+				Fields: []*protoMsgField{{
+					Name: "name",
+					Type: "ywrapper.StringValue",
+					Tag:  328240900,
+				}},
 			},
 		},
-		wantMsg: &protoMsg{
-			Name:     "listKey",
-			YANGPath: "/list",
-			Fields: []*protoMsgField{{
-				Tag:  1,
-				Name: "key",
-				Type: "string",
-			}, {
-				Tag:  2,
-				Name: "list",
-				Type: "pkg.list",
-			}},
-			Imports: []string{"base/path/base/pkg/pkg.proto"},
-		},
+	}, {
+		name:    "existing field named config",
+		inMsg:   msg("name", "config"),
+		wantErr: true,
 	}}
 
 	for _, tt := range tests {
-		got, err := genListKeyProto(tt.inListPackage, tt.inListName, tt.inArgs)
-		if (err != nil) != tt.wantErr {
-			t.Errorf("%s: genListKeyProto(%s, %s, %#v): got unexpected error returned, got: %v, want err: %v", tt.name, tt.inListPackage, tt.inListName, tt.inArgs, err, tt.wantErr)
+		got, errs := genProto3Msg(tt.inMsg, nil, newGenState(), &protoMsgConfig{
+			basePackageName:  "base",
+			enumPackageName:  "enums",
+			splitConfigState: true,
+		}, "", nil)
+		if (errs != nil) != tt.wantErr {
+			t.Errorf("%s: genProto3Msg(%v): did not get expected error status, got: %v, wantErr: %v", tt.name, tt.inMsg, errs, tt.wantErr)
+		}
+		if tt.wantErr {
+			continue
 		}
 
-		if diff := pretty.Compare(got, tt.wantMsg); diff != "" {
-			t.Errorf("%s: genListKeyProto(%s, %s, %#v): did not get expected return message, diff(-got,+want):\n%s", tt.name, tt.inListPackage, tt.inListName, tt.inArgs, diff)
+		gotMsgs := map[string]*protoMsg{}
+		for _, m := range got {
+			gotMsgs[m.Name] = m
+		}
+		if diff := pretty.Compare(gotMsgs, tt.wantMsgs); diff != "" {
+			t.Errorf("%s: genProto3Msg(%v): did not get expected messages, diff(-got,+want):\n%s", tt.name, tt.inMsg, diff)
 		}
 	}
 }
 
-func TestWriteProtoEnums(t *testing.T) {
-	// Create mock enumerations within goyang since we cannot create them in-line.
-	testEnums := map[string][]string{
-		"enumOne": {"SPEED_2.5G", "SPEED_40G"},
-		"enumTwo": {"VALUE_1", "VALUE_2"},
+func TestWriteProto3MsgSplitConfigStateNested(t *testing.T) {
+	parent := &yang.Entry{
+		Name:   "a-message",
+		Kind:   yang.DirectoryEntry,
+		Dir:    map[string]*yang.Entry{},
+		Parent: &yang.Entry{Name: "root", Kind: yang.DirectoryEntry},
+	}
+	parent.Dir["name"] = &yang.Entry{
+		Name:   "name",
+		Type:   &yang.YangType{Kind: yang.Ystring},
+		Parent: parent,
+	}
+	parent.Dir["counter"] = &yang.Entry{
+		Name:   "counter",
+		Type:   &yang.YangType{Kind: yang.Yuint64},
+		Config: yang.TSFalse,
+		Parent: parent,
+	}
+	msg := &yangDirectory{
+		name:   "AMessage",
+		entry:  parent,
+		fields: parent.Dir,
+		path:   []string{"", "root", "a-message"},
 	}
-	testYANGEnums := map[string]*yang.EnumType{}
 
-	for name, values := range testEnums {
-		enum := yang.NewEnumType()
-		for i, v := range values {
-			enum.Set(v, int64(i))
+	got, errs := writeProto3Msg(msg, nil, newGenState(), &protoMsgConfig{
+		basePackageName:  "base",
+		enumPackageName:  "enums",
+		nestedMessages:   true,
+		splitConfigState: true,
+	})
+	if errs != nil {
+		t.Fatalf("writeProto3Msg(%v): got unexpected errors: %v", msg, errs)
+	}
+
+	want := `
+message AMessage {
+  message AMessageConfig {
+    ywrapper.StringValue name = 328240900;
+  }
+  message AMessageState {
+    ywrapper.UintValue counter = 62970075;
+  }
+  AMessageConfig config = 280256943;
+  AMessageState state = 340817952;
+}`
+	if diff := pretty.Compare(got.MessageCode, want); diff != "" {
+		if diffl, _ := testutil.GenerateUnifiedDiff(got.MessageCode, want); diffl != "" {
+			diff = diffl
+		}
+		t.Errorf("writeProto3Msg(%v): did not get expected message code, diff(-got,+want):\n%s", msg, diff)
+	}
+}
+
+func TestGenProto3MsgExplicitFieldTags(t *testing.T) {
+	tagExt := func(tag string) []*yang.Statement {
+		return []*yang.Statement{{
+			Keyword:     "occodegenext:field-number",
+			HasArgument: true,
+			Argument:    tag,
+		}}
+	}
+
+	msg := func(fields map[string]*yang.Entry) *yangDirectory {
+		return &yangDirectory{
+			name: "MessageName",
+			entry: &yang.Entry{
+				Name: "message-name",
+				Dir:  map[string]*yang.Entry{},
+				Kind: yang.DirectoryEntry,
+			},
+			fields: fields,
+			path:   []string{"", "root", "message-name"},
 		}
-		testYANGEnums[name] = enum
 	}
 
 	tests := []struct {
-		name                string
-		inEnums             map[string]*yangEnum
-		inAnnotateEnumNames bool
-		wantEnums           []string
-		wantErr             bool
+		name       string
+		inMsg      *yangDirectory
+		wantFields []*protoMsgField
+		wantErr    bool
 	}{{
-		name: "skipped enumeration type",
-		inEnums: map[string]*yangEnum{
-			"e": {
-				name: "e",
-				entry: &yang.Entry{
-					Name: "e",
-					Type: &yang.YangType{
-						Name: "enumeration",
-						Kind: yang.Yenum,
-					},
-				},
+		name: "explicit tag honored",
+		inMsg: msg(map[string]*yang.Entry{
+			"field-one": {
+				Name: "field-one",
+				Type: &yang.YangType{Kind: yang.Ystring},
+				Exts: tagExt("7"),
 			},
-		},
-		wantEnums: []string{},
+			"field-two": {
+				Name: "field-two",
+				Type: &yang.YangType{Kind: yang.Ystring},
+			},
+		}),
+		wantFields: []*protoMsgField{{
+			Tag:  7,
+			Name: "field_one",
+			Type: "ywrapper.StringValue",
+		}, {
+			Tag:  25944937,
+			Name: "field_two",
+			Type: "ywrapper.StringValue",
+		}},
 	}, {
-		name: "enum for identityref",
-		inEnums: map[string]*yangEnum{
-			"EnumeratedValue": {
-				name: "EnumeratedValue",
-				entry: &yang.Entry{
-					Type: &yang.YangType{
-						IdentityBase: &yang.Identity{
-							Name: "IdentityValue",
-							Values: []*yang.Identity{
-								{Name: "VALUE_A", Parent: &yang.Module{Name: "mod"}},
-								{Name: "VALUE_B", Parent: &yang.Module{Name: "mod2"}},
-							},
-						},
-					},
-				},
+		name: "other extension is ignored",
+		inMsg: msg(map[string]*yang.Entry{
+			"field-one": {
+				Name: "field-one",
+				Type: &yang.YangType{Kind: yang.Ystring},
+				Exts: []*yang.Statement{{Keyword: "occodegenext:field-number-offset", HasArgument: true, Argument: "7"}},
 			},
-		},
-		wantEnums: []string{
-			`
-// EnumeratedValue represents an enumerated type generated for the YANG identity IdentityValue.
-enum EnumeratedValue {
-  ENUMERATEDVALUE_UNSET = 0;
-  ENUMERATEDVALUE_VALUE_A = 321526273;
-  ENUMERATEDVALUE_VALUE_B = 321526274;
+		}),
+		wantFields: []*protoMsgField{{
+			Tag:  410095931,
+			Name: "field_one",
+			Type: "ywrapper.StringValue",
+		}},
+	}, {
+		name: "conflicting explicit tags",
+		inMsg: msg(map[string]*yang.Entry{
+			"field-one": {
+				Name: "field-one",
+				Type: &yang.YangType{Kind: yang.Ystring},
+				Exts: tagExt("7"),
+			},
+			"field-two": {
+				Name: "field-two",
+				Type: &yang.YangType{Kind: yang.Ystring},
+				Exts: tagExt("7"),
+			},
+		}),
+		wantErr: true,
+	}, {
+		name: "explicit tag in protobuf reserved range",
+		inMsg: msg(map[string]*yang.Entry{
+			"field-one": {
+				Name: "field-one",
+				Type: &yang.YangType{Kind: yang.Ystring},
+				Exts: tagExt("19500"),
+			},
+		}),
+		wantErr: true,
+	}, {
+		name: "explicit tag that is not a number",
+		inMsg: msg(map[string]*yang.Entry{
+			"field-one": {
+				Name: "field-one",
+				Type: &yang.YangType{Kind: yang.Ystring},
+				Exts: tagExt("seven"),
+			},
+		}),
+		wantErr: true,
+	}}
+
+	for _, tt := range tests {
+		got, errs := genProto3Msg(tt.inMsg, nil, newGenState(), &protoMsgConfig{
+			basePackageName: "base",
+			enumPackageName: "enums",
+		}, "", nil)
+		if (errs != nil) != tt.wantErr {
+			t.Errorf("%s: genProto3Msg(%v): did not get expected error status, got: %v, wantErr: %v", tt.name, tt.inMsg, errs, tt.wantErr)
+		}
+		if errs != nil {
+			continue
+		}
+		if len(got) != 1 {
+			t.Errorf("%s: genProto3Msg(%v): did not get expected single message, got: %v", tt.name, tt.inMsg, got)
+			continue
+		}
+		if diff := pretty.Compare(got[0].Fields, tt.wantFields); diff != "" {
+			t.Errorf("%s: genProto3Msg(%v): did not get expected fields, diff(-got,+want):\n%s", tt.name, tt.inMsg, diff)
+		}
+	}
 }
-`,
+
+func TestWriteProto3MsgDescriptions(t *testing.T) {
+	msg := &yangDirectory{
+		name: "MessageName",
+		entry: &yang.Entry{
+			Name:   "message-name",
+			Dir:    map[string]*yang.Entry{},
+			Kind:   yang.DirectoryEntry,
+			Parent: &yang.Entry{Name: "root", Kind: yang.DirectoryEntry},
 		},
-	}, {
-		name: "enum for typedef enumeration",
-		inEnums: map[string]*yangEnum{
-			"e": {
-				name: "EnumName",
-				entry: &yang.Entry{
-					Name: "e",
-					Type: &yang.YangType{
-						Name: "typedef",
-						Kind: yang.Yenum,
-						Enum: testYANGEnums["enumOne"],
-					},
-					Annotation: map[string]interface{}{
-						"valuePrefix": []string{"enum-name"},
-					},
-				},
+		fields: map[string]*yang.Entry{
+			"documented": {
+				Name: "documented",
+				Type: &yang.YangType{Kind: yang.Ystring},
+				Description: `The name of the interface, which is used as the key of the interfaces
+        list, and must be unique within the system.
+
+        Names are case sensitive.`,
 			},
-			"f": {
-				name: "SecondEnum",
-				entry: &yang.Entry{
-					Name: "f",
-					Type: &yang.YangType{
-						Name: "derived",
-						Kind: yang.Yenum,
-						Enum: testYANGEnums["enumTwo"],
-					},
-					Annotation: map[string]interface{}{
-						"valuePrefix": []string{"secondenum"},
-					},
-				},
+			"undocumented": {
+				Name: "undocumented",
+				Type: &yang.YangType{Kind: yang.Ystring},
 			},
 		},
-		inAnnotateEnumNames: true,
-		wantEnums: []string{
-			`
-// EnumName represents an enumerated type generated for the YANG enumerated type typedef.
-enum EnumName {
-  ENUMNAME_UNSET = 0;
-  ENUMNAME_SPEED_2_5G = 1 [(yext.yang_name) = "SPEED_2.5G"];
-  ENUMNAME_SPEED_40G = 2 [(yext.yang_name) = "SPEED_40G"];
-}
-`, `
-// SecondEnum represents an enumerated type generated for the YANG enumerated type derived.
-enum SecondEnum {
-  SECONDENUM_UNSET = 0;
-  SECONDENUM_VALUE_1 = 1 [(yext.yang_name) = "VALUE_1"];
-  SECONDENUM_VALUE_2 = 2 [(yext.yang_name) = "VALUE_2"];
+		path: []string{"", "root", "message-name"},
+	}
+
+	tests := []struct {
+		name                string
+		inFieldDescriptions bool
+		wantCode            string
+	}{{
+		name: "descriptions not output",
+		wantCode: `
+// MessageName represents the /root/message-name YANG schema element.
+// Defined in module root.
+message MessageName {
+  ywrapper.StringValue undocumented = 226646413;
+  ywrapper.StringValue documented = 366283248;
+}`,
+	}, {
+		name:                "descriptions output",
+		inFieldDescriptions: true,
+		wantCode: `
+// MessageName represents the /root/message-name YANG schema element.
+// Defined in module root.
+message MessageName {
+  ywrapper.StringValue undocumented = 226646413;
+  // The name of the interface, which is used as the key of the interfaces
+  // list, and must be unique within the system.
+  // Names are case sensitive.
+  ywrapper.StringValue documented = 366283248;
+}`,
+	}}
+
+	for _, tt := range tests {
+		got, errs := writeProto3Msg(msg, nil, newGenState(), &protoMsgConfig{
+			basePackageName:   "base",
+			enumPackageName:   "enums",
+			fieldDescriptions: tt.inFieldDescriptions,
+		})
+		if errs != nil {
+			t.Errorf("%s: writeProto3Msg(%v): got unexpected errors: %v", tt.name, msg, errs)
+			continue
+		}
+		if diff := pretty.Compare(got.MessageCode, tt.wantCode); diff != "" {
+			if diffl, _ := testutil.GenerateUnifiedDiff(got.MessageCode, tt.wantCode); diffl != "" {
+				diff = diffl
+			}
+			t.Errorf("%s: writeProto3Msg(%v): did not get expected message code, diff(-got,+want):\n%s", tt.name, msg, diff)
+		}
+	}
 }
-`,
-		},
+
+func TestWrapCommentLines(t *testing.T) {
+	tests := []struct {
+		name    string
+		in      string
+		inWidth int
+		want    []string
+	}{{
+		name:    "empty string",
+		inWidth: 10,
+	}, {
+		name:    "short line",
+		in:      "a description",
+		inWidth: 70,
+		want:    []string{"a description"},
+	}, {
+		name:    "line wrapped at word boundaries",
+		in:      "one two three four five",
+		inWidth: 9,
+		want:    []string{"one two", "three", "four five"},
+	}, {
+		name:    "multiple lines with indentation and blank lines",
+		in:      "  first line\n\n      second   line  \n",
+		inWidth: 70,
+		want:    []string{"first line", "second line"},
+	}, {
+		name:    "word longer than width",
+		in:      "a verylongword b",
+		inWidth: 5,
+		want:    []string{"a", "verylongword", "b"},
 	}}
 
 	for _, tt := range tests {
-		got, err := writeProtoEnums(tt.inEnums, tt.inAnnotateEnumNames)
-		if (err != nil) != tt.wantErr {
-			t.Errorf("%s: writeProtoEnums(%v): did not get expected error, got: %v", tt.name, tt.inEnums, err)
+		if got := wrapCommentLines(tt.in, tt.inWidth); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: wrapCommentLines(%q, %d): did not get expected lines, got: %q, want: %q", tt.name, tt.in, tt.inWidth, got, tt.want)
+		}
+	}
+}
+
+func TestGenProto3MsgFieldOrder(t *testing.T) {
+	msg := &yangDirectory{
+		name: "MessageName",
+		entry: &yang.Entry{
+			Name: "message-name",
+			Dir:  map[string]*yang.Entry{},
+			Kind: yang.DirectoryEntry,
+		},
+		fields: map[string]*yang.Entry{},
+		path:   []string{"", "root", "message-name"},
+	}
+	for _, n := range []string{"alpha", "bravo", "charlie", "delta", "echo"} {
+		msg.fields[n] = &yang.Entry{
+			Name: n,
+			Type: &yang.YangType{Kind: yang.Ystring},
 		}
+	}
+
+	got, errs := genProto3Msg(msg, nil, newGenState(), &protoMsgConfig{
+		basePackageName: "base",
+		enumPackageName: "enums",
+	}, "", nil)
+	if errs != nil {
+		t.Fatalf("genProto3Msg(%v): could not generate message, got errors: %v", msg, errs)
+	}
+	if len(got) != 1 {
+		t.Fatalf("genProto3Msg(%v): did not get expected single message, got: %v", msg, got)
+	}
+
+	fields := got[0].Fields
+	if len(fields) != len(msg.fields) {
+		t.Fatalf("genProto3Msg(%v): did not get expected number of fields, got: %d, want: %d", msg, len(fields), len(msg.fields))
+	}
+	if !sort.SliceIsSorted(fields, func(i, j int) bool { return fields[i].Tag < fields[j].Tag }) {
+		t.Errorf("genProto3Msg(%v): fields were not in ascending tag order, got: %s", msg, pretty.Sprint(fields))
+	}
+}
+
+func TestGenProto3MsgOrderOneofsByTag(t *testing.T) {
+	var unionTypes []*yang.YangType
+	for _, k := range []yang.TypeKind{yang.Ystring, yang.Yint8, yang.Yuint8, yang.Ybool, yang.Ydecimal64} {
+		unionTypes = append(unionTypes, &yang.YangType{Name: k.String(), Kind: k})
+	}
+	msg := &yangDirectory{
+		name: "MessageName",
+		entry: &yang.Entry{
+			Name: "message-name",
+			Dir:  map[string]*yang.Entry{},
+			Kind: yang.DirectoryEntry,
+		},
+		fields: map[string]*yang.Entry{
+			"union-field": {
+				Name: "union-field",
+				Type: &yang.YangType{
+					Name: "union",
+					Kind: yang.Yunion,
+					Type: unionTypes,
+				},
+			},
+		},
+		path: []string{"", "root", "message-name"},
+	}
 
-		if err != nil {
-			continue
+	for _, order := range []bool{false, true} {
+		got, errs := genProto3Msg(msg, nil, newGenState(), &protoMsgConfig{
+			basePackageName:  "base",
+			enumPackageName:  "enums",
+			orderOneofsByTag: order,
+		}, "", nil)
+		if errs != nil {
+			t.Fatalf("genProto3Msg(%v), orderOneofsByTag: %v: could not generate message, got errors: %v", msg, order, errs)
+		}
+		if len(got) != 1 || len(got[0].Fields) != 1 || !got[0].Fields[0].IsOneOf {
+			t.Fatalf("genProto3Msg(%v), orderOneofsByTag: %v: did not get expected single oneof, got: %s", msg, order, pretty.Sprint(got))
 		}
 
-		// Sort the returned output to avoid test flakes.
-		sort.Strings(got)
-		if diff := pretty.Compare(got, tt.wantEnums); diff != "" {
-			t.Errorf("%s: writeProtoEnums(%v): did not get expected output, diff(-got,+want):\n%s", tt.name, tt.inEnums, diff)
+		members := got[0].Fields[0].OneOfFields
+		if len(members) != len(unionTypes) {
+			t.Fatalf("genProto3Msg(%v), orderOneofsByTag: %v: did not get expected number of oneof fields, got: %d, want: %d", msg, order, len(members), len(unionTypes))
+		}
+		byTag := sort.SliceIsSorted(members, func(i, j int) bool { return members[i].Tag < members[j].Tag })
+		byType := sort.SliceIsSorted(members, func(i, j int) bool { return members[i].Type < members[j].Type })
+		if order && !byTag {
+			t.Errorf("genProto3Msg(%v), orderOneofsByTag: true: oneof fields were not in ascending tag order, got: %s", msg, pretty.Sprint(members))
+		}
+		if !order && (!byType || byTag) {
+			t.Errorf("genProto3Msg(%v), orderOneofsByTag: false: oneof fields were not in type order, got: %s", msg, pretty.Sprint(members))
 		}
 	}
 }
 
-func TestUnionFieldToOneOf(t *testing.T) {
-	// Create mock enumerations within goyang since we cannot create them in-line.
-	testEnums := map[string][]string{
-		"enumOne": {"SPEED_2.5G", "SPEED_40G"},
+func TestSortFieldsByTag(t *testing.T) {
+	tests := []struct {
+		name string
+		in   []*protoMsgField
+		want []*protoMsgField
+	}{{
+		name: "fields in ascending tag order",
+		in:   []*protoMsgField{{Name: "a", Tag: 3}, {Name: "b", Tag: 1}, {Name: "c", Tag: 2}},
+		want: []*protoMsgField{{Name: "b", Tag: 1}, {Name: "c", Tag: 2}, {Name: "a", Tag: 3}},
+	}, {
+		name: "equal tags ordered by name",
+		in:   []*protoMsgField{{Name: "b", Tag: 1}, {Name: "a", Tag: 1}, {Name: "c", Tag: 0}},
+		want: []*protoMsgField{{Name: "c", Tag: 0}, {Name: "a", Tag: 1}, {Name: "b", Tag: 1}},
+	}, {
+		name: "oneof ordered by its lowest tag",
+		in: []*protoMsgField{{Name: "a", Tag: 3}, {
+			Name:        "b",
+			Tag:         1,
+			IsOneOf:     true,
+			OneOfFields: []*protoMsgField{{Name: "b_one", Tag: 5}, {Name: "b_two", Tag: 4}},
+		}},
+		want: []*protoMsgField{{Name: "a", Tag: 3}, {
+			Name:        "b",
+			Tag:         1,
+			IsOneOf:     true,
+			OneOfFields: []*protoMsgField{{Name: "b_one", Tag: 5}, {Name: "b_two", Tag: 4}},
+		}},
+	}, {
+		name: "oneof ordered before a field with a lower tag than its first field",
+		in: []*protoMsgField{{Name: "a", Tag: 3}, {
+			Name:        "b",
+			IsOneOf:     true,
+			OneOfFields: []*protoMsgField{{Name: "b_one", Tag: 4}, {Name: "b_two", Tag: 2}},
+		}},
+		want: []*protoMsgField{{
+			Name:        "b",
+			IsOneOf:     true,
+			OneOfFields: []*protoMsgField{{Name: "b_one", Tag: 4}, {Name: "b_two", Tag: 2}},
+		}, {Name: "a", Tag: 3}},
+	}}
+
+	for _, tt := range tests {
+		sortFieldsByTag(tt.in)
+		if diff := pretty.Compare(tt.in, tt.want); diff != "" {
+			t.Errorf("%s: sortFieldsByTag: did not get expected order, diff(-got,+want):\n%s", tt.name, diff)
+		}
 	}
-	testYANGEnums := map[string]*yang.EnumType{}
+}
 
-	for name, values := range testEnums {
-		enum := yang.NewEnumType()
-		for i, v := range values {
-			enum.Set(v, int64(i))
+func TestProtoImportCycle(t *testing.T) {
+	imports := func(paths ...string) map[string]interface{} {
+		m := map[string]interface{}{}
+		for _, p := range paths {
+			m[p] = true
 		}
-		testYANGEnums[name] = enum
+		return m
 	}
 
 	tests := []struct {
-		name                string
-		inName              string
-		inEntry             *yang.Entry
-		inMappedType        *mappedType
-		inAnnotateEnumNames bool
-		wantFields          []*protoMsgField
-		wantEnums           map[string]*protoMsgEnum
-		wantRepeatedMsg     *protoMsg
-		wantErr             bool
+		name             string
+		inPkgImports     map[string]map[string]interface{}
+		inBaseImportPath string
+		want             []string
 	}{{
-		name:   "simple string union",
-		inName: "FieldName",
-		inEntry: &yang.Entry{
-			Name: "field-name",
-			Type: &yang.YangType{
-				Type: []*yang.YangType{
-					{Kind: yang.Ystring},
-					{Kind: yang.Yint8},
-				},
-			},
-		},
-		inMappedType: &mappedType{
-			unionTypes: map[string]int{
-				"string": 0,
-				"sint64": 0,
-			},
+		name: "no cycle",
+		inPkgImports: map[string]map[string]interface{}{
+			"base":       imports("base/a/a.proto", "github.com/openconfig/ygot/proto/ywrapper/ywrapper.proto"),
+			"base.a":     imports("base/a/b/b.proto"),
+			"base.a.b":   imports("base/enums/enums.proto"),
+			"base.enums": imports(),
 		},
-		wantFields: []*protoMsgField{{
-			Tag:  171677331,
-			Name: "FieldName_sint64",
-			Type: "sint64",
-		}, {
-			Tag:  173535000,
-			Name: "FieldName_string",
-			Type: "string",
-		}},
-		wantEnums: map[string]*protoMsgEnum{},
 	}, {
-		name:   "decimal64 union",
-		inName: "FieldName",
-		inEntry: &yang.Entry{
-			Name: "field-name",
-			Type: &yang.YangType{
-				Type: []*yang.YangType{
-					{Kind: yang.Ystring},
-					{Kind: yang.Ydecimal64},
-				},
-			},
+		name: "two package cycle",
+		inPkgImports: map[string]map[string]interface{}{
+			"base":   imports("base/a/a.proto"),
+			"base.a": imports("base/b/b.proto"),
+			"base.b": imports("base/a/a.proto"),
 		},
-		inMappedType: &mappedType{
-			unionTypes: map[string]int{
-				"string":             0,
-				"ywrapper.Decimal64": 1,
-			},
-		},
-		wantFields: []*protoMsgField{{
-			Tag:  173535000,
-			Name: "FieldName_string",
-			Type: "string",
-		}, {
-			Tag:  328616554,
-			Name: "FieldName_decimal64",
-			Type: "ywrapper.Decimal64",
-		}},
-		wantEnums: map[string]*protoMsgEnum{},
+		want: []string{"base.a", "base.b", "base.a"},
 	}, {
-		name:   "union with an enumeration",
-		inName: "FieldName",
-		inEntry: &yang.Entry{
-			Name: "field-name",
-			Type: &yang.YangType{
-				Type: []*yang.YangType{
-					{
-						Name: "enumeration",
-						Kind: yang.Yenum,
-						Enum: testYANGEnums["enumOne"],
-					},
-					{Kind: yang.Ystring},
-				},
-			},
-		},
-		inMappedType: &mappedType{
-			unionTypes: map[string]int{
-				"SomeEnumType": 0,
-				"string":       1,
-			},
-		},
-		inAnnotateEnumNames: true,
-		wantFields: []*protoMsgField{{
-			Tag:  29065580,
-			Name: "FieldName_someenumtype",
-			Type: "SomeEnumType",
-		}, {
-			Tag:  173535000,
-			Name: "FieldName_string",
-			Type: "string",
-		}},
-		wantEnums: map[string]*protoMsgEnum{
-			"FieldName": {
-				Values: map[int64]protoEnumValue{
-					0: {ProtoLabel: "UNSET"},
-					1: {ProtoLabel: "SPEED_2_5G", YANGLabel: "SPEED_2.5G"},
-					2: {ProtoLabel: "SPEED_40G", YANGLabel: "SPEED_40G"},
-				},
-			},
+		name: "three package cycle with base import path",
+		inPkgImports: map[string]map[string]interface{}{
+			"base.a": imports("github.com/foo/base/b/b.proto"),
+			"base.b": imports("github.com/foo/base/c/c.proto"),
+			"base.c": imports("github.com/foo/base/a/a.proto"),
 		},
+		inBaseImportPath: "github.com/foo",
+		want:             []string{"base.a", "base.b", "base.c", "base.a"},
 	}, {
-		name:   "leaflist of union",
-		inName: "FieldName",
-		inEntry: &yang.Entry{
-			Name: "field-name",
-			Type: &yang.YangType{
-				Type: []*yang.YangType{
-					{Kind: yang.Ystring},
-					{Kind: yang.Yuint8},
-				},
-			},
-			Parent:   &yang.Entry{Name: "parent"},
-			ListAttr: &yang.ListAttr{},
-		},
-		inMappedType: &mappedType{
-			unionTypes: map[string]int{
-				"string": 0,
-				"uint64": 1,
-			},
+		name: "package importing itself",
+		inPkgImports: map[string]map[string]interface{}{
+			"base.a": imports("base/a/a.proto"),
 		},
-		wantRepeatedMsg: &protoMsg{
-			Name:     "FieldNameUnion",
-			YANGPath: "/parent/field-name union field field-name",
-			Fields: []*protoMsgField{{
-				Tag:  85114709,
-				Name: "FieldName_string",
-				Type: "string",
-			}, {
-				Tag:  192993976,
-				Name: "FieldName_uint64",
-				Type: "uint64",
-			}},
+		want: []string{"base.a", "base.a"},
+	}, {
+		name: "imports of files that are not generated",
+		inPkgImports: map[string]map[string]interface{}{
+			"base.a": imports("base/b/b.proto", "google/protobuf/any.proto"),
 		},
 	}}
 
 	for _, tt := range tests {
-		got, err := unionFieldToOneOf(tt.inName, tt.inEntry, tt.inMappedType, tt.inAnnotateEnumNames)
-		if (err != nil) != tt.wantErr {
-			t.Errorf("%s: unionFieldToOneOf(%s, %v, %v, %v): did not get expected error, got: %v, wanted err: %v", tt.name, tt.inName, tt.inEntry, tt.inMappedType, tt.inAnnotateEnumNames, err, tt.wantErr)
+		if diff := pretty.Compare(protoImportCycle(tt.inPkgImports, tt.inBaseImportPath), tt.want); diff != "" {
+			t.Errorf("%s: protoImportCycle(%v, %s): did not get expected cycle, diff(-got,+want):\n%s", tt.name, tt.inPkgImports, tt.inBaseImportPath, diff)
 		}
+	}
+}
 
-		if err != nil {
-			continue
+func TestHeadersEquivalent(t *testing.T) {
+	header := func(pkg string, imports, includePaths []string) proto3Header {
+		return proto3Header{
+			PackageName:            pkg,
+			Imports:                imports,
+			SourceYANGFiles:        []string{"a.yang"},
+			SourceYANGIncludePaths: includePaths,
+			CallerName:             "caller",
 		}
+	}
 
-		if diff := pretty.Compare(got.oneOfFields, tt.wantFields); diff != "" {
-			t.Errorf("%s: unionFieldToOneOf(%s, %v, %v, %v): did not get expected set of fields, diff(-got,+want):\n%s", tt.name, tt.inName, tt.inEntry, tt.inMappedType, tt.inAnnotateEnumNames, diff)
-		}
+	tests := []struct {
+		name      string
+		inA       proto3Header
+		inB       proto3Header
+		want      bool
+		wantDiffs []string
+	}{{
+		name: "identical headers",
+		inA:  header("base", []string{"a/a.proto", "b/b.proto"}, []string{"/yang"}),
+		inB:  header("base", []string{"a/a.proto", "b/b.proto"}, []string{"/yang"}),
+		want: true,
+	}, {
+		name: "reordered imports and include paths",
+		inA:  header("base", []string{"a/a.proto", "b/b.proto"}, []string{"/yang", "/other"}),
+		inB:  header("base", []string{"b/b.proto", "a/a.proto"}, []string{"/other", "/yang"}),
+		want: true,
+	}, {
+		name:      "differing package name",
+		inA:       header("base", []string{"a/a.proto"}, nil),
+		inB:       header("other", []string{"a/a.proto"}, nil),
+		wantDiffs: []string{"other fields differ"},
+	}, {
+		name:      "differing imports",
+		inA:       header("base", []string{"a/a.proto"}, nil),
+		inB:       header("base", []string{"a/a.proto", "b/b.proto"}, nil),
+		wantDiffs: []string{"Imports differ"},
+	}, {
+		name: "differing source files are compared in order",
+		inA:  header("base", nil, []string{"/yang"}),
+		inB: proto3Header{
+			PackageName:            "base",
+			SourceYANGFiles:        []string{"b.yang", "a.yang"},
+			SourceYANGIncludePaths: []string{"/yang", "/other"},
+			CallerName:             "caller",
+		},
+		wantDiffs: []string{"SourceYANGIncludePaths differ", "other fields differ"},
+	}}
 
-		if diff := pretty.Compare(got.enums, tt.wantEnums); diff != "" {
-			t.Errorf("%s: unionFieldToOneOf(%s, %v, %v, %v): did not get expected set of enums, diff(-got,+want):\n%s", tt.name, tt.inName, tt.inEntry, tt.inMappedType, tt.inAnnotateEnumNames, diff)
+	for _, tt := range tests {
+		got, diff := HeadersEquivalent(tt.inA, tt.inB)
+		if got != tt.want {
+			t.Errorf("%s: HeadersEquivalent(%v, %v): did not get expected result, got: %v, want: %v, diff: %s", tt.name, tt.inA, tt.inB, got, tt.want, diff)
 		}
+		if got != (diff == "") {
+			t.Errorf("%s: HeadersEquivalent(%v, %v): got inconsistent diff %q for result %v", tt.name, tt.inA, tt.inB, diff, got)
+		}
+		for _, d := range tt.wantDiffs {
+			if !strings.Contains(diff, d) {
+				t.Errorf("%s: HeadersEquivalent(%v, %v): diff did not report %q, got: %s", tt.name, tt.inA, tt.inB, d, diff)
+			}
+		}
+	}
+}
 
-		if diff := pretty.Compare(got.repeatedMsg, tt.wantRepeatedMsg); diff != "" {
-			t.Errorf("%s: unionFieldToOneOf(%s, %v, %v, %v): did not get expected repeated message, diff(-got,+want):\n%s", tt.name, tt.inName, tt.inEntry, tt.inMappedType, tt.inAnnotateEnumNames, diff)
+func TestNormalizeProtoCode(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{{
+		name: "already normalized",
+		in:   "\nmessage A {\n  string a = 1;\n}\n\nmessage B {\n}",
+		want: "\nmessage A {\n  string a = 1;\n}\n\nmessage B {\n}",
+	}, {
+		name: "missing leading newline and extra trailing newlines",
+		in:   "message A {\n}\n\n\n",
+		want: "\nmessage A {\n}",
+	}, {
+		name: "multiple blank lines between messages",
+		in:   "\n\n// A is a message.\nmessage A {\n}\n\n\n\n// B is a message.\nmessage B {\n}",
+		want: "\n// A is a message.\nmessage A {\n}\n\n// B is a message.\nmessage B {\n}",
+	}, {
+		name: "no blank line between messages",
+		in:   "\nmessage A {\n}\nmessage B {\n}",
+		want: "\nmessage A {\n}\n\nmessage B {\n}",
+	}, {
+		name: "trailing whitespace",
+		in:   "\nmessage A {  \n  string a = 1;\t\n  \n}",
+		want: "\nmessage A {\n  string a = 1;\n\n}",
+	}, {
+		name: "nested messages are not separated",
+		in:   "\nmessage A {\n  message B {\n  }\n  B b = 1;\n}",
+		want: "\nmessage A {\n  message B {\n  }\n  B b = 1;\n}",
+	}}
+
+	for _, tt := range tests {
+		if got := normalizeProtoCode(tt.in); got != tt.want {
+			t.Errorf("%s: normalizeProtoCode(%q): did not get expected code, got: %q, want: %q", tt.name, tt.in, got, tt.want)
+		}
+		if got := normalizeProtoCode(tt.want); got != tt.want {
+			t.Errorf("%s: normalizeProtoCode(%q): normalized code was not stable, got: %q", tt.name, tt.want, got)
 		}
 	}
 }
 
-func TestStripPackagePrefix(t *testing.T) {
+func TestBlockComments(t *testing.T) {
 	tests := []struct {
-		name         string
-		inPrefix     string
-		inPath       string
-		want         string
-		wantStripped bool
+		name string
+		in   string
+		want string
 	}{{
-		name:         "invalid prefix at element one",
-		inPrefix:     "one.two",
-		inPath:       "two.four",
-		want:         "two.four",
-		wantStripped: false,
+		name: "no comments",
+		in:   "\nmessage A {\n  string a = 1;\n}",
+		want: "\nmessage A {\n  string a = 1;\n}",
 	}, {
-		name:         "single element prefix",
-		inPrefix:     "one",
-		inPath:       "one.three",
-		want:         "three",
-		wantStripped: true,
+		name: "single line comment",
+		in:   "\n// A represents the /a YANG schema element.\nmessage A {\n}",
+		want: "\n/* A represents the /a YANG schema element. */\nmessage A {\n}",
 	}, {
-		name:         "longer prefix",
-		inPrefix:     "one.two.three",
-		inPath:       "one.two.three.five",
-		want:         "five",
-		wantStripped: true,
+		name: "multi-line field description",
+		in: "\nmessage A {\n" +
+			"  // The name of the interface.\n" +
+			"  //\n" +
+			"  //   Indented text.\n" +
+			"  ywrapper.StringValue name = 1;\n" +
+			"}",
+		want: "\nmessage A {\n" +
+			"  /*\n" +
+			"   * The name of the interface.\n" +
+			"   *\n" +
+			"   *   Indented text.\n" +
+			"   */\n" +
+			"  ywrapper.StringValue name = 1;\n" +
+			"}",
+	}, {
+		name: "adjacent comments with differing indentation",
+		in:   "\n// A is a message.\nmessage A {\n  // a is a field.\n  string a = 1;\n}",
+		want: "\n/* A is a message. */\nmessage A {\n  /* a is a field. */\n  string a = 1;\n}",
+	}, {
+		name: "comment containing closing delimiter",
+		in:   "\n// Matches a*/b.\nmessage A {\n}",
+		want: "\n/* Matches a* /b. */\nmessage A {\n}",
+	}, {
+		name: "header",
+		in: "// pkg is generated by test as a protobuf\n" +
+			"// representation of a YANG schema.\n" +
+			"//\n" +
+			"// Input schema modules:\n" +
+			"//  - a.yang\n" +
+			"syntax = \"proto3\";",
+		want: "/*\n" +
+			" * pkg is generated by test as a protobuf\n" +
+			" * representation of a YANG schema.\n" +
+			" *\n" +
+			" * Input schema modules:\n" +
+			" *  - a.yang\n" +
+			" */\n" +
+			"syntax = \"proto3\";",
 	}}
 
 	for _, tt := range tests {
-		got, stripped := stripPackagePrefix(tt.inPrefix, tt.inPath)
-		if got != tt.want {
-			t.Errorf("%s: stripPackagePrefix(%s, %s): did not get expected output, got: %s, want: %s", tt.name, tt.inPrefix, tt.inPath, got, tt.want)
+		if got := blockComments(tt.in); got != tt.want {
+			t.Errorf("%s: blockComments(%q): did not get expected code, got: %q, want: %q", tt.name, tt.in, got, tt.want)
 		}
+	}
+}
 
-		if stripped != tt.wantStripped {
-			t.Errorf("%s: stripPackagePrefix(%s, %s): did not get expected stipped status, got: %v, want: %v", tt.name, tt.inPrefix, tt.inPath, stripped, tt.wantStripped)
+func TestWriteProto3MsgMultipleMessagesFormatting(t *testing.T) {
+	parent := &yang.Entry{
+		Name:   "a-message",
+		Kind:   yang.DirectoryEntry,
+		Dir:    map[string]*yang.Entry{},
+		Parent: &yang.Entry{Name: "root", Kind: yang.DirectoryEntry},
+	}
+	parent.Dir["name"] = &yang.Entry{
+		Name:   "name",
+		Type:   &yang.YangType{Kind: yang.Ystring},
+		Parent: parent,
+	}
+	parent.Dir["counter"] = &yang.Entry{
+		Name:   "counter",
+		Type:   &yang.YangType{Kind: yang.Yuint64},
+		Config: yang.TSFalse,
+		Parent: parent,
+	}
+	msg := &yangDirectory{
+		name:   "AMessage",
+		entry:  parent,
+		fields: parent.Dir,
+		path:   []string{"", "root", "a-message"},
+	}
+
+	got, errs := writeProto3Msg(msg, nil, newGenState(), &protoMsgConfig{
+		basePackageName:  "base",
+		enumPackageName:  "enums",
+		splitConfigState: true,
+	})
+	if errs != nil {
+		t.Fatalf("writeProto3Msg(%v): got unexpected errors: %v", msg, errs)
+	}
+
+	want := `
+// AMessageConfig represents the /root/a-message YANG schema element.
+// Defined in module root.
+message AMessageConfig {
+  ywrapper.StringValue name = 328240900;
+}
+
+// AMessageState represents the /root/a-message YANG schema element.
+// Defined in module root.
+message AMessageState {
+  ywrapper.UintValue counter = 62970075;
+}
+
+// AMessage represents the /root/a-message YANG schema element.
+// Defined in module root.
+message AMessage {
+  AMessageConfig config = 280256943;
+  AMessageState state = 340817952;
+}`
+	if diff := pretty.Compare(got.MessageCode, want); diff != "" {
+		if diffl, _ := testutil.GenerateUnifiedDiff(got.MessageCode, want); diffl != "" {
+			diff = diffl
 		}
+		t.Errorf("writeProto3Msg(%v): did not get expected message code, diff(-got,+want):\n%s", msg, diff)
 	}
 
+	// Writing the messages of the package as a file, each followed by a
+	// newline, should result in a single trailing newline.
+	file := fmt.Sprintf("%s\n%s\n", got.MessageCode, got.MessageCode)
+	if strings.HasSuffix(file, "\n\n") || strings.Contains(file, "\n\n\n") {
+		t.Errorf("writeProto3Msg(%v): output file did not have consistent blank lines, got: %q", msg, file)
+	}
 }
@@ -0,0 +1,174 @@
+// Copyright 2018 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ygen
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"sort"
+	"strings"
+)
+
+// ProtoManifest is a machine-readable description of the protobuf messages
+// that were generated from a YANG schema. It records the field numbers that
+// are used within each message, such that the wire compatibility of
+// subsequent generations can be checked, and such that the field numbers can
+// be reserved by subsequent generations using the ReservedFieldTags method.
+type ProtoManifest struct {
+	// Messages is the set of generated messages, sorted by package and
+	// then by name.
+	Messages []*ProtoManifestMessage `json:"messages"`
+}
+
+// ProtoManifestMessage describes a single generated protobuf message.
+type ProtoManifestMessage struct {
+	// Package is the protobuf package that the message is within.
+	Package string `json:"package"`
+	// Name is the name of the message within its package. When nested
+	// messages are output, the name is qualified by the names of the
+	// messages within which it is nested, e.g., Parent.Child.
+	Name string `json:"name"`
+	// YANGPath is the path of the YANG schema element that the message
+	// was generated for.
+	YANGPath string `json:"yang_path"`
+	// ListKey indicates that the message was generated for the key of a
+	// YANG list, rather than for the schema element at YANGPath.
+	ListKey bool `json:"list_key,omitempty"`
+	// Fields is the set of fields that are within the message.
+	Fields []*ProtoManifestField `json:"fields,omitempty"`
+	// ReservedTags is the set of field numbers that are reserved within
+	// the message.
+	ReservedTags []uint32 `json:"reserved_tags,omitempty"`
+}
+
+// ProtoManifestField describes a field within a generated protobuf message.
+type ProtoManifestField struct {
+	// Name is the name of the field.
+	Name string `json:"name"`
+	// Tag is the field number of the field.
+	Tag uint32 `json:"tag"`
+	// Type is the protobuf type of the field.
+	Type string `json:"type"`
+	// Repeated indicates whether the field is repeated.
+	Repeated bool `json:"repeated,omitempty"`
+	// Oneof is the name of the oneof that the field is within, if any.
+	Oneof string `json:"oneof,omitempty"`
+	// SchemaPaths is the set of YANG schema paths that the field
+	// corresponds to. It is empty for fields that do not correspond to
+	// a schema element, e.g., the field of a list key message that
+	// contains the list member.
+	SchemaPaths []string `json:"schema_paths,omitempty"`
+}
+
+// ReservedFieldTags returns a map, keyed by YANG schema path, of the field
+// numbers that are used or reserved within the messages described by the
+// manifest. It is of the form expected by the ReservedFieldTags field of
+// ProtoOpts, such that a subsequent generation reserves the field numbers of
+// fields that have since been removed. Since the field numbers of list key
+// messages are not derived from schema paths, they are not included.
+func (m *ProtoManifest) ReservedFieldTags() map[string][]uint32 {
+	tags := map[string][]uint32{}
+	for _, msg := range m.Messages {
+		if msg.ListKey {
+			continue
+		}
+		for _, f := range msg.Fields {
+			tags[msg.YANGPath] = append(tags[msg.YANGPath], f.Tag)
+		}
+		tags[msg.YANGPath] = append(tags[msg.YANGPath], msg.ReservedTags...)
+	}
+	for p, t := range tags {
+		tags[p] = reservedFieldTags(t, nil)
+	}
+	return tags
+}
+
+// WriteProtoManifest writes the manifest m to w as JSON.
+func WriteProtoManifest(w io.Writer, m *ProtoManifest) error {
+	j, err := json.MarshalIndent(m, "", strings.Repeat(" ", 4))
+	if err != nil {
+		return fmt.Errorf("could not marshal manifest: %v", err)
+	}
+	if _, err := w.Write(append(j, '\n')); err != nil {
+		return fmt.Errorf("could not write manifest: %v", err)
+	}
+	return nil
+}
+
+// ReadProtoManifest reads a manifest that was written by WriteProtoManifest
+// from r.
+func ReadProtoManifest(r io.Reader) (*ProtoManifest, error) {
+	b, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("could not read manifest: %v", err)
+	}
+	m := &ProtoManifest{}
+	if err := json.Unmarshal(b, m); err != nil {
+		return nil, fmt.Errorf("could not unmarshal manifest: %v", err)
+	}
+	return m, nil
+}
+
+// protoManifestMessage returns the manifest entry describing the message
+// msgDef.
+func protoManifestMessage(msgDef *protoMsg) *ProtoManifestMessage {
+	m := &ProtoManifestMessage{
+		Name:         msgDef.Name,
+		YANGPath:     msgDef.YANGPath,
+		ListKey:      msgDef.ListKey,
+		ReservedTags: msgDef.ReservedTags,
+	}
+	for _, f := range msgDef.Fields {
+		if !f.IsOneOf {
+			m.Fields = append(m.Fields, protoManifestField(f, ""))
+			continue
+		}
+		// The fields within a oneof are fields of the message, and
+		// correspond to the schema element of the oneof.
+		for _, of := range f.OneOfFields {
+			mf := protoManifestField(of, f.Name)
+			mf.SchemaPaths = f.SchemaPaths
+			m.Fields = append(m.Fields, mf)
+		}
+	}
+	return m
+}
+
+// protoManifestField returns the manifest entry describing the field f, which
+// is within the oneof named oneof, if it is non-empty.
+func protoManifestField(f *protoMsgField, oneof string) *ProtoManifestField {
+	return &ProtoManifestField{
+		Name:        f.Name,
+		Tag:         f.Tag,
+		Type:        f.Type,
+		Repeated:    f.IsRepeated,
+		Oneof:       oneof,
+		SchemaPaths: f.SchemaPaths,
+	}
+}
+
+// sortProtoManifest sorts the messages within the manifest m by package and
+// then by name, such that the manifest is deterministic.
+func sortProtoManifest(m *ProtoManifest) {
+	sort.Slice(m.Messages, func(i, j int) bool {
+		a, b := m.Messages[i], m.Messages[j]
+		if a.Package != b.Package {
+			return a.Package < b.Package
+		}
+		return a.Name < b.Name
+	})
+}
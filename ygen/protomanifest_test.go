@@ -0,0 +1,235 @@
+// Copyright 2018 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ygen
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/kylelemons/godebug/pretty"
+	"github.com/openconfig/goyang/pkg/yang"
+)
+
+func TestProtoManifestRoundTrip(t *testing.T) {
+	module := &yang.Entry{Name: "module", Kind: yang.DirectoryEntry}
+	container := &yang.Entry{
+		Name:   "container",
+		Kind:   yang.DirectoryEntry,
+		Parent: module,
+	}
+	leaf := func(name string, t *yang.YangType) *yang.Entry {
+		return &yang.Entry{
+			Name:   name,
+			Kind:   yang.LeafEntry,
+			Parent: container,
+			Type:   t,
+		}
+	}
+	fields := map[string]*yang.Entry{
+		"mtu":         leaf("mtu", &yang.YangType{Kind: yang.Yuint32}),
+		"description": leaf("description", &yang.YangType{Kind: yang.Ystring}),
+		"address": leaf("address", &yang.YangType{
+			Kind: yang.Yunion,
+			Type: []*yang.YangType{{Kind: yang.Ystring}, {Kind: yang.Yuint32}},
+		}),
+	}
+	msg := func(fieldNames ...string) *yangDirectory {
+		d := &yangDirectory{
+			name:   "Container",
+			entry:  container,
+			fields: map[string]*yang.Entry{},
+			path:   []string{"", "module", "container"},
+		}
+		for _, n := range fieldNames {
+			d.fields[n] = fields[n]
+		}
+		return d
+	}
+
+	in := msg("mtu", "description", "address")
+	got, errs := writeProto3Msg(in, nil, newGenState(), &protoMsgConfig{
+		compressPaths:   true,
+		basePackageName: "base",
+		enumPackageName: "enums",
+		manifest:        true,
+	})
+	if errs != nil {
+		t.Fatalf("writeProto3Msg(%v): got unexpected errors: %v", in, errs)
+	}
+
+	want := &ProtoManifest{
+		Messages: []*ProtoManifestMessage{{
+			Name:     "Container",
+			YANGPath: "/module/container",
+			Fields: []*ProtoManifestField{{
+				Name:        "address_string",
+				Tag:         212741127,
+				Type:        "string",
+				Oneof:       "address",
+				SchemaPaths: []string{"/container/address"},
+			}, {
+				Name:        "address_uint64",
+				Tag:         17735034,
+				Type:        "uint64",
+				Oneof:       "address",
+				SchemaPaths: []string{"/container/address"},
+			}, {
+				Name:        "description",
+				Tag:         417348083,
+				Type:        "ywrapper.StringValue",
+				SchemaPaths: []string{"/container/description"},
+			}, {
+				Name:        "mtu",
+				Tag:         441717745,
+				Type:        "ywrapper.UintValue",
+				SchemaPaths: []string{"/container/mtu"},
+			}},
+		}},
+	}
+	gotManifest := &ProtoManifest{Messages: got.Manifest}
+	if diff := pretty.Compare(gotManifest, want); diff != "" {
+		t.Fatalf("writeProto3Msg(%v): did not get expected manifest, diff(-got,+want):\n%s", in, diff)
+	}
+
+	var b bytes.Buffer
+	if err := WriteProtoManifest(&b, gotManifest); err != nil {
+		t.Fatalf("WriteProtoManifest(%v): got unexpected error: %v", gotManifest, err)
+	}
+	read, err := ReadProtoManifest(&b)
+	if err != nil {
+		t.Fatalf("ReadProtoManifest: got unexpected error: %v", err)
+	}
+	if diff := pretty.Compare(read, gotManifest); diff != "" {
+		t.Fatalf("ReadProtoManifest: did not get the manifest that was written, diff(-got,+want):\n%s", diff)
+	}
+
+	// Generating the message again, with the mtu leaf removed from the
+	// schema, using the field numbers from the manifest should reserve the
+	// field number of the mtu field.
+	in = msg("description", "address")
+	regen, errs := writeProto3Msg(in, nil, newGenState(), &protoMsgConfig{
+		compressPaths:   true,
+		basePackageName: "base",
+		enumPackageName: "enums",
+		manifest:        true,
+		reservedTags:    read.ReservedFieldTags(),
+	})
+	if errs != nil {
+		t.Fatalf("writeProto3Msg(%v): got unexpected errors: %v", in, errs)
+	}
+	if !strings.Contains(regen.MessageCode, "reserved 441717745;") {
+		t.Errorf("writeProto3Msg(%v): did not reserve field number of removed field, got:\n%s", in, regen.MessageCode)
+	}
+	if diff := pretty.Compare(regen.Manifest[0].ReservedTags, []uint32{441717745}); diff != "" {
+		t.Errorf("writeProto3Msg(%v): did not get expected reserved tags in manifest, diff(-got,+want):\n%s", in, diff)
+	}
+}
+
+func TestProtoManifestReservedFieldTags(t *testing.T) {
+	tests := []struct {
+		name string
+		in   *ProtoManifest
+		want map[string][]uint32
+	}{{
+		name: "empty manifest",
+		in:   &ProtoManifest{},
+		want: map[string][]uint32{},
+	}, {
+		name: "used and reserved tags",
+		in: &ProtoManifest{
+			Messages: []*ProtoManifestMessage{{
+				Name:         "Container",
+				YANGPath:     "/module/container",
+				Fields:       []*ProtoManifestField{{Name: "b", Tag: 20}, {Name: "a", Tag: 10}},
+				ReservedTags: []uint32{15},
+			}},
+		},
+		want: map[string][]uint32{"/module/container": {10, 15, 20}},
+	}, {
+		name: "config and state messages with the same path",
+		in: &ProtoManifest{
+			Messages: []*ProtoManifestMessage{{
+				Name:     "Container",
+				YANGPath: "/module/container",
+				Fields:   []*ProtoManifestField{{Name: "config", Tag: 1}},
+			}, {
+				Name:     "ContainerConfig",
+				YANGPath: "/module/container",
+				Fields:   []*ProtoManifestField{{Name: "a", Tag: 10}},
+			}},
+		},
+		want: map[string][]uint32{"/module/container": {1, 10}},
+	}, {
+		name: "list key message",
+		in: &ProtoManifest{
+			Messages: []*ProtoManifestMessage{{
+				Name:     "List",
+				YANGPath: "/module/list",
+				Fields:   []*ProtoManifestField{{Name: "a", Tag: 10}},
+			}, {
+				Name:     "ListKey",
+				YANGPath: "/module/list",
+				ListKey:  true,
+				Fields:   []*ProtoManifestField{{Name: "key", Tag: 1}, {Name: "list", Tag: 2}},
+			}},
+		},
+		want: map[string][]uint32{"/module/list": {10}},
+	}}
+
+	for _, tt := range tests {
+		if diff := pretty.Compare(tt.in.ReservedFieldTags(), tt.want); diff != "" {
+			t.Errorf("%s: (%v).ReservedFieldTags(): did not get expected tags, diff(-got,+want):\n%s", tt.name, tt.in, diff)
+		}
+	}
+}
+
+func TestGenProto3MsgCodeNestedManifest(t *testing.T) {
+	child := &generatedProto3Message{
+		MessageCode: "message Child {}",
+		Manifest: []*ProtoManifestMessage{{
+			Name:     "Child",
+			YANGPath: "/module/parent/child",
+		}, {
+			Name:     "Child.Grandchild",
+			YANGPath: "/module/parent/child/grandchild",
+		}},
+	}
+	msgDefs := []*protoMsg{{
+		Name:      "Parent",
+		YANGPath:  "/module/parent",
+		Enums:     map[string]*protoMsgEnum{},
+		ChildMsgs: []*generatedProto3Message{child},
+	}}
+
+	got, errs := genProto3MsgCode("base", msgDefs, true)
+	if errs != nil {
+		t.Fatalf("genProto3MsgCode(%v): got unexpected errors: %v", msgDefs, errs)
+	}
+
+	want := []*ProtoManifestMessage{{
+		Name:     "Parent",
+		YANGPath: "/module/parent",
+	}, {
+		Name:     "Parent.Child",
+		YANGPath: "/module/parent/child",
+	}, {
+		Name:     "Parent.Child.Grandchild",
+		YANGPath: "/module/parent/child/grandchild",
+	}}
+	if diff := pretty.Compare(got.Manifest, want); diff != "" {
+		t.Errorf("genProto3MsgCode(%v): did not get expected manifest, diff(-got,+want):\n%s", msgDefs, diff)
+	}
+}
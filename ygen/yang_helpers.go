@@ -157,6 +157,37 @@ func parentModuleName(node yang.Node) string {
 	return definingMod.NName()
 }
 
+// dataTreeModuleName returns the name of the YANG module whose data tree
+// contains the entry e, which is the name of the root of the schema tree in
+// which e is found.
+func dataTreeModuleName(e *yang.Entry) string {
+	for ; e.Parent != nil; e = e.Parent {
+	}
+	return e.Name
+}
+
+// augmentingModuleName returns the name of the YANG module containing the
+// augment statement by which the entry e, or its nearest ancestor that was
+// added to the schema tree by an augment, was defined. If the augment is
+// within a submodule, the name of the module to which it belongs is returned.
+// An empty string is returned if neither e nor its ancestors were augmented.
+func augmentingModuleName(e *yang.Entry) string {
+	for ; e != nil; e = e.Parent {
+		if e.Node == nil || e.Node.ParentNode() == nil || e.Node.ParentNode().Kind() != "augment" {
+			continue
+		}
+		root := yang.RootNode(e.Node)
+		if root == nil {
+			return ""
+		}
+		if root.Kind() == "submodule" {
+			return root.BelongsTo.NName()
+		}
+		return root.NName()
+	}
+	return ""
+}
+
 // traverseElementSchemaPath takes an input yang.Entry and walks up the tree to find
 // its path, expressed as a slice of strings, which is returned.
 func traverseElementSchemaPath(elem *yang.Entry) []string {
@@ -221,6 +252,48 @@ func isIdentityrefLeaf(e *yang.Entry) bool {
 	return e.Type.IdentityBase != nil
 }
 
+// whenXPath returns the XPath expression of the when statement of the YANG
+// schema node that the supplied yang.Entry was created from, and a bool
+// indicating whether the node has a when statement. Only when statements that
+// are specified directly on leaf, leaf-list, container or list statements are
+// returned.
+func whenXPath(e *yang.Entry) (string, bool) {
+	var when *yang.Value
+	switch n := e.Node.(type) {
+	case *yang.Leaf:
+		when = n.When
+	case *yang.LeafList:
+		when = n.When
+	case *yang.Container:
+		when = n.When
+	case *yang.List:
+		when = n.When
+	}
+	if when == nil {
+		return "", false
+	}
+	return when.Name, true
+}
+
+// groupingOrigin returns the name of the grouping from which the contents of
+// the supplied directory yang.Entry were instantiated, and a bool indicating
+// whether such a grouping exists. A grouping is only returned if the entry has
+// a single uses statement, and all of its children are defined by the grouping
+// that it references - such that the entry is solely a use-site of the
+// grouping.
+func groupingOrigin(e *yang.Entry) (string, bool) {
+	if len(e.Uses) != 1 || e.Uses[0].Grouping == nil || len(e.Dir) == 0 {
+		return "", false
+	}
+	g := e.Uses[0].Grouping
+	for n := range e.Dir {
+		if _, ok := g.Dir[n]; !ok {
+			return "", false
+		}
+	}
+	return g.Name, true
+}
+
 // slicePathToString takes a path represented as a slice of strings, and outputs
 // it as a single string, with path elements separated by a forward slash.
 func slicePathToString(path []string) string {
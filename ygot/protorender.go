@@ -0,0 +1,554 @@
+// Copyright 2018 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ygot
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io/ioutil"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/ptypes/wrappers"
+	"github.com/openconfig/ygot/proto/yext"
+	"github.com/openconfig/ygot/proto/ywrapper"
+
+	dpb "github.com/golang/protobuf/protoc-gen-go/descriptor"
+	gnmipb "github.com/openconfig/gnmi/proto/gnmi"
+)
+
+// describedProtoMessage is the interface implemented by generated protobuf
+// messages that allows the descriptor of the message to be retrieved. The
+// gzipped FileDescriptorProto of the file in which the message is defined is
+// returned, along with the path to the message within the file.
+type describedProtoMessage interface {
+	proto.Message
+	Descriptor() ([]byte, []int)
+}
+
+// describedProtoEnum is the interface implemented by generated protobuf enums
+// that allows the descriptor of the enum to be retrieved. It returns the
+// gzipped FileDescriptorProto of the file in which the enum is defined, along
+// with the path to the enum within the file.
+type describedProtoEnum interface {
+	EnumDescriptor() ([]byte, []int)
+}
+
+// NotificationFromProto takes an input protobuf message, m, that was generated
+// from a YANG schema with schema path annotations, and renders it to a gNMI
+// Notification message, marked with the timestamp ts. The schemaPath supplied
+// is the schema path of m, which is used as the prefix of the Notification.
+// An update is included for each populated leaf or leaf-list field within m
+// and its descendant messages, whose path is the path specified by the field's
+// schemapath annotation, relative to the prefix. Where a field is annotated
+// with more than one schema path, the first is used.
+//
+// Lists that are represented as a repeated key message have the value of each
+// key field specified within the path of their descendant updates, including
+// where the key has its zero value; an error is returned if a key is not set.
+// Since fields of protobuf scalar types do not indicate whether they are set,
+// such leaves are otherwise not included in the Notification when they have
+// their zero value. Enumerated values are rendered as their YANG names, and
+// hence the enums must be generated with the yang_name annotation. Lists that
+// are represented as a protobuf map are not supported, since the name of their
+// key is not recorded within the message, and result in an error.
+func NotificationFromProto(m proto.Message, schemaPath string, ts int64) (*gnmipb.Notification, error) {
+	pfx, err := StringToStructuredPath(schemaPath)
+	if err != nil {
+		return nil, fmt.Errorf("invalid schema path %s: %v", schemaPath, err)
+	}
+
+	r := &protoRenderer{prefix: pfx.Elem}
+	if err := r.render(m, map[string]map[string]string{}); err != nil {
+		return nil, err
+	}
+
+	return &gnmipb.Notification{
+		Timestamp: ts,
+		Prefix:    pfx,
+		Update:    r.updates,
+	}, nil
+}
+
+// protoRenderer stores the state of rendering a protobuf message to a gNMI
+// Notification.
+type protoRenderer struct {
+	// prefix is the prefix of the Notification, which is removed from the
+	// schema path of each update.
+	prefix []*gnmipb.PathElem
+	// updates is the set of updates that have been rendered.
+	updates []*gnmipb.Update
+}
+
+// render appends an update to the updates of the protoRenderer for each
+// populated leaf and leaf-list field of the message m, and recurses into each
+// of the populated child messages of m. The keys supplied map the schema path
+// of each list that m is a descendant of to the values of the keys of the
+// list entry that contains m.
+func (r *protoRenderer) render(m proto.Message, keys map[string]map[string]string) error {
+	return forEachProtoField(m, false, func(fd *dpb.FieldDescriptorProto, v reflect.Value) error {
+		path, annotated, err := protoFieldSchemaPath(fd)
+		if err != nil {
+			return err
+		}
+
+		switch {
+		case v.Kind() == reflect.Map:
+			return fmt.Errorf("list field %s is a map, which cannot be rendered", fd.GetName())
+		case isProtoChildMessage(fd, v) && fd.GetLabel() == dpb.FieldDescriptorProto_LABEL_REPEATED:
+			if !annotated {
+				return fmt.Errorf("list field %s does not have a schema path annotation", fd.GetName())
+			}
+			for i := 0; i < v.Len(); i++ {
+				e, ok := v.Index(i).Interface().(proto.Message)
+				if !ok {
+					return fmt.Errorf("entry %d of list field %s is not a protobuf message", i, fd.GetName())
+				}
+				ek, err := protoListKeys(e, path)
+				if err != nil {
+					return err
+				}
+				nk := map[string]map[string]string{}
+				for p, k := range keys {
+					nk[p] = k
+				}
+				nk[path] = ek
+				if err := r.render(e, nk); err != nil {
+					return err
+				}
+			}
+			return nil
+		case isProtoChildMessage(fd, v):
+			// Child messages that are not annotated are the value of the entry
+			// of a list that is represented as a key message, and are rendered
+			// in the same way as those that are annotated.
+			cm, ok := v.Interface().(proto.Message)
+			if !ok {
+				return fmt.Errorf("field %s is not a protobuf message", fd.GetName())
+			}
+			return r.render(cm, keys)
+		}
+
+		if !annotated {
+			return fmt.Errorf("field %s does not have a schema path annotation", fd.GetName())
+		}
+
+		val, err := protoTypedValue(fd, v)
+		if err != nil {
+			return fmt.Errorf("cannot render field %s: %v", fd.GetName(), err)
+		}
+		p, err := r.updatePath(path, keys)
+		if err != nil {
+			return err
+		}
+		r.updates = append(r.updates, &gnmipb.Update{Path: p, Val: val})
+		return nil
+	})
+}
+
+// updatePath returns the path of the update for the leaf with the supplied
+// schema path, relative to the prefix of the protoRenderer. The keys supplied
+// are added to the elements of the path that correspond to lists.
+func (r *protoRenderer) updatePath(schemaPath string, keys map[string]map[string]string) (*gnmipb.Path, error) {
+	p, err := StringToStructuredPath(schemaPath)
+	if err != nil {
+		return nil, fmt.Errorf("invalid schema path %s: %v", schemaPath, err)
+	}
+	if len(p.Elem) <= len(r.prefix) {
+		return nil, fmt.Errorf("schema path %s is not a descendant of the prefix", schemaPath)
+	}
+
+	var names []string
+	for i, e := range p.Elem {
+		names = append(names, e.Name)
+		if i < len(r.prefix) && r.prefix[i].Name != e.Name {
+			return nil, fmt.Errorf("schema path %s is not a descendant of the prefix", schemaPath)
+		}
+		if k, ok := keys["/"+strings.Join(names, "/")]; ok {
+			e.Key = map[string]string{}
+			for n, v := range k {
+				e.Key[n] = v
+			}
+		}
+	}
+	return &gnmipb.Path{Elem: p.Elem[len(r.prefix):]}, nil
+}
+
+// protoListKeys returns the values of the keys of the list entry e of the
+// list with the supplied schema path. Where e is a key message, which is
+// identified by its unannotated field holding the list entry, the keys are the
+// fields of e whose schema path is a direct child of the list. Key values are
+// rendered as their string representation, including where they have their
+// zero value, and an error is returned if a key is not set. Entries that are
+// not key messages have no keys.
+func protoListKeys(e proto.Message, listPath string) (map[string]string, error) {
+	fields, err := protoMessageFields(e)
+	if err != nil {
+		return nil, err
+	}
+
+	var isKeyMsg bool
+	var names []string
+	for _, fd := range fields {
+		path, annotated, err := protoFieldSchemaPath(fd)
+		if err != nil {
+			return nil, err
+		}
+		if !annotated {
+			isKeyMsg = isKeyMsg || fd.GetType() == dpb.FieldDescriptorProto_TYPE_MESSAGE
+			continue
+		}
+		if i := strings.LastIndex(path, "/"); path[:i] == listPath {
+			names = append(names, path[i+1:])
+		}
+	}
+
+	keys := map[string]string{}
+	if !isKeyMsg {
+		return keys, nil
+	}
+
+	err = forEachProtoField(e, true, func(fd *dpb.FieldDescriptorProto, v reflect.Value) error {
+		path, annotated, err := protoFieldSchemaPath(fd)
+		if err != nil || !annotated {
+			return err
+		}
+		i := strings.LastIndex(path, "/")
+		if path[:i] != listPath || (v.Kind() == reflect.Ptr && v.IsNil()) {
+			return nil
+		}
+		val, err := protoScalarValue(v)
+		if err != nil {
+			return fmt.Errorf("cannot render key %s of list %s: %v", fd.GetName(), listPath, err)
+		}
+		keys[path[i+1:]] = fmt.Sprintf("%v", val)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Strings(names)
+	for _, n := range names {
+		if _, ok := keys[n]; !ok {
+			return nil, fmt.Errorf("key %s of an entry of list %s is not set", n, listPath)
+		}
+	}
+	return keys, nil
+}
+
+// forEachProtoField calls the function fn for each field of the protobuf
+// message m, supplying the descriptor of the field and its value. For fields
+// that are members of a oneof, the populated member is supplied, and unset
+// oneofs are skipped. Unless includeZero is true, only populated fields are
+// supplied, where fields of scalar types are considered populated when they do
+// not have their zero value.
+func forEachProtoField(m proto.Message, includeZero bool, fn func(*dpb.FieldDescriptorProto, reflect.Value) error) error {
+	mv := reflect.ValueOf(m)
+	if mv.Kind() != reflect.Ptr || mv.Type().Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("message %T is not a pointer to a struct", m)
+	}
+	if mv.IsNil() {
+		return nil
+	}
+
+	fields, err := protoMessageFields(m)
+	if err != nil {
+		return err
+	}
+
+	sv := mv.Elem()
+	for i := 0; i < sv.NumField(); i++ {
+		sf, v := sv.Type().Field(i), sv.Field(i)
+		if _, ok := sf.Tag.Lookup("protobuf_oneof"); ok {
+			if v.IsNil() {
+				continue
+			}
+			// The oneof is an interface which holds a pointer to a struct with
+			// a single field, which is the populated member of the oneof.
+			ov := v.Elem().Elem()
+			sf, v = ov.Type().Field(0), ov.Field(0)
+		}
+
+		tag, ok := sf.Tag.Lookup("protobuf")
+		if !ok {
+			continue
+		}
+		if !includeZero && isZeroProtoValue(v) {
+			continue
+		}
+
+		parts := strings.Split(tag, ",")
+		if len(parts) < 2 {
+			return fmt.Errorf("invalid protobuf tag for field %s of message %T: %s", sf.Name, m, tag)
+		}
+		n, err := strconv.ParseInt(parts[1], 10, 32)
+		if err != nil {
+			return fmt.Errorf("invalid field number for field %s of message %T: %v", sf.Name, m, err)
+		}
+		fd, ok := fields[int32(n)]
+		if !ok {
+			return fmt.Errorf("field %s of message %T is not in its descriptor", sf.Name, m)
+		}
+
+		if err := fn(fd, v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// isZeroProtoValue returns true if the value v of a protobuf message field is
+// unpopulated.
+func isZeroProtoValue(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		return v.IsNil()
+	case reflect.Slice, reflect.Map:
+		return v.Len() == 0
+	}
+	return reflect.DeepEqual(v.Interface(), reflect.Zero(v.Type()).Interface())
+}
+
+// isProtoChildMessage returns true if the field described by fd, with value v,
+// is a message that corresponds to a YANG container or list, rather than a
+// wrapper message that holds the value of a leaf. Both the ywrapper messages
+// and the google.protobuf wrapper messages are considered to be wrappers.
+func isProtoChildMessage(fd *dpb.FieldDescriptorProto, v reflect.Value) bool {
+	if fd.GetType() != dpb.FieldDescriptorProto_TYPE_MESSAGE {
+		return false
+	}
+	if v.Kind() == reflect.Slice {
+		v = reflect.Zero(v.Type().Elem())
+	}
+	switch v.Interface().(type) {
+	case *ywrapper.StringValue, *ywrapper.BoolValue, *ywrapper.IntValue, *ywrapper.UintValue, *ywrapper.BytesValue, *ywrapper.Decimal64Value:
+		return false
+	case *wrappers.StringValue, *wrappers.BoolValue, *wrappers.Int64Value, *wrappers.UInt64Value, *wrappers.BytesValue:
+		return false
+	}
+	return true
+}
+
+// protoTypedValue returns the gNMI TypedValue for the value v of the leaf or
+// leaf-list field described by fd.
+func protoTypedValue(fd *dpb.FieldDescriptorProto, v reflect.Value) (*gnmipb.TypedValue, error) {
+	if fd.GetLabel() != dpb.FieldDescriptorProto_LABEL_REPEATED || v.Kind() != reflect.Slice || v.Type().Elem().Kind() == reflect.Uint8 {
+		s, err := protoScalarValue(v)
+		if err != nil {
+			return nil, err
+		}
+		return scalarTypedValue(s)
+	}
+
+	arr := &gnmipb.ScalarArray{}
+	for i := 0; i < v.Len(); i++ {
+		s, err := protoScalarValue(v.Index(i))
+		if err != nil {
+			return nil, err
+		}
+		tv, err := scalarTypedValue(s)
+		if err != nil {
+			return nil, err
+		}
+		arr.Element = append(arr.Element, tv)
+	}
+	return &gnmipb.TypedValue{Value: &gnmipb.TypedValue_LeaflistVal{LeaflistVal: arr}}, nil
+}
+
+// scalarTypedValue returns the gNMI TypedValue for the scalar value s, which
+// is of one of the types returned by protoScalarValue.
+func scalarTypedValue(s interface{}) (*gnmipb.TypedValue, error) {
+	switch s := s.(type) {
+	case string:
+		return &gnmipb.TypedValue{Value: &gnmipb.TypedValue_StringVal{StringVal: s}}, nil
+	case bool:
+		return &gnmipb.TypedValue{Value: &gnmipb.TypedValue_BoolVal{BoolVal: s}}, nil
+	case int64:
+		return &gnmipb.TypedValue{Value: &gnmipb.TypedValue_IntVal{IntVal: s}}, nil
+	case uint64:
+		return &gnmipb.TypedValue{Value: &gnmipb.TypedValue_UintVal{UintVal: s}}, nil
+	case []byte:
+		return &gnmipb.TypedValue{Value: &gnmipb.TypedValue_BytesVal{BytesVal: s}}, nil
+	case *gnmipb.Decimal64:
+		return &gnmipb.TypedValue{Value: &gnmipb.TypedValue_DecimalVal{DecimalVal: s}}, nil
+	}
+	return nil, fmt.Errorf("unsupported value type %T", s)
+}
+
+// protoScalarValue returns the scalar value of v, which is a single value of a
+// protobuf field that represents a YANG leaf. The value returned is a string,
+// bool, int64, uint64, []byte or *gnmipb.Decimal64. Enumerated values are
+// returned as the YANG name of the value.
+func protoScalarValue(v reflect.Value) (interface{}, error) {
+	if e, ok := v.Interface().(describedProtoEnum); ok {
+		return protoEnumYANGName(e, int32(v.Int()))
+	}
+
+	switch s := v.Interface().(type) {
+	case *ywrapper.StringValue:
+		return s.Value, nil
+	case *ywrapper.BoolValue:
+		return s.Value, nil
+	case *ywrapper.IntValue:
+		return s.Value, nil
+	case *ywrapper.UintValue:
+		return s.Value, nil
+	case *ywrapper.BytesValue:
+		return s.Value, nil
+	case *ywrapper.Decimal64Value:
+		return &gnmipb.Decimal64{Digits: s.Digits, Precision: s.Precision}, nil
+	case *wrappers.StringValue:
+		return s.Value, nil
+	case *wrappers.BoolValue:
+		return s.Value, nil
+	case *wrappers.Int64Value:
+		return s.Value, nil
+	case *wrappers.UInt64Value:
+		return s.Value, nil
+	case *wrappers.BytesValue:
+		return s.Value, nil
+	}
+
+	switch v.Kind() {
+	case reflect.Ptr:
+		if v.IsNil() {
+			return nil, fmt.Errorf("nil value of type %s", v.Type())
+		}
+		return protoScalarValue(v.Elem())
+	case reflect.String:
+		return v.String(), nil
+	case reflect.Bool:
+		return v.Bool(), nil
+	case reflect.Int32, reflect.Int64:
+		return v.Int(), nil
+	case reflect.Uint32, reflect.Uint64:
+		return v.Uint(), nil
+	case reflect.Slice:
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			return v.Bytes(), nil
+		}
+	}
+	return nil, fmt.Errorf("unsupported field type %s", v.Type())
+}
+
+// protoEnumYANGName returns the YANG name of the value n of the generated
+// protobuf enum e, as specified by its yang_name annotation.
+func protoEnumYANGName(e describedProtoEnum, n int32) (string, error) {
+	gz, idx := e.EnumDescriptor()
+	fd, err := protoFileDescriptor(gz)
+	if err != nil {
+		return "", err
+	}
+
+	enums, msgs := fd.EnumType, fd.MessageType
+	for i, x := range idx {
+		if i == len(idx)-1 {
+			if x >= len(enums) {
+				return "", fmt.Errorf("invalid descriptor path %v for enum %T", idx, e)
+			}
+			for _, ev := range enums[x].Value {
+				if ev.GetNumber() != n {
+					continue
+				}
+				if ev.Options == nil || !proto.HasExtension(ev.Options, yext.E_YangName) {
+					return "", fmt.Errorf("value %s of enum %T does not have a YANG name annotation", ev.GetName(), e)
+				}
+				ext, err := proto.GetExtension(ev.Options, yext.E_YangName)
+				if err != nil {
+					return "", err
+				}
+				return *ext.(*string), nil
+			}
+			return "", fmt.Errorf("%d is not a value of enum %T", n, e)
+		}
+		if x >= len(msgs) {
+			return "", fmt.Errorf("invalid descriptor path %v for enum %T", idx, e)
+		}
+		enums, msgs = msgs[x].EnumType, msgs[x].NestedType
+	}
+	return "", fmt.Errorf("invalid descriptor path %v for enum %T", idx, e)
+}
+
+// protoMessageFields returns the descriptors of the fields of the generated
+// protobuf message m, keyed by field number.
+func protoMessageFields(m proto.Message) (map[int32]*dpb.FieldDescriptorProto, error) {
+	dm, ok := m.(describedProtoMessage)
+	if !ok {
+		return nil, fmt.Errorf("message %T does not have a descriptor", m)
+	}
+	gz, idx := dm.Descriptor()
+	fd, err := protoFileDescriptor(gz)
+	if err != nil {
+		return nil, err
+	}
+
+	msgs := fd.MessageType
+	var md *dpb.DescriptorProto
+	for _, i := range idx {
+		if i >= len(msgs) {
+			return nil, fmt.Errorf("invalid descriptor path %v for message %T", idx, m)
+		}
+		md = msgs[i]
+		msgs = md.NestedType
+	}
+	if md == nil {
+		return nil, fmt.Errorf("invalid descriptor path %v for message %T", idx, m)
+	}
+
+	fields := map[int32]*dpb.FieldDescriptorProto{}
+	for _, f := range md.Field {
+		fields[f.GetNumber()] = f
+	}
+	return fields, nil
+}
+
+// protoFieldSchemaPath returns the schema path with which the field described
+// by fd is annotated, and a bool indicating whether the field has such an
+// annotation. Where the field has more than one schema path, the first is
+// returned.
+func protoFieldSchemaPath(fd *dpb.FieldDescriptorProto) (string, bool, error) {
+	if fd.Options == nil || !proto.HasExtension(fd.Options, yext.E_Schemapath) {
+		return "", false, nil
+	}
+	ext, err := proto.GetExtension(fd.Options, yext.E_Schemapath)
+	if err != nil {
+		return "", false, fmt.Errorf("cannot read schema path of field %s: %v", fd.GetName(), err)
+	}
+	return strings.Split(*ext.(*string), "|")[0], true, nil
+}
+
+// protoFileDescriptor returns the FileDescriptorProto from the gzipped,
+// serialised form gz that is returned by the descriptor methods of generated
+// protobuf messages and enums.
+func protoFileDescriptor(gz []byte) (*dpb.FileDescriptorProto, error) {
+	r, err := gzip.NewReader(bytes.NewReader(gz))
+	if err != nil {
+		return nil, fmt.Errorf("cannot read file descriptor: %v", err)
+	}
+	b, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read file descriptor: %v", err)
+	}
+	fd := &dpb.FileDescriptorProto{}
+	if err := proto.Unmarshal(b, fd); err != nil {
+		return nil, fmt.Errorf("cannot unmarshal file descriptor: %v", err)
+	}
+	return fd, nil
+}
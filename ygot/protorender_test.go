@@ -0,0 +1,369 @@
+// Copyright 2018 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ygot
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"testing"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/openconfig/ygot/proto/yext"
+	"github.com/openconfig/ygot/testutil"
+
+	dpb "github.com/golang/protobuf/protoc-gen-go/descriptor"
+	wpb "github.com/golang/protobuf/ptypes/wrappers"
+	gnmipb "github.com/openconfig/gnmi/proto/gnmi"
+	ocenums "github.com/openconfig/ygot/demo/protobuf_getting_started/ribproto/openconfig/enums"
+	ocrpb "github.com/openconfig/ygot/demo/protobuf_getting_started/ribproto/openconfig/openconfig_rib_bgp"
+	ywpb "github.com/openconfig/ygot/proto/ywrapper"
+)
+
+// mapListMessage is a protobuf message, of the form generated for a list that
+// is output as a protobuf map, that is used to test the rendering of such
+// lists.
+type mapListMessage struct {
+	List map[string]*ocrpb.BgpRib_AttrSets_AttrSet `protobuf:"bytes,1,rep,name=list" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+}
+
+func (*mapListMessage) Reset()           {}
+func (m *mapListMessage) String() string { return fmt.Sprintf("%v", m.List) }
+func (*mapListMessage) ProtoMessage()    {}
+func (*mapListMessage) Descriptor() ([]byte, []int) {
+	return mapListDescriptor, []int{0}
+}
+
+// mapListDescriptor is the gzipped FileDescriptorProto of mapListMessage.
+var mapListDescriptor = func() []byte {
+	opts := &dpb.FieldOptions{}
+	if err := proto.SetExtension(opts, yext.E_Schemapath, proto.String("/bgp-rib/attr-sets/attr-set")); err != nil {
+		panic(err)
+	}
+	fd := &dpb.FileDescriptorProto{
+		Name: proto.String("map_list.proto"),
+		MessageType: []*dpb.DescriptorProto{{
+			Name: proto.String("MapList"),
+			Field: []*dpb.FieldDescriptorProto{{
+				Name:     proto.String("list"),
+				Number:   proto.Int32(1),
+				Label:    dpb.FieldDescriptorProto_LABEL_REPEATED.Enum(),
+				Type:     dpb.FieldDescriptorProto_TYPE_MESSAGE.Enum(),
+				TypeName: proto.String(".MapList.ListEntry"),
+				Options:  opts,
+			}},
+			NestedType: []*dpb.DescriptorProto{{
+				Name:    proto.String("ListEntry"),
+				Options: &dpb.MessageOptions{MapEntry: proto.Bool(true)},
+			}},
+		}},
+	}
+	return gzipFileDescriptor(fd)
+}()
+
+// wrapperListMessage, wrapperKeyMessage and wrapperEntryMessage are protobuf
+// messages, of the form generated for a keyed list when the google.protobuf
+// wrapper types are used, that are used to test the rendering of such lists.
+type wrapperListMessage struct {
+	List []*wrapperKeyMessage `protobuf:"bytes,1,rep,name=list"`
+}
+
+func (*wrapperListMessage) Reset()           {}
+func (m *wrapperListMessage) String() string { return fmt.Sprintf("%v", m.List) }
+func (*wrapperListMessage) ProtoMessage()    {}
+func (*wrapperListMessage) Descriptor() ([]byte, []int) {
+	return wrapperListDescriptor, []int{0}
+}
+
+type wrapperKeyMessage struct {
+	Name  *wpb.StringValue     `protobuf:"bytes,1,opt,name=name"`
+	Entry *wrapperEntryMessage `protobuf:"bytes,2,opt,name=entry"`
+}
+
+func (*wrapperKeyMessage) Reset()           {}
+func (m *wrapperKeyMessage) String() string { return fmt.Sprintf("%v %v", m.Name, m.Entry) }
+func (*wrapperKeyMessage) ProtoMessage()    {}
+func (*wrapperKeyMessage) Descriptor() ([]byte, []int) {
+	return wrapperListDescriptor, []int{1}
+}
+
+type wrapperEntryMessage struct {
+	Enabled *wpb.BoolValue   `protobuf:"bytes,1,opt,name=enabled"`
+	Mtu     *wpb.UInt64Value `protobuf:"bytes,2,opt,name=mtu"`
+}
+
+func (*wrapperEntryMessage) Reset()           {}
+func (m *wrapperEntryMessage) String() string { return fmt.Sprintf("%v %v", m.Enabled, m.Mtu) }
+func (*wrapperEntryMessage) ProtoMessage()    {}
+func (*wrapperEntryMessage) Descriptor() ([]byte, []int) {
+	return wrapperListDescriptor, []int{2}
+}
+
+// wrapperListDescriptor is the gzipped FileDescriptorProto of
+// wrapperListMessage, wrapperKeyMessage and wrapperEntryMessage.
+var wrapperListDescriptor = func() []byte {
+	field := func(name string, n int32, label dpb.FieldDescriptorProto_Label, typeName, schemaPath string) *dpb.FieldDescriptorProto {
+		fd := &dpb.FieldDescriptorProto{
+			Name:     proto.String(name),
+			Number:   proto.Int32(n),
+			Label:    label.Enum(),
+			Type:     dpb.FieldDescriptorProto_TYPE_MESSAGE.Enum(),
+			TypeName: proto.String(typeName),
+		}
+		if schemaPath != "" {
+			fd.Options = &dpb.FieldOptions{}
+			if err := proto.SetExtension(fd.Options, yext.E_Schemapath, proto.String(schemaPath)); err != nil {
+				panic(err)
+			}
+		}
+		return fd
+	}
+	opt, rep := dpb.FieldDescriptorProto_LABEL_OPTIONAL, dpb.FieldDescriptorProto_LABEL_REPEATED
+	return gzipFileDescriptor(&dpb.FileDescriptorProto{
+		Name: proto.String("wrapper_list.proto"),
+		MessageType: []*dpb.DescriptorProto{{
+			Name:  proto.String("WrapperList"),
+			Field: []*dpb.FieldDescriptorProto{field("list", 1, rep, ".WrapperKey", "/interfaces/interface")},
+		}, {
+			Name: proto.String("WrapperKey"),
+			Field: []*dpb.FieldDescriptorProto{
+				field("name", 1, opt, ".google.protobuf.StringValue", "/interfaces/interface/name"),
+				field("entry", 2, opt, ".WrapperEntry", ""),
+			},
+		}, {
+			Name: proto.String("WrapperEntry"),
+			Field: []*dpb.FieldDescriptorProto{
+				field("enabled", 1, opt, ".google.protobuf.BoolValue", "/interfaces/interface/enabled"),
+				field("mtu", 2, opt, ".google.protobuf.UInt64Value", "/interfaces/interface/mtu"),
+			},
+		}},
+	})
+}()
+
+// gzipFileDescriptor returns the gzipped, serialised form of fd, as is
+// returned by the descriptor methods of generated protobuf messages.
+func gzipFileDescriptor(fd *dpb.FileDescriptorProto) []byte {
+	b, err := proto.Marshal(fd)
+	if err != nil {
+		panic(err)
+	}
+	var gz bytes.Buffer
+	w := gzip.NewWriter(&gz)
+	if _, err := w.Write(b); err != nil {
+		panic(err)
+	}
+	if err := w.Close(); err != nil {
+		panic(err)
+	}
+	return gz.Bytes()
+}
+
+func TestNotificationFromProto(t *testing.T) {
+	attrSetPath := func(leaf ...string) *gnmipb.Path {
+		p := &gnmipb.Path{Elem: []*gnmipb.PathElem{
+			{Name: "attr-sets"},
+			{Name: "attr-set", Key: map[string]string{"index": "1"}},
+		}}
+		for _, l := range leaf {
+			p.Elem = append(p.Elem, &gnmipb.PathElem{Name: l})
+		}
+		return p
+	}
+
+	tests := []struct {
+		name         string
+		inMsg        proto.Message
+		inSchemaPath string
+		inTimestamp  int64
+		want         *gnmipb.Notification
+		wantErr      bool
+	}{{
+		name:         "empty message",
+		inMsg:        &ocrpb.BgpRib{},
+		inSchemaPath: "/bgp-rib",
+		inTimestamp:  42,
+		want: &gnmipb.Notification{
+			Timestamp: 42,
+			Prefix:    &gnmipb.Path{Elem: []*gnmipb.PathElem{{Name: "bgp-rib"}}},
+		},
+	}, {
+		name: "leaves within a keyed list",
+		inMsg: &ocrpb.BgpRib{
+			AttrSets: &ocrpb.BgpRib_AttrSets{
+				AttrSet: []*ocrpb.BgpRib_AttrSets_AttrSetKey{{
+					Index: 1,
+					AttrSet: &ocrpb.BgpRib_AttrSets_AttrSet{
+						State: &ocrpb.BgpRib_AttrSets_AttrSet_State{
+							AtomicAggregate: &ywpb.BoolValue{Value: true},
+							ClusterList:     []*ywpb.StringValue{{Value: "192.0.2.1"}, {Value: "192.0.2.2"}},
+							LocalPref:       &ywpb.UintValue{Value: 100},
+							NextHop:         &ywpb.StringValue{Value: "10.0.1.1"},
+							Origin:          ocenums.OpenconfigRibBgpBgpOriginAttrType_OPENCONFIGRIBBGPBGPORIGINATTRTYPE_EGP,
+						},
+					},
+				}},
+			},
+		},
+		inSchemaPath: "/bgp-rib",
+		inTimestamp:  42,
+		want: &gnmipb.Notification{
+			Timestamp: 42,
+			Prefix:    &gnmipb.Path{Elem: []*gnmipb.PathElem{{Name: "bgp-rib"}}},
+			Update: []*gnmipb.Update{{
+				Path: attrSetPath("index"),
+				Val:  &gnmipb.TypedValue{Value: &gnmipb.TypedValue_UintVal{UintVal: 1}},
+			}, {
+				Path: attrSetPath("state", "atomic-aggregate"),
+				Val:  &gnmipb.TypedValue{Value: &gnmipb.TypedValue_BoolVal{BoolVal: true}},
+			}, {
+				Path: attrSetPath("state", "cluster-list"),
+				Val: &gnmipb.TypedValue{Value: &gnmipb.TypedValue_LeaflistVal{LeaflistVal: &gnmipb.ScalarArray{
+					Element: []*gnmipb.TypedValue{
+						{Value: &gnmipb.TypedValue_StringVal{StringVal: "192.0.2.1"}},
+						{Value: &gnmipb.TypedValue_StringVal{StringVal: "192.0.2.2"}},
+					},
+				}}},
+			}, {
+				Path: attrSetPath("state", "local-pref"),
+				Val:  &gnmipb.TypedValue{Value: &gnmipb.TypedValue_UintVal{UintVal: 100}},
+			}, {
+				Path: attrSetPath("state", "next-hop"),
+				Val:  &gnmipb.TypedValue{Value: &gnmipb.TypedValue_StringVal{StringVal: "10.0.1.1"}},
+			}, {
+				Path: attrSetPath("state", "origin"),
+				Val:  &gnmipb.TypedValue{Value: &gnmipb.TypedValue_StringVal{StringVal: "EGP"}},
+			}},
+		},
+	}, {
+		name: "list key with its zero value",
+		inMsg: &ocrpb.BgpRib_AttrSets{
+			AttrSet: []*ocrpb.BgpRib_AttrSets_AttrSetKey{{
+				AttrSet: &ocrpb.BgpRib_AttrSets_AttrSet{
+					State: &ocrpb.BgpRib_AttrSets_AttrSet_State{
+						NextHop: &ywpb.StringValue{Value: "10.0.1.1"},
+					},
+				},
+			}},
+		},
+		inSchemaPath: "/bgp-rib/attr-sets",
+		want: &gnmipb.Notification{
+			Prefix: &gnmipb.Path{Elem: []*gnmipb.PathElem{{Name: "bgp-rib"}, {Name: "attr-sets"}}},
+			Update: []*gnmipb.Update{{
+				Path: &gnmipb.Path{Elem: []*gnmipb.PathElem{
+					{Name: "attr-set", Key: map[string]string{"index": "0"}},
+					{Name: "state"},
+					{Name: "next-hop"},
+				}},
+				Val: &gnmipb.TypedValue{Value: &gnmipb.TypedValue_StringVal{StringVal: "10.0.1.1"}},
+			}},
+		},
+	}, {
+		name: "list with google.protobuf wrapper key and leaves",
+		inMsg: &wrapperListMessage{
+			List: []*wrapperKeyMessage{{
+				Name: &wpb.StringValue{Value: "eth0"},
+				Entry: &wrapperEntryMessage{
+					Enabled: &wpb.BoolValue{Value: true},
+					Mtu:     &wpb.UInt64Value{Value: 1500},
+				},
+			}},
+		},
+		inSchemaPath: "/interfaces",
+		want: &gnmipb.Notification{
+			Prefix: &gnmipb.Path{Elem: []*gnmipb.PathElem{{Name: "interfaces"}}},
+			Update: []*gnmipb.Update{{
+				Path: &gnmipb.Path{Elem: []*gnmipb.PathElem{{Name: "interface", Key: map[string]string{"name": "eth0"}}, {Name: "name"}}},
+				Val:  &gnmipb.TypedValue{Value: &gnmipb.TypedValue_StringVal{StringVal: "eth0"}},
+			}, {
+				Path: &gnmipb.Path{Elem: []*gnmipb.PathElem{{Name: "interface", Key: map[string]string{"name": "eth0"}}, {Name: "enabled"}}},
+				Val:  &gnmipb.TypedValue{Value: &gnmipb.TypedValue_BoolVal{BoolVal: true}},
+			}, {
+				Path: &gnmipb.Path{Elem: []*gnmipb.PathElem{{Name: "interface", Key: map[string]string{"name": "eth0"}}, {Name: "mtu"}}},
+				Val:  &gnmipb.TypedValue{Value: &gnmipb.TypedValue_UintVal{UintVal: 1500}},
+			}},
+		},
+	}, {
+		name: "list key that is not set",
+		inMsg: &wrapperListMessage{
+			List: []*wrapperKeyMessage{{
+				Entry: &wrapperEntryMessage{
+					Enabled: &wpb.BoolValue{Value: true},
+				},
+			}},
+		},
+		inSchemaPath: "/interfaces",
+		wantErr:      true,
+	}, {
+		name: "list with an enumerated key",
+		inMsg: &ocrpb.BgpRib_AfiSafis{
+			AfiSafi: []*ocrpb.BgpRib_AfiSafis_AfiSafiKey{{
+				AfiSafiName: ocenums.OpenconfigBgpTypesAFISAFITYPE_OPENCONFIGBGPTYPESAFISAFITYPE_IPV4_UNICAST,
+			}},
+		},
+		inSchemaPath: "/bgp-rib/afi-safis",
+		want: &gnmipb.Notification{
+			Prefix: &gnmipb.Path{Elem: []*gnmipb.PathElem{{Name: "bgp-rib"}, {Name: "afi-safis"}}},
+			Update: []*gnmipb.Update{{
+				Path: &gnmipb.Path{Elem: []*gnmipb.PathElem{
+					{Name: "afi-safi", Key: map[string]string{"afi-safi-name": "IPV4_UNICAST"}},
+					{Name: "afi-safi-name"},
+				}},
+				Val: &gnmipb.TypedValue{Value: &gnmipb.TypedValue_StringVal{StringVal: "IPV4_UNICAST"}},
+			}},
+		},
+	}, {
+		name: "schema path that is not an ancestor of the message's fields",
+		inMsg: &ocrpb.BgpRib_AttrSets_AttrSet_State{
+			NextHop: &ywpb.StringValue{Value: "10.0.1.1"},
+		},
+		inSchemaPath: "/bgp-rib/afi-safis",
+		wantErr:      true,
+	}, {
+		name: "field without a schema path annotation",
+		inMsg: &ocrpb.BgpRib_AfiSafis_AfiSafi_Ipv4Unicast_LocRib_Routes_RouteKey{
+			Prefix: "192.0.2.0/24",
+			Origin: &ocrpb.BgpRib_AfiSafis_AfiSafi_Ipv4Unicast_LocRib_Routes_RouteKey_OriginString{OriginString: "static"},
+		},
+		inSchemaPath: "/bgp-rib/afi-safis/afi-safi/ipv4-unicast/loc-rib/routes",
+		wantErr:      true,
+	}, {
+		name: "list represented as a map",
+		inMsg: &mapListMessage{
+			List: map[string]*ocrpb.BgpRib_AttrSets_AttrSet{
+				"1": {
+					State: &ocrpb.BgpRib_AttrSets_AttrSet_State{
+						NextHop: &ywpb.StringValue{Value: "10.0.1.1"},
+					},
+				},
+			},
+		},
+		inSchemaPath: "/bgp-rib/attr-sets",
+		wantErr:      true,
+	}}
+
+	for _, tt := range tests {
+		got, err := NotificationFromProto(tt.inMsg, tt.inSchemaPath, tt.inTimestamp)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: NotificationFromProto(%v, %s, %d): did not get expected error status, got: %v, wantErr: %v", tt.name, tt.inMsg, tt.inSchemaPath, tt.inTimestamp, err, tt.wantErr)
+		}
+		if err != nil {
+			continue
+		}
+		if !testutil.NotificationSetEqual([]*gnmipb.Notification{got}, []*gnmipb.Notification{tt.want}) {
+			diff := testutil.NotificationSetDiff([]*gnmipb.Notification{got}, []*gnmipb.Notification{tt.want})
+			t.Errorf("%s: NotificationFromProto(%v, %s, %d): did not get expected Notification, diff(-got,+want):\n%s", tt.name, tt.inMsg, tt.inSchemaPath, tt.inTimestamp, diff)
+		}
+	}
+}